@@ -56,6 +56,7 @@ import (
 // It implements analysis.SchemaAdapter.
 type Adapter struct {
 	registry *neogo.Registry
+	base     *analysis.TypeSchema
 }
 
 // NewAdapter creates a new adapter with the given types registered.
@@ -69,7 +70,20 @@ func NewAdapter(types ...any) *Adapter {
 	}
 }
 
+// NewAdapterWithBase creates an adapter whose extracted schema is merged into
+// base, e.g. a hand-written schema for nodes that have no neogo model.
+// Extracted models take precedence; base itself is not modified.
+func NewAdapterWithBase(base *analysis.TypeSchema, types ...any) *Adapter {
+	a := NewAdapter(types...)
+	a.base = base
+
+	return a
+}
+
 // ExtractSchema discovers models from registered types and returns a TypeSchema.
+// With a base schema, the result is the base merged with the extracted models.
+// Nested struct properties also contribute dot-separated fields such as
+// "address.city", so completions can follow u.address.
 func (a *Adapter) ExtractSchema() (*analysis.TypeSchema, error) {
 	schema := analysis.NewTypeSchema()
 
@@ -87,7 +101,20 @@ func (a *Adapter) ExtractSchema() (*analysis.TypeSchema, error) {
 		}
 	}
 
-	return schema, nil
+	if a.base == nil {
+		return schema, nil
+	}
+
+	merged := analysis.NewTypeSchema()
+	if err := merged.Merge(a.base); err != nil {
+		return nil, err
+	}
+
+	if err := merged.Merge(schema); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
 }
 
 // extractNodeModel converts a RegisteredNode to a Model.
@@ -102,7 +129,7 @@ func (a *Adapter) extractNodeModel(node *neogo.RegisteredNode) *analysis.Model {
 	if fieldsToProps != nil {
 		nodeType := node.Type()
 		if nodeType != nil {
-			model.Fields = a.extractFields(nodeType, fieldsToProps)
+			model.Fields, model.NestedFields = a.extractFields(nodeType, fieldsToProps)
 		}
 	}
 
@@ -112,6 +139,7 @@ func (a *Adapter) extractNodeModel(node *neogo.RegisteredNode) *analysis.Model {
 		model.Relationships = make([]*analysis.Relationship, 0, len(relationships))
 		for fieldName, relTarget := range relationships {
 			rel := a.extractRelationship(fieldName, relTarget, model.Name)
+			rel.Cardinality = relationshipCardinality(node.Type(), fieldName)
 			model.Relationships = append(model.Relationships, rel)
 		}
 	}
@@ -137,25 +165,28 @@ func (a *Adapter) extractRelationshipModel(rel *neogo.RegisteredRelationship) *a
 	if fieldsToProps != nil {
 		relType := rel.Type()
 		if relType != nil {
-			model.Fields = a.extractFields(relType, fieldsToProps)
+			model.Fields, model.NestedFields = a.extractFields(relType, fieldsToProps)
 		}
 	}
 
 	return model
 }
 
-// extractFields extracts Field definitions from a struct type.
-func (a *Adapter) extractFields(typ reflect.Type, fieldsToProps map[string]string) []*analysis.Field {
+// extractFields extracts Field definitions from a struct type, along with
+// the flattened fields of its struct-valued properties.
+func (a *Adapter) extractFields(typ reflect.Type, fieldsToProps map[string]string) ([]*analysis.Field, []*analysis.Field) {
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
 	}
 
 	if typ.Kind() != reflect.Struct {
-		return nil
+		return nil, nil
 	}
 
 	fields := make([]*analysis.Field, 0)
 
+	var nested []*analysis.Field
+
 	for goFieldName, dbFieldName := range fieldsToProps {
 		// Skip startNode/endNode - these are relationship navigation, not stored properties
 		if dbFieldName == "startNode" || dbFieldName == "endNode" {
@@ -184,11 +215,121 @@ func (a *Adapter) extractFields(typ reflect.Type, fieldsToProps map[string]strin
 			Type:     fieldType,
 			Required: required,
 		})
+
+		nested = append(nested, nestedFields(dbFieldName, field.Type, identityType, nil)...)
+	}
+
+	return fields, nested
+}
+
+// nestedFields flattens the fields of a nested struct property into
+// dot-separated names (e.g. "address.city"), recursing through structs,
+// pointers, slices, arrays and string-keyed maps. wrap applies the
+// containers the path has passed through to each field's type, so a field
+// under a slice is a list. visiting guards against self-referential types.
+func nestedFields(prefix string, typ reflect.Type, wrap func(*analysis.Type) *analysis.Type, visiting map[reflect.Type]bool) []*analysis.Field {
+	st, inner := nestedStruct(typ)
+	if st == nil || visiting[st] {
+		return nil
+	}
+
+	outer := wrap
+	wrap = func(t *analysis.Type) *analysis.Type { return outer(inner(t)) }
+
+	if visiting == nil {
+		visiting = make(map[reflect.Type]bool)
+	}
+
+	visiting[st] = true
+	defer delete(visiting, st)
+
+	var fields []*analysis.Field
+
+	for i := range st.NumField() {
+		field := st.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		propName, ok := nestedPropName(field)
+		if !ok {
+			continue
+		}
+
+		name := prefix + "." + propName
+
+		fields = append(fields, &analysis.Field{
+			Name:     name,
+			Type:     wrap(reflectTypeToType(field.Type)),
+			Required: field.Type.Kind() != reflect.Ptr,
+		})
+
+		fields = append(fields, nestedFields(name, field.Type, wrap, visiting)...)
 	}
 
 	return fields
 }
 
+// nestedStruct returns the struct type a property holds, looking through
+// pointers, slices, arrays and string-keyed maps, and a function wrapping a
+// type in the slices and maps it looked through. Structs without exported
+// fields, such as time.Time, are treated as leaf values.
+func nestedStruct(typ reflect.Type) (reflect.Type, func(*analysis.Type) *analysis.Type) {
+	wrap := identityType
+
+	for {
+		outer := wrap
+
+		switch typ.Kind() { //nolint:exhaustive // Other kinds are leaf values
+		case reflect.Ptr:
+			typ = typ.Elem()
+		case reflect.Slice, reflect.Array:
+			wrap = func(t *analysis.Type) *analysis.Type { return outer(analysis.SliceOf(t)) }
+			typ = typ.Elem()
+		case reflect.Map:
+			if typ.Key().Kind() != reflect.String {
+				return nil, nil
+			}
+
+			wrap = func(t *analysis.Type) *analysis.Type { return outer(analysis.MapOf(analysis.TypeString, t)) }
+			typ = typ.Elem()
+		case reflect.Struct:
+			for i := range typ.NumField() {
+				if typ.Field(i).IsExported() {
+					return typ, wrap
+				}
+			}
+
+			return nil, nil
+		default:
+			return nil, nil
+		}
+	}
+}
+
+func identityType(t *analysis.Type) *analysis.Type { return t }
+
+// nestedPropName returns the property name of a nested struct field: the
+// neo4j tag name, then the json tag name, then the Go field name.
+func nestedPropName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"neo4j", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		if tag == "-" {
+			return "", false
+		}
+
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name, true
+		}
+	}
+
+	return field.Name, true
+}
+
 // extractRelationship converts a RelationshipTarget to a Relationship.
 func (a *Adapter) extractRelationship(fieldName string, target *neogo.RelationshipTarget, sourceNodeName string) *analysis.Relationship {
 	rel := &analysis.Relationship{
@@ -217,6 +358,39 @@ func (a *Adapter) extractRelationship(fieldName string, target *neogo.Relationsh
 	return rel
 }
 
+// relationshipCardinality reads the cardinality option of a relationship
+// field's tag, e.g. `neo4j:"->,cardinality:one"`. It returns zero when the
+// field has no cardinality option.
+func relationshipCardinality(typ reflect.Type, fieldName string) analysis.RelCardinality {
+	if typ == nil {
+		return 0
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return 0
+	}
+
+	field, ok := typ.FieldByName(fieldName)
+	if !ok {
+		return 0
+	}
+
+	for _, option := range strings.Split(field.Tag.Get("neo4j"), ",")[1:] {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(option), "cardinality:"); ok {
+			cardinality, err := analysis.ParseRelCardinality(name)
+			if err == nil {
+				return cardinality
+			}
+		}
+	}
+
+	return 0
+}
+
 // resolveRelationshipTarget finds the target model name for a relationship struct.
 func (a *Adapter) resolveRelationshipTarget(relName string, outgoing bool) string {
 	relMeta := a.registry.GetRelMeta(relName)
@@ -1,6 +1,10 @@
 package neogo_test
 
 import (
+	"bytes"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/rlch/neogo"
@@ -70,6 +74,35 @@ type NodeWithComplexTypes struct {
 	PointerInt    *int     `neo4j:"pointerInt"`
 }
 
+// Address is a nested struct property.
+type Address struct {
+	City   string `json:"city"`
+	Street string
+	Geo    *Geo `json:"geo"`
+}
+
+// Geo is nested two levels deep.
+type Geo struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Tree is self-referential through a nested property.
+type Tree struct {
+	Label    string  `json:"label"`
+	Children []*Tree `json:"children"`
+}
+
+// NodeWithNestedStructs tests flattening of nested struct properties.
+type NodeWithNestedStructs struct {
+	neogo.Node `neo4j:"NodeWithNestedStructs"`
+
+	Address   Address   `neo4j:"address"`
+	Previous  *Address  `neo4j:"previous"`
+	Addresses []Address `neo4j:"addresses"`
+	Tree      Tree      `neo4j:"tree"`
+}
+
 // Test models for relationship direction tests.
 type IncomingNode struct {
 	neogo.Node `neo4j:"IncomingNode"`
@@ -105,6 +138,28 @@ type Pet struct {
 	Name string `neo4j:"name"`
 }
 
+// Test models for cardinality tag options.
+type Citizen struct {
+	neogo.Node `neo4j:"Citizen"`
+
+	Name     string              `neo4j:"name"`
+	Passport neogo.One[Passport] `neo4j:"HOLDS>,cardinality:one"`
+	Visited  neogo.Many[Country] `neo4j:"VISITED>,cardinality:many"`
+	Lived    neogo.Many[Country] `neo4j:"LIVED_IN>"`
+}
+
+type Passport struct {
+	neogo.Node `neo4j:"Passport"`
+
+	Number string `neo4j:"number"`
+}
+
+type Country struct {
+	neogo.Node `neo4j:"Country"`
+
+	Name string `neo4j:"name"`
+}
+
 // Test model for nodes without relationships.
 type Standalone struct {
 	neogo.Node `neo4j:"Standalone"`
@@ -219,6 +274,54 @@ func TestExtractSchema_Nodes(t *testing.T) {
 	}
 }
 
+func TestExtractSchema_WithBase(t *testing.T) {
+	base := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			// External node with no neogo model
+			"Country": {Name: "Country", Fields: []*analysis.Field{{Name: "code", Type: analysis.TypeString}}},
+			// Extends the extracted Person model
+			"Person": {Name: "Person", Fields: []*analysis.Field{{Name: "email", Type: analysis.TypeString, Unique: true}}},
+		},
+	}
+
+	a := adapter.NewAdapterWithBase(base, &Person{}, &Movie{})
+
+	schema, err := a.ExtractSchema()
+	if err != nil {
+		t.Fatalf("ExtractSchema failed: %v", err)
+	}
+
+	for _, name := range []string{"Country", "Person", "Movie"} {
+		if _, ok := schema.Models[name]; !ok {
+			t.Errorf("%s model not found", name)
+		}
+	}
+
+	personFields := fieldMap(schema.Models["Person"].Fields)
+	for _, name := range []string{"email", "name", "age"} {
+		if _, ok := personFields[name]; !ok {
+			t.Errorf("Person should have %q field", name)
+		}
+	}
+
+	if len(base.Models["Person"].Fields) != 1 {
+		t.Errorf("base Person was modified: %d fields", len(base.Models["Person"].Fields))
+	}
+}
+
+func TestExtractSchema_WithBaseConflict(t *testing.T) {
+	base := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"Person": {Name: "Person", Fields: []*analysis.Field{{Name: "age", Type: analysis.TypeString}}},
+		},
+	}
+
+	_, err := adapter.NewAdapterWithBase(base, &Person{}).ExtractSchema()
+	if err == nil {
+		t.Fatal("ExtractSchema should fail when base and extracted field types differ")
+	}
+}
+
 func TestExtractSchema_RelationshipModels(t *testing.T) {
 	a := adapter.NewAdapter(&Person{}, &Movie{}, &ActedIn{}, &Friendship{})
 
@@ -388,6 +491,116 @@ func TestExtractSchema_FieldTypes(t *testing.T) {
 	}
 }
 
+func TestExtractSchema_NestedStructs(t *testing.T) {
+	a := adapter.NewAdapter(&NodeWithNestedStructs{})
+
+	schema, err := a.ExtractSchema()
+	if err != nil {
+		t.Fatalf("ExtractSchema failed: %v", err)
+	}
+
+	model, ok := schema.Models["NodeWithNestedStructs"]
+	if !ok {
+		t.Fatal("NodeWithNestedStructs model not found")
+	}
+
+	if got := fieldMap(model.Fields)["address"]; got == nil || got.Type.String() != "neogo_test.Address" {
+		t.Errorf("address field = %+v, want type neogo_test.Address", got)
+	}
+
+	for _, field := range model.Fields {
+		if strings.Contains(field.Name, ".") {
+			t.Errorf("Fields contains nested field %q", field.Name)
+		}
+	}
+
+	fields := fieldMap(model.NestedFields)
+
+	tests := []struct {
+		name     string
+		wantType string
+	}{
+		{"address.city", "string"},
+		{"address.Street", "string"},
+		{"address.geo", "*neogo_test.Geo"},
+		{"address.geo.lat", "float64"},
+		{"previous.city", "string"},
+		{"addresses.city", "[]string"},
+		{"addresses.geo", "[]*neogo_test.Geo"},
+		{"addresses.geo.lng", "[]float64"},
+		{"tree.label", "string"},
+		{"tree.children", "[]*neogo_test.Tree"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, ok := fields[tt.name]
+			if !ok {
+				t.Fatalf("field %q not found", tt.name)
+			}
+
+			if got := field.Type.String(); got != tt.wantType {
+				t.Errorf("field %q type = %q, want %q", tt.name, got, tt.wantType)
+			}
+		})
+	}
+
+	// Self-referential types stop at the first repeat.
+	if _, ok := fields["tree.children.label"]; ok {
+		t.Error("self-referential nested fields should not be expanded")
+	}
+
+	if fields["address.geo"].Required {
+		t.Error("pointer nested field should not be required")
+	}
+}
+
+// TestExtractSchema_NestedStructs_RoundTrip checks that nested fields don't
+// leak into schema files or the structs generated from them.
+func TestExtractSchema_NestedStructs_RoundTrip(t *testing.T) {
+	a := adapter.NewAdapter(&NodeWithNestedStructs{})
+
+	schema, err := a.ExtractSchema()
+	if err != nil {
+		t.Fatalf("ExtractSchema failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := analysis.WriteSchemaJSON(&buf, schema); err != nil {
+		t.Fatalf("WriteSchemaJSON failed: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "address.city") {
+		t.Errorf("WriteSchemaJSON wrote nested fields:\n%s", buf.String())
+	}
+
+	read, err := analysis.ReadSchemaJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadSchemaJSON failed: %v", err)
+	}
+
+	got := make([]string, 0)
+	for _, field := range read.Models["NodeWithNestedStructs"].Fields {
+		got = append(got, field.Name)
+	}
+
+	sort.Strings(got)
+
+	want := []string{"address", "addresses", "id", "previous", "tree"}
+	if !slices.Equal(got, want) {
+		t.Errorf("fields after round trip = %v, want %v", got, want)
+	}
+
+	src, err := analysis.GenerateGoStructs(read, "models")
+	if err != nil {
+		t.Fatalf("GenerateGoStructs failed: %v", err)
+	}
+
+	if strings.Contains(string(src), `neo4j:"address.`) {
+		t.Errorf("GenerateGoStructs generated nested fields:\n%s", src)
+	}
+}
+
 func TestExtractSchema_RequiredFields(t *testing.T) {
 	a := adapter.NewAdapter(&NodeWithOptionalFields{})
 
@@ -646,6 +859,40 @@ func TestExtractSchema_OneRelationship(t *testing.T) {
 	}
 }
 
+func TestExtractSchema_Cardinality(t *testing.T) {
+	a := adapter.NewAdapter(&Citizen{}, &Passport{}, &Country{})
+
+	schema, err := a.ExtractSchema()
+	if err != nil {
+		t.Fatalf("ExtractSchema failed: %v", err)
+	}
+
+	citizen := schema.Models["Citizen"]
+	if citizen == nil {
+		t.Fatal("Citizen model not found")
+	}
+
+	rels := relationshipMap(citizen.Relationships)
+
+	tests := map[string]analysis.RelCardinality{
+		"Passport": analysis.CardinalityOne,
+		"Visited":  analysis.CardinalityMany,
+		"Lived":    0, // no cardinality option
+	}
+
+	for name, want := range tests {
+		rel, ok := rels[name]
+		if !ok {
+			t.Errorf("Citizen should have %q relationship", name)
+			continue
+		}
+
+		if rel.Cardinality != want {
+			t.Errorf("%s.Cardinality = %v, want %v", name, rel.Cardinality, want)
+		}
+	}
+}
+
 func TestExtractSchema_NoRelationships(t *testing.T) {
 	// Test node with no relationships
 	a := adapter.NewAdapter(&Standalone{})
@@ -3,8 +3,11 @@ package analysis
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
@@ -33,6 +36,14 @@ type Analyzer struct {
 
 	// rules is the set of semantic checks to run.
 	rules []*Rule
+
+	// config holds project overrides for rules, from .scaf.yaml.
+	// Can be nil to run every rule with its default severity.
+	config *scaf.AnalysisConfig
+
+	// parseOptions bounds parsing of the analyzed files.
+	// Zero means no limits.
+	parseOptions scaf.ParseOptions
 }
 
 // FileLoader is an interface for loading files during analysis.
@@ -102,6 +113,25 @@ func (a *Analyzer) SetSchema(schema *TypeSchema) {
 	a.schema = schema
 }
 
+// SetRules replaces the set of semantic checks run by the analyzer.
+func (a *Analyzer) SetRules(rules []*Rule) {
+	a.rules = rules
+}
+
+// SetAnalysisConfig sets project rule overrides. Rules disabled in cfg are
+// skipped and configured severities replace the severity of the rule's
+// diagnostics. Pass nil to clear the overrides.
+func (a *Analyzer) SetAnalysisConfig(cfg *scaf.AnalysisConfig) {
+	a.config = cfg
+}
+
+// SetParseOptions sets resource limits for parsing analyzed files. A file that
+// exceeds a limit gets a single parse-error diagnostic and no further analysis.
+// The Recovery option is ignored; Analyze decides when to recover.
+func (a *Analyzer) SetParseOptions(opts scaf.ParseOptions) {
+	a.parseOptions = opts
+}
+
 // NewAnalyzerWithRules creates an analyzer with custom rules.
 func NewAnalyzerWithRules(loader FileLoader, rules []*Rule) *Analyzer {
 	return &Analyzer{
@@ -110,6 +140,128 @@ func NewAnalyzerWithRules(loader FileLoader, rules []*Rule) *Analyzer {
 	}
 }
 
+// ErrUnknownDialect is returned by AnalyzeFile for a dialect with no
+// registered query analyzer.
+var ErrUnknownDialect = errors.New("unknown dialect")
+
+// AnalyzeOptions configures AnalyzeFile. The zero value analyzes a single file
+// with the default rules and no dialect.
+type AnalyzeOptions struct {
+	// Schema enables schema-aware rules and type inference. Can be nil.
+	Schema *TypeSchema
+
+	// Resolver loads imported files for cross-file checks. Can be nil.
+	Resolver FileResolver
+
+	// Dialect names the query dialect, e.g. scaf.DialectCypher. Its analyzer
+	// must be registered, usually by importing the dialect package. Empty
+	// skips query analysis.
+	Dialect string
+
+	// Rules are the checks to run. Nil runs DefaultRules.
+	Rules []*Rule
+
+	// Config holds project rule overrides, from .scaf.yaml. Can be nil.
+	Config *scaf.AnalysisConfig
+}
+
+// AnalyzeFile parses and analyzes the scaf file at path, whose content is src.
+// It is the entry point for analysis outside the LSP. Syntax errors don't
+// fail the call; they are reported in the file's diagnostics and ParseError,
+// with symbols from the partial AST. The error is for invalid options.
+func AnalyzeFile(path string, src []byte, opts *AnalyzeOptions) (*AnalyzedFile, error) {
+	if opts == nil {
+		opts = &AnalyzeOptions{}
+	}
+
+	var queryAnalyzer scaf.QueryAnalyzer
+	if opts.Dialect != "" {
+		queryAnalyzer = scaf.GetAnalyzer(opts.Dialect)
+		if queryAnalyzer == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownDialect, opts.Dialect)
+		}
+	}
+
+	a := &Analyzer{
+		queryAnalyzer: queryAnalyzer,
+		schema:        opts.Schema,
+		rules:         opts.Rules,
+		config:        opts.Config,
+	}
+
+	// Assigned only when set, so a nil resolver stays a nil interface
+	if opts.Resolver != nil {
+		a.resolver = opts.Resolver
+	}
+
+	if a.rules == nil {
+		a.rules = DefaultRules()
+	}
+
+	return a.Analyze(path, src), nil
+}
+
+// AnalyzeBatch runs rules over files analyzed together, such as all the files
+// of a project. Per-file rules run on each file first, then batch rules run
+// once with all files. Files should come from an analysis that didn't already
+// run the same per-file rules, e.g. one with an empty rule set.
+func AnalyzeBatch(files []*AnalyzedFile, rules []*Rule) {
+	sorted := sortRules(rules)
+
+	for _, f := range files {
+		if f.Suite == nil {
+			continue
+		}
+
+		for _, rule := range sorted {
+			if rule.Run != nil {
+				rule.Run(f)
+			}
+		}
+	}
+
+	for _, rule := range sorted {
+		if rule.RunBatch != nil {
+			rule.RunBatch(files)
+		}
+	}
+
+	for _, f := range files {
+		if f.Suite != nil {
+			applySuppressions(f)
+		}
+	}
+}
+
+// RunBatchRules runs the batch rules in opts over files already analyzed
+// with the same opts, such as by AnalyzeFile or AnalyzeParallel, completing
+// the checks AnalyzeBatch would make. Rule overrides in opts.Config apply as
+// they do when analyzing a single file.
+func RunBatchRules(files []*AnalyzedFile, opts *AnalyzeOptions) {
+	if opts == nil {
+		opts = &AnalyzeOptions{}
+	}
+
+	rules := opts.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+
+	a := &Analyzer{config: opts.Config}
+
+	for _, rule := range sortRules(rules) {
+		if rule.RunBatch != nil && rule.Run == nil {
+			a.runBatchRule(rule, files)
+		}
+	}
+
+	for _, f := range files {
+		if f.Suite != nil {
+			applySuppressions(f)
+		}
+	}
+}
+
 // Analyze parses and analyzes a scaf file.
 // On parse errors, still extracts symbols from the partial AST so that
 // LSP features like completion and hover continue to work.
@@ -127,10 +279,22 @@ func (a *Analyzer) Analyze(path string, content []byte) *AnalyzedFile {
 	// NOTE: We use non-recovery mode here because recovery can break parsing of valid
 	// syntax like "setup FunctionName()" (the recovery mechanism is too aggressive
 	// with optional groups).
-	suite, err := scaf.Parse(content)
+	opts := a.parseOptions
+	opts.Recovery = false
+
+	suite, err := scaf.ParseWithOptions(content, opts)
 	result.Suite = suite
 	result.ParseError = err
 
+	var limitErr *scaf.ParseLimitError
+	if errors.As(err, &limitErr) {
+		result.Diagnostics = append(result.Diagnostics, singleErrorToDiagnostic(err))
+		return result
+	}
+
+	result.Tokens = lexTokens(content)
+	result.CommentSpans = commentSpans(result.Tokens)
+
 	if err != nil {
 		// Convert parse errors to diagnostics
 		result.Diagnostics = append(result.Diagnostics, parseErrorsToDiagnostics(err)...)
@@ -139,7 +303,8 @@ func (a *Analyzer) Analyze(path string, content []byte) *AnalyzedFile {
 		// Also do a recovery parse for completion context.
 		// This may give us better information about what the user was typing
 		// even though it might misparse valid syntax elsewhere.
-		recoverySuite, recoveryErr := scaf.ParseWithRecovery(content, true)
+		opts.Recovery = true
+		recoverySuite, recoveryErr := scaf.ParseWithOptions(content, opts)
 		result.RecoverySuite = recoverySuite
 		result.RecoveryError = recoveryErr
 	}
@@ -147,7 +312,7 @@ func (a *Analyzer) Analyze(path string, content []byte) *AnalyzedFile {
 	// Build symbol table from partial or complete AST.
 	// Symbols defined before the error location will still be available.
 	if suite != nil {
-		buildSymbols(result, a.queryAnalyzer)
+		buildSymbols(result)
 	} else if err != nil {
 		// Fallback: if Participle returned nil AST, use regex extraction
 		extractPartialSymbols(result, content)
@@ -158,14 +323,177 @@ func (a *Analyzer) Analyze(path string, content []byte) *AnalyzedFile {
 	// This ensures users get semantic diagnostics (type errors, unused imports, etc.)
 	// even when there's a syntax error elsewhere in the file.
 	if suite != nil {
-		for _, rule := range a.rules {
-			rule.Run(result)
+		for _, rule := range sortRules(a.rules) {
+			a.runRule(rule, result)
 		}
+
+		applySuppressions(result)
 	}
 
 	return result
 }
 
+// runRule runs rule on f, applying any override from the analysis config.
+func (a *Analyzer) runRule(rule *Rule, f *AnalyzedFile) {
+	if rule.Run == nil {
+		return // Batch-only rule
+	}
+
+	var override scaf.RuleConfig
+
+	configured := false
+	if a.config != nil {
+		override, configured = a.config.Rules[rule.Name]
+	}
+
+	if !configured {
+		rule.Run(f)
+		return
+	}
+
+	if !override.Enabled {
+		return
+	}
+
+	start := len(f.Diagnostics)
+	rule.Run(f)
+
+	if severity, ok := severityFromName(override.Severity); ok {
+		for i := start; i < len(f.Diagnostics); i++ {
+			f.Diagnostics[i].Severity = severity
+		}
+	}
+}
+
+// runBatchRule runs the batch rule over files, applying any override from
+// the analysis config.
+func (a *Analyzer) runBatchRule(rule *Rule, files []*AnalyzedFile) {
+	var override scaf.RuleConfig
+
+	configured := false
+	if a.config != nil {
+		override, configured = a.config.Rules[rule.Name]
+	}
+
+	if !configured {
+		rule.RunBatch(files)
+		return
+	}
+
+	if !override.Enabled {
+		return
+	}
+
+	starts := make([]int, len(files))
+	for i, f := range files {
+		starts[i] = len(f.Diagnostics)
+	}
+
+	rule.RunBatch(files)
+
+	if severity, ok := severityFromName(override.Severity); ok {
+		for i, f := range files {
+			for j := starts[i]; j < len(f.Diagnostics); j++ {
+				f.Diagnostics[j].Severity = severity
+			}
+		}
+	}
+}
+
+// severityFromName maps a scaf.RuleSeverities name to a severity.
+func severityFromName(name string) (DiagnosticSeverity, bool) {
+	switch name {
+	case "error":
+		return SeverityError, true
+	case "warning":
+		return SeverityWarning, true
+	case "info":
+		return SeverityInformation, true
+	case "hint":
+		return SeverityHint, true
+	default:
+		return 0, false
+	}
+}
+
+// applySuppressions marks diagnostics silenced by scaf-disable annotations.
+// A disable-next-line annotation covers the following source line; a disable
+// annotation covers the innermost scope, group, or test containing it, or the
+// whole file at top level.
+func applySuppressions(f *AnalyzedFile) {
+	if len(f.Suite.Annotations) == 0 {
+		return
+	}
+
+	blocks := collectBlockSpans(f.Suite)
+
+	for i := range f.Diagnostics {
+		diag := &f.Diagnostics[i]
+
+		for _, a := range f.Suite.Annotations {
+			if !a.Suppresses(diag.Code) {
+				continue
+			}
+
+			if a.Kind == scaf.AnnotationDisableNextLine {
+				if diag.Span.Start.Line == a.Span.Start.Line+1 {
+					diag.Suppressed = true
+				}
+
+				continue
+			}
+
+			block, ok := innermostBlock(blocks, a.Span.Start)
+			if !ok || block.Contains(diag.Span.Start) {
+				diag.Suppressed = true
+			}
+		}
+	}
+}
+
+// collectBlockSpans returns the spans of all scopes, groups, and tests.
+func collectBlockSpans(suite *scaf.Suite) []scaf.Span {
+	var spans []scaf.Span
+
+	var walkItems func(items []*scaf.TestOrGroup)
+	walkItems = func(items []*scaf.TestOrGroup) {
+		for _, item := range items {
+			switch {
+			case item.Test != nil:
+				spans = append(spans, item.Test.Span())
+			case item.Group != nil:
+				spans = append(spans, item.Group.Span())
+				walkItems(item.Group.Items)
+			}
+		}
+	}
+
+	for _, scope := range suite.Scopes {
+		spans = append(spans, scope.Span())
+		walkItems(scope.Items)
+	}
+
+	return spans
+}
+
+// innermostBlock returns the smallest block span containing pos.
+// Blocks are nested or disjoint, so the last containing span in walk order is innermost.
+func innermostBlock(blocks []scaf.Span, pos lexer.Position) (scaf.Span, bool) {
+	var (
+		best  scaf.Span
+		found bool
+	)
+
+	for _, block := range blocks {
+		if block.Contains(pos) {
+			best = block
+			found = true
+		}
+	}
+
+	return best, found
+}
+
 // parseErrorToDiagnostic converts a parse error to a diagnostic.
 // If the error is a RecoveryError (containing multiple errors), it returns
 // a slice of diagnostics - one for each recovered error.
@@ -198,20 +526,13 @@ func parseErrorsToDiagnostics(err error) []Diagnostic {
 
 // singleErrorToDiagnostic converts a single error to a diagnostic.
 func singleErrorToDiagnostic(err error) Diagnostic {
-	// participle errors implement Error interface with Position().
 	span := scaf.Span{}
 	msg := err.Error()
 
-	// Try to extract position from participle error
-	type participleError interface {
-		Position() lexer.Position
-		Message() string
-	}
-
-	if pe, ok := err.(participleError); ok {
-		pos := pe.Position()
-		span = scaf.Span{Start: pos, End: pos}
-		msg = pe.Message()
+	var parseErr *scaf.ParseError
+	if errors.As(err, &parseErr) {
+		span = scaf.Span{Start: parseErr.Pos, End: parseErr.Pos}
+		msg = parseErr.Message()
 	}
 
 	return Diagnostic{
@@ -226,7 +547,7 @@ func singleErrorToDiagnostic(err error) Diagnostic {
 // buildSymbols extracts all symbol definitions from the AST.
 // Handles partial ASTs gracefully - symbols defined before parse errors
 // will still be extracted.
-func buildSymbols(f *AnalyzedFile, queryAnalyzer scaf.QueryAnalyzer) {
+func buildSymbols(f *AnalyzedFile) {
 	if f.Suite == nil {
 		return
 	}
@@ -263,8 +584,15 @@ func buildSymbols(f *AnalyzedFile, queryAnalyzer scaf.QueryAnalyzer) {
 		params := extractQueryParams(q.Body)
 		declaredParams := extractDeclaredParams(q)
 		typedParams := extractTypedParams(q)
-		queryBodyParams := extractQueryBodyParams(q.Body, queryAnalyzer, f.Schema)
-		queryBodyReturns := extractQueryBodyReturns(q.Body, queryAnalyzer, f.Schema)
+
+		var queryBodyParams []scaf.ParameterInfo
+		var queryBodyReturns []scaf.ReturnInfo
+
+		if body := f.GetBodyAnalysis(q.Body); body != nil {
+			queryBodyParams = body.Params
+			queryBodyReturns = body.Returns
+		}
+
 		f.Symbols.Queries[q.Name] = &QuerySymbol{
 			Symbol: Symbol{
 				Name: q.Name,
@@ -350,7 +678,8 @@ func baseNameFromPath(path string) string {
 // This enables completion to work while the user is typing (and the file is temporarily invalid).
 // It uses regex-based extraction which is less accurate than AST-based but works on broken code.
 func extractPartialSymbols(f *AnalyzedFile, content []byte) {
-	text := string(content)
+	// Commented-out definitions are not symbols
+	text := string(blankSpans(content, f.CommentSpans))
 
 	// Extract imports: import [alias] "path"
 	// Pattern: import fixtures "./fixtures" OR import "./fixtures"
@@ -398,6 +727,73 @@ func extractPartialSymbols(f *AnalyzedFile, content []byte) {
 	}
 }
 
+// lexTokens returns the tokens of content in source order, without
+// whitespace. Lexing stops at the first lexer error, keeping the tokens
+// before it.
+func lexTokens(content []byte) []lexer.Token {
+	def := scaf.ExportedLexer()
+
+	// Lexing records trivia on the shared definition, so serialize with parsing.
+	def.Lock()
+	defer def.Unlock()
+
+	lex, err := def.LexBytes("", content)
+	if err != nil {
+		return nil
+	}
+
+	var tokens []lexer.Token
+
+	for {
+		tok, err := lex.Next()
+		if err != nil || tok.EOF() {
+			return tokens
+		}
+
+		if tok.Type != scaf.TokenWhitespace {
+			tokens = append(tokens, tok)
+		}
+	}
+}
+
+// commentSpans returns the span of each // comment among tokens, from the
+// slashes to the end of the line. Annotations are directives rather than
+// commented-out code, so they are left out.
+func commentSpans(tokens []lexer.Token) []scaf.Span {
+	var spans []scaf.Span
+
+	for _, tok := range tokens {
+		if tok.Type != scaf.TokenComment {
+			continue
+		}
+
+		end := tok.Pos
+		end.Offset += len(tok.Value)
+		end.Column += utf8.RuneCountInString(tok.Value)
+
+		spans = append(spans, scaf.Span{Start: tok.Pos, End: end})
+	}
+
+	return spans
+}
+
+// blankSpans returns a copy of content with the bytes of each span replaced
+// by spaces, so offsets, lines, and columns are unchanged.
+func blankSpans(content []byte, spans []scaf.Span) []byte {
+	if len(spans) == 0 {
+		return content
+	}
+
+	blanked := slices.Clone(content)
+	for _, span := range spans {
+		for i := span.Start.Offset; i < span.End.Offset && i < len(blanked); i++ {
+			blanked[i] = ' '
+		}
+	}
+
+	return blanked
+}
+
 // extractQueryParams extracts $-prefixed parameters from a query body.
 var paramRegex = regexp.MustCompile(`\$(\w+)`)
 
@@ -417,63 +813,109 @@ func extractQueryParams(body string) []string {
 	return params
 }
 
-// schemaAwareAnalyzer is an interface for analyzers that support schema-aware type inference.
-type schemaAwareAnalyzer interface {
-	AnalyzeQueryWithSchema(query string, schema *TypeSchema) (*scaf.QueryMetadata, error)
+// ErrNoQueryAnalyzer is returned by AnalyzeQueryBody without a dialect analyzer.
+var ErrNoQueryAnalyzer = errors.New("no query analyzer")
+
+// BodyAnalysis is what a dialect analyzer finds in a query body. Bodies don't
+// change once parsed, so an analysis can be shared by every rule that looks at
+// the same body.
+type BodyAnalysis struct {
+	// Returns are the fields the body returns.
+	Returns []scaf.ReturnInfo
+
+	// Params are the $-prefixed parameters the body uses.
+	Params []scaf.ParameterInfo
+
+	// UsedLabels are the node labels in the body's patterns, whether matched
+	// or created, sorted and without duplicates.
+	UsedLabels []string
+
+	// UsedRelTypes are the relationship types in the body's patterns.
+	UsedRelTypes []string
+
+	// Warnings are the analyzer's non-fatal findings, such as a deprecated
+	// function. Metadata.WarningDetails has their codes and locations.
+	Warnings []string
+
+	// Metadata is the analyzer's full result, for what the fields above leave
+	// out, such as whether the body creates data.
+	Metadata *scaf.QueryMetadata
 }
 
-// extractQueryBodyParams extracts parameters from the query body using the dialect analyzer.
-// If schema is provided and the analyzer supports it, uses schema-aware analysis for type inference.
-// Returns nil if no analyzer is available or if analysis fails.
-func extractQueryBodyParams(body string, queryAnalyzer scaf.QueryAnalyzer, schema *TypeSchema) []scaf.ParameterInfo {
-	if queryAnalyzer == nil || body == "" {
-		return nil
+// AnalyzeQueryBody analyzes a query body with a dialect analyzer, using
+// schema-aware analysis when a schema is given and the analyzer supports it,
+// and falling back to basic analysis if that fails.
+func AnalyzeQueryBody(queryAnalyzer scaf.QueryAnalyzer, body string, schema *TypeSchema) (*BodyAnalysis, error) {
+	if queryAnalyzer == nil {
+		return nil, ErrNoQueryAnalyzer
 	}
 
-	// Try schema-aware analyzer first for better type inference
-	if schema != nil {
-		if schemaAnalyzer, ok := queryAnalyzer.(schemaAwareAnalyzer); ok {
-			metadata, err := schemaAnalyzer.AnalyzeQueryWithSchema(body, schema)
-			if err == nil && metadata != nil {
-				return metadata.Parameters
-			}
+	var metadata *scaf.QueryMetadata
+
+	if schemaAnalyzer, ok := queryAnalyzer.(SchemaAwareAnalyzer); ok && schema != nil {
+		if m, err := schemaAnalyzer.AnalyzeQueryWithSchema(body, schema); err == nil {
+			metadata = m
 		}
 	}
 
-	// Fall back to basic analysis
-	metadata, err := queryAnalyzer.AnalyzeQuery(body)
-	if err != nil || metadata == nil {
-		return nil
+	if metadata == nil {
+		var err error
+
+		metadata, err = queryAnalyzer.AnalyzeQuery(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if metadata == nil {
+			metadata = &scaf.QueryMetadata{}
+		}
 	}
 
-	return metadata.Parameters
+	labels := slices.Concat(metadata.MatchedLabels, metadata.CreatedLabels)
+	slices.Sort(labels)
+
+	return &BodyAnalysis{
+		Returns:      metadata.Returns,
+		Params:       metadata.Parameters,
+		UsedLabels:   slices.Compact(labels),
+		UsedRelTypes: metadata.RelationshipTypes,
+		Warnings:     metadata.Warnings,
+		Metadata:     metadata,
+	}, nil
 }
 
-// extractQueryBodyReturns extracts return fields from the query body using the dialect analyzer.
-// If schema is provided and the analyzer supports it, uses schema-aware analysis for type inference.
-// Returns nil if no analyzer is available or if analysis fails.
-func extractQueryBodyReturns(body string, queryAnalyzer scaf.QueryAnalyzer, schema *TypeSchema) []scaf.ReturnInfo {
-	if queryAnalyzer == nil || body == "" {
+// GetBodyAnalysis analyzes a query body with the file's dialect analyzer and
+// schema. Results are cached per file, so rules can call this freely without
+// re-parsing the same body. Returns nil if no analyzer is available or if
+// analysis fails.
+func (f *AnalyzedFile) GetBodyAnalysis(body string) *BodyAnalysis {
+	if f.QueryAnalyzer == nil || body == "" {
 		return nil
 	}
 
-	// Try schema-aware analyzer first for better type inference
-	if schema != nil {
-		if schemaAnalyzer, ok := queryAnalyzer.(schemaAwareAnalyzer); ok {
-			metadata, err := schemaAnalyzer.AnalyzeQueryWithSchema(body, schema)
-			if err == nil && metadata != nil {
-				return metadata.Returns
-			}
-		}
+	if result, ok := f.bodyAnalysisCache[body]; ok {
+		return result
 	}
 
-	// Fall back to basic analysis
-	metadata, err := queryAnalyzer.AnalyzeQuery(body)
-	if err != nil || metadata == nil {
-		return nil
+	if f.bodyAnalysisCache == nil {
+		f.bodyAnalysisCache = make(map[string]*BodyAnalysis)
+	}
+
+	result, _ := AnalyzeQueryBody(f.QueryAnalyzer, body, f.Schema)
+	f.bodyAnalysisCache[body] = result
+
+	return result
+}
+
+// GetQueryMetadata returns the full analyzer result for a query body, cached
+// as by GetBodyAnalysis. Returns nil if no analyzer is available or if
+// analysis fails.
+func (f *AnalyzedFile) GetQueryMetadata(body string) *scaf.QueryMetadata {
+	if result := f.GetBodyAnalysis(body); result != nil {
+		return result.Metadata
 	}
 
-	return metadata.Returns
+	return nil
 }
 
 // extractDeclaredParams extracts all parameter names from a function definition.
@@ -1,9 +1,15 @@
 package analysis_test
 
 import (
+	"errors"
+	"fmt"
 	"slices"
+	"strings"
 	"testing"
 
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"github.com/rlch/scaf"
 	"github.com/rlch/scaf/analysis"
 )
 
@@ -166,6 +172,256 @@ Q {
 	}
 }
 
+func TestAnalyzeFile(t *testing.T) {
+	t.Parallel()
+
+	src := []byte("fn Q() `MATCH (u:User) RETURN u LIMIT 1`\n\nUndefined {\n\ttest \"t\" {}\n}\n")
+
+	codes := func(f *analysis.AnalyzedFile) []string {
+		var codes []string
+		for _, d := range f.Diagnostics {
+			codes = append(codes, d.Code)
+		}
+
+		return codes
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := analysis.AnalyzeFile("test.scaf", src, nil)
+		if err != nil {
+			t.Fatalf("AnalyzeFile() error: %v", err)
+		}
+
+		got := codes(f)
+		if !slices.Contains(got, "undefined-query") || slices.Contains(got, "limit-without-order-by") {
+			t.Errorf("codes = %v, want undefined-query and no query warnings without a dialect", got)
+		}
+	})
+
+	t.Run("dialect and rules", func(t *testing.T) {
+		t.Parallel()
+
+		var invalidExpression *analysis.Rule
+		for _, rule := range analysis.DefaultRules() {
+			if rule.Name == "invalid-expression" {
+				invalidExpression = rule
+			}
+		}
+
+		f, err := analysis.AnalyzeFile("test.scaf", src, &analysis.AnalyzeOptions{
+			Dialect: scaf.DialectCypher,
+			Rules:   []*analysis.Rule{invalidExpression},
+		})
+		if err != nil {
+			t.Fatalf("AnalyzeFile() error: %v", err)
+		}
+
+		if got := codes(f); !slices.Equal(got, []string{"limit-without-order-by"}) {
+			t.Errorf("codes = %v, want only limit-without-order-by", got)
+		}
+	})
+
+	t.Run("config overrides", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := analysis.AnalyzeFile("test.scaf", src, &analysis.AnalyzeOptions{
+			Config: &scaf.AnalysisConfig{Rules: map[string]scaf.RuleConfig{"undefined-query": {Enabled: false}}},
+		})
+		if err != nil {
+			t.Fatalf("AnalyzeFile() error: %v", err)
+		}
+
+		if got := codes(f); slices.Contains(got, "undefined-query") {
+			t.Errorf("codes = %v, want undefined-query disabled", got)
+		}
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		t.Parallel()
+
+		f, err := analysis.AnalyzeFile("test.scaf", []byte("fn Q() `MATCH (n) RETURN n`\n\nQ {\n"), nil)
+		if err != nil {
+			t.Fatalf("AnalyzeFile() error: %v", err)
+		}
+
+		if f.ParseError == nil || !f.HasErrors() {
+			t.Errorf("want a parse error diagnostic, got %+v", f.Diagnostics)
+		}
+
+		if _, ok := f.Symbols.Queries["Q"]; !ok {
+			t.Error("want symbols from the partial AST")
+		}
+	})
+
+	t.Run("unknown dialect", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := analysis.AnalyzeFile("test.scaf", src, &analysis.AnalyzeOptions{Dialect: "gremlin"})
+		if !errors.Is(err, analysis.ErrUnknownDialect) {
+			t.Errorf("AnalyzeFile() error = %v, want ErrUnknownDialect", err)
+		}
+	})
+}
+
+func TestAnalyzeBatch(t *testing.T) {
+	t.Parallel()
+
+	analyzeEmpty := func(path, src string) *analysis.AnalyzedFile {
+		t.Helper()
+
+		f, err := analysis.AnalyzeFile(path, []byte(src), &analysis.AnalyzeOptions{Rules: []*analysis.Rule{}})
+		if err != nil {
+			t.Fatalf("AnalyzeFile() error: %v", err)
+		}
+
+		return f
+	}
+
+	a := analyzeEmpty("a.scaf", "import b \"./b\"\n\nfn Q() `MATCH (n) RETURN n`\n\nsetup b.R()\n\nQ {\n\ttest \"t\" {}\n}\n")
+	b := analyzeEmpty("b.scaf", "import a \"./a\"\n\nfn R() `MATCH (n) RETURN n`\n\nsetup a.Q()\n")
+	c := analyzeEmpty("c.scaf", "import a \"./a\"\nimport b \"./b\"\n\nsetup b.R()\n")
+
+	analysis.AnalyzeBatch([]*analysis.AnalyzedFile{a, b, c}, analysis.DefaultRules())
+
+	byCode := func(f *analysis.AnalyzedFile, code string) []analysis.Diagnostic {
+		var diags []analysis.Diagnostic
+		for _, d := range f.Diagnostics {
+			if d.Code == code {
+				diags = append(diags, d)
+			}
+		}
+
+		return diags
+	}
+
+	if diags := byCode(a, "circular-imports"); len(diags) != 1 || diags[0].Message != "circular import: a.scaf -> b.scaf -> a.scaf" {
+		t.Errorf("a.scaf circular-imports = %+v", diags)
+	}
+
+	if diags := byCode(b, "circular-imports"); len(diags) != 1 {
+		t.Errorf("b.scaf circular-imports = %+v", diags)
+	}
+
+	if diags := byCode(c, "circular-imports"); len(diags) != 0 {
+		t.Errorf("c.scaf isn't in the cycle, got %+v", diags)
+	}
+
+	if diags := byCode(a, "never-tested-query"); len(diags) != 0 {
+		t.Errorf("Q is tested, got %+v", diags)
+	}
+
+	if diags := byCode(b, "never-tested-query"); len(diags) != 1 || diags[0].Message != "query 'R' is never tested" {
+		t.Errorf("b.scaf never-tested-query = %+v", diags)
+	}
+
+	// Per-file rules ran too
+	if diags := byCode(c, "unused-import"); len(diags) != 1 {
+		t.Errorf("c.scaf unused-import = %+v", diags)
+	}
+}
+
+func TestRunBatchRules(t *testing.T) {
+	t.Parallel()
+
+	opts := &analysis.AnalyzeOptions{
+		Config: &scaf.AnalysisConfig{Rules: map[string]scaf.RuleConfig{
+			"circular-imports":   {Enabled: true, Severity: "error"},
+			"never-tested-query": {Enabled: false},
+		}},
+	}
+
+	analyze := func(path, src string) *analysis.AnalyzedFile {
+		t.Helper()
+
+		f, err := analysis.AnalyzeFile(path, []byte(src), opts)
+		if err != nil {
+			t.Fatalf("AnalyzeFile() error: %v", err)
+		}
+
+		return f
+	}
+
+	a := analyze("a.scaf", "import b \"./b\"\n\nfn Q() `MATCH (n) RETURN n`\n\nsetup b.R()\n")
+	b := analyze("b.scaf", "import a \"./a\"\n\nfn R() `MATCH (n) RETURN n`\n\nsetup a.Q()\n")
+	before := len(a.Diagnostics)
+
+	analysis.RunBatchRules([]*analysis.AnalyzedFile{a, b}, opts)
+
+	var codes []string
+	for _, d := range a.Diagnostics[before:] {
+		codes = append(codes, d.Code)
+
+		if d.Severity != analysis.SeverityError {
+			t.Errorf("%s severity = %v, want the configured error", d.Code, d.Severity)
+		}
+	}
+
+	// Per-file rules don't run again, and the disabled batch rule doesn't run
+	if !slices.Equal(codes, []string{"circular-imports"}) {
+		t.Errorf("new diagnostics = %v, want [circular-imports]", codes)
+	}
+}
+
+func TestAnalyzer_Suppressions(t *testing.T) {
+	t.Parallel()
+
+	input := `
+// scaf-disable-next-line unused-import
+import unused "./unused"
+import other "./other"
+
+fn Q() ` + "`Q`" + `
+fn R() ` + "`R`" + `
+
+Q {
+	// scaf-disable empty-test
+	test "a" {}
+	group "g" {
+		test "b" {}
+	}
+}
+
+R {
+	test "c" {}
+	// scaf-disable-next-line duplicate-test
+	test "d" {}
+}
+`
+
+	result := analysis.NewAnalyzer(nil).Analyze("test.scaf", []byte(input))
+
+	var active, suppressed []string
+
+	for _, d := range result.Diagnostics {
+		label := fmt.Sprintf("%s@%d", d.Code, d.Span.Start.Line)
+		if d.Suppressed {
+			suppressed = append(suppressed, label)
+		} else {
+			active = append(active, label)
+		}
+	}
+
+	// The "unused" import and the tests in Q are suppressed; the duplicate-test
+	// annotation on "d" names a rule that doesn't fire, so "d" stays reported.
+	wantSuppressed := []string{"unused-import@3", "empty-test@11", "empty-test@13"}
+	wantActive := []string{"unused-import@4", "empty-test@18", "empty-test@20"}
+
+	slices.Sort(active)
+	slices.Sort(suppressed)
+	slices.Sort(wantActive)
+	slices.Sort(wantSuppressed)
+
+	if !slices.Equal(suppressed, wantSuppressed) {
+		t.Errorf("suppressed = %v, want %v", suppressed, wantSuppressed)
+	}
+
+	if !slices.Equal(active, wantActive) {
+		t.Errorf("active = %v, want %v", active, wantActive)
+	}
+}
+
 func TestAnalyzer_PartialParsing(t *testing.T) {
 	t.Parallel()
 
@@ -245,6 +501,86 @@ Q {
 	}
 }
 
+func TestAnalyzer_CommentSpans(t *testing.T) {
+	t.Parallel()
+
+	input := "// leading comment\n" +
+		"fn GetUser() `MATCH (u:User) RETURN u` // trailing é\n" +
+		"\n" +
+		"// scaf-disable-next-line unused-import\n" +
+		"// GetPost {\n" +
+		"//   test \"gone\" {}\n" +
+		"// }\n"
+
+	result := analysis.NewAnalyzer(nil).Analyze("test.scaf", []byte(input))
+
+	type span struct{ line, startCol, endCol int }
+
+	got := make([]span, 0, len(result.CommentSpans))
+	for _, s := range result.CommentSpans {
+		if s.Start.Line != s.End.Line {
+			t.Errorf("comment span %v crosses lines", s)
+		}
+
+		if text := input[s.Start.Offset:s.End.Offset]; !strings.HasPrefix(text, "//") || strings.Contains(text, "\n") {
+			t.Errorf("comment span covers %q", text)
+		}
+
+		got = append(got, span{s.Start.Line, s.Start.Column, s.End.Column})
+	}
+
+	// The annotation on line 4 is a directive, not a comment
+	want := []span{{1, 1, 19}, {2, 40, 53}, {5, 1, 13}, {6, 1, 20}, {7, 1, 5}}
+	if !slices.Equal(got, want) {
+		t.Errorf("CommentSpans = %v, want %v", got, want)
+	}
+
+	if !result.InComment(scaf.Span{
+		Start: lexer.Position{Line: 5, Column: 4},
+		End:   lexer.Position{Line: 5, Column: 11},
+	}) {
+		t.Error("GetPost on line 5 should be in a comment")
+	}
+
+	if result.InComment(scaf.Span{
+		Start: lexer.Position{Line: 2, Column: 4},
+		End:   lexer.Position{Line: 2, Column: 11},
+	}) {
+		t.Error("GetUser on line 2 should not be in a comment")
+	}
+}
+
+func TestAnalyzer_Tokens(t *testing.T) {
+	t.Parallel()
+
+	input := "fn Q() `MATCH (n) RETURN n` // note\nQ {\n\ttest \"t\" {\n\t\tn: \n"
+
+	result := analysis.NewAnalyzer(nil).Analyze("test.scaf", []byte(input))
+	if result.ParseError == nil {
+		t.Fatal("expected a parse error for the unfinished test")
+	}
+
+	got := make([]string, 0, len(result.Tokens))
+	for _, tok := range result.Tokens {
+		if tok.Type == scaf.TokenWhitespace {
+			t.Errorf("whitespace token %q at %v", tok.Value, tok.Pos)
+		}
+
+		got = append(got, tok.Value)
+	}
+
+	want := []string{"fn", "Q", "(", ")", "`MATCH (n) RETURN n`", "// note", "Q", "{", "test", `"t"`, "{", "n", ":"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tokens = %q, want %q", got, want)
+	}
+
+	// The token stream still answers position queries when the parse fails
+	tok := analysis.PrevTokenAtPosition(result, lexer.Position{Line: 4, Column: 6})
+	if tok == nil || tok.Value != ":" {
+		t.Errorf("PrevTokenAtPosition = %v, want \":\"", tok)
+	}
+}
+
 func TestAnalyzer_ExtractQueryParams(t *testing.T) {
 	t.Parallel()
 
@@ -277,3 +613,170 @@ GetUser {
 		}
 	}
 }
+
+func TestAnalyzeQueryBody(t *testing.T) {
+	t.Parallel()
+
+	body, err := analysis.AnalyzeQueryBody(scaf.GetAnalyzer("cypher"),
+		"MATCH (u:User)-[:AUTHORED]->(p:Post) WHERE u.id = $id CREATE (p)-[:TAGGED]->(:Tag) RETURN u.name, p", nil)
+	if err != nil {
+		t.Fatalf("AnalyzeQueryBody() error: %v", err)
+	}
+
+	if !slices.Equal(body.UsedLabels, []string{"Post", "Tag", "User"}) {
+		t.Errorf("UsedLabels = %v", body.UsedLabels)
+	}
+
+	if !slices.Equal(body.UsedRelTypes, []string{"AUTHORED", "TAGGED"}) {
+		t.Errorf("UsedRelTypes = %v", body.UsedRelTypes)
+	}
+
+	if len(body.Params) != 1 || body.Params[0].Name != "id" || len(body.Returns) != 2 || !body.Metadata.CreatesData {
+		t.Errorf("AnalyzeQueryBody() = %+v", body)
+	}
+
+	if _, err := analysis.AnalyzeQueryBody(nil, "MATCH (u) RETURN u", nil); !errors.Is(err, analysis.ErrNoQueryAnalyzer) {
+		t.Errorf("AnalyzeQueryBody(nil) error = %v, want ErrNoQueryAnalyzer", err)
+	}
+
+	if _, err := analysis.AnalyzeQueryBody(&countingAnalyzer{calls: make(map[string]int)}, "broken", nil); err == nil {
+		t.Error("AnalyzeQueryBody(broken) error = nil")
+	}
+}
+
+// countingAnalyzer records how often each query body is analyzed.
+type countingAnalyzer struct {
+	calls map[string]int
+}
+
+func (c *countingAnalyzer) AnalyzeQuery(query string) (*scaf.QueryMetadata, error) {
+	c.calls[query]++
+
+	if query == "broken" {
+		return nil, errors.New("parse error")
+	}
+
+	return &scaf.QueryMetadata{
+		Returns: []scaf.ReturnInfo{{Name: "u", Expression: "u"}},
+	}, nil
+}
+
+func TestAnalyzedFile_GetQueryMetadata(t *testing.T) {
+	t.Parallel()
+
+	counter := &countingAnalyzer{calls: make(map[string]int)}
+	f := &analysis.AnalyzedFile{QueryAnalyzer: counter}
+
+	first := f.GetQueryMetadata("MATCH (u) RETURN u")
+	if first == nil {
+		t.Fatal("expected metadata")
+	}
+
+	if second := f.GetQueryMetadata("MATCH (u) RETURN u"); second != first {
+		t.Error("expected cached metadata on second call")
+	}
+
+	if f.GetQueryMetadata("broken") != nil || f.GetQueryMetadata("broken") != nil {
+		t.Error("expected nil metadata for a failing query")
+	}
+
+	for body, n := range counter.calls {
+		if n != 1 {
+			t.Errorf("AnalyzeQuery(%q) called %d times, want 1", body, n)
+		}
+	}
+
+	// Caches are per file.
+	other := &analysis.AnalyzedFile{QueryAnalyzer: counter}
+	other.GetQueryMetadata("MATCH (u) RETURN u")
+
+	if n := counter.calls["MATCH (u) RETURN u"]; n != 2 {
+		t.Errorf("AnalyzeQuery called %d times across files, want 2", n)
+	}
+}
+
+func TestAnalyzedFile_ExportSymbols(t *testing.T) {
+	t.Parallel()
+
+	input := `
+import fixtures "./fixtures"
+
+fn CreateUser() ` + "`CREATE (u:User) RETURN u`" + `
+fn _reset() ` + "`MATCH (n) DETACH DELETE n`" + `
+`
+
+	f := analysis.NewAnalyzer(nil).Analyze("test.scaf", []byte(input))
+
+	exports := f.ExportSymbols()
+	if exports == nil {
+		t.Fatal("expected exported symbols")
+	}
+
+	if _, ok := exports.Queries["CreateUser"]; !ok {
+		t.Error("expected CreateUser to be exported")
+	}
+
+	if _, ok := exports.Queries["_reset"]; ok {
+		t.Error("expected _reset to be internal")
+	}
+
+	if _, ok := f.Symbols.Queries["_reset"]; !ok {
+		t.Error("ExportSymbols should leave the file's own symbols intact")
+	}
+
+	if _, ok := exports.Imports["fixtures"]; !ok {
+		t.Error("expected imports to be kept")
+	}
+
+	if (&analysis.AnalyzedFile{}).ExportSymbols() != nil {
+		t.Error("expected nil exports for a file without symbols")
+	}
+}
+
+func TestAnalyzer_Analyze_QueryParsedOnce(t *testing.T) {
+	t.Parallel()
+
+	body := "MATCH (u:User {id: $id}) RETURN u"
+	counter := &countingAnalyzer{calls: make(map[string]int)}
+	analyzer := analysis.NewAnalyzerWithQueryAnalyzer(nil, nil, counter)
+
+	analyzer.Analyze("test.scaf", []byte(`
+fn GetUser() `+"`"+body+"`"+`
+
+GetUser {
+	test "a" {
+		$id: 1
+		assert (u != nil)
+	}
+	test "b" {
+		$id: 2
+		assert GetUser($id: 1) { (u != nil) }
+	}
+}
+`))
+
+	if n := counter.calls[body]; n != 1 {
+		t.Errorf("AnalyzeQuery called %d times, want 1", n)
+	}
+}
+
+func TestAnalyzer_SetParseOptions(t *testing.T) {
+	t.Parallel()
+
+	analyzer := analysis.NewAnalyzer(nil)
+	analyzer.SetParseOptions(scaf.ParseOptions{MaxBytes: 16})
+
+	result := analyzer.Analyze("test.scaf", []byte("fn Q() `MATCH (n) RETURN n`\n"))
+
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(result.Diagnostics), result.Diagnostics)
+	}
+
+	if diag := result.Diagnostics[0]; diag.Code != "parse-error" || diag.Message != "scaf: file exceeds the limit of 16 bytes" {
+		t.Errorf("diagnostic = %+v", diag)
+	}
+
+	if result.Suite != nil || len(result.Symbols.Queries) != 0 {
+		t.Error("expected no AST or symbols for a file over the limit")
+	}
+}
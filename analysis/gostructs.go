@@ -0,0 +1,537 @@
+package analysis
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"maps"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// neogoImport is the import path of the neogo ORM used by generated structs.
+const neogoImport = "github.com/rlch/neogo"
+
+// knownImports maps the short package names used in schema types to their
+// import paths. Types whose package is a full import path need no entry.
+var knownImports = map[string]string{
+	"time":  "time",
+	"neo4j": "github.com/neo4j/neo4j-go-driver/v5/neo4j",
+}
+
+// goAcronyms are name parts written in upper case in generated identifiers.
+var goAcronyms = map[string]bool{
+	"ID": true, "URL": true, "API": true, "HTTP": true, "JSON": true,
+	"XML": true, "SQL": true, "UUID": true, "DB": true,
+}
+
+// GenerateGoStructs generates Go source declaring a neogo model struct for
+// each model in schema, in package pkg. Models, fields, and relationships are
+// sorted by name so the output is deterministic.
+//
+// Property fields carry neo4j and json tags. Relationships are written as
+// neogo.One or neogo.Many fields. A model named by a relationship field (e.g.
+// Person's ActedIn relationship and an ActedIn model) is generated as a
+// relationship struct with startNode and endNode fields; other relationships
+// use the shorthand "REL_TYPE>" or "<REL_TYPE" tags, except where that would
+// make a recursive type (see planRelationships).
+func GenerateGoStructs(schema *TypeSchema, pkg string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+
+	g := &structGenerator{
+		schema:    schema,
+		imports:   map[string]bool{neogoImport: true},
+		typeNames: make(map[string]string),
+	}
+
+	if schema != nil {
+		g.names = sortedModelNames(schema)
+	}
+
+	if err := g.resolveNames(); err != nil {
+		return nil, err
+	}
+
+	g.findRelationshipModels()
+
+	if err := g.planRelationships(); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	for _, name := range g.names {
+		model := schema.Models[name]
+		if model == nil {
+			model = &Model{Name: name}
+		}
+
+		if err := g.writeModel(&body, model); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rs := range g.extraRels {
+		if err := writeExtraRelationship(&body, rs); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by scaf. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+
+	if len(g.names) > 0 {
+		writeImports(&out, slices.Sorted(maps.Keys(g.imports)))
+	}
+
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated structs: %w", err)
+	}
+
+	return src, nil
+}
+
+// writeImports writes an import block with standard library packages first.
+func writeImports(buf *bytes.Buffer, imports []string) {
+	std, other := []string{}, []string{}
+	for _, imp := range imports {
+		if strings.Contains(strings.Split(imp, "/")[0], ".") {
+			other = append(other, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+
+	buf.WriteString("import (\n")
+	for _, imp := range std {
+		fmt.Fprintf(buf, "\t%q\n", imp)
+	}
+
+	if len(std) > 0 && len(other) > 0 {
+		buf.WriteString("\n")
+	}
+
+	for _, imp := range other {
+		fmt.Fprintf(buf, "\t%q\n", imp)
+	}
+	buf.WriteString(")\n")
+}
+
+// structGenerator holds state while generating model structs.
+type structGenerator struct {
+	schema *TypeSchema
+	// names are the model names in output order.
+	names []string
+	// typeNames maps model names to their Go type names.
+	typeNames map[string]string
+	// imports are the import paths the generated code needs.
+	imports map[string]bool
+	// relModels maps the names of models generated as relationship structs to
+	// their start and end nodes.
+	relModels map[string]*relStruct
+	// extraRels are relationship structs with no model of their own; see
+	// planRelationships.
+	extraRels []*relStruct
+	// relFields are the planned One/Many fields, by relationship.
+	relFields map[*Relationship]relField
+	// holds maps a Go type to the types its One/Many fields hold by value.
+	holds map[string][]string
+}
+
+// relStruct is a generated neogo.Relationship struct.
+type relStruct struct {
+	typeName   string
+	relType    string
+	start, end string // Go type names
+}
+
+// relField is a planned One/Many relationship field.
+type relField struct {
+	typ, tag string
+}
+
+// resolveNames assigns each model a Go type name.
+func (g *structGenerator) resolveNames() error {
+	owners := make(map[string]string, len(g.names))
+
+	for _, name := range g.names {
+		typeName := goName(name)
+		if typeName == "" {
+			return fmt.Errorf("model %q: cannot derive a Go type name", name)
+		}
+
+		if other, ok := owners[typeName]; ok {
+			return fmt.Errorf("models %s and %s both generate type %s", other, name, typeName)
+		}
+
+		owners[typeName] = name
+		g.typeNames[name] = typeName
+	}
+
+	return nil
+}
+
+// findRelationshipModels finds models that are relationship structs: models
+// named by a relationship field on another model and declaring no
+// relationships of their own. The first declaring model, by name, defines the
+// struct's start and end nodes.
+func (g *structGenerator) findRelationshipModels() {
+	g.relModels = make(map[string]*relStruct)
+
+	for _, name := range g.names {
+		for _, rel := range sortedRelationships(g.schema.Models[name]) {
+			model, ok := g.schema.Models[rel.Name]
+			if !ok || model == nil || rel.Name == name || len(model.Relationships) > 0 {
+				continue
+			}
+
+			if _, seen := g.relModels[rel.Name]; !seen {
+				g.relModels[rel.Name] = g.newRelStruct(g.typeNames[rel.Name], name, rel)
+			}
+		}
+	}
+}
+
+// newRelStruct describes a relationship struct for rel, declared on source.
+func (g *structGenerator) newRelStruct(typeName, source string, rel *Relationship) *relStruct {
+	start, end := g.typeNames[source], g.modelType(rel.Target)
+	if rel.Direction == DirectionIncoming {
+		start, end = end, start
+	}
+
+	return &relStruct{typeName: typeName, relType: rel.RelType, start: start, end: end}
+}
+
+// planRelationships decides the type and tag of every relationship field.
+//
+// One and Many hold their type argument by value, so shorthand fields that
+// form a cycle (Person holding Many[Person], or Person and Movie holding each
+// other) would be an invalid recursive type. Such a relationship goes through
+// a generated relationship struct instead, which refers to its nodes by
+// pointer.
+func (g *structGenerator) planRelationships() error {
+	g.relFields = make(map[*Relationship]relField)
+	g.holds = make(map[string][]string)
+
+	taken := make(map[string]bool, len(g.typeNames))
+	for _, typeName := range g.typeNames {
+		taken[typeName] = true
+	}
+
+	for _, name := range g.names {
+		source := g.typeNames[name]
+
+		for _, rel := range sortedRelationships(g.schema.Models[name]) {
+			wrapper := "neogo.One"
+			if rel.Many {
+				wrapper = "neogo.Many"
+			}
+
+			arrow := "->"
+			if rel.Direction == DirectionIncoming {
+				arrow = "<-"
+			}
+
+			if rs, ok := g.relModels[rel.Name]; ok && rel.Name != name {
+				g.relFields[rel] = relField{typ: wrapper + "[" + rs.typeName + "]", tag: relTag(arrow, rel)}
+				continue
+			}
+
+			target, ok := g.typeNames[rel.Target]
+			if !ok {
+				return fmt.Errorf("model %s, relationship %s: unknown target model %q", name, rel.Name, rel.Target)
+			}
+
+			if target != source && !g.reaches(target, source) {
+				g.holds[source] = append(g.holds[source], target)
+				g.relFields[rel] = relField{typ: wrapper + "[" + target + "]", tag: relTag(shorthandTag(rel), rel)}
+
+				continue
+			}
+
+			typeName := source + goName(rel.Name)
+			if taken[typeName] {
+				typeName += "Rel"
+			}
+			if taken[typeName] {
+				return fmt.Errorf("model %s, relationship %s: type name %s is already taken", name, rel.Name, typeName)
+			}
+			taken[typeName] = true
+
+			g.extraRels = append(g.extraRels, g.newRelStruct(typeName, name, rel))
+			g.relFields[rel] = relField{typ: wrapper + "[" + typeName + "]", tag: relTag(arrow, rel)}
+		}
+	}
+
+	sort.Slice(g.extraRels, func(i, j int) bool { return g.extraRels[i].typeName < g.extraRels[j].typeName })
+
+	return nil
+}
+
+// reaches reports whether from holds to by value, directly or transitively.
+func (g *structGenerator) reaches(from, to string) bool {
+	seen := make(map[string]bool)
+	stack := []string{from}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current == to {
+			return true
+		}
+
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		stack = append(stack, g.holds[current]...)
+	}
+
+	return false
+}
+
+// shorthandTag returns the "REL_TYPE>" or "<REL_TYPE" tag of a shorthand relationship.
+func shorthandTag(rel *Relationship) string {
+	if rel.Direction == DirectionIncoming {
+		return "<" + rel.RelType
+	}
+
+	return rel.RelType + ">"
+}
+
+// relTag adds rel's cardinality, when known, to a relationship field tag,
+// e.g. "->,cardinality:one".
+func relTag(tag string, rel *Relationship) string {
+	if rel.Cardinality == 0 {
+		return tag
+	}
+
+	return tag + ",cardinality:" + rel.Cardinality.String()
+}
+
+// writeModel writes the struct for model.
+func (g *structGenerator) writeModel(buf *bytes.Buffer, model *Model) error {
+	fields := newFieldNames(model.Name)
+
+	fmt.Fprintf(buf, "\ntype %s struct {\n", g.typeNames[model.Name])
+
+	rs, isRel := g.relModels[model.Name]
+	if isRel {
+		fmt.Fprintf(buf, "\tneogo.Relationship `neo4j:%q`\n", rs.relType)
+	} else {
+		fmt.Fprintf(buf, "\tneogo.Node `neo4j:%q`\n", model.Name)
+	}
+
+	if len(model.Fields) > 0 {
+		buf.WriteString("\n")
+	}
+
+	for _, field := range sortedFields(model) {
+		name, err := fields.add(field.Name)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(buf, "\t%s %s `neo4j:%q json:%q`\n", name, g.goType(field.Type), field.Name, field.Name)
+	}
+
+	if isRel {
+		if err := writeRelationshipEnds(buf, fields, rs); err != nil {
+			return err
+		}
+	}
+
+	if rels := sortedRelationships(model); len(rels) > 0 {
+		buf.WriteString("\n")
+
+		for _, rel := range rels {
+			name, err := fields.add(rel.Name)
+			if err != nil {
+				return err
+			}
+
+			field := g.relFields[rel]
+			fmt.Fprintf(buf, "\t%s %s `neo4j:%q`\n", name, field.typ, field.tag)
+		}
+	}
+
+	buf.WriteString("}\n")
+
+	return nil
+}
+
+// writeExtraRelationship writes a relationship struct that has no model.
+func writeExtraRelationship(buf *bytes.Buffer, rs *relStruct) error {
+	fmt.Fprintf(buf, "\ntype %s struct {\n", rs.typeName)
+	fmt.Fprintf(buf, "\tneogo.Relationship `neo4j:%q`\n", rs.relType)
+
+	if err := writeRelationshipEnds(buf, newFieldNames(rs.typeName), rs); err != nil {
+		return err
+	}
+
+	buf.WriteString("}\n")
+
+	return nil
+}
+
+// writeRelationshipEnds writes the startNode and endNode fields of a relationship struct.
+func writeRelationshipEnds(buf *bytes.Buffer, fields *fieldNames, rs *relStruct) error {
+	buf.WriteString("\n")
+
+	for _, node := range []struct{ field, tag, typ string }{
+		{"StartNode", "startNode", rs.start},
+		{"EndNode", "endNode", rs.end},
+	} {
+		if _, err := fields.add(node.field); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(buf, "\t%s *%s `neo4j:%q`\n", node.field, node.typ, node.tag)
+	}
+
+	return nil
+}
+
+// modelType returns the Go type name of a model, or any for an unknown model.
+func (g *structGenerator) modelType(name string) string {
+	if typeName, ok := g.typeNames[name]; ok {
+		return typeName
+	}
+
+	return "any"
+}
+
+// goType returns the Go spelling of t, qualified by package name, recording
+// the imports it needs. A nil type is any.
+func (g *structGenerator) goType(t *Type) string {
+	if t == nil {
+		return "any"
+	}
+
+	switch t.Kind {
+	case TypeKindSlice:
+		return "[]" + g.goType(t.Elem)
+	case TypeKindArray:
+		return fmt.Sprintf("[%d]%s", t.ArrayLen, g.goType(t.Elem))
+	case TypeKindMap:
+		return "map[" + g.goType(t.Key) + "]" + g.goType(t.Elem)
+	case TypeKindPointer:
+		return "*" + g.goType(t.Elem)
+	case TypeKindNamed:
+		if t.Package == "" {
+			return t.Name
+		}
+
+		importPath, ok := knownImports[t.Package]
+		if !ok {
+			importPath = t.Package
+		}
+
+		g.imports[importPath] = true
+
+		return path.Base(importPath) + "." + t.Name
+	default:
+		return t.String()
+	}
+}
+
+// fieldNames tracks the Go field names used in one struct.
+type fieldNames struct {
+	model string
+	used  map[string]string
+}
+
+func newFieldNames(model string) *fieldNames {
+	return &fieldNames{model: model, used: map[string]string{"Node": "", "Relationship": ""}}
+}
+
+// add returns the Go field name for name, failing if it is already taken.
+func (f *fieldNames) add(name string) (string, error) {
+	goField := goName(name)
+	if goField == "" {
+		return "", fmt.Errorf("model %s, field %q: cannot derive a Go field name", f.model, name)
+	}
+
+	if other, ok := f.used[goField]; ok {
+		if other == "" {
+			return "", fmt.Errorf("model %s, field %q: Go field name %s is reserved", f.model, name, goField)
+		}
+
+		return "", fmt.Errorf("model %s: fields %q and %q both generate Go field %s", f.model, other, name, goField)
+	}
+
+	f.used[goField] = name
+
+	return goField, nil
+}
+
+// goName converts a schema name such as "user_id", "ACTED_IN", or "email" to
+// an exported Go identifier ("UserID", "ActedIn", "Email"). Returns "" if
+// name has no letters or digits.
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+
+	for _, part := range parts {
+		upper := strings.ToUpper(part)
+
+		switch {
+		case goAcronyms[upper]:
+			sb.WriteString(upper)
+		case part == upper:
+			// Upper-case words such as ACTED become Acted
+			runes := []rune(strings.ToLower(part))
+			runes[0] = unicode.ToUpper(runes[0])
+			sb.WriteString(string(runes))
+		default:
+			runes := []rune(part)
+			runes[0] = unicode.ToUpper(runes[0])
+			sb.WriteString(string(runes))
+		}
+	}
+
+	ident := sb.String()
+	if ident != "" && !unicode.IsLetter([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+
+	return ident
+}
+
+// sortedFields returns model's fields sorted by name.
+func sortedFields(model *Model) []*Field {
+	if model == nil {
+		return nil
+	}
+
+	fields := slices.Clone(model.Fields)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	return fields
+}
+
+// sortedRelationships returns model's relationships sorted by name.
+func sortedRelationships(model *Model) []*Relationship {
+	if model == nil {
+		return nil
+	}
+
+	rels := slices.Clone(model.Relationships)
+	sort.Slice(rels, func(i, j int) bool { return rels[i].Name < rels[j].Name })
+
+	return rels
+}
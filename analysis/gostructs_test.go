@@ -0,0 +1,251 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateGoStructs(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{Models: map[string]*Model{
+		"Person": {
+			Name: "Person",
+			Fields: []*Field{
+				{Name: "user_id", Type: TypeString, Required: true},
+				{Name: "name", Type: TypeString, Required: true},
+				{Name: "born", Type: TypeDateTime},
+				{Name: "nickname", Type: PointerTo(TypeString)},
+			},
+			Relationships: []*Relationship{
+				{Name: "Favorite", RelType: "FAVORITE", Target: "Movie", Direction: DirectionOutgoing},
+				{Name: "ActedIn", RelType: "ACTED_IN", Target: "Movie", Many: true, Direction: DirectionOutgoing},
+				{Name: "Friends", RelType: "FRIENDS_WITH", Target: "Person", Many: true, Direction: DirectionOutgoing},
+			},
+		},
+		"Movie": {
+			Name: "Movie",
+			Fields: []*Field{
+				{Name: "title", Type: TypeString},
+				{Name: "released", Type: TypeDate},
+				{Name: "tags", Type: SliceOf(TypeString)},
+			},
+		},
+		"ActedIn": {
+			Name:   "ActedIn",
+			Fields: []*Field{{Name: "roles", Type: SliceOf(TypeString)}},
+		},
+	}}
+
+	got, err := GenerateGoStructs(schema, "models")
+	require.NoError(t, err)
+
+	want := "// Code generated by scaf. DO NOT EDIT.\n" + `
+package models
+
+import (
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rlch/neogo"
+)
+
+type ActedIn struct {
+	neogo.Relationship ` + "`neo4j:\"ACTED_IN\"`" + `
+
+	Roles []string ` + "`neo4j:\"roles\" json:\"roles\"`" + `
+
+	StartNode *Person ` + "`neo4j:\"startNode\"`" + `
+	EndNode   *Movie  ` + "`neo4j:\"endNode\"`" + `
+}
+
+type Movie struct {
+	neogo.Node ` + "`neo4j:\"Movie\"`" + `
+
+	Released neo4j.Date ` + "`neo4j:\"released\" json:\"released\"`" + `
+	Tags     []string   ` + "`neo4j:\"tags\" json:\"tags\"`" + `
+	Title    string     ` + "`neo4j:\"title\" json:\"title\"`" + `
+}
+
+type Person struct {
+	neogo.Node ` + "`neo4j:\"Person\"`" + `
+
+	Born     time.Time ` + "`neo4j:\"born\" json:\"born\"`" + `
+	Name     string    ` + "`neo4j:\"name\" json:\"name\"`" + `
+	Nickname *string   ` + "`neo4j:\"nickname\" json:\"nickname\"`" + `
+	UserID   string    ` + "`neo4j:\"user_id\" json:\"user_id\"`" + `
+
+	ActedIn  neogo.Many[ActedIn]       ` + "`neo4j:\"->\"`" + `
+	Favorite neogo.One[Movie]          ` + "`neo4j:\"FAVORITE>\"`" + `
+	Friends  neogo.Many[PersonFriends] ` + "`neo4j:\"->\"`" + `
+}
+
+type PersonFriends struct {
+	neogo.Relationship ` + "`neo4j:\"FRIENDS_WITH\"`" + `
+
+	StartNode *Person ` + "`neo4j:\"startNode\"`" + `
+	EndNode   *Person ` + "`neo4j:\"endNode\"`" + `
+}
+`
+	assert.Equal(t, want, string(got))
+
+	// Output doesn't depend on map or slice order.
+	again, err := GenerateGoStructs(schema, "models")
+	require.NoError(t, err)
+	assert.Equal(t, string(got), string(again))
+}
+
+func TestGenerateGoStructs_IncomingRelationshipStruct(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{Models: map[string]*Model{
+		"Movie": {
+			Name: "Movie",
+			Relationships: []*Relationship{
+				{Name: "ActedIn", RelType: "ACTED_IN", Target: "Person", Many: true, Direction: DirectionIncoming},
+			},
+		},
+		"Person":  {Name: "Person"},
+		"ActedIn": {Name: "ActedIn"},
+	}}
+
+	got, err := GenerateGoStructs(schema, "models")
+	require.NoError(t, err)
+
+	assert.Contains(t, string(got), "StartNode *Person `neo4j:\"startNode\"`")
+	assert.Contains(t, string(got), "EndNode   *Movie  `neo4j:\"endNode\"`")
+	assert.Contains(t, string(got), "ActedIn neogo.Many[ActedIn] `neo4j:\"<-\"`")
+}
+
+func TestGenerateGoStructs_Cardinality(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{Models: map[string]*Model{
+		"Owner": {
+			Name: "Owner",
+			Relationships: []*Relationship{
+				{Name: "Owns", RelType: "OWNS", Target: "Pet", Direction: DirectionOutgoing, Cardinality: CardinalityOne},
+			},
+		},
+		"Pet": {Name: "Pet"},
+	}}
+
+	got, err := GenerateGoStructs(schema, "models")
+	require.NoError(t, err)
+
+	assert.Contains(t, string(got), "Owns neogo.One[Pet] `neo4j:\"OWNS>,cardinality:one\"`")
+}
+
+func TestGenerateGoStructs_Cycle(t *testing.T) {
+	t.Parallel()
+
+	// Person and Movie can't hold each other by value, so the second
+	// relationship (by model name) goes through a relationship struct.
+	schema := &TypeSchema{Models: map[string]*Model{
+		"Movie": {
+			Name: "Movie",
+			Relationships: []*Relationship{
+				{Name: "Cast", RelType: "ACTED_IN", Target: "Person", Many: true, Direction: DirectionIncoming},
+			},
+		},
+		"Person": {
+			Name: "Person",
+			Relationships: []*Relationship{
+				{Name: "Movies", RelType: "ACTED_IN", Target: "Movie", Many: true, Direction: DirectionOutgoing},
+			},
+		},
+	}}
+
+	got, err := GenerateGoStructs(schema, "models")
+	require.NoError(t, err)
+
+	assert.Contains(t, string(got), "Cast neogo.Many[Person] `neo4j:\"<ACTED_IN\"`")
+	assert.Contains(t, string(got), "Movies neogo.Many[PersonMovies] `neo4j:\"->\"`")
+	assert.Contains(t, string(got), "type PersonMovies struct {\n\tneogo.Relationship `neo4j:\"ACTED_IN\"`")
+}
+
+func TestGenerateGoStructs_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		schema  *TypeSchema
+		pkg     string
+		wantErr string
+	}{
+		{
+			name:    "invalid package",
+			schema:  NewTypeSchema(),
+			pkg:     "my-models",
+			wantErr: `invalid package name "my-models"`,
+		},
+		{
+			name: "field name collision",
+			schema: &TypeSchema{Models: map[string]*Model{
+				"User": {Name: "User", Fields: []*Field{
+					{Name: "user_id", Type: TypeString},
+					{Name: "userID", Type: TypeString},
+				}},
+			}},
+			pkg:     "models",
+			wantErr: "both generate Go field UserID",
+		},
+		{
+			name: "unknown target",
+			schema: &TypeSchema{Models: map[string]*Model{
+				"User": {Name: "User", Relationships: []*Relationship{
+					{Name: "Owns", RelType: "OWNS", Target: "Car"},
+				}},
+			}},
+			pkg:     "models",
+			wantErr: `unknown target model "Car"`,
+		},
+		{
+			name: "type name collision",
+			schema: &TypeSchema{Models: map[string]*Model{
+				"ACTED_IN": {Name: "ACTED_IN"},
+				"ActedIn":  {Name: "ActedIn"},
+			}},
+			pkg:     "models",
+			wantErr: "both generate type ActedIn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := GenerateGoStructs(tt.schema, tt.pkg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestGenerateGoStructs_Empty(t *testing.T) {
+	t.Parallel()
+
+	got, err := GenerateGoStructs(NewTypeSchema(), "models")
+	require.NoError(t, err)
+	assert.Equal(t, "// Code generated by scaf. DO NOT EDIT.\n\npackage models\n", string(got))
+}
+
+func TestGoName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"name":       "Name",
+		"user_id":    "UserID",
+		"createdAt":  "CreatedAt",
+		"ACTED_IN":   "ActedIn",
+		"api-url":    "APIURL",
+		"2fa_secret": "X2faSecret",
+		"___":        "",
+	}
+
+	for input, want := range tests {
+		assert.Equal(t, want, goName(input), "goName(%q)", input)
+	}
+}
@@ -0,0 +1,234 @@
+package analysis
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// ImportGraph is the import dependency graph of a set of files.
+type ImportGraph struct {
+	// Nodes are the file paths: the files the graph was built from, in order,
+	// then the files they import from outside the set.
+	Nodes []string
+
+	// Edges are the imports between nodes, as (importer, importee) pairs.
+	Edges [][2]string
+}
+
+// DependencyGraph returns the import graph of files. Imports are resolved the
+// same way as by AnalyzeBatch; files without a suite import nothing.
+func DependencyGraph(files []*AnalyzedFile) *ImportGraph {
+	g := &ImportGraph{}
+
+	// nodes maps the batchPath of each node to its name in the graph.
+	nodes := make(map[string]string, len(files))
+
+	for _, f := range files {
+		key := batchPath(f.Path)
+		if _, ok := nodes[key]; !ok {
+			nodes[key] = f.Path
+			g.Nodes = append(g.Nodes, f.Path)
+		}
+	}
+
+	seen := make(map[[2]string]bool)
+
+	for _, f := range files {
+		if f.Suite == nil {
+			continue
+		}
+
+		// A file listed under several spellings of its path is one node
+		importer := nodes[batchPath(f.Path)]
+
+		for _, imp := range f.Suite.Imports {
+			key := resolveBatchImport(f, imp)
+
+			target, ok := nodes[key]
+			if !ok {
+				target = key
+				nodes[key] = key
+				g.Nodes = append(g.Nodes, key)
+			}
+
+			edge := [2]string{importer, target}
+			if !seen[edge] {
+				seen[edge] = true
+				g.Edges = append(g.Edges, edge)
+			}
+		}
+	}
+
+	return g
+}
+
+// HasCycle reports whether any file imports itself, directly or indirectly.
+func (g *ImportGraph) HasCycle() bool {
+	return len(g.Cycles()) > 0
+}
+
+// Cycles returns each distinct import cycle in the graph, as the files along
+// it in import order starting from the least path, without repeating the
+// first. A file that imports itself is a cycle of one. Only the shortest cycle
+// through each import is found, so longer cycles over the same imports may be
+// left out.
+func (g *ImportGraph) Cycles() [][]string {
+	imports := g.imports()
+
+	var cycles [][]string
+
+	seen := make(map[string]bool)
+
+	for _, edge := range g.Edges {
+		back := shortestImportPath(imports, edge[1], edge[0])
+		if back == nil {
+			continue
+		}
+
+		cycle := append([]string{edge[0]}, back[:len(back)-1]...)
+
+		// Rotate to start at the least path so each cycle is found once
+		start := slices.Index(cycle, slices.Min(cycle))
+		cycle = slices.Concat(cycle[start:], cycle[:start])
+
+		key := strings.Join(cycle, "\x00")
+		if !seen[key] {
+			seen[key] = true
+			cycles = append(cycles, cycle)
+		}
+	}
+
+	return cycles
+}
+
+// Importers returns the files that import file, directly or through other
+// imports, in node order. file itself is included only when it is part of an
+// import cycle.
+func (g *ImportGraph) Importers(file string) []string {
+	importers := make(map[string][]string, len(g.Nodes))
+	for _, edge := range g.Edges {
+		importers[edge[1]] = append(importers[edge[1]], edge[0])
+	}
+
+	found := make(map[string]bool)
+	queue := []string{file}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		for _, importer := range importers[node] {
+			if !found[importer] {
+				found[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+
+	var result []string
+
+	for _, node := range g.Nodes {
+		if found[node] {
+			result = append(result, node)
+		}
+	}
+
+	return result
+}
+
+// path returns the shortest chain of imports from one file to another,
+// including both, or nil if to can't be reached.
+func (g *ImportGraph) path(from, to string) []string {
+	return shortestImportPath(g.imports(), from, to)
+}
+
+// imports maps each node to the nodes it imports.
+func (g *ImportGraph) imports() map[string][]string {
+	imports := make(map[string][]string, len(g.Nodes))
+	for _, edge := range g.Edges {
+		imports[edge[0]] = append(imports[edge[0]], edge[1])
+	}
+
+	return imports
+}
+
+// shortestImportPath finds the shortest chain of imports from start to target
+// by breadth-first search.
+func shortestImportPath(imports map[string][]string, start, target string) []string {
+	prev := map[string]string{}
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if node == target {
+			chain := []string{node}
+			for node != start {
+				node = prev[node]
+				chain = append(chain, node)
+			}
+
+			slices.Reverse(chain)
+
+			return chain
+		}
+
+		for _, next := range imports[node] {
+			if !visited[next] {
+				visited[next] = true
+				prev[next] = node
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return nil
+}
+
+// graphvizGraph is the graph layout of Graphviz's JSON output (dot -Tjson),
+// without the drawing attributes.
+type graphvizGraph struct {
+	Name     string           `json:"name"`
+	Directed bool             `json:"directed"`
+	Strict   bool             `json:"strict"`
+	Objects  []graphvizObject `json:"objects"`
+	Edges    []graphvizEdge   `json:"edges"`
+}
+
+type graphvizObject struct {
+	ID   int    `json:"_gvid"`
+	Name string `json:"name"`
+}
+
+type graphvizEdge struct {
+	ID   int `json:"_gvid"`
+	Tail int `json:"tail"`
+	Head int `json:"head"`
+}
+
+// ToJSON encodes the graph in Graphviz's JSON format, with a node object per
+// file and an edge from each importer to the file it imports.
+func (g *ImportGraph) ToJSON() ([]byte, error) {
+	out := graphvizGraph{
+		Name:     "imports",
+		Directed: true,
+		Strict:   true,
+		Objects:  make([]graphvizObject, len(g.Nodes)),
+		Edges:    make([]graphvizEdge, 0, len(g.Edges)),
+	}
+
+	ids := make(map[string]int, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node] = i
+		out.Objects[i] = graphvizObject{ID: i, Name: node}
+	}
+
+	for i, edge := range g.Edges {
+		out.Edges = append(out.Edges, graphvizEdge{ID: i, Tail: ids[edge[0]], Head: ids[edge[1]]})
+	}
+
+	return json.Marshal(out)
+}
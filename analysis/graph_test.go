@@ -0,0 +1,107 @@
+package analysis_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/rlch/scaf/analysis"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	t.Parallel()
+
+	parse := func(path, src string) *analysis.AnalyzedFile {
+		t.Helper()
+
+		f, err := analysis.AnalyzeFile(path, []byte(src), &analysis.AnalyzeOptions{Rules: []*analysis.Rule{}})
+		if err != nil {
+			t.Fatalf("AnalyzeFile() error: %v", err)
+		}
+
+		return f
+	}
+
+	graph := analysis.DependencyGraph([]*analysis.AnalyzedFile{
+		parse("/p/a.scaf", "import b \"./b\"\nimport c \"./c\"\n"),
+		parse("/p/b.scaf", "import a \"./a\"\n"),
+		parse("/p/c.scaf", "import c \"./c\"\nimport shared \"./shared\"\n"),
+	})
+
+	wantNodes := []string{"/p/a.scaf", "/p/b.scaf", "/p/c.scaf", "/p/shared.scaf"}
+	if diff := cmp.Diff(wantNodes, graph.Nodes); diff != "" {
+		t.Errorf("Nodes mismatch (-want +got):\n%s", diff)
+	}
+
+	wantEdges := [][2]string{
+		{"/p/a.scaf", "/p/b.scaf"},
+		{"/p/a.scaf", "/p/c.scaf"},
+		{"/p/b.scaf", "/p/a.scaf"},
+		{"/p/c.scaf", "/p/c.scaf"},
+		{"/p/c.scaf", "/p/shared.scaf"},
+	}
+	if diff := cmp.Diff(wantEdges, graph.Edges); diff != "" {
+		t.Errorf("Edges mismatch (-want +got):\n%s", diff)
+	}
+
+	if !graph.HasCycle() {
+		t.Error("HasCycle() = false, want true")
+	}
+
+	wantCycles := [][]string{{"/p/a.scaf", "/p/b.scaf"}, {"/p/c.scaf"}}
+	if diff := cmp.Diff(wantCycles, graph.Cycles()); diff != "" {
+		t.Errorf("Cycles() mismatch (-want +got):\n%s", diff)
+	}
+
+	data, err := graph.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error: %v", err)
+	}
+
+	var decoded struct {
+		Directed bool `json:"directed"`
+		Objects  []struct {
+			Name string `json:"name"`
+		} `json:"objects"`
+		Edges []struct {
+			Tail int `json:"tail"`
+			Head int `json:"head"`
+		} `json:"edges"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+
+	if !decoded.Directed || len(decoded.Objects) != 4 || len(decoded.Edges) != 5 {
+		t.Fatalf("ToJSON() = %s", data)
+	}
+
+	if e := decoded.Edges[4]; decoded.Objects[e.Tail].Name != "/p/c.scaf" || decoded.Objects[e.Head].Name != "/p/shared.scaf" {
+		t.Errorf("edge 4 = %+v, want c.scaf -> shared.scaf", e)
+	}
+
+	if diff := cmp.Diff([]string{"/p/a.scaf", "/p/b.scaf", "/p/c.scaf"}, graph.Importers("/p/shared.scaf")); diff != "" {
+		t.Errorf("Importers(shared) mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := graph.Importers("/p/missing.scaf"); got != nil {
+		t.Errorf("Importers(missing) = %v, want nil", got)
+	}
+
+	// Edges from a second spelling of a path start at the node it was
+	// listed under, so they still map to a node in the JSON.
+	dup := analysis.DependencyGraph([]*analysis.AnalyzedFile{
+		parse("/p/a.scaf", ""),
+		parse("/p/./a.scaf", "import b \"./b\"\n"),
+	})
+
+	if diff := cmp.Diff([][2]string{{"/p/a.scaf", "/p/b.scaf"}}, dup.Edges); diff != "" {
+		t.Errorf("duplicate path Edges mismatch (-want +got):\n%s", diff)
+	}
+
+	acyclic := analysis.DependencyGraph([]*analysis.AnalyzedFile{parse("/p/d.scaf", "import e \"./e\"\n")})
+	if acyclic.HasCycle() {
+		t.Errorf("HasCycle() = true for %+v", acyclic)
+	}
+}
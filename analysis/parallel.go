@@ -0,0 +1,256 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/rlch/scaf"
+)
+
+// AnalyzeParallel analyzes the files at paths with a pool of workers
+// goroutines, or runtime.NumCPU() of them if workers is less than one. Each
+// file is analyzed as by AnalyzeFile, so batch rules don't run; see
+// RunBatchRules.
+//
+// Files are scheduled in import order: a file is analyzed only after the files
+// it imports from the same set, starting with those that import nothing.
+// Files in an import cycle are started one at a time once nothing else can
+// run, so a cycle doesn't stall the pool.
+//
+// Results arrive in the order they finish. Files that can't be read, or an
+// invalid opts, are reported on the error channel. Both channels are closed
+// once every file is done.
+func AnalyzeParallel(files []string, opts *AnalyzeOptions, workers int) (<-chan *AnalyzedFile, <-chan error) {
+	results := make(chan *AnalyzedFile, len(files))
+	errs := make(chan error, len(files)+1)
+
+	if opts == nil {
+		opts = &AnalyzeOptions{}
+	}
+
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	go func() {
+		defer close(results)
+		defer close(errs)
+
+		if opts.Dialect != "" && scaf.GetAnalyzer(opts.Dialect) == nil {
+			errs <- fmt.Errorf("%w: %s", ErrUnknownDialect, opts.Dialect)
+			return
+		}
+
+		sources := make([][]byte, len(files))
+		readErrs := make([]error, len(files))
+		imports := make([][]string, len(files))
+
+		// Reading and parsing for imports is as parallel as the analysis
+		forEachParallel(len(files), workers, func(i int) {
+			data, err := os.ReadFile(files[i])
+			if err != nil {
+				readErrs[i] = fmt.Errorf("failed to read %s: %w", files[i], err)
+				return
+			}
+
+			sources[i] = data
+			imports[i] = fileImports(files[i], data, opts.Resolver)
+		})
+
+		s := newImportScheduler(files, imports)
+
+		for i, err := range readErrs {
+			if err != nil {
+				errs <- err
+				s.skip(i)
+			}
+		}
+
+		jobs := make(chan int)
+		done := make(chan int)
+
+		for range workers {
+			go func() {
+				for i := range jobs {
+					f, err := AnalyzeFile(files[i], sources[i], opts)
+					if err != nil {
+						errs <- err
+					} else {
+						results <- f
+					}
+
+					done <- i
+				}
+			}()
+		}
+
+		s.run(jobs, done)
+		close(jobs)
+	}()
+
+	return results, errs
+}
+
+// forEachParallel calls fn with each index below n, from up to workers
+// goroutines, and waits for them all.
+func forEachParallel(n, workers int, fn func(i int)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range min(workers, n) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := range n {
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+}
+
+// fileImports returns the batch paths of the files that the scaf source at
+// path imports, resolved as by AnalyzeBatch. A file that doesn't parse imports
+// what its partial AST does.
+func fileImports(path string, src []byte, resolver FileResolver) []string {
+	suite, _ := scaf.Parse(src)
+	if suite == nil {
+		return nil
+	}
+
+	f := &AnalyzedFile{Path: path}
+	if resolver != nil {
+		f.Resolver = resolver
+	}
+
+	paths := make([]string, 0, len(suite.Imports))
+	for _, imp := range suite.Imports {
+		if imp != nil {
+			paths = append(paths, resolveBatchImport(f, imp))
+		}
+	}
+
+	return paths
+}
+
+// importScheduler hands out files once the files they import are done.
+type importScheduler struct {
+	// pending counts the imports of each file that aren't done yet.
+	pending []int
+
+	// dependents lists the files that import each file.
+	dependents [][]int
+
+	// queued marks files that are ready, started, or done.
+	queued []bool
+
+	ready     []int
+	remaining int
+}
+
+// newImportScheduler builds a scheduler for files, where imports[i] holds the
+// batch paths file i imports. Imports from outside the set, and of a file by
+// itself, don't hold a file back.
+func newImportScheduler(files []string, imports [][]string) *importScheduler {
+	s := &importScheduler{
+		pending:    make([]int, len(files)),
+		dependents: make([][]int, len(files)),
+		queued:     make([]bool, len(files)),
+		remaining:  len(files),
+	}
+
+	index := make(map[string]int, len(files))
+	for i, path := range files {
+		if _, ok := index[batchPath(path)]; !ok {
+			index[batchPath(path)] = i
+		}
+	}
+
+	for i, paths := range imports {
+		seen := make(map[int]bool)
+
+		for _, path := range paths {
+			j, ok := index[path]
+			if !ok || j == i || seen[j] {
+				continue
+			}
+
+			seen[j] = true
+			s.pending[i]++
+			s.dependents[j] = append(s.dependents[j], i)
+		}
+	}
+
+	for i, n := range s.pending {
+		if n == 0 {
+			s.queued[i] = true
+			s.ready = append(s.ready, i)
+		}
+	}
+
+	return s
+}
+
+// skip marks file i done without running it, e.g. because it couldn't be read.
+func (s *importScheduler) skip(i int) {
+	s.queued[i] = true
+	s.ready = slices.DeleteFunc(s.ready, func(r int) bool { return r == i })
+	s.finish(i)
+}
+
+// finish marks file i done, readying the files waiting only on it.
+func (s *importScheduler) finish(i int) {
+	s.remaining--
+
+	for _, d := range s.dependents[i] {
+		s.pending[d]--
+		if s.pending[d] == 0 && !s.queued[d] {
+			s.queued[d] = true
+			s.ready = append(s.ready, d)
+		}
+	}
+}
+
+// run sends files to jobs as they become ready and records those reported on
+// done, until every file is done.
+func (s *importScheduler) run(jobs chan<- int, done <-chan int) {
+	running := 0
+
+	for s.remaining > 0 {
+		if len(s.ready) == 0 && running == 0 {
+			// Everything left waits on an import cycle; break it at the first file
+			i := slices.Index(s.queued, false)
+			s.queued[i] = true
+			s.ready = append(s.ready, i)
+		}
+
+		var send chan<- int
+
+		next := -1
+		if len(s.ready) > 0 {
+			send = jobs
+			next = s.ready[0]
+		}
+
+		select {
+		case send <- next:
+			s.ready = s.ready[1:]
+			running++
+		case i := <-done:
+			running--
+			s.finish(i)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package analysis_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+	_ "github.com/rlch/scaf/dialects/cypher"
+)
+
+func TestAnalyzeParallel(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	sources := map[string]string{
+		"a.scaf": "import b \"./b\"\nimport c \"./c\"\n",
+		"b.scaf": "import c \"./c\"\n",
+		"c.scaf": "fn Q(id) `MATCH (n {id: $id}) RETURN n.name AS name`\n\nQ {\n\ttest \"t\" {\n\t\t$id: 1\n\t}\n}\n",
+		"d.scaf": "import e \"./e\"\n",
+		"e.scaf": "import d \"./d\"\n",
+	}
+
+	var files []string
+	for _, name := range []string{"a.scaf", "b.scaf", "c.scaf", "d.scaf", "e.scaf", "missing.scaf"} {
+		path := filepath.Join(dir, name)
+		if src, ok := sources[name]; ok {
+			if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		files = append(files, path)
+	}
+
+	// Run the real rules and query analyzer, so -race covers what they share
+	opts := &analysis.AnalyzeOptions{Rules: analysis.DefaultRules(), Dialect: scaf.DialectCypher}
+	results, errs := analysis.AnalyzeParallel(files, opts, 4)
+
+	var order []string
+
+	var analyzedQueries bool
+
+	for f := range results {
+		order = append(order, filepath.Base(f.Path))
+
+		for _, d := range f.Diagnostics {
+			analyzedQueries = analyzedQueries || d.Code == "unused-return-field"
+		}
+	}
+
+	if !analyzedQueries {
+		t.Error("want an unused-return-field hint in c.scaf from the cypher analyzer")
+	}
+
+	var errCount int
+	for range errs {
+		errCount++
+	}
+
+	if errCount != 1 {
+		t.Errorf("got %d errors, want 1 for missing.scaf", errCount)
+	}
+
+	if len(order) != 5 {
+		t.Fatalf("analyzed %v, want 5 files", order)
+	}
+
+	// Each file comes after the files it imports
+	for _, edge := range [][2]string{{"c.scaf", "b.scaf"}, {"b.scaf", "a.scaf"}, {"c.scaf", "a.scaf"}} {
+		if slices.Index(order, edge[0]) > slices.Index(order, edge[1]) {
+			t.Errorf("order = %v, want %s before %s", order, edge[0], edge[1])
+		}
+	}
+
+	_, errs = analysis.AnalyzeParallel(files, &analysis.AnalyzeOptions{Dialect: "nope"}, 0)
+	if err := <-errs; !errors.Is(err, analysis.ErrUnknownDialect) {
+		t.Errorf("AnalyzeParallel() with an unknown dialect error = %v, want ErrUnknownDialect", err)
+	}
+}
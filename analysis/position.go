@@ -1,6 +1,8 @@
 package analysis
 
 import (
+	"sort"
+
 	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/rlch/scaf"
 )
@@ -20,14 +22,14 @@ func NodeAtPosition(f *AnalyzedFile, pos lexer.Position) scaf.Node {
 
 	// Check imports.
 	for _, imp := range f.Suite.Imports {
-		if ContainsPosition(imp.Span(), pos) {
+		if imp.Span().Contains(pos) {
 			best = imp
 		}
 	}
 
 	// Check queries.
 	for _, q := range f.Suite.Functions {
-		if ContainsPosition(q.Span(), pos) {
+		if q.Span().Contains(pos) {
 			// Check for more specific nodes inside query (parameters)
 			if child := nodeInQuery(q, pos); child != nil {
 				best = child
@@ -38,9 +40,9 @@ func NodeAtPosition(f *AnalyzedFile, pos lexer.Position) scaf.Node {
 	}
 
 	// Check global setup clause (Suite.Setup).
-	if f.Suite.Setup != nil && ContainsPosition(f.Suite.Setup.Span(), pos) {
+	if f.Suite.Setup != nil && f.Suite.Setup.Span().Contains(pos) {
 		// Check for call inside
-		if f.Suite.Setup.Call != nil && ContainsPosition(f.Suite.Setup.Call.Span(), pos) {
+		if f.Suite.Setup.Call != nil && f.Suite.Setup.Call.Span().Contains(pos) {
 			best = f.Suite.Setup.Call
 		} else if child := nodeInSetupBlock(f.Suite.Setup, pos); child != nil {
 			best = child
@@ -49,9 +51,14 @@ func NodeAtPosition(f *AnalyzedFile, pos lexer.Position) scaf.Node {
 		}
 	}
 
+	// Check global teardown clause (Suite.Teardown).
+	if child := nodeInTeardown(f.Suite.Teardown, pos); child != nil {
+		best = child
+	}
+
 	// Check scopes.
 	for _, scope := range f.Suite.Scopes {
-		if ContainsPosition(scope.Span(), pos) {
+		if scope.Span().Contains(pos) {
 			// Check if we're in a more specific child.
 			if child := nodeInScope(scope, pos); child != nil {
 				best = child
@@ -70,7 +77,7 @@ func NodeAtPosition(f *AnalyzedFile, pos lexer.Position) scaf.Node {
 func nodeInQuery(q *scaf.Query, pos lexer.Position) scaf.Node {
 	// Check parameters
 	for _, p := range q.Params {
-		if p != nil && ContainsPosition(p.Span(), pos) {
+		if p != nil && p.Span().Contains(pos) {
 			return p
 		}
 	}
@@ -80,9 +87,9 @@ func nodeInQuery(q *scaf.Query, pos lexer.Position) scaf.Node {
 //nolint:ireturn // Returning interface is intentional for AST node polymorphism.
 func nodeInScope(scope *scaf.QueryScope, pos lexer.Position) scaf.Node {
 	// Check setup clause first (more specific)
-	if scope.Setup != nil && ContainsPosition(scope.Setup.Span(), pos) {
+	if scope.Setup != nil && scope.Setup.Span().Contains(pos) {
 		// Check for call inside
-		if scope.Setup.Call != nil && ContainsPosition(scope.Setup.Call.Span(), pos) {
+		if scope.Setup.Call != nil && scope.Setup.Call.Span().Contains(pos) {
 			return scope.Setup.Call
 		}
 		// Check for block items
@@ -92,6 +99,10 @@ func nodeInScope(scope *scaf.QueryScope, pos lexer.Position) scaf.Node {
 		return scope.Setup
 	}
 
+	if child := nodeInTeardown(scope.Teardown, pos); child != nil {
+		return child
+	}
+
 	// Then check items
 	if child := nodeInItems(scope.Items, pos); child != nil {
 		return child
@@ -105,9 +116,9 @@ func nodeInScope(scope *scaf.QueryScope, pos lexer.Position) scaf.Node {
 //nolint:ireturn // Returning interface is intentional for AST node polymorphism.
 func nodeInSetupBlock(setup *scaf.SetupClause, pos lexer.Position) scaf.Node {
 	for _, item := range setup.Block {
-		if ContainsPosition(item.Span(), pos) {
+		if item.Span().Contains(pos) {
 			// Check for call inside the item
-			if item.Call != nil && ContainsPosition(item.Call.Span(), pos) {
+			if item.Call != nil && item.Call.Span().Contains(pos) {
 				return item.Call
 			}
 			return item
@@ -116,10 +127,31 @@ func nodeInSetupBlock(setup *scaf.SetupClause, pos lexer.Position) scaf.Node {
 	return nil
 }
 
+// nodeInTeardown returns the most specific node of teardown containing pos:
+// a call, a block item, or the clause itself. Returns nil if teardown is nil
+// or doesn't contain pos.
+//
+//nolint:ireturn // Returning interface is intentional for AST node polymorphism.
+func nodeInTeardown(teardown *scaf.TeardownClause, pos lexer.Position) scaf.Node {
+	if teardown == nil || !teardown.Span().Contains(pos) {
+		return nil
+	}
+
+	if teardown.Call != nil && teardown.Call.Span().Contains(pos) {
+		return teardown.Call
+	}
+
+	if child := nodeInSetupBlock((*scaf.SetupClause)(teardown), pos); child != nil {
+		return child
+	}
+
+	return teardown
+}
+
 //nolint:ireturn // Returning interface is intentional for AST node polymorphism.
 func nodeInItems(items []*scaf.TestOrGroup, pos lexer.Position) scaf.Node {
 	for _, item := range items {
-		if item.Test != nil && ContainsPosition(item.Test.Span(), pos) {
+		if item.Test != nil && item.Test.Span().Contains(pos) {
 			// Check for more specific nodes inside test
 			if child := nodeInTest(item.Test, pos); child != nil {
 				return child
@@ -127,10 +159,10 @@ func nodeInItems(items []*scaf.TestOrGroup, pos lexer.Position) scaf.Node {
 			return item.Test
 		}
 
-		if item.Group != nil && ContainsPosition(item.Group.Span(), pos) {
+		if item.Group != nil && item.Group.Span().Contains(pos) {
 			// Check setup in group
-			if item.Group.Setup != nil && ContainsPosition(item.Group.Setup.Span(), pos) {
-				if item.Group.Setup.Call != nil && ContainsPosition(item.Group.Setup.Call.Span(), pos) {
+			if item.Group.Setup != nil && item.Group.Setup.Span().Contains(pos) {
+				if item.Group.Setup.Call != nil && item.Group.Setup.Call.Span().Contains(pos) {
 					return item.Group.Setup.Call
 				}
 				if child := nodeInSetupBlock(item.Group.Setup, pos); child != nil {
@@ -139,6 +171,10 @@ func nodeInItems(items []*scaf.TestOrGroup, pos lexer.Position) scaf.Node {
 				return item.Group.Setup
 			}
 
+			if child := nodeInTeardown(item.Group.Teardown, pos); child != nil {
+				return child
+			}
+
 			// Check children first.
 			if child := nodeInItems(item.Group.Items, pos); child != nil {
 				return child
@@ -154,8 +190,8 @@ func nodeInItems(items []*scaf.TestOrGroup, pos lexer.Position) scaf.Node {
 //nolint:ireturn // Returning interface is intentional for AST node polymorphism.
 func nodeInTest(test *scaf.Test, pos lexer.Position) scaf.Node {
 	// Check setup
-	if test.Setup != nil && ContainsPosition(test.Setup.Span(), pos) {
-		if test.Setup.Call != nil && ContainsPosition(test.Setup.Call.Span(), pos) {
+	if test.Setup != nil && test.Setup.Span().Contains(pos) {
+		if test.Setup.Call != nil && test.Setup.Call.Span().Contains(pos) {
 			return test.Setup.Call
 		}
 		if child := nodeInSetupBlock(test.Setup, pos); child != nil {
@@ -164,18 +200,22 @@ func nodeInTest(test *scaf.Test, pos lexer.Position) scaf.Node {
 		return test.Setup
 	}
 
+	if child := nodeInTeardown(test.Teardown, pos); child != nil {
+		return child
+	}
+
 	// Check statements
 	for _, stmt := range test.Statements {
-		if ContainsPosition(stmt.Span(), pos) {
+		if stmt.Span().Contains(pos) {
 			return stmt
 		}
 	}
 
 	// Check asserts
 	for _, assert := range test.Asserts {
-		if ContainsPosition(assert.Span(), pos) {
+		if assert.Span().Contains(pos) {
 			// Check if we're on the AssertQuery (more specific)
-			if assert.Query != nil && ContainsPosition(assert.Query.Span(), pos) {
+			if assert.Query != nil && assert.Query.Span().Contains(pos) {
 				return assert.Query
 			}
 			return assert
@@ -186,26 +226,10 @@ func nodeInTest(test *scaf.Test, pos lexer.Position) scaf.Node {
 }
 
 // ContainsPosition checks if a span contains a position.
+//
+// Deprecated: Use scaf.Span.Contains instead.
 func ContainsPosition(span scaf.Span, pos lexer.Position) bool {
-	// Check if pos is after start.
-	if pos.Line < span.Start.Line {
-		return false
-	}
-
-	if pos.Line == span.Start.Line && pos.Column < span.Start.Column {
-		return false
-	}
-
-	// Check if pos is before end.
-	if pos.Line > span.End.Line {
-		return false
-	}
-
-	if pos.Line == span.End.Line && pos.Column > span.End.Column {
-		return false
-	}
-
-	return true
+	return span.Contains(pos)
 }
 
 // PositionToLexer converts LSP 0-based line/character to participle's 1-based line/column.
@@ -243,21 +267,21 @@ func QueryAtPosition(f *AnalyzedFile, pos lexer.Position) *QuerySymbol {
 func SymbolAtPosition(f *AnalyzedFile, pos lexer.Position) *Symbol {
 	// Check queries.
 	for _, q := range f.Symbols.Queries {
-		if ContainsPosition(q.Span, pos) {
+		if q.Span.Contains(pos) {
 			return &q.Symbol
 		}
 	}
 
 	// Check imports.
 	for _, imp := range f.Symbols.Imports {
-		if ContainsPosition(imp.Span, pos) {
+		if imp.Span.Contains(pos) {
 			return &imp.Symbol
 		}
 	}
 
 	// Check tests.
 	for _, t := range f.Symbols.Tests {
-		if ContainsPosition(t.Span, pos) {
+		if t.Span.Contains(pos) {
 			return &t.Symbol
 		}
 	}
@@ -506,8 +530,14 @@ func tokenEndsBefore(tok *lexer.Token, pos lexer.Position) bool {
 
 // PrevTokenAtPosition finds the non-whitespace token immediately before a given position.
 // This is useful for completion to know what token precedes the cursor.
-// Whitespace and comment tokens are skipped.
+// Whitespace and comment tokens are skipped. The file's cached token stream is
+// binary searched; files without one, such as those built by hand, fall back
+// to scanning the AST's tokens.
 func PrevTokenAtPosition(f *AnalyzedFile, pos lexer.Position) *lexer.Token {
+	if len(f.Tokens) > 0 {
+		return prevStreamToken(f.Tokens, pos)
+	}
+
 	if f.Suite == nil {
 		return nil
 	}
@@ -519,7 +549,7 @@ func PrevTokenAtPosition(f *AnalyzedFile, pos lexer.Position) *lexer.Token {
 	// Skips whitespace and comment tokens
 	checkToken := func(tok *lexer.Token) {
 		// Skip whitespace and comments
-		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment {
+		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment || tok.Type == scaf.TokenAnnotation {
 			return
 		}
 		if !tokenEndsBefore(tok, pos) {
@@ -565,11 +595,28 @@ func PrevTokenAtPosition(f *AnalyzedFile, pos lexer.Position) *lexer.Token {
 	return best
 }
 
+// prevStreamToken returns the last token in tokens that ends before pos,
+// skipping comments and annotations. Tokens are in source order, so the
+// tokens ending before pos form a prefix.
+func prevStreamToken(tokens []lexer.Token, pos lexer.Position) *lexer.Token {
+	n := sort.Search(len(tokens), func(i int) bool {
+		return !tokenEndsBefore(&tokens[i], pos)
+	})
+
+	for i := n - 1; i >= 0; i-- {
+		if typ := tokens[i].Type; typ != scaf.TokenComment && typ != scaf.TokenAnnotation {
+			return &tokens[i]
+		}
+	}
+
+	return nil
+}
+
 // findPrevTokenInScope searches for previous token in a scope.
 func findPrevTokenInScope(scope *scaf.QueryScope, pos lexer.Position, best **lexer.Token, bestEnd *lexer.Position) {
 	checkToken := func(tok *lexer.Token) {
 		// Skip whitespace and comments
-		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment {
+		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment || tok.Type == scaf.TokenAnnotation {
 			return
 		}
 		if !tokenEndsBefore(tok, pos) {
@@ -611,7 +658,7 @@ func findPrevTokenInTestOrGroup(item *scaf.TestOrGroup, pos lexer.Position, best
 
 	checkToken := func(tok *lexer.Token) {
 		// Skip whitespace and comments
-		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment {
+		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment || tok.Type == scaf.TokenAnnotation {
 			return
 		}
 		if !tokenEndsBefore(tok, pos) {
@@ -659,7 +706,7 @@ func findPrevTokenInTestOrGroup(item *scaf.TestOrGroup, pos lexer.Position, best
 func findPrevTokenInSetupClause(setup *scaf.SetupClause, pos lexer.Position, best **lexer.Token, bestEnd *lexer.Position) {
 	checkToken := func(tok *lexer.Token) {
 		// Skip whitespace and comments
-		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment {
+		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment || tok.Type == scaf.TokenAnnotation {
 			return
 		}
 		if !tokenEndsBefore(tok, pos) {
@@ -740,25 +787,25 @@ func GetTokenContext(f *AnalyzedFile, pos lexer.Position) *TokenContext {
 
 	// Determine context from node hierarchy
 	for _, scope := range f.Suite.Scopes {
-		if ContainsPosition(scope.Span(), pos) {
+		if scope.Span().Contains(pos) {
 			ctx.QueryScope = scope.FunctionName
 
 			// Check if in setup
-			if scope.Setup != nil && ContainsPosition(scope.Setup.Span(), pos) {
+			if scope.Setup != nil && scope.Setup.Span().Contains(pos) {
 				ctx.InSetup = true
 			}
 
 			// Check items
 			for _, item := range scope.Items {
-				if item.Test != nil && ContainsPosition(item.Test.Span(), pos) {
+				if item.Test != nil && item.Test.Span().Contains(pos) {
 					ctx.InTest = true
 					// Check setup in test
-					if item.Test.Setup != nil && ContainsPosition(item.Test.Setup.Span(), pos) {
+					if item.Test.Setup != nil && item.Test.Setup.Span().Contains(pos) {
 						ctx.InSetup = true
 					}
 					// Check asserts
 					for _, assert := range item.Test.Asserts {
-						if ContainsPosition(assert.Span(), pos) {
+						if assert.Span().Contains(pos) {
 							ctx.InAssert = true
 						}
 					}
@@ -776,24 +823,24 @@ func GetTokenContext(f *AnalyzedFile, pos lexer.Position) *TokenContext {
 
 // checkInGroup recursively checks if position is in a group.
 func checkInGroup(group *scaf.Group, pos lexer.Position, ctx *TokenContext) bool {
-	if !ContainsPosition(group.Span(), pos) {
+	if !group.Span().Contains(pos) {
 		return false
 	}
 
 	ctx.InGroup = true
 
-	if group.Setup != nil && ContainsPosition(group.Setup.Span(), pos) {
+	if group.Setup != nil && group.Setup.Span().Contains(pos) {
 		ctx.InSetup = true
 	}
 
 	for _, item := range group.Items {
-		if item.Test != nil && ContainsPosition(item.Test.Span(), pos) {
+		if item.Test != nil && item.Test.Span().Contains(pos) {
 			ctx.InTest = true
-			if item.Test.Setup != nil && ContainsPosition(item.Test.Setup.Span(), pos) {
+			if item.Test.Setup != nil && item.Test.Setup.Span().Contains(pos) {
 				ctx.InSetup = true
 			}
 			for _, assert := range item.Test.Asserts {
-				if ContainsPosition(assert.Span(), pos) {
+				if assert.Span().Contains(pos) {
 					ctx.InAssert = true
 				}
 			}
@@ -3,7 +3,6 @@ package analysis
 import (
 	"errors"
 
-	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/rlch/scaf"
 )
@@ -143,7 +142,7 @@ func prevTokenFromSuite(suite *scaf.Suite, pos lexer.Position) *lexer.Token {
 	// Helper to update best if this token is closer to pos but still before it
 	checkToken := func(tok *lexer.Token) {
 		// Skip whitespace and comments
-		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment {
+		if tok.Type == scaf.TokenWhitespace || tok.Type == scaf.TokenComment || tok.Type == scaf.TokenAnnotation {
 			return
 		}
 		endCol := tok.Pos.Column + len(tok.Value)
@@ -201,20 +200,10 @@ func prevTokenFromSuite(suite *scaf.Suite, pos lexer.Position) *lexer.Token {
 
 // getErrorPosition extracts the position from a parse error.
 func getErrorPosition(err error) lexer.Position {
-	// Check for RecoveryError first
-	var recoveryErr *participle.RecoveryError
-	if errors.As(err, &recoveryErr) && len(recoveryErr.Errors) > 0 {
-		// Get position from first error
-		var perr participle.Error
-		if errors.As(recoveryErr.Errors[0], &perr) {
-			return perr.Position()
-		}
-	}
-
-	// Check for single participle error
-	var perr participle.Error
-	if errors.As(err, &perr) {
-		return perr.Position()
+	// A RecoveryError unwraps to its first error
+	var parseErr *scaf.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Pos
 	}
 
 	return lexer.Position{}
@@ -289,7 +278,7 @@ func analyzeTokenContext(ctx *RecoveryCompletionContext, pos lexer.Position, sui
 
 	// Determine context from surrounding structure
 	for _, scope := range suite.Scopes {
-		if ContainsPosition(scope.Span(), pos) {
+		if scope.Span().Contains(pos) {
 			ctx.QueryScope = scope.FunctionName
 
 			// Check if we're in setup context (after "setup" keyword)
@@ -373,14 +362,14 @@ func findRecoveredInScope(scope *scaf.QueryScope, pos lexer.Position, ctx *Recov
 	}
 
 	// Track query scope context
-	if ContainsPosition(scope.Span(), pos) {
+	if scope.Span().Contains(pos) {
 		ctx.QueryScope = scope.FunctionName
 	}
 
 	// Check setup in scope
 	if scope.Setup != nil {
 		findRecoveredInSetup(scope.Setup, pos, ctx)
-		if ContainsPosition(scope.Setup.Span(), pos) {
+		if scope.Setup.Span().Contains(pos) {
 			ctx.InSetup = true
 		}
 	}
@@ -419,7 +408,7 @@ func findRecoveredInTest(test *scaf.Test, pos lexer.Position, ctx *RecoveryCompl
 	}
 
 	// Track test context
-	if ContainsPosition(test.Span(), pos) {
+	if test.Span().Contains(pos) {
 		ctx.InTest = true
 	}
 
@@ -433,7 +422,7 @@ func findRecoveredInTest(test *scaf.Test, pos lexer.Position, ctx *RecoveryCompl
 	// Check setup
 	if test.Setup != nil {
 		findRecoveredInSetup(test.Setup, pos, ctx)
-		if ContainsPosition(test.Setup.Span(), pos) {
+		if test.Setup.Span().Contains(pos) {
 			ctx.InSetup = true
 		}
 	}
@@ -467,7 +456,7 @@ func findRecoveredInGroup(group *scaf.Group, pos lexer.Position, ctx *RecoveryCo
 	// Check setup
 	if group.Setup != nil {
 		findRecoveredInSetup(group.Setup, pos, ctx)
-		if ContainsPosition(group.Setup.Span(), pos) {
+		if group.Setup.Span().Contains(pos) {
 			ctx.InSetup = true
 		}
 	}
@@ -524,7 +513,7 @@ func findRecoveredInAssert(assert *scaf.Assert, pos lexer.Position, ctx *Recover
 	}
 
 	// Track assert context
-	if ContainsPosition(assert.Span(), pos) {
+	if assert.Span().Contains(pos) {
 		ctx.InAssert = true
 	}
 
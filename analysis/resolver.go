@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileResolver is a CrossFileResolver whose cached analyses can be evicted,
+// e.g. when a watched file is saved.
+type FileResolver interface {
+	CrossFileResolver
+
+	// Invalidate drops the cached analysis of path so it is reloaded on next use.
+	Invalidate(path string)
+}
+
+// fileSystemResolver resolves imports against the local file system and
+// caches the analysis of each imported file.
+type fileSystemResolver struct {
+	rootDir string
+
+	mu       sync.Mutex
+	analyzed map[string]*AnalyzedFile
+}
+
+// NewFileSystemResolver returns a FileResolver that loads imported .scaf files
+// from disk. Relative paths are resolved against rootDir. Imported files are
+// analyzed without a resolver of their own, so imports are followed one level deep.
+func NewFileSystemResolver(rootDir string) FileResolver { //nolint:ireturn // Callers depend on the interface.
+	return &fileSystemResolver{
+		rootDir:  rootDir,
+		analyzed: make(map[string]*AnalyzedFile),
+	}
+}
+
+// ResolveImportPath resolves importPath relative to the directory of from.
+// A missing extension is filled in with .scaf, or with a dialect-specific
+// extension such as .cypher.scaf when exactly one such file exists.
+func (r *fileSystemResolver) ResolveImportPath(from, importPath string) string {
+	resolved := importPath
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(r.abs(from)), importPath)
+	}
+
+	resolved = filepath.Clean(resolved)
+
+	if strings.HasSuffix(resolved, ".scaf") {
+		return resolved
+	}
+
+	if _, err := os.Stat(resolved); err == nil {
+		return resolved
+	}
+
+	withScaf := resolved + ".scaf"
+	if _, err := os.Stat(withScaf); err == nil {
+		return withScaf
+	}
+
+	matches, err := filepath.Glob(resolved + "*.scaf")
+	if err == nil && len(matches) == 1 {
+		return matches[0]
+	}
+
+	return withScaf
+}
+
+// LoadAndAnalyze returns the cached analysis of path, reading and analyzing
+// the file on first use. Returns nil if the file cannot be read.
+func (r *fileSystemResolver) LoadAndAnalyze(path string) *AnalyzedFile {
+	path = r.abs(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.analyzed[path]; ok {
+		return f
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	f := NewAnalyzer(nil).Analyze(path, content)
+	r.analyzed[path] = f
+
+	return f
+}
+
+// Invalidate drops the cached analysis of path.
+func (r *fileSystemResolver) Invalidate(path string) {
+	path = r.abs(path)
+
+	r.mu.Lock()
+	delete(r.analyzed, path)
+	r.mu.Unlock()
+}
+
+// abs makes path absolute, treating relative paths as relative to rootDir.
+func (r *fileSystemResolver) abs(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.rootDir, path)
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(path)
+}
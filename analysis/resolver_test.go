@@ -0,0 +1,114 @@
+package analysis_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rlch/scaf/analysis"
+)
+
+func writeScaf(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileSystemResolver_ResolveImportPath(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeScaf(t, filepath.Join(root, "shared", "fixtures.scaf"), "")
+	writeScaf(t, filepath.Join(root, "shared", "users.cypher.scaf"), "")
+
+	r := analysis.NewFileSystemResolver(root)
+
+	tests := []struct {
+		name       string
+		from       string
+		importPath string
+		want       string
+	}{
+		{"adds .scaf", "main.scaf", "./shared/fixtures", filepath.Join(root, "shared", "fixtures.scaf")},
+		{"keeps .scaf", "main.scaf", "./shared/fixtures.scaf", filepath.Join(root, "shared", "fixtures.scaf")},
+		{"dialect extension", "main.scaf", "./shared/users", filepath.Join(root, "shared", "users.cypher.scaf")},
+		{"relative to importer", "sub/main.scaf", "../shared/fixtures", filepath.Join(root, "shared", "fixtures.scaf")},
+		{"missing file", "main.scaf", "./missing", filepath.Join(root, "missing.scaf")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := r.ResolveImportPath(tt.from, tt.importPath); got != tt.want {
+				t.Errorf("ResolveImportPath(%q, %q) = %q, want %q", tt.from, tt.importPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSystemResolver_LoadAndAnalyze(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	path := filepath.Join(root, "fixtures.scaf")
+	writeScaf(t, path, "fn CreateUser() `CREATE (u:User)`\n")
+
+	r := analysis.NewFileSystemResolver(root)
+
+	f := r.LoadAndAnalyze("fixtures.scaf")
+	if f == nil {
+		t.Fatal("LoadAndAnalyze() = nil")
+	}
+
+	if _, ok := f.Symbols.Queries["CreateUser"]; !ok {
+		t.Errorf("expected CreateUser query, got %v", f.Symbols.Queries)
+	}
+
+	// Cached until invalidated.
+	writeScaf(t, path, "fn DeleteUser() `MATCH (u:User) DELETE u`\n")
+
+	if got := r.LoadAndAnalyze(path); got != f {
+		t.Error("LoadAndAnalyze() reloaded a cached file")
+	}
+
+	r.Invalidate(path)
+
+	f = r.LoadAndAnalyze(path)
+	if _, ok := f.Symbols.Queries["DeleteUser"]; !ok {
+		t.Errorf("expected DeleteUser after Invalidate, got %v", f.Symbols.Queries)
+	}
+
+	if got := r.LoadAndAnalyze(filepath.Join(root, "missing.scaf")); got != nil {
+		t.Errorf("LoadAndAnalyze(missing) = %v, want nil", got)
+	}
+}
+
+func TestFileSystemResolver_UndefinedSetupQuery(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeScaf(t, filepath.Join(root, "fixtures.scaf"), "fn CreateUser() `CREATE (u:User)`\n")
+
+	main := filepath.Join(root, "main.scaf")
+	content := "import fixtures \"./fixtures\"\n\n" +
+		"fn Q() `MATCH (n) RETURN n`\n\n" +
+		"Q {\n\ttest \"t\" {\n\t\tsetup fixtures.CreateAdmin()\n\t}\n}\n"
+
+	analyzer := analysis.NewAnalyzerWithResolver(nil, analysis.NewFileSystemResolver(root))
+	f := analyzer.Analyze(main, []byte(content))
+
+	for _, d := range f.Diagnostics {
+		if d.Code == "undefined-setup-query" {
+			return
+		}
+	}
+
+	t.Errorf("expected undefined-setup-query diagnostic, got %v", f.Diagnostics)
+}
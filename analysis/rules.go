@@ -1,11 +1,16 @@
 package analysis
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"maps"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/expr-lang/expr"
@@ -25,8 +30,29 @@ type Rule struct {
 	// Severity is the default severity for diagnostics from this rule.
 	Severity DiagnosticSeverity
 
+	// Priority orders rule execution: lower values run first, and rules with
+	// equal priority run in the order given. Rules that rely on state set by
+	// another rule (such as imports marked used) need a higher priority.
+	Priority int
+
 	// Run executes the rule and appends any diagnostics to the file.
 	Run func(f *AnalyzedFile)
+
+	// RunBatch executes a rule that needs every file at once, appending
+	// diagnostics to the files they concern. It is only run by AnalyzeBatch;
+	// rules with RunBatch and no Run are skipped when analyzing a single file.
+	RunBatch func(files []*AnalyzedFile)
+}
+
+// sortRules returns rules ordered by priority, keeping the given order for
+// rules with equal priority.
+func sortRules(rules []*Rule) []*Rule {
+	sorted := slices.Clone(rules)
+	slices.SortStableFunc(sorted, func(a, b *Rule) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+
+	return sorted
 }
 
 // DefaultRules returns all built-in semantic analysis rules.
@@ -38,9 +64,11 @@ func DefaultRules() []*Rule {
 		duplicateQueryRule,
 		duplicateImportRule,
 		undefinedAssertQueryRule,
+		paramInAssertQueryRule,    // Params passed to assert queries
 		undefinedSetupQueryRule,   // Cross-file validation
 		paramTypeMismatchRule,     // Type checking for function parameters
 		returnTypeMismatchRule,    // Type checking for return value assertions
+		unknownReturnFieldRule,    // Expected fields the query doesn't return
 		undeclaredQueryParamRule,  // Parameters used in query body but not declared
 		unknownParameterRule,      // Using a parameter that doesn't exist in the query
 		duplicateTestRule,         // Duplicate test names cause conflicts
@@ -53,10 +81,19 @@ func DefaultRules() []*Rule {
 		unusedImportRule,
 		unusedDeclaredParamRule, // Declared param not used in query body
 		emptyGroupRule,
+		teardownMissingRule,     // Setup data left in the database
+		teardownCreatesDataRule, // Teardowns should only delete
+		tableRowWidthRule,       // Table rows that don't match the header
 
 		// Hint-level checks.
 		emptyTestRule,
 		unusedQueryParamRule,
+		unusedReturnFieldRule, // Returned fields no test checks
+		setupOrderRule,        // Setup items that need data a later item creates
+
+		// Batch checks, run by AnalyzeBatch.
+		circularImportsRule,
+		neverTestedQueryRule,
 	}
 }
 
@@ -97,6 +134,7 @@ var undefinedImportRule = &Rule{
 	Name:     "undefined-import",
 	Doc:      "Reports setup calls that reference undefined imports.",
 	Severity: SeverityError,
+	Priority: 10, // Marks imports as used for unused-import
 	Run:      checkUndefinedImports,
 }
 
@@ -135,19 +173,23 @@ func checkUndefinedImports(f *AnalyzedFile) {
 		for _, item := range items {
 			if item.Test != nil {
 				checkSetup(item.Test.Setup)
+				checkSetup((*scaf.SetupClause)(item.Test.Teardown))
 			}
 
 			if item.Group != nil {
 				checkSetup(item.Group.Setup)
+				checkSetup((*scaf.SetupClause)(item.Group.Teardown))
 				checkItems(item.Group.Items)
 			}
 		}
 	}
 
 	checkSetup(f.Suite.Setup)
+	checkSetup((*scaf.SetupClause)(f.Suite.Teardown))
 
 	for _, scope := range f.Suite.Scopes {
 		checkSetup(scope.Setup)
+		checkSetup((*scaf.SetupClause)(scope.Teardown))
 		checkItems(scope.Items)
 	}
 }
@@ -206,6 +248,7 @@ func checkDuplicateQueries(f *AnalyzedFile) {
 				Message:  "duplicate query name: " + q.Name + " (first defined at line " + formatLine(firstSpan) + ")",
 				Code:     "duplicate-query",
 				Source:   "scaf",
+				Related:  firstDefinition(firstSpan, "query "+q.Name),
 			})
 		} else {
 			seen[q.Name] = q.Span()
@@ -213,6 +256,12 @@ func checkDuplicateQueries(f *AnalyzedFile) {
 	}
 }
 
+// firstDefinition returns related information pointing at the first
+// definition of a duplicated name, e.g. "query GetUser".
+func firstDefinition(span scaf.Span, what string) []DiagnosticRelatedInformation {
+	return []DiagnosticRelatedInformation{{Span: span, Message: "first definition of " + what}}
+}
+
 // ----------------------------------------------------------------------------
 // Rule: duplicate-import
 // ----------------------------------------------------------------------------
@@ -244,6 +293,7 @@ func checkDuplicateImports(f *AnalyzedFile) {
 				Message:  "duplicate import alias: " + alias + " (first defined at line " + formatLine(firstSpan) + ")",
 				Code:     "duplicate-import",
 				Source:   "scaf",
+				Related:  firstDefinition(firstSpan, "import "+alias),
 			})
 		} else {
 			seen[alias] = imp.Span()
@@ -259,11 +309,11 @@ var unusedImportRule = &Rule{
 	Name:     "unused-import",
 	Doc:      "Reports imports that are never referenced.",
 	Severity: SeverityWarning,
+	Priority: 20, // Runs after undefined-import has marked imports as used
 	Run:      checkUnusedImports,
 }
 
 func checkUnusedImports(f *AnalyzedFile) {
-	// Note: Must run after undefinedImportRule which marks imports as used.
 	for alias, imp := range f.Symbols.Imports {
 		if !imp.Used {
 			f.Diagnostics = append(f.Diagnostics, Diagnostic{
@@ -272,11 +322,42 @@ func checkUnusedImports(f *AnalyzedFile) {
 				Message:  "unused import: " + alias,
 				Code:     "unused-import",
 				Source:   "scaf",
+				AutoFix:  &TextEdit{Span: lineSpan(f, imp.Span)},
 			})
 		}
 	}
 }
 
+// lineSpan extends span to the whole lines it covers, including the final
+// newline, so deleting it leaves no blank line behind.
+func lineSpan(f *AnalyzedFile, span scaf.Span) scaf.Span {
+	span.Start.Offset -= span.Start.Column - 1
+	span.Start.Column = 1
+
+	if f.Suite == nil {
+		return span
+	}
+
+	for _, tok := range f.Suite.Tokens {
+		if tok.Pos.Offset < span.End.Offset {
+			continue
+		}
+
+		if i := strings.IndexByte(tok.Value, '\n'); i >= 0 {
+			span.End = lexer.Position{
+				Filename: span.End.Filename,
+				Offset:   tok.Pos.Offset + i + 1,
+				Line:     tok.Pos.Line + strings.Count(tok.Value[:i], "\n") + 1,
+				Column:   1,
+			}
+
+			break
+		}
+	}
+
+	return span
+}
+
 // ----------------------------------------------------------------------------
 // Rule: unknown-parameter
 // ----------------------------------------------------------------------------
@@ -430,8 +511,9 @@ func checkDuplicateTestNamesInItems(f *AnalyzedFile, items []*scaf.TestOrGroup)
 					Severity: SeverityError,
 					Message: "duplicate test name in scope: " + item.Test.Name +
 						" (first defined at line " + formatLine(firstSpan) + ")",
-					Code:   "duplicate-test",
-					Source: "scaf",
+					Code:    "duplicate-test",
+					Source:  "scaf",
+					Related: firstDefinition(firstSpan, "test "+item.Test.Name),
 				})
 			} else {
 				testNames[item.Test.Name] = item.Test.Span()
@@ -456,8 +538,9 @@ func checkDuplicateGroupNamesInItems(f *AnalyzedFile, items []*scaf.TestOrGroup)
 					Severity: SeverityError,
 					Message: "duplicate group name in scope: " + item.Group.Name +
 						" (first defined at line " + formatLine(firstSpan) + ")",
-					Code:   "duplicate-group",
-					Source: "scaf",
+					Code:    "duplicate-group",
+					Source:  "scaf",
+					Related: firstDefinition(firstSpan, "group "+item.Group.Name),
 				})
 			} else {
 				groupNames[item.Group.Name] = item.Group.Span()
@@ -518,6 +601,100 @@ func checkUndefinedAssertQueries(f *AnalyzedFile) {
 	}
 }
 
+// ----------------------------------------------------------------------------
+// Rule: param-in-assert-query
+// ----------------------------------------------------------------------------
+
+var paramInAssertQueryRule = &Rule{
+	Name:     "param-in-assert-query",
+	Doc:      "Reports parameters passed to assert queries that the query doesn't declare, or whose values don't match its type annotations.",
+	Severity: SeverityError,
+	Run:      checkParamsInAssertQueries,
+}
+
+func checkParamsInAssertQueries(f *AnalyzedFile) {
+	if f.Suite == nil {
+		return
+	}
+
+	var checkItems func([]*scaf.TestOrGroup)
+
+	checkItems = func(items []*scaf.TestOrGroup) {
+		for _, item := range items {
+			if item.Test != nil {
+				for _, assert := range item.Test.Asserts {
+					if assert.Query != nil && assert.Query.QueryName != nil {
+						checkAssertQueryParams(f, assert.Query)
+					}
+				}
+			}
+
+			if item.Group != nil {
+				checkItems(item.Group.Items)
+			}
+		}
+	}
+
+	for _, scope := range f.Suite.Scopes {
+		checkItems(scope.Items)
+	}
+}
+
+// checkAssertQueryParams checks the params of a named assert query against the
+// called query's parameters and type annotations.
+func checkAssertQueryParams(f *AnalyzedFile, aq *scaf.AssertQuery) {
+	queryName := *aq.QueryName
+
+	query, ok := f.Symbols.Queries[queryName]
+	if !ok {
+		return // Already reported as undefined-assert-query.
+	}
+
+	known := maps.Clone(query.DeclaredParams)
+	if known == nil {
+		known = make(map[string]bool)
+	}
+
+	for _, p := range query.Params {
+		known[p] = true
+	}
+
+	for _, param := range aq.Params {
+		if param == nil {
+			continue
+		}
+
+		paramName := strings.TrimPrefix(param.Name, "$")
+		if !known[paramName] {
+			f.Diagnostics = append(f.Diagnostics, Diagnostic{
+				Span:     param.Span(),
+				Severity: SeverityError,
+				Message:  "parameter $" + paramName + " not found in query " + queryName,
+				Code:     "param-in-assert-query",
+				Source:   "scaf",
+			})
+
+			continue
+		}
+
+		if param.Value == nil || param.Value.Literal == nil {
+			continue // Field references are resolved at runtime
+		}
+
+		if expectedType := query.TypedParams[paramName]; expectedType != nil {
+			if err := checkValueMatchesType(param.Value.Literal, expectedType); err != nil {
+				f.Diagnostics = append(f.Diagnostics, Diagnostic{
+					Span:     param.Span(),
+					Severity: SeverityError,
+					Message:  "type mismatch for parameter $" + paramName + " in " + queryName + ": " + err.Error(),
+					Code:     "param-in-assert-query",
+					Source:   "scaf",
+				})
+			}
+		}
+	}
+}
+
 // ----------------------------------------------------------------------------
 // Rule: missing-required-params
 // ----------------------------------------------------------------------------
@@ -621,10 +798,12 @@ func checkUnusedDeclaredParams(f *AnalyzedFile) {
 			if !usedParams[paramName] {
 				// Try to find the specific parameter span for precise highlighting
 				paramSpan := query.Span // Fallback to function span
+				var fix *TextEdit
 				if query.Node != nil {
 					for _, p := range query.Node.Params {
 						if p != nil && p.Name == paramName {
 							paramSpan = p.Span()
+							fix = removeParamFix(query.Node, p)
 							break
 						}
 					}
@@ -635,12 +814,52 @@ func checkUnusedDeclaredParams(f *AnalyzedFile) {
 					Message:  "declared parameter " + paramName + " is not used in query body",
 					Code:     "unused-declared-param",
 					Source:   "scaf",
+					AutoFix:  fix,
 				})
 			}
 		}
 	}
 }
 
+// removeParamFix rewrites fn's parameter list without param, keeping the
+// other parameters as written. A trailing comma is dropped with the last
+// parameter.
+func removeParamFix(fn *scaf.Function, param *scaf.FnParam) *TextEdit {
+	first, last := fn.Params[0], fn.Params[len(fn.Params)-1]
+	span := scaf.Span{Start: first.Pos, End: last.EndPos}
+
+	var kept []string
+	for _, p := range fn.Params {
+		if p != param {
+			kept = append(kept, paramSource(p))
+		}
+	}
+
+	if len(kept) == 0 && fn.TrailingComma {
+		for _, tok := range fn.Tokens {
+			if tok.Pos.Offset >= span.End.Offset && tok.Value == "," {
+				span.End = tok.Pos
+				span.End.Offset++
+				span.End.Column++
+
+				break
+			}
+		}
+	}
+
+	return &TextEdit{Span: span, NewText: strings.Join(kept, ", ")}
+}
+
+// paramSource returns a parameter's source text from its tokens.
+func paramSource(p *scaf.FnParam) string {
+	var b strings.Builder
+	for _, tok := range p.Tokens {
+		b.WriteString(tok.Value)
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
 // ----------------------------------------------------------------------------
 // Rule: empty-group
 // ----------------------------------------------------------------------------
@@ -682,6 +901,317 @@ func checkEmptyGroups(f *AnalyzedFile) {
 	}
 }
 
+// ----------------------------------------------------------------------------
+// Rule: teardown-missing
+// ----------------------------------------------------------------------------
+
+var teardownMissingRule = &Rule{
+	Name:     "teardown-missing",
+	Doc:      "Reports setups that create data with no teardown at the same or an enclosing level.",
+	Severity: SeverityWarning,
+	Run:      checkTeardownMissing,
+}
+
+func checkTeardownMissing(f *AnalyzedFile) {
+	if f.Suite == nil {
+		return
+	}
+
+	check := func(setup *scaf.SetupClause, hasTeardown bool) {
+		if setup == nil || hasTeardown || !setupCreatesData(f, setup) {
+			return
+		}
+
+		f.Diagnostics = append(f.Diagnostics, Diagnostic{
+			Span:     setup.Span(),
+			Severity: SeverityWarning,
+			Message:  "setup creates data but has no teardown; it will remain in the database for later tests",
+			Code:     "teardown-missing",
+			Source:   "scaf",
+		})
+	}
+
+	// A teardown at any enclosing level cleans up after nested setups.
+	var checkItems func(items []*scaf.TestOrGroup, hasTeardown bool)
+	checkItems = func(items []*scaf.TestOrGroup, hasTeardown bool) {
+		for _, item := range items {
+			switch {
+			case item.Test != nil:
+				check(item.Test.Setup, hasTeardown || item.Test.Teardown != nil)
+			case item.Group != nil:
+				groupTeardown := hasTeardown || item.Group.Teardown != nil
+				check(item.Group.Setup, groupTeardown)
+				checkItems(item.Group.Items, groupTeardown)
+			}
+		}
+	}
+
+	fileTeardown := f.Suite.Teardown != nil
+	check(f.Suite.Setup, fileTeardown)
+
+	for _, scope := range f.Suite.Scopes {
+		scopeTeardown := fileTeardown || scope.Teardown != nil
+		check(scope.Setup, scopeTeardown)
+		checkItems(scope.Items, scopeTeardown)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Rule: teardown-creates-data
+// ----------------------------------------------------------------------------
+
+var teardownCreatesDataRule = &Rule{
+	Name:     "teardown-creates-data",
+	Doc:      "Reports teardowns that create data instead of only deleting it.",
+	Severity: SeverityWarning,
+	Run:      checkTeardownCreatesData,
+}
+
+func checkTeardownCreatesData(f *AnalyzedFile) {
+	if f.Suite == nil {
+		return
+	}
+
+	check := func(teardown *scaf.TeardownClause) {
+		if teardown == nil || !setupCreatesData(f, (*scaf.SetupClause)(teardown)) {
+			return
+		}
+
+		f.Diagnostics = append(f.Diagnostics, Diagnostic{
+			Span:     teardown.Span(),
+			Severity: SeverityWarning,
+			Message:  "teardown creates data; it should only delete what setups created",
+			Code:     "teardown-creates-data",
+			Source:   "scaf",
+		})
+	}
+
+	var checkItems func(items []*scaf.TestOrGroup)
+	checkItems = func(items []*scaf.TestOrGroup) {
+		for _, item := range items {
+			if item.Test != nil {
+				check(item.Test.Teardown)
+			}
+
+			if item.Group != nil {
+				check(item.Group.Teardown)
+				checkItems(item.Group.Items)
+			}
+		}
+	}
+
+	check(f.Suite.Teardown)
+
+	for _, scope := range f.Suite.Scopes {
+		check(scope.Teardown)
+		checkItems(scope.Items)
+	}
+}
+
+// setupCreatesData reports whether any query a setup runs creates data. Inline
+// queries and queries called from imported modules are checked.
+func setupCreatesData(f *AnalyzedFile, setup *scaf.SetupClause) bool {
+	if setup.Inline != nil && queryCreatesData(f, *setup.Inline) {
+		return true
+	}
+
+	if body, ok := setupCallBody(f, setup.Call); ok && queryCreatesData(f, body) {
+		return true
+	}
+
+	for _, item := range setup.Block {
+		if item.Inline != nil && queryCreatesData(f, *item.Inline) {
+			return true
+		}
+
+		if body, ok := setupCallBody(f, item.Call); ok && queryCreatesData(f, body) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setupCallBody returns the body of the module query a setup call runs.
+func setupCallBody(f *AnalyzedFile, call *scaf.SetupCall) (string, bool) {
+	if call == nil || f.Resolver == nil || f.Symbols == nil {
+		return "", false
+	}
+
+	imp, ok := f.Symbols.Imports[call.Module]
+	if !ok {
+		return "", false
+	}
+
+	importedFile := f.Resolver.LoadAndAnalyze(f.Resolver.ResolveImportPath(f.Path, imp.Path))
+	if importedFile == nil || importedFile.Symbols == nil {
+		return "", false
+	}
+
+	query, ok := importedFile.Symbols.Queries[call.Query]
+	if !ok {
+		return "", false
+	}
+
+	return query.Body, true
+}
+
+// queryCreatesData checks for CREATE or MERGE in a query body. The substring
+// check is a fast filter; with a dialect analyzer, its parse decides.
+func queryCreatesData(f *AnalyzedFile, body string) bool {
+	upper := strings.ToUpper(body)
+	if !strings.Contains(upper, "CREATE") && !strings.Contains(upper, "MERGE") {
+		return false
+	}
+
+	if f.QueryAnalyzer == nil {
+		return true
+	}
+
+	result := f.GetBodyAnalysis(body)
+	if result == nil {
+		return true
+	}
+
+	return result.Metadata.CreatesData
+}
+
+// ----------------------------------------------------------------------------
+// Rule: setup-order
+// ----------------------------------------------------------------------------
+
+var setupOrderRule = &Rule{
+	Name:     "setup-order",
+	Doc:      "Reports setup block items that match labels a later item creates.",
+	Severity: SeverityHint,
+	Run:      checkSetupOrder,
+}
+
+// checkSetupOrder compares the labels each item of a setup block matches with
+// those the items after it create. It goes by label names alone, so it can't
+// tell whether the data an item needs already exists.
+func checkSetupOrder(f *AnalyzedFile) {
+	if f.Suite == nil || f.QueryAnalyzer == nil {
+		return
+	}
+
+	check := func(setup *scaf.SetupClause) {
+		if setup == nil || len(setup.Block) < 2 {
+			return
+		}
+
+		bodies := make([]*BodyAnalysis, len(setup.Block))
+
+		for i, item := range setup.Block {
+			body, _ := setupCallBody(f, item.Call)
+			if item.Inline != nil {
+				body = *item.Inline
+			}
+
+			bodies[i] = f.GetBodyAnalysis(body)
+		}
+
+		for i, item := range setup.Block {
+			if bodies[i] == nil {
+				continue
+			}
+
+			for _, label := range bodies[i].Metadata.MatchedLabels {
+				later := slices.IndexFunc(bodies[i+1:], func(b *BodyAnalysis) bool {
+					return b != nil && slices.Contains(b.Metadata.CreatedLabels, label)
+				})
+				if later < 0 {
+					continue
+				}
+
+				f.Diagnostics = append(f.Diagnostics, Diagnostic{
+					Span:     item.Span(),
+					Severity: SeverityHint,
+					Message: fmt.Sprintf("setup item %d matches :%s, which setup item %d creates; the items may need reordering",
+						i+1, label, i+later+2),
+					Code:   "setup-order",
+					Source: "scaf",
+				})
+
+				break
+			}
+		}
+	}
+
+	var checkItems func(items []*scaf.TestOrGroup)
+	checkItems = func(items []*scaf.TestOrGroup) {
+		for _, item := range items {
+			if item.Test != nil {
+				check(item.Test.Setup)
+			}
+
+			if item.Group != nil {
+				check(item.Group.Setup)
+				checkItems(item.Group.Items)
+			}
+		}
+	}
+
+	check(f.Suite.Setup)
+
+	for _, scope := range f.Suite.Scopes {
+		check(scope.Setup)
+		checkItems(scope.Items)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Rule: table-row-width
+// ----------------------------------------------------------------------------
+
+var tableRowWidthRule = &Rule{
+	Name:     "table-row-width",
+	Doc:      "Reports table rows with more or fewer cells than the header has columns.",
+	Severity: SeverityWarning,
+	Run:      checkTableRowWidths,
+}
+
+func checkTableRowWidths(f *AnalyzedFile) {
+	if f.Suite == nil {
+		return
+	}
+
+	var checkItems func([]*scaf.TestOrGroup)
+
+	checkItems = func(items []*scaf.TestOrGroup) {
+		for _, item := range items {
+			if item.Group != nil {
+				checkItems(item.Group.Items)
+			}
+
+			if item.Table == nil || item.Table.Header == nil {
+				continue
+			}
+
+			columns := len(item.Table.Header.Columns)
+
+			for i, row := range item.Table.Rows {
+				if len(row.Cells) == columns {
+					continue
+				}
+
+				f.Diagnostics = append(f.Diagnostics, Diagnostic{
+					Span:     row.Span(),
+					Severity: SeverityWarning,
+					Message: fmt.Sprintf("%s has %d cells but the header has %d columns",
+						item.Table.TestName(i), len(row.Cells), columns),
+					Code:   "table-row-width",
+					Source: "scaf",
+				})
+			}
+		}
+	}
+
+	for _, scope := range f.Suite.Scopes {
+		checkItems(scope.Items)
+	}
+}
+
 // ----------------------------------------------------------------------------
 // Rule: undefined-setup-query
 // ----------------------------------------------------------------------------
@@ -761,20 +1291,24 @@ func checkUndefinedSetupQueries(f *AnalyzedFile) {
 		for _, item := range items {
 			if item.Test != nil {
 				checkSetup(item.Test.Setup)
+				checkSetup((*scaf.SetupClause)(item.Test.Teardown))
 			}
 			if item.Group != nil {
 				checkSetup(item.Group.Setup)
+				checkSetup((*scaf.SetupClause)(item.Group.Teardown))
 				checkItems(item.Group.Items)
 			}
 		}
 	}
 
-	// Check global setup
+	// Check global setup and teardown
 	checkSetup(f.Suite.Setup)
+	checkSetup((*scaf.SetupClause)(f.Suite.Teardown))
 
 	// Check all scopes
 	for _, scope := range f.Suite.Scopes {
 		checkSetup(scope.Setup)
+		checkSetup((*scaf.SetupClause)(scope.Teardown))
 		checkItems(scope.Items)
 	}
 }
@@ -909,8 +1443,9 @@ func checkUndeclaredQueryParams(f *AnalyzedFile) {
 // ----------------------------------------------------------------------------
 
 var paramTypeMismatchRule = &Rule{
-	Name:     "param-type-mismatch",
-	Doc:      "Reports test parameters with values that don't match the function's type annotation or schema-inferred type.",
+	Name: "param-type-mismatch",
+	Doc: "Reports test parameters with values that don't match the function's type annotation or schema-inferred type, " +
+		"and type annotations that conflict with how the query uses the parameter.",
 	Severity: SeverityError,
 	Run:      checkParamTypeMismatch,
 }
@@ -920,6 +1455,8 @@ func checkParamTypeMismatch(f *AnalyzedFile) {
 		return
 	}
 
+	checkDeclaredParamUsage(f)
+
 	for _, scope := range f.Suite.Scopes {
 		query, ok := f.Symbols.Queries[scope.FunctionName]
 		if !ok {
@@ -943,6 +1480,54 @@ func checkParamTypeMismatch(f *AnalyzedFile) {
 	}
 }
 
+// checkDeclaredParamUsage reports type annotations that conflict with how the
+// query body uses the parameter, e.g. an int parameter used with STARTS WITH.
+// Requires a dialect analyzer implementing ParameterAwareAnalyzer.
+func checkDeclaredParamUsage(f *AnalyzedFile) {
+	paramAnalyzer, ok := f.QueryAnalyzer.(ParameterAwareAnalyzer)
+	if !ok {
+		return
+	}
+
+	for _, fn := range f.Suite.Functions {
+		query, ok := f.Symbols.Queries[fn.Name]
+		if !ok || len(query.TypedParams) == 0 {
+			continue
+		}
+
+		metadata, err := paramAnalyzer.AnalyzeQueryWithParameters(fn.Body, query.TypedParams, f.Schema)
+		if err != nil || metadata == nil {
+			continue
+		}
+
+		for _, warning := range metadata.WarningDetails {
+			if warning.Param == "" {
+				continue // Reported by checkQueryWarnings
+			}
+
+			f.Diagnostics = append(f.Diagnostics, Diagnostic{
+				Span:     paramDeclSpan(fn, warning.Param),
+				Severity: SeverityError,
+				Message:  warning.Message,
+				Code:     "param-type-mismatch",
+				Source:   "scaf",
+			})
+		}
+	}
+}
+
+// paramDeclSpan returns the span of the declaration of the named parameter,
+// falling back to the whole function.
+func paramDeclSpan(fn *scaf.Function, name string) scaf.Span {
+	for _, p := range fn.Params {
+		if p != nil && strings.TrimPrefix(p.Name, "$") == name {
+			return p.Span()
+		}
+	}
+
+	return fn.Span()
+}
+
 func checkItemParamTypes(f *AnalyzedFile, items []*scaf.TestOrGroup, typedParams map[string]*scaf.TypeExpr, inferredTypes map[string]*scaf.Type, queryName string) {
 	for _, item := range items {
 		if item.Test != nil {
@@ -1036,6 +1621,22 @@ func checkValueMatchesInferredType(v *scaf.Value, t *scaf.Type) error {
 			}
 		}
 		return nil
+	case scaf.TypeKindStruct:
+		if v.Map == nil {
+			return errTypeMismatch(t.String(), inferValueType(v))
+		}
+		// Check entries against the field with the same name
+		for _, entry := range v.Map.Entries {
+			for _, field := range t.Fields {
+				if field.Name != entry.Key {
+					continue
+				}
+				if err := checkValueMatchesInferredType(entry.Value, field.Type); err != nil {
+					return errMapValueMismatch(entry.Key, err)
+				}
+			}
+		}
+		return nil
 	case scaf.TypeKindNamed:
 		// Named types (custom types) - allow any value for now
 		return nil
@@ -1160,8 +1761,12 @@ func checkMapType(v *scaf.Value, mapType *scaf.MapTypeExpr) error {
 		return errTypeMismatch("map", inferValueType(v))
 	}
 
+	// Keys are always strings in the DSL, so a map type must use string keys
+	if !isStringMapKey(mapType.Key) {
+		return errInvalidMapKey(mapType.Key)
+	}
+
 	// Check each value matches the value type
-	// (Keys are always strings in the DSL, so we only check values)
 	for _, entry := range v.Map.Entries {
 		if err := checkValueMatchesType(entry.Value, mapType.Value); err != nil {
 			return errMapValueMismatch(entry.Key, err)
@@ -1260,26 +1865,110 @@ func errMapValueMismatch(key string, err error) error {
 	return &mapValueError{key: key, err: err}
 }
 
-// ----------------------------------------------------------------------------
-// Rule: invalid-expression
-// ----------------------------------------------------------------------------
+type mapKeyError struct {
+	key string
+}
+
+func (e *mapKeyError) Error() string {
+	return "map keys must be string, got " + e.key
+}
+
+func errInvalidMapKey(key *scaf.TypeExpr) error {
+	return &mapKeyError{key: typeExprToString(key)}
+}
+
+// ----------------------------------------------------------------------------
+// Rule: invalid-expression
+// ----------------------------------------------------------------------------
+
+var invalidExpressionRule = &Rule{
+	Name: "invalid-expression",
+	Doc: "Reports invalid expr-lang expressions in assertions and statement values, " +
+		"and warnings from the dialect analyzer about query bodies.",
+	Severity: SeverityError,
+	Run:      checkInvalidExpressions,
+}
+
+func checkInvalidExpressions(f *AnalyzedFile) {
+	if f.Suite == nil {
+		return
+	}
+
+	checkQueryWarnings(f)
+
+	// Check all scopes
+	for _, scope := range f.Suite.Scopes {
+		checkScopeExpressions(f, scope)
+	}
+}
+
+// checkQueryWarnings reports the QueryMetadata.Warnings of each function body
+// as warnings, e.g. a deprecated function or a LIMIT without ORDER BY, each
+// where the analyzer found it in the body. Parameter type conflicts are left
+// to param-type-mismatch.
+func checkQueryWarnings(f *AnalyzedFile) {
+	for _, fn := range f.Suite.Functions {
+		if fn == nil {
+			continue
+		}
+
+		result := f.GetBodyAnalysis(fn.Body)
+		if result == nil {
+			continue
+		}
+
+		for _, warning := range result.Metadata.WarningDetails {
+			if warning.Param != "" {
+				continue
+			}
+
+			code := warning.Code
+			if code == "" {
+				code = "query-warning"
+			}
+
+			f.Diagnostics = append(f.Diagnostics, Diagnostic{
+				Span:     queryRangeSpan(fn, warning.Range),
+				Severity: SeverityWarning,
+				Message:  warning.Message,
+				Code:     code,
+				Source:   "scaf",
+			})
+		}
+	}
+}
+
+// queryRangeSpan returns the span in the file of a range in fn's body,
+// falling back to the whole function when the range is unknown.
+func queryRangeSpan(fn *scaf.Function, r scaf.QueryDiagnosticRange) scaf.Span {
+	if r.StartPos.Line == 0 {
+		return fn.Span()
+	}
+
+	for _, tok := range fn.Tokens {
+		if tok.Type == scaf.TokenRawString {
+			return scaf.Span{Start: bodyPosition(tok.Pos, r.StartPos), End: bodyPosition(tok.Pos, r.EndPos)}
+		}
+	}
 
-var invalidExpressionRule = &Rule{
-	Name:     "invalid-expression",
-	Doc:      "Reports invalid expr-lang expressions in assertions and statement values.",
-	Severity: SeverityError,
-	Run:      checkInvalidExpressions,
+	return fn.Span()
 }
 
-func checkInvalidExpressions(f *AnalyzedFile) {
-	if f.Suite == nil {
-		return
+// bodyPosition returns the position in the file of pos in a query body whose
+// opening backtick is at open.
+func bodyPosition(open, pos lexer.Position) lexer.Position {
+	out := lexer.Position{
+		Filename: open.Filename,
+		Offset:   open.Offset + 1 + pos.Offset,
+		Line:     open.Line + pos.Line - 1,
+		Column:   pos.Column,
 	}
 
-	// Check all scopes
-	for _, scope := range f.Suite.Scopes {
-		checkScopeExpressions(f, scope)
+	if pos.Line == 1 {
+		out.Column = open.Column + pos.Column
 	}
+
+	return out
 }
 
 // checkScopeExpressions checks expressions in a query scope.
@@ -1400,57 +2089,23 @@ func boolOption() expr.Option {
 // accessing `p.name` in an assertion will be type-checked as string, so
 // `assert (p.name)` will error with "expected bool, but got string".
 func buildExprEnvFromQuery(f *AnalyzedFile, queryName string) map[string]any {
-	if f.QueryAnalyzer == nil {
-		return nil
-	}
-
 	query, ok := f.Symbols.Queries[queryName]
-	if !ok || query.Body == "" {
+	if !ok {
 		return nil
 	}
 
-	metadata := analyzeQueryWithSchemaIfAvailable(f, query.Body)
-	if metadata == nil {
-		return nil
-	}
-
-	return buildExprEnvFromMetadata(metadata)
+	return buildExprEnvFromInlineQuery(f, query.Body)
 }
 
 // buildExprEnvFromInlineQuery builds an expr environment from an inline query string.
 // Used for assert blocks with inline queries like: assert `MATCH (c:Comment) RETURN c` { (c.id > 0) }
 func buildExprEnvFromInlineQuery(f *AnalyzedFile, queryBody string) map[string]any {
-	if f.QueryAnalyzer == nil || queryBody == "" {
-		return nil
-	}
-
-	metadata := analyzeQueryWithSchemaIfAvailable(f, queryBody)
-	if metadata == nil {
+	result := f.GetBodyAnalysis(queryBody)
+	if result == nil {
 		return nil
 	}
 
-	return buildExprEnvFromMetadata(metadata)
-}
-
-// analyzeQueryWithSchemaIfAvailable analyzes a query using the schema if available.
-// Falls back to basic analysis without schema if schema-aware analysis is not supported.
-func analyzeQueryWithSchemaIfAvailable(f *AnalyzedFile, queryBody string) *scaf.QueryMetadata {
-	// Try schema-aware analysis first if schema is available
-	if f.Schema != nil {
-		if schemaAnalyzer, ok := f.QueryAnalyzer.(SchemaAwareAnalyzer); ok {
-			metadata, err := schemaAnalyzer.AnalyzeQueryWithSchema(queryBody, f.Schema)
-			if err == nil && metadata != nil {
-				return metadata
-			}
-		}
-	}
-
-	// Fall back to basic analysis
-	metadata, err := f.QueryAnalyzer.AnalyzeQuery(queryBody)
-	if err != nil {
-		return nil
-	}
-	return metadata
+	return buildExprEnvFromMetadata(result.Metadata)
 }
 
 // buildExprEnvFromMetadata builds an expr environment from query metadata.
@@ -1650,6 +2305,13 @@ func typeToExprValue(t *scaf.Type) any {
 		return []any{}
 	case scaf.TypeKindMap:
 		return map[string]any{}
+	case scaf.TypeKindStruct:
+		// Struct types (from map projections) expose their fields as map keys
+		fields := make(map[string]any, len(t.Fields))
+		for _, field := range t.Fields {
+			fields[field.Name] = typeToExprValue(field.Type)
+		}
+		return fields
 	case scaf.TypeKindPointer:
 		// For pointer types (like *User), return a map to allow property access
 		return map[string]any{}
@@ -1681,14 +2343,14 @@ func checkExpressionAtSpan(f *AnalyzedFile, parenExpr *scaf.ParenExpr, span scaf
 		return
 	}
 
-	// Build compile options with strict environment checking
-	opts := []expr.Option{expr.Env(env)}
+	// Compile with strict environment checking
+	var opts []expr.Option
 	if typeOpt != nil {
 		opts = append(opts, typeOpt)
 	}
 
 	// Try to compile the expression with expr-lang.
-	_, err := expr.Compile(exprStr, opts...)
+	_, err := parenExpr.Compile(env, opts...)
 	if err == nil {
 		return
 	}
@@ -1716,17 +2378,14 @@ func checkTypedExpressionAtSpan(f *AnalyzedFile, parenExpr *scaf.ParenExpr, span
 		return
 	}
 
-	// Build compile options with strict environment checking
-	opts := []expr.Option{expr.Env(env)}
-
-	// Map scaf types to expr type options
-	typeOpt := typeExprToExprOption(expectedType)
-	if typeOpt != nil {
+	// Compile with strict environment checking, mapping scaf types to expr type options
+	var opts []expr.Option
+	if typeOpt := typeExprToExprOption(expectedType); typeOpt != nil {
 		opts = append(opts, typeOpt)
 	}
 
 	// Try to compile the expression with expr-lang.
-	_, err := expr.Compile(exprStr, opts...)
+	_, err := parenExpr.Compile(env, opts...)
 	if err == nil {
 		return
 	}
@@ -1949,9 +2608,25 @@ func validateTypeExpr(f *AnalyzedFile, t *scaf.TypeExpr) {
 	case t.Map != nil:
 		validateTypeExpr(f, t.Map.Key)
 		validateTypeExpr(f, t.Map.Value)
+
+		if t.Map.Key != nil && !isStringMapKey(t.Map.Key) {
+			f.Diagnostics = append(f.Diagnostics, Diagnostic{
+				Span:     t.Map.Key.Span(),
+				Severity: SeverityError,
+				Message:  errInvalidMapKey(t.Map.Key).Error(),
+				Code:     "invalid-type-annotation",
+				Source:   "scaf",
+			})
+		}
 	}
 }
 
+// isStringMapKey reports whether key is a valid map key type. Map literals in
+// the DSL only have string keys.
+func isStringMapKey(key *scaf.TypeExpr) bool {
+	return key != nil && key.Simple != nil && *key.Simple == "string" && !key.Nullable
+}
+
 // isValidScafType returns true if typeName is a valid scaf DSL type.
 // Scaf uses a subset of Go primitive types.
 func isValidScafType(typeName string) bool {
@@ -2067,3 +2742,391 @@ func checkItemReturnTypes(f *AnalyzedFile, items []*scaf.TestOrGroup, returnType
 		}
 	}
 }
+
+// ----------------------------------------------------------------------------
+// Rule: unknown-return-field
+// ----------------------------------------------------------------------------
+
+var unknownReturnFieldRule = &Rule{
+	Name:     "unknown-return-field",
+	Doc:      "Reports test statements that expect fields the query doesn't return.",
+	Severity: SeverityError,
+	Run:      checkUnknownReturnFields,
+}
+
+// maxSuggestionDistance is the maximum edit distance for "did you mean" suggestions.
+const maxSuggestionDistance = 2
+
+func checkUnknownReturnFields(f *AnalyzedFile) {
+	if f.Suite == nil || f.QueryAnalyzer == nil {
+		return
+	}
+
+	for _, scope := range f.Suite.Scopes {
+		query, ok := f.Symbols.Queries[scope.FunctionName]
+		if !ok {
+			continue // Already reported as undefined-query.
+		}
+
+		// No returns means the analyzer couldn't parse the body, or the
+		// query doesn't RETURN anything; either way we can't check fields.
+		if len(query.QueryBodyReturns) == 0 {
+			continue
+		}
+
+		columns := make([]string, 0, len(query.QueryBodyReturns))
+		wildcard := false
+
+		for _, ret := range query.QueryBodyReturns {
+			if ret.IsWildcard && ret.Expression == "*" {
+				wildcard = true
+				break
+			}
+
+			// Result columns are keyed by alias, or by the expression itself
+			if ret.Alias != "" {
+				columns = append(columns, ret.Alias)
+			} else if ret.Expression != "" {
+				columns = append(columns, ret.Expression)
+			}
+		}
+
+		if wildcard {
+			continue // RETURN * - fields depend on the query's bindings.
+		}
+
+		checkItemReturnFields(f, scope.Items, columns, scope.FunctionName)
+	}
+}
+
+func checkItemReturnFields(f *AnalyzedFile, items []*scaf.TestOrGroup, columns []string, queryName string) {
+	for _, item := range items {
+		if item.Test != nil {
+			for _, stmt := range item.Test.Statements {
+				key := stmt.Key()
+				if key == "" || strings.HasPrefix(key, "$") {
+					continue
+				}
+
+				if returnFieldExists(key, columns) {
+					continue
+				}
+
+				msg := "field '" + key + "' not returned by query " + queryName
+				if suggestion := closestName(key, columns); suggestion != "" {
+					msg += " (did you mean '" + suggestion + "'?)"
+				}
+
+				f.Diagnostics = append(f.Diagnostics, Diagnostic{
+					Span:     stmt.Span(),
+					Severity: SeverityError,
+					Message:  msg,
+					Code:     "unknown-return-field",
+					Source:   "scaf",
+				})
+			}
+		}
+
+		if item.Group != nil {
+			checkItemReturnFields(f, item.Group.Items, columns, queryName)
+		}
+	}
+}
+
+// returnFieldExists reports whether a test statement key refers to a returned column.
+// Nodes, relationships and maps are flattened by the database layer, so when a
+// query returns "u" the test may also reference "u.name".
+func returnFieldExists(key string, columns []string) bool {
+	for _, col := range columns {
+		if key == col || strings.HasPrefix(key, col+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// closestName returns the candidate closest to name by Levenshtein distance,
+// or "" if none is within maxSuggestionDistance.
+func closestName(name string, candidates []string) string {
+	best := ""
+	bestDist := maxSuggestionDistance + 1
+
+	for _, c := range candidates {
+		if d := levenshtein(name, c); d < bestDist {
+			best = c
+			bestDist = d
+		}
+	}
+
+	return best
+}
+
+// levenshtein computes the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// ----------------------------------------------------------------------------
+// Rule: unused-return-field
+// ----------------------------------------------------------------------------
+
+var unusedReturnFieldRule = &Rule{
+	Name:     "unused-return-field",
+	Doc:      "Reports query return fields that no test in the scope checks.",
+	Severity: SeverityHint,
+	Run:      checkUnusedReturnFields,
+}
+
+func checkUnusedReturnFields(f *AnalyzedFile) {
+	if f.Suite == nil || f.QueryAnalyzer == nil {
+		return
+	}
+
+	for _, scope := range f.Suite.Scopes {
+		query, ok := f.Symbols.Queries[scope.FunctionName]
+		if !ok {
+			continue // Already reported as undefined-query.
+		}
+
+		// Collect all output keys checked across all tests in this scope.
+		var keys []string
+		collectCheckedFields(scope.Items, &keys)
+
+		for _, ret := range query.QueryBodyReturns {
+			if ret.IsWildcard {
+				continue // RETURN * or n.* - fields depend on the query's bindings.
+			}
+
+			column := ret.Alias
+			if column == "" {
+				column = ret.Expression
+			}
+
+			if column == "" || returnFieldChecked(column, keys) {
+				continue
+			}
+
+			f.Diagnostics = append(f.Diagnostics, Diagnostic{
+				Span:     returnFieldSpan(query.Node, ret, scope.Span()),
+				Severity: SeverityHint,
+				Message:  "return field '" + column + "' is never checked by any test within this scope",
+				Code:     "unused-return-field",
+				Source:   "scaf",
+			})
+		}
+	}
+}
+
+// returnFieldSpan returns the span in the file of a returned field's
+// expression in fn's body, or fallback if its position is unknown.
+func returnFieldSpan(fn *scaf.Function, ret scaf.ReturnInfo, fallback scaf.Span) scaf.Span {
+	if fn == nil || ret.Line == 0 {
+		return fallback
+	}
+
+	start := bodyOffset(fn.Body, ret.Line, ret.Column)
+
+	return queryRangeSpan(fn, scaf.NewQueryDiagnosticRange(fn.Body, start, start+ret.Length))
+}
+
+// bodyOffset returns the byte offset of a 1-indexed line and column in body.
+func bodyOffset(body string, line, column int) int {
+	offset := 0
+
+	for range line - 1 {
+		i := strings.IndexByte(body[offset:], '\n')
+		if i < 0 {
+			return len(body)
+		}
+
+		offset += i + 1
+	}
+
+	for range column - 1 {
+		if offset >= len(body) || body[offset] == '\n' {
+			break
+		}
+
+		_, size := utf8.DecodeRuneInString(body[offset:])
+		offset += size
+	}
+
+	return offset
+}
+
+func collectCheckedFields(items []*scaf.TestOrGroup, keys *[]string) {
+	for _, item := range items {
+		if item.Test != nil {
+			for _, stmt := range item.Test.Statements {
+				key := stmt.Key()
+				if key != "" && !strings.HasPrefix(key, "$") {
+					*keys = append(*keys, key)
+				}
+			}
+		}
+
+		if item.Group != nil {
+			collectCheckedFields(item.Group.Items, keys)
+		}
+	}
+}
+
+// returnFieldChecked reports whether any statement key refers to column,
+// either directly or through a flattened property such as "u.name" for "u".
+func returnFieldChecked(column string, keys []string) bool {
+	for _, key := range keys {
+		if returnFieldExists(key, []string{column}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ----------------------------------------------------------------------------
+// Rule: circular-imports
+// ----------------------------------------------------------------------------
+
+var circularImportsRule = &Rule{
+	Name:     "circular-imports",
+	Doc:      "Reports imports that lead back to the importing file.",
+	Severity: SeverityWarning,
+	RunBatch: checkCircularImports,
+}
+
+func checkCircularImports(files []*AnalyzedFile) {
+	graph := DependencyGraph(files)
+
+	byPath := make(map[string]*AnalyzedFile, len(files))
+	for _, f := range files {
+		if _, ok := byPath[batchPath(f.Path)]; !ok {
+			byPath[batchPath(f.Path)] = f
+		}
+	}
+
+	for _, f := range files {
+		if f.Suite == nil {
+			continue
+		}
+
+		for _, imp := range f.Suite.Imports {
+			target, ok := byPath[resolveBatchImport(f, imp)]
+			if !ok {
+				continue
+			}
+
+			chain := graph.path(target.Path, f.Path)
+			if chain == nil {
+				continue
+			}
+
+			f.Diagnostics = append(f.Diagnostics, Diagnostic{
+				Span:     imp.Span(),
+				Severity: SeverityWarning,
+				Message:  "circular import: " + strings.Join(append([]string{f.Path}, chain...), " -> "),
+				Code:     "circular-imports",
+				Source:   "scaf",
+			})
+		}
+	}
+}
+
+// resolveBatchImport returns the path of the file imp refers to, in the form
+// batchPath gives. Without a resolver, the import is taken relative to f with
+// a .scaf extension.
+func resolveBatchImport(f *AnalyzedFile, imp *scaf.Import) string {
+	if f.Resolver != nil {
+		return batchPath(f.Resolver.ResolveImportPath(f.Path, imp.Path))
+	}
+
+	resolved := imp.Path
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(f.Path), resolved)
+	}
+
+	if !strings.HasSuffix(resolved, ".scaf") {
+		resolved += ".scaf"
+	}
+
+	return batchPath(resolved)
+}
+
+// batchPath normalizes a file path so files in a batch can be matched.
+func batchPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(path)
+}
+
+// ----------------------------------------------------------------------------
+// Rule: never-tested-query
+// ----------------------------------------------------------------------------
+
+var neverTestedQueryRule = &Rule{
+	Name:     "never-tested-query",
+	Doc:      "Reports queries that no file in the project tests.",
+	Severity: SeverityHint,
+	RunBatch: checkNeverTestedQueries,
+}
+
+func checkNeverTestedQueries(files []*AnalyzedFile) {
+	// Scopes test the queries of their own file.
+	tested := make(map[string]bool)
+
+	for _, f := range files {
+		if f.Suite == nil {
+			continue
+		}
+
+		for _, scope := range f.Suite.Scopes {
+			tested[batchPath(f.Path)+"#"+scope.FunctionName] = true
+		}
+	}
+
+	for _, f := range files {
+		if f.Suite == nil {
+			continue
+		}
+
+		for _, fn := range f.Suite.Functions {
+			if tested[batchPath(f.Path)+"#"+fn.Name] {
+				continue
+			}
+
+			f.Diagnostics = append(f.Diagnostics, Diagnostic{
+				Span:     fn.Span(),
+				Severity: SeverityHint,
+				Message:  "query '" + fn.Name + "' is never tested",
+				Code:     "never-tested-query",
+				Source:   "scaf",
+			})
+		}
+	}
+}
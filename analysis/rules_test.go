@@ -1,6 +1,7 @@
 package analysis_test
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
@@ -75,6 +76,140 @@ Q {
 	assertNoDiagnostic(t, result, "unused-import")
 }
 
+func TestRule_UsedImport_Teardown(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+import fixtures "./fixtures"
+
+fn Q() `+"`Q`"+`
+
+Q {
+	teardown fixtures
+
+	test "t" {}
+}
+`)
+
+	assertNoDiagnostic(t, result, "unused-import")
+
+	result = analyze(t, `
+fn Q() `+"`Q`"+`
+
+teardown { missing.Cleanup() }
+
+Q {
+	test "t" {}
+}
+`)
+
+	assertHasDiagnostic(t, result, "undefined-import")
+}
+
+func TestRule_UnusedImport_ReversedRules(t *testing.T) {
+	t.Parallel()
+
+	rules := analysis.DefaultRules()
+	slices.Reverse(rules)
+
+	analyzer := analysis.NewAnalyzerWithRules(nil, rules)
+
+	used := analyzer.Analyze("test.scaf", []byte(`
+import fixtures "./fixtures"
+
+fn Q() `+"`Q`"+`
+
+setup fixtures.Setup()
+
+Q {
+	test "t" {}
+}
+`))
+	assertNoDiagnostic(t, used, "unused-import")
+
+	unused := analyzer.Analyze("test.scaf", []byte(`
+import fixtures "./fixtures"
+
+fn Q() `+"`Q`"+`
+
+Q {
+	test "t" {}
+}
+`))
+	assertHasDiagnostic(t, unused, "unused-import")
+}
+
+func TestRule_Priority(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+
+	record := func(name string, priority int) *analysis.Rule {
+		return &analysis.Rule{
+			Name:     name,
+			Priority: priority,
+			Run:      func(*analysis.AnalyzedFile) { order = append(order, name) },
+		}
+	}
+
+	analyzer := analysis.NewAnalyzerWithRules(nil, []*analysis.Rule{
+		record("late", 20),
+		record("default-a", 0),
+		record("early", 10),
+		record("default-b", 0),
+	})
+	analyzer.Analyze("test.scaf", []byte("fn Q() `Q`\n"))
+
+	// Equal priorities keep their given order.
+	want := []string{"default-a", "default-b", "early", "late"}
+	if !slices.Equal(order, want) {
+		t.Errorf("rules ran in order %v, want %v", order, want)
+	}
+}
+
+func TestAnalyzer_AnalysisConfig(t *testing.T) {
+	t.Parallel()
+
+	input := []byte(`
+import fixtures "./fixtures"
+
+fn Q() ` + "`Q`" + `
+
+Q {
+	test "t" {}
+}
+`)
+
+	analyzer := analysis.NewAnalyzer(nil)
+	analyzer.SetAnalysisConfig(&scaf.AnalysisConfig{Rules: map[string]scaf.RuleConfig{
+		"unused-import": {Enabled: true, Severity: "error"},
+		"empty-test":    {Enabled: false},
+	}})
+
+	result := analyzer.Analyze("test.scaf", input)
+
+	assertNoDiagnostic(t, result, "empty-test")
+
+	for _, d := range result.Diagnostics {
+		if d.Code == "unused-import" && d.Severity != analysis.SeverityError {
+			t.Errorf("unused-import severity = %v, want error", d.Severity)
+		}
+	}
+	assertHasDiagnostic(t, result, "unused-import")
+
+	// Clearing the config restores the defaults.
+	analyzer.SetAnalysisConfig(nil)
+	result = analyzer.Analyze("test.scaf", input)
+
+	assertHasDiagnostic(t, result, "empty-test")
+
+	for _, d := range result.Diagnostics {
+		if d.Code == "unused-import" && d.Severity != analysis.SeverityWarning {
+			t.Errorf("unused-import severity = %v, want warning", d.Severity)
+		}
+	}
+}
+
 func TestRule_DuplicateQuery(t *testing.T) {
 	t.Parallel()
 
@@ -107,6 +242,55 @@ Q {
 	assertHasDiagnostic(t, result, "duplicate-import")
 }
 
+func TestRule_Duplicates_RelatedInformation(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+import fixtures "./fixtures"
+import fixtures "./other"
+
+fn Q() `+"`Q`"+`
+fn Q() `+"`Q`"+`
+
+Q {
+	test "t" {}
+	test "t" {}
+	group "g" {}
+	group "g" {}
+}
+`)
+
+	// Each duplicate points back to the first definition, on the line before.
+	want := map[string]string{
+		"duplicate-import": "first definition of import fixtures",
+		"duplicate-query":  "first definition of query Q",
+		"duplicate-test":   "first definition of test t",
+		"duplicate-group":  "first definition of group g",
+	}
+
+	for _, d := range result.Diagnostics {
+		message, ok := want[d.Code]
+		if !ok {
+			continue
+		}
+		delete(want, d.Code)
+
+		if len(d.Related) != 1 {
+			t.Errorf("%s: got %d related locations, want 1", d.Code, len(d.Related))
+			continue
+		}
+
+		related := d.Related[0]
+		if related.Message != message || related.URI != "" || related.Span.Start.Line != d.Span.Start.Line-1 {
+			t.Errorf("%s: related = %+v, want %q on line %d", d.Code, related, message, d.Span.Start.Line-1)
+		}
+	}
+
+	for code := range want {
+		t.Errorf("expected diagnostic %q", code)
+	}
+}
+
 func TestRule_UnknownParameter(t *testing.T) {
 	t.Parallel()
 
@@ -193,6 +377,62 @@ Q {
 	assertHasDiagnostic(t, result, "undefined-assert-query")
 }
 
+func TestRule_ParamInAssertQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		assert string
+		want   bool
+	}{
+		{name: "valid params", assert: `assert GetUser($id: 1) { u.name == "Alice" }`},
+		{name: "field reference", assert: `assert GetUser($id: u.id) { u.name == "Alice" }`},
+		{name: "unknown param", assert: `assert GetUser($userId: 1) { u.name == "Alice" }`, want: true},
+		{name: "type mismatch", assert: `assert GetUser($id: "one") { u.name == "Alice" }`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := analyze(t, `
+fn GetUser(id: int) `+"`MATCH (u:User {id: $id}) RETURN u`"+`
+
+GetUser {
+	test "t" {
+		$id: 1
+		`+tt.assert+`
+	}
+}
+`)
+
+			if tt.want {
+				assertHasDiagnostic(t, result, "param-in-assert-query")
+			} else {
+				assertNoDiagnostic(t, result, "param-in-assert-query")
+			}
+		})
+	}
+}
+
+func TestRule_ParamInAssertQuery_BodyParams(t *testing.T) {
+	t.Parallel()
+
+	// Untyped queries accept any parameter used in the body.
+	result := analyze(t, `
+fn Q() `+"`MATCH (u:User {id: $id}) RETURN u`"+`
+
+Q {
+	test "t" {
+		$id: 1
+		assert Q($id: "one") { u != null }
+	}
+}
+`)
+
+	assertNoDiagnostic(t, result, "param-in-assert-query")
+}
+
 func TestRule_MissingRequiredParams(t *testing.T) {
 	t.Parallel()
 
@@ -240,6 +480,191 @@ Q {
 	assertHasDiagnostic(t, result, "empty-group")
 }
 
+func TestRule_TableRowWidth(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+fn Q(id) `+"`MATCH (u:User {id: $id}) RETURN u.name`"+`
+
+Q {
+	table "lookups" {
+		| $id | u.name  |
+		| 1   | "Alice" |
+		| 2   |
+	}
+}
+`)
+
+	assertHasDiagnostic(t, result, "table-row-width")
+
+	for _, d := range result.Diagnostics {
+		if d.Code == "table-row-width" && d.Span.Start.Line != 8 {
+			t.Errorf("table-row-width at line %d, want 8", d.Span.Start.Line)
+		}
+	}
+}
+
+func TestRule_TeardownMissing(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+fn Q() `+"`MATCH (u:User) RETURN u`"+`
+
+Q {
+	setup `+"`CREATE (:User {name: \"Alice\"})`"+`
+
+	test "finds users" {}
+}
+`)
+
+	assertHasDiagnostic(t, result, "teardown-missing")
+}
+
+func TestRule_TeardownMissing_EnclosingTeardown(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+fn Q() `+"`MATCH (u:User) RETURN u`"+`
+
+teardown `+"`MATCH (n) DETACH DELETE n`"+`
+
+Q {
+	setup `+"`MERGE (:User {name: \"Alice\"})`"+`
+
+	group "nested" {
+		test "finds users" {
+			setup `+"`CREATE (:User {name: \"Bob\"})`"+`
+		}
+	}
+}
+`)
+
+	assertNoDiagnostic(t, result, "teardown-missing")
+}
+
+func TestRule_TeardownMissing_ReadOnlySetup(t *testing.T) {
+	t.Parallel()
+
+	// The substring heuristic matches created_at; the Cypher parse does not.
+	result := analyzeWithQueryAnalyzer(t, `
+fn Q() `+"`MATCH (u:User) RETURN u`"+`
+
+Q {
+	setup `+"`MATCH (u:User) SET u.created_at = 1`"+`
+
+	test "finds users" {}
+}
+`)
+
+	assertNoDiagnostic(t, result, "teardown-missing")
+}
+
+func TestRule_TeardownMissing_Suppressed(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+fn Q() `+"`MATCH (u:User) RETURN u`"+`
+
+Q {
+	// scaf-disable teardown-missing
+	setup `+"`CREATE (:User)`"+`
+
+	test "finds users" {}
+}
+`)
+
+	assertHasDiagnostic(t, result, "teardown-missing")
+
+	for _, d := range result.Diagnostics {
+		if d.Code == "teardown-missing" && !d.Suppressed {
+			t.Errorf("expected teardown-missing to be suppressed: %s", d.Message)
+		}
+	}
+}
+
+func TestRule_TeardownCreatesData(t *testing.T) {
+	t.Parallel()
+
+	result := analyzeWithQueryAnalyzer(t, `
+fn Q() `+"`MATCH (u:User) RETURN u`"+`
+
+teardown `+"`MATCH (n) DETACH DELETE n`"+`
+
+Q {
+	setup `+"`CREATE (:User)`"+`
+	teardown `+"`MATCH (u:User) DELETE u CREATE (:Audit)`"+`
+
+	test "finds users" {}
+}
+`)
+
+	var lines []int
+	for _, d := range result.Diagnostics {
+		if d.Code == "teardown-creates-data" {
+			lines = append(lines, d.Span.Start.Line)
+		}
+	}
+
+	// Only the scope teardown creates data
+	if len(lines) != 1 || lines[0] != 8 {
+		t.Errorf("teardown-creates-data at lines %v, want [8]", lines)
+	}
+}
+
+func TestRule_SetupOrder(t *testing.T) {
+	t.Parallel()
+
+	result := analyzeWithQueryAnalyzer(t, `
+fn Q() `+"`MATCH (p:Post) RETURN p`"+`
+
+Q {
+	setup {
+		`+"`MATCH (u:User) CREATE (u)-[:AUTHORED]->(:Post)`"+`
+		`+"`CREATE (:User)`"+`
+		`+"`MATCH (p:Post) SET p.published = true`"+`
+	}
+
+	test "finds posts" {
+		setup {
+			`+"`CREATE (:Tag)`"+`
+			`+"`MATCH (t:Tag) CREATE (:Post)-[:TAGGED]->(t)`"+`
+		}
+	}
+}
+`)
+
+	var lines []int
+	for _, d := range result.Diagnostics {
+		if d.Code == "setup-order" {
+			lines = append(lines, d.Span.Start.Line)
+		}
+	}
+
+	// Only the first item matches a label a later item creates
+	if len(lines) != 1 || lines[0] != 6 {
+		t.Errorf("setup-order at lines %v, want [6]", lines)
+	}
+}
+
+func TestRule_SetupOrder_NoQueryAnalyzer(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+fn Q() `+"`MATCH (u:User) RETURN u`"+`
+
+Q {
+	setup {
+		`+"`MATCH (u:User) SET u.active = true`"+`
+		`+"`CREATE (:User)`"+`
+	}
+
+	test "finds users" {}
+}
+`)
+
+	assertNoDiagnostic(t, result, "setup-order")
+}
+
 func TestRule_UnusedDeclaredParam(t *testing.T) {
 	t.Parallel()
 
@@ -289,6 +714,64 @@ GetUser {
 	assertHasDiagnostic(t, result, "unused-declared-param")
 }
 
+func TestRule_AutoFix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		code  string
+		input string
+		want  string
+	}{
+		{
+			name:  "unused import",
+			code:  "unused-import",
+			input: "import fixtures \"./fixtures\"\nimport \"./other\"\n\nfn Q() `Q`\n\nsetup other.Setup()\n",
+			want:  "import \"./other\"\n\nfn Q() `Q`\n\nsetup other.Setup()\n",
+		},
+		{
+			name:  "unused typed param",
+			code:  "unused-declared-param",
+			input: "fn Q(unused: int, id: string?) `MATCH (u {id: $id}) RETURN u`\n",
+			want:  "fn Q(id: string?) `MATCH (u {id: $id}) RETURN u`\n",
+		},
+		{
+			name:  "only param with trailing comma",
+			code:  "unused-declared-param",
+			input: "fn Q(\n\tunused: int,\n) `MATCH (u) RETURN u`\n",
+			want:  "fn Q(\n\t\n) `MATCH (u) RETURN u`\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := analyze(t, tt.input)
+
+			for _, d := range result.Diagnostics {
+				if d.Code != tt.code {
+					continue
+				}
+
+				if d.AutoFix == nil {
+					t.Fatalf("%s diagnostic has no AutoFix", tt.code)
+				}
+
+				span := d.AutoFix.Span
+				got := tt.input[:span.Start.Offset] + d.AutoFix.NewText + tt.input[span.End.Offset:]
+				if got != tt.want {
+					t.Errorf("after fix:\n%q\nwant:\n%q", got, tt.want)
+				}
+
+				return
+			}
+
+			t.Fatalf("expected diagnostic %q", tt.code)
+		})
+	}
+}
+
 func TestRule_UndeclaredQueryParam(t *testing.T) {
 	t.Parallel()
 
@@ -763,6 +1246,39 @@ FindPerson {
 	assertNoDiagnostic(t, result, "param-type-mismatch")
 }
 
+func TestRule_ParamTypeMismatch_DeclaredTypeVsUsage(t *testing.T) {
+	t.Parallel()
+
+	result := analyzeWithQueryAnalyzer(t, `
+fn FindByPrefix(prefix: int) `+"`MATCH (p:Person) WHERE p.name STARTS WITH $prefix RETURN p`"+`
+`)
+
+	assertHasDiagnostic(t, result, "param-type-mismatch")
+
+	for _, d := range result.Diagnostics {
+		if d.Code != "param-type-mismatch" {
+			continue
+		}
+		if d.Severity != analysis.SeverityError {
+			t.Errorf("Severity = %v, want the rule's severity", d.Severity)
+		}
+		// Reported on the parameter declaration.
+		if d.Span.Start.Line != 2 || d.Span.Start.Column != 17 {
+			t.Errorf("Span.Start = %d:%d, want 2:17", d.Span.Start.Line, d.Span.Start.Column)
+		}
+	}
+}
+
+func TestRule_ParamTypeMismatch_DeclaredTypeMatchesUsage(t *testing.T) {
+	t.Parallel()
+
+	result := analyzeWithQueryAnalyzer(t, `
+fn FindByPrefix(prefix: string, ids: [int]) `+"`MATCH (p:Person) WHERE p.name STARTS WITH $prefix AND p.id IN $ids RETURN p`"+`
+`)
+
+	assertNoDiagnostic(t, result, "param-type-mismatch")
+}
+
 // Test helpers
 
 func analyze(t *testing.T, input string) *analysis.AnalyzedFile {
@@ -1173,6 +1689,23 @@ GetData {
 	assertHasDiagnostic(t, result, "invalid-type-annotation")
 }
 
+func TestRule_InvalidTypeAnnotation_MapWithNonStringKey(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+fn GetData(data: {int: string}) `+"`MATCH (d:Data {data: $data}) RETURN d`"+`
+
+GetData {
+	test "gets data" {
+		$data: {foo: "bar"}
+	}
+}
+`)
+
+	assertHasDiagnostic(t, result, "invalid-type-annotation")
+	assertHasDiagnostic(t, result, "param-type-mismatch")
+}
+
 // TestRule_InvalidExpression_FriendlyUndefinedError tests that undefined variable errors
 // are reported with a friendly message pointing to the RETURN clause.
 func TestRule_InvalidExpression_FriendlyUndefinedError(t *testing.T) {
@@ -1454,6 +1987,64 @@ GetUser {
 	assertNoDiagnostic(t, result, "invalid-expression")
 }
 
+func TestRule_InvalidExpression_QueryWarnings(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {Name: "User", Fields: []*analysis.Field{{Name: "name", Type: analysis.TypeString}}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+		// at is the text the diagnostic covers.
+		at string
+	}{
+		{"deprecated exists", "MATCH (u:User) WHERE exists(u.name) RETURN u", "deprecated-function", "exists(u.name)"},
+		{"cartesian product", "MATCH (u:User) MATCH (p:Post) RETURN u, p", "cartesian-product", "(p:Post)"},
+		{"limit without order", "MATCH (u:User)\nRETURN u LIMIT 10", "limit-without-order-by", "LIMIT 10"},
+		{"unlabeled property access", "MATCH (n) RETURN n.name AS name", "unlabeled-property-access", "n.name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := "fn Q() `" + tt.query + "`\n"
+			result := analyzeWithSchema(t, src, schema)
+
+			assertHasDiagnostic(t, result, tt.code)
+
+			for _, d := range result.Diagnostics {
+				if d.Code != tt.code {
+					continue
+				}
+
+				if d.Severity != analysis.SeverityWarning {
+					t.Errorf("%s severity = %v, want warning", d.Code, d.Severity)
+				}
+
+				if got := src[d.Span.Start.Offset:d.Span.End.Offset]; got != tt.at {
+					t.Errorf("%s covers %q, want %q", d.Code, got, tt.at)
+				}
+
+				if line := strings.Count(src[:d.Span.Start.Offset], "\n") + 1; d.Span.Start.Line != line {
+					t.Errorf("%s starts on line %d, want %d", d.Code, d.Span.Start.Line, line)
+				}
+			}
+		})
+	}
+
+	// Ordered, connected queries have nothing to report
+	result := analyzeWithSchema(t, "fn Q() `MATCH (u:User)-[:WROTE]->(p:Post) RETURN u.name AS name ORDER BY name LIMIT 10`\n", schema)
+	for _, code := range []string{"deprecated-function", "cartesian-product", "limit-without-order-by", "unlabeled-property-access"} {
+		assertNoDiagnostic(t, result, code)
+	}
+}
+
 // ============================================================================
 // Return type mismatch tests
 // ============================================================================
@@ -1599,3 +2190,196 @@ GetUser {
 
 	assertNoDiagnostic(t, result, "return-type-mismatch")
 }
+
+// ============================================================================
+// Unknown return field tests
+// ============================================================================
+
+func TestRule_UnknownReturnField_Typo(t *testing.T) {
+	t.Parallel()
+
+	result := analyzeWithQueryAnalyzer(t, `
+fn GetUser(id) `+"`MATCH (u:User {id: $id}) RETURN u.name AS name`"+`
+
+GetUser {
+	test "finds user" {
+		$id: 1
+		naem: "Alice"
+	}
+}
+`)
+
+	assertHasDiagnostic(t, result, "unknown-return-field")
+
+	for _, d := range result.Diagnostics {
+		if d.Code == "unknown-return-field" {
+			want := "field 'naem' not returned by query GetUser (did you mean 'name'?)"
+			if d.Message != want {
+				t.Errorf("message = %q, want %q", d.Message, want)
+			}
+
+			if d.Severity != analysis.SeverityError {
+				t.Errorf("severity = %v, want error", d.Severity)
+			}
+		}
+	}
+}
+
+func TestRule_UnknownReturnField_NoSuggestion(t *testing.T) {
+	t.Parallel()
+
+	result := analyzeWithQueryAnalyzer(t, `
+fn GetUser(id) `+"`MATCH (u:User {id: $id}) RETURN u.fullName`"+`
+
+GetUser {
+	test "finds user" {
+		$id: 1
+		name: "Alice"
+	}
+}
+`)
+
+	for _, d := range result.Diagnostics {
+		if d.Code == "unknown-return-field" && strings.Contains(d.Message, "did you mean") {
+			t.Errorf("unexpected suggestion: %s", d.Message)
+		}
+	}
+
+	assertHasDiagnostic(t, result, "unknown-return-field")
+}
+
+func TestRule_UnknownReturnField_Valid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		field string
+	}{
+		{"expression", "MATCH (u:User) RETURN u.name", "u.name"},
+		{"alias", "MATCH (u:User) RETURN u.name AS userName", "userName"},
+		{"node property", "MATCH (u:User) RETURN u", "u.name"},
+		{"whole node", "MATCH (u:User) RETURN u", "u"},
+		{"wildcard", "MATCH (u:User) RETURN *", "anything"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := analyzeWithQueryAnalyzer(t, `
+fn Q() `+"`"+tt.query+"`"+`
+
+Q {
+	test "t" {
+		`+tt.field+`: 1
+	}
+}
+`)
+
+			assertNoDiagnostic(t, result, "unknown-return-field")
+		})
+	}
+}
+
+func TestRule_UnknownReturnField_NoQueryAnalyzer(t *testing.T) {
+	t.Parallel()
+
+	result := analyze(t, `
+fn GetUser() `+"`MATCH (u:User) RETURN u.name`"+`
+
+GetUser {
+	test "t" {
+		naem: "Alice"
+	}
+}
+`)
+
+	assertNoDiagnostic(t, result, "unknown-return-field")
+}
+
+func TestRule_UnusedReturnField(t *testing.T) {
+	t.Parallel()
+
+	src := `
+fn GetUser(id) ` + "`" + `
+MATCH (u:User {id: $id})
+RETURN u.name AS name,
+	u.email AS email, u.age AS age, u
+` + "`" + `
+
+GetUser {
+	test "finds name" {
+		$id: 1
+		name: "Alice"
+	}
+	group "nested" {
+		test "finds profile" {
+			$id: 2
+			u.bio: "hi"
+		}
+	}
+}
+`
+	result := analyzeWithQueryAnalyzer(t, src)
+
+	var unused, at []string
+
+	for _, d := range result.Diagnostics {
+		if d.Code == "unused-return-field" {
+			if d.Severity != analysis.SeverityHint {
+				t.Errorf("severity = %v, want hint", d.Severity)
+			}
+
+			if line := strings.Count(src[:d.Span.Start.Offset], "\n") + 1; d.Span.Start.Line != line {
+				t.Errorf("%s: line = %d, want %d", d.Message, d.Span.Start.Line, line)
+			}
+
+			unused = append(unused, d.Message)
+			at = append(at, src[d.Span.Start.Offset:d.Span.End.Offset])
+		}
+	}
+
+	want := []string{
+		"return field 'email' is never checked by any test within this scope",
+		"return field 'age' is never checked by any test within this scope",
+	}
+
+	if !slices.Equal(unused, want) {
+		t.Errorf("unused-return-field diagnostics = %q, want %q", unused, want)
+	}
+
+	// Each is reported on its RETURN item
+	if wantAt := []string{"u.email", "u.age"}; !slices.Equal(at, wantAt) {
+		t.Errorf("unused-return-field spans cover %q, want %q", at, wantAt)
+	}
+}
+
+func TestRule_UnusedReturnField_Skipped(t *testing.T) {
+	t.Parallel()
+
+	wildcard := analyzeWithQueryAnalyzer(t, `
+fn GetUser(id) `+"`MATCH (u:User {id: $id}) RETURN *`"+`
+
+GetUser {
+	test "t" {
+		$id: 1
+	}
+}
+`)
+
+	assertNoDiagnostic(t, wildcard, "unused-return-field")
+
+	// Without a query analyzer the return fields are unknown.
+	noAnalyzer := analyze(t, `
+fn GetUser(id) `+"`MATCH (u:User {id: $id}) RETURN u.name AS name`"+`
+
+GetUser {
+	test "t" {
+		$id: 1
+	}
+}
+`)
+
+	assertNoDiagnostic(t, noAnalyzer, "unused-return-field")
+}
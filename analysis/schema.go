@@ -1,14 +1,25 @@
 package analysis
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 
-	"github.com/rlch/scaf"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
 	"gopkg.in/yaml.v3"
+
+	"github.com/rlch/scaf"
 )
 
 // TypeSchema represents the database schema extracted from user code.
@@ -30,33 +41,365 @@ func NewTypeSchema() *TypeSchema {
 	}
 }
 
-// yamlSchema is the YAML representation of TypeSchema.
-type yamlSchema struct {
-	Models map[string]*yamlModel `yaml:"models"`
+// RelationshipInfo is a relationship together with the model defining it.
+type RelationshipInfo struct {
+	*Relationship
+
+	// SourceModel is the name of the model the relationship is defined on.
+	SourceModel string
+}
+
+// FieldInfo is a field together with the model defining it.
+type FieldInfo struct {
+	*Field
+
+	// ModelName is the name of the model the field is defined on.
+	ModelName string
+}
+
+// Relationships returns every relationship in the schema keyed by its
+// relationship type. Each list is ordered by source model name, then
+// declaration order. Relationships without a type are skipped. It is safe
+// for concurrent use as long as the schema is not modified.
+func (s *TypeSchema) Relationships() map[string][]*RelationshipInfo {
+	rels := make(map[string][]*RelationshipInfo)
+	if s == nil {
+		return rels
+	}
+
+	for _, name := range sortedModelNames(s) {
+		model := s.Models[name]
+		if model == nil {
+			continue
+		}
+
+		for _, rel := range model.Relationships {
+			if rel == nil || rel.RelType == "" {
+				continue
+			}
+
+			rels[rel.RelType] = append(rels[rel.RelType], &RelationshipInfo{Relationship: rel, SourceModel: name})
+		}
+	}
+
+	return rels
+}
+
+// AllFields returns every field in the schema, ordered by model name, then
+// declaration order. It is safe for concurrent use as long as the schema is
+// not modified.
+func (s *TypeSchema) AllFields() []*FieldInfo {
+	if s == nil {
+		return nil
+	}
+
+	var fields []*FieldInfo
+
+	for _, name := range sortedModelNames(s) {
+		model := s.Models[name]
+		if model == nil {
+			continue
+		}
+
+		for _, field := range model.Fields {
+			if field != nil {
+				fields = append(fields, &FieldInfo{Field: field, ModelName: name})
+			}
+		}
+	}
+
+	return fields
+}
+
+// Merge adds the models of other to s. A model defined by both keeps every
+// field and relationship from each, matched by name; for a name defined twice,
+// other's definition wins. Merge fails without changing s if a field is
+// defined by both with different types.
+func (s *TypeSchema) Merge(other *TypeSchema) error {
+	if other == nil {
+		return nil
+	}
+
+	var conflicts []error
+
+	for _, name := range sortedModelNames(other) {
+		if existing, ok := s.Models[name]; ok {
+			if fields := conflictingFields(existing, other.Models[name]); len(fields) > 0 {
+				conflicts = append(conflicts, fmt.Errorf("model %s: conflicting types for fields %s", name, strings.Join(fields, ", ")))
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("merging schema: %w", errors.Join(conflicts...))
+	}
+
+	if s.Models == nil {
+		s.Models = make(map[string]*Model, len(other.Models))
+	}
+
+	for name, model := range other.Models {
+		s.Models[name] = mergeModels(s.Models[name], model)
+	}
+
+	return nil
+}
+
+// conflictingFields returns the names, sorted, of fields that a and b both
+// define with different types. A field with no type conflicts with nothing.
+func conflictingFields(a, b *Model) []string {
+	if a == nil || b == nil {
+		return nil
+	}
+
+	types := make(map[string]string, len(a.Fields))
+	for _, f := range a.Fields {
+		if f != nil && f.Type != nil {
+			types[f.Name] = f.Type.String()
+		}
+	}
+
+	var names []string
+
+	for _, f := range b.Fields {
+		if f == nil || f.Type == nil {
+			continue
+		}
+
+		if typ, ok := types[f.Name]; ok && typ != f.Type.String() && !slices.Contains(names, f.Name) {
+			names = append(names, f.Name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// mergeModels returns a new model with the fields and relationships of base
+// followed by those of override, which replaces any of base's with the same name.
+// The result shares no slices with either, so later merges leave them intact.
+func mergeModels(base, override *Model) *Model {
+	if override == nil {
+		return base
+	}
+
+	merged := &Model{Name: override.Name}
+
+	if base != nil {
+		merged.Fields = mergeByName(base.Fields, override.Fields, func(f *Field) string { return f.Name })
+		merged.Relationships = mergeByName(base.Relationships, override.Relationships, func(r *Relationship) string { return r.Name })
+		merged.NestedFields = mergeByName(base.NestedFields, override.NestedFields, func(f *Field) string { return f.Name })
+	} else {
+		merged.Fields = slices.Clone(override.Fields)
+		merged.Relationships = slices.Clone(override.Relationships)
+		merged.NestedFields = slices.Clone(override.NestedFields)
+	}
+
+	return merged
+}
+
+// mergeByName appends override to base, replacing base's items that share a
+// name with an override item in place so declaration order is kept.
+func mergeByName[T any](base, override []*T, name func(*T) string) []*T {
+	merged := slices.Clone(base)
+	index := make(map[string]int, len(merged))
+
+	for i, item := range merged {
+		if item != nil {
+			index[name(item)] = i
+		}
+	}
+
+	for _, item := range override {
+		if item == nil {
+			continue
+		}
+
+		if i, ok := index[name(item)]; ok {
+			merged[i] = item
+			continue
+		}
+
+		index[name(item)] = len(merged)
+		merged = append(merged, item)
+	}
+
+	return merged
+}
+
+// sortedModelNames returns the model names of schema in sorted order.
+func sortedModelNames(schema *TypeSchema) []string {
+	names := make([]string, 0, len(schema.Models))
+	for name := range schema.Models {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
 }
 
-// yamlModel is the YAML representation of Model.
-type yamlModel struct {
-	Fields        map[string]*yamlField        `yaml:"fields,omitempty"`
-	Relationships map[string]*yamlRelationship `yaml:"relationships,omitempty"`
+// SchemaError describes an inconsistency found by TypeSchema.Validate.
+type SchemaError struct {
+	// ModelName is the model with the problem.
+	ModelName string
+	// FieldName is the field or relationship with the problem, if any.
+	FieldName string
+	// Message describes the problem.
+	Message string
 }
 
-// yamlField is the YAML representation of Field.
-type yamlField struct {
-	Type     string `yaml:"type"`
-	Required bool   `yaml:"required,omitempty"`
-	Unique   bool   `yaml:"unique,omitempty"`
+// Error formats the error like the ones returned by LoadSchema.
+func (e SchemaError) Error() string {
+	if e.FieldName == "" {
+		return fmt.Sprintf("model %s: %s", e.ModelName, e.Message)
+	}
+
+	return fmt.Sprintf("model %s, field %s: %s", e.ModelName, e.FieldName, e.Message)
 }
 
-// yamlRelationship is the YAML representation of Relationship.
-type yamlRelationship struct {
-	RelType   string `yaml:"rel_type"`
-	Target    string `yaml:"target"`
-	Many      bool   `yaml:"many,omitempty"`
-	Direction string `yaml:"direction"`
+// Validate checks the schema for inconsistencies that would otherwise surface
+// as confusing errors during type inference: relationships whose target model
+// does not exist, fields with missing or malformed types, and models that
+// define a field twice. Errors are ordered by model name, then field order.
+func (s *TypeSchema) Validate() []SchemaError {
+	if s == nil {
+		return nil
+	}
+
+	var errs []SchemaError
+
+	for _, name := range sortedModelNames(s) {
+		model := s.Models[name]
+		if model == nil {
+			continue
+		}
+
+		seen := make(map[string]bool, len(model.Fields))
+
+		for _, field := range model.Fields {
+			if field == nil {
+				continue
+			}
+
+			if seen[field.Name] {
+				errs = append(errs, SchemaError{ModelName: name, FieldName: field.Name, Message: "duplicate field"})
+			}
+
+			seen[field.Name] = true
+
+			if msg := validateType(field.Type); msg != "" {
+				errs = append(errs, SchemaError{ModelName: name, FieldName: field.Name, Message: msg})
+			}
+		}
+
+		for _, rel := range model.Relationships {
+			if rel == nil {
+				continue
+			}
+
+			if _, ok := s.Models[rel.Target]; !ok {
+				errs = append(errs, SchemaError{
+					ModelName: name,
+					FieldName: rel.Name,
+					Message:   fmt.Sprintf("relationship target %q does not exist", rel.Target),
+				})
+			}
+		}
+	}
+
+	return errs
 }
 
-// LoadSchema loads a TypeSchema from a YAML file.
+// validateType returns a description of what is wrong with t, or "" if it
+// is well formed.
+func validateType(t *Type) string {
+	if t == nil {
+		return "missing type"
+	}
+
+	switch t.Kind {
+	case TypeKindPrimitive, TypeKindNamed:
+		if t.Name == "" {
+			return "type has no name"
+		}
+	case TypeKindSlice, TypeKindArray, TypeKindPointer:
+		if t.Elem == nil {
+			return fmt.Sprintf("%s type has no element type", t.Kind)
+		}
+
+		return validateType(t.Elem)
+	case TypeKindMap:
+		if t.Key == nil {
+			return "map type has no key type"
+		}
+
+		if t.Elem == nil {
+			return "map type has no value type"
+		}
+
+		if msg := validateType(t.Key); msg != "" {
+			return msg
+		}
+
+		return validateType(t.Elem)
+	case TypeKindStruct:
+		for _, field := range t.Fields {
+			// Untyped struct fields are any
+			if field.Type == nil {
+				continue
+			}
+
+			if msg := validateType(field.Type); msg != "" {
+				return msg
+			}
+		}
+	default:
+		return fmt.Sprintf("unknown type kind %q", t.Kind)
+	}
+
+	return ""
+}
+
+// schemaFile is the serialized form of TypeSchema, shared by the YAML and
+// JSON formats so both have the same structure.
+type schemaFile struct {
+	// Schema is the JSON Schema URL written as "$schema" in JSON output.
+	// YAML output carries it as a yaml-language-server comment instead.
+	Schema string                `yaml:"-"      json:"$schema,omitempty"`
+	Models map[string]*modelFile `yaml:"models" json:"models"`
+}
+
+// modelFile is the serialized form of Model.
+type modelFile struct {
+	Fields        map[string]*fieldFile        `yaml:"fields,omitempty"        json:"fields,omitempty"`
+	Relationships map[string]*relationshipFile `yaml:"relationships,omitempty" json:"relationships,omitempty"`
+}
+
+// fieldFile is the serialized form of Field.
+type fieldFile struct {
+	Type     string `yaml:"type"               json:"type"`
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
+	Unique   bool   `yaml:"unique,omitempty"   json:"unique,omitempty"`
+	Indexed  bool   `yaml:"indexed,omitempty"  json:"indexed,omitempty"`
+}
+
+// relationshipFile is the serialized form of Relationship.
+type relationshipFile struct {
+	RelType     string `yaml:"rel_type"              json:"rel_type"`
+	Target      string `yaml:"target"                json:"target"`
+	Many        bool   `yaml:"many,omitempty"        json:"many,omitempty"`
+	Direction   string `yaml:"direction"             json:"direction"`
+	Cardinality string `yaml:"cardinality,omitempty" json:"cardinality,omitempty"`
+}
+
+// typeSchemaURL is the JSON Schema that validates schema files.
+const typeSchemaURL = "https://raw.githubusercontent.com/rlch/scaf/main/.scaf-type.schema.json"
+
+// LoadSchema loads a TypeSchema from a YAML, JSON, or HCL file.
+// Files ending in .json are read as JSON and files ending in .hcl as HCL;
+// anything else is read as YAML.
 // The path can be absolute or relative to baseDir.
 func LoadSchema(path, baseDir string) (*TypeSchema, error) {
 	if path == "" {
@@ -75,53 +418,87 @@ func LoadSchema(path, baseDir string) (*TypeSchema, error) {
 		return nil, fmt.Errorf("reading schema file: %w", err)
 	}
 
-	var ys yamlSchema
-	if err := yaml.Unmarshal(data, &ys); err != nil {
+	switch ext := filepath.Ext(cleanPath); {
+	case strings.EqualFold(ext, ".json"):
+		return ReadSchemaJSON(bytes.NewReader(data))
+	case strings.EqualFold(ext, ".hcl"):
+		return ReadSchemaHCL(bytes.NewReader(data))
+	}
+
+	var sf schemaFile
+	if err := yaml.Unmarshal(data, &sf); err != nil {
 		return nil, fmt.Errorf("parsing schema: %w", err)
 	}
 
-	return yamlSchemaToTypeSchema(&ys)
+	return schemaFileToTypeSchema(&sf)
 }
 
-// yamlSchemaToTypeSchema converts the YAML representation to TypeSchema.
-func yamlSchemaToTypeSchema(ys *yamlSchema) (*TypeSchema, error) {
+// ReadSchemaJSON reads a TypeSchema written by WriteSchemaJSON.
+func ReadSchemaJSON(r io.Reader) (*TypeSchema, error) {
+	var sf schemaFile
+	if err := json.NewDecoder(r).Decode(&sf); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+
+	return schemaFileToTypeSchema(&sf)
+}
+
+// schemaFileToTypeSchema converts the serialized form to TypeSchema.
+func schemaFileToTypeSchema(sf *schemaFile) (*TypeSchema, error) {
 	schema := NewTypeSchema()
 
-	for modelName, ym := range ys.Models {
+	for modelName, mf := range sf.Models {
 		model := &Model{
 			Name:          modelName,
 			Fields:        make([]*Field, 0),
 			Relationships: make([]*Relationship, 0),
 		}
 
+		// A model written as null in JSON (or ~ in YAML) has no fields
+		if mf == nil {
+			schema.Models[model.Name] = model
+			continue
+		}
+
 		// Convert fields
-		if ym.Fields != nil {
-			for fieldName, yf := range ym.Fields {
-				typ, err := ParseTypeString(yf.Type)
-				if err != nil {
-					return nil, fmt.Errorf("model %s, field %s: %w", modelName, fieldName, err)
-				}
+		for fieldName, ff := range mf.Fields {
+			if ff == nil {
+				return nil, fmt.Errorf("model %s, field %s: missing type", modelName, fieldName)
+			}
 
-				model.Fields = append(model.Fields, &Field{
-					Name:     fieldName,
-					Type:     typ,
-					Required: yf.Required,
-					Unique:   yf.Unique,
-				})
+			typ, err := ParseTypeString(ff.Type)
+			if err != nil {
+				return nil, fmt.Errorf("model %s, field %s: %w", modelName, fieldName, err)
 			}
+
+			model.Fields = append(model.Fields, &Field{
+				Name:     fieldName,
+				Type:     typ,
+				Required: ff.Required,
+				Unique:   ff.Unique,
+				Indexed:  ff.Indexed,
+			})
 		}
 
 		// Convert relationships
-		if ym.Relationships != nil {
-			for relName, yr := range ym.Relationships {
-				model.Relationships = append(model.Relationships, &Relationship{
-					Name:      relName,
-					RelType:   yr.RelType,
-					Target:    yr.Target,
-					Many:      yr.Many,
-					Direction: Direction(yr.Direction),
-				})
+		for relName, rf := range mf.Relationships {
+			if rf == nil {
+				return nil, fmt.Errorf("model %s, relationship %s: missing definition", modelName, relName)
+			}
+
+			cardinality, err := ParseRelCardinality(rf.Cardinality)
+			if err != nil {
+				return nil, fmt.Errorf("model %s, relationship %s: %w", modelName, relName, err)
 			}
+
+			model.Relationships = append(model.Relationships, &Relationship{
+				Name:        relName,
+				RelType:     rf.RelType,
+				Target:      rf.Target,
+				Many:        rf.Many,
+				Direction:   Direction(rf.Direction),
+				Cardinality: cardinality,
+			})
 		}
 
 		schema.Models[model.Name] = model
@@ -130,62 +507,60 @@ func yamlSchemaToTypeSchema(ys *yamlSchema) (*TypeSchema, error) {
 	return schema, nil
 }
 
-// WriteSchema writes a TypeSchema as YAML to the given writer.
-// The output includes a yaml-language-server schema comment for editor validation.
-func WriteSchema(w io.Writer, schema *TypeSchema) (err error) {
-	// Write schema comment for editor validation
-	if _, err := fmt.Fprintln(w, "# yaml-language-server: $schema=https://raw.githubusercontent.com/rlch/scaf/main/.scaf-type.schema.json"); err != nil {
-		return err
-	}
-	if _, err := fmt.Fprintln(w); err != nil {
-		return err
+// typeSchemaToSchemaFile converts a TypeSchema to its serialized form.
+func typeSchemaToSchemaFile(schema *TypeSchema) *schemaFile {
+	sf := &schemaFile{
+		Models: make(map[string]*modelFile),
 	}
 
-	// Convert to YAML representation
-	ys := &yamlSchema{
-		Models: make(map[string]*yamlModel),
-	}
-
-	// Sort model names for deterministic output
-	modelNames := make([]string, 0, len(schema.Models))
-	for name := range schema.Models {
-		modelNames = append(modelNames, name)
-	}
-	sort.Strings(modelNames)
-
-	for _, name := range modelNames {
-		model := schema.Models[name]
-
-		ym := &yamlModel{}
+	for name, model := range schema.Models {
+		mf := &modelFile{}
 
 		// Convert fields
 		if len(model.Fields) > 0 {
-			ym.Fields = make(map[string]*yamlField)
+			mf.Fields = make(map[string]*fieldFile)
 			for _, field := range model.Fields {
-				ym.Fields[field.Name] = &yamlField{
+				mf.Fields[field.Name] = &fieldFile{
 					Type:     field.Type.String(),
 					Required: field.Required,
 					Unique:   field.Unique,
+					Indexed:  field.Indexed,
 				}
 			}
 		}
 
 		// Convert relationships
 		if len(model.Relationships) > 0 {
-			ym.Relationships = make(map[string]*yamlRelationship)
+			mf.Relationships = make(map[string]*relationshipFile)
 			for _, rel := range model.Relationships {
-				ym.Relationships[rel.Name] = &yamlRelationship{
-					RelType:   rel.RelType,
-					Target:    rel.Target,
-					Many:      rel.Many,
-					Direction: string(rel.Direction),
+				mf.Relationships[rel.Name] = &relationshipFile{
+					RelType:     rel.RelType,
+					Target:      rel.Target,
+					Many:        rel.Many,
+					Direction:   string(rel.Direction),
+					Cardinality: rel.Cardinality.String(),
 				}
 			}
 		}
 
-		ys.Models[name] = ym
+		sf.Models[name] = mf
 	}
 
+	return sf
+}
+
+// WriteSchema writes a TypeSchema as YAML to the given writer.
+// The output includes a yaml-language-server schema comment for editor validation.
+func WriteSchema(w io.Writer, schema *TypeSchema) (err error) {
+	// Write schema comment for editor validation
+	if _, err := fmt.Fprintln(w, "# yaml-language-server: $schema="+typeSchemaURL); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+
+	// The encoder sorts map keys, so output is deterministic
 	encoder := yaml.NewEncoder(w)
 	encoder.SetIndent(2)
 	defer func() {
@@ -194,14 +569,192 @@ func WriteSchema(w io.Writer, schema *TypeSchema) (err error) {
 		}
 	}()
 
-	return encoder.Encode(ys)
+	return encoder.Encode(typeSchemaToSchemaFile(schema))
+}
+
+// WriteSchemaJSON writes a TypeSchema as indented JSON to the given writer.
+// The output has the same structure as WriteSchema's YAML, plus a "$schema"
+// property for editor validation.
+func WriteSchemaJSON(w io.Writer, schema *TypeSchema) error {
+	sf := typeSchemaToSchemaFile(schema)
+	sf.Schema = typeSchemaURL
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(sf)
+}
+
+// hclSchemaFile is the HCL form of TypeSchema. Unlike the YAML and JSON
+// forms, models, fields, and relationships are repeated labeled blocks:
+//
+//	model "User" {
+//	  field "name" {
+//	    type = "string"
+//	  }
+//	}
+type hclSchemaFile struct {
+	Models []*hclModel `hcl:"model,block"`
+}
+
+// hclModel is the HCL form of Model.
+type hclModel struct {
+	Name          string             `hcl:"name,label"`
+	Fields        []*hclField        `hcl:"field,block"`
+	Relationships []*hclRelationship `hcl:"relationship,block"`
+}
+
+// hclField is the HCL form of Field.
+type hclField struct {
+	Name     string `hcl:"name,label"`
+	Type     string `hcl:"type"`
+	Required bool   `hcl:"required,optional"`
+	Unique   bool   `hcl:"unique,optional"`
+	Indexed  bool   `hcl:"indexed,optional"`
+}
+
+// hclRelationship is the HCL form of Relationship.
+type hclRelationship struct {
+	Name        string `hcl:"name,label"`
+	RelType     string `hcl:"rel_type"`
+	Target      string `hcl:"target"`
+	Many        bool   `hcl:"many,optional"`
+	Direction   string `hcl:"direction"`
+	Cardinality string `hcl:"cardinality,optional"`
+}
+
+// ReadSchemaHCL reads a TypeSchema written by WriteSchemaHCL.
+// Fields and relationships keep the order of their blocks.
+func ReadSchemaHCL(r io.Reader) (*TypeSchema, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %w", err)
+	}
+
+	file, diags := hclsyntax.ParseConfig(data, "schema.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("parsing schema: %w", diags)
+	}
+
+	var sf hclSchemaFile
+	if diags := gohcl.DecodeBody(file.Body, nil, &sf); diags.HasErrors() {
+		return nil, fmt.Errorf("parsing schema: %w", diags)
+	}
+
+	schema := NewTypeSchema()
+
+	for _, mf := range sf.Models {
+		if _, ok := schema.Models[mf.Name]; ok {
+			return nil, fmt.Errorf("model %s: defined more than once", mf.Name)
+		}
+
+		model := &Model{
+			Name:          mf.Name,
+			Fields:        make([]*Field, 0, len(mf.Fields)),
+			Relationships: make([]*Relationship, 0, len(mf.Relationships)),
+		}
+
+		for _, ff := range mf.Fields {
+			typ, err := ParseTypeString(ff.Type)
+			if err != nil {
+				return nil, fmt.Errorf("model %s, field %s: %w", mf.Name, ff.Name, err)
+			}
+
+			model.Fields = append(model.Fields, &Field{
+				Name:     ff.Name,
+				Type:     typ,
+				Required: ff.Required,
+				Unique:   ff.Unique,
+				Indexed:  ff.Indexed,
+			})
+		}
+
+		for _, rf := range mf.Relationships {
+			cardinality, err := ParseRelCardinality(rf.Cardinality)
+			if err != nil {
+				return nil, fmt.Errorf("model %s, relationship %s: %w", mf.Name, rf.Name, err)
+			}
+
+			model.Relationships = append(model.Relationships, &Relationship{
+				Name:        rf.Name,
+				RelType:     rf.RelType,
+				Target:      rf.Target,
+				Many:        rf.Many,
+				Direction:   Direction(rf.Direction),
+				Cardinality: cardinality,
+			})
+		}
+
+		schema.Models[model.Name] = model
+	}
+
+	return schema, nil
+}
+
+// WriteSchemaHCL writes a TypeSchema as HCL to the given writer, with a
+// model block per model in name order. Fields and relationships are written
+// in declaration order, and false or empty optional attributes are omitted.
+func WriteSchemaHCL(w io.Writer, schema *TypeSchema) error {
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+
+	for i, name := range sortedModelNames(schema) {
+		if i > 0 {
+			body.AppendNewline()
+		}
+
+		modelBody := body.AppendNewBlock("model", []string{name}).Body()
+
+		model := schema.Models[name]
+		if model == nil {
+			continue
+		}
+
+		for _, field := range model.Fields {
+			fieldBody := modelBody.AppendNewBlock("field", []string{field.Name}).Body()
+			fieldBody.SetAttributeValue("type", cty.StringVal(field.Type.String()))
+
+			if field.Required {
+				fieldBody.SetAttributeValue("required", cty.True)
+			}
+
+			if field.Unique {
+				fieldBody.SetAttributeValue("unique", cty.True)
+			}
+
+			if field.Indexed {
+				fieldBody.SetAttributeValue("indexed", cty.True)
+			}
+		}
+
+		for _, rel := range model.Relationships {
+			relBody := modelBody.AppendNewBlock("relationship", []string{rel.Name}).Body()
+			relBody.SetAttributeValue("rel_type", cty.StringVal(rel.RelType))
+			relBody.SetAttributeValue("target", cty.StringVal(rel.Target))
+
+			if rel.Many {
+				relBody.SetAttributeValue("many", cty.True)
+			}
+
+			relBody.SetAttributeValue("direction", cty.StringVal(string(rel.Direction)))
+
+			if cardinality := rel.Cardinality.String(); cardinality != "" {
+				relBody.SetAttributeValue("cardinality", cty.StringVal(cardinality))
+			}
+		}
+	}
+
+	_, err := w.Write(hclwrite.Format(file.Bytes()))
+
+	return err
 }
 
 // Type aliases - re-export from main scaf package for backward compatibility.
 // These allow existing code using analysis.Type to continue working.
 type (
-	TypeKind = scaf.TypeKind
-	Type     = scaf.Type
+	TypeKind    = scaf.TypeKind
+	Type        = scaf.Type
+	StructField = scaf.StructField
 )
 
 // Type kind constants - re-exported from main package.
@@ -212,6 +765,7 @@ const (
 	TypeKindMap       = scaf.TypeKindMap
 	TypeKindPointer   = scaf.TypeKindPointer
 	TypeKindNamed     = scaf.TypeKindNamed
+	TypeKindStruct    = scaf.TypeKindStruct
 )
 
 // ParseTypeString parses a Go-style type string into a Type.
@@ -227,12 +781,32 @@ var (
 	TypeBool    = scaf.TypeBool
 )
 
+// Temporal and spatial types - re-exported from main package.
+var (
+	TypeDate          = scaf.TypeDate
+	TypeTime          = scaf.TypeTime
+	TypeDateTime      = scaf.TypeDateTime
+	TypeLocalDateTime = scaf.TypeLocalDateTime
+	TypeLocalTime     = scaf.TypeLocalTime
+	TypeDuration      = scaf.TypeDuration
+	TypePoint         = scaf.TypePoint
+	TypePoint3D       = scaf.TypePoint3D
+)
+
+// Graph structure types - re-exported from main package.
+var (
+	TypePath         = scaf.TypePath
+	TypeNode         = scaf.TypeNode
+	TypeRelationship = scaf.TypeRelationship
+)
+
 // Type constructors - re-exported from main package.
 var (
 	SliceOf   = scaf.SliceOf
 	PointerTo = scaf.PointerTo
 	MapOf     = scaf.MapOf
 	NamedType = scaf.NamedType
+	StructOf  = scaf.StructOf
 )
 
 // Model represents a database entity (node, relationship, table, etc.).
@@ -248,6 +822,12 @@ type Model struct {
 	// Relationships are edges from this model to other models.
 	// Only applicable for node-like models in graph databases.
 	Relationships []*Relationship
+
+	// NestedFields are the properties inside struct-valued fields, named by
+	// their dotted path (e.g., "address.city"). They aren't properties of the
+	// model itself, so they're kept out of Fields and aren't written to
+	// schema files; they only drive completion after a nested path.
+	NestedFields []*Field
 }
 
 // Field represents a property/column on a model.
@@ -264,6 +844,10 @@ type Field struct {
 	// Unique indicates whether this field has a uniqueness constraint.
 	// When a query filters on a unique field with equality, it returns at most one row.
 	Unique bool
+
+	// Indexed indicates whether this field has an index, so filtering on it
+	// doesn't scan every entity with the model's label.
+	Indexed bool
 }
 
 // Relationship represents an edge from one model to another.
@@ -285,6 +869,50 @@ type Relationship struct {
 
 	// Direction is the relationship direction.
 	Direction Direction
+
+	// Cardinality constrains how many of these relationships a source node
+	// has in the database. Unlike Many, which mirrors the Go field type, it
+	// describes the data, and is zero when unknown.
+	Cardinality RelCardinality
+}
+
+// RelCardinality is the number of relationships a source node may have.
+// The zero value means the cardinality is unknown.
+type RelCardinality int
+
+const (
+	// CardinalityOne means a source node has at most one such relationship.
+	CardinalityOne RelCardinality = iota + 1
+	// CardinalityMany means a source node may have any number of them.
+	CardinalityMany
+)
+
+// String returns the name used for the cardinality in schema files:
+// "one", "many", or "" when unknown.
+func (c RelCardinality) String() string {
+	switch c {
+	case CardinalityOne:
+		return "one"
+	case CardinalityMany:
+		return "many"
+	default:
+		return ""
+	}
+}
+
+// ParseRelCardinality parses a cardinality name as written in schema files.
+// An empty name is the unknown cardinality.
+func ParseRelCardinality(name string) (RelCardinality, error) {
+	switch name {
+	case "":
+		return 0, nil
+	case "one":
+		return CardinalityOne, nil
+	case "many":
+		return CardinalityMany, nil
+	default:
+		return 0, fmt.Errorf("unknown cardinality %q (want \"one\" or \"many\")", name)
+	}
 }
 
 // Direction represents the direction of a relationship.
@@ -355,3 +983,17 @@ type SchemaAwareAnalyzer interface {
 	// If schema is nil, behaves like AnalyzeQuery with ReturnsOne = false.
 	AnalyzeQueryWithSchema(query string, schema *TypeSchema) (*scaf.QueryMetadata, error)
 }
+
+// ParameterAwareAnalyzer extends scaf.QueryAnalyzer with checking of declared
+// parameter types against how parameters are used in the query body.
+//
+// The param-type-mismatch rule uses this, when available, to report
+// declarations that conflict with the query as well as test values that
+// conflict with the declarations.
+type ParameterAwareAnalyzer interface {
+	// AnalyzeQueryWithParameters extracts metadata like AnalyzeQueryWithSchema
+	// and reports conflicts between params and their usage in Warnings, with
+	// the parameter's name in WarningDetails.
+	// schema may be nil.
+	AnalyzeQueryWithParameters(query string, params map[string]*scaf.TypeExpr, schema *TypeSchema) (*scaf.QueryMetadata, error)
+}
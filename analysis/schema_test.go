@@ -28,6 +28,7 @@ models:
       name:
         type: string
         required: true
+        indexed: true
       age:
         type: int
         required: true
@@ -60,6 +61,10 @@ models:
 	assert.True(t, idField.Required)
 	assert.Equal(t, "string", idField.Type.Name)
 	assert.Equal(t, TypeKindPrimitive, idField.Type.Kind)
+
+	for _, f := range user.Fields {
+		assert.Equal(t, f.Name == "name", f.Indexed, "field %s indexed", f.Name)
+	}
 }
 
 func TestLoadSchemaWithRelationships(t *testing.T) {
@@ -81,6 +86,7 @@ models:
         target: Movie
         many: true
         direction: outgoing
+        cardinality: many
   Movie:
     fields:
       title:
@@ -106,6 +112,7 @@ models:
 	assert.Equal(t, "Movie", rel.Target)
 	assert.True(t, rel.Many)
 	assert.Equal(t, DirectionOutgoing, rel.Direction)
+	assert.Equal(t, CardinalityMany, rel.Cardinality)
 
 	// Verify Movie model
 	movie, ok := schema.Models["Movie"]
@@ -303,6 +310,41 @@ func TestWriteSchema(t *testing.T) {
 	assert.Contains(t, output, "direction: outgoing")
 }
 
+func TestLoadSchemaInvalidCardinality(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.yaml")
+
+	schemaYAML := `
+models:
+  Person:
+    relationships:
+      Knows:
+        rel_type: KNOWS
+        target: Person
+        direction: outgoing
+        cardinality: few
+`
+
+	err := os.WriteFile(schemaPath, []byte(schemaYAML), 0o644)
+	require.NoError(t, err)
+
+	_, err = LoadSchema(schemaPath, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `model Person, relationship Knows: unknown cardinality "few"`)
+}
+
+func TestRelCardinalityString(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []RelCardinality{0, CardinalityOne, CardinalityMany} {
+		parsed, err := ParseRelCardinality(c.String())
+		require.NoError(t, err)
+		assert.Equal(t, c, parsed)
+	}
+}
+
 func TestWriteSchemaRoundTrip(t *testing.T) {
 	t.Parallel()
 
@@ -313,16 +355,17 @@ func TestWriteSchemaRoundTrip(t *testing.T) {
 				Name: "User",
 				Fields: []*Field{
 					{Name: "id", Type: TypeString, Required: true, Unique: true},
-					{Name: "emails", Type: SliceOf(TypeString), Required: true},
+					{Name: "emails", Type: SliceOf(TypeString), Required: true, Indexed: true},
 					{Name: "metadata", Type: MapOf(TypeString, TypeString)},
 				},
 				Relationships: []*Relationship{
 					{
-						Name:      "Friends",
-						RelType:   "FRIENDS_WITH",
-						Target:    "User",
-						Many:      true,
-						Direction: DirectionOutgoing,
+						Name:        "Friends",
+						RelType:     "FRIENDS_WITH",
+						Target:      "User",
+						Many:        true,
+						Direction:   DirectionOutgoing,
+						Cardinality: CardinalityMany,
 					},
 				},
 			},
@@ -367,6 +410,7 @@ func TestWriteSchemaRoundTrip(t *testing.T) {
 	assert.Equal(t, "Friends", rel.Name)
 	assert.Equal(t, "FRIENDS_WITH", rel.RelType)
 	assert.Equal(t, DirectionOutgoing, rel.Direction)
+	assert.Equal(t, CardinalityMany, rel.Cardinality)
 }
 
 func TestWriteSchemaEmptyModel(t *testing.T) {
@@ -398,6 +442,716 @@ func TestWriteSchemaEmptyModel(t *testing.T) {
 	require.NotNil(t, loaded.Models["Empty"])
 }
 
+func TestLoadSchemaJSON(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.json")
+
+	schemaJSON := `{
+  "models": {
+    "User": {
+      "fields": {
+        "id": {"type": "string", "required": true, "unique": true},
+        "name": {"type": "string", "required": true},
+        "age": {"type": "int", "required": true}
+      }
+    }
+  }
+}`
+
+	err := os.WriteFile(schemaPath, []byte(schemaJSON), 0o644)
+	require.NoError(t, err)
+
+	schema, err := LoadSchema(schemaPath, "")
+	require.NoError(t, err)
+	require.NotNil(t, schema)
+
+	user, ok := schema.Models["User"]
+	require.True(t, ok, "User model should exist")
+	assert.Equal(t, "User", user.Name)
+	assert.Len(t, user.Fields, 3)
+
+	var idField *Field
+	for _, f := range user.Fields {
+		if f.Name == "id" {
+			idField = f
+
+			break
+		}
+	}
+	require.NotNil(t, idField)
+	assert.True(t, idField.Unique)
+	assert.True(t, idField.Required)
+	assert.Equal(t, "string", idField.Type.Name)
+	assert.Equal(t, TypeKindPrimitive, idField.Type.Kind)
+}
+
+func TestLoadSchemaJSONWithRelationships(t *testing.T) {
+	t.Parallel()
+
+	schemaJSON := `{
+  "models": {
+    "Person": {
+      "fields": {"name": {"type": "string", "required": true}},
+      "relationships": {
+        "ActedIn": {"rel_type": "ACTED_IN", "target": "Movie", "many": true, "direction": "outgoing"}
+      }
+    },
+    "Movie": {
+      "fields": {"title": {"type": "string", "required": true}}
+    }
+  }
+}`
+
+	schema, err := ReadSchemaJSON(strings.NewReader(schemaJSON))
+	require.NoError(t, err)
+	require.NotNil(t, schema)
+
+	person, ok := schema.Models["Person"]
+	require.True(t, ok)
+	assert.Len(t, person.Relationships, 1)
+
+	rel := person.Relationships[0]
+	assert.Equal(t, "ActedIn", rel.Name)
+	assert.Equal(t, "ACTED_IN", rel.RelType)
+	assert.Equal(t, "Movie", rel.Target)
+	assert.True(t, rel.Many)
+	assert.Equal(t, DirectionOutgoing, rel.Direction)
+
+	movie, ok := schema.Models["Movie"]
+	require.True(t, ok)
+	assert.Len(t, movie.Relationships, 0)
+}
+
+func TestLoadSchemaJSONComplexTypes(t *testing.T) {
+	t.Parallel()
+
+	schemaJSON := `{
+  "models": {
+    "TestModel": {
+      "fields": {
+        "stringSlice": {"type": "[]string", "required": true},
+        "intPointer": {"type": "*int"},
+        "stringMap": {"type": "map[string]int"},
+        "namedType": {"type": "time.Time"},
+        "intArray": {"type": "[5]int"},
+        "nestedSlice": {"type": "[][]string"}
+      }
+    }
+  }
+}`
+
+	schema, err := ReadSchemaJSON(strings.NewReader(schemaJSON))
+	require.NoError(t, err)
+
+	model, ok := schema.Models["TestModel"]
+	require.True(t, ok)
+
+	fieldMap := make(map[string]*Field)
+	for _, f := range model.Fields {
+		fieldMap[f.Name] = f
+	}
+
+	assert.Equal(t, SliceOf(TypeString).String(), fieldMap["stringSlice"].Type.String())
+	assert.Equal(t, TypeKindPointer, fieldMap["intPointer"].Type.Kind)
+	assert.Equal(t, TypeKindMap, fieldMap["stringMap"].Type.Kind)
+	assert.Equal(t, "time", fieldMap["namedType"].Type.Package)
+	assert.Equal(t, 5, fieldMap["intArray"].Type.ArrayLen)
+	assert.Equal(t, TypeKindSlice, fieldMap["nestedSlice"].Type.Elem.Kind)
+}
+
+func TestLoadSchemaJSONRelativePath(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(tmpDir, "schema.json"), []byte(`{"models": {"Empty": {}}}`), 0o644)
+	require.NoError(t, err)
+
+	schema, err := LoadSchema("schema.json", tmpDir)
+	require.NoError(t, err)
+	require.NotNil(t, schema.Models["Empty"])
+}
+
+func TestLoadSchemaJSONInvalid(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "invalid.json")
+
+	// Valid YAML, but not JSON: the extension decides the format
+	err := os.WriteFile(schemaPath, []byte("models:\n  Empty: {}\n"), 0o644)
+	require.NoError(t, err)
+
+	_, err = LoadSchema(schemaPath, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parsing schema")
+}
+
+func TestLoadSchemaJSONInvalidType(t *testing.T) {
+	t.Parallel()
+
+	_, err := ReadSchemaJSON(strings.NewReader(`{"models": {"User": {"fields": {"id": {"type": "[abc]int"}}}}}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model User, field id")
+}
+
+func TestWriteSchemaJSON(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*Field{
+					{Name: "id", Type: TypeString, Required: true, Unique: true},
+					{Name: "age", Type: TypeInt},
+				},
+				Relationships: []*Relationship{
+					{Name: "ActedIn", RelType: "ACTED_IN", Target: "Movie", Many: true, Direction: DirectionOutgoing},
+				},
+			},
+			"Movie": {
+				Name:   "Movie",
+				Fields: []*Field{{Name: "genres", Type: SliceOf(TypeString)}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSchemaJSON(&buf, schema)
+	require.NoError(t, err)
+
+	want := `{
+  "$schema": "https://raw.githubusercontent.com/rlch/scaf/main/.scaf-type.schema.json",
+  "models": {
+    "Movie": {
+      "fields": {
+        "genres": {
+          "type": "[]string"
+        }
+      }
+    },
+    "Person": {
+      "fields": {
+        "age": {
+          "type": "int"
+        },
+        "id": {
+          "type": "string",
+          "required": true,
+          "unique": true
+        }
+      },
+      "relationships": {
+        "ActedIn": {
+          "rel_type": "ACTED_IN",
+          "target": "Movie",
+          "many": true,
+          "direction": "outgoing"
+        }
+      }
+    }
+  }
+}
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteSchemaJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	original := &TypeSchema{
+		Models: map[string]*Model{
+			"User": {
+				Name: "User",
+				Fields: []*Field{
+					{Name: "id", Type: TypeString, Required: true, Unique: true},
+					{Name: "emails", Type: SliceOf(TypeString), Required: true},
+					{Name: "metadata", Type: MapOf(TypeString, TypeString)},
+				},
+				Relationships: []*Relationship{
+					{Name: "Friends", RelType: "FRIENDS_WITH", Target: "User", Many: true, Direction: DirectionOutgoing},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSchemaJSON(&buf, original)
+	require.NoError(t, err)
+
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.json")
+	err = os.WriteFile(schemaPath, buf.Bytes(), 0o644)
+	require.NoError(t, err)
+
+	loaded, err := LoadSchema(schemaPath, "")
+	require.NoError(t, err)
+	require.Len(t, loaded.Models, 1)
+
+	user := loaded.Models["User"]
+	require.NotNil(t, user)
+	assert.Len(t, user.Fields, 3)
+	assert.Len(t, user.Relationships, 1)
+
+	fieldMap := make(map[string]*Field)
+	for _, f := range user.Fields {
+		fieldMap[f.Name] = f
+	}
+
+	assert.Equal(t, TypeKindPrimitive, fieldMap["id"].Type.Kind)
+	assert.True(t, fieldMap["id"].Unique)
+	assert.Equal(t, TypeKindSlice, fieldMap["emails"].Type.Kind)
+	assert.Equal(t, TypeKindMap, fieldMap["metadata"].Type.Kind)
+
+	rel := user.Relationships[0]
+	assert.Equal(t, "Friends", rel.Name)
+	assert.Equal(t, "FRIENDS_WITH", rel.RelType)
+	assert.Equal(t, DirectionOutgoing, rel.Direction)
+}
+
+func TestWriteSchemaJSONEmptyModel(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Empty": {Name: "Empty"},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSchemaJSON(&buf, schema)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `"Empty": {}`)
+
+	loaded, err := ReadSchemaJSON(&buf)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.Models["Empty"])
+}
+
+func TestSchemaJSONMatchesYAML(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*Field{
+					{Name: "name", Type: TypeString, Required: true},
+					{Name: "born", Type: TypeDate},
+				},
+				Relationships: []*Relationship{
+					{Name: "Knows", RelType: "KNOWS", Target: "Person", Many: true, Direction: DirectionOutgoing},
+				},
+			},
+			"Empty": {Name: "Empty"},
+		},
+	}
+
+	var yamlBuf, jsonBuf bytes.Buffer
+	require.NoError(t, WriteSchema(&yamlBuf, schema))
+	require.NoError(t, WriteSchemaJSON(&jsonBuf, schema))
+
+	tmpDir := t.TempDir()
+	yamlPath := filepath.Join(tmpDir, "schema.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, yamlBuf.Bytes(), 0o644))
+
+	fromYAML, err := LoadSchema(yamlPath, "")
+	require.NoError(t, err)
+
+	fromJSON, err := ReadSchemaJSON(&jsonBuf)
+	require.NoError(t, err)
+
+	// Re-serialize both so field order within a model doesn't matter
+	var a, b bytes.Buffer
+	require.NoError(t, WriteSchemaJSON(&a, fromYAML))
+	require.NoError(t, WriteSchemaJSON(&b, fromJSON))
+	assert.Equal(t, a.String(), b.String())
+}
+
+func TestWriteSchemaHCL(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*Field{
+					{Name: "id", Type: TypeString, Required: true, Unique: true},
+					{Name: "age", Type: TypeInt},
+				},
+				Relationships: []*Relationship{
+					{Name: "ActedIn", RelType: "ACTED_IN", Target: "Movie", Many: true, Direction: DirectionOutgoing, Cardinality: CardinalityMany},
+				},
+			},
+			"Movie": {
+				Name:   "Movie",
+				Fields: []*Field{{Name: "genres", Type: SliceOf(TypeString)}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSchemaHCL(&buf, schema)
+	require.NoError(t, err)
+
+	want := `model "Movie" {
+  field "genres" {
+    type = "[]string"
+  }
+}
+
+model "Person" {
+  field "id" {
+    type     = "string"
+    required = true
+    unique   = true
+  }
+  field "age" {
+    type = "int"
+  }
+  relationship "ActedIn" {
+    rel_type    = "ACTED_IN"
+    target      = "Movie"
+    many        = true
+    direction   = "outgoing"
+    cardinality = "many"
+  }
+}
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteSchemaHCLRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	original := &TypeSchema{
+		Models: map[string]*Model{
+			"User": {
+				Name: "User",
+				Fields: []*Field{
+					{Name: "id", Type: TypeString, Required: true, Unique: true},
+					{Name: "emails", Type: SliceOf(TypeString), Required: true},
+					{Name: "metadata", Type: MapOf(TypeString, TypeString)},
+				},
+				Relationships: []*Relationship{
+					{Name: "Friends", RelType: "FRIENDS_WITH", Target: "User", Many: true, Direction: DirectionOutgoing},
+				},
+			},
+			"Empty": {Name: "Empty"},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSchemaHCL(&buf, original)
+	require.NoError(t, err)
+
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, ".scaf-schema.hcl")
+	err = os.WriteFile(schemaPath, buf.Bytes(), 0o644)
+	require.NoError(t, err)
+
+	loaded, err := LoadSchema(schemaPath, "")
+	require.NoError(t, err)
+	require.Len(t, loaded.Models, 2)
+	require.NotNil(t, loaded.Models["Empty"])
+
+	// HCL blocks keep declaration order, so the loaded model matches exactly
+	assert.Equal(t, original.Models["User"], loaded.Models["User"])
+}
+
+func TestReadSchemaHCLErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{
+			name:    "syntax error",
+			input:   `model "User" {`,
+			wantErr: "parsing schema",
+		},
+		{
+			name:    "missing type",
+			input:   "model \"User\" {\n  field \"id\" {}\n}",
+			wantErr: `Missing required argument`,
+		},
+		{
+			name:    "invalid type",
+			input:   "model \"User\" {\n  field \"id\" {\n    type = \"[abc]int\"\n  }\n}",
+			wantErr: "model User, field id",
+		},
+		{
+			name: "invalid cardinality",
+			input: `model "User" {
+  relationship "Friends" {
+    rel_type    = "FRIENDS"
+    target      = "User"
+    direction   = "outgoing"
+    cardinality = "some"
+  }
+}`,
+			wantErr: "model User, relationship Friends",
+		},
+		{
+			name:    "duplicate model",
+			input:   "model \"User\" {}\nmodel \"User\" {}",
+			wantErr: "model User: defined more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ReadSchemaHCL(strings.NewReader(tt.input))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTypeSchemaRelationships(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Relationships: []*Relationship{
+					{Name: "ActedIn", RelType: "ACTED_IN", Target: "Movie", Direction: DirectionOutgoing},
+					{Name: "Knows", RelType: "KNOWS", Target: "Person", Direction: DirectionOutgoing},
+					{Name: "Untyped", Target: "Person"},
+				},
+			},
+			"Actor": {
+				Name: "Actor",
+				Relationships: []*Relationship{
+					{Name: "ActedIn", RelType: "ACTED_IN", Target: "Movie", Direction: DirectionOutgoing},
+				},
+			},
+			"Movie": {Name: "Movie"},
+		},
+	}
+
+	rels := schema.Relationships()
+	require.Len(t, rels, 2)
+
+	actedIn := rels["ACTED_IN"]
+	require.Len(t, actedIn, 2)
+	assert.Equal(t, "Actor", actedIn[0].SourceModel)
+	assert.Equal(t, "Person", actedIn[1].SourceModel)
+	assert.Equal(t, "Movie", actedIn[1].Target)
+
+	require.Len(t, rels["KNOWS"], 1)
+	assert.Equal(t, "Knows", rels["KNOWS"][0].Name)
+
+	assert.Empty(t, (*TypeSchema)(nil).Relationships())
+}
+
+func TestTypeSchemaAllFields(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*Field{
+					{Name: "name", Type: TypeString},
+					{Name: "age", Type: TypeInt},
+				},
+			},
+			"Movie": {
+				Name:   "Movie",
+				Fields: []*Field{{Name: "title", Type: TypeString}},
+			},
+		},
+	}
+
+	var got []string
+	for _, field := range schema.AllFields() {
+		got = append(got, field.ModelName+"."+field.Name)
+	}
+
+	assert.Equal(t, []string{"Movie.title", "Person.name", "Person.age"}, got)
+	assert.Nil(t, (*TypeSchema)(nil).AllFields())
+}
+
+func TestTypeSchemaMerge(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*Field{
+					{Name: "id", Type: TypeString, Required: true},
+					{Name: "name", Type: TypeString},
+				},
+				Relationships: []*Relationship{
+					{Name: "Friends", RelType: "FRIENDS", Target: "Person", Many: true, Direction: DirectionOutgoing},
+				},
+			},
+			"Movie": {Name: "Movie", Fields: []*Field{{Name: "title", Type: TypeString}}},
+		},
+	}
+
+	other := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*Field{
+					{Name: "name", Type: TypeString, Required: true},
+					{Name: "age", Type: TypeInt},
+				},
+				Relationships: []*Relationship{
+					{Name: "Friends", RelType: "KNOWS", Target: "Person", Many: true, Direction: DirectionOutgoing},
+					{Name: "Likes", RelType: "LIKES", Target: "Movie", Many: true, Direction: DirectionOutgoing},
+				},
+				NestedFields: []*Field{{Name: "address.city", Type: TypeString}},
+			},
+			"Country": {Name: "Country", Fields: []*Field{{Name: "code", Type: TypeString}}},
+		},
+	}
+
+	require.NoError(t, schema.Merge(other))
+
+	require.Len(t, schema.Models, 3)
+	assert.Contains(t, schema.Models, "Movie")
+	assert.Contains(t, schema.Models, "Country")
+
+	person := schema.Models["Person"]
+	require.Len(t, person.Fields, 3)
+	assert.Equal(t, "id", person.Fields[0].Name)
+	assert.Equal(t, "name", person.Fields[1].Name)
+	assert.True(t, person.Fields[1].Required, "other's definition of name should win")
+	assert.Equal(t, "age", person.Fields[2].Name)
+
+	require.Len(t, person.Relationships, 2)
+	assert.Equal(t, "KNOWS", person.Relationships[0].RelType, "other's definition of Friends should win")
+	assert.Equal(t, "Likes", person.Relationships[1].Name)
+
+	require.Len(t, person.NestedFields, 1)
+	assert.Equal(t, "address.city", person.NestedFields[0].Name)
+
+	// Merging must not alias other's slices
+	person.Fields = append(person.Fields, &Field{Name: "extra", Type: TypeBool})
+	assert.Len(t, other.Models["Person"].Fields, 2)
+}
+
+func TestTypeSchemaMergeConflict(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {Name: "Person", Fields: []*Field{
+				{Name: "age", Type: TypeInt},
+				{Name: "born", Type: TypeDate},
+				{Name: "name", Type: TypeString},
+			}},
+		},
+	}
+
+	other := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {Name: "Person", Fields: []*Field{
+				{Name: "name", Type: TypeString},
+				{Name: "born", Type: TypeString},
+				{Name: "age", Type: TypeString},
+			}},
+			"Movie": {Name: "Movie"},
+		},
+	}
+
+	err := schema.Merge(other)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model Person: conflicting types for fields age, born")
+	assert.NotContains(t, err.Error(), "name")
+
+	// A failed merge leaves the schema unchanged
+	assert.NotContains(t, schema.Models, "Movie")
+	assert.Equal(t, TypeInt, schema.Models["Person"].Fields[0].Type)
+}
+
+func TestTypeSchemaMergeNil(t *testing.T) {
+	t.Parallel()
+
+	schema := NewTypeSchema()
+	require.NoError(t, schema.Merge(nil))
+
+	empty := &TypeSchema{}
+	require.NoError(t, empty.Merge(&TypeSchema{Models: map[string]*Model{"A": {Name: "A"}}}))
+	assert.Contains(t, empty.Models, "A")
+}
+
+func TestTypeSchemaValidate(t *testing.T) {
+	t.Parallel()
+
+	schema := &TypeSchema{
+		Models: map[string]*Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*Field{
+					{Name: "name", Type: TypeString},
+					{Name: "tags", Type: &Type{Kind: TypeKindSlice}},
+					{Name: "name", Type: TypeString},
+					{Name: "scores", Type: MapOf(TypeString, &Type{Kind: TypeKindPointer})},
+					{Name: "nickname"},
+				},
+				Relationships: []*Relationship{
+					{Name: "Friends", RelType: "FRIENDS", Target: "Person"},
+					{Name: "WorksAt", RelType: "WORKS_AT", Target: "Company"},
+				},
+			},
+			"Movie": {
+				Name:   "Movie",
+				Fields: []*Field{{Name: "released", Type: &Type{Kind: "tuple"}}},
+			},
+			"Tag": {Name: "Tag", Fields: []*Field{{Name: "label", Type: SliceOf(TypeString)}}},
+		},
+	}
+
+	assert.Equal(t, []SchemaError{
+		{ModelName: "Movie", FieldName: "released", Message: `unknown type kind "tuple"`},
+		{ModelName: "Person", FieldName: "tags", Message: "slice type has no element type"},
+		{ModelName: "Person", FieldName: "name", Message: "duplicate field"},
+		{ModelName: "Person", FieldName: "scores", Message: "pointer type has no element type"},
+		{ModelName: "Person", FieldName: "nickname", Message: "missing type"},
+		{ModelName: "Person", FieldName: "WorksAt", Message: `relationship target "Company" does not exist`},
+	}, schema.Validate())
+}
+
+func TestTypeSchemaValidateLoaded(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+models:
+  Person:
+    fields:
+      name:
+        type: string
+    relationships:
+      ActedIn:
+        rel_type: ACTED_IN
+        target: Movie
+        direction: outgoing
+`), 0o600))
+
+	schema, err := LoadSchema(path, "")
+	require.NoError(t, err)
+
+	errs := schema.Validate()
+	require.Len(t, errs, 1)
+	assert.Equal(t, `model Person, field ActedIn: relationship target "Movie" does not exist`, errs[0].Error())
+
+	assert.Empty(t, NewTypeSchema().Validate())
+	assert.Empty(t, (*TypeSchema)(nil).Validate())
+}
+
 func TestParseTypeString(t *testing.T) {
 	t.Parallel()
 
@@ -423,6 +1177,13 @@ func TestParseTypeString(t *testing.T) {
 		{"map string to bool", "map[string]bool", MapOf(TypeString, TypeBool), false},
 		{"named type time.Time", "time.Time", NamedType("time", "Time"), false},
 		{"named type uuid.UUID", "uuid.UUID", NamedType("uuid", "UUID"), false},
+		{"database date", "date", TypeDate, false},
+		{"database datetime", "datetime", TypeDateTime, false},
+		{"database duration", "duration", TypeDuration, false},
+		{"database point3d", "point3d", TypePoint3D, false},
+		{"database path", "path", TypePath, false},
+		{"slice of database node pointers", "[]*node", SliceOf(PointerTo(TypeNode)), false},
+		{"slice of database date", "[]date", SliceOf(TypeDate), false},
 		{"empty string", "", nil, true},
 		{"invalid array syntax", "[abc]int", nil, true},
 	}
@@ -0,0 +1,175 @@
+package analysis
+
+import (
+	"maps"
+	"slices"
+)
+
+// SchemaDiff describes how a schema changed between two versions.
+// Model and field names are sorted so diffs print stably.
+type SchemaDiff struct {
+	// AddedModels are models only in the new schema.
+	AddedModels []string
+
+	// RemovedModels are models only in the old schema.
+	RemovedModels []string
+
+	// ModifiedModels maps the name of each model in both schemas whose
+	// fields or relationships changed to what changed.
+	ModifiedModels map[string]*ModelDiff
+}
+
+// ModelDiff describes how a model present in both schemas changed.
+type ModelDiff struct {
+	// AddedFields are fields only in the new model.
+	AddedFields []string
+
+	// RemovedFields are fields only in the old model.
+	RemovedFields []string
+
+	// ChangedFieldTypes maps fields in both models whose type changed to
+	// their old and new types.
+	ChangedFieldTypes map[string][2]*Type
+
+	// AddedRelationships are relationships only in the new model.
+	AddedRelationships []string
+
+	// RemovedRelationships are relationships only in the old model.
+	RemovedRelationships []string
+}
+
+// IsEmpty reports whether the schemas were equivalent.
+func (d *SchemaDiff) IsEmpty() bool {
+	return d == nil || len(d.AddedModels) == 0 && len(d.RemovedModels) == 0 && len(d.ModifiedModels) == 0
+}
+
+// ModifiedModelNames returns the names of modified models in sorted order.
+func (d *SchemaDiff) ModifiedModelNames() []string {
+	return slices.Sorted(maps.Keys(d.ModifiedModels))
+}
+
+// IsEmpty reports whether the model is unchanged.
+func (d *ModelDiff) IsEmpty() bool {
+	return len(d.AddedFields) == 0 && len(d.RemovedFields) == 0 && len(d.ChangedFieldTypes) == 0 &&
+		len(d.AddedRelationships) == 0 && len(d.RemovedRelationships) == 0
+}
+
+// DiffWith compares s, the old schema, with newSchema. Fields and
+// relationships are matched by name, so a renamed property shows as one
+// removed and one added field. Field types are compared by their String form.
+func (s *TypeSchema) DiffWith(newSchema *TypeSchema) *SchemaDiff {
+	if s == nil {
+		s = NewTypeSchema()
+	}
+
+	if newSchema == nil {
+		newSchema = NewTypeSchema()
+	}
+
+	diff := &SchemaDiff{ModifiedModels: make(map[string]*ModelDiff)}
+
+	for _, name := range sortedModelNames(s) {
+		newModel, ok := newSchema.Models[name]
+		if !ok {
+			diff.RemovedModels = append(diff.RemovedModels, name)
+
+			continue
+		}
+
+		if md := diffModels(s.Models[name], newModel); !md.IsEmpty() {
+			diff.ModifiedModels[name] = md
+		}
+	}
+
+	for _, name := range sortedModelNames(newSchema) {
+		if _, ok := s.Models[name]; !ok {
+			diff.AddedModels = append(diff.AddedModels, name)
+		}
+	}
+
+	return diff
+}
+
+// diffModels compares two versions of a model.
+func diffModels(oldModel, newModel *Model) *ModelDiff {
+	md := &ModelDiff{ChangedFieldTypes: make(map[string][2]*Type)}
+
+	oldFields := fieldsByName(oldModel)
+	newFields := fieldsByName(newModel)
+
+	for _, name := range slices.Sorted(maps.Keys(oldFields)) {
+		newField, ok := newFields[name]
+		if !ok {
+			md.RemovedFields = append(md.RemovedFields, name)
+
+			continue
+		}
+
+		oldType, newType := oldFields[name].Type, newField.Type
+		if typeString(oldType) != typeString(newType) {
+			md.ChangedFieldTypes[name] = [2]*Type{oldType, newType}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(newFields)) {
+		if _, ok := oldFields[name]; !ok {
+			md.AddedFields = append(md.AddedFields, name)
+		}
+	}
+
+	oldRels := relationshipsByName(oldModel)
+	newRels := relationshipsByName(newModel)
+
+	for _, name := range slices.Sorted(maps.Keys(oldRels)) {
+		if _, ok := newRels[name]; !ok {
+			md.RemovedRelationships = append(md.RemovedRelationships, name)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(newRels)) {
+		if _, ok := oldRels[name]; !ok {
+			md.AddedRelationships = append(md.AddedRelationships, name)
+		}
+	}
+
+	return md
+}
+
+func fieldsByName(model *Model) map[string]*Field {
+	fields := make(map[string]*Field)
+	if model == nil {
+		return fields
+	}
+
+	for _, f := range model.Fields {
+		if f != nil {
+			fields[f.Name] = f
+		}
+	}
+
+	return fields
+}
+
+func relationshipsByName(model *Model) map[string]*Relationship {
+	rels := make(map[string]*Relationship)
+	if model == nil {
+		return rels
+	}
+
+	for _, r := range model.Relationships {
+		if r != nil {
+			rels[r.Name] = r
+		}
+	}
+
+	return rels
+}
+
+// typeString returns t's String form, or "" for a field with no type.
+func typeString(t *Type) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.String()
+}
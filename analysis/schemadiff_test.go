@@ -0,0 +1,63 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeSchemaDiffWith(t *testing.T) {
+	t.Parallel()
+
+	oldSchema := &TypeSchema{
+		Models: map[string]*Model{
+			"User": {
+				Name: "User",
+				Fields: []*Field{
+					{Name: "id", Type: TypeString},
+					{Name: "email", Type: TypeString},
+					{Name: "age", Type: TypeInt},
+				},
+				Relationships: []*Relationship{
+					{Name: "Likes", RelType: "LIKES", Target: "Comment"},
+				},
+			},
+			"Comment": {Name: "Comment", Fields: []*Field{{Name: "text", Type: TypeString}}},
+			"Tag":     {Name: "Tag", Fields: []*Field{{Name: "label", Type: TypeString}}},
+		},
+	}
+
+	newSchema := &TypeSchema{
+		Models: map[string]*Model{
+			"User": {
+				Name: "User",
+				Fields: []*Field{
+					{Name: "id", Type: TypeString},
+					{Name: "age", Type: TypeInt64},
+					{Name: "bio", Type: PointerTo(TypeString)},
+				},
+				Relationships: []*Relationship{
+					{Name: "Follows", RelType: "FOLLOWS", Target: "User"},
+				},
+			},
+			"Post": {Name: "Post"},
+			"Tag":  {Name: "Tag", Fields: []*Field{{Name: "label", Type: TypeString}}},
+		},
+	}
+
+	diff := oldSchema.DiffWith(newSchema)
+
+	assert.Equal(t, []string{"Post"}, diff.AddedModels)
+	assert.Equal(t, []string{"Comment"}, diff.RemovedModels)
+	assert.Equal(t, []string{"User"}, diff.ModifiedModelNames())
+
+	user := diff.ModifiedModels["User"]
+	assert.Equal(t, []string{"bio"}, user.AddedFields)
+	assert.Equal(t, []string{"email"}, user.RemovedFields)
+	assert.Equal(t, map[string][2]*Type{"age": {TypeInt, TypeInt64}}, user.ChangedFieldTypes)
+	assert.Equal(t, []string{"Follows"}, user.AddedRelationships)
+	assert.Equal(t, []string{"Likes"}, user.RemovedRelationships)
+
+	assert.True(t, newSchema.DiffWith(newSchema).IsEmpty())
+	assert.Equal(t, []string{"Post", "Tag", "User"}, (*TypeSchema)(nil).DiffWith(newSchema).AddedModels)
+}
@@ -0,0 +1,153 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rlch/scaf"
+)
+
+// SchemaCacheFile is the file, next to the config, that LoadConfigSchema
+// caches a schema fetched from scaf.Config.SchemaURL in.
+const SchemaCacheFile = ".scaf-schema-cache.yaml"
+
+// schemaFetchTimeout bounds a request to scaf.Config.SchemaURL.
+const schemaFetchTimeout = 10 * time.Second
+
+// ErrSchemaFetch is returned when a schema URL can't be fetched and no
+// cached copy exists.
+var ErrSchemaFetch = errors.New("fetching schema")
+
+// schemaCacheFile is the serialized form of a cached remote schema. The
+// validators let a re-fetch skip the download when the schema is unchanged.
+type schemaCacheFile struct {
+	URL          string                `yaml:"url"`
+	ETag         string                `yaml:"etag,omitempty"`
+	LastModified string                `yaml:"last_modified,omitempty"`
+	Models       map[string]*modelFile `yaml:"models"`
+}
+
+// LoadConfigSchema loads the schema cfg names. With cfg.SchemaURL set, the
+// schema is fetched as JSON and cached in SchemaCacheFile under baseDir;
+// otherwise cfg.Generate.Schema is loaded relative to baseDir. Returns nil
+// if cfg names no schema.
+//
+// A re-fetch sends the cached ETag and Last-Modified validators and keeps the
+// cache when the server answers 304 Not Modified. If the URL can't be reached
+// or returns an error status, the cached schema is used when there is one.
+func LoadConfigSchema(cfg *scaf.Config, baseDir string) (*TypeSchema, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.SchemaURL == "" {
+		return LoadSchema(cfg.Generate.Schema, baseDir)
+	}
+
+	return fetchSchema(cfg.SchemaURL, filepath.Join(baseDir, SchemaCacheFile))
+}
+
+// LoadSchemaCache loads a schema cached by LoadConfigSchema without
+// contacting its URL.
+func LoadSchemaCache(path string) (*TypeSchema, error) {
+	cache, err := readSchemaCache(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaFileToTypeSchema(&schemaFile{Models: cache.Models})
+}
+
+// fetchSchema fetches the schema at url, revalidating and updating the cache
+// at cachePath. A cache that can't be written is skipped.
+func fetchSchema(url, cachePath string) (*TypeSchema, error) {
+	cache, err := readSchemaCache(cachePath)
+	if err != nil || cache.URL != url {
+		cache = nil
+	}
+
+	cached := func(cause error) (*TypeSchema, error) {
+		if cache == nil {
+			return nil, fmt.Errorf("%w from %s: %w", ErrSchemaFetch, url, cause)
+		}
+
+		return schemaFileToTypeSchema(&schemaFile{Models: cache.Models})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), schemaFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w from %s: %w", ErrSchemaFetch, url, err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if cache != nil {
+		if cache.ETag != "" {
+			req.Header.Set("If-None-Match", cache.ETag)
+		}
+
+		if cache.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cache.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return cached(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// 304 Not Modified only follows validators, so the cache exists
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return cached(errors.New(resp.Status))
+	}
+
+	schema, err := ReadSchemaJSON(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = writeSchemaCache(cachePath, &schemaCacheFile{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Models:       typeSchemaToSchemaFile(schema).Models,
+	})
+
+	return schema, nil
+}
+
+// readSchemaCache reads the cache file at path.
+func readSchemaCache(path string) (*schemaCacheFile, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading schema cache: %w", err)
+	}
+
+	var cache schemaCacheFile
+	if err := yaml.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("parsing schema cache: %w", err)
+	}
+
+	return &cache, nil
+}
+
+// writeSchemaCache writes cache to path.
+func writeSchemaCache(path string, cache *schemaCacheFile) error {
+	data, err := yaml.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rlch/scaf"
+)
+
+func TestLoadConfigSchemaURL(t *testing.T) {
+	t.Parallel()
+
+	const etag = `"v1"`
+
+	var requests, notModified atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(`{"models": {"User": {"fields": {"name": {"type": "string"}}}}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := &scaf.Config{SchemaURL: server.URL + "/schema.json"}
+
+	schema, err := LoadConfigSchema(cfg, dir)
+	require.NoError(t, err)
+	require.Contains(t, schema.Models, "User")
+
+	cachePath := filepath.Join(dir, SchemaCacheFile)
+	cached, err := LoadSchemaCache(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, schema, cached)
+
+	// The second fetch revalidates with the cached ETag
+	schema, err = LoadConfigSchema(cfg, dir)
+	require.NoError(t, err)
+	require.Contains(t, schema.Models, "User")
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, int32(1), notModified.Load())
+
+	// An unreachable URL falls back to the cache
+	server.Close()
+
+	schema, err = LoadConfigSchema(cfg, dir)
+	require.NoError(t, err)
+	require.Contains(t, schema.Models, "User")
+}
+
+func TestLoadConfigSchemaURLErrors(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	_, err := LoadConfigSchema(&scaf.Config{SchemaURL: server.URL}, dir)
+	require.ErrorIs(t, err, ErrSchemaFetch)
+	assert.Contains(t, err.Error(), "503")
+
+	_, err = os.Stat(filepath.Join(dir, SchemaCacheFile))
+	assert.True(t, os.IsNotExist(err), "no cache should be written for a failed fetch")
+}
+
+func TestLoadConfigSchemaFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schema.yaml"), []byte("models:\n  User: {}\n"), 0o644))
+
+	schema, err := LoadConfigSchema(&scaf.Config{Generate: scaf.GenerateConfig{Schema: "schema.yaml"}}, dir)
+	require.NoError(t, err)
+	assert.Contains(t, schema.Models, "User")
+
+	schema, err = LoadConfigSchema(&scaf.Config{}, dir)
+	require.NoError(t, err)
+	assert.Nil(t, schema)
+}
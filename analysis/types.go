@@ -1,6 +1,11 @@
 package analysis
 
 import (
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
 	"github.com/rlch/scaf"
 )
 
@@ -18,6 +23,15 @@ type AnalyzedFile struct {
 	// Diagnostics contains all errors and warnings found during analysis.
 	Diagnostics []Diagnostic
 
+	// Tokens is the file's token stream in source order, without whitespace,
+	// so features that walk tokens needn't lex the file again. Lexing stops
+	// at the first lexer error. Nil when the file exceeds the parse limits.
+	Tokens []lexer.Token
+
+	// CommentSpans are the spans of the file's // comments, excluding
+	// annotations, in source order.
+	CommentSpans []scaf.Span
+
 	// Symbols contains all definitions in this file.
 	Symbols *SymbolTable
 
@@ -31,6 +45,7 @@ type AnalyzedFile struct {
 	RecoveryError error
 
 	// Resolver is used for cross-file analysis (e.g., validating setup calls).
+	// The LSP supplies its own; other tools can use NewFileSystemResolver.
 	// May be nil if cross-file analysis is not available.
 	Resolver CrossFileResolver
 
@@ -43,6 +58,10 @@ type AnalyzedFile struct {
 	// Used for parameter type checking against inferred types.
 	// May be nil if no schema is available.
 	Schema *TypeSchema
+
+	// bodyAnalysisCache memoizes GetBodyAnalysis by query body, including
+	// nil results for queries that failed to analyze.
+	bodyAnalysisCache map[string]*BodyAnalysis
 }
 
 // SymbolTable holds all named definitions in a file.
@@ -168,6 +187,29 @@ type Diagnostic struct {
 	Message  string
 	Code     string // e.g., "undefined-query", "unused-import"
 	Source   string // "scaf"
+	// Suppressed is true when a scaf-disable annotation silences this diagnostic.
+	// Suppressed diagnostics are kept so editors can show them faded.
+	Suppressed bool
+	// Related points to other locations involved, such as the first
+	// definition of a duplicate name.
+	Related []DiagnosticRelatedInformation
+	// AutoFix, if set, is a single edit that resolves the diagnostic.
+	AutoFix *TextEdit
+}
+
+// TextEdit replaces the source in Span with NewText.
+// An empty NewText deletes the span.
+type TextEdit struct {
+	Span    scaf.Span
+	NewText string
+}
+
+// DiagnosticRelatedInformation is a location related to a diagnostic.
+type DiagnosticRelatedInformation struct {
+	Span    scaf.Span
+	Message string
+	// URI is the file containing Span. Empty means the diagnostic's own file.
+	URI string
 }
 
 // DiagnosticSeverity indicates the severity of a diagnostic.
@@ -181,23 +223,55 @@ const (
 	SeverityHint
 )
 
-// HasErrors returns true if there are any error-level diagnostics.
+// HasErrors returns true if there are any unsuppressed error-level diagnostics.
 func (f *AnalyzedFile) HasErrors() bool {
 	for _, d := range f.Diagnostics {
-		if d.Severity == SeverityError {
+		if d.Severity == SeverityError && !d.Suppressed {
 			return true
 		}
 	}
 	return false
 }
 
-// Errors returns all error-level diagnostics.
+// Errors returns all unsuppressed error-level diagnostics.
 func (f *AnalyzedFile) Errors() []Diagnostic {
 	var errors []Diagnostic
 	for _, d := range f.Diagnostics {
-		if d.Severity == SeverityError {
+		if d.Severity == SeverityError && !d.Suppressed {
 			errors = append(errors, d)
 		}
 	}
 	return errors
 }
+
+// InComment reports whether span lies entirely within a // comment.
+func (f *AnalyzedFile) InComment(span scaf.Span) bool {
+	return slices.ContainsFunc(f.CommentSpans, func(comment scaf.Span) bool {
+		return comment.ContainsSpan(span)
+	})
+}
+
+// ExportSymbols returns the symbols other files see when they import f.
+// Queries whose names start with "_" are internal helpers and are left out;
+// the other tables are shared with f.Symbols. Returns nil if f has no symbols.
+func (f *AnalyzedFile) ExportSymbols() *SymbolTable {
+	if f == nil || f.Symbols == nil {
+		return nil
+	}
+
+	exported := *f.Symbols
+	exported.Queries = make(map[string]*QuerySymbol, len(f.Symbols.Queries))
+
+	for name, q := range f.Symbols.Queries {
+		if IsExportedQuery(name) {
+			exported.Queries[name] = q
+		}
+	}
+
+	return &exported
+}
+
+// IsExportedQuery reports whether a query named name is visible to importers.
+func IsExportedQuery(name string) bool {
+	return !strings.HasPrefix(name, "_")
+}
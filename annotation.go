@@ -0,0 +1,111 @@
+package scaf
+
+import "strings"
+
+// Annotation comment prefixes.
+const (
+	annotationDisableNextLine = "scaf-disable-next-line"
+	annotationDisable         = "scaf-disable"
+)
+
+// AnnotationKind distinguishes the kinds of suppression annotations.
+type AnnotationKind int
+
+// AnnotationKind constants.
+const (
+	// AnnotationDisableNextLine suppresses rules on the following source line.
+	//
+	//	// scaf-disable-next-line unused-import
+	AnnotationDisableNextLine AnnotationKind = iota + 1
+	// AnnotationDisable suppresses rules for the enclosing block.
+	//
+	//	// scaf-disable empty-test
+	AnnotationDisable
+)
+
+// Annotation is a rule suppression comment such as
+// "// scaf-disable-next-line unused-import".
+type Annotation struct {
+	Kind AnnotationKind
+	// Codes are the rule codes being suppressed.
+	Codes []string
+	Span  Span
+}
+
+// Suppresses returns true if the annotation suppresses the given rule code.
+func (a *Annotation) Suppresses(code string) bool {
+	for _, c := range a.Codes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseAnnotation parses a comment as a suppression annotation.
+// Returns nil if the comment is not an annotation or names no rules.
+func parseAnnotation(comment string, span Span) *Annotation {
+	text, ok := strings.CutPrefix(comment, "//")
+	if !ok {
+		return nil
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 { //nolint:mnd // directive plus at least one rule code
+		return nil
+	}
+
+	var kind AnnotationKind
+
+	switch fields[0] {
+	case annotationDisableNextLine:
+		kind = AnnotationDisableNextLine
+	case annotationDisable:
+		kind = AnnotationDisable
+	default:
+		return nil
+	}
+
+	var codes []string
+
+	for _, field := range fields[1:] {
+		for code := range strings.SplitSeq(field, ",") {
+			if code != "" {
+				codes = append(codes, code)
+			}
+		}
+	}
+
+	if len(codes) == 0 {
+		return nil
+	}
+
+	return &Annotation{Kind: kind, Codes: codes, Span: span}
+}
+
+// isAnnotation returns true if the comment text is a suppression annotation.
+func isAnnotation(comment string) bool {
+	return parseAnnotation(comment, Span{}) != nil
+}
+
+// collectAnnotations extracts suppression annotations from lexed trivia.
+func collectAnnotations(trivia *TriviaList) []*Annotation {
+	if trivia == nil {
+		return nil
+	}
+
+	var annotations []*Annotation
+
+	for _, t := range trivia.All() {
+		if t.Type != TriviaComment {
+			continue
+		}
+
+		if a := parseAnnotation(t.Text, t.Span); a != nil {
+			annotations = append(annotations, a)
+		}
+	}
+
+	return annotations
+}
@@ -3,10 +3,16 @@ package scaf
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/conf"
+	"github.com/expr-lang/expr/vm"
 )
 
 // =============================================================================
@@ -112,8 +118,11 @@ type File struct {
 	Imports   []*Import        `parser:"@@*"`
 	Functions []*Function      `parser:"@@*"`
 	Setup     *SetupClause     `parser:"('setup' @@)?"`
-	Teardown  *string          `parser:"('teardown' @RawString)?"`
+	Teardown  *TeardownClause  `parser:"('teardown' @@)?"`
 	Scopes    []*FunctionScope `parser:"@@*"`
+
+	// Annotations are the rule suppression comments in the file (populated after parsing).
+	Annotations []*Annotation `parser:""`
 }
 
 // Suite is an alias for File for backward compatibility.
@@ -259,6 +268,21 @@ func (s *SetupClause) IsComplete() bool {
 	return s.Inline != nil || s.Module != nil || s.Call != nil || len(s.Block) > 0
 }
 
+// TeardownClause represents a teardown, with the same forms as SetupClause.
+// A module reference runs the module's teardown clause rather than its setup.
+// Examples:
+//
+//	teardown `MATCH (n) DETACH DELETE n`          // inline query
+//	teardown fixtures                             // module teardown
+//	teardown fixtures.DeleteUser($id: 1)          // query call with params
+//	teardown { fixtures `MATCH (n) DELETE n` }    // block with multiple items
+type TeardownClause SetupClause
+
+// IsComplete returns true if the teardown clause has content.
+func (t *TeardownClause) IsComplete() bool {
+	return (*SetupClause)(t).IsComplete()
+}
+
 // SetupItem represents a single item in a setup block.
 // Can be an inline query, module setup, or query call.
 type SetupItem struct {
@@ -358,11 +382,11 @@ type FunctionScope struct {
 	NodeMeta
 	CommentMeta
 	RecoveryMeta
-	FunctionName string         `parser:"@Ident '{'"`
-	Setup        *SetupClause   `parser:"('setup' @@)?"`
-	Teardown     *string        `parser:"('teardown' @RawString)?"`
-	Items        []*TestOrGroup `parser:"@@*"`
-	Close        string         `parser:"@'}'"`
+	FunctionName string          `parser:"@Ident '{'"`
+	Setup        *SetupClause    `parser:"('setup' @@)?"`
+	Teardown     *TeardownClause `parser:"('teardown' @@)?"`
+	Items        []*TestOrGroup  `parser:"@@*"`
+	Close        string          `parser:"@'}'"`
 }
 
 // QueryScope is an alias for FunctionScope for backward compatibility.
@@ -375,12 +399,13 @@ func (q *FunctionScope) IsComplete() bool {
 	return q.Close != ""
 }
 
-// TestOrGroup is a union type - either a Test or a Group.
+// TestOrGroup is a union type - either a Test, a Group, or a Table.
 type TestOrGroup struct {
 	NodeMeta
 	RecoveryMeta
 	Test  *Test  `parser:"@@"`
 	Group *Group `parser:"| @@"`
+	Table *Table `parser:"| @@"`
 }
 
 // Group organizes related tests with optional shared setup and teardown.
@@ -388,11 +413,11 @@ type Group struct {
 	NodeMeta
 	CommentMeta
 	RecoveryMeta
-	Name     string         `parser:"'group' @String '{'"`
-	Setup    *SetupClause   `parser:"('setup' @@)?"`
-	Teardown *string        `parser:"('teardown' @RawString)?"`
-	Items    []*TestOrGroup `parser:"@@*"`
-	Close    string         `parser:"@'}'"`
+	Name     string          `parser:"'group' @String '{'"`
+	Setup    *SetupClause    `parser:"('setup' @@)?"`
+	Teardown *TeardownClause `parser:"('teardown' @@)?"`
+	Items    []*TestOrGroup  `parser:"@@*"`
+	Close    string          `parser:"@'}'"`
 }
 
 // IsComplete returns true if the group has a closing brace.
@@ -401,16 +426,71 @@ func (g *Group) IsComplete() bool {
 }
 
 // Test defines a single test case with inputs, expected outputs, and optional assertions.
-// Tests run in a transaction that rolls back after execution, so no teardown is needed.
+// Setup runs before the test's query and Teardown after it, all in one
+// transaction that rolls back when the database supports transactions.
 type Test struct {
 	NodeMeta
 	CommentMeta
 	RecoveryMeta
-	Name       string       `parser:"'test' @String '{'"`
-	Setup      *SetupClause `parser:"('setup' @@)?"`
-	Statements []*Statement `parser:"@@*"`
-	Asserts    []*Assert    `parser:"@@*"`
-	Close      string       `parser:"@'}'"`
+	Name       string          `parser:"'test' @String '{'"`
+	Setup      *SetupClause    `parser:"('setup' @@)?"`
+	Teardown   *TeardownClause `parser:"('teardown' @@)?"`
+	Statements []*Statement    `parser:"@@*"`
+	Asserts    []*Assert       `parser:"@@*"`
+	Close      string          `parser:"@'}'"`
+
+	// FromTable is the table this test was expanded from, or nil for a test
+	// written out in full (populated after parsing).
+	FromTable *Table `parser:""`
+}
+
+// Table is a parameterised test: a header row naming inputs ($param) and
+// expected fields, then one data row per test. Rows are written between
+// pipes, one per line:
+//
+//	table "lookups" {
+//		| $id | u.name  |
+//		| 1   | "Alice" |
+//		| 2   | "Bob"   |
+//	}
+//
+// After parsing, each row is expanded into a Test that follows the table in
+// the enclosing item list, so tables need no special handling elsewhere.
+// "table" is matched by value rather than lexed as a keyword, so fields
+// named table keep working in statements.
+type Table struct {
+	NodeMeta
+	CommentMeta
+	RecoveryMeta
+	Name   string       `parser:"'table' @String '{'"`
+	Header *TableHeader `parser:"@@"`
+	Rows   []*TableRow  `parser:"@@*"`
+	Close  string       `parser:"@'}'"`
+}
+
+// IsComplete returns true if the table has a closing brace.
+func (t *Table) IsComplete() bool {
+	return t.Close != ""
+}
+
+// TestName returns the name of the test expanded from the row at index i.
+func (t *Table) TestName(i int) string {
+	return t.Name + " #" + strconv.Itoa(i+1)
+}
+
+// TableHeader names the columns of a table: $-prefixed parameters and
+// expected output fields.
+type TableHeader struct {
+	NodeMeta
+	RecoveryMeta
+	Columns []*DottedIdent `parser:"'|' (@@ '|')+"`
+}
+
+// TableRow is one data row of a table, with a value per column.
+type TableRow struct {
+	NodeMeta
+	RecoveryMeta
+	Cells []*StatementValue `parser:"'|' (@@ '|')+"`
 }
 
 // IsComplete returns true if the test has a closing brace.
@@ -473,6 +553,67 @@ type ParenExpr struct {
 	NodeMeta
 	RecoveryMeta
 	Tokens []*BalancedExprToken `parser:"'(' @@* ')'"`
+
+	// compiled caches the program built by Compile.
+	compiled atomic.Pointer[compiledExpr]
+}
+
+// compiledExpr is a compiled expression and the environment shape and
+// options it was type-checked against.
+type compiledExpr struct {
+	program *vm.Program
+	key     string
+}
+
+// Compile compiles the expression against env, with any further options such
+// as expr.AsBool(). The first successful compile is cached, and later calls
+// with an env whose values have the same types and options with the same
+// settings reuse it. A program is type-checked against its env and expected
+// result, so any other env or options are compiled again without replacing
+// the cache. Options are told apart by the settings they change, not by
+// the operators, patches or constants they add, which should be the same on
+// every call.
+// Safe for concurrent use.
+func (p *ParenExpr) Compile(env map[string]any, opts ...expr.Option) (*vm.Program, error) {
+	key := envKey(env) + optionsKey(opts)
+	if cached := p.compiled.Load(); cached != nil && cached.key == key {
+		return cached.program, nil
+	}
+
+	program, err := expr.Compile(p.String(), append([]expr.Option{expr.Env(env)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// If another goroutine compiled first, both programs are equivalent
+	p.compiled.CompareAndSwap(nil, &compiledExpr{program: program, key: key})
+
+	return program, nil
+}
+
+// envKey describes the names and value types of env.
+func envKey(env map[string]any) string {
+	names := slices.Sorted(maps.Keys(env))
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s:%T;", name, env[name])
+	}
+
+	return b.String()
+}
+
+// optionsKey describes the compile settings opts change, such as the
+// expected result kind.
+func optionsKey(opts []expr.Option) string {
+	c := conf.CreateNew()
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return fmt.Sprintf("expect:%s,%t;strict:%t;optimize:%t;nodes:%d;functions:%v;disabled:%v",
+		c.Expect, c.ExpectAny, c.Strict, c.Optimize, c.MaxNodes,
+		slices.Sorted(maps.Keys(c.Functions)), slices.Sorted(maps.Keys(c.Disabled)))
 }
 
 // String returns the expression string without the surrounding parentheses.
@@ -812,6 +953,10 @@ func (sv *StatementValue) HasWhere() bool {
 // ToValue returns the literal value, or nil if this is an expression.
 // For backward compatibility with code that expects *Value.
 func (sv *StatementValue) ToValue() *Value {
+	if sv == nil {
+		return nil
+	}
+
 	return sv.Literal
 }
 
@@ -1052,6 +1197,122 @@ func (v *Value) ToGo() any {
 	}
 }
 
+// ValueFromGo converts a native Go value, such as a query result, to a Value.
+// Integers and floats of any size become numbers. It returns false for types
+// with no Value form, such as times.
+func ValueFromGo(x any) (*Value, bool) {
+	switch x := x.(type) {
+	case nil:
+		return &Value{Null: true}, true
+	case string:
+		return &Value{Str: &x}, true
+	case bool:
+		b := Boolean(x)
+		return &Value{Boolean: &b}, true
+	case []any:
+		list := &List{Values: make([]*Value, len(x))}
+		for i, elem := range x {
+			v, ok := ValueFromGo(elem)
+			if !ok {
+				return nil, false
+			}
+			list.Values[i] = v
+		}
+
+		return &Value{List: list}, true
+	case map[string]any:
+		m := &Map{}
+		for _, key := range slices.Sorted(maps.Keys(x)) {
+			v, ok := ValueFromGo(x[key])
+			if !ok {
+				return nil, false
+			}
+			m.Entries = append(m.Entries, &MapEntry{Key: key, Value: v})
+		}
+
+		return &Value{Map: m}, true
+	}
+
+	var n float64
+
+	switch x := x.(type) {
+	case int:
+		n = float64(x)
+	case int8:
+		n = float64(x)
+	case int16:
+		n = float64(x)
+	case int32:
+		n = float64(x)
+	case int64:
+		n = float64(x)
+	case uint:
+		n = float64(x)
+	case uint8:
+		n = float64(x)
+	case uint16:
+		n = float64(x)
+	case uint32:
+		n = float64(x)
+	case uint64:
+		n = float64(x)
+	case float32:
+		n = float64(x)
+	case float64:
+		n = x
+	default:
+		return nil, false
+	}
+
+	return &Value{Number: &n}, true
+}
+
+// Equal reports whether v and other are the same value. Numbers compare by
+// value, so 42 equals 42.0; lists compare element by element and maps key by
+// key, in any order. A nil Value equals null.
+func (v *Value) Equal(other *Value) bool {
+	if v.isNull() || other.isNull() {
+		return v.isNull() && other.isNull()
+	}
+
+	switch {
+	case v.Str != nil:
+		return other.Str != nil && *v.Str == *other.Str
+	case v.Number != nil:
+		return other.Number != nil && *v.Number == *other.Number
+	case v.Boolean != nil:
+		return other.Boolean != nil && *v.Boolean == *other.Boolean
+	case v.List != nil:
+		return other.List != nil && slices.EqualFunc(v.List.Values, other.List.Values, (*Value).Equal)
+	case v.Map != nil:
+		return other.Map != nil && v.Map.equal(other.Map)
+	default:
+		return false
+	}
+}
+
+func (v *Value) isNull() bool {
+	return v == nil || v.Null
+}
+
+// equal reports whether m and other have the same keys with equal values.
+// Later entries win for a key given more than once.
+func (m *Map) equal(other *Map) bool {
+	entries := m.byKey()
+	otherEntries := other.byKey()
+
+	return maps.EqualFunc(entries, otherEntries, (*Value).Equal)
+}
+
+func (m *Map) byKey() map[string]*Value {
+	entries := make(map[string]*Value, len(m.Entries))
+	for _, e := range m.Entries {
+		entries[e.Key] = e.Value
+	}
+
+	return entries
+}
+
 // String returns a string representation of the Value.
 func (v *Value) String() string {
 	switch {
@@ -0,0 +1,144 @@
+package scaf_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/expr-lang/expr"
+
+	"github.com/rlch/scaf"
+)
+
+func TestParenExpr_Compile(t *testing.T) {
+	t.Parallel()
+
+	suite, err := scaf.Parse([]byte("fn Q() `MATCH (n) RETURN n`\n\nQ {\n\ttest \"t\" {\n\t\tassert (age > 18)\n\t}\n}\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	cond := suite.Scopes[0].Items[0].Test.Asserts[0].Shorthand
+
+	env := map[string]any{"age": 30}
+
+	first, err := cond.Compile(env, expr.AsBool())
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	// Concurrent compiles against the same env shape share the cached program.
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			program, err := cond.Compile(map[string]any{"age": 12}, expr.AsBool())
+			if err != nil {
+				t.Errorf("Compile() error: %v", err)
+			}
+
+			if program != first {
+				t.Error("expected the cached program")
+			}
+		})
+	}
+	wg.Wait()
+
+	// Other options are compiled again against the same env.
+	program, err := cond.Compile(env)
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	if program == first {
+		t.Error("expected a new program without AsBool")
+	}
+
+	if _, err := cond.Compile(env, expr.AsInt()); err == nil {
+		t.Error("expected an error for a bool expression compiled AsInt")
+	}
+
+	// An env of another type is compiled again.
+	program, err = cond.Compile(map[string]any{"age": 30.5}, expr.AsBool())
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	if program == first {
+		t.Error("expected a new program for a float env")
+	}
+
+	out, err := expr.Run(program, map[string]any{"age": 30.5})
+	if err != nil || out != true {
+		t.Errorf("Run() = %v, %v; want true", out, err)
+	}
+
+	// A failed compile is reported and not cached.
+	if _, err := cond.Compile(map[string]any{}, expr.AsBool()); err == nil {
+		t.Error("expected an error for an env without age")
+	}
+}
+
+func TestValue_Equal(t *testing.T) {
+	t.Parallel()
+
+	parse := func(src string) *scaf.Value {
+		t.Helper()
+
+		suite, err := scaf.Parse([]byte("fn Q() `Q`\n\nQ {\n\ttest \"t\" {\n\t\tv: " + src + "\n\t}\n}\n"))
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", src, err)
+		}
+
+		return suite.Scopes[0].Items[0].Test.Statements[0].Value.Literal
+	}
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{`"a"`, `"a"`, true},
+		{`"a"`, `"b"`, false},
+		{`42`, `42.0`, true},
+		{`true`, `true`, true},
+		{`true`, `"true"`, false},
+		{`null`, `null`, true},
+		{`null`, `0`, false},
+		{`[1, [2, 3]]`, `[1, [2, 3]]`, true},
+		{`[1, 2]`, `[2, 1]`, false},
+		{`[1, 2]`, `[1, 2, 3]`, false},
+		{`{a: 1, b: "x"}`, `{b: "x", a: 1}`, true},
+		{`{a: 1}`, `{a: 1, b: 2}`, false},
+		{`{a: {b: null}}`, `{a: {b: null}}`, true},
+	}
+
+	for _, tt := range tests {
+		if got := parse(tt.a).Equal(parse(tt.b)); got != tt.want {
+			t.Errorf("%s.Equal(%s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	var missing *scaf.Value
+	if !missing.Equal(parse(`null`)) || missing.Equal(parse(`0`)) {
+		t.Error("a nil Value should equal only null")
+	}
+}
+
+func TestValueFromGo(t *testing.T) {
+	t.Parallel()
+
+	v, ok := scaf.ValueFromGo(map[string]any{
+		"age":   int64(42),
+		"score": float32(1.5),
+		"tags":  []any{"a", nil, true},
+	})
+	if !ok {
+		t.Fatal("ValueFromGo() not ok")
+	}
+
+	if got, want := v.String(), `{age: 42, score: 1.5, tags: ["a", null, true]}`; got != want {
+		t.Errorf("ValueFromGo() = %s, want %s", got, want)
+	}
+
+	if _, ok := scaf.ValueFromGo(struct{}{}); ok {
+		t.Error("ValueFromGo() of a struct should not be ok")
+	}
+}
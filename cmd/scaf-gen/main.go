@@ -0,0 +1,124 @@
+// Command scaf-gen generates neogo model structs from a scaf type schema.
+//
+// Usage:
+//
+//	scaf-gen [--schema PATH] [--package NAME] [--out FILE]
+//
+// The schema and package default to generate.schema and generate.package in
+// .scaf.yaml. Output is written to stdout unless --out is given. Models are
+// emitted in alphabetical order so the result is stable under version control.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+)
+
+var version = "dev"
+
+// defaultPackage is used when neither --package nor generate.package is set.
+const defaultPackage = "models"
+
+// Generate command errors.
+var ErrNoSchema = errors.New("no schema specified (use --schema or generate.schema in .scaf.yaml)")
+
+func main() {
+	app := &cli.Command{
+		Name:    "scaf-gen",
+		Version: version,
+		Usage:   "Generate neogo Go structs from a scaf type schema",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "schema",
+				Aliases: []string{"s"},
+				Usage:   "path to the schema file (default: generate.schema from .scaf.yaml)",
+			},
+			&cli.StringFlag{
+				Name:    "package",
+				Aliases: []string{"p"},
+				Usage:   "Go package name (default: generate.package from .scaf.yaml, or " + defaultPackage + ")",
+			},
+			&cli.StringFlag{
+				Name:    "out",
+				Aliases: []string{"o"},
+				Usage:   "file to write; stdout if empty",
+			},
+		},
+		Action: runGen,
+	}
+
+	err := app.Run(context.Background(), os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runGen(_ context.Context, cmd *cli.Command) error {
+	schemaPath := cmd.String("schema")
+	pkg := cmd.String("package")
+
+	if schemaPath == "" || pkg == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		if configPath, err := scaf.FindConfig(cwd); err == nil {
+			cfg, err := scaf.LoadConfigFile(configPath)
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if schemaPath == "" && cfg.Generate.Schema != "" {
+				schemaPath = cfg.Generate.Schema
+				if !filepath.IsAbs(schemaPath) {
+					schemaPath = filepath.Join(filepath.Dir(configPath), schemaPath)
+				}
+			}
+
+			if pkg == "" {
+				pkg = cfg.Generate.Package
+			}
+		}
+	}
+
+	if schemaPath == "" {
+		return ErrNoSchema
+	}
+
+	if pkg == "" {
+		pkg = defaultPackage
+	}
+
+	schema, err := analysis.LoadSchema(schemaPath, "")
+	if err != nil {
+		return fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	src, err := analysis.GenerateGoStructs(schema, pkg)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.String("out")
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+
+		return err
+	}
+
+	if err := os.WriteFile(out, src, 0o644); err != nil { //nolint:gosec // G306: output file permissions are fine
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+
+	return nil
+}
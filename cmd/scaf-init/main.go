@@ -0,0 +1,386 @@
+// Command scaf-init scaffolds a new scaf project from a live Neo4j database.
+//
+// Usage:
+//
+//	scaf-init [--uri URI] [--username USER] [--password PASS] [--database DB] [--format yaml|hcl] [--dry-run] [--force]
+//
+// It introspects the database schema and writes three files to the current
+// directory: .scaf-schema.yaml (.scaf-schema.hcl with --format hcl), .scaf.yaml
+// with the connection details, and queries.scaf with a stub query for each
+// node label. Connection settings are read from flags, then
+// SCAF_URI/SCAF_USER/SCAF_PASS, then prompted for. Existing files are only
+// overwritten with --force or when confirmed at the prompt.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+	"github.com/rlch/scaf/databases/neo4j"
+)
+
+var version = "dev"
+
+// Files written by scaf-init.
+const (
+	configFile    = ".scaf.yaml"
+	schemaFile    = ".scaf-schema.yaml"
+	schemaFileHCL = ".scaf-schema.hcl"
+	queriesFile   = "queries.scaf"
+)
+
+// Schema formats.
+const (
+	formatYAML = "yaml"
+	formatHCL  = "hcl"
+)
+
+// Init command errors.
+var (
+	ErrNoConnectionURI = errors.New("no connection URI specified (use --uri or SCAF_URI)")
+	ErrAborted         = errors.New("aborted")
+	ErrUnknownFormat   = errors.New("unknown schema format")
+)
+
+func main() {
+	app := &cli.Command{
+		Name:    "scaf-init",
+		Version: version,
+		Usage:   "Scaffold a scaf project from a live Neo4j database",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "uri",
+				Usage:   "database connection URI",
+				Sources: cli.EnvVars("SCAF_URI"),
+			},
+			&cli.StringFlag{
+				Name:    "username",
+				Aliases: []string{"u"},
+				Usage:   "database username",
+				Sources: cli.EnvVars("SCAF_USER"),
+			},
+			&cli.StringFlag{
+				Name:    "password",
+				Aliases: []string{"p"},
+				Usage:   "database password",
+				Sources: cli.EnvVars("SCAF_PASS"),
+			},
+			&cli.StringFlag{
+				Name:    "database",
+				Aliases: []string{"d"},
+				Usage:   "database name for multi-database setups",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "schema file format: yaml or hcl",
+				Value: formatYAML,
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "print the files that would be written without touching the filesystem",
+			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "overwrite existing files without asking",
+			},
+		},
+		Action: runInit,
+	}
+
+	err := app.Run(context.Background(), os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generatedFile is a file scaf-init writes.
+type generatedFile struct {
+	path    string
+	content []byte
+}
+
+func runInit(_ context.Context, cmd *cli.Command) error {
+	in := bufio.NewReader(os.Stdin)
+	dryRun := cmd.Bool("dry-run")
+
+	format := cmd.String("format")
+	if format != formatYAML && format != formatHCL {
+		return fmt.Errorf("%w: %s (want yaml or hcl)", ErrUnknownFormat, format)
+	}
+
+	cfg := &scaf.Neo4jConfig{
+		URI:      cmd.String("uri"),
+		Username: cmd.String("username"),
+		Password: cmd.String("password"),
+		Database: cmd.String("database"),
+	}
+
+	if cfg.URI == "" {
+		cfg.URI = prompt(in, "Neo4j URI", "bolt://localhost:7687")
+	}
+
+	if cfg.URI == "" {
+		return ErrNoConnectionURI
+	}
+
+	if cfg.Username == "" {
+		cfg.Username = prompt(in, "Username", "neo4j")
+	}
+
+	if cfg.Username != "" && cfg.Password == "" {
+		cfg.Password = prompt(in, "Password", "")
+	}
+
+	// Check before connecting so an aborted run has no side effects.
+	overwrite := cmd.Bool("force")
+	if !dryRun && !overwrite {
+		if existing := existingFiles(".", outputPaths(format)); len(existing) > 0 {
+			list := strings.Join(existing, ", ")
+			if !confirm(in, list+" already exist. Overwrite?") {
+				return fmt.Errorf("%w: %s already exist (use --force to overwrite)", ErrAborted, list)
+			}
+
+			overwrite = true
+		}
+	}
+
+	db, err := neo4j.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	schema, err := db.ExtractSchema()
+	if err != nil {
+		return err
+	}
+
+	// Introspection can see relationships to labels it found no nodes for;
+	// report them so the schema can be fixed before it is relied on.
+	for _, schemaErr := range schema.Validate() {
+		fmt.Fprintf(os.Stderr, "warning: schema: %v\n", schemaErr)
+	}
+
+	labels, err := db.Labels()
+	if err != nil {
+		return err
+	}
+
+	files, err := generateFiles(cfg, schema, labels, format)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return printFiles(os.Stdout, files)
+	}
+
+	return writeFiles(".", files, overwrite)
+}
+
+// outputPaths returns the paths of the files scaf-init writes for format.
+func outputPaths(format string) []string {
+	path := schemaFile
+	if format == formatHCL {
+		path = schemaFileHCL
+	}
+
+	return []string{path, configFile, queriesFile}
+}
+
+// existingFiles returns the paths, relative to dir, that already exist there.
+func existingFiles(dir string, paths []string) []string {
+	var existing []string
+
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+			existing = append(existing, path)
+		}
+	}
+
+	return existing
+}
+
+// writeFiles writes files into dir. Unless overwrite is set, a file that
+// already exists is left alone and fails the write, even one created since
+// existingFiles checked.
+func writeFiles(dir string, files []generatedFile, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+
+	for _, file := range files {
+		if err := writeFile(filepath.Join(dir, file.path), file.content, flags); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.path, err)
+		}
+
+		fmt.Printf("wrote %s\n", file.path)
+	}
+
+	return nil
+}
+
+// writeFile writes content to a file opened with flags.
+func writeFile(path string, content []byte, flags int) error {
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// generateFiles renders the schema in format, the config, and query stubs.
+func generateFiles(cfg *scaf.Neo4jConfig, schema *analysis.TypeSchema, labels []string, format string) ([]generatedFile, error) {
+	path, write := outputPaths(format)[0], analysis.WriteSchema
+	if format == formatHCL {
+		write = analysis.WriteSchemaHCL
+	}
+
+	var schemaBuf bytes.Buffer
+	if err := write(&schemaBuf, schema); err != nil {
+		return nil, fmt.Errorf("failed to render schema: %w", err)
+	}
+
+	config, err := yaml.Marshal(&scaf.Config{
+		Neo4j:    cfg,
+		Generate: scaf.GenerateConfig{Schema: path},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render config: %w", err)
+	}
+
+	return []generatedFile{
+		{path: path, content: schemaBuf.Bytes()},
+		{path: configFile, content: config},
+		{path: queriesFile, content: queryStubs(labels)},
+	}, nil
+}
+
+// queryStubs returns a scaf file with a MATCH query for each node label.
+func queryStubs(labels []string) []byte {
+	var b strings.Builder
+
+	b.WriteString("// Generated by scaf-init. Each query is a starting point; add parameters and tests.\n")
+
+	seen := make(map[string]bool)
+
+	for _, label := range labels {
+		// Queries are raw strings, so labels that need backtick quoting can't be written.
+		if !isPlainLabel(label) {
+			fmt.Fprintf(&b, "\n// Skipped label %q: it needs backtick quoting.\n", label)
+
+			continue
+		}
+
+		name := "Get" + identifier(label)
+		for seen[name] {
+			name += "_"
+		}
+		seen[name] = true
+
+		fmt.Fprintf(&b, "\nfn %s() `MATCH (n:%s) RETURN n`\n", name, label)
+	}
+
+	return []byte(b.String())
+}
+
+// identifier converts a label into an exported identifier
+// (e.g. "Person" → "Person", "user_account" → "UserAccount").
+func identifier(label string) string {
+	var b strings.Builder
+
+	upper := true
+
+	for _, r := range label {
+		if r == '_' {
+			upper = true
+
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// isPlainLabel reports whether label is a Cypher identifier that needs no quoting.
+func isPlainLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+
+	for i, r := range label {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// printFiles writes each file with a header for --dry-run.
+func printFiles(w io.Writer, files []generatedFile) error {
+	for i, file := range files {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "==> %s <==\n%s", file.path, file.content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prompt asks for a value on stderr, returning def if the answer is empty.
+func prompt(in *bufio.Reader, label, def string) string {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", label)
+	}
+
+	line, _ := in.ReadString('\n')
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+
+	return def
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func confirm(in *bufio.Reader, question string) bool {
+	answer := prompt(in, question+" (y/N)", "")
+
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteFiles_ExistingFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, queriesFile), []byte("fn Mine() `RETURN 1`\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := existingFiles(dir, outputPaths(formatYAML)); !slices.Equal(got, []string{queriesFile}) {
+		t.Errorf("existingFiles() = %v, want [%s]", got, queriesFile)
+	}
+
+	files := []generatedFile{{path: queriesFile, content: []byte("// Generated by scaf-init.\n")}}
+
+	if err := writeFiles(dir, files, false); !errors.Is(err, os.ErrExist) {
+		t.Errorf("writeFiles() without overwrite error = %v, want os.ErrExist", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, queriesFile)); string(data) != "fn Mine() `RETURN 1`\n" {
+		t.Errorf("existing file was changed to %q", data)
+	}
+
+	if err := writeFiles(dir, files, true); err != nil {
+		t.Fatalf("writeFiles() with overwrite error = %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, queriesFile)); string(data) != "// Generated by scaf-init.\n" {
+		t.Errorf("overwritten file = %q", data)
+	}
+}
@@ -0,0 +1,415 @@
+// Command scaf-lint runs scaf's semantic analysis over .scaf files without an editor.
+//
+// Usage:
+//
+//	scaf-lint [--format text|json|junit-xml] [--rules a,!b] [--max-errors N] [--watch] [--workers N] [files, directories, or globs...]
+//
+// The exit code reflects the highest severity found: 0 for clean or hints only,
+// 1 for warnings, and 2 for errors. With --watch, scaf-lint keeps running and
+// reprints diagnostics whenever a .scaf file or the schema is saved.
+//
+// More than --parallel-threshold files are analyzed by a pool of --workers
+// goroutines, with progress on stderr when it's a terminal.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v3"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+
+	// Register dialects.
+	_ "github.com/rlch/scaf/dialects/cypher"
+	_ "github.com/rlch/scaf/dialects/sql"
+)
+
+var version = "dev"
+
+// Exit codes.
+const (
+	exitClean    = 0
+	exitWarnings = 1
+	exitErrors   = 2
+)
+
+// defaultParallelThreshold is the file count above which files are analyzed
+// in parallel.
+const defaultParallelThreshold = 10
+
+// Lint command errors.
+var (
+	ErrNoScafFiles   = errors.New("no .scaf files found")
+	ErrUnknownRule   = errors.New("unknown rule")
+	ErrUnknownFormat = errors.New("unknown format")
+)
+
+func main() {
+	app := &cli.Command{
+		Name:      "scaf-lint",
+		Version:   version,
+		Usage:     "Report scaf diagnostics for .scaf files",
+		ArgsUsage: "[files, directories, or globs...]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Usage:   "output format: text, json, or junit-xml",
+				Value:   formatText,
+			},
+			&cli.StringFlag{
+				Name:  "rules",
+				Usage: "comma-separated rule codes to enable; prefix with ! to disable (e.g. unused-import,!empty-test)",
+			},
+			&cli.IntFlag{
+				Name:  "max-errors",
+				Usage: "stop after reporting N errors and exit with code 2 (0 = unlimited)",
+			},
+			&cli.BoolFlag{
+				Name:    "watch",
+				Aliases: []string{"w"},
+				Usage:   "re-run when a .scaf file or the schema is saved",
+			},
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "number of files to analyze at once (0 = number of CPUs)",
+			},
+			&cli.IntFlag{
+				Name:  "parallel-threshold",
+				Usage: "analyze in parallel when there are more than N files",
+				Value: defaultParallelThreshold,
+			},
+		},
+		Action: runLint,
+	}
+
+	err := app.Run(context.Background(), os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(exitErrors)
+	}
+}
+
+func runLint(ctx context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	rules, err := selectRules(analysis.DefaultRules(), cmd.String("rules"))
+	if err != nil {
+		return err
+	}
+
+	formatter, err := newFormatter(cmd.String("format"), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	files, err := scaf.CollectFiles(args)
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return ErrNoScafFiles
+	}
+
+	// Use the dialect from config to pick the query analyzer.
+	dialectName := scaf.DialectCypher
+
+	var cfg *scaf.Config
+	var configDir string
+
+	if configPath, err := scaf.FindConfig(filepath.Dir(files[0])); err == nil {
+		if cfg, err = scaf.LoadConfigFile(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to load config: %v\n", err)
+		} else {
+			configDir = filepath.Dir(configPath)
+			if d := cfg.DialectName(); d != "" {
+				dialectName = d
+			}
+		}
+	}
+
+	// Lint paths are relative to the working directory.
+	resolver := analysis.NewFileSystemResolver(".")
+
+	l := &linter{
+		opts: analysis.AnalyzeOptions{
+			Resolver: resolver,
+			Dialect:  dialectName,
+			Rules:    rules,
+		},
+		resolver:          resolver,
+		formatter:         formatter,
+		maxErrors:         int(cmd.Int("max-errors")),
+		workers:           int(cmd.Int("workers")),
+		parallelThreshold: int(cmd.Int("parallel-threshold")),
+	}
+
+	// The progress line redraws itself with \r, which only works on a terminal
+	if isatty.IsTerminal(os.Stderr.Fd()) {
+		l.progress = os.Stderr
+	}
+
+	var schemaPath string
+	if cfg != nil {
+		l.opts.Config = &cfg.Analysis
+		l.config = cfg
+		l.configDir = configDir
+		l.loadSchema()
+
+		// A schema file is watched; a schema URL is fetched once
+		if cfg.SchemaURL == "" && cfg.Generate.Schema != "" {
+			schemaPath = cfg.Generate.Schema
+			if !filepath.IsAbs(schemaPath) {
+				schemaPath = filepath.Join(configDir, schemaPath)
+			}
+		}
+	}
+
+	if cmd.Bool("watch") {
+		return l.watch(ctx, files, schemaPath)
+	}
+
+	exitCode, err := l.lint(files)
+	if err != nil {
+		return err
+	}
+
+	if exitCode != exitClean {
+		return cli.Exit("", exitCode)
+	}
+
+	return nil
+}
+
+// loadSchema loads the schema the config names, from a file or a schema URL,
+// for schema-aware rules. A schema that fails to load is skipped with a
+// warning.
+func (l *linter) loadSchema() {
+	schema, err := analysis.LoadConfigSchema(l.config, l.configDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to load schema: %v\n", err)
+
+		return
+	}
+
+	if schema != nil {
+		l.opts.Schema = schema
+	}
+}
+
+// linter analyzes files and reports their diagnostics.
+type linter struct {
+	opts      analysis.AnalyzeOptions
+	resolver  analysis.FileResolver
+	formatter formatter
+	maxErrors int
+
+	// config and configDir are the project config, if any, and the directory
+	// its paths are relative to.
+	config    *scaf.Config
+	configDir string
+
+	// workers and parallelThreshold configure parallel analysis; see analyze.
+	workers           int
+	parallelThreshold int
+
+	// progress receives the parallel analysis progress line, or is nil to
+	// print none.
+	progress io.Writer
+}
+
+// lint analyzes files, writes the report, and returns the exit code implied by
+// the diagnostics found.
+func (l *linter) lint(files []string) (int, error) {
+	errorCount := 0
+	exitCode := exitClean
+
+	var results []fileResult
+
+	analyses, err := l.analyze(files)
+	if err != nil {
+		return exitErrors, err
+	}
+
+lint:
+	for i, file := range files {
+		analyzed := analyses[i]
+
+		result := fileResult{path: file}
+		for _, diag := range analyzed.Diagnostics {
+			if diag.Suppressed {
+				continue
+			}
+
+			result.diagnostics = append(result.diagnostics, diag)
+			exitCode = max(exitCode, severityExitCode(diag.Severity))
+
+			if diag.Severity == analysis.SeverityError {
+				errorCount++
+				if l.maxErrors > 0 && errorCount >= l.maxErrors {
+					results = append(results, result)
+					break lint
+				}
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	if err := l.formatter.Format(results); err != nil {
+		return exitErrors, fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return exitCode, nil
+}
+
+// analyze returns the analysis of each file, in order, with the batch rules
+// run over all of them. More than parallelThreshold files are analyzed by a
+// pool of workers, with progress overwriting a single line of stderr.
+func (l *linter) analyze(files []string) ([]*analysis.AnalyzedFile, error) {
+	var (
+		analyses []*analysis.AnalyzedFile
+		err      error
+	)
+
+	if len(files) <= l.parallelThreshold {
+		analyses, err = l.analyzeSequential(files)
+	} else {
+		analyses, err = l.analyzeParallel(files)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	analysis.RunBatchRules(analyses, &l.opts)
+
+	return analyses, nil
+}
+
+// analyzeSequential analyzes files one at a time, in order.
+func (l *linter) analyzeSequential(files []string) ([]*analysis.AnalyzedFile, error) {
+	analyses := make([]*analysis.AnalyzedFile, len(files))
+
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		if analyses[i], err = analysis.AnalyzeFile(file, data, &l.opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return analyses, nil
+}
+
+// analyzeParallel analyzes files with a pool of workers and returns them in
+// order.
+func (l *linter) analyzeParallel(files []string) ([]*analysis.AnalyzedFile, error) {
+	analyses := make([]*analysis.AnalyzedFile, len(files))
+
+	start := time.Now()
+	results, errs := analysis.AnalyzeParallel(files, &l.opts, l.workers)
+
+	byPath := make(map[string]*analysis.AnalyzedFile, len(files))
+	for f := range results {
+		byPath[f.Path] = f
+
+		if l.progress != nil {
+			fmt.Fprintf(l.progress, "\ranalyzed %d/%d files (%s)", len(byPath), len(files), time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	if l.progress != nil {
+		fmt.Fprintln(l.progress)
+	}
+
+	// Both channels are closed by now; report the first error, if any
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	for i, file := range files {
+		analyses[i] = byPath[file]
+	}
+
+	return analyses, nil
+}
+
+// severityExitCode maps a diagnostic severity to the exit code it implies.
+func severityExitCode(severity analysis.DiagnosticSeverity) int {
+	switch severity {
+	case analysis.SeverityError:
+		return exitErrors
+	case analysis.SeverityWarning:
+		return exitWarnings
+	default:
+		return exitClean
+	}
+}
+
+// selectRules filters rules by a comma-separated spec.
+// Plain codes enable only the listed rules; codes prefixed with ! disable a rule.
+// An empty spec, or one with only disabled rules, starts from all rules.
+func selectRules(rules []*analysis.Rule, spec string) ([]*analysis.Rule, error) {
+	if strings.TrimSpace(spec) == "" {
+		return rules, nil
+	}
+
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.Name] = true
+	}
+
+	enabled := make(map[string]bool)
+	disabled := make(map[string]bool)
+
+	for _, part := range strings.Split(spec, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			continue
+		}
+
+		negate := strings.HasPrefix(name, "!")
+		name = strings.TrimPrefix(name, "!")
+
+		if !known[name] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRule, name)
+		}
+
+		if negate {
+			disabled[name] = true
+		} else {
+			enabled[name] = true
+		}
+	}
+
+	// Non-nil even when every rule is disabled, since nil means all rules
+	selected := []*analysis.Rule{}
+	for _, rule := range rules {
+		if len(enabled) > 0 && !enabled[rule.Name] {
+			continue
+		}
+		if disabled[rule.Name] {
+			continue
+		}
+		selected = append(selected, rule)
+	}
+
+	return selected, nil
+}
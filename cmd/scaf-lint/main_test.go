@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rlch/scaf/analysis"
+)
+
+func TestLinter_ReportsCircularImports(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.scaf": "import b \"./b\"\n\nfn Q() `MATCH (n) RETURN n`\n\nsetup b.R()\n\nQ {\n\ttest \"t\" {}\n}\n",
+		"b.scaf": "import a \"./a\"\n\nfn R() `MATCH (n) RETURN n`\n\nsetup a.Q()\n\nR {\n\ttest \"t\" {}\n}\n",
+	}
+
+	var paths []string
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	// Both the sequential and the parallel path run the batch rules
+	for _, threshold := range []int{len(paths), 0} {
+		var out bytes.Buffer
+
+		resolver := analysis.NewFileSystemResolver(dir)
+		l := &linter{
+			opts:              analysis.AnalyzeOptions{Resolver: resolver},
+			resolver:          resolver,
+			formatter:         &jsonFormatter{w: &out},
+			parallelThreshold: threshold,
+		}
+
+		exitCode, err := l.lint(paths)
+		if err != nil {
+			t.Fatalf("lint() error: %v", err)
+		}
+
+		var diagnostics []jsonDiagnostic
+		if err := json.Unmarshal(out.Bytes(), &diagnostics); err != nil {
+			t.Fatalf("invalid JSON report: %v\n%s", err, out.String())
+		}
+
+		cycles := 0
+		for _, d := range diagnostics {
+			if d.Code == "circular-imports" {
+				cycles++
+			}
+		}
+
+		if cycles != 2 {
+			t.Errorf("threshold %d: got %d circular-imports diagnostics, want one per file:\n%s", threshold, cycles, out.String())
+		}
+
+		if exitCode != exitWarnings {
+			t.Errorf("threshold %d: exit code = %d, want %d", threshold, exitCode, exitWarnings)
+		}
+	}
+}
+
+func TestLinter_ExitCodes(t *testing.T) {
+	t.Parallel()
+
+	rules, err := selectRules(analysis.DefaultRules(), "empty-test,empty-group,duplicate-query")
+	if err != nil {
+		t.Fatalf("selectRules() error: %v", err)
+	}
+
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	hint := write("hint.scaf", "fn Q() `MATCH (n) RETURN n`\n\nQ {\n\ttest \"t\" {}\n}\n")
+	warning := write("warning.scaf", "fn Q() `MATCH (n) RETURN n`\n\nQ {\n\tgroup \"g\" {}\n}\n")
+	errs := write("error.scaf", "fn Q() `MATCH (n) RETURN n`\nfn Q() `MATCH (m) RETURN m`\nfn Q() `MATCH (o) RETURN o`\n")
+
+	tests := []struct {
+		name      string
+		files     []string
+		maxErrors int
+		wantCode  int
+		wantLines []string
+	}{
+		{
+			name:      "hints only",
+			files:     []string{hint},
+			wantCode:  exitClean,
+			wantLines: []string{hint + ":4:2: hint: empty test: t [empty-test]"},
+		},
+		{
+			name:      "warnings",
+			files:     []string{hint, warning},
+			wantCode:  exitWarnings,
+			wantLines: []string{hint + ":4:2: hint: empty test: t [empty-test]", warning + ":4:2: warning: empty group: g [empty-group]"},
+		},
+		{
+			name:      "errors",
+			files:     []string{warning, errs},
+			wantCode:  exitErrors,
+			wantLines: []string{warning + ":4:2: warning: empty group: g [empty-group]", errs + ":2:1: error: duplicate query name: Q (first defined at line 1) [duplicate-query]", errs + ":3:1: error: duplicate query name: Q (first defined at line 1) [duplicate-query]"},
+		},
+		{
+			name:      "max errors",
+			files:     []string{errs, warning},
+			maxErrors: 1,
+			wantCode:  exitErrors,
+			wantLines: []string{errs + ":2:1: error: duplicate query name: Q (first defined at line 1) [duplicate-query]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+
+			l := &linter{
+				opts:              analysis.AnalyzeOptions{Rules: rules},
+				formatter:         &textFormatter{w: &out},
+				maxErrors:         tt.maxErrors,
+				parallelThreshold: defaultParallelThreshold,
+			}
+
+			exitCode, err := l.lint(tt.files)
+			if err != nil {
+				t.Fatalf("lint() error: %v", err)
+			}
+
+			if exitCode != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", exitCode, tt.wantCode)
+			}
+
+			if got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"); !slices.Equal(got, tt.wantLines) {
+				t.Errorf("output mismatch:\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.wantLines, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/rlch/scaf/analysis"
+)
+
+// Output formats.
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatJUnitXML = "junit-xml"
+)
+
+// fileResult holds the diagnostics reported for a single file.
+type fileResult struct {
+	path        string
+	diagnostics []analysis.Diagnostic
+}
+
+// formatter writes lint results in a specific output format.
+type formatter interface {
+	Format(results []fileResult) error
+}
+
+func newFormatter(format string, w io.Writer) (formatter, error) {
+	switch format {
+	case formatText, "":
+		return &textFormatter{w: w}, nil
+	case formatJSON:
+		return &jsonFormatter{w: w}, nil
+	case formatJUnitXML:
+		return &junitFormatter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s (want text, json, or junit-xml)", ErrUnknownFormat, format)
+	}
+}
+
+// severityName returns the lowercase name used for a severity in reports.
+func severityName(severity analysis.DiagnosticSeverity) string {
+	switch severity {
+	case analysis.SeverityError:
+		return "error"
+	case analysis.SeverityWarning:
+		return "warning"
+	case analysis.SeverityInformation:
+		return "info"
+	case analysis.SeverityHint:
+		return "hint"
+	default:
+		return "unknown"
+	}
+}
+
+// textFormatter writes one "file:line:col: severity: message [code]" line per diagnostic.
+type textFormatter struct {
+	w io.Writer
+}
+
+func (f *textFormatter) Format(results []fileResult) error {
+	for _, result := range results {
+		for _, diag := range result.diagnostics {
+			_, err := fmt.Fprintf(f.w, "%s:%d:%d: %s: %s [%s]\n",
+				result.path, diag.Span.Start.Line, diag.Span.Start.Column,
+				severityName(diag.Severity), diag.Message, diag.Code)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonDiagnostic is the JSON representation of a diagnostic.
+type jsonDiagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// jsonFormatter writes all diagnostics as a single JSON array.
+type jsonFormatter struct {
+	w io.Writer
+}
+
+func (f *jsonFormatter) Format(results []fileResult) error {
+	diagnostics := []jsonDiagnostic{}
+
+	for _, result := range results {
+		for _, diag := range result.diagnostics {
+			diagnostics = append(diagnostics, jsonDiagnostic{
+				File:     result.path,
+				Line:     diag.Span.Start.Line,
+				Col:      diag.Span.Start.Column,
+				Severity: severityName(diag.Severity),
+				Code:     diag.Code,
+				Message:  diag.Message,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(f.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(diagnostics)
+}
+
+// JUnit XML structures. Each file is a test suite and each diagnostic a test
+// case; errors and warnings are failures, information and hints pass.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// junitFormatter writes a JUnit XML report for CI systems.
+type junitFormatter struct {
+	w io.Writer
+}
+
+func (f *junitFormatter) Format(results []fileResult) error {
+	report := junitTestSuites{Name: "scaf-lint"}
+
+	for _, result := range results {
+		suite := junitTestSuite{Name: result.path}
+
+		if len(result.diagnostics) == 0 {
+			// A passing case so clean files still show up in reports.
+			suite.Cases = append(suite.Cases, junitTestCase{Name: "lint", Classname: result.path})
+		}
+
+		for _, diag := range result.diagnostics {
+			tc := junitTestCase{
+				Name:      fmt.Sprintf("%s:%d:%d %s", result.path, diag.Span.Start.Line, diag.Span.Start.Column, diag.Code),
+				Classname: result.path,
+			}
+
+			if diag.Severity == analysis.SeverityError || diag.Severity == analysis.SeverityWarning {
+				tc.Failure = &junitFailure{
+					Message: diag.Message,
+					Type:    severityName(diag.Severity),
+					Body:    diag.Message,
+				}
+				suite.Failures++
+			}
+
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(f.w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(f.w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(f.w, "\n")
+
+	return err
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+)
+
+// watchSettle is how long to wait for further events after a save, since
+// editors often write a file in several steps.
+const watchSettle = 100 * time.Millisecond
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// watch lints files, then re-lints whenever a watched file is saved until
+// interrupted. The files they import, directly or indirectly, are watched
+// too, and saving any file re-lints every linted file that imports it along
+// some chain of imports. Saving the schema re-lints everything. An interrupt
+// during a run lets the run finish and print before exiting.
+func (l *linter) watch(ctx context.Context, files []string, schemaPath string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watcher: %w", err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	// Events carry absolute paths; files keep the paths given for reporting.
+	watched := make(map[string]string, len(files))
+	paths := make([]string, 0, len(files))
+
+	for _, file := range files {
+		path := absPath(file)
+		watched[path] = file
+		paths = append(paths, path)
+	}
+
+	schemaPath = absPath(schemaPath)
+	graph := importGraph(paths, l.resolver)
+
+	dirs := make(map[string]bool)
+	if err := watchDirs(watcher, dirs, graph, schemaPath); err != nil {
+		return err
+	}
+
+	l.rerun(files, len(files))
+
+	for {
+		changed, err := nextChanges(ctx, watcher)
+		if err != nil {
+			return err
+		}
+
+		if changed == nil {
+			return nil // Interrupted
+		}
+
+		// Imported files are cached for cross-file rules; drop stale copies.
+		for path := range changed {
+			l.resolver.Invalidate(path)
+		}
+
+		// A save can add or remove imports, so the graph is rebuilt and
+		// any newly imported directories watched.
+		graph = importGraph(paths, l.resolver)
+		if err := watchDirs(watcher, dirs, graph, schemaPath); err != nil {
+			return err
+		}
+
+		var affected []string
+
+		switch {
+		case schemaPath != "" && changed[schemaPath]:
+			l.loadSchema()
+
+			affected = files
+		default:
+			affected = affectedFiles(files, watched, graph, changed)
+		}
+
+		if len(affected) > 0 {
+			l.rerun(affected, len(files))
+		}
+	}
+}
+
+// watchDirs adds the directory of each file in graph, and of the schema, to
+// watcher, skipping those already in dirs and recording the ones added.
+// Directories are watched rather than files so editors that save by renaming
+// over the original are still seen.
+func watchDirs(watcher *fsnotify.Watcher, dirs map[string]bool, graph *analysis.ImportGraph, schemaPath string) error {
+	paths := graph.Nodes
+	if schemaPath != "" {
+		paths = append(slices.Clip(paths), schemaPath)
+	}
+
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if dirs[dir] {
+			continue
+		}
+
+		// An import of a missing directory is reported by the lint itself
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+
+		dirs[dir] = true
+	}
+
+	return nil
+}
+
+// rerun clears the terminal and lints files, reporting errors without exiting.
+func (l *linter) rerun(files []string, total int) {
+	fmt.Print(clearScreen)
+
+	if _, err := l.lint(files); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "\n[%s] linted %d of %d files; watching for changes (Ctrl+C to stop)\n",
+		time.Now().Format(time.TimeOnly), len(files), total)
+}
+
+// nextChanges blocks until at least one file is written, then collects
+// further events until they settle. Returns nil when ctx is done.
+func nextChanges(ctx context.Context, watcher *fsnotify.Watcher) (map[string]bool, error) {
+	changed := make(map[string]bool)
+
+	var settle <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil //nolint:nilnil // nil map signals a clean shutdown
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil, nil //nolint:nilnil // watcher closed
+			}
+
+			return nil, fmt.Errorf("watch error: %w", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil, nil //nolint:nilnil // watcher closed
+			}
+
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+				changed[absPath(event.Name)] = true
+				settle = time.After(watchSettle)
+			}
+		case <-settle:
+			return changed, nil
+		}
+	}
+}
+
+// affectedFiles returns the watched files that changed, plus every watched
+// file that imports a changed file directly or indirectly, in lint order.
+func affectedFiles(files []string, watched map[string]string, graph *analysis.ImportGraph, changed map[string]bool) []string {
+	affected := make(map[string]bool)
+
+	for path := range changed {
+		if file, ok := watched[path]; ok {
+			affected[file] = true
+		}
+
+		for _, importer := range graph.Importers(path) {
+			if file, ok := watched[importer]; ok {
+				affected[file] = true
+			}
+		}
+	}
+
+	var result []string
+	for _, file := range files {
+		if affected[file] {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// importGraph returns the import graph of the files at paths and of every
+// file they import, directly or indirectly. Imports are resolved by resolver,
+// and nodes are absolute paths.
+func importGraph(paths []string, resolver analysis.FileResolver) *analysis.ImportGraph {
+	var files []*analysis.AnalyzedFile
+
+	seen := make(map[string]bool)
+	queue := slices.Clone(paths)
+
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+
+		if seen[path] {
+			continue
+		}
+
+		seen[path] = true
+
+		f := &analysis.AnalyzedFile{Path: path, Resolver: resolver}
+		files = append(files, f)
+
+		// A file that doesn't parse imports what its partial AST does
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		if f.Suite, _ = scaf.Parse(data); f.Suite == nil {
+			continue
+		}
+
+		for _, imp := range f.Suite.Imports {
+			if imp != nil && imp.Path != "" {
+				queue = append(queue, absPath(resolver.ResolveImportPath(path, imp.Path)))
+			}
+		}
+	}
+
+	return analysis.DependencyGraph(files)
+}
+
+// absPath returns path as an absolute path so it matches watcher events.
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	return abs
+}
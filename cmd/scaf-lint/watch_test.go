@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rlch/scaf/analysis"
+)
+
+// watchFixture writes linted files under tests/ that import, through lib/b,
+// a file lib/c outside the linted directory. It returns the root and the
+// linted paths.
+func watchFixture(t *testing.T) (string, []string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"tests/a.scaf": "import b \"../lib/b\"\n\nfn Q() `MATCH (n) RETURN n`\n\nsetup b.R()\n\nQ {\n\ttest \"t\" {}\n}\n",
+		"tests/d.scaf": "fn S() `MATCH (n) RETURN n`\n\nS {\n\ttest \"t\" {}\n}\n",
+		"lib/b.scaf":   "import c \"./c\"\n\nfn R() `MATCH (n) RETURN n`\n\nsetup c.P()\n",
+		"lib/c.scaf":   "fn P() `MATCH (n) RETURN n`\n",
+	}
+
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir, []string{filepath.Join(dir, "tests", "a.scaf"), filepath.Join(dir, "tests", "d.scaf")}
+}
+
+func TestAffectedFiles_TransitiveImporters(t *testing.T) {
+	t.Parallel()
+
+	dir, files := watchFixture(t)
+	watched := map[string]string{files[0]: files[0], files[1]: files[1]}
+	graph := importGraph(files, analysis.NewFileSystemResolver(dir))
+
+	lib := filepath.Join(dir, "lib", "c.scaf")
+	if !slices.Contains(graph.Nodes, lib) {
+		t.Fatalf("importGraph() nodes = %v, want %s", graph.Nodes, lib)
+	}
+
+	affected := affectedFiles(files, watched, graph, map[string]bool{lib: true})
+	if !slices.Equal(affected, files[:1]) {
+		t.Errorf("affectedFiles(c.scaf) = %v, want %v", affected, files[:1])
+	}
+
+	affected = affectedFiles(files, watched, graph, map[string]bool{files[1]: true})
+	if !slices.Equal(affected, files[1:]) {
+		t.Errorf("affectedFiles(d.scaf) = %v, want %v", affected, files[1:])
+	}
+}
+
+// reportFormatter sends each report's results on the channel.
+type reportFormatter chan []fileResult
+
+func (f reportFormatter) Format(results []fileResult) error {
+	f <- results
+
+	return nil
+}
+
+func TestLinter_Watch_ImportedFileOutsideLintedDirs(t *testing.T) {
+	t.Parallel()
+
+	dir, files := watchFixture(t)
+	reports := make(reportFormatter, 1)
+
+	resolver := analysis.NewFileSystemResolver(dir)
+	l := &linter{
+		opts:              analysis.AnalyzeOptions{Resolver: resolver},
+		resolver:          resolver,
+		formatter:         reports,
+		parallelThreshold: len(files),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- l.watch(ctx, files, "") }()
+
+	next := func() []string {
+		t.Helper()
+
+		select {
+		case results := <-reports:
+			var paths []string
+			for _, result := range results {
+				paths = append(paths, result.path)
+			}
+
+			return paths
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a lint run")
+
+			return nil
+		}
+	}
+
+	if got := next(); !slices.Equal(got, files) {
+		t.Fatalf("first run linted %v, want %v", got, files)
+	}
+
+	lib := filepath.Join(dir, "lib", "c.scaf")
+	if err := os.WriteFile(lib, []byte("fn P() `MATCH (m) RETURN m`\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := next(); !slices.Equal(got, files[:1]) {
+		t.Errorf("saving lib/c.scaf linted %v, want %v", got, files[:1])
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("watch() error: %v", err)
+	}
+}
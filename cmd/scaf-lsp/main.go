@@ -9,21 +9,24 @@ import (
 	"os"
 
 	"go.lsp.dev/jsonrpc2"
-	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/rlch/scaf/analysis"
 	"github.com/rlch/scaf/lsp"
 
 	// Import dialects to register their analyzers via init().
 	_ "github.com/rlch/scaf/dialects/cypher"
+	_ "github.com/rlch/scaf/dialects/sql"
 )
 
 var (
-	dialectFlag = flag.String("dialect", "cypher", "Query dialect (cypher, sql)")
-	debugFlag   = flag.Bool("debug", false, "Enable debug logging")
-	logfileFlag = flag.String("logfile", "", "Log file path (in addition to LSP window/logMessage)")
-	traceFlag   = flag.Bool("trace", false, "Enable trace logging (very verbose)")
+	dialectFlag      = flag.String("dialect", "cypher", "Query dialect (cypher, sql)")
+	debugFlag        = flag.Bool("debug", false, "Enable debug logging")
+	logfileFlag      = flag.String("logfile", "", "Log file path (in addition to LSP window/logMessage)")
+	traceFlag        = flag.Bool("trace", false, "Enable trace logging (very verbose)")
+	schemaFlag       = flag.String("schema", "", "Schema file (YAML or JSON) to load before any workspace schema")
+	maxFileCacheFlag = flag.Int("max-file-cache", lsp.DefaultMaxFileCache, "Maximum number of imported files to cache (0 = unlimited)")
 )
 
 func main() {
@@ -55,11 +58,19 @@ func main() {
 		zap.String("dialect", *dialectFlag),
 		zap.Bool("debug", *debugFlag),
 		zap.Bool("trace", *traceFlag),
-		zap.String("logfile", *logfileFlag))
+		zap.String("logfile", *logfileFlag),
+		zap.String("schema", *schemaFlag),
+		zap.Int("maxFileCache", *maxFileCacheFlag))
+
+	schema, err := analysis.LoadSchema(*schemaFlag, "")
+	if err != nil {
+		startupLogger.Error("Failed to load schema", zap.String("path", *schemaFlag), zap.Error(err))
+		os.Exit(1)
+	}
 
 	ctx := context.Background()
 
-	err = run(ctx, startupLogger, os.Stdin, os.Stdout, *dialectFlag, level, *logfileFlag)
+	err = run(ctx, startupLogger, os.Stdin, os.Stdout, *dialectFlag, schema, level, *logfileFlag, *maxFileCacheFlag)
 	if err != nil {
 		// EOF is expected when client disconnects - don't treat as fatal
 		if errors.Is(err, io.EOF) {
@@ -76,13 +87,13 @@ func main() {
 	}
 }
 
-func run(ctx context.Context, startupLogger *zap.Logger, in io.Reader, out io.Writer, dialect string, level zapcore.Level, logfile string) error {
+func run(ctx context.Context, startupLogger *zap.Logger, in io.Reader, out io.Writer, dialect string, schema *analysis.TypeSchema, level zapcore.Level, logfile string, maxFileCache int) error {
 	// Create a JSON-RPC stream connection over stdio
 	stream := jsonrpc2.NewStream(&readWriteCloser{in, out})
 	conn := jsonrpc2.NewConn(stream)
 
 	// Create a client to send notifications to the editor
-	client := protocol.ClientDispatcher(conn, startupLogger)
+	client := lsp.NewClient(conn, startupLogger)
 
 	// Create a logger that sends to both LSP window/logMessage and stderr/file.
 	// This ensures logs appear in Neovim's :LspLog (via window/logMessage)
@@ -111,14 +122,22 @@ func run(ctx context.Context, startupLogger *zap.Logger, in io.Reader, out io.Wr
 	logger.Info("LSP connection established, logging to window/logMessage")
 
 	// Create our LSP server with the dual logger
-	server := lsp.NewServer(client, logger, dialect)
+	server := lsp.NewServerWithSchema(client, logger, dialect, schema)
+	server.SetMaxFileCache(maxFileCache)
 
 	// Register the server handler with the connection
-	conn.Go(ctx, protocol.ServerHandler(server, nil))
+	conn.Go(ctx, lsp.NewHandler(server))
 
 	// Wait for the connection to close
 	<-conn.Done()
 
+	// After a shutdown request, however the connection ends is a clean exit
+	if server.ShutdownReceived() {
+		logger.Info("Connection closed after shutdown")
+
+		return nil
+	}
+
 	return conn.Err()
 }
 
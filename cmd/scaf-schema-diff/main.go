@@ -0,0 +1,322 @@
+// Command scaf-schema-diff compares two versions of a scaf type schema.
+//
+// Usage:
+//
+//	scaf-schema-diff [--scaf PATH]... OLD NEW
+//	scaf-schema-diff --live [--scaf PATH]... OLD
+//
+// With --live, OLD is compared against the schema introspected from the Neo4j
+// database configured in .scaf.yaml. The diff lists added, removed, and
+// modified models, then the queries in .scaf files (under the current
+// directory unless --scaf is given) that use a changed model, so their tests
+// can be reviewed. The exit code is 0 when the schemas match and 1 otherwise.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+	"github.com/rlch/scaf/databases/neo4j"
+)
+
+var version = "dev"
+
+// Exit codes.
+const (
+	exitChanged = 1
+	exitError   = 2
+)
+
+// Diff command errors.
+var (
+	ErrUsage   = errors.New("expected OLD and NEW schema paths, or OLD with --live")
+	ErrNoNeo4j = errors.New("--live needs a neo4j section in .scaf.yaml")
+)
+
+func main() {
+	err := diffCommand().Run(context.Background(), os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func diffCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "scaf-schema-diff",
+		Version:   version,
+		Usage:     "Show what changed between two scaf type schemas",
+		ArgsUsage: "OLD [NEW]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "live",
+				Usage: "compare OLD against the schema of the database in .scaf.yaml",
+			},
+			&cli.StringSliceFlag{
+				Name:  "scaf",
+				Usage: ".scaf files or directories to check for affected tests (default: current directory)",
+			},
+		},
+		Action: runDiff,
+	}
+}
+
+func runDiff(_ context.Context, cmd *cli.Command) error {
+	args := cmd.Args().Slice()
+
+	live := cmd.Bool("live")
+	if (live && len(args) != 1) || (!live && len(args) != 2) {
+		return ErrUsage
+	}
+
+	oldSchema, err := analysis.LoadSchema(args[0], "")
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", args[0], err)
+	}
+
+	var newSchema *analysis.TypeSchema
+	if live {
+		newSchema, err = liveSchema()
+	} else {
+		newSchema, err = analysis.LoadSchema(args[1], "")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	out := cmd.Root().Writer
+
+	diff := oldSchema.DiffWith(newSchema)
+	if diff.IsEmpty() {
+		fmt.Fprintln(out, "schemas are identical")
+
+		return nil
+	}
+
+	writeDiff(out, diff)
+
+	paths := cmd.StringSlice("scaf")
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	affected, err := affectedQueries(paths, oldSchema, diff)
+	if err != nil {
+		return err
+	}
+
+	writeAffected(out, affected)
+
+	return cli.Exit("", exitChanged)
+}
+
+// liveSchema introspects the database configured in the nearest .scaf.yaml.
+func liveSchema() (*analysis.TypeSchema, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	configPath, err := scaf.FindConfig(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := scaf.LoadConfigFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if cfg.Neo4j == nil {
+		return nil, ErrNoNeo4j
+	}
+
+	db, err := neo4j.New(cfg.Neo4j)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+
+	return db.ExtractSchema()
+}
+
+// writeDiff prints the diff: + for added, - for removed, ~ for modified.
+func writeDiff(w io.Writer, diff *analysis.SchemaDiff) {
+	for _, name := range diff.AddedModels {
+		fmt.Fprintf(w, "+ model %s\n", name)
+	}
+
+	for _, name := range diff.RemovedModels {
+		fmt.Fprintf(w, "- model %s\n", name)
+	}
+
+	for _, name := range diff.ModifiedModelNames() {
+		md := diff.ModifiedModels[name]
+
+		fmt.Fprintf(w, "~ model %s\n", name)
+
+		for _, field := range md.AddedFields {
+			fmt.Fprintf(w, "    + field %s\n", field)
+		}
+
+		for _, field := range md.RemovedFields {
+			fmt.Fprintf(w, "    - field %s\n", field)
+		}
+
+		for _, field := range sortedFields(md.ChangedFieldTypes) {
+			types := md.ChangedFieldTypes[field]
+			fmt.Fprintf(w, "    ~ field %s: %s -> %s\n", field, types[0], types[1])
+		}
+
+		for _, rel := range md.AddedRelationships {
+			fmt.Fprintf(w, "    + relationship %s\n", rel)
+		}
+
+		for _, rel := range md.RemovedRelationships {
+			fmt.Fprintf(w, "    - relationship %s\n", rel)
+		}
+	}
+}
+
+// affectedQuery is a query whose tests may need updating.
+type affectedQuery struct {
+	path    string
+	line    int
+	name    string
+	reasons []string
+}
+
+// affectedQueries finds queries in the .scaf files under paths that use a
+// removed model, or a modified model together with one of its changed fields
+// or removed relationship types. Matching is textual, so it can over-report.
+func affectedQueries(paths []string, oldSchema *analysis.TypeSchema, diff *analysis.SchemaDiff) ([]affectedQuery, error) {
+	files, err := scaf.CollectFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var affected []affectedQuery
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		suite, err := scaf.Parse(data)
+		if err != nil || suite == nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping %s: %v\n", path, err)
+
+			continue
+		}
+
+		for _, fn := range suite.Functions {
+			if reasons := changeReasons(fn.Body, oldSchema, diff); len(reasons) > 0 {
+				affected = append(affected, affectedQuery{
+					path:    path,
+					line:    fn.Pos.Line,
+					name:    fn.Name,
+					reasons: reasons,
+				})
+			}
+		}
+	}
+
+	return affected, nil
+}
+
+// changeReasons describes how the changes in diff touch a query body.
+func changeReasons(body string, oldSchema *analysis.TypeSchema, diff *analysis.SchemaDiff) []string {
+	var reasons []string
+
+	for _, name := range diff.RemovedModels {
+		if mentions(body, ":\\s*", name) {
+			reasons = append(reasons, name+" removed")
+		}
+	}
+
+	for _, name := range diff.ModifiedModelNames() {
+		if !mentions(body, ":\\s*", name) {
+			continue
+		}
+
+		md := diff.ModifiedModels[name]
+
+		for _, field := range md.RemovedFields {
+			if mentions(body, "\\.", field) {
+				reasons = append(reasons, name+"."+field+" removed")
+			}
+		}
+
+		for _, field := range sortedFields(md.ChangedFieldTypes) {
+			if mentions(body, "\\.", field) {
+				types := md.ChangedFieldTypes[field]
+				reasons = append(reasons, fmt.Sprintf("%s.%s is now %s", name, field, types[1]))
+			}
+		}
+
+		for _, rel := range md.RemovedRelationships {
+			if relType := oldRelType(oldSchema, name, rel); relType != "" && mentions(body, ":\\s*", relType) {
+				reasons = append(reasons, name+"."+rel+" removed")
+			}
+		}
+	}
+
+	return reasons
+}
+
+// mentions reports whether body contains name as a whole word after prefix,
+// a regular expression such as ":" for labels or "\." for properties.
+func mentions(body, prefix, name string) bool {
+	re := regexp.MustCompile(prefix + regexp.QuoteMeta(name) + `\b`)
+
+	return re.MatchString(body)
+}
+
+// oldRelType returns the relationship type of a relationship in the old schema.
+func oldRelType(schema *analysis.TypeSchema, model, rel string) string {
+	m := schema.Models[model]
+	if m == nil {
+		return ""
+	}
+
+	for _, r := range m.Relationships {
+		if r != nil && r.Name == rel {
+			return r.RelType
+		}
+	}
+
+	return ""
+}
+
+// writeAffected prints the queries that may need their tests updated.
+func writeAffected(w io.Writer, affected []affectedQuery) {
+	if len(affected) == 0 {
+		fmt.Fprintln(w, "\nno .scaf queries use the changed models")
+
+		return
+	}
+
+	fmt.Fprintln(w, "\npossibly affected tests:")
+
+	for _, q := range affected {
+		fmt.Fprintf(w, "  %s:%d: %s (%s)\n", q.path, q.line, q.name, strings.Join(q.reasons, "; "))
+	}
+}
+
+// sortedFields returns the field names of a ChangedFieldTypes map in order.
+func sortedFields(changed map[string][2]*analysis.Type) []string {
+	return slices.Sorted(maps.Keys(changed))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+const userSchema = `models:
+  User:
+    fields:
+      id:
+        type: string
+      email:
+        type: string
+`
+
+// runSchemaDiff runs scaf-schema-diff with args and returns its output and
+// exit code.
+func runSchemaDiff(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	exitCode := 0
+
+	cmd := diffCommand()
+	cmd.Writer = &out
+	cmd.ExitErrHandler = func(_ context.Context, _ *cli.Command, err error) {
+		var coder cli.ExitCoder
+		if errors.As(err, &coder) {
+			exitCode = coder.ExitCode()
+		}
+	}
+
+	if err := cmd.Run(t.Context(), append([]string{"scaf-schema-diff"}, args...)); err != nil && exitCode == 0 {
+		t.Fatalf("scaf-schema-diff %v: %v", args, err)
+	}
+
+	return out.String(), exitCode
+}
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestRunDiff_Identical(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	oldPath := writeFile(t, filepath.Join(dir, "old.yaml"), userSchema)
+	newPath := writeFile(t, filepath.Join(dir, "new.yaml"), userSchema)
+
+	out, exitCode := runSchemaDiff(t, "--scaf", dir, oldPath, newPath)
+	if out != "schemas are identical\n" || exitCode != 0 {
+		t.Errorf("output = %q, exit code %d; want identical, 0", out, exitCode)
+	}
+}
+
+func TestRunDiff_AffectedTests(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	oldPath := writeFile(t, filepath.Join(dir, "old.yaml"), userSchema)
+	newPath := writeFile(t, filepath.Join(dir, "new.yaml"), "models:\n  User:\n    fields:\n      id:\n        type: string\n")
+
+	queries := writeFile(t, filepath.Join(dir, "queries", "users.scaf"),
+		"fn ByEmail(email: string) `MATCH (u:User) WHERE u.email = $email RETURN u.id`\n\n"+
+			"fn ByID(id: string) `MATCH (u:User {id: $id}) RETURN u`\n")
+
+	out, exitCode := runSchemaDiff(t, "--scaf", filepath.Join(dir, "queries"), oldPath, newPath)
+
+	want := "~ model User\n" +
+		"    - field email\n" +
+		"\npossibly affected tests:\n" +
+		"  " + queries + ":1: ByEmail (User.email removed)\n"
+	if out != want {
+		t.Errorf("output mismatch:\ngot:\n%s\nwant:\n%s", out, want)
+	}
+
+	if exitCode != exitChanged {
+		t.Errorf("exit code = %d, want %d", exitCode, exitChanged)
+	}
+}
@@ -146,19 +146,16 @@ func runGenerate(ctx context.Context, cmd *cli.Command) error {
 		packageName = cfg.Generate.Package
 	}
 
-	schemaPath := cmd.String("schema")
-	if schemaPath == "" && cfg != nil && cfg.Generate.Schema != "" {
-		schemaPath = cfg.Generate.Schema
-	}
-
-	// Load schema if specified
+	// Load schema if specified, by flag or by the config's path or URL
 	var schema *analysis.TypeSchema
-	if schemaPath != "" {
-		var err error
+	if schemaPath := cmd.String("schema"); schemaPath != "" {
 		schema, err = analysis.LoadSchema(schemaPath, configDir)
-		if err != nil {
-			return fmt.Errorf("loading schema: %w", err)
-		}
+	} else {
+		schema, err = analysis.LoadConfigSchema(cfg, configDir)
+	}
+
+	if err != nil {
+		return fmt.Errorf("loading schema: %w", err)
 	}
 
 	// Get language
@@ -4,15 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/rlch/scaf"
 	"github.com/rlch/scaf/analysis"
 	_ "github.com/rlch/scaf/databases/neo4j"
+	_ "github.com/rlch/scaf/databases/postgres"
 	_ "github.com/rlch/scaf/dialects/cypher"
+	_ "github.com/rlch/scaf/dialects/sql"
 	"github.com/rlch/scaf/module"
 	"github.com/rlch/scaf/runner"
 	"github.com/urfave/cli/v3"
@@ -21,8 +22,9 @@ import (
 // Test command errors.
 var (
 	ErrNoScafFiles         = errors.New("no .scaf files found")
-	ErrNoDatabase          = errors.New("no database specified (use neo4j config in .scaf.yaml)")
+	ErrNoDatabase          = errors.New("no database specified (use neo4j or postgres config in .scaf.yaml)")
 	ErrNoConnectionURI     = errors.New("no connection URI specified (use --uri or .scaf.yaml)")
+	ErrNoConnectionConfig  = errors.New("--connection needs a .scaf.yaml with connections")
 	ErrUnsupportedDatabase = errors.New("unsupported database")
 	ErrDiagnosticErrors    = errors.New("scaf files contain errors")
 )
@@ -30,14 +32,21 @@ var (
 func testCommand() *cli.Command {
 	return &cli.Command{
 		Name:      "test",
+		Aliases:   []string{"run"},
 		Usage:     "Run scaf tests",
-		ArgsUsage: "[files or directories...]",
+		ArgsUsage: "[files, directories, or globs...]",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "database",
 				Aliases: []string{"d"},
 				Usage:   "database to use (overrides config)",
 			},
+			&cli.StringFlag{
+				Name:    "connection",
+				Aliases: []string{"c"},
+				Usage:   "connection profile from .scaf.yaml (overrides default_connection)",
+				Sources: cli.EnvVars("SCAF_CONNECTION"),
+			},
 			&cli.StringFlag{
 				Name:    "uri",
 				Usage:   "database connection URI",
@@ -59,6 +68,10 @@ func testCommand() *cli.Command {
 				Name:  "json",
 				Usage: "output results as JSON",
 			},
+			&cli.BoolFlag{
+				Name:  "junit",
+				Usage: "output a JUnit XML report",
+			},
 			&cli.BoolFlag{
 				Name:    "verbose",
 				Aliases: []string{"v"},
@@ -72,6 +85,19 @@ func testCommand() *cli.Command {
 				Name:  "run",
 				Usage: "run only tests matching pattern",
 			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "time limit for each test (overrides test_timeout in config; default 60s)",
+			},
+			&cli.BoolFlag{
+				Name:  "profile",
+				Usage: "print p50/p95/p99 durations per query and test after the run",
+			},
+			&cli.DurationFlag{
+				Name:  "slow-threshold",
+				Usage: "with --profile, mark entries whose p99 exceeds this duration as slow",
+				Value: runner.DefaultSlowThreshold,
+			},
 			&cli.BoolFlag{
 				Name:   "lag",
 				Usage:  "add artificial lag (500ms-1.5s) for TUI testing",
@@ -97,7 +123,7 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	// Collect test files
-	files, err := collectTestFiles(args)
+	files, err := scaf.CollectFiles(args)
 	if err != nil {
 		return err
 	}
@@ -129,6 +155,18 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 		if configErr == nil && loadedCfg.Neo4j != nil {
 			neo4jCfg = loadedCfg.Neo4j
 		}
+		// Apply the connection profile (flag > default_connection)
+		if configErr == nil {
+			conn, err := loadedCfg.Connection(cmd.String("connection"))
+			if err != nil {
+				return err
+			}
+			if conn != nil {
+				neo4jCfg = conn.Neo4j(neo4jCfg)
+			}
+		} else if cmd.String("connection") != "" {
+			return ErrNoConnectionConfig
+		}
 		// Override with flags if provided
 		if uri := cmd.String("uri"); uri != "" {
 			neo4jCfg.URI = uri
@@ -143,6 +181,34 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 			return ErrNoConnectionURI
 		}
 		dbCfg = neo4jCfg
+	case scaf.DatabasePostgres:
+		pgCfg := &scaf.PostgresConfig{}
+		if configErr == nil && loadedCfg.Postgres != nil {
+			pgCfg = loadedCfg.Postgres
+		}
+		// Apply the connection profile (flag > default_connection)
+		if configErr == nil {
+			conn, err := loadedCfg.Connection(cmd.String("connection"))
+			if err != nil {
+				return err
+			}
+			if conn != nil {
+				pgCfg = conn.Postgres(pgCfg)
+			}
+		} else if cmd.String("connection") != "" {
+			return ErrNoConnectionConfig
+		}
+		// Override with flags if provided; unset settings fall back to PG* variables
+		if uri := cmd.String("uri"); uri != "" {
+			pgCfg.URI = uri
+		}
+		if username := cmd.String("username"); username != "" {
+			pgCfg.User = username
+		}
+		if password := cmd.String("password"); password != "" {
+			pgCfg.Password = password
+		}
+		dbCfg = pgCfg
 	default:
 		return fmt.Errorf("%w: %s", ErrUnsupportedDatabase, databaseName)
 	}
@@ -188,12 +254,25 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
-	queryAnalyzer := scaf.GetAnalyzer(dialectName)
-	analyzer := analysis.NewAnalyzerWithQueryAnalyzer(nil, nil, queryAnalyzer)
+	analyzeOpts := &analysis.AnalyzeOptions{Dialect: dialectName}
+
+	// The schema, from the config's path or URL, enables schema-aware checks
+	if configErr == nil {
+		schema, err := analysis.LoadConfigSchema(loadedCfg, configDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to load schema: %v\n", err)
+		}
+
+		analyzeOpts.Schema = schema
+	}
 
 	var hasErrors bool
 	for _, ps := range suites {
-		result := analyzer.Analyze(ps.path, ps.data)
+		result, err := analysis.AnalyzeFile(ps.path, ps.data, analyzeOpts)
+		if err != nil {
+			return err
+		}
+
 		if result.HasErrors() {
 			hasErrors = true
 			// Print errors
@@ -229,6 +308,9 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 	case cmd.Bool("json"):
 		formatter := runner.NewJSONFormatter(os.Stdout)
 		formatHandler = runner.NewFormatHandler(formatter, os.Stderr)
+	case cmd.Bool("junit"):
+		formatter := runner.NewJUnitFormatter(os.Stdout)
+		formatHandler = runner.NewFormatHandler(formatter, os.Stderr)
 	case verbose:
 		formatter := runner.NewVerboseFormatter(os.Stdout)
 		formatHandler = runner.NewFormatHandler(formatter, os.Stderr)
@@ -251,6 +333,19 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 		formatHandler = tuiHandler
 	}
 
+	// Test timeouts (flag > config > default); per-scope overrides come from config
+	timeout := scaf.DefaultTestTimeout
+
+	var scopeTimeouts map[string]time.Duration
+	if configErr == nil {
+		timeout = loadedCfg.TestTimeoutDuration()
+		scopeTimeouts = loadedCfg.ScopeTimeouts()
+	}
+
+	if cmd.IsSet("timeout") {
+		timeout = cmd.Duration("timeout")
+	}
+
 	// Run all test files
 	var totalResult *runner.Result
 
@@ -263,6 +358,8 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 			runner.WithFilter(cmd.String("run")),
 			runner.WithModules(ps.resolved),
 			runner.WithLag(cmd.Bool("lag")),
+			runner.WithTimeout(timeout),
+			runner.WithScopeTimeouts(scopeTimeouts),
 		)
 
 		result, err := suiteRunner.Run(ctx, ps.suite, ps.path)
@@ -283,42 +380,21 @@ func runTest(ctx context.Context, cmd *cli.Command) error {
 			_ = summarizer.Summary(totalResult)
 		}
 
-		if !totalResult.Ok() {
-			return cli.Exit("", 1)
-		}
-	}
-
-	return nil
-}
-
-func collectTestFiles(args []string) ([]string, error) {
-	var files []string
+		if cmd.Bool("profile") {
+			// Keep stdout parseable when it carries a JSON or JUnit report
+			out := os.Stdout
+			if cmd.Bool("json") || cmd.Bool("junit") {
+				out = os.Stderr
+			}
 
-	for _, arg := range args {
-		info, err := os.Stat(arg)
-		if err != nil {
-			return nil, err
+			_, _ = fmt.Fprintln(out)
+			_ = runner.WriteProfile(out, totalResult.Profile(), cmd.Duration("slow-threshold"))
 		}
 
-		if info.IsDir() {
-			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
-				if err != nil {
-					return err
-				}
-
-				if !d.IsDir() && strings.HasSuffix(path, ".scaf") {
-					files = append(files, path)
-				}
-
-				return nil
-			})
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			files = append(files, arg)
+		if !totalResult.Ok() {
+			return cli.Exit("", 1)
 		}
 	}
 
-	return files, nil
+	return nil
 }
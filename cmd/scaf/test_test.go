@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+const sqlExample = "../../example/sql"
+
+func runScaf(t *testing.T, args ...string) error {
+	t.Helper()
+
+	app := &cli.Command{
+		Name:     "scaf",
+		Commands: []*cli.Command{testCommand()},
+	}
+
+	return app.Run(t.Context(), append([]string{"scaf"}, args...))
+}
+
+func TestRunTest_SQLExampleUsesPostgres(t *testing.T) {
+	// Nothing listens on port 1, so getting as far as connecting shows the
+	// example analyzes cleanly and selects a registered database
+	err := runScaf(t, "test", "--json", "--uri", "postgres://127.0.0.1:1/app?connect_timeout=5", sqlExample)
+	if err == nil {
+		t.Fatal("runTest() succeeded without a database")
+	}
+
+	if errors.Is(err, ErrUnsupportedDatabase) || errors.Is(err, ErrDiagnosticErrors) {
+		t.Fatalf("runTest() error = %v, want a connection error", err)
+	}
+
+	if !strings.Contains(err.Error(), "postgres: failed to connect") {
+		t.Errorf("runTest() error = %v, want a postgres connection error", err)
+	}
+}
+
+// TestRunTest_SQLExample_Integration runs the SQL example against a real
+// PostgreSQL instance. Set SCAF_POSTGRES_URI to run.
+func TestRunTest_SQLExample_Integration(t *testing.T) {
+	uri := os.Getenv("SCAF_POSTGRES_URI")
+	if uri == "" {
+		t.Skip("SCAF_POSTGRES_URI not set, skipping integration test")
+	}
+
+	if err := runScaf(t, "test", "--json", "--uri", uri, sqlExample); err != nil {
+		t.Fatalf("scaf test %s: %v", sqlExample, err)
+	}
+}
@@ -1,8 +1,14 @@
 package scaf
 
 import (
+	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,8 +21,79 @@ type Config struct {
 	Neo4j    *Neo4jConfig    `yaml:"neo4j,omitempty"`
 	Postgres *PostgresConfig `yaml:"postgres,omitempty"`
 
+	// Connections are named connection profiles for the configured
+	// database, such as "dev", "staging", and "prod". The active profile
+	// overrides the database config's connection settings.
+	Connections map[string]*ConnectionConfig `yaml:"connections,omitempty"`
+
+	// DefaultConnection names the profile in Connections to use when none
+	// is requested. Empty means the database config is used as is.
+	DefaultConnection string `yaml:"default_connection,omitempty"`
+
 	// Generate config for code generation
 	Generate GenerateConfig `yaml:"generate,omitempty"`
+
+	// SchemaURL is an HTTP endpoint serving the schema as JSON, for setups
+	// where the database can't be introspected locally. When set, it takes
+	// precedence over Generate.Schema (see analysis.LoadConfigSchema).
+	SchemaURL string `yaml:"schema_url,omitempty"`
+
+	// Analysis config for semantic analysis rules
+	Analysis AnalysisConfig `yaml:"analysis,omitempty"`
+
+	// Cypher config for the Cypher dialect
+	Cypher CypherConfig `yaml:"cypher,omitempty"`
+
+	// TestTimeout bounds the execution of each test, as a duration such as
+	// "30s". Empty means DefaultTestTimeout.
+	TestTimeout string `yaml:"test_timeout,omitempty"`
+
+	// Timeouts overrides TestTimeout for the tests of a query scope, keyed by
+	// the scope's function name.
+	Timeouts map[string]string `yaml:"timeouts,omitempty"`
+}
+
+// DefaultTestTimeout is the test timeout used when TestTimeout is empty.
+const DefaultTestTimeout = 60 * time.Second
+
+// TestTimeoutDuration returns TestTimeout as a duration, or
+// DefaultTestTimeout if it is empty or invalid.
+func (c *Config) TestTimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(c.TestTimeout)
+	if err != nil {
+		return DefaultTestTimeout
+	}
+
+	return d
+}
+
+// ScopeTimeouts returns the valid entries of Timeouts as durations.
+func (c *Config) ScopeTimeouts() map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(c.Timeouts))
+	for scope, value := range c.Timeouts {
+		if d, err := time.ParseDuration(value); err == nil {
+			timeouts[scope] = d
+		}
+	}
+
+	return timeouts
+}
+
+// validateTimeouts reports a test timeout that isn't a valid duration.
+func (c *Config) validateTimeouts() error {
+	if c.TestTimeout != "" {
+		if _, err := time.ParseDuration(c.TestTimeout); err != nil {
+			return fmt.Errorf("%w %q for test_timeout", ErrInvalidTimeout, c.TestTimeout)
+		}
+	}
+
+	for _, scope := range slices.Sorted(maps.Keys(c.Timeouts)) {
+		if _, err := time.ParseDuration(c.Timeouts[scope]); err != nil {
+			return fmt.Errorf("%w %q for scope %s", ErrInvalidTimeout, c.Timeouts[scope], scope)
+		}
+	}
+
+	return nil
 }
 
 // Neo4jConfig holds Neo4j connection settings.
@@ -25,6 +102,105 @@ type Neo4jConfig struct {
 	Username string `yaml:"username,omitempty"`
 	Password string `yaml:"password,omitempty"`
 	Database string `yaml:"database,omitempty"`
+
+	// ReadOnly opens sessions in read access mode, so the server rejects
+	// writes.
+	ReadOnly bool `yaml:"read_only,omitempty"`
+}
+
+// ConnectionConfig is a named connection profile. Password may reference
+// environment variables as $VAR or ${VAR}; LoadConfigFile expands them.
+type ConnectionConfig struct {
+	URI      string `yaml:"uri"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// ReadOnly marks a connection that tests must not write through, such
+	// as production.
+	ReadOnly bool `yaml:"read_only,omitempty"`
+}
+
+// Connection returns the connection profile called name, or the default
+// profile if name is empty. It returns nil if name is empty and there is no
+// default, and ErrUnknownConnection if the profile doesn't exist.
+func (c *Config) Connection(name string) (*ConnectionConfig, error) {
+	if name == "" {
+		name = c.DefaultConnection
+	}
+
+	if name == "" {
+		return nil, nil //nolint:nilnil // No profile selected
+	}
+
+	conn, ok := c.Connections[name]
+	if !ok || conn == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownConnection, name)
+	}
+
+	return conn, nil
+}
+
+// Neo4j returns cfg with the profile's connection settings applied. Settings
+// the profile leaves empty keep their values from cfg, which may be nil.
+func (c *ConnectionConfig) Neo4j(cfg *Neo4jConfig) *Neo4jConfig {
+	merged := Neo4jConfig{}
+	if cfg != nil {
+		merged = *cfg
+	}
+
+	if c.URI != "" {
+		merged.URI = c.URI
+	}
+
+	if c.Username != "" {
+		merged.Username = c.Username
+	}
+
+	if c.Password != "" {
+		merged.Password = c.Password
+	}
+
+	merged.ReadOnly = merged.ReadOnly || c.ReadOnly
+
+	return &merged
+}
+
+// Postgres returns cfg with the profile's connection settings applied, as
+// Neo4j does. A profile URI replaces the host, port, and database settings.
+func (c *ConnectionConfig) Postgres(cfg *PostgresConfig) *PostgresConfig {
+	merged := PostgresConfig{}
+	if cfg != nil {
+		merged = *cfg
+	}
+
+	if c.URI != "" {
+		merged.URI = c.URI
+	}
+
+	if c.Username != "" {
+		merged.User = c.Username
+	}
+
+	if c.Password != "" {
+		merged.Password = c.Password
+	}
+
+	merged.ReadOnly = merged.ReadOnly || c.ReadOnly
+
+	return &merged
+}
+
+// validateConnections reports a default connection that isn't defined.
+func (c *Config) validateConnections() error {
+	if c.DefaultConnection == "" {
+		return nil
+	}
+
+	if _, err := c.Connection(""); err != nil {
+		return fmt.Errorf("default_connection: %w", err)
+	}
+
+	return nil
 }
 
 // PostgresConfig holds PostgreSQL connection settings.
@@ -37,6 +213,10 @@ type PostgresConfig struct {
 	SSLMode  string `yaml:"sslmode,omitempty"`
 	// Alternative: connection string
 	URI string `yaml:"uri,omitempty"`
+
+	// ReadOnly makes every transaction read-only, so the server rejects
+	// writes.
+	ReadOnly bool `yaml:"read_only,omitempty"`
 }
 
 // DatabaseName returns the configured database name, or empty if none.
@@ -70,11 +250,95 @@ type GenerateConfig struct {
 	// Package name for generated code (Go-specific)
 	Package string `yaml:"package,omitempty"`
 
-	// Schema is the path to the schema YAML file (e.g., ".scaf-schema.yaml").
+	// Schema is the path to the schema file (e.g., ".scaf-schema.yaml").
+	// Files ending in .json are read as JSON; anything else as YAML.
 	// The schema provides type information for accurate code generation.
 	Schema string `yaml:"schema,omitempty"`
 }
 
+// AnalysisConfig holds per-project settings for semantic analysis.
+type AnalysisConfig struct {
+	// Rules configures analysis rules by name (e.g., "unused-import").
+	// Rules not listed keep their defaults.
+	Rules map[string]RuleConfig `yaml:"rules,omitempty"`
+}
+
+// RuleConfig configures a single analysis rule.
+type RuleConfig struct {
+	// Enabled turns the rule on or off. Defaults to true when omitted.
+	Enabled bool `yaml:"enabled"`
+
+	// Severity overrides the rule's default severity: "error", "warning",
+	// "info", or "hint". Empty keeps the default.
+	Severity string `yaml:"severity,omitempty"`
+}
+
+// RuleSeverities are the severity names accepted by RuleConfig.Severity.
+var RuleSeverities = []string{"error", "warning", "info", "hint"}
+
+// UnmarshalYAML decodes a rule config, enabling the rule unless it says otherwise.
+func (r *RuleConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain RuleConfig
+
+	cfg := plain{Enabled: true}
+	if err := node.Decode(&cfg); err != nil {
+		return err
+	}
+
+	*r = RuleConfig(cfg)
+
+	return nil
+}
+
+// validate reports rules configured with an unknown severity.
+func (c *AnalysisConfig) validate() error {
+	for _, name := range slices.Sorted(maps.Keys(c.Rules)) {
+		severity := c.Rules[name].Severity
+		if severity != "" && !slices.Contains(RuleSeverities, severity) {
+			return fmt.Errorf("%w %q for rule %s (want one of %s)",
+				ErrInvalidSeverity, severity, name, strings.Join(RuleSeverities, ", "))
+		}
+	}
+
+	return nil
+}
+
+// CypherConfig holds per-project settings for the Cypher dialect.
+type CypherConfig struct {
+	// Formatting sets the defaults for formatting Cypher query bodies.
+	Formatting CypherFormatConfig `yaml:"formatting,omitempty"`
+}
+
+// CypherFormatConfig configures Cypher formatting. Unset fields keep the
+// dialect's defaults.
+type CypherFormatConfig struct {
+	// KeywordCase is "upper", "lower", or "mixed" to keep keywords as written.
+	KeywordCase string `yaml:"keyword_case,omitempty"`
+
+	// IndentWidth is the number of spaces per indentation level.
+	IndentWidth int `yaml:"indent_width,omitempty"`
+
+	// MaxLineLength is the width past which clauses wrap at their commas.
+	MaxLineLength int `yaml:"max_line_length,omitempty"`
+
+	// AlignClauses right-aligns clause keywords so clause bodies start in
+	// the same column.
+	AlignClauses bool `yaml:"align_clauses,omitempty"`
+}
+
+// KeywordCases are the keyword cases accepted by CypherFormatConfig.KeywordCase.
+var KeywordCases = []string{"upper", "lower", "mixed"}
+
+// validate reports an unknown keyword case.
+func (c *CypherFormatConfig) validate() error {
+	if c.KeywordCase != "" && !slices.Contains(KeywordCases, c.KeywordCase) {
+		return fmt.Errorf("%w %q (want one of %s)",
+			ErrInvalidKeywordCase, c.KeywordCase, strings.Join(KeywordCases, ", "))
+	}
+
+	return nil
+}
+
 // DefaultConfigNames are the filenames we search for.
 var DefaultConfigNames = []string{".scaf.yaml", ".scaf.yml", "scaf.yaml", "scaf.yml"}
 
@@ -88,8 +352,27 @@ func LoadConfig(dir string) (*Config, error) {
 	return LoadConfigFile(path)
 }
 
+// ConfigEnvVar is the environment variable that names the config file to use,
+// overriding the search in FindConfig.
+const ConfigEnvVar = "SCAF_CONFIG"
+
 // FindConfig searches for a config file starting from dir and walking up.
+// If ConfigEnvVar is set, it returns that path instead, or an error if the
+// file doesn't exist.
 func FindConfig(dir string) (string, error) {
+	if path := os.Getenv(ConfigEnvVar); path != "" {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := os.Stat(absPath); err != nil {
+			return "", fmt.Errorf("%s: %w", ConfigEnvVar, err)
+		}
+
+		return absPath, nil
+	}
+
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return "", err
@@ -114,6 +397,140 @@ func FindConfig(dir string) (string, error) {
 	}
 }
 
+// skippedConfigDirs are directories FindAllConfigs never descends into.
+var skippedConfigDirs = map[string]bool{
+	"node_modules": true,
+	".git":         true,
+}
+
+// FindAllConfigs walks root and returns the path of every config file below
+// it, for workspaces with several sub-projects. A directory with more than one
+// config file contributes the one FindConfig would pick. Paths are sorted by
+// depth, shallowest first, then by path.
+func FindAllConfigs(root string) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+
+	err = filepath.WalkDir(absRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		if path != absRoot && skippedConfigDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+
+		for _, name := range DefaultConfigNames {
+			candidate := filepath.Join(path, name)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				paths = append(paths, candidate)
+
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(paths, func(a, b string) int {
+		if da, db := pathDepth(a), pathDepth(b); da != db {
+			return da - db
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	return paths, nil
+}
+
+// LoadConfigs loads every config found by FindAllConfigs, in the same order.
+// Each config inherits the generate settings it leaves unset from the nearest
+// config in a parent directory. Database connections and the schema path are
+// never inherited: they belong to a single sub-project.
+// Returns ErrConfigNotFound if root contains no config.
+func LoadConfigs(root string) ([]*Config, error) {
+	paths, err := FindAllConfigs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, ErrConfigNotFound
+	}
+
+	configs := make([]*Config, len(paths))
+	byDir := make(map[string]*Config, len(paths))
+
+	for i, path := range paths {
+		cfg, err := LoadConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		// Parents sort before their children, so they are already merged.
+		dir := filepath.Dir(path)
+		if parent := nearestParentConfig(dir, byDir); parent != nil {
+			cfg.Generate.inherit(parent.Generate)
+		}
+
+		configs[i] = cfg
+		byDir[dir] = cfg
+	}
+
+	return configs, nil
+}
+
+// nearestParentConfig returns the config of the closest ancestor of dir.
+func nearestParentConfig(dir string, byDir map[string]*Config) *Config {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+
+		if cfg, ok := byDir[parent]; ok {
+			return cfg
+		}
+
+		dir = parent
+	}
+}
+
+// inherit fills the settings g leaves unset from parent, except Schema.
+func (g *GenerateConfig) inherit(parent GenerateConfig) {
+	if g.Lang == "" {
+		g.Lang = parent.Lang
+	}
+
+	if g.Adapter == "" {
+		g.Adapter = parent.Adapter
+	}
+
+	if g.Out == "" {
+		g.Out = parent.Out
+	}
+
+	if g.Package == "" {
+		g.Package = parent.Package
+	}
+}
+
+// pathDepth returns the number of path separators in path.
+func pathDepth(path string) int {
+	return strings.Count(filepath.Clean(path), string(filepath.Separator))
+}
+
 // LoadConfigFile loads a config from a specific path.
 func LoadConfigFile(path string) (*Config, error) {
 	data, err := os.ReadFile(filepath.Clean(path))
@@ -128,5 +545,28 @@ func LoadConfigFile(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.Analysis.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := cfg.Cypher.Formatting.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := cfg.validateTimeouts(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := cfg.validateConnections(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Passwords can come from the environment rather than the file
+	for _, conn := range cfg.Connections {
+		if conn != nil {
+			conn.Password = os.ExpandEnv(conn.Password)
+		}
+	}
+
 	return &cfg, nil
 }
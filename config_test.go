@@ -0,0 +1,392 @@
+package scaf_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/rlch/scaf"
+)
+
+// writeConfigs writes each config body to its path relative to root.
+func writeConfigs(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for rel, body := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindAllConfigs(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		"services/billing/.scaf.yaml":          "",
+		"services/auth/.scaf.yaml":             "",
+		"services/auth/scaf.yml":               "", // Shadowed by .scaf.yaml
+		".scaf.yaml":                           "",
+		"tools/deep/nested/scaf.yaml":          "",
+		"node_modules/pkg/.scaf.yaml":          "",
+		".git/modules/x/.scaf.yaml":            "",
+		"services/auth/node_modules/.scaf.yml": "",
+	})
+
+	got, err := scaf.FindAllConfigs(root)
+	if err != nil {
+		t.Fatalf("FindAllConfigs() error: %v", err)
+	}
+
+	for i, path := range got {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[i] = filepath.ToSlash(rel)
+	}
+
+	want := []string{
+		".scaf.yaml",
+		"services/auth/.scaf.yaml",
+		"services/billing/.scaf.yaml",
+		"tools/deep/nested/scaf.yaml",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindAllConfigs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFindAllConfigs_None(t *testing.T) {
+	t.Parallel()
+
+	got, err := scaf.FindAllConfigs(t.TempDir())
+	if err != nil {
+		t.Fatalf("FindAllConfigs() error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("FindAllConfigs() = %v, want none", got)
+	}
+}
+
+func TestLoadConfigs(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml": `
+neo4j:
+  uri: bolt://root:7687
+generate:
+  lang: go
+  adapter: neogo
+  package: queries
+  schema: .scaf-schema.yaml
+`,
+		"services/auth/.scaf.yaml": `
+neo4j:
+  uri: bolt://auth:7687
+generate:
+  package: auth
+  schema: auth-schema.yaml
+`,
+		"services/auth/internal/.scaf.yaml": `
+generate:
+  out: gen
+`,
+		"services/billing/.scaf.yaml": `
+generate:
+  adapter: custom
+`,
+	})
+
+	got, err := scaf.LoadConfigs(root)
+	if err != nil {
+		t.Fatalf("LoadConfigs() error: %v", err)
+	}
+
+	want := []*scaf.Config{
+		{
+			Neo4j:    &scaf.Neo4jConfig{URI: "bolt://root:7687"},
+			Generate: scaf.GenerateConfig{Lang: "go", Adapter: "neogo", Package: "queries", Schema: ".scaf-schema.yaml"},
+		},
+		{
+			Neo4j:    &scaf.Neo4jConfig{URI: "bolt://auth:7687"},
+			Generate: scaf.GenerateConfig{Lang: "go", Adapter: "neogo", Package: "auth", Schema: "auth-schema.yaml"},
+		},
+		{
+			// Neither the connection nor the schema is inherited
+			Generate: scaf.GenerateConfig{Lang: "go", Adapter: "custom", Package: "queries"},
+		},
+		{
+			// Inherits from services/auth, the nearest parent config
+			Generate: scaf.GenerateConfig{Lang: "go", Adapter: "neogo", Out: "gen", Package: "auth"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("LoadConfigs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadConfigs_NotFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := scaf.LoadConfigs(t.TempDir())
+	if !errors.Is(err, scaf.ErrConfigNotFound) {
+		t.Errorf("LoadConfigs() error = %v, want ErrConfigNotFound", err)
+	}
+}
+
+func TestLoadConfigs_Invalid(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		"a/.scaf.yaml": "neo4j: [not, a, map]",
+	})
+
+	if _, err := scaf.LoadConfigs(root); err == nil {
+		t.Error("LoadConfigs() error = nil, want parse error")
+	}
+}
+
+func TestLoadConfigFile_Analysis(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml": `analysis:
+  rules:
+    unused-import:
+      severity: error
+    empty-test:
+      enabled: false
+    unused-query-param:
+      enabled: true
+      severity: warning
+`,
+	})
+
+	cfg, err := scaf.LoadConfigFile(filepath.Join(root, ".scaf.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error: %v", err)
+	}
+
+	want := scaf.AnalysisConfig{Rules: map[string]scaf.RuleConfig{
+		// Enabled defaults to true when only the severity is set
+		"unused-import":      {Enabled: true, Severity: "error"},
+		"empty-test":         {Enabled: false},
+		"unused-query-param": {Enabled: true, Severity: "warning"},
+	}}
+	if diff := cmp.Diff(want, cfg.Analysis); diff != "" {
+		t.Errorf("Analysis mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadConfigFile_InvalidSeverity(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml": "analysis:\n  rules:\n    unused-import:\n      severity: fatal\n",
+	})
+
+	_, err := scaf.LoadConfigFile(filepath.Join(root, ".scaf.yaml"))
+	if !errors.Is(err, scaf.ErrInvalidSeverity) {
+		t.Errorf("LoadConfigFile() error = %v, want ErrInvalidSeverity", err)
+	}
+}
+
+func TestLoadConfigFile_CypherFormatting(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml": `cypher:
+  formatting:
+    keyword_case: lower
+    indent_width: 4
+    max_line_length: 100
+    align_clauses: true
+`,
+	})
+
+	cfg, err := scaf.LoadConfigFile(filepath.Join(root, ".scaf.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error: %v", err)
+	}
+
+	want := scaf.CypherFormatConfig{KeywordCase: "lower", IndentWidth: 4, MaxLineLength: 100, AlignClauses: true}
+	if diff := cmp.Diff(want, cfg.Cypher.Formatting); diff != "" {
+		t.Errorf("Cypher.Formatting mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadConfigFile_InvalidKeywordCase(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml": "cypher:\n  formatting:\n    keyword_case: title\n",
+	})
+
+	_, err := scaf.LoadConfigFile(filepath.Join(root, ".scaf.yaml"))
+	if !errors.Is(err, scaf.ErrInvalidKeywordCase) {
+		t.Errorf("LoadConfigFile() error = %v, want ErrInvalidKeywordCase", err)
+	}
+}
+
+func TestLoadConfigFile_Timeouts(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml":    "test_timeout: 30s\ntimeouts:\n  SlowReport: 5m\n",
+		"defaults.yaml": "neo4j:\n  uri: bolt://localhost\n",
+	})
+
+	cfg, err := scaf.LoadConfigFile(filepath.Join(root, ".scaf.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error: %v", err)
+	}
+
+	if got := cfg.TestTimeoutDuration(); got != 30*time.Second {
+		t.Errorf("TestTimeoutDuration() = %s, want 30s", got)
+	}
+
+	want := map[string]time.Duration{"SlowReport": 5 * time.Minute}
+	if diff := cmp.Diff(want, cfg.ScopeTimeouts()); diff != "" {
+		t.Errorf("ScopeTimeouts() mismatch (-want +got):\n%s", diff)
+	}
+
+	cfg, err = scaf.LoadConfigFile(filepath.Join(root, "defaults.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error: %v", err)
+	}
+
+	if got := cfg.TestTimeoutDuration(); got != scaf.DefaultTestTimeout {
+		t.Errorf("TestTimeoutDuration() = %s, want the default", got)
+	}
+}
+
+func TestLoadConfigFile_InvalidTimeout(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml":   "test_timeout: soon\n",
+		"scoped.yaml":  "timeouts:\n  GetUser: 10\n",
+		"valid.yaml":   "timeouts:\n  GetUser: 10s\n",
+		"nothing.yaml": "\n",
+	})
+
+	for name, wantErr := range map[string]bool{
+		".scaf.yaml":   true,
+		"scoped.yaml":  true,
+		"valid.yaml":   false,
+		"nothing.yaml": false,
+	} {
+		_, err := scaf.LoadConfigFile(filepath.Join(root, name))
+		if got := errors.Is(err, scaf.ErrInvalidTimeout); got != wantErr {
+			t.Errorf("%s: LoadConfigFile() error = %v, want ErrInvalidTimeout: %t", name, err, wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFile_Connections(t *testing.T) {
+	t.Setenv("SCAF_TEST_STAGING_PASSWORD", "s3cret")
+
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		".scaf.yaml": `neo4j:
+  uri: bolt://localhost:7687
+  database: app
+default_connection: dev
+connections:
+  dev:
+    uri: bolt://localhost:7687
+  staging:
+    uri: bolt://staging:7687
+    username: neo4j
+    password: $SCAF_TEST_STAGING_PASSWORD
+    read_only: true
+`,
+		"undefined.yaml": "default_connection: prod\nconnections:\n  dev:\n    uri: bolt://localhost\n",
+	})
+
+	cfg, err := scaf.LoadConfigFile(filepath.Join(root, ".scaf.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error: %v", err)
+	}
+
+	conn, err := cfg.Connection("")
+	if err != nil || conn == nil || conn.URI != "bolt://localhost:7687" {
+		t.Errorf("Connection(\"\") = %+v, %v; want the dev profile", conn, err)
+	}
+
+	conn, err = cfg.Connection("staging")
+	if err != nil {
+		t.Fatalf("Connection(staging) error: %v", err)
+	}
+
+	want := &scaf.Neo4jConfig{
+		URI:      "bolt://staging:7687",
+		Username: "neo4j",
+		Password: "s3cret",
+		Database: "app",
+		ReadOnly: true,
+	}
+	if diff := cmp.Diff(want, conn.Neo4j(cfg.Neo4j)); diff != "" {
+		t.Errorf("Neo4j() mismatch (-want +got):\n%s", diff)
+	}
+
+	wantPostgres := &scaf.PostgresConfig{
+		Host:     "localhost",
+		User:     "neo4j",
+		Password: "s3cret",
+		URI:      "bolt://staging:7687",
+		ReadOnly: true,
+	}
+	if diff := cmp.Diff(wantPostgres, conn.Postgres(&scaf.PostgresConfig{Host: "localhost", User: "app"})); diff != "" {
+		t.Errorf("Postgres() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := cfg.Connection("prod"); !errors.Is(err, scaf.ErrUnknownConnection) {
+		t.Errorf("Connection(prod) error = %v, want ErrUnknownConnection", err)
+	}
+
+	if _, err := scaf.LoadConfigFile(filepath.Join(root, "undefined.yaml")); !errors.Is(err, scaf.ErrUnknownConnection) {
+		t.Errorf("LoadConfigFile() error = %v, want ErrUnknownConnection", err)
+	}
+}
+
+func TestFindConfig_EnvOverride(t *testing.T) {
+	root := t.TempDir()
+	writeConfigs(t, root, map[string]string{
+		"project/.scaf.yaml": "",
+		"ci/scaf.yaml":       "",
+	})
+
+	forced := filepath.Join(root, "ci", "scaf.yaml")
+	t.Setenv(scaf.ConfigEnvVar, forced)
+
+	got, err := scaf.FindConfig(filepath.Join(root, "project"))
+	if err != nil || got != forced {
+		t.Errorf("FindConfig() = %q, %v; want %q", got, err, forced)
+	}
+
+	t.Setenv(scaf.ConfigEnvVar, filepath.Join(root, "missing.yaml"))
+
+	if _, err := scaf.FindConfig(filepath.Join(root, "project")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("FindConfig() error = %v, want not exist", err)
+	}
+}
@@ -43,6 +43,17 @@ type TransactionalDatabase interface {
 	Begin(ctx context.Context) (DatabaseTransaction, error)
 }
 
+// DiscardableDatabase is implemented by databases that can abandon the
+// connection a query ran on. The runner calls Discard after a test times out,
+// since the query may still be running on the server and the connection
+// can't safely be reused.
+type DiscardableDatabase interface {
+	Database
+
+	// Discard drops the current connection; later queries use a new one.
+	Discard(ctx context.Context) error
+}
+
 // DatabaseFactory creates a Database from configuration.
 type DatabaseFactory func(cfg any) (Database, error)
 
@@ -30,10 +30,11 @@ func init() {
 
 // Database implements scaf.Database and scaf.TransactionalDatabase for Neo4j.
 type Database struct {
-	driver  neo4j.DriverWithContext
-	session neo4j.SessionWithContext
-	db      string
-	dialect scaf.Dialect
+	driver     neo4j.DriverWithContext
+	session    neo4j.SessionWithContext
+	sessionCfg neo4j.SessionConfig
+	db         string
+	dialect    scaf.Dialect
 }
 
 // New creates a new Neo4j database connection from the given configuration.
@@ -65,14 +66,17 @@ func New(cfg *scaf.Neo4jConfig) (*Database, error) {
 	}
 
 	// Create session config
-	sessionCfg := neo4j.SessionConfig{
+	d.sessionCfg = neo4j.SessionConfig{
 		AccessMode: neo4j.AccessModeWrite,
 	}
+	if cfg.ReadOnly {
+		d.sessionCfg.AccessMode = neo4j.AccessModeRead
+	}
 	if d.db != "" {
-		sessionCfg.DatabaseName = d.db
+		d.sessionCfg.DatabaseName = d.db
 	}
 
-	d.session = driver.NewSession(ctx, sessionCfg)
+	d.session = driver.NewSession(ctx, d.sessionCfg)
 
 	return d, nil
 }
@@ -139,6 +143,21 @@ func (d *Database) Close() error {
 	return nil
 }
 
+// Discard replaces the session after a query timed out. The driver marks a
+// connection interrupted by a cancelled context as broken, so closing the old
+// session drops the connection rather than returning it to the pool.
+func (d *Database) Discard(ctx context.Context) error {
+	old := d.session
+	d.session = d.driver.NewSession(ctx, d.sessionCfg)
+
+	err := old.Close(ctx)
+	if err != nil {
+		return fmt.Errorf("neo4j: failed to close session: %w", err)
+	}
+
+	return nil
+}
+
 // Begin starts a new transaction for isolated test execution.
 func (d *Database) Begin(ctx context.Context) (scaf.DatabaseTransaction, error) {
 	tx, err := d.session.BeginTransaction(ctx)
@@ -317,5 +336,6 @@ func flattenValue(result map[string]any, key string, value any) {
 var (
 	_ scaf.Database              = (*Database)(nil)
 	_ scaf.TransactionalDatabase = (*Database)(nil)
+	_ scaf.DiscardableDatabase   = (*Database)(nil)
 	_ scaf.DatabaseTransaction   = (*Transaction)(nil)
 )
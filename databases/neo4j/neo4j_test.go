@@ -2,6 +2,7 @@
 package neo4j
 
 import (
+	"fmt"
 	"os"
 	"slices"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
 	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
 )
 
 func TestDatabase_Name(t *testing.T) {
@@ -155,6 +157,96 @@ func TestFlattenRecord_NestedMap(t *testing.T) {
 	}
 }
 
+func TestPropertyType(t *testing.T) {
+	tests := []struct {
+		types []string
+		want  string
+	}{
+		{[]string{"String"}, "string"},
+		{[]string{"Long"}, "int"},
+		{[]string{"Double"}, "float64"},
+		{[]string{"Boolean"}, "bool"},
+		{[]string{"DateTime"}, "time.Time"},
+		{[]string{"Point"}, "neo4j.Point2D"},
+		{[]string{"StringArray"}, "[]string"},
+		{[]string{"LongArray"}, "[]int"},
+		{[]string{"String", "Long"}, "any"},
+		{nil, "any"},
+	}
+
+	for _, tt := range tests {
+		if got := propertyType(tt.types).String(); got != tt.want {
+			t.Errorf("propertyType(%v) = %q, want %q", tt.types, got, tt.want)
+		}
+	}
+}
+
+func TestRelationshipModelName(t *testing.T) {
+	tests := map[string]string{
+		"ACTED_IN":     "ActedIn",
+		":`ACTED_IN`":  "ActedIn",
+		"FOLLOWS":      "Follows",
+		"HAS__MANY_OF": "HasManyOf",
+	}
+
+	for relType, want := range tests {
+		if got := relationshipModelName(relType); got != want {
+			t.Errorf("relationshipModelName(%q) = %q, want %q", relType, got, want)
+		}
+	}
+}
+
+func TestAddRelationship(t *testing.T) {
+	schema := analysis.NewTypeSchema()
+	person := modelFor(schema, "Person")
+
+	addRelationship(person, "LIKES", "Movie", false)
+	addRelationship(person, "LIKES", "Movie", true)
+	addRelationship(person, "LIKES", "Book", false)
+
+	got := make([]string, 0, len(person.Relationships))
+	for _, rel := range person.Relationships {
+		got = append(got, fmt.Sprintf("%s %s->%s many=%v cardinality=%s", rel.Name, rel.RelType, rel.Target, rel.Many, rel.Cardinality))
+	}
+
+	want := []string{
+		"Likes LIKES->Movie many=true cardinality=many",
+		"LikesBook LIKES->Book many=false cardinality=",
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("relationships mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarkCardinalityOne(t *testing.T) {
+	schema := analysis.NewTypeSchema()
+	person := modelFor(schema, "Person")
+
+	addRelationship(person, "HOLDS", "Passport", false)
+	addRelationship(person, "LIKES", "Movie", true)
+	addRelationship(person, "KNOWS", "Person", false)
+
+	markCardinalityOne(schema, "HOLDS")
+	markCardinalityOne(schema, "LIKES")
+
+	got := make([]string, 0, len(person.Relationships))
+	for _, rel := range person.Relationships {
+		got = append(got, fmt.Sprintf("%s cardinality=%s", rel.RelType, rel.Cardinality))
+	}
+
+	// Observed data wins over the constraint hint
+	want := []string{
+		"HOLDS cardinality=one",
+		"LIKES cardinality=many",
+		"KNOWS cardinality=",
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("relationships mismatch (-want +got):\n%s", diff)
+	}
+}
+
 // Integration tests - only run with a real Neo4j instance.
 // Set SCAF_NEO4J_URI, SCAF_NEO4J_USER, SCAF_NEO4J_PASS to run.
 
@@ -228,6 +320,44 @@ func TestDatabase_Transaction_Integration(t *testing.T) {
 	}
 }
 
+func TestDatabase_ExtractSchema_Integration(t *testing.T) {
+	db := setupIntegrationTest(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := t.Context()
+
+	_, _ = db.Execute(ctx, "MATCH (n:ScafSchemaTest) DETACH DELETE n", nil)
+	defer func() { _, _ = db.Execute(ctx, "MATCH (n:ScafSchemaTest) DETACH DELETE n", nil) }()
+
+	_, err := db.Execute(ctx, "CREATE (:ScafSchemaTest {name: 'a', age: 1})-[:SCAF_KNOWS]->(:ScafSchemaTest {name: 'b', age: 2}) RETURN 1", nil)
+	if err != nil {
+		t.Fatalf("failed to create test data: %v", err)
+	}
+
+	schema, err := db.ExtractSchema()
+	if err != nil {
+		t.Fatalf("ExtractSchema() error: %v", err)
+	}
+
+	model, ok := schema.Models["ScafSchemaTest"]
+	if !ok {
+		t.Fatal("expected ScafSchemaTest model")
+	}
+
+	fields := make(map[string]string)
+	for _, field := range model.Fields {
+		fields[field.Name] = field.Type.String()
+	}
+
+	if fields["name"] != "string" || fields["age"] != "int" {
+		t.Errorf("fields = %v, want name: string, age: int", fields)
+	}
+
+	if len(model.Relationships) != 1 || model.Relationships[0].RelType != "SCAF_KNOWS" {
+		t.Errorf("relationships = %+v, want SCAF_KNOWS", model.Relationships)
+	}
+}
+
 func setupIntegrationTest(t *testing.T) *Database {
 	t.Helper()
 
@@ -0,0 +1,380 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rlch/scaf/analysis"
+)
+
+// Schema introspection queries.
+const (
+	nodeTypePropertiesQuery = `CALL db.schema.nodeTypeProperties()
+YIELD nodeLabels, propertyName, propertyTypes, mandatory
+RETURN nodeLabels, propertyName, propertyTypes, mandatory`
+
+	relTypePropertiesQuery = `CALL db.schema.relTypeProperties()
+YIELD relType, propertyName, propertyTypes, mandatory
+RETURN relType, propertyName, propertyTypes, mandatory`
+
+	// relationshipsQuery finds each (source label, rel type, target label) triple.
+	// many is true when any source node has more than one such relationship.
+	relationshipsQuery = `MATCH (a)-[r]->(b)
+WITH a, type(r) AS relType, labels(b) AS targets, count(r) AS n
+UNWIND labels(a) AS source
+UNWIND targets AS target
+RETURN source, relType, target, max(n) > 1 AS many`
+
+	labelsQuery = `CALL db.labels() YIELD label RETURN label ORDER BY label`
+
+	uniqueConstraintsQuery = `SHOW CONSTRAINTS
+YIELD type, entityType, labelsOrTypes, properties
+WHERE type CONTAINS 'UNIQUE' OR type CONTAINS 'KEY'
+RETURN entityType, labelsOrTypes, properties`
+
+	// indexesQuery finds the indexes that can serve property lookups. Token
+	// lookup indexes cover labels rather than properties, and full-text
+	// indexes don't serve equality.
+	indexesQuery = `SHOW INDEXES
+YIELD type, entityType, labelsOrTypes, properties
+WHERE type <> 'LOOKUP' AND type <> 'FULLTEXT'
+RETURN entityType, labelsOrTypes, properties`
+)
+
+// ExtractSchema introspects the connected database and returns its schema.
+// Node labels and relationship types become models, property types are mapped
+// to scaf types, single-property uniqueness constraints mark unique fields,
+// and single-property indexes mark indexed fields.
+// Relationship cardinality is many when some node has several relationships
+// of a type, and one when the type has a uniqueness constraint.
+//
+// Database implements analysis.SchemaAdapter.
+func (d *Database) ExtractSchema() (*analysis.TypeSchema, error) {
+	ctx := context.Background()
+	schema := analysis.NewTypeSchema()
+
+	nodeProps, err := d.collect(ctx, nodeTypePropertiesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("neo4j: failed to read node properties: %w", err)
+	}
+
+	for _, row := range nodeProps {
+		for _, label := range stringList(row["nodeLabels"]) {
+			addProperty(modelFor(schema, label), row)
+		}
+	}
+
+	relProps, err := d.collect(ctx, relTypePropertiesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("neo4j: failed to read relationship properties: %w", err)
+	}
+
+	for _, row := range relProps {
+		relType, _ := row["relType"].(string)
+		addProperty(modelFor(schema, relationshipModelName(relType)), row)
+	}
+
+	rels, err := d.collect(ctx, relationshipsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("neo4j: failed to read relationships: %w", err)
+	}
+
+	for _, row := range rels {
+		source, _ := row["source"].(string)
+		relType, _ := row["relType"].(string)
+		target, _ := row["target"].(string)
+		many, _ := row["many"].(bool)
+
+		addRelationship(modelFor(schema, source), relType, target, many)
+	}
+
+	constraints, err := d.collect(ctx, uniqueConstraintsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("neo4j: failed to read constraints: %w", err)
+	}
+
+	for _, row := range constraints {
+		labels := stringList(row["labelsOrTypes"])
+		props := stringList(row["properties"])
+
+		if len(labels) != 1 {
+			continue
+		}
+
+		name := labels[0]
+		if entityType, _ := row["entityType"].(string); entityType == "RELATIONSHIP" {
+			markCardinalityOne(schema, name)
+			name = relationshipModelName(name)
+		}
+
+		// Composite keys don't make any single field unique.
+		if len(props) != 1 {
+			continue
+		}
+
+		if model, ok := schema.Models[name]; ok {
+			markUnique(model, props[0])
+		}
+	}
+
+	indexes, err := d.collect(ctx, indexesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("neo4j: failed to read indexes: %w", err)
+	}
+
+	for _, row := range indexes {
+		labels := stringList(row["labelsOrTypes"])
+		props := stringList(row["properties"])
+
+		// Composite indexes need every property in the predicate.
+		if len(labels) != 1 || len(props) != 1 {
+			continue
+		}
+
+		name := labels[0]
+		if entityType, _ := row["entityType"].(string); entityType == "RELATIONSHIP" {
+			name = relationshipModelName(name)
+		}
+
+		if model, ok := schema.Models[name]; ok {
+			markIndexed(model, props[0])
+		}
+	}
+
+	for _, model := range schema.Models {
+		sort.Slice(model.Fields, func(i, j int) bool { return model.Fields[i].Name < model.Fields[j].Name })
+		sort.Slice(model.Relationships, func(i, j int) bool { return model.Relationships[i].Name < model.Relationships[j].Name })
+	}
+
+	return schema, nil
+}
+
+// Labels returns the node labels in the database, sorted by name.
+func (d *Database) Labels() ([]string, error) {
+	rows, err := d.collect(context.Background(), labelsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("neo4j: failed to read labels: %w", err)
+	}
+
+	labels := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if label, ok := row["label"].(string); ok {
+			labels = append(labels, label)
+		}
+	}
+
+	return labels, nil
+}
+
+// collect runs a read query and returns its records as maps keyed by column.
+func (d *Database) collect(ctx context.Context, query string) ([]map[string]any, error) {
+	result, err := d.session.Run(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := result.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]map[string]any, len(records))
+	for i, record := range records {
+		rows[i] = record.AsMap()
+	}
+
+	return rows, nil
+}
+
+// modelFor returns the named model, creating it if needed.
+func modelFor(schema *analysis.TypeSchema, name string) *analysis.Model {
+	if model, ok := schema.Models[name]; ok {
+		return model
+	}
+
+	model := &analysis.Model{
+		Name:          name,
+		Fields:        make([]*analysis.Field, 0),
+		Relationships: make([]*analysis.Relationship, 0),
+	}
+	schema.Models[name] = model
+
+	return model
+}
+
+// addProperty adds the property described by a schema.*TypeProperties row.
+// Rows for labels or types without properties have a null propertyName.
+func addProperty(model *analysis.Model, row map[string]any) {
+	name, _ := row["propertyName"].(string)
+	if name == "" {
+		return
+	}
+
+	for _, field := range model.Fields {
+		if field.Name == name {
+			return
+		}
+	}
+
+	mandatory, _ := row["mandatory"].(bool)
+
+	model.Fields = append(model.Fields, &analysis.Field{
+		Name:     name,
+		Type:     propertyType(stringList(row["propertyTypes"])),
+		Required: mandatory,
+	})
+}
+
+// addRelationship adds an outgoing relationship to model.
+// Relationships are named after their type, qualified by target when a type
+// connects to several labels.
+func addRelationship(model *analysis.Model, relType, target string, many bool) {
+	name := relationshipModelName(relType)
+
+	for _, rel := range model.Relationships {
+		if rel.Name == name {
+			if rel.RelType == relType && rel.Target == target {
+				rel.Many = rel.Many || many
+				if many {
+					rel.Cardinality = analysis.CardinalityMany
+				}
+
+				return
+			}
+
+			name += target
+
+			break
+		}
+	}
+
+	rel := &analysis.Relationship{
+		Name:      name,
+		RelType:   relType,
+		Target:    target,
+		Many:      many,
+		Direction: analysis.DirectionOutgoing,
+	}
+	if many {
+		rel.Cardinality = analysis.CardinalityMany
+	}
+
+	model.Relationships = append(model.Relationships, rel)
+}
+
+// markCardinalityOne marks relationships of relType as one-per-node, unless
+// the data already shows a node with several of them. A uniqueness
+// constraint on a relationship type is only a hint, so this is a best guess.
+func markCardinalityOne(schema *analysis.TypeSchema, relType string) {
+	for _, model := range schema.Models {
+		for _, rel := range model.Relationships {
+			if rel.RelType == relType && rel.Cardinality == 0 {
+				rel.Cardinality = analysis.CardinalityOne
+			}
+		}
+	}
+}
+
+// markUnique marks the named field of model as unique.
+func markUnique(model *analysis.Model, name string) {
+	for _, field := range model.Fields {
+		if field.Name == name {
+			field.Unique = true
+
+			return
+		}
+	}
+}
+
+// markIndexed marks the named field of model as indexed.
+func markIndexed(model *analysis.Model, name string) {
+	for _, field := range model.Fields {
+		if field.Name == name {
+			field.Indexed = true
+
+			return
+		}
+	}
+}
+
+// propertyTypeNames maps Neo4j property type names to scaf types.
+var propertyTypeNames = map[string]*analysis.Type{
+	"String":        analysis.TypeString,
+	"Long":          analysis.TypeInt,
+	"Integer":       analysis.TypeInt,
+	"Double":        analysis.TypeFloat64,
+	"Float":         analysis.TypeFloat64,
+	"Boolean":       analysis.TypeBool,
+	"Date":          analysis.TypeDate,
+	"Time":          analysis.TypeTime,
+	"DateTime":      analysis.TypeDateTime,
+	"LocalDateTime": analysis.TypeLocalDateTime,
+	"LocalTime":     analysis.TypeLocalTime,
+	"Duration":      analysis.TypeDuration,
+	"Point":         analysis.TypePoint,
+}
+
+// typeAny is used for properties whose type is unknown or varies between entities.
+var typeAny = &analysis.Type{Kind: analysis.TypeKindPrimitive, Name: "any"}
+
+// propertyType maps the property types reported by db.schema.*TypeProperties
+// (e.g. ["String"], ["LongArray"]) to a scaf type.
+func propertyType(names []string) *analysis.Type {
+	if len(names) != 1 {
+		return typeAny
+	}
+
+	name := names[0]
+
+	if elem, ok := strings.CutSuffix(name, "Array"); ok {
+		if typ, ok := propertyTypeNames[elem]; ok {
+			return analysis.SliceOf(typ)
+		}
+
+		return analysis.SliceOf(typeAny)
+	}
+
+	if typ, ok := propertyTypeNames[name]; ok {
+		return typ
+	}
+
+	return typeAny
+}
+
+// relationshipModelName converts a relationship type such as ":`ACTED_IN`"
+// or "ACTED_IN" to a model name ("ActedIn").
+func relationshipModelName(relType string) string {
+	relType = strings.Trim(strings.TrimPrefix(relType, ":"), "`")
+
+	var b strings.Builder
+
+	for _, part := range strings.Split(relType, "_") {
+		if part == "" {
+			continue
+		}
+
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(strings.ToLower(part[1:]))
+	}
+
+	return b.String()
+}
+
+// stringList converts a list value from a record into strings.
+func stringList(v any) []string {
+	items, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+
+	strs := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+
+	return strs
+}
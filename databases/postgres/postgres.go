@@ -0,0 +1,406 @@
+// Package postgres provides a scaf Database implementation for PostgreSQL.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/dialects/sql"
+)
+
+// Postgres errors.
+var (
+	// ErrInvalidConfig is returned when an invalid configuration is provided.
+	ErrInvalidConfig = errors.New("postgres: expected *scaf.PostgresConfig")
+
+	// ErrMissingParameter is returned when a query uses a parameter the
+	// test doesn't set.
+	ErrMissingParameter = errors.New("postgres: missing parameter")
+)
+
+//nolint:gochecknoinits // Database self-registration pattern
+func init() {
+	scaf.RegisterDatabase(scaf.DatabasePostgres, func(cfg any) (scaf.Database, error) {
+		pgCfg, ok := cfg.(*scaf.PostgresConfig)
+		if !ok {
+			return nil, fmt.Errorf("%w, got %T", ErrInvalidConfig, cfg)
+		}
+
+		return New(pgCfg)
+	})
+}
+
+// Database implements scaf.Database and scaf.TransactionalDatabase for
+// PostgreSQL.
+type Database struct {
+	conn    *pgx.Conn
+	connCfg *pgx.ConnConfig
+	dialect scaf.Dialect
+}
+
+// New creates a new PostgreSQL connection from the given configuration.
+// cfg.URI, if set, replaces the host, port, database, and SSL mode settings;
+// cfg.User and cfg.Password still override its credentials.
+func New(cfg *scaf.PostgresConfig) (*Database, error) {
+	connCfg, err := pgx.ParseConfig(connString(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("postgres: invalid connection settings: %w", err)
+	}
+
+	if cfg.User != "" {
+		connCfg.User = cfg.User
+	}
+
+	if cfg.Password != "" {
+		connCfg.Password = cfg.Password
+	}
+
+	if cfg.ReadOnly {
+		connCfg.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+
+	conn, err := pgx.ConnectConfig(context.Background(), connCfg)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to connect: %w", err)
+	}
+
+	return &Database{
+		conn:    conn,
+		connCfg: connCfg,
+		dialect: sql.NewDialect(),
+	}, nil
+}
+
+// connString returns the connection string for cfg. Settings left empty fall
+// back to libpq's defaults and PG* environment variables.
+func connString(cfg *scaf.PostgresConfig) string {
+	if cfg.URI != "" {
+		return cfg.URI
+	}
+
+	var settings []string
+
+	add := func(key, value string) {
+		if value != "" {
+			quoted := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+			settings = append(settings, key+"='"+quoted+"'")
+		}
+	}
+
+	add("host", cfg.Host)
+
+	if cfg.Port != 0 {
+		add("port", strconv.Itoa(cfg.Port))
+	}
+
+	add("dbname", cfg.Database)
+	add("user", cfg.User)
+	add("password", cfg.Password)
+	add("sslmode", cfg.SSLMode)
+
+	return strings.Join(settings, " ")
+}
+
+// Name returns the database identifier.
+func (d *Database) Name() string {
+	return scaf.DatabasePostgres
+}
+
+// Dialect returns the SQL dialect for query analysis.
+func (d *Database) Dialect() scaf.Dialect {
+	return d.dialect
+}
+
+// Execute runs a SQL query and returns the results, keyed by column name.
+// Parameters are written $name, as in the scaf file. Multi-statement queries
+// (separated by semicolons) are executed sequentially, returning results
+// from the last statement.
+func (d *Database) Execute(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	return execute(ctx, d.conn, query, params)
+}
+
+// Close releases the database connection.
+func (d *Database) Close() error {
+	err := d.conn.Close(context.Background())
+	if err != nil {
+		return fmt.Errorf("postgres: failed to close connection: %w", err)
+	}
+
+	return nil
+}
+
+// Discard replaces the connection after a query timed out. pgx closes a
+// connection whose query was interrupted by a cancelled context, so the old
+// one can't be reused.
+func (d *Database) Discard(ctx context.Context) error {
+	conn, err := pgx.ConnectConfig(ctx, d.connCfg)
+	if err != nil {
+		return fmt.Errorf("postgres: failed to reconnect: %w", err)
+	}
+
+	old := d.conn
+	d.conn = conn
+
+	err = old.Close(ctx)
+	if err != nil {
+		return fmt.Errorf("postgres: failed to close connection: %w", err)
+	}
+
+	return nil
+}
+
+// Begin starts a new transaction for isolated test execution.
+func (d *Database) Begin(ctx context.Context) (scaf.DatabaseTransaction, error) {
+	tx, err := d.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: failed to begin transaction: %w", err)
+	}
+
+	return &Transaction{tx: tx}, nil
+}
+
+// Transaction wraps a PostgreSQL transaction to implement
+// scaf.DatabaseTransaction.
+type Transaction struct {
+	tx pgx.Tx
+}
+
+// Execute runs a SQL query within this transaction.
+func (t *Transaction) Execute(ctx context.Context, query string, params map[string]any) ([]map[string]any, error) {
+	return execute(ctx, t.tx, query, params)
+}
+
+// Commit commits the transaction.
+func (t *Transaction) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+// Rollback aborts the transaction.
+func (t *Transaction) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
+
+// querier is the query method shared by connections and transactions.
+type querier interface {
+	Query(ctx context.Context, query string, args ...any) (pgx.Rows, error)
+}
+
+func execute(ctx context.Context, q querier, query string, params map[string]any) ([]map[string]any, error) {
+	statements, err := prepare(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]any
+
+	for _, stmt := range statements {
+		result, err := q.Query(ctx, stmt.sql, stmt.args...)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: query execution failed: %w", err)
+		}
+
+		// Keep results from the last statement
+		rows, err = collectRows(result)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: query execution failed: %w", err)
+		}
+	}
+
+	return rows, nil
+}
+
+// collectRows reads and closes rows, keying each value by its column name.
+func collectRows(rows pgx.Rows) ([]map[string]any, error) {
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+
+	var result []map[string]any
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(fields))
+		for i, field := range fields {
+			row[field.Name] = resultValue(values[i])
+		}
+
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// resultValue converts the NUMERIC values pgx decodes to pgtype.Numeric into
+// floats, so they compare against scaf numbers. Other values are returned
+// as is.
+func resultValue(v any) any {
+	n, ok := v.(pgtype.Numeric)
+	if !ok {
+		return v
+	}
+
+	f, err := n.Float64Value()
+	if err != nil || !f.Valid {
+		return nil
+	}
+
+	return f.Float64
+}
+
+// statement is a single SQL statement with its parameters bound positionally.
+type statement struct {
+	sql  string
+	args []any
+}
+
+// prepare splits query into statements at top-level semicolons and replaces
+// each $name parameter with a positional one bound to params[name].
+// Semicolons and $ signs in string literals, quoted identifiers, comments,
+// and dollar-quoted strings are left alone, as are positional parameters.
+func prepare(query string, params map[string]any) ([]statement, error) {
+	var (
+		statements []statement
+		current    strings.Builder
+		args       []any
+		positions  = make(map[string]int)
+	)
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			statements = append(statements, statement{sql: s, args: args})
+		}
+
+		current.Reset()
+
+		args = nil
+		positions = make(map[string]int)
+	}
+
+	for i := 0; i < len(query); {
+		rest := query[i:]
+
+		var end int
+
+		switch {
+		case rest[0] == ';':
+			flush()
+			i++
+
+			continue
+		case rest[0] == '\'' || rest[0] == '"':
+			end = quotedEnd(rest)
+		case strings.HasPrefix(rest, "--"):
+			end = indexOr(rest, "\n", 0, len(rest))
+		case strings.HasPrefix(rest, "/*"):
+			end = indexOr(rest, "*/", 2, len(rest)-2) + 2
+		case rest[0] == '$':
+			name := identifier(rest[1:])
+			if tagEnd := 1 + len(name); tagEnd < len(rest) && rest[tagEnd] == '$' {
+				// Dollar-quoted string: $$...$$ or $tag$...$tag$
+				tag := rest[:tagEnd+1]
+				end = indexOr(rest, tag, len(tag), len(rest)-len(tag)) + len(tag)
+
+				break
+			}
+
+			if name == "" || isDigit(name[0]) {
+				end = 1
+
+				break
+			}
+
+			value, ok := params[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: $%s", ErrMissingParameter, name)
+			}
+
+			n, ok := positions[name]
+			if !ok {
+				args = append(args, value)
+				n = len(args)
+				positions[name] = n
+			}
+
+			current.WriteString("$" + strconv.Itoa(n))
+			i += 1 + len(name)
+
+			continue
+		default:
+			end = 1
+		}
+
+		current.WriteString(rest[:end])
+		i += end
+	}
+
+	flush()
+
+	return statements, nil
+}
+
+// quotedEnd returns the length of the quoted string or identifier at the
+// start of s, where a doubled quote is an escaped one. An unterminated quote
+// runs to the end of s.
+func quotedEnd(s string) int {
+	quote := s[0]
+
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+
+			continue
+		}
+
+		return i + 1
+	}
+
+	return len(s)
+}
+
+// indexOr returns the index of substr in s at or after from, or fallback if
+// s has none.
+func indexOr(s, substr string, from, fallback int) int {
+	if i := strings.Index(s[from:], substr); i >= 0 {
+		return from + i
+	}
+
+	return fallback
+}
+
+// identifier returns the identifier characters at the start of s.
+func identifier(s string) string {
+	for i := range len(s) {
+		c := s[i]
+		if c != '_' && !isDigit(c) && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return s[:i]
+		}
+	}
+
+	return s
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Compile-time interface checks.
+var (
+	_ scaf.Database              = (*Database)(nil)
+	_ scaf.TransactionalDatabase = (*Database)(nil)
+	_ scaf.DiscardableDatabase   = (*Database)(nil)
+	_ scaf.DatabaseTransaction   = (*Transaction)(nil)
+)
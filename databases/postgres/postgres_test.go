@@ -0,0 +1,228 @@
+//nolint:testpackage
+package postgres
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rlch/scaf"
+)
+
+func TestDatabase_Registration(t *testing.T) {
+	databases := scaf.RegisteredDatabases()
+
+	if !slices.Contains(databases, scaf.DatabasePostgres) {
+		t.Error("postgres database not registered")
+	}
+}
+
+func TestNewDatabase_InvalidConfig(t *testing.T) {
+	_, err := scaf.NewDatabase(scaf.DatabasePostgres, &scaf.Neo4jConfig{})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("NewDatabase() error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestConnString(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *scaf.PostgresConfig
+		want string
+	}{
+		{
+			name: "settings",
+			cfg: &scaf.PostgresConfig{
+				Host:     "localhost",
+				Port:     5432,
+				Database: "app",
+				User:     "postgres",
+				Password: `it's\secret`,
+				SSLMode:  "disable",
+			},
+			want: `host='localhost' port='5432' dbname='app' user='postgres' password='it\'s\\secret' sslmode='disable'`,
+		},
+		{
+			name: "empty settings are left out",
+			cfg:  &scaf.PostgresConfig{Database: "app"},
+			want: `dbname='app'`,
+		},
+		{
+			name: "uri",
+			cfg:  &scaf.PostgresConfig{Host: "ignored", URI: "postgres://localhost/app"},
+			want: "postgres://localhost/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := connString(tt.cfg); got != tt.want {
+				t.Errorf("connString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		params map[string]any
+		want   []statement
+	}{
+		{
+			name:   "named parameters",
+			query:  "SELECT name FROM users WHERE id = $id AND age > $age OR id = $id",
+			params: map[string]any{"id": 1.0, "age": 30.0},
+			want: []statement{{
+				sql:  "SELECT name FROM users WHERE id = $1 AND age > $2 OR id = $1",
+				args: []any{1.0, 30.0},
+			}},
+		},
+		{
+			name:  "statements",
+			query: "\nINSERT INTO users (id) VALUES (1);\nINSERT INTO users (id) VALUES ($id);\n",
+			params: map[string]any{
+				"id": 2.0,
+			},
+			want: []statement{
+				{sql: "INSERT INTO users (id) VALUES (1)"},
+				{sql: "INSERT INTO users (id) VALUES ($1)", args: []any{2.0}},
+			},
+		},
+		{
+			name:  "quoted text",
+			query: `SELECT 'a;$b''c', "x;$y", $$d;$e$$, $t$f;$g$t$ -- h;$i` + "\n/* j;$k */ FROM t",
+			want: []statement{{
+				sql: `SELECT 'a;$b''c', "x;$y", $$d;$e$$, $t$f;$g$t$ -- h;$i` + "\n/* j;$k */ FROM t",
+			}},
+		},
+		{
+			name:  "positional parameters",
+			query: "SELECT $1",
+			want:  []statement{{sql: "SELECT $1"}},
+		},
+		{
+			name:  "empty statements",
+			query: " ; ;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prepare(tt.query, tt.params)
+			if err != nil {
+				t.Fatalf("prepare() error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(statement{})); diff != "" {
+				t.Errorf("prepare() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPrepare_MissingParameter(t *testing.T) {
+	_, err := prepare("SELECT $id", nil)
+	if !errors.Is(err, ErrMissingParameter) {
+		t.Errorf("prepare() error = %v, want ErrMissingParameter", err)
+	}
+}
+
+func TestResultValue(t *testing.T) {
+	var numeric pgtype.Numeric
+	if err := numeric.Scan("12.5"); err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+
+	if got := resultValue(numeric); got != 12.5 {
+		t.Errorf("resultValue(numeric) = %v, want 12.5", got)
+	}
+
+	if got := resultValue(pgtype.Numeric{}); got != nil {
+		t.Errorf("resultValue(NULL numeric) = %v, want nil", got)
+	}
+
+	if got := resultValue(int32(3)); got != int32(3) {
+		t.Errorf("resultValue(int32) = %v, want 3", got)
+	}
+}
+
+// Integration tests - only run with a real PostgreSQL instance.
+// Set SCAF_POSTGRES_URI to run.
+
+func TestDatabase_Execute_Integration(t *testing.T) {
+	db := setupIntegrationTest(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := t.Context()
+
+	rows, err := db.Execute(ctx, `
+CREATE TEMP TABLE scaf_test (id BIGINT, name TEXT);
+INSERT INTO scaf_test VALUES ($id, $name);
+SELECT id, name FROM scaf_test WHERE id = $id
+`, map[string]any{"id": 1.0, "name": "test-row"})
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	want := []map[string]any{{"id": int64(1), "name": "test-row"}}
+	if diff := cmp.Diff(want, rows); diff != "" {
+		t.Errorf("Execute() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDatabase_Transaction_Integration(t *testing.T) {
+	db := setupIntegrationTest(t)
+	defer func() { _ = db.Close() }()
+
+	ctx := t.Context()
+
+	_, err := db.Execute(ctx, "CREATE TEMP TABLE scaf_tx_test (name TEXT)", nil)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	_, err = tx.Execute(ctx, "INSERT INTO scaf_tx_test VALUES ('in-tx')", nil)
+	if err != nil {
+		t.Fatalf("failed to insert in tx: %v", err)
+	}
+
+	err = tx.Rollback(ctx)
+	if err != nil {
+		t.Fatalf("failed to rollback: %v", err)
+	}
+
+	rows, err := db.Execute(ctx, "SELECT name FROM scaf_tx_test", nil)
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+
+	if len(rows) != 0 {
+		t.Error("row should not exist after rollback")
+	}
+}
+
+func setupIntegrationTest(t *testing.T) *Database {
+	t.Helper()
+
+	uri := os.Getenv("SCAF_POSTGRES_URI")
+	if uri == "" {
+		t.Skip("SCAF_POSTGRES_URI not set, skipping integration test")
+	}
+
+	db, err := New(&scaf.PostgresConfig{URI: uri})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	return db
+}
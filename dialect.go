@@ -8,6 +8,19 @@ type Dialect interface {
 
 	// Analyze extracts metadata from a query string.
 	Analyze(query string) (*QueryMetadata, error)
+
+	// Format rewrites a query in the dialect's canonical style.
+	// Queries that cannot be parsed are returned unchanged.
+	Format(query string) (string, error)
+}
+
+// ConfigurableFormatter is implemented by dialects whose formatting can be
+// tuned by the project config and the editor.
+type ConfigurableFormatter interface {
+	// WithFormatConfig returns the dialect formatting as cfg says, where cfg
+	// may be nil. indentWidth, such as an editor's tab size, is used when cfg
+	// sets no indent width; 0 keeps the dialect's default.
+	WithFormatConfig(cfg *Config, indentWidth int) Dialect
 }
 
 var dialects = make(map[string]Dialect)
@@ -120,6 +133,61 @@ type QueryMetadata struct {
 	// Set to true when the query filters on a unique field with equality,
 	// uses LIMIT 1, or is otherwise guaranteed to return a single row.
 	ReturnsOne bool
+
+	// IsDistinct indicates the returned rows are deduplicated by an explicit
+	// DISTINCT, either on RETURN or on a WITH whose columns the RETURN passes
+	// through without further matching. Aggregation alone does not set it.
+	IsDistinct bool
+
+	// IsOrdered indicates the returned rows are sorted by ORDER BY, either on
+	// RETURN or on a WITH that the RETURN projects from without further matching.
+	IsOrdered bool
+
+	// CreatesData indicates the query creates graph data, e.g. with CREATE or MERGE.
+	CreatesData bool
+
+	// MatchedLabels are the node labels the query's MATCH patterns look for,
+	// sorted and without duplicates.
+	MatchedLabels []string
+
+	// CreatedLabels are the node labels in the query's CREATE and MERGE
+	// patterns, sorted and without duplicates.
+	CreatedLabels []string
+
+	// RelationshipTypes are the relationship types in the query's MATCH,
+	// CREATE, and MERGE patterns, sorted and without duplicates.
+	RelationshipTypes []string
+
+	// Warnings are non-fatal problems found while analyzing the query,
+	// such as a declared parameter type that conflicts with how it is used.
+	Warnings []string
+
+	// WarningDetails are Warnings in the same order, each with the code and
+	// location of its diagnostic. AddWarning keeps the two in step.
+	WarningDetails []QueryWarning
+}
+
+// AddWarning records a warning in both Warnings and WarningDetails.
+func (m *QueryMetadata) AddWarning(w QueryWarning) {
+	m.Warnings = append(m.Warnings, w.Message)
+	m.WarningDetails = append(m.WarningDetails, w)
+}
+
+// QueryWarning is a non-fatal problem found while analyzing a query.
+type QueryWarning struct {
+	// Code classifies the warning and is the code of its diagnostic, e.g.
+	// "deprecated-function" or "limit-without-order-by".
+	Code string
+
+	// Message describes the problem.
+	Message string
+
+	// Range is the location of the problem in the query.
+	Range QueryDiagnosticRange
+
+	// Param is the name (without $ prefix) of the parameter whose declared
+	// type conflicts with its use, or empty for other warnings.
+	Param string
 }
 
 // ParameterInfo describes a query parameter.
@@ -172,6 +240,10 @@ type ReturnInfo struct {
 	// When false, code generators should use pointer types to represent nil values.
 	Required bool
 
+	// Nullable indicates the field is a variable bound by OPTIONAL MATCH, which
+	// is null for rows where the optional pattern didn't match.
+	Nullable bool
+
 	// Line is the 1-indexed line number in the query where this return field appears.
 	Line int
 
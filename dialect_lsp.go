@@ -1,5 +1,11 @@
 package scaf
 
+import (
+	"errors"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
 // DialectLSP provides LSP features for positions inside query bodies.
 // Dialects optionally implement this interface to provide rich editing support
 // for their query language (completions, hover, diagnostics, etc.).
@@ -7,8 +13,9 @@ package scaf
 // The LSP server detects when the cursor is inside a query body (backtick string)
 // and delegates to the dialect's LSP implementation for that content.
 //
-// All positions are byte offsets within the query string (not the document).
-// The LSP server handles mapping between document positions and query offsets.
+// All positions are byte offsets within the query string (not the document),
+// except diagnostic ranges, which also carry lines and columns. The LSP server
+// handles mapping between document positions and query positions.
 type DialectLSP interface {
 	// Complete returns completions for a position within a query body.
 	// offset is the byte offset within the query string.
@@ -59,6 +66,11 @@ type QueryLSPContext struct {
 	// TriggerCharacter is the character that triggered completion (e.g., ".", ":").
 	// Empty for manual/typing triggers.
 	TriggerCharacter string
+
+	// AssertQuery is true for an assert query, inline or the body of a function
+	// a named assert runs, whose conditions are checked against its first
+	// result row only.
+	AssertQuery bool
 }
 
 // QueryCompletion represents a completion item from a dialect.
@@ -148,10 +160,40 @@ type QueryRange struct {
 	End int
 }
 
+// QueryDiagnosticRange is the location of a diagnostic within a query string.
+// Positions are relative to the start of the query: offsets are in bytes, and
+// lines and columns are 1-based, with columns counted in characters.
+type QueryDiagnosticRange struct {
+	// StartPos is the position of the range start (inclusive).
+	StartPos lexer.Position
+	// EndPos is the position of the range end (exclusive).
+	EndPos lexer.Position
+}
+
+// NewQueryDiagnosticRange returns the range between the byte offsets start
+// and end of query.
+func NewQueryDiagnosticRange(query string, start, end int) QueryDiagnosticRange {
+	return QueryDiagnosticRange{
+		StartPos: QueryPosition(query, start),
+		EndPos:   QueryPosition(query, end),
+	}
+}
+
+// QueryPosition returns the position of a byte offset in query, clamped to
+// the bounds of the query.
+func QueryPosition(query string, offset int) lexer.Position {
+	offset = max(0, min(offset, len(query)))
+
+	pos := lexer.Position{Line: 1, Column: 1}
+	pos.Advance(query[:offset])
+
+	return pos
+}
+
 // QueryDiagnostic represents an error or warning in a query.
 type QueryDiagnostic struct {
 	// Range is the location of the diagnostic in the query.
-	Range QueryRange
+	Range QueryDiagnosticRange
 
 	// Severity indicates error vs warning vs info vs hint.
 	Severity QueryDiagnosticSeverity
@@ -212,6 +254,9 @@ type QueryParameterInfo struct {
 
 	// Documentation is longer documentation for this parameter.
 	Documentation string
+
+	// Optional is true if the parameter may be omitted from the call.
+	Optional bool
 }
 
 // QueryLocation represents a location for go-to-definition results.
@@ -225,13 +270,18 @@ type QueryLocation struct {
 }
 
 // QueryInlayHint represents an inlay hint from a dialect.
-// Used to display inferred type information for parameters.
+// Used to display inferred type information for parameters and expressions.
 type QueryInlayHint struct {
 	// ParameterName identifies which function parameter this hint is for.
 	// The LSP server uses this to position the hint after the parameter name
 	// in the function signature.
 	ParameterName string
 
+	// Offset is the byte offset in the query the hint follows, for hints
+	// inside the query body (e.g. a function call's return type).
+	// Only used when ParameterName is empty.
+	Offset int
+
 	// Label is the hint text to display (e.g., ": string").
 	Label string
 
@@ -254,6 +304,27 @@ const (
 	QueryInlayHintParameter
 )
 
+// ErrNoRenameTarget is returned by QueryRenamer.Rename when there's nothing it
+// can rename at the offset.
+var ErrNoRenameTarget = errors.New("nothing to rename at offset")
+
+// QueryRenamer is implemented by dialects that can rename variables within a
+// query body.
+type QueryRenamer interface {
+	// Rename renames the identifier at offset, and every other occurrence of
+	// it, to newName. It returns the renamed query and the edits that produce
+	// it, sorted by descending Start.
+	Rename(query string, offset int, newName string) (string, []RenameEdit, error)
+}
+
+// RenameEdit replaces the bytes of a query from Start to End with NewText.
+// Offsets are into the original query.
+type RenameEdit struct {
+	Start   int
+	End     int
+	NewText string
+}
+
 // GetDialectLSP returns the DialectLSP implementation for a dialect.
 // Returns nil if the dialect doesn't implement DialectLSP.
 func GetDialectLSP(dialectName string) DialectLSP { //nolint:ireturn
@@ -1,9 +1,13 @@
 package cypher
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/alecthomas/participle/v2/lexer"
+
 	"github.com/rlch/scaf"
 	"github.com/rlch/scaf/analysis"
 	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
@@ -18,12 +22,56 @@ func init() {
 	})
 }
 
+// ErrUndefinedVariable is returned by a strict analyzer for a query that
+// references a variable it never declares.
+var ErrUndefinedVariable = errors.New("undefined variable")
+
 // Analyzer implements scaf.QueryAnalyzer for Cypher queries.
-type Analyzer struct{}
+type Analyzer struct {
+	strict   bool
+	maxDepth int
+	apoc     bool
+}
+
+// AnalyzerOption configures an Analyzer.
+type AnalyzerOption func(*Analyzer)
+
+// WithStrict makes a reference to a variable the query never declares, in an
+// expression whose type is inferred (such as a RETURN or WITH item), an
+// ErrUndefinedVariable error rather than an unknown type. Analyzers are
+// lenient by default.
+func WithStrict(strict bool) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.strict = strict
+	}
+}
+
+// WithMaxDepth limits how deeply nested an expression type inference
+// descends into; anything deeper is left untyped. This bounds the recursion
+// on pathologically nested queries. Zero, the default, means no limit.
+func WithMaxDepth(depth int) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.maxDepth = depth
+	}
+}
+
+// WithAPOC sets whether APOC functions get return types from the built-in
+// table. It is enabled by default; disable it when the database doesn't
+// have APOC installed, so apoc.* calls are left untyped.
+func WithAPOC(apoc bool) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.apoc = apoc
+	}
+}
 
 // NewAnalyzer creates a new Cypher query analyzer.
-func NewAnalyzer() *Analyzer {
-	return &Analyzer{}
+func NewAnalyzer(opts ...AnalyzerOption) *Analyzer {
+	a := &Analyzer{apoc: true}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
 }
 
 // variableBinding tracks a variable's binding to a model (label).
@@ -32,18 +80,141 @@ type variableBinding struct {
 	labels   []string // e.g., ["User"]
 }
 
+// QueryScope holds the variables visible to a query from an enclosing query,
+// such as the outer query of a CALL { ... } body being analyzed on its own.
+type QueryScope struct {
+	// Variables maps variable names to their types. A nil type marks a
+	// variable whose type is unknown.
+	Variables map[string]*scaf.Type
+}
+
 // queryContext holds context during query analysis.
 type queryContext struct {
-	bindings map[string]*variableBinding // variable name -> binding (from MATCH)
-	locals   map[string]*analysis.Type   // local variable types (from list comprehensions, UNWIND, etc.)
-	schema   *analysis.TypeSchema
+	bindings    map[string]*variableBinding // variable name -> binding (from MATCH)
+	locals      map[string]*analysis.Type   // local variable types (from list comprehensions, UNWIND, etc.)
+	paramUsages map[string][]paramUsage     // parameter name -> operators that constrain its type
+	schema      *analysis.TypeSchema
+
+	// subqueries maps each CALL { ... } to the scope of its body, shared by
+	// every context of one query.
+	subqueries map[*cyphergrammar.CallSubquery]*queryContext
+
+	// inference holds the analyzer's settings and inference state, shared
+	// by every context of one query.
+	inference *inferenceState
+
+	// outer is the frame before the WITH that started this one, if any.
+	// Variables are looked up through outer frames unless opaque is set:
+	// a WITH that lists its items hides everything it doesn't project.
+	outer  *queryContext
+	opaque bool
+}
+
+// inferenceState holds what type inference tracks across one query.
+type inferenceState struct {
+	strict   bool
+	maxDepth int
+	apoc     bool
+
+	// depth is how many expressions inference is currently nested in.
+	depth int
+
+	// declared holds the names of variables bound anywhere in the query,
+	// such as relationship variables and WITH aliases, that may have no type.
+	declared map[string]bool
+
+	// undefined lists the variables referenced but never declared, in order
+	// of first reference. Only tracked when strict.
+	undefined []string
+
+	// frames lists the scope frames opened by WITH clauses and CALL { ... }
+	// bodies, in query order.
+	frames []*queryContext
+
+	// scopes maps each clause to the frame its expressions are resolved in.
+	// A WITH clause is resolved in the frame before the one it starts,
+	// except for its WHERE, which sees the frame in withScopes.
+	scopes     map[*cyphergrammar.Clause]*queryContext
+	withScopes map[*cyphergrammar.WithClause]*queryContext
 }
 
-func newQueryContext(schema *analysis.TypeSchema) *queryContext {
+func newQueryContext(schema *analysis.TypeSchema, inference *inferenceState) *queryContext {
 	return &queryContext{
-		bindings: make(map[string]*variableBinding),
-		locals:   make(map[string]*analysis.Type),
-		schema:   schema,
+		bindings:    make(map[string]*variableBinding),
+		locals:      make(map[string]*analysis.Type),
+		paramUsages: make(map[string][]paramUsage),
+		schema:      schema,
+		subqueries:  make(map[*cyphergrammar.CallSubquery]*queryContext),
+		inference:   inference,
+	}
+}
+
+// subqueryContext returns a scope for the body of a CALL { ... } subquery.
+// The body sees only the variables its leading importing WITH takes from
+// qctx (WITH * imports all of them); anything else it binds, including names
+// already used outside, stays in the body.
+func (qctx *queryContext) subqueryContext(body *cyphergrammar.SingleQuery) *queryContext {
+	inner := &queryContext{
+		bindings:    make(map[string]*variableBinding),
+		locals:      make(map[string]*analysis.Type),
+		paramUsages: qctx.paramUsages,
+		schema:      qctx.schema,
+		subqueries:  qctx.subqueries,
+		inference:   qctx.inference,
+	}
+
+	if qctx.inference != nil {
+		qctx.inference.frames = append(qctx.inference.frames, inner)
+	}
+
+	if body == nil || len(body.Clauses) == 0 || body.Clauses[0].With == nil {
+		return inner
+	}
+
+	with := body.Clauses[0].With
+	if with.Body == nil || with.Body.Items == nil {
+		return inner
+	}
+
+	importVar := func(name string) {
+		if binding, ok := qctx.binding(name); ok {
+			inner.bindings[name] = binding
+		}
+		if typ, ok := qctx.local(name); ok {
+			inner.locals[name] = typ
+		}
+	}
+
+	if with.Body.Items.Star {
+		for _, frame := range qctx.visibleFrames() {
+			for name := range frame.bindings {
+				importVar(name)
+			}
+			for name := range frame.locals {
+				importVar(name)
+			}
+		}
+
+		return inner
+	}
+
+	for _, item := range with.Body.Items.Items {
+		if item == nil || item.Expr == nil {
+			continue
+		}
+
+		if name := expressionToString(item.Expr); item.Alias == "" || item.Alias == name {
+			importVar(name)
+		}
+	}
+
+	return inner
+}
+
+// declare records that the query binds name, whether or not its type is known.
+func (qctx *queryContext) declare(name string) {
+	if name != "" && qctx.inference != nil {
+		qctx.inference.declared[name] = true
 	}
 }
 
@@ -56,25 +227,169 @@ func (qctx *queryContext) withLocal(name string, typ *analysis.Type) *queryConte
 	}
 	newLocals[name] = typ
 	return &queryContext{
-		bindings: qctx.bindings,
-		locals:   newLocals,
-		schema:   qctx.schema,
+		bindings:    qctx.bindings,
+		locals:      newLocals,
+		paramUsages: qctx.paramUsages,
+		schema:      qctx.schema,
+		subqueries:  qctx.subqueries,
+		inference:   qctx.inference,
+		outer:       qctx.outer,
+		opaque:      qctx.opaque,
 	}
 }
 
+// withScope returns the frame a WITH clause starts after qctx. WITH * keeps
+// every variable in scope; otherwise only the projected items are, under
+// their aliases, typed as they were in qctx.
+func (qctx *queryContext) withScope(with *cyphergrammar.WithClause) *queryContext {
+	frame := &queryContext{
+		bindings:    make(map[string]*variableBinding),
+		locals:      make(map[string]*analysis.Type),
+		paramUsages: qctx.paramUsages,
+		schema:      qctx.schema,
+		subqueries:  qctx.subqueries,
+		inference:   qctx.inference,
+		outer:       qctx,
+	}
+
+	if qctx.inference != nil {
+		qctx.inference.frames = append(qctx.inference.frames, frame)
+		qctx.inference.withScopes[with] = frame
+	}
+
+	if with.Body == nil || with.Body.Items == nil {
+		return frame
+	}
+
+	frame.opaque = !with.Body.Items.Star
+
+	for _, item := range with.Body.Items.Items {
+		projectItem(item, qctx, frame)
+	}
+
+	return frame
+}
+
+// projectItem binds a projected column, named by its alias or expression, in
+// to. A bare variable keeps its label binding under the new name; anything
+// else gets the type of its expression in from.
+func projectItem(item *cyphergrammar.ProjectionItem, from, to *queryContext) {
+	if item == nil || item.Expr == nil {
+		return
+	}
+
+	expression := expressionToString(item.Expr)
+	name := item.Alias
+	if name == "" {
+		name = expression
+	}
+
+	if binding, ok := from.binding(expression); ok {
+		to.bindings[name] = &variableBinding{
+			variable: name,
+			labels:   binding.labels,
+		}
+
+		return
+	}
+
+	to.locals[name] = inferExpressionType(item.Expr, from)
+}
+
+// visibleFrames returns qctx and the outer frames whose variables it can see,
+// innermost first.
+func (qctx *queryContext) visibleFrames() []*queryContext {
+	var frames []*queryContext
+
+	for frame := qctx; frame != nil; frame = frame.outer {
+		frames = append(frames, frame)
+		if frame.opaque {
+			break
+		}
+	}
+
+	return frames
+}
+
+// binding returns the label binding of a variable in scope.
+func (qctx *queryContext) binding(name string) (*variableBinding, bool) {
+	for _, frame := range qctx.visibleFrames() {
+		if binding, ok := frame.bindings[name]; ok {
+			return binding, true
+		}
+	}
+
+	return nil, false
+}
+
+// local returns the type of a local variable in scope.
+func (qctx *queryContext) local(name string) (*analysis.Type, bool) {
+	for _, frame := range qctx.visibleFrames() {
+		if typ, ok := frame.locals[name]; ok {
+			return typ, true
+		}
+	}
+
+	return nil, false
+}
+
+// scopeAt returns the frame clause is resolved in, or qctx if the clause
+// wasn't visited when extracting bindings.
+func (qctx *queryContext) scopeAt(clause *cyphergrammar.Clause) *queryContext {
+	if qctx.inference != nil {
+		if frame, ok := qctx.inference.scopes[clause]; ok {
+			return frame
+		}
+	}
+
+	return qctx
+}
+
 // AnalyzeQuery parses a Cypher query and extracts metadata.
 func (a *Analyzer) AnalyzeQuery(query string) (*scaf.QueryMetadata, error) {
-	return a.analyzeQueryInternal(query, nil)
+	return a.analyzeQueryInternal(query, nil, nil, nil)
+}
+
+// AnalyzeQueryWithContext analyzes a query that runs inside another, such as
+// the body of a CALL { ... } subquery, with the variables of outerScope in
+// scope. Their types carry through to the returns that use them. outerScope
+// may be nil.
+func (a *Analyzer) AnalyzeQueryWithContext(query string, outerScope *QueryScope) (*scaf.QueryMetadata, error) {
+	return a.analyzeQueryInternal(query, nil, nil, outerScope)
 }
 
 // AnalyzeQueryWithSchema parses a Cypher query and extracts metadata with type inference.
 // If schema is provided, it infers types for parameters and returns.
 func (a *Analyzer) AnalyzeQueryWithSchema(query string, schema *analysis.TypeSchema) (*scaf.QueryMetadata, error) {
-	return a.analyzeQueryInternal(query, schema)
+	return a.analyzeQueryInternal(query, schema, nil, nil)
+}
+
+// AnalyzeQueryParams returns the parameters of a Cypher query in order of
+// first use, each with the type inferred from how the query uses it: the
+// property it is compared with (u.age > $min), the list it is tested against
+// ($tag IN u.tags), or the property tested against it (u.id IN $ids).
+// Types needing the schema are unknown if schema is nil.
+func (a *Analyzer) AnalyzeQueryParams(query string, schema *analysis.TypeSchema) ([]scaf.ParameterInfo, error) {
+	metadata, err := a.analyzeQueryInternal(query, schema, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.Parameters, nil
+}
+
+// AnalyzeQueryWithParameters analyzes a query like AnalyzeQueryWithSchema and
+// also checks the declared parameter types against how the query uses them.
+// Conflicts, such as an int parameter used with STARTS WITH, are reported in
+// QueryMetadata.Warnings, with the parameter's name in WarningDetails. schema may be nil.
+func (a *Analyzer) AnalyzeQueryWithParameters(query string, params map[string]*scaf.TypeExpr, schema *analysis.TypeSchema) (*scaf.QueryMetadata, error) {
+	return a.analyzeQueryInternal(query, schema, params, nil)
 }
 
 // analyzeQueryInternal is the shared implementation for query analysis.
-func (a *Analyzer) analyzeQueryInternal(query string, schema *analysis.TypeSchema) (*scaf.QueryMetadata, error) {
+// params are the declared parameter types to check, and scope the variables
+// of an enclosing query, if any.
+func (a *Analyzer) analyzeQueryInternal(query string, schema *analysis.TypeSchema, params map[string]*scaf.TypeExpr, scope *QueryScope) (*scaf.QueryMetadata, error) {
 	ast, err := cyphergrammar.Parse(query)
 	if err != nil {
 		// Return partial results even on parse errors - we still want completion
@@ -85,7 +400,20 @@ func (a *Analyzer) analyzeQueryInternal(query string, schema *analysis.TypeSchem
 		}, nil
 	}
 
-	ctx := newQueryContext(schema)
+	ctx := newQueryContext(schema, &inferenceState{
+		strict:     a.strict,
+		maxDepth:   a.maxDepth,
+		apoc:       a.apoc,
+		declared:   make(map[string]bool),
+		scopes:     make(map[*cyphergrammar.Clause]*queryContext),
+		withScopes: make(map[*cyphergrammar.WithClause]*queryContext),
+	})
+	if scope != nil {
+		for name, typ := range scope.Variables {
+			ctx.locals[name] = typ
+		}
+	}
+
 	result := &scaf.QueryMetadata{
 		Parameters: []scaf.ParameterInfo{},
 		Returns:    []scaf.ReturnInfo{},
@@ -95,10 +423,20 @@ func (a *Analyzer) analyzeQueryInternal(query string, schema *analysis.TypeSchem
 	// First pass: extract variable bindings from MATCH clauses
 	extractBindings(ast, ctx)
 
-	// Export bindings to result for hover/completion
-	for varName, binding := range ctx.bindings {
-		if len(binding.labels) > 0 {
-			result.Bindings[varName] = binding.labels
+	// Export bindings to result for hover/completion. Bindings of later
+	// frames, such as WITH clauses and subquery bodies, are included unless
+	// an earlier variable of the same name takes precedence.
+	seen := make(map[string]bool)
+
+	for _, frame := range append([]*queryContext{ctx}, ctx.inference.frames...) {
+		for varName, binding := range frame.bindings {
+			if !seen[varName] && len(binding.labels) > 0 {
+				result.Bindings[varName] = binding.labels
+			}
+		}
+
+		for varName := range frame.bindings {
+			seen[varName] = true
 		}
 	}
 
@@ -108,14 +446,50 @@ func (a *Analyzer) analyzeQueryInternal(query string, schema *analysis.TypeSchem
 	// Extract return items with type inference
 	extractReturns(ast, result, ctx)
 
+	// Record whether DISTINCT or ORDER BY shape the returned rows
+	extractRowOrdering(ast, result)
+
+	result.CreatesData = createsData(ast)
+	result.MatchedLabels, result.CreatedLabels, result.RelationshipTypes = patternLabels(ast)
+
 	// Check for unique field filters if schema is provided
 	if schema != nil {
 		result.ReturnsOne = checkUniqueFilter(ast, schema)
 	}
 
+	// Check declared parameter types against their usage
+	var warnings []scaf.QueryWarning
+	if params != nil {
+		warnings = checkDeclaredParameters(result.Parameters, params, ctx)
+	}
+
+	for _, warning := range append(warnings, queryWarnings(ast, ctx)...) {
+		result.AddWarning(warning)
+	}
+
+	if ctx.inference.strict {
+		if name := ctx.inference.firstUndefined(); name != "" {
+			return nil, fmt.Errorf("%w: %s", ErrUndefinedVariable, name)
+		}
+	}
+
 	return result, nil
 }
 
+// firstUndefined returns the first variable referenced without being
+// declared anywhere in the query, or "" if there is none. Declarations are
+// checked at the end so a variable declared later in a UNION branch or
+// subquery isn't reported.
+func (s *inferenceState) firstUndefined() string {
+	for _, name := range s.undefined {
+		if !s.declared[name] {
+			return name
+		}
+	}
+
+	return ""
+}
+
 // extractBindings walks the AST to find variable bindings from node patterns.
 // E.g., MATCH (u:User) binds variable "u" to label "User".
 func extractBindings(ast *cyphergrammar.Script, ctx *queryContext) {
@@ -134,37 +508,228 @@ func extractBindingsFromRegularQuery(rq *cyphergrammar.RegularQuery, ctx *queryC
 		return
 	}
 
-	for _, clause := range rq.SingleQuery.Clauses {
+	extractBindingsFromClauses(rq.SingleQuery.Clauses, ctx)
+
+	// Also check union queries
+	for _, union := range rq.Unions {
+		if union.Query != nil {
+			extractBindingsFromClauses(union.Query.Clauses, ctx)
+		}
+	}
+}
+
+// extractBindingsFromClauses binds the variables of each clause in order,
+// starting in ctx. Each WITH starts a new frame for the clauses after it, and
+// every clause records the frame it is resolved in.
+func extractBindingsFromClauses(clauses []*cyphergrammar.Clause, ctx *queryContext) {
+	frame := ctx
+
+	for _, clause := range clauses {
+		declareClauseVariables(clause, frame)
+
+		if frame.inference != nil {
+			frame.inference.scopes[clause] = frame
+		}
+
+		// WITH projects the variables the next clauses see
+		if clause.With != nil {
+			frame = frame.withScope(clause.With)
+			continue
+		}
 		// Extract from MATCH clauses
 		if clause.Reading != nil && clause.Reading.Match != nil {
-			extractBindingsFromPattern(clause.Reading.Match.Pattern, ctx)
+			extractBindingsFromPattern(clause.Reading.Match.Pattern, frame)
+		}
+		// Extract from UNWIND clauses
+		if clause.Reading != nil && clause.Reading.Unwind != nil {
+			extractUnwindBinding(clause.Reading.Unwind, frame)
+		}
+		// Extract from CALL procedure() YIELD clauses
+		if clause.Reading != nil && clause.Reading.Call != nil {
+			extractProcedureCallBindings(clause.Reading.Call, frame)
 		}
 		// Extract from CREATE clauses
 		if clause.Updating != nil && clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
-			extractBindingsFromPattern(clause.Updating.Create.Pattern, ctx)
+			extractBindingsFromPattern(clause.Updating.Create.Pattern, frame)
 		}
 		// Extract from MERGE clauses
 		if clause.Updating != nil && clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
-			extractBindingsFromPatternElement(clause.Updating.Merge.Pattern.Element, ctx)
+			extractBindingsFromPatternElement(clause.Updating.Merge.Pattern.Element, frame)
+		}
+		// Extract from FOREACH loop variables and bodies
+		if clause.Updating != nil && clause.Updating.ForEach != nil {
+			extractForEachBindings(clause.Updating.ForEach, frame)
+		}
+		// Extract from CALL { ... } subqueries
+		if clause.CallSubquery != nil {
+			extractBindingsFromCallSubquery(clause.CallSubquery, frame)
 		}
 	}
+}
 
-	// Also check union queries
-	for _, union := range rq.Unions {
-		if union.Query != nil {
-			for _, clause := range union.Query.Clauses {
-				if clause.Reading != nil && clause.Reading.Match != nil {
-					extractBindingsFromPattern(clause.Reading.Match.Pattern, ctx)
-				}
-				if clause.Updating != nil && clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
-					extractBindingsFromPattern(clause.Updating.Create.Pattern, ctx)
-				}
-				if clause.Updating != nil && clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
-					extractBindingsFromPatternElement(clause.Updating.Merge.Pattern.Element, ctx)
-				}
+// declareClauseVariables records the variables a clause declares that may
+// get no type or label binding: projection aliases, UNWIND and FOREACH
+// variables, and YIELDed procedure columns.
+func declareClauseVariables(clause *cyphergrammar.Clause, ctx *queryContext) {
+	declareAliases := func(body *cyphergrammar.ProjectionBody) {
+		if body == nil || body.Items == nil {
+			return
+		}
+		for _, item := range body.Items.Items {
+			if item != nil {
+				ctx.declare(item.Alias)
 			}
 		}
 	}
+
+	if clause.With != nil {
+		declareAliases(clause.With.Body)
+	}
+	if clause.Return != nil {
+		declareAliases(clause.Return.Body)
+	}
+	if clause.Reading != nil && clause.Reading.Unwind != nil {
+		ctx.declare(clause.Reading.Unwind.Symbol)
+	}
+	if clause.Reading != nil && clause.Reading.Call != nil {
+		for _, item := range clause.Reading.Call.Yield {
+			ctx.declare(item.Name)
+		}
+	}
+	if clause.Updating != nil && clause.Updating.ForEach != nil {
+		ctx.declare(clause.Updating.ForEach.Variable)
+	}
+}
+
+// extractUnwindBinding binds the UNWIND variable to the element type of the list.
+// E.g., UNWIND u.tags AS tag binds "tag" to string when u.tags is []string,
+// and UNWIND collect(u) AS x binds "x" to the User model.
+func extractUnwindBinding(unwind *cyphergrammar.UnwindClause, ctx *queryContext) {
+	if unwind == nil {
+		return
+	}
+
+	bindListElement(unwind.Symbol, unwind.Expr, ctx)
+}
+
+// procedureYields maps built-in procedures to the types of the columns they
+// yield. Procedures not listed here still bring their variables into scope,
+// just without a type.
+// A procedures.yaml alongside the type schema could
+// extend this with user-defined signatures.
+var procedureYields = map[string]map[string]*analysis.Type{
+	"db.labels":            {"label": analysis.TypeString},
+	"db.relationshipTypes": {"relationshipType": analysis.TypeString},
+	"db.propertyKeys":      {"propertyKey": analysis.TypeString},
+
+	"apoc.path.subgraphNodes": {"node": analysis.PointerTo(analysis.TypeNode)},
+	"apoc.path.subgraphAll": {
+		"nodes":         analysis.SliceOf(analysis.PointerTo(analysis.TypeNode)),
+		"relationships": analysis.SliceOf(analysis.PointerTo(analysis.TypeRelationship)),
+	},
+	"apoc.path.spanningTree": {"path": analysis.TypePath},
+	"apoc.path.expand":       {"path": analysis.TypePath},
+	"apoc.path.expandConfig": {"path": analysis.TypePath},
+}
+
+// extractProcedureCallBindings brings the variables yielded by a procedure call
+// into scope. E.g., CALL db.labels() YIELD label binds "label" to string, and
+// YIELD label AS l binds "l" to string.
+func extractProcedureCallBindings(call *cyphergrammar.ProcedureCall, ctx *queryContext) {
+	if call == nil {
+		return
+	}
+
+	known := procedureYields[call.FullName()]
+	for _, item := range call.Yield {
+		if item.Name == "*" {
+			continue
+		}
+		ctx.locals[item.Name] = known[item.Column()]
+	}
+}
+
+// extractForEachBindings binds the FOREACH loop variable to the element type of
+// the list, then extracts bindings from the update clauses in its body.
+// E.g., FOREACH (f IN collect(u) | SET f.name = $name) binds "f" to the User model.
+func extractForEachBindings(forEach *cyphergrammar.ForEachClause, ctx *queryContext) {
+	if forEach == nil {
+		return
+	}
+
+	bindListElement(forEach.Variable, forEach.List, ctx)
+
+	for _, clause := range forEach.Updates {
+		if clause.Updating == nil {
+			continue
+		}
+		if clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
+			extractBindingsFromPattern(clause.Updating.Create.Pattern, ctx)
+		}
+		if clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
+			extractBindingsFromPatternElement(clause.Updating.Merge.Pattern.Element, ctx)
+		}
+		if clause.Updating.ForEach != nil {
+			extractForEachBindings(clause.Updating.ForEach, ctx)
+		}
+	}
+}
+
+// bindListElement binds symbol to the element type of the list expression.
+func bindListElement(symbol string, list *cyphergrammar.Expression, ctx *queryContext) {
+	if symbol == "" {
+		return
+	}
+
+	listType := inferExpressionType(list, ctx)
+	if listType == nil || listType.Kind != analysis.TypeKindSlice || listType.Elem == nil {
+		return
+	}
+
+	elemType := listType.Elem
+	ctx.locals[symbol] = elemType
+
+	// Model elements also get a label binding so schema lookups (hover,
+	// required fields) work the same as for MATCH variables
+	model := elemType
+	if model.Kind == analysis.TypeKindPointer && model.Elem != nil {
+		model = model.Elem
+	}
+	if model.Kind == analysis.TypeKindNamed && model.Name != "" {
+		ctx.bindings[symbol] = &variableBinding{
+			variable: symbol,
+			labels:   []string{model.Name},
+		}
+	}
+}
+
+// extractBindingsFromCallSubquery walks the body of a CALL { ... } subquery
+// in its own scope (see subqueryContext). Columns the body RETURNs are
+// absorbed into the outer scope rather than being exposed as top-level query
+// returns; nothing else the body binds is visible outside it.
+func extractBindingsFromCallSubquery(call *cyphergrammar.CallSubquery, ctx *queryContext) {
+	if call == nil || call.Body == nil || call.Body.Query == nil {
+		return
+	}
+
+	rq := call.Body.Query.RegularQuery
+	if rq == nil || rq.SingleQuery == nil {
+		return
+	}
+
+	inner := ctx.subqueryContext(rq.SingleQuery)
+	ctx.subqueries[call] = inner
+
+	extractBindingsFromRegularQuery(rq, inner)
+
+	for _, clause := range rq.SingleQuery.Clauses {
+		if clause.Return == nil || clause.Return.Body == nil || clause.Return.Body.Items == nil {
+			continue
+		}
+		for _, item := range clause.Return.Body.Items.Items {
+			projectItem(item, inner.scopeAt(clause), ctx)
+		}
+	}
 }
 
 func extractBindingsFromPattern(pattern *cyphergrammar.Pattern, ctx *queryContext) {
@@ -173,8 +738,13 @@ func extractBindingsFromPattern(pattern *cyphergrammar.Pattern, ctx *queryContex
 	}
 
 	for _, part := range pattern.Parts {
-		if part.Element != nil {
-			extractBindingsFromPatternElement(part.Element, ctx)
+		// p = (a)-->(b) or p = shortestPath(...) binds a path.
+		if part.Var != "" {
+			ctx.locals[part.Var] = analysis.TypePath
+		}
+
+		if part.PatternElement() != nil {
+			extractBindingsFromPatternElement(part.PatternElement(), ctx)
 		}
 	}
 }
@@ -197,6 +767,11 @@ func extractBindingsFromPatternElement(elem *cyphergrammar.PatternElement, ctx *
 
 	// Extract from chain
 	for _, chain := range elem.Chain {
+		// Relationship variables have no label binding, but are declared
+		if chain.Rel != nil && chain.Rel.Detail != nil {
+			ctx.declare(chain.Rel.Detail.Variable)
+		}
+
 		if chain.Node != nil {
 			extractNodeBinding(chain.Node, ctx)
 		}
@@ -213,6 +788,12 @@ func extractNodeBinding(node *cyphergrammar.NodePattern, ctx *queryContext) {
 		labels = node.Labels.Labels
 	}
 
+	// Re-referencing a bound variable without labels (e.g. MATCH (u)-[:KNOWS]->()
+	// after MATCH (u:User), or inside a subquery importing u) keeps its labels.
+	if _, ok := ctx.binding(node.Variable); ok && len(labels) == 0 {
+		return
+	}
+
 	ctx.bindings[node.Variable] = &variableBinding{
 		variable: node.Variable,
 		labels:   labels,
@@ -270,6 +851,12 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 
 	var walkExpr func(expr *cyphergrammar.Expression, propName string, labels []string)
 	var walkAtom func(atom *cyphergrammar.Atom, propName string, labels []string)
+	var walkNodePattern func(node *cyphergrammar.NodePattern)
+	var walkClauses func([]*cyphergrammar.Clause)
+
+	// comparedType is the type of a local variable the parameter being walked
+	// is compared with for equality (x = $param), if known
+	var comparedType *analysis.Type
 
 	walkAtom = func(atom *cyphergrammar.Atom, propName string, labels []string) {
 		if atom == nil {
@@ -288,8 +875,11 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 				position := param.Pos.Offset
 				length := len(paramName) + 1 // +1 for $
 
-				// Infer type from schema
+				// Infer type from schema, or from a local compared with the parameter
 				paramType := inferParameterType(propName, labels, ctx)
+				if paramType == nil {
+					paramType = comparedType
+				}
 
 				if idx, exists := indexByKey[paramName]; exists {
 					result.Parameters[idx].Count++
@@ -326,10 +916,50 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 		}
 
 		if atom.ListComprehension != nil {
-			walkExpr(atom.ListComprehension.Source, propName, labels)
-			if atom.ListComprehension.Mapping != nil {
-				walkExpr(atom.ListComprehension.Mapping, propName, labels)
+			lc := atom.ListComprehension
+			walkExpr(lc.Source, propName, labels)
+
+			// The filter and mapping see the loop variable, typed by its source list
+			outer := ctx
+			if lc.Variable != "" {
+				var elemType *analysis.Type
+				if listType := inferExpression(lc.Source, ctx); listType != nil && listType.Kind == analysis.TypeKindSlice {
+					elemType = listType.Elem
+				}
+				ctx = ctx.withLocal(lc.Variable, elemType)
+			}
+
+			if lc.Where != nil {
+				walkExpr(lc.Where.Expr, "", nil)
 			}
+			if lc.Mapping != nil {
+				walkExpr(lc.Mapping, propName, labels)
+			}
+
+			ctx = outer
+		}
+
+		if atom.PatternComprehension != nil && atom.PatternComprehension.Pattern != nil {
+			pc := atom.PatternComprehension
+
+			// The pattern, filter, and mapping see the variables the pattern binds
+			outer := ctx
+			ctx = extractPatternBindings(pc.Pattern, ctx)
+
+			walkNodePattern(pc.Pattern.Node)
+			for _, chain := range pc.Pattern.Chain {
+				if chain.Rel != nil && chain.Rel.Detail != nil && chain.Rel.Detail.InlineWhere != nil {
+					walkExpr(chain.Rel.Detail.InlineWhere, "", nil)
+				}
+				walkNodePattern(chain.Node)
+			}
+
+			if pc.Where != nil {
+				walkExpr(pc.Where.Expr, "", nil)
+			}
+			walkExpr(pc.Mapping, propName, labels)
+
+			ctx = outer
 		}
 
 		if atom.FunctionCall != nil {
@@ -342,6 +972,21 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 			walkExpr(atom.Parenthesized, propName, labels)
 		}
 
+		if atom.MapProjection != nil {
+			for _, item := range atom.MapProjection.Items {
+				if item.Value != nil {
+					walkExpr(item.Value, item.Key, labels)
+				}
+			}
+		}
+
+		// EXISTS { ... } and COUNT { ... } bodies can reference outer parameters
+		if body := atom.Subquery(); body != nil && body.Query != nil {
+			if inner := body.Query.RegularQuery; inner != nil && inner.SingleQuery != nil {
+				walkClauses(inner.SingleQuery.Clauses)
+			}
+		}
+
 		if atom.CaseExpr != nil {
 			if atom.CaseExpr.Input != nil {
 				walkExpr(atom.CaseExpr.Input, propName, labels)
@@ -403,18 +1048,39 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 											return
 										}
 
-										walkAtom(unary.Expr.Atom, ctxProp, ctxLabels)
+										post := unary.Expr
+										outerCompared := comparedType
+
+										// $param IN list and $param STARTS WITH ... type the parameter
+										if post.Atom != nil && post.Atom.Parameter != nil && len(post.Suffixes) > 0 {
+											switch first := post.Suffixes[0]; {
+											case first.In != nil:
+												if listType := inferAddSubExpression(first.In.Expr, ctx); listType != nil && listType.Kind == analysis.TypeKindSlice {
+													comparedType = listType.Elem
+												}
+											case first.StringPred != nil:
+												comparedType = analysis.TypeString
+											}
+										}
+
+										walkAtom(post.Atom, ctxProp, ctxLabels)
+										comparedType = outerCompared
 
 										// Walk suffixes
-										for _, suffix := range unary.Expr.Suffixes {
+										for i, suffix := range post.Suffixes {
 											if suffix.Index != nil {
 												walkExpr(suffix.Index.Start, ctxProp, ctxLabels)
 												walkExpr(suffix.Index.End, ctxProp, ctxLabels)
 											}
 											if suffix.In != nil && suffix.In.Expr != nil {
+												recordParamUsage(suffix.In.Expr, "IN", ctx)
+												comparedType = inOperandType(post, i, ctx)
 												walkAddSubExprSimple(suffix.In.Expr, ctxProp, ctxLabels, walkAtom)
+												comparedType = outerCompared
 											}
 											if suffix.StringPred != nil {
+												recordStringPredUsage(post.Atom, suffix.StringPred, ctx)
+												comparedType = analysis.TypeString
 												if suffix.StringPred.StartsWith != nil {
 													walkAddSubExprSimple(suffix.StringPred.StartsWith, ctxProp, ctxLabels, walkAtom)
 												}
@@ -424,6 +1090,7 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 												if suffix.StringPred.Contains != nil {
 													walkAddSubExprSimple(suffix.StringPred.Contains, ctxProp, ctxLabels, walkAtom)
 												}
+												comparedType = outerCompared
 											}
 										}
 									}
@@ -446,8 +1113,8 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 							}
 						}
 
-						// For equality comparisons (p.name = $param), infer type from property access
-						if len(comp.Right) == 1 && comp.Right[0].Op == "=" {
+						// For comparisons (p.name = $param, p.age > $min), infer type from property access
+						if len(comp.Right) == 1 {
 							leftInfo := extractPropertyAccess(comp.Left, ctx)
 							rightInfo := extractPropertyAccess(comp.Right[0].Expr, ctx)
 
@@ -461,6 +1128,20 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 								walkAddSubExprWithCtx(comp.Right[0].Expr, propName, labels)
 								return
 							}
+
+							// A local such as a list comprehension variable types the parameter
+							if t := localType(comp.Left, ctx); t != nil && addSubParameter(comp.Right[0].Expr) != "" {
+								comparedType = t
+								walkAddSubExprWithCtx(comp.Right[0].Expr, propName, labels)
+								comparedType = nil
+								return
+							}
+							if t := localType(comp.Right[0].Expr, ctx); t != nil && addSubParameter(comp.Left) != "" {
+								comparedType = t
+								walkAddSubExprWithCtx(comp.Left, propName, labels)
+								comparedType = nil
+								return
+							}
 						}
 
 						// Default: walk with passed-in context
@@ -492,9 +1173,23 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 	}
 
 	// Walk map literals in node patterns to get property context
-	var walkNodePattern func(node *cyphergrammar.NodePattern)
+	walkRelDetail := func(detail *cyphergrammar.RelationshipDetail) {
+		if detail.InlineWhere != nil {
+			walkExpr(detail.InlineWhere, "", nil)
+		}
+	}
+
 	walkNodePattern = func(node *cyphergrammar.NodePattern) {
-		if node == nil || node.Properties == nil {
+		if node == nil {
+			return
+		}
+
+		// Inline WHERE predicates resolve like an outer WHERE clause
+		if node.InlineWhere != nil {
+			walkExpr(node.InlineWhere, "", nil)
+		}
+
+		if node.Properties == nil {
 			return
 		}
 
@@ -530,91 +1225,134 @@ func extractParameters(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ct
 		}
 	}
 
-	// Walk the entire AST
-	if ast.Query != nil {
-		if rq := ast.Query.RegularQuery; rq != nil && rq.SingleQuery != nil {
-			for _, clause := range rq.SingleQuery.Clauses {
-				if clause.Reading != nil {
-					if clause.Reading.Match != nil && clause.Reading.Match.Pattern != nil {
-						for _, part := range clause.Reading.Match.Pattern.Parts {
-							if part.Element != nil {
-								walkPatternElement(part.Element, walkNodePattern)
-							}
-						}
-						if clause.Reading.Match.Where != nil {
-							walkExpr(clause.Reading.Match.Where.Expr, "", nil)
+	// Walk the entire AST, descending into CALL { ... } subquery bodies with
+	// the body's scope in ctx
+	walkClauses = func(clauses []*cyphergrammar.Clause) {
+		// Each clause is walked in its own scope frame
+		base := ctx
+		defer func() { ctx = base }()
+
+		for _, clause := range clauses {
+			ctx = base.scopeAt(clause)
+
+			if call := clause.CallSubquery; call != nil && call.Body != nil && call.Body.Query != nil {
+				if inner := call.Body.Query.RegularQuery; inner != nil && inner.SingleQuery != nil {
+					outer := ctx
+					if scoped, ok := ctx.subqueries[call]; ok {
+						ctx = scoped
+					}
+
+					walkClauses(inner.SingleQuery.Clauses)
+
+					ctx = outer
+				}
+			}
+			if clause.Reading != nil {
+				if clause.Reading.Match != nil && clause.Reading.Match.Pattern != nil {
+					for _, part := range clause.Reading.Match.Pattern.Parts {
+						if part.PatternElement() != nil {
+							walkPatternElement(part.PatternElement(), walkNodePattern, walkRelDetail)
 						}
 					}
-					if clause.Reading.Unwind != nil {
-						walkExpr(clause.Reading.Unwind.Expr, "", nil)
+					if clause.Reading.Match.Where != nil {
+						walkExpr(clause.Reading.Match.Where.Expr, "", nil)
 					}
 				}
-				if clause.Updating != nil {
-					if clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
-						for _, part := range clause.Updating.Create.Pattern.Parts {
-							if part.Element != nil {
-								walkPatternElement(part.Element, walkNodePattern)
-							}
-						}
+				if clause.Reading.Unwind != nil {
+					walkExpr(clause.Reading.Unwind.Expr, "", nil)
+				}
+				if call := clause.Reading.Call; call != nil {
+					for _, arg := range call.Args {
+						walkExpr(arg, "", nil)
+					}
+					if call.Where != nil {
+						walkExpr(call.Where.Expr, "", nil)
 					}
-					if clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
-						if clause.Updating.Merge.Pattern.Element != nil {
-							walkPatternElement(clause.Updating.Merge.Pattern.Element, walkNodePattern)
+				}
+			}
+			if clause.Updating != nil {
+				if clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
+					for _, part := range clause.Updating.Create.Pattern.Parts {
+						if part.PatternElement() != nil {
+							walkPatternElement(part.PatternElement(), walkNodePattern, walkRelDetail)
 						}
-						for _, action := range clause.Updating.Merge.Actions {
-							if action.Set != nil {
-								for _, item := range action.Set.Items {
-									walkSetItem(item, walkExpr)
-								}
+					}
+				}
+				if clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
+					if clause.Updating.Merge.Pattern.Element != nil {
+						walkPatternElement(clause.Updating.Merge.Pattern.Element, walkNodePattern, walkRelDetail)
+					}
+					for _, action := range clause.Updating.Merge.Actions {
+						if action.Set != nil {
+							for _, item := range action.Set.Items {
+								walkSetItem(item, ctx, walkExpr)
 							}
 						}
 					}
-					if clause.Updating.Delete != nil {
-						for _, expr := range clause.Updating.Delete.Exprs {
-							walkExpr(expr, "", nil)
-						}
+				}
+				if clause.Updating.Delete != nil {
+					for _, expr := range clause.Updating.Delete.Exprs {
+						walkExpr(expr, "", nil)
 					}
-					if clause.Updating.Set != nil {
-						for _, item := range clause.Updating.Set.Items {
-							walkSetItem(item, walkExpr)
-						}
+				}
+				if clause.Updating.Set != nil {
+					for _, item := range clause.Updating.Set.Items {
+						walkSetItem(item, ctx, walkExpr)
 					}
-					// REMOVE clause doesn't typically contain parameters
 				}
-				if clause.Return != nil && clause.Return.Body != nil {
-					if clause.Return.Body.Items != nil {
-						for _, item := range clause.Return.Body.Items.Items {
-							walkExpr(item.Expr, "", nil)
-						}
+				if forEach := clause.Updating.ForEach; forEach != nil {
+					walkExpr(forEach.List, "", nil)
+					walkClauses(forEach.Updates)
+				}
+				// REMOVE clause doesn't typically contain parameters
+			}
+			if clause.Return != nil && clause.Return.Body != nil {
+				if clause.Return.Body.Items != nil {
+					for _, item := range clause.Return.Body.Items.Items {
+						walkExpr(item.Expr, "", nil)
 					}
 				}
-				if clause.With != nil && clause.With.Body != nil {
-					if clause.With.Body.Items != nil {
-						for _, item := range clause.With.Body.Items.Items {
-							walkExpr(item.Expr, "", nil)
-						}
+			}
+			if clause.With != nil && clause.With.Body != nil {
+				if clause.With.Body.Items != nil {
+					for _, item := range clause.With.Body.Items.Items {
+						walkExpr(item.Expr, "", nil)
 					}
-					if clause.With.Where != nil {
-						walkExpr(clause.With.Where.Expr, "", nil)
+				}
+				if clause.With.Where != nil {
+					// WHERE filters the projected rows
+					if frame, ok := ctx.inference.withScopes[clause.With]; ok {
+						ctx = frame
 					}
+
+					walkExpr(clause.With.Where.Expr, "", nil)
 				}
 			}
 		}
 	}
+
+	if ast.Query != nil {
+		if rq := ast.Query.RegularQuery; rq != nil && rq.SingleQuery != nil {
+			walkClauses(rq.SingleQuery.Clauses)
+		}
+	}
 }
 
-func walkPatternElement(elem *cyphergrammar.PatternElement, walkNode func(*cyphergrammar.NodePattern)) {
+func walkPatternElement(elem *cyphergrammar.PatternElement, walkNode func(*cyphergrammar.NodePattern), walkRel func(*cyphergrammar.RelationshipDetail)) {
 	if elem == nil {
 		return
 	}
 	if elem.Paren != nil {
-		walkPatternElement(elem.Paren, walkNode)
+		walkPatternElement(elem.Paren, walkNode, walkRel)
 		return
 	}
 	if elem.Node != nil {
 		walkNode(elem.Node)
 	}
 	for _, chain := range elem.Chain {
+		if chain.Rel != nil && chain.Rel.Detail != nil {
+			walkRel(chain.Rel.Detail)
+		}
 		if chain.Node != nil {
 			walkNode(chain.Node)
 		}
@@ -622,12 +1360,21 @@ func walkPatternElement(elem *cyphergrammar.PatternElement, walkNode func(*cyphe
 }
 
 // walkSetItem walks a SET item to find expressions that may contain parameters.
-func walkSetItem(item *cyphergrammar.SetItem, walkExpr func(*cyphergrammar.Expression, string, []string)) {
+func walkSetItem(item *cyphergrammar.SetItem, ctx *queryContext, walkExpr func(*cyphergrammar.Expression, string, []string)) {
 	if item == nil {
 		return
 	}
 	if item.PropertyExpr != nil {
-		walkExpr(item.PropertyExpr, "", nil)
+		// SET u.name = $name: the parameter takes the type of the property
+		var propName string
+		var labels []string
+		if prop := item.Property; prop != nil && len(prop.Props) == 1 {
+			if binding, ok := ctx.binding(prop.Base); ok {
+				propName = prop.Props[0]
+				labels = binding.labels
+			}
+		}
+		walkExpr(item.PropertyExpr, propName, labels)
 	}
 	if item.VarExpr != nil {
 		walkExpr(item.VarExpr, "", nil)
@@ -654,6 +1401,68 @@ func inferParameterType(propName string, labels []string, ctx *queryContext) *an
 	return nil
 }
 
+// localType returns the type of add if it is a bare local variable, such as a
+// list comprehension or UNWIND variable, with a known type.
+func localType(add *cyphergrammar.AddSubExpr, ctx *queryContext) *analysis.Type {
+	atom := bareAtom(add)
+	if atom == nil || atom.Variable == "" {
+		return nil
+	}
+
+	typ, _ := ctx.local(atom.Variable)
+
+	return typ
+}
+
+// inOperandType returns the type a parameter takes in the operand of the IN
+// suffix at post.Suffixes[i]: a list of the subject's type for u.id IN $ids,
+// or the subject's type for each item of u.id IN [$a, $b].
+func inOperandType(post *cyphergrammar.PostfixExpr, i int, ctx *queryContext) *analysis.Type {
+	atom := bareAtom(post.Suffixes[i].In.Expr)
+	if atom == nil {
+		return nil
+	}
+
+	subject := inferPostfixExpression(&cyphergrammar.PostfixExpr{Atom: post.Atom, Suffixes: post.Suffixes[:i]}, ctx)
+	if subject == nil {
+		return nil
+	}
+
+	switch {
+	case atom.Parameter != nil:
+		return analysis.SliceOf(subject)
+	case atom.Literal != nil && atom.Literal.List != nil:
+		return subject
+	default:
+		return nil
+	}
+}
+
+// bareAtom returns the atom add consists of, if it has no operators or suffixes.
+func bareAtom(add *cyphergrammar.AddSubExpr) *cyphergrammar.Atom {
+	post := barePostfix(add)
+	if post == nil || len(post.Suffixes) > 0 {
+		return nil
+	}
+
+	return post.Atom
+}
+
+// barePostfix returns the postfix expression add consists of, if it has no
+// operators.
+func barePostfix(add *cyphergrammar.AddSubExpr) *cyphergrammar.PostfixExpr {
+	if add == nil || len(add.Right) > 0 || add.Left == nil || len(add.Left.Right) > 0 {
+		return nil
+	}
+
+	pow := add.Left.Left
+	if pow == nil || len(pow.Right) > 0 || pow.Left == nil || pow.Left.Op != "" {
+		return nil
+	}
+
+	return pow.Left.Expr
+}
+
 // propertyAccessInfo holds information about a property access expression like "p.name"
 type propertyAccessInfo struct {
 	varName  string   // The variable name (e.g., "p")
@@ -707,10 +1516,14 @@ func extractPropertyAccess(add *cyphergrammar.AddSubExpr, ctx *queryContext) *pr
 
 	propName := suffix.Property
 
-	// Look up the variable's labels from bindings
+	// Look up the variable's labels from bindings, or from a scoped node
+	// local such as a pattern comprehension variable
 	var labels []string
-	if binding, ok := ctx.bindings[varName]; ok {
+	if binding, ok := ctx.binding(varName); ok {
 		labels = binding.labels
+	} else if local, _ := ctx.local(varName); local != nil && local.Kind == analysis.TypeKindPointer &&
+		local.Elem != nil && local.Elem.Kind == analysis.TypeKindNamed {
+		labels = []string{local.Elem.Name}
 	}
 
 	return &propertyAccessInfo{
@@ -721,18 +1534,191 @@ func extractPropertyAccess(add *cyphergrammar.AddSubExpr, ctx *queryContext) *pr
 }
 
 // extractReturns walks the AST to find RETURN clause items.
+// Only top-level RETURN clauses are considered; a RETURN inside a CALL { ... }
+// subquery feeds the outer scope instead (see extractBindingsFromCallSubquery).
 func extractReturns(ast *cyphergrammar.Script, result *scaf.QueryMetadata, ctx *queryContext) {
 	if ast == nil || ast.Query == nil {
 		return
 	}
 
 	if rq := ast.Query.RegularQuery; rq != nil && rq.SingleQuery != nil {
+		nullable := nullableVariables(rq.SingleQuery.Clauses, nil)
+
 		for _, clause := range rq.SingleQuery.Clauses {
-			if clause.Return != nil {
-				extractReturnInfo(clause.Return, result, ctx)
+			if clause.Return == nil {
+				continue
+			}
+
+			start := len(result.Returns)
+			extractReturnInfo(clause.Return, result, ctx.scopeAt(clause))
+
+			for i := start; i < len(result.Returns); i++ {
+				result.Returns[i].Nullable = nullable[result.Returns[i].Expression]
+			}
+		}
+	}
+}
+
+// extractRowOrdering sets IsDistinct and IsOrdered from the projections that
+// produce the final rows. Each WITH replaces the state of the ones before it.
+// A WITH ... ORDER BY carries through to RETURN, and a WITH DISTINCT does when
+// the RETURN passes all of its columns through, until a reading or updating
+// clause produces new rows. A UNION (without ALL) deduplicates the combined
+// rows and leaves them unordered.
+func extractRowOrdering(ast *cyphergrammar.Script, result *scaf.QueryMetadata) {
+	if ast == nil || ast.Query == nil {
+		return
+	}
+
+	rq := ast.Query.RegularQuery
+	if rq == nil || rq.SingleQuery == nil {
+		return
+	}
+
+	if len(rq.Unions) > 0 {
+		result.IsDistinct = true
+
+		for _, union := range rq.Unions {
+			if union.All {
+				result.IsDistinct = false
+			}
+		}
+
+		return
+	}
+
+	var (
+		with    *cyphergrammar.ProjectionBody
+		ordered bool
+	)
+
+	for _, clause := range rq.SingleQuery.Clauses {
+		switch {
+		case clause.With != nil && clause.With.Body != nil:
+			// ORDER BY sorts the WITH's output rows, after any aggregation
+			with = clause.With.Body
+			ordered = with.Order != nil
+		case clause.Return != nil && clause.Return.Body != nil:
+			body := clause.Return.Body
+
+			result.IsDistinct = body.Distinct || (with != nil && with.Distinct && passesColumns(with, body))
+			result.IsOrdered = body.Order != nil || (ordered && !hasAggregate(body))
+		default:
+			with, ordered = nil, false
+		}
+	}
+}
+
+// passesColumns reports whether the projection next returns every column
+// that prev projects unchanged, so rows that were distinct stay distinct.
+func passesColumns(prev, next *cyphergrammar.ProjectionBody) bool {
+	if prev.Items == nil || next.Items == nil {
+		return false
+	}
+
+	if next.Items.Star {
+		return true
+	}
+
+	if prev.Items.Star {
+		return false
+	}
+
+	returned := make(map[string]bool, len(next.Items.Items))
+	for _, item := range next.Items.Items {
+		returned[expressionToString(item.Expr)] = true
+	}
+
+	for _, item := range prev.Items.Items {
+		column := item.Alias
+		if column == "" {
+			column = expressionToString(item.Expr)
+		}
+
+		if !returned[column] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// createsData reports whether a query has a CREATE or MERGE clause anywhere,
+// including inside subqueries and FOREACH.
+func createsData(ast *cyphergrammar.Script) bool {
+	found := false
+
+	cyphergrammar.Inspect(ast, func(node any) bool {
+		switch node.(type) {
+		case *cyphergrammar.CreateClause, *cyphergrammar.MergeClause:
+			found = true
+		}
+
+		return !found
+	})
+
+	return found
+}
+
+// patternLabels returns the node labels in the MATCH patterns of a query, those
+// in its CREATE and MERGE patterns, and the relationship types in all three,
+// each sorted and deduplicated.
+func patternLabels(ast *cyphergrammar.Script) (matched, created, relTypes []string) {
+	collect := func(pattern any) []string {
+		var labels []string
+
+		cyphergrammar.Inspect(pattern, func(node any) bool {
+			switch n := node.(type) {
+			case *cyphergrammar.NodePattern:
+				if n.Labels != nil {
+					labels = append(labels, n.Labels.Labels...)
+				}
+			case *cyphergrammar.RelationshipDetail:
+				if n.Types != nil {
+					relTypes = append(relTypes, n.Types.Types...)
+				}
 			}
+
+			return true
+		})
+
+		return labels
+	}
+
+	cyphergrammar.Inspect(ast, func(node any) bool {
+		switch n := node.(type) {
+		case *cyphergrammar.MatchClause:
+			matched = append(matched, collect(n.Pattern)...)
+		case *cyphergrammar.CreateClause:
+			created = append(created, collect(n.Pattern)...)
+		case *cyphergrammar.MergeClause:
+			created = append(created, collect(n.Pattern)...)
+		}
+
+		return true
+	})
+
+	slices.Sort(matched)
+	slices.Sort(created)
+	slices.Sort(relTypes)
+
+	return slices.Compact(matched), slices.Compact(created), slices.Compact(relTypes)
+}
+
+// hasAggregate reports whether a projection aggregates, which regroups rows
+// and discards any earlier ordering.
+func hasAggregate(body *cyphergrammar.ProjectionBody) bool {
+	if body.Items == nil {
+		return false
+	}
+
+	for _, item := range body.Items.Items {
+		if item != nil && item.Expr != nil && isAggregateExpression(item.Expr) {
+			return true
 		}
 	}
+
+	return false
 }
 
 func extractReturnInfo(ret *cyphergrammar.ReturnClause, result *scaf.QueryMetadata, ctx *queryContext) {
@@ -831,25 +1817,12 @@ func lookupFieldFromExpression(expression string, ctx *queryContext) *analysis.F
 	}
 
 	// Look up the binding to get the model
-	binding, ok := ctx.bindings[varName]
+	binding, ok := ctx.binding(varName)
 	if !ok || len(binding.labels) == 0 {
 		return nil
 	}
 
-	modelName := binding.labels[0]
-	model, ok := ctx.schema.Models[modelName]
-	if !ok {
-		return nil
-	}
-
-	// Find the field
-	for _, field := range model.Fields {
-		if field.Name == propName {
-			return field
-		}
-	}
-
-	return nil
+	return lookupProperty(propName, binding.labels, ctx.schema)
 }
 
 // expressionToString converts an Expression AST back to a string representation.
@@ -1044,6 +2017,22 @@ func atomToString(sb *strings.Builder, atom *cyphergrammar.Atom) {
 		sb.WriteString("]")
 		return
 	}
+	if atom.PatternComprehension != nil {
+		tokensToString(sb, atom.PatternComprehension.Tokens)
+		return
+	}
+	if atom.PathFunction != nil {
+		tokensToString(sb, atom.PathFunction.Tokens)
+		return
+	}
+	if atom.ExistsSubquery != nil {
+		tokensToString(sb, atom.ExistsSubquery.Tokens)
+		return
+	}
+	if atom.CountSubquery != nil {
+		tokensToString(sb, atom.CountSubquery.Tokens)
+		return
+	}
 	if atom.FunctionCall != nil {
 		sb.WriteString(atom.FunctionCall.Name.String())
 		sb.WriteString("(")
@@ -1084,12 +2073,56 @@ func atomToString(sb *strings.Builder, atom *cyphergrammar.Atom) {
 		sb.WriteString(" END")
 		return
 	}
+	if atom.MapProjection != nil {
+		sb.WriteString(atom.MapProjection.Variable)
+		sb.WriteString(" {")
+		for i, item := range atom.MapProjection.Items {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(" ")
+			switch {
+			case item.AllProperties:
+				sb.WriteString(".*")
+			case item.Property != "":
+				sb.WriteString(".")
+				sb.WriteString(item.Property)
+			case item.Key != "":
+				sb.WriteString(item.Key)
+				sb.WriteString(": ")
+				sb.WriteString(expressionToString(item.Value))
+			default:
+				sb.WriteString(item.Variable)
+			}
+		}
+		sb.WriteString(" }")
+		return
+	}
 	if atom.Variable != "" {
 		sb.WriteString(atom.Variable)
 		return
 	}
 }
 
+// tokensToString writes tokens as source text, skipping whitespace and
+// comments and keeping a single space wherever the source separated tokens.
+func tokensToString(sb *strings.Builder, tokens []lexer.Token) {
+	var prev *lexer.Token
+
+	for i := range tokens {
+		tok := &tokens[i]
+		if strings.TrimSpace(tok.Value) == "" || strings.HasPrefix(tok.Value, "//") || strings.HasPrefix(tok.Value, "/*") {
+			continue
+		}
+
+		if prev != nil && prev.Pos.Offset+len(prev.Value) < tok.Pos.Offset {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(tok.Value)
+		prev = tok
+	}
+}
+
 func literalToString(sb *strings.Builder, lit *cyphergrammar.Literal) {
 	if lit == nil {
 		return
@@ -1363,7 +2396,7 @@ func checkPatternForUnique(pattern *cyphergrammar.Pattern, schema *analysis.Type
 	}
 
 	for _, part := range pattern.Parts {
-		if part.Element != nil && checkPatternElementForUnique(part.Element, schema) {
+		if part.PatternElement() != nil && checkPatternElementForUnique(part.PatternElement(), schema) {
 			return true
 		}
 	}
@@ -1427,8 +2460,10 @@ func checkNodePatternForUnique(node *cyphergrammar.NodePattern, schema *analysis
 	return false
 }
 
-// Ensure Analyzer implements scaf.QueryAnalyzer and analysis.SchemaAwareAnalyzer.
+// Ensure Analyzer implements scaf.QueryAnalyzer, analysis.SchemaAwareAnalyzer,
+// and analysis.ParameterAwareAnalyzer.
 var (
-	_ scaf.QueryAnalyzer           = (*Analyzer)(nil)
-	_ analysis.SchemaAwareAnalyzer = (*Analyzer)(nil)
+	_ scaf.QueryAnalyzer              = (*Analyzer)(nil)
+	_ analysis.SchemaAwareAnalyzer    = (*Analyzer)(nil)
+	_ analysis.ParameterAwareAnalyzer = (*Analyzer)(nil)
 )
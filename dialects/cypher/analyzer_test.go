@@ -1,9 +1,11 @@
 package cypher_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/rlch/scaf"
 	"github.com/rlch/scaf/analysis"
 	"github.com/rlch/scaf/dialects/cypher"
@@ -438,6 +440,224 @@ RETURN u.name AS userName, count(f) AS followerCount
 	}
 }
 
+func TestAnalyzer_AnalyzeQuery_CallSubquery(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name: "User",
+				Fields: []*analysis.Field{
+					{Name: "name", Type: analysis.TypeString},
+				},
+			},
+		},
+	}
+
+	query := `
+MATCH (u:User)
+CALL {
+  WITH u
+  MATCH (u)-[:KNOWS]->(f:User)
+  WHERE f.name <> $exclude
+  RETURN count(f) AS friends, f AS friend
+}
+RETURN u.name AS name, friends, friend.name AS friendName
+`
+
+	analyzer := cypher.NewAnalyzer()
+
+	metadata, err := analyzer.AnalyzeQueryWithSchema(query, schema)
+	if err != nil {
+		t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+	}
+
+	// Parameters inside the subquery body are still collected
+	if len(metadata.Parameters) != 1 || metadata.Parameters[0].Name != "exclude" {
+		t.Errorf("expected parameter 'exclude', got %+v", metadata.Parameters)
+	}
+
+	// The inner RETURN is absorbed into the outer scope, not exposed
+	gotReturns := make(map[string]string)
+	for _, r := range metadata.Returns {
+		gotReturns[r.Name] = typeStr(r.Type)
+	}
+
+	wantReturns := map[string]string{
+		"name":       "string",
+		"friends":    "int",
+		"friendName": "string",
+	}
+	if diff := cmp.Diff(wantReturns, gotReturns); diff != "" {
+		t.Errorf("returns mismatch (-want +got):\n%s", diff)
+	}
+
+	if labels := metadata.Bindings["friend"]; len(labels) != 1 || labels[0] != "User" {
+		t.Errorf("expected 'friend' bound to User, got %v", labels)
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_CallSubqueryScope(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name:   "User",
+				Fields: []*analysis.Field{{Name: "name", Type: analysis.TypeString}},
+			},
+			"Post": {
+				Name:   "Post",
+				Fields: []*analysis.Field{{Name: "likes", Type: analysis.TypeInt}},
+			},
+		},
+	}
+
+	// The body's u is a new variable that doesn't replace the outer one
+	query := `
+MATCH (u:User)
+CALL {
+  MATCH (u:Post)
+  WHERE u.likes = $min
+  RETURN max(u.likes) AS top
+}
+RETURN u.name AS name, top
+`
+
+	metadata, err := cypher.NewAnalyzer().AnalyzeQueryWithSchema(query, schema)
+	if err != nil {
+		t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+	}
+
+	gotReturns := make(map[string]string)
+	for _, r := range metadata.Returns {
+		gotReturns[r.Name] = typeStr(r.Type)
+	}
+
+	wantReturns := map[string]string{"name": "string", "top": "int"}
+	if diff := cmp.Diff(wantReturns, gotReturns); diff != "" {
+		t.Errorf("returns mismatch (-want +got):\n%s", diff)
+	}
+
+	// Parameters in the body are inferred from the body's bindings
+	if len(metadata.Parameters) != 1 || typeStr(metadata.Parameters[0].Type) != "int" {
+		t.Errorf("expected int parameter 'min', got %+v", metadata.Parameters)
+	}
+
+	if labels := metadata.Bindings["u"]; len(labels) != 1 || labels[0] != "User" {
+		t.Errorf("expected 'u' bound to User, got %v", labels)
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithContext(t *testing.T) {
+	t.Parallel()
+
+	scope := &cypher.QueryScope{
+		Variables: map[string]*scaf.Type{
+			"score": analysis.TypeFloat64,
+			"tags":  scaf.SliceOf(analysis.TypeString),
+		},
+	}
+
+	metadata, err := cypher.NewAnalyzer().AnalyzeQueryWithContext(
+		"WITH score, tags UNWIND tags AS tag RETURN score AS s, tag, $limit AS limit",
+		scope,
+	)
+	if err != nil {
+		t.Fatalf("AnalyzeQueryWithContext() error: %v", err)
+	}
+
+	gotReturns := make(map[string]string)
+	for _, r := range metadata.Returns {
+		gotReturns[r.Name] = typeStr(r.Type)
+	}
+
+	wantReturns := map[string]string{"s": "float64", "tag": "string", "limit": ""}
+	if diff := cmp.Diff(wantReturns, gotReturns); diff != "" {
+		t.Errorf("returns mismatch (-want +got):\n%s", diff)
+	}
+
+	// Without a scope the outer variables are unknown
+	metadata, err = cypher.NewAnalyzer().AnalyzeQueryWithContext("RETURN score AS s", nil)
+	if err != nil {
+		t.Fatalf("AnalyzeQueryWithContext() error: %v", err)
+	}
+
+	if len(metadata.Returns) != 1 || metadata.Returns[0].Type != nil {
+		t.Errorf("expected untyped return without a scope, got %+v", metadata.Returns)
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_ProcedureCall(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		query  string
+		want   map[string]string // return name -> type
+		params []string
+	}{
+		{
+			name:  "built-in yield",
+			query: "CALL db.labels() YIELD label RETURN label",
+			want:  map[string]string{"label": "string"},
+		},
+		{
+			name:  "other built-in",
+			query: "CALL db.relationshipTypes() YIELD relationshipType RETURN relationshipType",
+			want:  map[string]string{"relationshipType": "string"},
+		},
+		{
+			name:  "aliased yield",
+			query: "CALL db.labels() YIELD label AS l RETURN l",
+			want:  map[string]string{"l": "string"},
+		},
+		{
+			name:   "unknown procedure",
+			query:  "CALL apoc.text.join($parts, ', ') YIELD value RETURN value",
+			want:   map[string]string{"value": ""},
+			params: []string{"parts"},
+		},
+		{
+			name:   "yield where",
+			query:  "CALL db.labels() YIELD label WHERE label <> $skip RETURN label",
+			want:   map[string]string{"label": "string"},
+			params: []string{"skip"},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQuery(tt.query)
+			if err != nil {
+				t.Fatalf("AnalyzeQuery() error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, r := range metadata.Returns {
+				got[r.Name] = typeStr(r.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("returns mismatch (-want +got):\n%s", diff)
+			}
+
+			var params []string
+			for _, p := range metadata.Parameters {
+				params = append(params, p.Name)
+			}
+
+			if diff := cmp.Diff(tt.params, params); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestAnalyzer_AnalyzeQueryWithSchema_ReturnsOne(t *testing.T) {
 	t.Parallel()
 
@@ -511,6 +731,100 @@ func TestAnalyzer_AnalyzeQueryWithSchema_ReturnsOne(t *testing.T) {
 	}
 }
 
+func TestAnalyzer_AnalyzeQuery_DistinctAndOrdered(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		query        string
+		wantDistinct bool
+		wantOrdered  bool
+	}{
+		{
+			name:  "plain return",
+			query: "MATCH (u:User) RETURN u.name, u.age",
+		},
+		{
+			name:         "return distinct",
+			query:        "MATCH (u:User) RETURN DISTINCT u.name, u.age",
+			wantDistinct: true,
+		},
+		{
+			name:        "return order by",
+			query:       "MATCH (u:User) RETURN u.name ORDER BY u.name",
+			wantOrdered: true,
+		},
+		{
+			name:         "with distinct carries to return",
+			query:        "MATCH (u:User) WITH DISTINCT u.name AS name RETURN name",
+			wantDistinct: true,
+		},
+		{
+			name:  "return of a property after with distinct",
+			query: "MATCH (u:User) WITH DISTINCT u RETURN u.name",
+		},
+		{
+			name:  "return of some columns after with distinct",
+			query: "MATCH (u:User) WITH DISTINCT u.name AS name, u.age AS age RETURN name",
+		},
+		{
+			name:         "return star after with distinct",
+			query:        "MATCH (u:User) WITH DISTINCT u.name AS name, u.age AS age RETURN *",
+			wantDistinct: true,
+		},
+		{
+			name:  "plain with after with distinct",
+			query: "MATCH (u:User) WITH DISTINCT u.name AS name WITH name RETURN name",
+		},
+		{
+			name:  "match after with distinct",
+			query: "MATCH (u:User) WITH DISTINCT u MATCH (u)-[:KNOWS]->(f) RETURN f.name",
+		},
+		{
+			name:        "with order by carries to return",
+			query:       "MATCH (u:User) WITH u ORDER BY u.name LIMIT 10 RETURN u.name",
+			wantOrdered: true,
+		},
+		{
+			name:        "aggregating with order by carries to return",
+			query:       "MATCH (n:User) WITH n.x AS x, count(*) AS c ORDER BY c RETURN x, c",
+			wantOrdered: true,
+		},
+		{
+			name:  "aggregate after with order by",
+			query: "MATCH (u:User) WITH u ORDER BY u.name RETURN count(u)",
+		},
+		{
+			name:         "union",
+			query:        "MATCH (u:User) RETURN u.name AS name UNION MATCH (p:Post) RETURN p.title AS name",
+			wantDistinct: true,
+		},
+		{
+			name:  "union all",
+			query: "MATCH (u:User) RETURN u.name AS name UNION ALL MATCH (p:Post) RETURN p.title AS name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := cypher.NewAnalyzer().AnalyzeQuery(tt.query)
+			if err != nil {
+				t.Fatalf("AnalyzeQuery() error: %v", err)
+			}
+
+			if metadata.IsDistinct != tt.wantDistinct {
+				t.Errorf("IsDistinct = %v, want %v", metadata.IsDistinct, tt.wantDistinct)
+			}
+
+			if metadata.IsOrdered != tt.wantOrdered {
+				t.Errorf("IsOrdered = %v, want %v", metadata.IsOrdered, tt.wantOrdered)
+			}
+		})
+	}
+}
+
 func TestAnalyzer_AnalyzeQueryWithSchema_NilSchema(t *testing.T) {
 	t.Parallel()
 
@@ -724,9 +1038,7 @@ func TestAnalyzer_AnalyzeQueryWithSchema_TypeInference(t *testing.T) {
 	})
 }
 
-// TestAnalyzer_RequiredField tests that ReturnInfo.Required is correctly set
-// based on field.Required from the schema.
-func TestAnalyzer_RequiredField(t *testing.T) {
+func TestAnalyzer_AnalyzeQueryWithSchema_Unwind(t *testing.T) {
 	t.Parallel()
 
 	schema := &analysis.TypeSchema{
@@ -734,84 +1046,931 @@ func TestAnalyzer_RequiredField(t *testing.T) {
 			"User": {
 				Name: "User",
 				Fields: []*analysis.Field{
-					// Required fields
-					{Name: "id", Type: analysis.TypeString, Required: true},
-					{Name: "age", Type: analysis.TypeInt, Required: true},
-					// Nullable fields (Required: false is default)
-					{Name: "bio", Type: analysis.TypeString, Required: false},
-					{Name: "score", Type: analysis.TypeFloat64, Required: false},
-					// Reference types (nil-able regardless of Required)
-					{Name: "tags", Type: analysis.SliceOf(analysis.TypeString), Required: false},
+					{Name: "name", Type: analysis.TypeString, Required: true},
+					{Name: "tags", Type: analysis.SliceOf(analysis.TypeString)},
 				},
 			},
 		},
 	}
 
 	tests := []struct {
-		name         string
-		query        string
-		wantType     string
-		wantRequired bool
+		name  string
+		query string
+		want  map[string]string // return name -> type
 	}{
 		{
-			name:         "required string field",
-			query:        "MATCH (u:User) RETURN u.id",
-			wantType:     "string",
-			wantRequired: true,
-		},
-		{
-			name:         "required int field",
-			query:        "MATCH (u:User) RETURN u.age",
-			wantType:     "int",
-			wantRequired: true,
+			name:  "schema list property",
+			query: "MATCH (u:User) UNWIND u.tags AS tag RETURN tag",
+			want:  map[string]string{"tag": "string"},
 		},
 		{
-			name:         "nullable string field",
-			query:        "MATCH (u:User) RETURN u.bio",
-			wantType:     "string", // Type stays string, Required=false signals nullable
-			wantRequired: false,
+			name:  "range",
+			query: "UNWIND range(1, 10) AS n RETURN n",
+			want:  map[string]string{"n": "int"},
 		},
 		{
-			name:         "nullable float64 field",
-			query:        "MATCH (u:User) RETURN u.score",
-			wantType:     "float64",
-			wantRequired: false,
+			name:  "list literal",
+			query: "UNWIND ['a', 'b'] AS s RETURN s",
+			want:  map[string]string{"s": "string"},
 		},
 		{
-			name:         "nullable slice field",
-			query:        "MATCH (u:User) RETURN u.tags",
-			wantType:     "[]string",
-			wantRequired: false,
+			name:  "collected nodes",
+			query: "MATCH (u:User) UNWIND collect(u) AS item RETURN item.name, item",
+			want:  map[string]string{"name": "string", "item": "*User"},
 		},
 		{
-			name:         "complex expression defaults to required",
-			query:        "MATCH (u:User) RETURN u.bio IS NULL",
-			wantType:     "bool",
-			wantRequired: true, // Not a simple property access
+			name:  "untyped parameter",
+			query: "UNWIND $rows AS row RETURN row",
+			want:  map[string]string{"row": ""},
 		},
 	}
 
+	analyzer := cypher.NewAnalyzer()
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			analyzer := cypher.NewAnalyzer()
 			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, schema)
 			if err != nil {
-				t.Fatalf("error: %v", err)
-			}
-			if len(metadata.Returns) != 1 {
-				t.Fatalf("expected 1 return, got %d", len(metadata.Returns))
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
 			}
 
-			ret := metadata.Returns[0]
-			gotType := typeStr(ret.Type)
-			if gotType != tt.wantType {
-				t.Errorf("type = %q, want %q", gotType, tt.wantType)
+			got := make(map[string]string)
+			for _, r := range metadata.Returns {
+				got[r.Name] = typeStr(r.Type)
 			}
-			if ret.Required != tt.wantRequired {
-				t.Errorf("Required = %v, want %v", ret.Required, tt.wantRequired)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("returns mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestAnalyzer_AnalyzeQueryWithSchema_WithScope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		query      string
+		want       map[string]string // return name -> type
+		wantParams map[string]string // parameter name -> type
+	}{
+		{
+			name:  "projected aliases",
+			query: "MATCH (u:User) WITH u.name AS name, count(*) AS cnt RETURN name, cnt",
+			want:  map[string]string{"name": "string", "cnt": "int"},
+		},
+		{
+			name:  "alias shadows variable",
+			query: "MATCH (u:User) WITH u.age AS u RETURN u",
+			want:  map[string]string{"u": "int"},
+		},
+		{
+			name:  "dropped variable",
+			query: "UNWIND [1, 2] AS x WITH x AS y RETURN y, x",
+			want:  map[string]string{"y": "int", "x": ""},
+		},
+		{
+			name:  "with star",
+			query: "MATCH (u:User) WITH * RETURN u.name, u.age",
+			want:  map[string]string{"name": "string", "age": "int"},
+		},
+		{
+			name:       "where after with",
+			query:      "MATCH (u:User) WITH u AS person WHERE person.name = $name RETURN person.age",
+			want:       map[string]string{"age": "int"},
+			wantParams: map[string]string{"name": "string"},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, testSchema())
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, r := range metadata.Returns {
+				got[r.Name] = typeStr(r.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("returns mismatch (-want +got):\n%s", diff)
+			}
+
+			if tt.wantParams == nil {
+				return
+			}
+
+			gotParams := make(map[string]string)
+			for _, p := range metadata.Parameters {
+				gotParams[p.Name] = typeStr(p.Type)
+			}
+
+			if diff := cmp.Diff(tt.wantParams, gotParams); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		t.Parallel()
+
+		strict := cypher.NewAnalyzer(cypher.WithStrict(true))
+
+		_, err := strict.AnalyzeQueryWithSchema("MATCH (u:User) WITH count(u) AS n RETURN u.name", testSchema())
+		if !errors.Is(err, cypher.ErrUndefinedVariable) || err.Error() != "undefined variable: u" {
+			t.Errorf("AnalyzeQueryWithSchema() error = %v, want ErrUndefinedVariable for u", err)
+		}
+	})
+}
+
+func TestAnalyzer_AnalyzeQuery_NullableReturns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]bool // return name -> nullable
+	}{
+		{
+			name:  "optional match",
+			query: "MATCH (u:User) OPTIONAL MATCH (u)-[f:FOLLOWS]->(u2:User) RETURN u, f, u2, u2.name",
+			want:  map[string]bool{"u": false, "f": true, "u2": true, "name": false},
+		},
+		{
+			name:  "through with",
+			query: "MATCH (u:User) OPTIONAL MATCH (u)-[:FOLLOWS]->(u2:User) WITH u, u2 AS friend RETURN u, friend",
+			want:  map[string]bool{"u": false, "friend": true},
+		},
+		{
+			name:  "required by later match",
+			query: "OPTIONAL MATCH (u:User) MATCH (u)-[:AUTHORED]->(p:Post) RETURN u, p",
+			want:  map[string]bool{"u": false, "p": false},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQuery(tt.query)
+			if err != nil {
+				t.Fatalf("AnalyzeQuery() error: %v", err)
+			}
+
+			got := make(map[string]bool)
+			for _, r := range metadata.Returns {
+				got[r.Name] = r.Nullable
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("nullable mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_PatternLabels(t *testing.T) {
+	t.Parallel()
+
+	metadata, err := cypher.NewAnalyzer().AnalyzeQuery(
+		"MATCH (u:User), (t:Tag) OPTIONAL MATCH (u)-[:IN]->(o:Org) CREATE (u)-[:AUTHORED]->(p:Post:Draft) MERGE (p)-[:TAGGED]->(:Tag)")
+	if err != nil {
+		t.Fatalf("AnalyzeQuery() error: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"Org", "Tag", "User"}, metadata.MatchedLabels); diff != "" {
+		t.Errorf("MatchedLabels mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"Draft", "Post", "Tag"}, metadata.CreatedLabels); diff != "" {
+		t.Errorf("CreatedLabels mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"AUTHORED", "IN", "TAGGED"}, metadata.RelationshipTypes); diff != "" {
+		t.Errorf("RelationshipTypes mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithSchema_ForEach(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name: "User",
+				Fields: []*analysis.Field{
+					{Name: "name", Type: analysis.TypeString, Required: true},
+					{Name: "age", Type: analysis.TypeInt},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string // parameter name -> type
+	}{
+		{
+			name:  "loop variable bound to collected model",
+			query: "MATCH (u:User) FOREACH (f IN collect(u) | SET f.name = $name)",
+			want:  map[string]string{"name": "string"},
+		},
+		{
+			name:  "parameters in list and body",
+			query: "MATCH (u:User) FOREACH (x IN $ids | CREATE (:User {age: $age}))",
+			want:  map[string]string{"ids": "", "age": "int"},
+		},
+		{
+			name:  "nested foreach",
+			query: "MATCH (u:User) FOREACH (a IN [collect(u)] | FOREACH (b IN a | SET b.age = $age))",
+			want:  map[string]string{"age": "int"},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, schema)
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, p := range metadata.Parameters {
+				got[p.Name] = typeStr(p.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_MapProjection(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name: "User",
+				Fields: []*analysis.Field{
+					{Name: "name", Type: analysis.TypeString, Required: true},
+					{Name: "age", Type: analysis.TypeInt},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		schema *analysis.TypeSchema
+		want   map[string]string // return name -> type
+	}{
+		{
+			name:  "without schema",
+			query: "MATCH (u:User) RETURN u { .name, .age } AS user",
+			want:  map[string]string{"user": "map[string]"},
+		},
+		{
+			name:   "property selectors",
+			query:  "MATCH (u:User) RETURN u { .name, .age } AS user",
+			schema: schema,
+			want:   map[string]string{"user": "struct{name string; age int}"},
+		},
+		{
+			name:   "literal entry",
+			query:  "MATCH (u:User) RETURN u { .name, double: u.age * 2, label: 'x' } AS user",
+			schema: schema,
+			want:   map[string]string{"user": "struct{name string; double int; label string}"},
+		},
+		{
+			name:   "all properties and variable",
+			query:  "MATCH (u:User)-[:KNOWS]->(f:User) RETURN u { .*, f } AS user",
+			schema: schema,
+			want:   map[string]string{"user": "struct{name string; age int; f *User}"},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, tt.schema)
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, r := range metadata.Returns {
+				got[r.Name] = typeStr(r.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("returns mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_PathFunctions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string // return name -> type
+	}{
+		{
+			name:  "shortest path variable",
+			query: "MATCH p = shortestPath((a:User)-[*]->(b:User)) RETURN p",
+			want:  map[string]string{"p": "neo4j.Path"},
+		},
+		{
+			name:  "all shortest paths variable",
+			query: "MATCH p = allShortestPaths((a:User)-[*]-(b:User)) RETURN p",
+			want:  map[string]string{"p": "neo4j.Path"},
+		},
+		{
+			name:  "path functions",
+			query: "MATCH p = shortestPath((a)-[*]->(b)) RETURN length(p) AS hops, nodes(p) AS ns, relationships(p) AS rs",
+			want:  map[string]string{"hops": "int", "ns": "[]*neo4j.Node", "rs": "[]*neo4j.Relationship"},
+		},
+		{
+			name:  "expression form",
+			query: "MATCH (a:User), (b:User) RETURN shortestPath((a)-[*]->(b)) AS p, allShortestPaths((a)-[*]->(b)) AS ps",
+			want:  map[string]string{"p": "neo4j.Path", "ps": "[]neo4j.Path"},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, r := range metadata.Returns {
+				got[r.Name] = typeStr(r.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("returns mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_Subqueries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		query      string
+		want       map[string]string // return name -> type
+		wantParams []string
+	}{
+		{
+			name:  "exists in return",
+			query: "MATCH (u:User) RETURN EXISTS { MATCH (u)-[:KNOWS]->() } AS social",
+			want:  map[string]string{"social": "bool"},
+		},
+		{
+			name:  "count in return",
+			query: "MATCH (u:User) RETURN COUNT { MATCH (u)-[:KNOWS]->() } AS friends",
+			want:  map[string]string{"friends": "int"},
+		},
+		{
+			name:  "count pattern",
+			query: "MATCH (u:User) RETURN COUNT { (u)-->() } AS degree",
+			want:  map[string]string{"degree": "int"},
+		},
+		{
+			name:       "parameters inside subquery",
+			query:      "MATCH (u:User) WHERE EXISTS { MATCH (u)-[:KNOWS]->(f) WHERE f.age > $minAge } RETURN COUNT { MATCH (u)-->(p {id: $id}) } AS n",
+			want:       map[string]string{"n": "int"},
+			wantParams: []string{"minAge", "id"},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQuery(tt.query)
+			if err != nil {
+				t.Fatalf("AnalyzeQuery() error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, r := range metadata.Returns {
+				got[r.Name] = typeStr(r.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("returns mismatch (-want +got):\n%s", diff)
+			}
+
+			var params []string
+			for _, p := range metadata.Parameters {
+				params = append(params, p.Name)
+			}
+
+			if diff := cmp.Diff(tt.wantParams, params); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_InlineWhere(t *testing.T) {
+	t.Parallel()
+
+	query := "MATCH (u:User WHERE u.age = $age)-[r:KNOWS WHERE r.since > $since]->(f:User) RETURN f.name AS name"
+
+	metadata, err := cypher.NewAnalyzer().AnalyzeQueryWithSchema(query, testSchema())
+	if err != nil {
+		t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+	}
+
+	params := make(map[string]string)
+	for _, p := range metadata.Parameters {
+		params[p.Name] = typeStr(p.Type)
+	}
+
+	// $age resolves through u like it would in an outer WHERE
+	want := map[string]string{"age": "int", "since": ""}
+	if diff := cmp.Diff(want, params); diff != "" {
+		t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(metadata.Returns) != 1 || typeStr(metadata.Returns[0].Type) != "string" {
+		t.Errorf("Returns = %+v, want name string", metadata.Returns)
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithSchema_MultipleLabels(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{Models: map[string]*analysis.Model{
+		"Person": {Name: "Person", Fields: []*analysis.Field{
+			{Name: "name", Type: analysis.TypeString},
+		}},
+		"Employee": {Name: "Employee", Fields: []*analysis.Field{
+			{Name: "name", Type: analysis.TypeInt}, // Shadowed by Person.name
+			{Name: "salary", Type: analysis.TypeFloat64},
+		}},
+	}}
+
+	query := "MATCH (n:Person:Employee) WHERE n.salary = $salary RETURN n, n.name AS name, n.salary AS salary"
+
+	metadata, err := cypher.NewAnalyzer().AnalyzeQueryWithSchema(query, schema)
+	if err != nil {
+		t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+	}
+
+	returns := make(map[string]string)
+	for _, r := range metadata.Returns {
+		returns[r.Name] = typeStr(r.Type)
+	}
+
+	want := map[string]string{"n": "*Person", "name": "string", "salary": "float64"}
+	if diff := cmp.Diff(want, returns); diff != "" {
+		t.Errorf("returns mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(metadata.Parameters) != 1 || typeStr(metadata.Parameters[0].Type) != "float64" {
+		t.Errorf("Parameters = %+v, want salary float64", metadata.Parameters)
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithParameters(t *testing.T) {
+	t.Parallel()
+
+	simple := func(name string) *scaf.TypeExpr { return &scaf.TypeExpr{Simple: &name} }
+
+	tests := []struct {
+		name   string
+		query  string
+		params map[string]*scaf.TypeExpr
+		want   []scaf.QueryWarning
+	}{
+		{
+			name:   "int used with STARTS WITH",
+			query:  "MATCH (u:User) WHERE u.name STARTS WITH $prefix RETURN u",
+			params: map[string]*scaf.TypeExpr{"prefix": simple("int")},
+			want:   []scaf.QueryWarning{{Code: "param-type-mismatch", Message: "parameter $prefix is declared as int but used as a string with STARTS WITH", Param: "prefix"}},
+		},
+		{
+			name:   "parameter as subject of CONTAINS",
+			query:  "MATCH (u:User) WHERE $text CONTAINS u.name RETURN u",
+			params: map[string]*scaf.TypeExpr{"text": simple("bool")},
+			want:   []scaf.QueryWarning{{Code: "param-type-mismatch", Message: "parameter $text is declared as bool but used as a string with CONTAINS", Param: "text"}},
+		},
+		{
+			name:   "scalar used with IN",
+			query:  "MATCH (u:User) WHERE u.id IN $ids RETURN u",
+			params: map[string]*scaf.TypeExpr{"ids": simple("string")},
+			want:   []scaf.QueryWarning{{Code: "param-type-mismatch", Message: "parameter $ids is declared as string but used as a list with IN", Param: "ids"}},
+		},
+		{
+			name:   "matching types",
+			query:  "MATCH (u:User) WHERE u.name ENDS WITH $suffix AND u.id IN $ids RETURN u",
+			params: map[string]*scaf.TypeExpr{"suffix": simple("string"), "ids": {Array: simple("int")}},
+		},
+		{
+			name:   "any and undeclared",
+			query:  "MATCH (u:User) WHERE u.name STARTS WITH $prefix AND u.bio CONTAINS $text RETURN u",
+			params: map[string]*scaf.TypeExpr{"prefix": simple("any")},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+	ignoreRange := cmpopts.IgnoreFields(scaf.QueryWarning{}, "Range")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQueryWithParameters(tt.query, tt.params, nil)
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithParameters() error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, metadata.WarningDetails, ignoreRange); diff != "" {
+				t.Errorf("warnings mismatch (-want +got):\n%s", diff)
+			}
+
+			var messages []string
+			for _, w := range tt.want {
+				messages = append(messages, w.Message)
+			}
+
+			if diff := cmp.Diff(messages, metadata.Warnings); diff != "" {
+				t.Errorf("warning messages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_Warnings(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {Name: "User", Fields: []*analysis.Field{{Name: "name", Type: analysis.TypeString}}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		schema *analysis.TypeSchema
+		want   []scaf.QueryWarning
+	}{
+		{
+			name:  "deprecated exists",
+			query: "MATCH (u:User) WHERE exists(u.name) RETURN u",
+			want:  []scaf.QueryWarning{{Code: "deprecated-function", Message: "deprecated function exists() is removed in Neo4j 5; use IS NOT NULL instead"}},
+		},
+		{
+			name:  "exists subquery",
+			query: "MATCH (u:User) WHERE EXISTS { (u)-[:WROTE]->(:Post) } RETURN u",
+		},
+		{
+			name:  "disconnected patterns",
+			query: "MATCH (u:User), (p:Post) MATCH (c:Comment) RETURN u, p, c",
+			want:  []scaf.QueryWarning{{Code: "cartesian-product", Message: "cartesian product between disconnected patterns (u), (p), (c); connect them with a relationship"}},
+		},
+		{
+			name:  "patterns connected later",
+			query: "MATCH (u:User) MATCH (p:Post) MATCH (u)-[:WROTE]->(p) RETURN u, p",
+		},
+		{
+			name:  "limit without order by",
+			query: "MATCH (u:User) WITH u LIMIT 5 RETURN u",
+			want:  []scaf.QueryWarning{{Code: "limit-without-order-by", Message: "LIMIT without ORDER BY on WITH returns an arbitrary subset of rows"}},
+		},
+		{
+			name:  "limit on aggregate result",
+			query: "MATCH (a) RETURN count(a) AS c LIMIT 10",
+		},
+		{
+			name:  "limit on grouped aggregate",
+			query: "MATCH (u:User) RETURN u.name, count(*) AS c LIMIT 10",
+			want:  []scaf.QueryWarning{{Code: "limit-without-order-by", Message: "LIMIT without ORDER BY on RETURN returns an arbitrary subset of rows"}},
+		},
+		{
+			name:  "limit ordered by earlier WITH",
+			query: "MATCH (u:User) WITH u ORDER BY u.name RETURN u LIMIT 5",
+		},
+		{
+			name:   "unlabeled property access",
+			query:  "MATCH (n)-[:FOLLOWS]->(u:User) RETURN n.name, n.name AS again, u.name",
+			schema: schema,
+			want:   []scaf.QueryWarning{{Code: "unlabeled-property-access", Message: "property access n.name on unlabeled node n can't be checked against the schema; add a label"}},
+		},
+		{
+			name:  "unlabeled property access without schema",
+			query: "MATCH (n) RETURN n.name",
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+	ignoreRange := cmpopts.IgnoreFields(scaf.QueryWarning{}, "Range")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, tt.schema)
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, metadata.WarningDetails, ignoreRange); diff != "" {
+				t.Errorf("warnings mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryParams(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name: "User",
+				Fields: []*analysis.Field{
+					{Name: "id", Type: analysis.TypeString},
+					{Name: "name", Type: analysis.TypeString},
+					{Name: "age", Type: analysis.TypeInt},
+					{Name: "tags", Type: analysis.SliceOf(analysis.TypeString)},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{
+			name:  "equality",
+			query: "MATCH (u:User) WHERE u.name = $name RETURN u",
+			want:  map[string]string{"name": "string"},
+		},
+		{
+			name:  "ordering comparisons",
+			query: "MATCH (u:User) WHERE u.age > $min AND $max >= u.age AND u.name <> $other RETURN u",
+			want:  map[string]string{"min": "int", "max": "int", "other": "string"},
+		},
+		{
+			name:  "property IN parameter",
+			query: "MATCH (u:User) WHERE u.id IN $ids RETURN u",
+			want:  map[string]string{"ids": "[]string"},
+		},
+		{
+			name:  "parameter IN list property",
+			query: "MATCH (u:User) WHERE $tag IN u.tags RETURN u",
+			want:  map[string]string{"tag": "string"},
+		},
+		{
+			name:  "parameter IN list literal",
+			query: "MATCH (u:User) WHERE $n IN [1, 2, 3] RETURN u",
+			want:  map[string]string{"n": "int"},
+		},
+		{
+			name:  "parameters in an IN list",
+			query: "MATCH (u:User) WHERE u.age IN [$a, $b] RETURN u",
+			want:  map[string]string{"a": "int", "b": "int"},
+		},
+		{
+			name:  "string predicates",
+			query: "MATCH (u:User) WHERE u.name STARTS WITH $prefix OR $text CONTAINS u.name RETURN u",
+			want:  map[string]string{"prefix": "string", "text": "string"},
+		},
+		{
+			name:  "unknown",
+			query: "MATCH (u:User) WHERE size(u.tags) > $count RETURN u",
+			want:  map[string]string{"count": ""},
+		},
+	}
+
+	analyzer := cypher.NewAnalyzer()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			params, err := analyzer.AnalyzeQueryParams(tt.query, schema)
+			if err != nil {
+				t.Fatalf("AnalyzeQueryParams() error: %v", err)
+			}
+
+			got := make(map[string]string, len(params))
+			for _, p := range params {
+				got[p.Name] = typeString(p.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parameter types mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestAnalyzer_RequiredField tests that ReturnInfo.Required is correctly set
+// based on field.Required from the schema.
+func TestAnalyzer_RequiredField(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name: "User",
+				Fields: []*analysis.Field{
+					// Required fields
+					{Name: "id", Type: analysis.TypeString, Required: true},
+					{Name: "age", Type: analysis.TypeInt, Required: true},
+					// Nullable fields (Required: false is default)
+					{Name: "bio", Type: analysis.TypeString, Required: false},
+					{Name: "score", Type: analysis.TypeFloat64, Required: false},
+					// Reference types (nil-able regardless of Required)
+					{Name: "tags", Type: analysis.SliceOf(analysis.TypeString), Required: false},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		wantType     string
+		wantRequired bool
+	}{
+		{
+			name:         "required string field",
+			query:        "MATCH (u:User) RETURN u.id",
+			wantType:     "string",
+			wantRequired: true,
+		},
+		{
+			name:         "required int field",
+			query:        "MATCH (u:User) RETURN u.age",
+			wantType:     "int",
+			wantRequired: true,
+		},
+		{
+			name:         "nullable string field",
+			query:        "MATCH (u:User) RETURN u.bio",
+			wantType:     "string", // Type stays string, Required=false signals nullable
+			wantRequired: false,
+		},
+		{
+			name:         "nullable float64 field",
+			query:        "MATCH (u:User) RETURN u.score",
+			wantType:     "float64",
+			wantRequired: false,
+		},
+		{
+			name:         "nullable slice field",
+			query:        "MATCH (u:User) RETURN u.tags",
+			wantType:     "[]string",
+			wantRequired: false,
+		},
+		{
+			name:         "complex expression defaults to required",
+			query:        "MATCH (u:User) RETURN u.bio IS NULL",
+			wantType:     "bool",
+			wantRequired: true, // Not a simple property access
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			analyzer := cypher.NewAnalyzer()
+			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, schema)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if len(metadata.Returns) != 1 {
+				t.Fatalf("expected 1 return, got %d", len(metadata.Returns))
+			}
+
+			ret := metadata.Returns[0]
+			gotType := typeStr(ret.Type)
+			if gotType != tt.wantType {
+				t.Errorf("type = %q, want %q", gotType, tt.wantType)
+			}
+			if ret.Required != tt.wantRequired {
+				t.Errorf("Required = %v, want %v", ret.Required, tt.wantRequired)
+			}
+		})
+	}
+}
+
+func TestNewAnalyzer_Options(t *testing.T) {
+	t.Parallel()
+
+	returnType := func(t *testing.T, a *cypher.Analyzer, query string) string {
+		t.Helper()
+
+		metadata, err := a.AnalyzeQuery(query)
+		if err != nil {
+			t.Fatalf("AnalyzeQuery(%q) error: %v", query, err)
+		}
+
+		if len(metadata.Returns) != 1 {
+			t.Fatalf("AnalyzeQuery(%q) returns = %+v, want one", query, metadata.Returns)
+		}
+
+		return typeStr(metadata.Returns[0].Type)
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		t.Parallel()
+
+		strict := cypher.NewAnalyzer(cypher.WithStrict(true))
+
+		declared := []string{
+			"MATCH (a)-[r:KNOWS]->(b) RETURN type(r) AS t",
+			"UNWIND $xs AS x RETURN [y IN x | y] AS ys",
+			"MATCH (u:User) RETURN [(u)-[f:FOLLOWS]->(v) WHERE f.since > 1 | v.name] AS names",
+			"MATCH (u:User) WITH u.name AS n RETURN n ORDER BY n",
+			"CALL db.labels() YIELD label RETURN label",
+		}
+		for _, query := range declared {
+			if _, err := strict.AnalyzeQuery(query); err != nil {
+				t.Errorf("AnalyzeQuery(%q) error: %v", query, err)
+			}
+		}
+
+		_, err := strict.AnalyzeQuery("MATCH (u:User) RETURN v.name AS name")
+		if !errors.Is(err, cypher.ErrUndefinedVariable) || err.Error() != "undefined variable: v" {
+			t.Errorf("AnalyzeQuery() error = %v, want ErrUndefinedVariable for v", err)
+		}
+
+		// Lenient analyzers leave the type unknown
+		if got := returnType(t, cypher.NewAnalyzer(), "MATCH (u:User) RETURN v.name AS name"); got != "" {
+			t.Errorf("lenient return type = %q, want unknown", got)
+		}
+	})
+
+	t.Run("max depth", func(t *testing.T) {
+		t.Parallel()
+
+		query := "RETURN [[[1]]] AS x"
+
+		if got := returnType(t, cypher.NewAnalyzer(), query); got != "[][][]int" {
+			t.Errorf("unlimited return type = %q, want [][][]int", got)
+		}
+
+		if got := returnType(t, cypher.NewAnalyzer(cypher.WithMaxDepth(2)), query); got != "[][]" {
+			t.Errorf("depth 2 return type = %q, want [][]", got)
+		}
+	})
+
+	t.Run("apoc", func(t *testing.T) {
+		t.Parallel()
+
+		query := "RETURN apoc.text.join(['a'], ',') AS s"
+
+		if got := returnType(t, cypher.NewAnalyzer(), query); got != "string" {
+			t.Errorf("default return type = %q, want string", got)
+		}
+
+		if got := returnType(t, cypher.NewAnalyzer(cypher.WithAPOC(false)), query); got != "" {
+			t.Errorf("return type without APOC = %q, want unknown", got)
+		}
+	})
+}
@@ -9,7 +9,10 @@ func init() {
 }
 
 // Dialect implements scaf.Dialect for Cypher query analysis.
-type Dialect struct{}
+type Dialect struct {
+	// format holds the options Format uses.
+	format FormatOptions
+}
 
 // NewDialect creates a new Cypher dialect for query analysis.
 func NewDialect() *Dialect {
@@ -26,4 +29,41 @@ func (d *Dialect) Analyze(query string) (*scaf.QueryMetadata, error) {
 	return NewAnalyzer().AnalyzeQuery(query)
 }
 
-var _ scaf.Dialect = (*Dialect)(nil)
+// Format rewrites a Cypher query in canonical style: keywords uppercased,
+// each clause on its own line, ON CREATE/ON MATCH and subquery bodies
+// indented by two spaces, clauses wrapped at commas past 80 columns, and
+// single spaces around operators. A dialect from WithFormatConfig uses the
+// configured style instead. Queries that cannot be parsed are returned
+// unchanged.
+func (d *Dialect) Format(query string) (string, error) {
+	return d.FormatWithOptions(query, d.format)
+}
+
+// FormatWithOptions rewrites a Cypher query like Format, in the style opts
+// describes. Queries that cannot be parsed are returned unchanged.
+func (d *Dialect) FormatWithOptions(query string, opts FormatOptions) (string, error) {
+	formatted, _ := formatCypher(query, opts)
+
+	return formatted, nil
+}
+
+// WithFormatConfig returns a dialect that formats with the options in the
+// config's cypher.formatting section. indentWidth, such as an editor's tab
+// size, is used when the config sets no indent width.
+func (d *Dialect) WithFormatConfig(cfg *scaf.Config, indentWidth int) scaf.Dialect { //nolint:ireturn
+	var opts FormatOptions
+	if cfg != nil {
+		opts = FormatOptions(cfg.Cypher.Formatting)
+	}
+
+	if opts.IndentWidth <= 0 {
+		opts.IndentWidth = indentWidth
+	}
+
+	return &Dialect{format: opts}
+}
+
+var (
+	_ scaf.Dialect               = (*Dialect)(nil)
+	_ scaf.ConfigurableFormatter = (*Dialect)(nil)
+)
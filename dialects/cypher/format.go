@@ -0,0 +1,689 @@
+package cypher
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
+)
+
+// Keyword cases for FormatOptions.KeywordCase.
+const (
+	KeywordCaseUpper = "upper"
+	KeywordCaseLower = "lower"
+	// KeywordCaseMixed keeps keywords as written.
+	KeywordCaseMixed = "mixed"
+)
+
+// Formatting defaults.
+const (
+	DefaultIndentWidth   = 2
+	DefaultMaxLineLength = 80
+)
+
+// FormatOptions configures FormatWithOptions. Zero fields take the defaults.
+type FormatOptions struct {
+	// KeywordCase is KeywordCaseUpper, KeywordCaseLower, or KeywordCaseMixed.
+	KeywordCase string
+
+	// IndentWidth is the number of spaces for continuation lines and each
+	// level of subquery body.
+	IndentWidth int
+
+	// MaxLineLength is the width past which a clause wraps at its commas,
+	// continuing on indented lines. A negative length never wraps.
+	MaxLineLength int
+
+	// AlignClauses right-aligns clause keywords so clause bodies start in
+	// the same column.
+	AlignClauses bool
+}
+
+// DefaultFormatOptions returns the options Format uses.
+func DefaultFormatOptions() FormatOptions {
+	return FormatOptions{
+		KeywordCase:   KeywordCaseUpper,
+		IndentWidth:   DefaultIndentWidth,
+		MaxLineLength: DefaultMaxLineLength,
+	}
+}
+
+// withDefaults fills the unset fields of o from DefaultFormatOptions.
+func (o FormatOptions) withDefaults() FormatOptions {
+	defaults := DefaultFormatOptions()
+
+	if o.KeywordCase == "" {
+		o.KeywordCase = defaults.KeywordCase
+	}
+
+	if o.IndentWidth <= 0 {
+		o.IndentWidth = defaults.IndentWidth
+	}
+
+	if o.MaxLineLength == 0 {
+		o.MaxLineLength = defaults.MaxLineLength
+	}
+
+	return o
+}
+
+var cypherSymbols = cyphergrammar.CypherLexer.Symbols()
+
+var (
+	tokWhitespace   = cypherSymbols["Whitespace"]
+	tokLineComment  = cypherSymbols["LineComment"]
+	tokBlockComment = cypherSymbols["BlockComment"]
+	tokIdent        = cypherSymbols["Ident"]
+	tokDot          = cypherSymbols["Dot"]
+	tokRange        = cypherSymbols["Range"]
+	tokComma        = cypherSymbols["Comma"]
+	tokSemicolon    = cypherSymbols["Semicolon"]
+	tokColon        = cypherSymbols["Colon"]
+	tokPipe         = cypherSymbols["Pipe"]
+	tokDollar       = cypherSymbols["Dollar"]
+	tokStar         = cypherSymbols["Star"]
+	tokMinus        = cypherSymbols["Minus"]
+	tokPlus         = cypherSymbols["Plus"]
+	tokLess         = cypherSymbols["Less"]
+	tokGreater      = cypherSymbols["Greater"]
+	tokLParen       = cypherSymbols["LParen"]
+	tokRParen       = cypherSymbols["RParen"]
+	tokLBrace       = cypherSymbols["LBrace"]
+	tokRBrace       = cypherSymbols["RBrace"]
+	tokLBracket     = cypherSymbols["LBracket"]
+	tokRBracket     = cypherSymbols["RBracket"]
+	tokEscapedIdent = cypherSymbols["EscapedIdent"]
+	tokString       = cypherSymbols["String"]
+)
+
+// formatKeywords are the Cypher keywords whose case Format normalizes.
+// Literals (true, false, null) and function names keep their case.
+var formatKeywords = map[string]bool{
+	"MATCH": true, "OPTIONAL": true, "WHERE": true, "WITH": true, "RETURN": true,
+	"UNWIND": true, "CREATE": true, "MERGE": true, "DELETE": true, "DETACH": true,
+	"SET": true, "REMOVE": true, "ORDER": true, "BY": true, "SKIP": true,
+	"LIMIT": true, "UNION": true, "CALL": true, "YIELD": true, "FOREACH": true,
+	"LOAD": true, "CSV": true, "HEADERS": true, "FROM": true, "USE": true,
+	"ON": true, "AS": true, "DISTINCT": true, "ASC": true, "ASCENDING": true,
+	"DESC": true, "DESCENDING": true, "AND": true, "OR": true, "XOR": true,
+	"NOT": true, "IN": true, "IS": true, "STARTS": true, "ENDS": true,
+	"CONTAINS": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true,
+	"END": true, "EXISTS": true, "TRANSACTIONS": true, "OF": true, "ROWS": true,
+}
+
+// clauseKeywords start a new line when they begin a clause.
+var clauseKeywords = map[string]bool{
+	"MATCH": true, "OPTIONAL": true, "WHERE": true, "WITH": true, "RETURN": true,
+	"UNWIND": true, "CREATE": true, "MERGE": true, "DELETE": true, "DETACH": true,
+	"SET": true, "REMOVE": true, "ORDER": true, "SKIP": true, "LIMIT": true,
+	"UNION": true, "CALL": true, "FOREACH": true, "LOAD": true, "USE": true,
+}
+
+// subqueryKeywords introduce a braced subquery body: CALL { ... }, EXISTS { ... }.
+var subqueryKeywords = map[string]bool{
+	"CALL": true, "EXISTS": true, "COUNT": true, "COLLECT": true,
+}
+
+// formatCypher reformats query in the style opts describes. It reports false
+// when the query doesn't parse or the result wouldn't lex to the same tokens.
+func formatCypher(query string, opts FormatOptions) (string, bool) {
+	if strings.TrimSpace(query) == "" {
+		return query, false
+	}
+
+	parsed, err := cyphergrammar.Parse(query)
+	if err != nil {
+		return query, false
+	}
+
+	tokens, err := lexCypher(query)
+	if err != nil {
+		return query, false
+	}
+
+	opts = opts.withDefaults()
+	names := nameOffsets(parsed, tokens)
+
+	p := &cypherPrinter{tokens: tokens, opts: opts, names: names}
+	if opts.AlignClauses {
+		// A first pass finds the widest clause keyword to align with.
+		first := &cypherPrinter{tokens: tokens, opts: opts, names: names, measure: true}
+		first.print()
+		p.alignWidth = first.clauseWidth
+	}
+
+	formatted := p.print()
+
+	// The printer only changes keyword case and whitespace; anything else is a bug.
+	if _, err := cyphergrammar.Parse(formatted); err != nil {
+		return query, false
+	}
+
+	after, err := lexCypher(formatted)
+	if err != nil || !sameTokens(tokens, after, p.keywords) {
+		return query, false
+	}
+
+	return formatted, true
+}
+
+// lexCypher returns the tokens of query, including comments but not whitespace.
+func lexCypher(query string) ([]lexer.Token, error) {
+	lex, err := cyphergrammar.CypherLexer.LexString("", query)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []lexer.Token
+
+	for {
+		tok, err := lex.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok.EOF() {
+			return tokens, nil
+		}
+
+		if tok.Type != tokWhitespace {
+			tokens = append(tokens, tok)
+		}
+	}
+}
+
+// nameOffsets returns the offsets of the identifier tokens in the query that
+// name a variable, alias, or procedure output, which keep their case even
+// when spelled like a keyword: MATCH (from) RETURN from.
+func nameOffsets(parsed *cyphergrammar.Script, tokens []lexer.Token) map[int]bool {
+	names := make(map[int]bool)
+
+	for _, tok := range variableOccurrences(parsed, tokens) {
+		names[tok.Pos.Offset] = true
+	}
+
+	// YIELD items aren't all captured, so they're read off the tokens:
+	// CALL ns.proc(args) YIELD field AS alias, field
+	cyphergrammar.Inspect(parsed, func(node any) bool {
+		call, ok := node.(*cyphergrammar.ProcedureCall)
+		if !ok || len(call.Yield) == 0 {
+			return true
+		}
+
+		i := sort.Search(len(tokens), func(i int) bool {
+			return tokens[i].Pos.Offset >= call.Pos.Offset
+		}) + 1
+
+		// Skip the dotted procedure name and its arguments.
+		for i+1 < len(tokens) && tokens[i+1].Type == tokDot {
+			i += 2
+		}
+
+		i++
+
+		if i < len(tokens) && tokens[i].Type == tokLParen {
+			for depth := 0; i < len(tokens); i++ {
+				switch tokens[i].Type {
+				case tokLParen:
+					depth++
+				case tokRParen:
+					depth--
+				}
+
+				if depth == 0 {
+					i++
+					break
+				}
+			}
+		}
+
+		if i >= len(tokens) || !strings.EqualFold(tokens[i].Value, "YIELD") {
+			return true
+		}
+
+		for i++; i < len(tokens) && tokens[i].Type == tokIdent; i++ {
+			names[tokens[i].Pos.Offset] = true
+
+			if i+2 < len(tokens) && strings.EqualFold(tokens[i+1].Value, "AS") && tokens[i+2].Type == tokIdent {
+				i += 2
+				names[tokens[i].Pos.Offset] = true
+			}
+
+			if i+1 >= len(tokens) || tokens[i+1].Type != tokComma {
+				break
+			}
+
+			i++
+		}
+
+		return true
+	})
+
+	return names
+}
+
+// sameTokens reports whether a and b are the same token sequence, allowing
+// only the identifiers at the keyword indexes to differ in case.
+func sameTokens(a, b []lexer.Token, keywords []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Type != b[i].Type {
+			return false
+		}
+
+		if a[i].Value == b[i].Value {
+			continue
+		}
+
+		if a[i].Type != tokIdent || !slices.Contains(keywords, i) || !strings.EqualFold(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// printerFrame is an open bracket being printed.
+type printerFrame struct {
+	open lexer.TokenType
+	// block marks a subquery body, whose clauses go on their own lines.
+	block bool
+	// rel marks a relationship detail bracket: -[r:KNOWS*1..3]-.
+	rel bool
+}
+
+// cypherPrinter prints a token stream in the style of its options.
+type cypherPrinter struct {
+	tokens []lexer.Token
+	opts   FormatOptions
+	b      strings.Builder
+	stack  []printerFrame
+
+	// names holds the offsets of identifiers that are never keywords, from
+	// nameOffsets.
+	names map[int]bool
+
+	// keywords lists the indexes of the tokens printed as keywords.
+	keywords []int
+
+	// pendingNewline is set after a line comment.
+	pendingNewline bool
+
+	// alignWidth is the column width clause keywords are right-aligned to,
+	// or 0 when clauses aren't aligned.
+	alignWidth int
+
+	// measure marks a pass that only records clauseWidth, the width of the
+	// widest clause keyword, without wrapping.
+	measure     bool
+	clauseWidth int
+}
+
+func (p *cypherPrinter) print() string {
+	for i := range p.tokens {
+		p.emit(i)
+	}
+
+	return p.b.String()
+}
+
+// emit prints the token at i, after the line break or space it needs.
+func (p *cypherPrinter) emit(i int) {
+	tok := p.tokens[i]
+
+	value := tok.Value
+	if p.isKeyword(i) {
+		value = p.keywordCase(value)
+		p.keywords = append(p.keywords, i)
+	}
+
+	switch {
+	case i == 0 && p.isClauseStart(i):
+		p.alignClause(value)
+
+	case tok.Type == tokRBrace && p.inBlock():
+		p.stack = p.stack[:len(p.stack)-1]
+		p.newline(0)
+
+	case i > 0 && p.canBreak() && p.isClauseStart(i):
+		p.newline(0)
+		p.alignClause(value)
+
+	case i > 0 && p.canBreak() && p.isContinuation(i):
+		p.newline(1)
+
+	case p.pendingNewline:
+		p.newline(0)
+
+	case i > 0 && p.canBreak() && p.typeAt(i-1) == tokComma && p.overflows(i):
+		p.newline(1)
+
+	case i > 0 && p.needSpace(i):
+		p.b.WriteByte(' ')
+	}
+
+	p.b.WriteString(value)
+	p.pendingNewline = tok.Type == tokLineComment
+
+	switch tok.Type {
+	case tokLParen, tokLBracket, tokLBrace:
+		p.stack = append(p.stack, printerFrame{
+			open:  tok.Type,
+			block: tok.Type == tokLBrace && p.opensSubquery(i),
+			rel:   tok.Type == tokLBracket && i > 0 && p.isArrow(i-1),
+		})
+
+	case tokRParen, tokRBracket, tokRBrace:
+		if len(p.stack) > 0 {
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+	}
+}
+
+// keywordCase returns keyword in the configured case.
+func (p *cypherPrinter) keywordCase(keyword string) string {
+	switch p.opts.KeywordCase {
+	case KeywordCaseLower:
+		return strings.ToLower(keyword)
+	case KeywordCaseMixed:
+		return keyword
+	default:
+		return strings.ToUpper(keyword)
+	}
+}
+
+// alignClause pads before a clause keyword so it ends at alignWidth.
+func (p *cypherPrinter) alignClause(keyword string) {
+	p.clauseWidth = max(p.clauseWidth, len(keyword))
+
+	if pad := p.alignWidth - len(keyword); pad > 0 {
+		p.b.WriteString(strings.Repeat(" ", pad))
+	}
+}
+
+// newline starts a new line indented for the current block depth, plus extra
+// continuation levels. Aligned continuations start in the clause body column.
+func (p *cypherPrinter) newline(extra int) {
+	p.b.WriteByte('\n')
+
+	depth := 0
+	for _, f := range p.stack {
+		if f.block {
+			depth++
+		}
+	}
+
+	indent := depth * p.opts.IndentWidth
+	if extra > 0 && p.alignWidth > 0 {
+		indent += p.alignWidth + 1
+	} else {
+		indent += extra * p.opts.IndentWidth
+	}
+
+	p.b.WriteString(strings.Repeat(" ", indent))
+}
+
+// overflows reports whether the clause item starting at i, just after a
+// comma, would run past MaxLineLength on the current line.
+func (p *cypherPrinter) overflows(i int) bool {
+	if p.measure || p.opts.MaxLineLength < 0 {
+		return false
+	}
+
+	out := p.b.String()
+	line := out[strings.LastIndexByte(out, '\n')+1:]
+
+	return utf8.RuneCountInString(line)+p.itemWidth(i) > p.opts.MaxLineLength
+}
+
+// itemWidth returns the printed width of the clause item starting at i,
+// with its leading space and trailing comma, up to the first line break.
+func (p *cypherPrinter) itemWidth(i int) int {
+	item := &cypherPrinter{
+		tokens: p.tokens,
+		opts:   p.opts,
+		names:  p.names,
+		stack:  append([]printerFrame(nil), p.stack...),
+		// Nested items are measured on one line.
+		measure: true,
+	}
+
+	j := i
+	for ; j < len(p.tokens); j++ {
+		if j > i && item.canBreak() && (p.typeAt(j) == tokComma || item.isClauseStart(j) || item.isContinuation(j) ||
+			p.typeAt(j) == tokRBrace && item.inBlock()) {
+			break
+		}
+
+		item.emit(j)
+	}
+
+	out := item.b.String()
+	if k := strings.IndexByte(out, '\n'); k >= 0 {
+		out = out[:k]
+	}
+
+	width := utf8.RuneCountInString(out)
+	if p.typeAt(j) == tokComma {
+		width++
+	}
+
+	return width
+}
+
+func (p *cypherPrinter) top() printerFrame {
+	if len(p.stack) == 0 {
+		return printerFrame{}
+	}
+
+	return p.stack[len(p.stack)-1]
+}
+
+func (p *cypherPrinter) inBlock() bool {
+	return len(p.stack) > 0 && p.top().block
+}
+
+// canBreak reports whether clauses may start new lines here: at the top
+// level or directly inside a subquery body, but not inside expressions.
+func (p *cypherPrinter) canBreak() bool {
+	return len(p.stack) == 0 || p.top().block
+}
+
+func (p *cypherPrinter) typeAt(i int) lexer.TokenType {
+	if i < 0 || i >= len(p.tokens) {
+		return lexer.EOF
+	}
+
+	return p.tokens[i].Type
+}
+
+// upperAt returns the uppercased identifier at i, or "" if i is not an identifier.
+func (p *cypherPrinter) upperAt(i int) string {
+	if p.typeAt(i) != tokIdent {
+		return ""
+	}
+
+	return strings.ToUpper(p.tokens[i].Value)
+}
+
+// isKeyword reports whether the identifier at i is used as a keyword rather
+// than as a property, label, parameter, map key, alias or variable.
+func (p *cypherPrinter) isKeyword(i int) bool {
+	if !formatKeywords[p.upperAt(i)] || p.names[p.tokens[i].Pos.Offset] {
+		return false
+	}
+
+	switch p.typeAt(i - 1) {
+	case tokDot, tokColon, tokDollar:
+		return false
+	case tokPipe:
+		if p.top().rel {
+			return false
+		}
+	}
+
+	if p.upperAt(i-1) == "AS" && p.isKeyword(i-1) {
+		return false
+	}
+
+	switch p.typeAt(i + 1) {
+	case tokDot:
+		return false
+	case tokColon:
+		if p.top().open == tokLBrace && !p.top().block {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isClauseStart reports whether the token at i begins a clause.
+func (p *cypherPrinter) isClauseStart(i int) bool {
+	kw := p.upperAt(i)
+	if !clauseKeywords[kw] || !p.isKeyword(i) {
+		return false
+	}
+
+	prev := p.upperAt(i - 1)
+
+	switch kw {
+	case "MATCH", "CREATE":
+		return prev != "OPTIONAL" && prev != "ON"
+	case "DELETE":
+		return prev != "DETACH"
+	case "SET":
+		return !((prev == "CREATE" || prev == "MATCH") && p.upperAt(i-2) == "ON")
+	case "WITH":
+		return prev != "STARTS" && prev != "ENDS" && prev != "CSV"
+	}
+
+	return true
+}
+
+// isContinuation reports whether the token at i begins an indented
+// continuation line, such as ON CREATE SET after MERGE.
+func (p *cypherPrinter) isContinuation(i int) bool {
+	if p.upperAt(i) != "ON" || !p.isKeyword(i) {
+		return false
+	}
+
+	next := p.upperAt(i + 1)
+
+	return next == "CREATE" || next == "MATCH"
+}
+
+// opensSubquery reports whether the brace at i opens a subquery body.
+func (p *cypherPrinter) opensSubquery(i int) bool {
+	return subqueryKeywords[p.upperAt(i-1)] && clauseKeywords[p.upperAt(i+1)]
+}
+
+// isArrow reports whether the -, < or > at i is part of a relationship
+// pattern, e.g. (a)-[r]->(b) or (a)<--(b), rather than an operator.
+func (p *cypherPrinter) isArrow(i int) bool {
+	switch p.typeAt(i) {
+	case tokMinus, tokLess, tokGreater:
+	default:
+		return false
+	}
+
+	switch p.typeAt(i - 1) {
+	case tokRParen, tokRBracket, tokMinus, tokLess:
+	default:
+		return false
+	}
+
+	switch p.typeAt(i + 1) {
+	case tokLParen, tokLBracket, tokMinus, tokGreater:
+		return true
+	}
+
+	return false
+}
+
+// isUnary reports whether the + or - at i is a unary sign.
+func (p *cypherPrinter) isUnary(i int) bool {
+	if typ := p.typeAt(i); typ != tokMinus && typ != tokPlus {
+		return false
+	}
+
+	if p.isArrow(i) {
+		return false
+	}
+
+	return !p.endsOperand(i - 1)
+}
+
+// endsOperand reports whether the token at i can end an operand, so an
+// operator following it is binary.
+func (p *cypherPrinter) endsOperand(i int) bool {
+	switch p.typeAt(i) {
+	case tokRParen, tokRBracket, tokRBrace, tokString, tokEscapedIdent:
+		return true
+	case tokIdent:
+		return !p.isKeyword(i) || p.upperAt(i) == "END"
+	case tokLParen, tokLBracket, tokLBrace, tokComma, tokColon, tokSemicolon,
+		tokDollar, tokDot, tokPipe, tokLineComment, tokBlockComment, lexer.EOF:
+		return false
+	}
+
+	// Operators end nothing; literals (numbers) end operands.
+	return p.tokens[i].Type != tokStar && !isOperator(p.tokens[i].Type)
+}
+
+func isOperator(typ lexer.TokenType) bool {
+	for _, name := range []string{
+		"NotEqual", "LessEqual", "GreaterEqual", "AddAssign", "Eq", "Less",
+		"Greater", "Plus", "Minus", "Slash", "Percent", "Caret",
+	} {
+		if cypherSymbols[name] == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// needSpace reports whether a space separates the tokens at i-1 and i.
+func (p *cypherPrinter) needSpace(i int) bool {
+	prev, cur := p.typeAt(i-1), p.typeAt(i)
+
+	switch {
+	case p.isArrow(i - 1), p.isArrow(i):
+		return false
+	case p.isUnary(i - 1):
+		return false
+	case prev == tokLParen, prev == tokLBracket, prev == tokLBrace:
+		return false
+	case cur == tokRParen, cur == tokRBracket, cur == tokRBrace:
+		return false
+	case cur == tokComma, cur == tokSemicolon:
+		return false
+	case prev == tokDot, cur == tokDot, prev == tokRange, cur == tokRange:
+		return false
+	case prev == tokDollar:
+		return false
+	case cur == tokColon:
+		return false
+	case prev == tokColon:
+		// Map entries read "key: value"; labels and types read "n:Label".
+		return p.top().open == tokLBrace && !p.top().block
+	case prev == tokPipe, cur == tokPipe, prev == tokStar, cur == tokStar:
+		return !p.top().rel
+	case cur == tokLParen:
+		// Function and procedure calls: count(x), db.labels().
+		return prev != tokIdent || p.isKeyword(i-1)
+	case cur == tokLBracket:
+		// Indexing and slicing: list[0], n['key'].
+		return !p.endsOperand(i-1) || (prev == tokIdent && p.isKeyword(i-1))
+	}
+
+	return true
+}
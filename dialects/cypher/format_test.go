@@ -0,0 +1,212 @@
+//nolint:testpackage
+package cypher
+
+import (
+	"testing"
+)
+
+func TestDialect_Format(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "uppercases keywords and breaks clauses",
+			query: "match (u:User {id:$id}) where u.age>18 and u.name starts with 'A' return u.name as name order by name desc limit 10",
+			want: "MATCH (u:User {id: $id})\n" +
+				"WHERE u.age > 18 AND u.name STARTS WITH 'A'\n" +
+				"RETURN u.name AS name\n" +
+				"ORDER BY name DESC\n" +
+				"LIMIT 10",
+		},
+		{
+			name:  "relationship patterns keep their arrows",
+			query: "MATCH (a)-[r:KNOWS]->(b)<-[:LIKES|LOVES]-(c) RETURN a",
+			want:  "MATCH (a)-[r:KNOWS]->(b)<-[:LIKES|LOVES]-(c)\nRETURN a",
+		},
+		{
+			name:  "merge actions are continuation lines",
+			query: "merge (n:User {id: $id}) on create set n.created = timestamp() on match set n.seen=n.seen+1",
+			want: "MERGE (n:User {id: $id})\n" +
+				"  ON CREATE SET n.created = timestamp()\n" +
+				"  ON MATCH SET n.seen = n.seen + 1",
+		},
+		{
+			name:  "subquery bodies are indented",
+			query: "MATCH (u) CALL { WITH u MATCH (u)-->(p:Post) RETURN count(p) AS posts } RETURN u, posts",
+			want: "MATCH (u)\n" +
+				"CALL {\n" +
+				"  WITH u\n" +
+				"  MATCH (u)-->(p:Post)\n" +
+				"  RETURN count(p) AS posts\n" +
+				"}\n" +
+				"RETURN u, posts",
+		},
+		{
+			name:  "expressions stay on one line",
+			query: "MATCH (o:Order) RETURN [x IN o.tags WHERE x <> '' | toUpper(x)] AS tags, o.items[0], count(*)",
+			want:  "MATCH (o:Order)\nRETURN [x IN o.tags WHERE x <> '' | toUpper(x)] AS tags, o.items[0], count(*)",
+		},
+		{
+			name:  "labels, properties and aliases keep their case",
+			query: "MATCH (o:Order) RETURN o.limit AS set",
+			want:  "MATCH (o:Order)\nRETURN o.limit AS set",
+		},
+		{
+			name:  "variables spelled like keywords keep their case",
+			query: "match (from:Airport)-[rows:FLIGHT]->(end) return from, rows, end.code as count",
+			want:  "MATCH (from:Airport)-[rows:FLIGHT]->(end)\nRETURN from, rows, end.code AS count",
+		},
+		{
+			name:  "aliases spelled like keywords keep their case",
+			query: "match (a) with a.code as from, count(*) as rows unwind [1] as end return from, rows, end",
+			want:  "MATCH (a)\nWITH a.code AS from, count(*) AS rows\nUNWIND [1] AS end\nRETURN from, rows, end",
+		},
+		{
+			name:  "yielded names spelled like keywords keep their case",
+			query: "call db.labels() yield label as from return from",
+			want:  "CALL db.labels() YIELD label AS from\nRETURN from",
+		},
+		{
+			name:  "unparseable query is unchanged",
+			query: "MATCH (u RETURN u",
+			want:  "MATCH (u RETURN u",
+		},
+	}
+
+	d := NewDialect()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := d.Format(tt.query)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Format() =\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			again, _ := d.Format(got)
+			if again != got {
+				t.Errorf("Format() is not idempotent:\n%s\nthen:\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestDialect_FormatWithOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		opts  FormatOptions
+		query string
+		want  string
+	}{
+		{
+			name:  "lowercase keywords",
+			opts:  FormatOptions{KeywordCase: KeywordCaseLower},
+			query: "MATCH (u:User) WHERE u.age > 18 RETURN u.name AS name",
+			want:  "match (u:User)\nwhere u.age > 18\nreturn u.name as name",
+		},
+		{
+			name:  "lowercase leaves keyword-like variables alone",
+			opts:  FormatOptions{KeywordCase: KeywordCaseLower},
+			query: "MATCH (FROM:Airport)-[ROWS]->(End) RETURN FROM, ROWS, End AS COUNT",
+			want:  "match (FROM:Airport)-[ROWS]->(End)\nreturn FROM, ROWS, End as COUNT",
+		},
+		{
+			name:  "uppercase leaves keyword-like variables alone",
+			opts:  FormatOptions{KeywordCase: KeywordCaseUpper},
+			query: "match (from:Airport)-[rows]->(end) return from, rows, end as count",
+			want:  "MATCH (from:Airport)-[rows]->(end)\nRETURN from, rows, end AS count",
+		},
+		{
+			name:  "mixed keeps keywords as written",
+			opts:  FormatOptions{KeywordCase: KeywordCaseMixed},
+			query: "match (u:User) WHERE u.age > 18 Return u",
+			want:  "match (u:User)\nWHERE u.age > 18\nReturn u",
+		},
+		{
+			name:  "indent width applies to continuations and subquery bodies",
+			opts:  FormatOptions{IndentWidth: 4},
+			query: "MERGE (n:User) ON CREATE SET n.new = true WITH n CALL { WITH n RETURN n.id AS id } RETURN id",
+			want: "MERGE (n:User)\n" +
+				"    ON CREATE SET n.new = true\n" +
+				"WITH n\n" +
+				"CALL {\n" +
+				"    WITH n\n" +
+				"    RETURN n.id AS id\n" +
+				"}\n" +
+				"RETURN id",
+		},
+		{
+			name:  "long clauses wrap at commas",
+			opts:  FormatOptions{MaxLineLength: 30},
+			query: "MATCH (u:User) RETURN u.name AS name, u.email AS email, u.age AS age, count(*)",
+			want: "MATCH (u:User)\n" +
+				"RETURN u.name AS name,\n" +
+				"  u.email AS email,\n" +
+				"  u.age AS age, count(*)",
+		},
+		{
+			name:  "commas inside expressions never wrap",
+			opts:  FormatOptions{MaxLineLength: 20},
+			query: "RETURN coalesce(a, b, c, d, e, f) AS x",
+			want:  "RETURN coalesce(a, b, c, d, e, f) AS x",
+		},
+		{
+			name:  "negative max line length never wraps",
+			opts:  FormatOptions{MaxLineLength: -1},
+			query: "MATCH (u:User) RETURN u.name AS name, u.email AS email, u.age AS age, u.id AS id, count(*) AS total",
+			want:  "MATCH (u:User)\nRETURN u.name AS name, u.email AS email, u.age AS age, u.id AS id, count(*) AS total",
+		},
+		{
+			name:  "aligned clauses",
+			opts:  FormatOptions{AlignClauses: true},
+			query: "MATCH (u:User) WHERE u.age > 18 OPTIONAL MATCH (u)-->(p) RETURN u, p LIMIT 5",
+			want: "   MATCH (u:User)\n" +
+				"   WHERE u.age > 18\n" +
+				"OPTIONAL MATCH (u)-->(p)\n" +
+				"  RETURN u, p\n" +
+				"   LIMIT 5",
+		},
+		{
+			name:  "aligned continuations start in the body column",
+			opts:  FormatOptions{AlignClauses: true, MaxLineLength: 30},
+			query: "MERGE (n:User) ON CREATE SET n.new = true RETURN n.name AS name, n.email AS email",
+			want: " MERGE (n:User)\n" +
+				"       ON CREATE SET n.new = true\n" +
+				"RETURN n.name AS name,\n" +
+				"       n.email AS email",
+		},
+	}
+
+	d := NewDialect()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := d.FormatWithOptions(tt.query, tt.opts)
+			if err != nil {
+				t.Fatalf("FormatWithOptions() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("FormatWithOptions() =\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			again, _ := d.FormatWithOptions(got, tt.opts)
+			if again != got {
+				t.Errorf("FormatWithOptions() is not idempotent:\n%s\nthen:\n%s", got, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package cypher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FunctionSignature describes one overload of a built-in Cypher function.
+type FunctionSignature struct {
+	// Name is the function's canonical spelling, e.g. "toLower".
+	Name string
+	// Params are the arguments in call order.
+	Params []ParamInfo
+	// ReturnType is the Cypher type returned, e.g. "STRING".
+	ReturnType string
+	// Description is a one-line summary of this overload.
+	Description string
+}
+
+// ParamInfo describes a function parameter.
+type ParamInfo struct {
+	Name string
+	Type string
+	// Optional parameters may be omitted from the end of the call.
+	Optional bool
+	// Description says what the argument is for.
+	Description string
+}
+
+// String formats the signature as "name(param: TYPE, opt?: TYPE) → TYPE".
+func (s FunctionSignature) String() string {
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		params[i] = p.String()
+	}
+
+	return fmt.Sprintf("%s(%s) → %s", s.Name, strings.Join(params, ", "), s.ReturnType)
+}
+
+// String formats the parameter as it appears in its signature, e.g.
+// "length?: INTEGER".
+func (p ParamInfo) String() string {
+	name := p.Name
+	if p.Optional {
+		name += "?"
+	}
+
+	return name + ": " + p.Type
+}
+
+// variadic reports whether the parameter takes any number of arguments.
+func (p ParamInfo) variadic() bool {
+	return strings.HasSuffix(p.Type, "...")
+}
+
+// formatSignatureHover renders every overload of a function as Markdown,
+// one signature per line followed by its description.
+func formatSignatureHover(sigs []FunctionSignature) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "**%s** (function)", sigs[0].Name)
+
+	for _, sig := range sigs {
+		fmt.Fprintf(&sb, "\n\n`%s`", sig)
+		if sig.Description != "" {
+			sb.WriteString("\n\n" + sig.Description)
+		}
+	}
+
+	return sb.String()
+}
+
+// arg returns a required parameter.
+func arg(name, typ, description string) ParamInfo {
+	return ParamInfo{Name: name, Type: typ, Description: description}
+}
+
+// optionalArg returns a parameter that may be omitted.
+func optionalArg(name, typ, description string) ParamInfo {
+	return ParamInfo{Name: name, Type: typ, Optional: true, Description: description}
+}
+
+// overload builds a FunctionSignature.
+func overload(name, returnType, description string, params ...ParamInfo) FunctionSignature {
+	return FunctionSignature{Name: name, Params: params, ReturnType: returnType, Description: description}
+}
+
+// cypherFunctionSignatures maps function names (lowercase) to their overloads,
+// covering the most commonly used built-in functions and APOC procedures.
+var cypherFunctionSignatures = map[string][]FunctionSignature{
+	// Aggregation functions
+	"count": {
+		overload("count", "INTEGER", "Returns the number of non-null values.",
+			arg("expression", "ANY", "The values to count; nulls are skipped.")),
+		overload("count", "INTEGER", "`count(*)` returns the number of rows.",
+			arg("*", "ROWS", "Counts every row, including those with null values.")),
+	},
+	"sum": {
+		overload("sum", "INTEGER | FLOAT | DURATION", "Returns the sum of a set of numeric or duration values.",
+			arg("input", "INTEGER | FLOAT | DURATION", "The values to add up; nulls are skipped.")),
+	},
+	"avg": {
+		overload("avg", "FLOAT | DURATION", "Returns the average of a set of numeric or duration values.",
+			arg("input", "INTEGER | FLOAT | DURATION", "The values to average; nulls are skipped.")),
+	},
+	"min": {
+		overload("min", "ANY", "Returns the smallest value in a set of values.",
+			arg("input", "ANY", "The values to compare; nulls are skipped.")),
+	},
+	"max": {
+		overload("max", "ANY", "Returns the largest value in a set of values.",
+			arg("input", "ANY", "The values to compare; nulls are skipped.")),
+	},
+	"collect": {
+		overload("collect", "LIST<ANY>", "Returns a list containing the non-null values of an expression.",
+			arg("input", "ANY", "The values to gather into the list.")),
+	},
+	"stdev": {
+		overload("stDev", "FLOAT", "Returns the standard deviation of a sample of values.",
+			arg("input", "INTEGER | FLOAT", "The sample values; nulls are skipped.")),
+	},
+	"percentilecont": {
+		overload("percentileCont", "FLOAT", "Returns the percentile of a value over a group, interpolating between values.",
+			arg("input", "FLOAT", "The values to take the percentile of."),
+			arg("percentile", "FLOAT", "The percentile, between 0.0 and 1.0.")),
+	},
+
+	// Type conversion functions
+	"tostring": {
+		overload("toString", "STRING", "Converts a value to a string.",
+			arg("input", "ANY", "The value to convert.")),
+	},
+	"tointeger": {
+		overload("toInteger", "INTEGER", "Converts a value to an integer, or null if it cannot be converted.",
+			arg("input", "ANY", "A string, float, or boolean to convert.")),
+	},
+	"tofloat": {
+		overload("toFloat", "FLOAT", "Converts a value to a float, or null if it cannot be converted.",
+			arg("input", "ANY", "A string or integer to convert.")),
+	},
+	"toboolean": {
+		overload("toBoolean", "BOOLEAN", "Converts a value to a boolean, or null if it cannot be converted.",
+			arg("input", "ANY", "A string such as \"true\", or an integer, to convert.")),
+	},
+
+	// Scalar functions
+	"size": {
+		overload("size", "INTEGER", "Returns the number of elements in a list.",
+			arg("input", "LIST<ANY>", "The list to measure.")),
+		overload("size", "INTEGER", "Returns the number of characters in a string.",
+			arg("input", "STRING", "The string to measure.")),
+	},
+	"length": {
+		overload("length", "INTEGER", "Returns the number of relationships in a path.",
+			arg("input", "PATH", "The path to measure.")),
+	},
+	"type": {
+		overload("type", "STRING", "Returns the type of a relationship.",
+			arg("relationship", "RELATIONSHIP", "The relationship whose type to return.")),
+	},
+	"id": {
+		overload("id", "INTEGER", "Returns the internal id of a node or relationship. Deprecated in favor of elementId.",
+			arg("input", "NODE | RELATIONSHIP", "The node or relationship whose id to return.")),
+	},
+	"elementid": {
+		overload("elementId", "STRING", "Returns the element id of a node or relationship.",
+			arg("input", "NODE | RELATIONSHIP", "The node or relationship whose element id to return.")),
+	},
+	"labels": {
+		overload("labels", "LIST<STRING>", "Returns the labels of a node.",
+			arg("node", "NODE", "The node whose labels to return.")),
+	},
+	"keys": {
+		overload("keys", "LIST<STRING>", "Returns the property keys of a node, relationship, or map.",
+			arg("input", "NODE | RELATIONSHIP | MAP", "The entity or map whose keys to return.")),
+	},
+	"properties": {
+		overload("properties", "MAP", "Returns the properties of a node, relationship, or map.",
+			arg("input", "NODE | RELATIONSHIP | MAP", "The entity or map whose properties to return.")),
+	},
+	"coalesce": {
+		overload("coalesce", "ANY", "Returns the first non-null value.",
+			arg("input", "ANY", "The first candidate value."),
+			optionalArg("inputs", "ANY...", "Further candidates, tried in order.")),
+	},
+	"head": {
+		overload("head", "ANY", "Returns the first element of a list.",
+			arg("list", "LIST<ANY>", "The list to take the first element of; null if it is empty.")),
+	},
+	"last": {
+		overload("last", "ANY", "Returns the last element of a list.",
+			arg("list", "LIST<ANY>", "The list to take the last element of; null if it is empty.")),
+	},
+	"tail": {
+		overload("tail", "LIST<ANY>", "Returns a list of all but the first element.",
+			arg("list", "LIST<ANY>", "The list to drop the first element of.")),
+	},
+	"startnode": {
+		overload("startNode", "NODE", "Returns the start node of a relationship.",
+			arg("relationship", "RELATIONSHIP", "The relationship whose start node to return.")),
+	},
+	"endnode": {
+		overload("endNode", "NODE", "Returns the end node of a relationship.",
+			arg("relationship", "RELATIONSHIP", "The relationship whose end node to return.")),
+	},
+	"timestamp":  {overload("timestamp", "INTEGER", "Returns the milliseconds since midnight, January 1, 1970 UTC.")},
+	"randomuuid": {overload("randomUUID", "STRING", "Returns a random UUID.")},
+	"range": {
+		overload("range", "LIST<INTEGER>", "Returns the integers from start to end inclusive, stepping by step.",
+			arg("start", "INTEGER", "The first number in the range."),
+			arg("end", "INTEGER", "The last number in the range, included if the step reaches it."),
+			optionalArg("step", "INTEGER", "The difference between consecutive numbers; defaults to 1.")),
+	},
+
+	// Predicate functions
+	"isempty": {
+		overload("isEmpty", "BOOLEAN", "Returns whether a list contains no elements.",
+			arg("input", "LIST<ANY>", "The list to check.")),
+		overload("isEmpty", "BOOLEAN", "Returns whether a map contains no keys.",
+			arg("input", "MAP", "The map to check.")),
+		overload("isEmpty", "BOOLEAN", "Returns whether a string contains no characters.",
+			arg("input", "STRING", "The string to check.")),
+	},
+
+	// Path functions
+	"nodes": {
+		overload("nodes", "LIST<NODE>", "Returns the nodes in a path.",
+			arg("path", "PATH", "The path whose nodes to return.")),
+	},
+	"relationships": {
+		overload("relationships", "LIST<RELATIONSHIP>", "Returns the relationships in a path.",
+			arg("path", "PATH", "The path whose relationships to return.")),
+	},
+	"shortestpath": {
+		overload("shortestPath", "PATH", "Returns a shortest path matching the pattern.",
+			arg("pattern", "PATTERN", "A relationship pattern between two bound nodes.")),
+	},
+
+	// Math functions
+	"abs": {
+		overload("abs", "INTEGER | FLOAT", "Returns the absolute value of a number.",
+			arg("input", "INTEGER | FLOAT", "The number to take the absolute value of.")),
+	},
+	"ceil": {
+		overload("ceil", "FLOAT", "Returns the smallest integer greater than or equal to a number.",
+			arg("input", "FLOAT", "The number to round up.")),
+	},
+	"floor": {
+		overload("floor", "FLOAT", "Returns the largest integer less than or equal to a number.",
+			arg("input", "FLOAT", "The number to round down.")),
+	},
+	"round": {
+		overload("round", "FLOAT", "Returns a number rounded to the nearest integer.",
+			arg("value", "FLOAT", "The number to round.")),
+		overload("round", "FLOAT", "Returns a number rounded to precision decimal places using an optional rounding mode.",
+			arg("value", "FLOAT", "The number to round."),
+			arg("precision", "INTEGER", "The number of decimal places to keep."),
+			optionalArg("mode", "STRING", "The rounding mode, such as \"HALF_UP\" or \"CEILING\"; defaults to \"HALF_UP\".")),
+	},
+	"sign": {
+		overload("sign", "INTEGER", "Returns -1, 0, or 1 for the sign of a number.",
+			arg("input", "INTEGER | FLOAT", "The number whose sign to return.")),
+	},
+	"rand": {overload("rand", "FLOAT", "Returns a random number in the range [0, 1).")},
+	"sqrt": {
+		overload("sqrt", "FLOAT", "Returns the square root of a number.",
+			arg("input", "FLOAT", "The number to take the square root of.")),
+	},
+	"log": {
+		overload("log", "FLOAT", "Returns the natural logarithm of a number.",
+			arg("input", "FLOAT", "The number to take the logarithm of.")),
+	},
+	"exp": {
+		overload("exp", "FLOAT", "Returns e raised to the power of a number.",
+			arg("input", "FLOAT", "The power to raise e to.")),
+	},
+
+	// String functions
+	"tolower": {
+		overload("toLower", "STRING", "Returns a string in lowercase.",
+			arg("input", "STRING", "The string to convert.")),
+	},
+	"toupper": {
+		overload("toUpper", "STRING", "Returns a string in uppercase.",
+			arg("input", "STRING", "The string to convert.")),
+	},
+	"trim": {
+		overload("trim", "STRING", "Returns a string with leading and trailing whitespace removed.",
+			arg("input", "STRING", "The string to trim.")),
+	},
+	"ltrim": {
+		overload("ltrim", "STRING", "Returns a string with leading whitespace removed.",
+			arg("input", "STRING", "The string to trim.")),
+	},
+	"rtrim": {
+		overload("rtrim", "STRING", "Returns a string with trailing whitespace removed.",
+			arg("input", "STRING", "The string to trim.")),
+	},
+	"replace": {
+		overload("replace", "STRING", "Returns a string with every occurrence of search replaced.",
+			arg("original", "STRING", "The string to search in."),
+			arg("search", "STRING", "The substring to look for."),
+			arg("replace", "STRING", "The string to put in place of each match.")),
+	},
+	"substring": {
+		overload("substring", "STRING", "Returns length characters of a string starting at the zero-based start, or the rest of the string.",
+			arg("original", "STRING", "The string to take a substring of."),
+			arg("start", "INTEGER", "The zero-based index of the first character."),
+			optionalArg("length", "INTEGER", "The number of characters to return; defaults to the rest of the string.")),
+	},
+	"left": {
+		overload("left", "STRING", "Returns the leftmost length characters of a string.",
+			arg("original", "STRING", "The string to take characters from."),
+			arg("length", "INTEGER", "The number of characters to return.")),
+	},
+	"right": {
+		overload("right", "STRING", "Returns the rightmost length characters of a string.",
+			arg("original", "STRING", "The string to take characters from."),
+			arg("length", "INTEGER", "The number of characters to return.")),
+	},
+	"split": {
+		overload("split", "LIST<STRING>", "Splits a string on a delimiter.",
+			arg("original", "STRING", "The string to split."),
+			arg("delimiter", "STRING", "The string to split on.")),
+		overload("split", "LIST<STRING>", "Splits a string on any of several delimiters.",
+			arg("original", "STRING", "The string to split."),
+			arg("delimiters", "LIST<STRING>", "The strings to split on.")),
+	},
+	"reverse": {
+		overload("reverse", "STRING", "Returns a string with its characters reversed.",
+			arg("input", "STRING", "The string to reverse.")),
+		overload("reverse", "LIST<ANY>", "Returns a list with its elements reversed.",
+			arg("input", "LIST<ANY>", "The list to reverse.")),
+	},
+
+	// Temporal functions
+	"date": {
+		overload("date", "DATE", "Returns the current date."),
+		overload("date", "DATE", "Parses a date from an ISO 8601 string.",
+			arg("input", "STRING", "A date such as \"2015-07-21\".")),
+		overload("date", "DATE", "Creates a date from components such as {year, month, day}.",
+			arg("input", "MAP", "The date components, or {timezone} for today in that zone.")),
+	},
+	"datetime": {
+		overload("datetime", "ZONED DATETIME", "Returns the current date and time."),
+		overload("datetime", "ZONED DATETIME", "Parses a date and time from an ISO 8601 string.",
+			arg("input", "STRING", "A date and time such as \"2015-07-21T21:40:32.142+0100\".")),
+		overload("datetime", "ZONED DATETIME", "Creates a date and time from components such as {year, month, day, hour}.",
+			arg("input", "MAP", "The date, time, and timezone components, or {epochMillis}.")),
+	},
+	"duration": {
+		overload("duration", "DURATION", "Parses a duration from an ISO 8601 string such as \"P14DT16H12M\".",
+			arg("input", "STRING", "The duration in ISO 8601 format.")),
+		overload("duration", "DURATION", "Creates a duration from components such as {days, hours}.",
+			arg("input", "MAP", "The duration components, from years down to nanoseconds.")),
+	},
+
+	// Spatial functions
+	"point": {
+		overload("point", "POINT", "Creates a point from {x, y[, z]} or {longitude, latitude[, height]} and an optional crs.",
+			arg("input", "MAP", "The coordinates of the point, and optionally its crs or srid.")),
+	},
+
+	// APOC functions
+	"apoc.text.join": {
+		overload("apoc.text.join", "STRING", "Joins a list of strings with a delimiter.",
+			arg("list", "LIST<STRING>", "The strings to join."),
+			arg("delimiter", "STRING", "The string to put between each pair of elements.")),
+	},
+	"apoc.text.split": {
+		overload("apoc.text.split", "LIST<STRING>", "Splits a string on a regular expression.",
+			arg("text", "STRING", "The string to split."),
+			arg("regex", "STRING", "The regular expression to split on."),
+			optionalArg("limit", "INTEGER", "The maximum number of pieces; defaults to no limit.")),
+	},
+	"apoc.coll.contains": {
+		overload("apoc.coll.contains", "BOOLEAN", "Returns whether a list contains a value.",
+			arg("coll", "LIST<ANY>", "The list to search."),
+			arg("value", "ANY", "The value to look for.")),
+	},
+	"apoc.coll.sum": {
+		overload("apoc.coll.sum", "FLOAT", "Returns the sum of a list of numbers.",
+			arg("coll", "LIST<INTEGER | FLOAT>", "The numbers to add up.")),
+	},
+	"apoc.map.merge": {
+		overload("apoc.map.merge", "MAP", "Merges two maps, with keys of the second taking precedence.",
+			arg("map1", "MAP", "The map to merge into."),
+			arg("map2", "MAP", "The map whose entries override those of map1.")),
+	},
+	"apoc.date.format": {
+		overload("apoc.date.format", "STRING", "Formats an epoch time as a string.",
+			arg("time", "INTEGER", "The time since the epoch."),
+			optionalArg("unit", "STRING", "The unit of time, such as \"ms\" or \"s\"; defaults to \"ms\"."),
+			optionalArg("format", "STRING", "A Java date format pattern; defaults to \"yyyy-MM-dd HH:mm:ss\"."),
+			optionalArg("timezone", "STRING", "The timezone to format in; defaults to UTC.")),
+	},
+}
@@ -70,6 +70,12 @@ var cypherFunctionTypes = map[string]*analysis.Type{
 	"timestamp":        analysis.TypeInt,
 	"range":            analysis.SliceOf(analysis.TypeInt),
 
+	// Path functions
+	"shortestpath":     analysis.TypePath,
+	"allshortestpaths": analysis.SliceOf(analysis.TypePath),
+	"nodes":            analysis.SliceOf(analysis.PointerTo(analysis.TypeNode)),
+	"relationships":    analysis.SliceOf(analysis.PointerTo(analysis.TypeRelationship)),
+
 	// Graph element functions
 	"startnode": nil,
 	"endnode":   nil,
@@ -124,40 +130,48 @@ var cypherFunctionTypes = map[string]*analysis.Type{
 	"concat":    analysis.TypeString,
 
 	// Temporal functions
-	"date":          analysis.NamedType("time", "Time"),
-	"datetime":      analysis.NamedType("time", "Time"),
-	"localdatetime": analysis.NamedType("time", "Time"),
-	"localtime":     analysis.NamedType("time", "Time"),
-	"time":          analysis.NamedType("time", "Time"),
-	"duration":      analysis.NamedType("time", "Duration"),
+	"date":          analysis.TypeDate,
+	"datetime":      analysis.TypeDateTime,
+	"localdatetime": analysis.TypeLocalDateTime,
+	"localtime":     analysis.TypeLocalTime,
+	"time":          analysis.TypeTime,
+	"duration":      analysis.TypeDuration,
 
 	// Temporal - current values
-	"date.realtime":          analysis.NamedType("time", "Time"),
-	"date.statement":         analysis.NamedType("time", "Time"),
-	"date.transaction":       analysis.NamedType("time", "Time"),
-	"datetime.realtime":      analysis.NamedType("time", "Time"),
-	"datetime.statement":     analysis.NamedType("time", "Time"),
-	"datetime.transaction":   analysis.NamedType("time", "Time"),
-	"localdatetime.realtime": analysis.NamedType("time", "Time"),
-	"localtime.realtime":     analysis.NamedType("time", "Time"),
-	"time.realtime":          analysis.NamedType("time", "Time"),
+	"date.realtime":             analysis.TypeDate,
+	"date.statement":            analysis.TypeDate,
+	"date.transaction":          analysis.TypeDate,
+	"datetime.realtime":         analysis.TypeDateTime,
+	"datetime.statement":        analysis.TypeDateTime,
+	"datetime.transaction":      analysis.TypeDateTime,
+	"datetime.fromepoch":        analysis.TypeDateTime,
+	"datetime.fromepochmillis":  analysis.TypeDateTime,
+	"localdatetime.realtime":    analysis.TypeLocalDateTime,
+	"localdatetime.statement":   analysis.TypeLocalDateTime,
+	"localdatetime.transaction": analysis.TypeLocalDateTime,
+	"localtime.realtime":        analysis.TypeLocalTime,
+	"localtime.statement":       analysis.TypeLocalTime,
+	"localtime.transaction":     analysis.TypeLocalTime,
+	"time.realtime":             analysis.TypeTime,
+	"time.statement":            analysis.TypeTime,
+	"time.transaction":          analysis.TypeTime,
 
 	// Temporal - truncation
-	"date.truncate":          analysis.NamedType("time", "Time"),
-	"datetime.truncate":      analysis.NamedType("time", "Time"),
-	"localdatetime.truncate": analysis.NamedType("time", "Time"),
-	"localtime.truncate":     analysis.NamedType("time", "Time"),
-	"time.truncate":          analysis.NamedType("time", "Time"),
+	"date.truncate":          analysis.TypeDate,
+	"datetime.truncate":      analysis.TypeDateTime,
+	"localdatetime.truncate": analysis.TypeLocalDateTime,
+	"localtime.truncate":     analysis.TypeLocalTime,
+	"time.truncate":          analysis.TypeTime,
 
 	// Temporal - duration
-	"duration.between":   analysis.NamedType("time", "Duration"),
-	"duration.inmonths":  analysis.NamedType("time", "Duration"),
-	"duration.indays":    analysis.NamedType("time", "Duration"),
-	"duration.inseconds": analysis.NamedType("time", "Duration"),
+	"duration.between":   analysis.TypeDuration,
+	"duration.inmonths":  analysis.TypeDuration,
+	"duration.indays":    analysis.TypeDuration,
+	"duration.inseconds": analysis.TypeDuration,
 
-	// Spatial functions
-	"point":            analysis.NamedType("neo4j", "Point"),
+	// Spatial functions (point is inferred from its arguments)
 	"point.withinbbox": analysis.TypeBool,
+	"point.distance":   analysis.TypeFloat64,
 	"distance":         analysis.TypeFloat64,
 
 	// ============================================================================
@@ -198,7 +212,7 @@ var cypherFunctionTypes = map[string]*analysis.Type{
 	// APOC - Date/time functions
 	"apoc.date.format":                analysis.TypeString,
 	"apoc.date.parse":                 analysis.TypeInt,
-	"apoc.date.parseaszondeddatetime": analysis.NamedType("time", "Time"),
+	"apoc.date.parseaszondeddatetime": analysis.TypeDateTime,
 	"apoc.date.currenttimestamp":      analysis.TypeInt,
 	"apoc.date.toiso8601":             analysis.TypeString,
 	"apoc.date.fromiso8601":           analysis.TypeInt,
@@ -207,7 +221,7 @@ var cypherFunctionTypes = map[string]*analysis.Type{
 	"apoc.date.add":                   analysis.TypeInt,
 	"apoc.date.convert":               analysis.TypeInt,
 	"apoc.temporal.format":            analysis.TypeString,
-	"apoc.temporal.tozonedtemporal":   analysis.NamedType("time", "Time"),
+	"apoc.temporal.tozonedtemporal":   analysis.TypeDateTime,
 
 	// APOC - Collection functions
 	"apoc.coll.sum":                    analysis.TypeFloat64,
@@ -332,11 +346,16 @@ var cypherFunctionTypes = map[string]*analysis.Type{
 	"apoc.any.property":             nil,
 	"apoc.any.properties":           analysis.MapOf(analysis.TypeString, nil),
 
-	// APOC - Path functions
-	"apoc.path.elements": analysis.SliceOf(nil),
-	"apoc.path.combine":  nil,
-	"apoc.path.create":   nil,
-	"apoc.path.slice":    nil,
+	// APOC - Path functions and expanders
+	"apoc.path.elements":      analysis.SliceOf(nil),
+	"apoc.path.combine":       analysis.TypePath,
+	"apoc.path.create":        analysis.TypePath,
+	"apoc.path.slice":         analysis.TypePath,
+	"apoc.path.subgraphnodes": analysis.SliceOf(analysis.PointerTo(analysis.TypeNode)),
+	"apoc.path.subgraphall":   analysis.MapOf(analysis.TypeString, analysis.SliceOf(nil)),
+	"apoc.path.spanningtree":  analysis.SliceOf(analysis.TypePath),
+	"apoc.path.expand":        analysis.SliceOf(analysis.TypePath),
+	"apoc.path.expandconfig":  analysis.SliceOf(analysis.TypePath),
 
 	// APOC - Scoring functions
 	"apoc.scoring.existence": analysis.TypeFloat64,
@@ -363,16 +382,15 @@ var cypherFunctionTypes = map[string]*analysis.Type{
 
 // functionsWithArgInference lists function names that need argument-based type inference.
 var functionsWithArgInference = map[string]bool{
-	"collect":       true,
-	"head":          true,
-	"last":          true,
-	"tail":          true,
-	"coalesce":      true,
-	"min":           true,
-	"max":           true,
-	"properties":    true,
-	"nodes":         true,
-	"relationships": true,
+	"collect":    true,
+	"head":       true,
+	"last":       true,
+	"tail":       true,
+	"coalesce":   true,
+	"min":        true,
+	"max":        true,
+	"properties": true,
+	"point":      true,
 }
 
 // inferFunctionInvocation determines the return type of a function call.
@@ -384,6 +402,10 @@ func inferFunctionInvocation(fc *cyphergrammar.FunctionCall, qctx *queryContext)
 	funcName := fc.Name.String()
 	lowerName := strings.ToLower(funcName)
 
+	if qctx != nil && qctx.inference != nil && !qctx.inference.apoc && strings.HasPrefix(lowerName, "apoc.") {
+		return nil
+	}
+
 	// Check fixed return type first
 	if typ, ok := cypherFunctionTypes[lowerName]; ok {
 		return typ
@@ -436,11 +458,31 @@ func inferFunctionWithArgs(funcName string, args []*cyphergrammar.Expression, qc
 		// properties(node) → map[string]any
 		return analysis.MapOf(analysis.TypeString, nil)
 
-	case "nodes", "relationships":
-		// nodes(path) / relationships(path) → list
-		return analysis.SliceOf(nil)
+	case "point":
+		// point({x, y, z}) / point({longitude, latitude, height}) → 3D point
+		if len(args) > 0 && pointHas3DKey(args[0]) {
+			return analysis.TypePoint3D
+		}
+		return analysis.TypePoint
 
 	default:
 		return nil
 	}
 }
+
+// pointHas3DKey reports whether a point() argument is a map literal with a z or height key.
+func pointHas3DKey(arg *cyphergrammar.Expression) bool {
+	atom := extractAtomFromExpression(arg)
+	if atom == nil || atom.Literal == nil || atom.Literal.Map == nil {
+		return false
+	}
+
+	for _, pair := range atom.Literal.Map.Pairs {
+		switch strings.ToLower(pair.Key) {
+		case "z", "height":
+			return true
+		}
+	}
+
+	return false
+}
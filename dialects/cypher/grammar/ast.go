@@ -17,11 +17,11 @@ type Script struct {
 	Semi  string `@Semicolon?`
 }
 
-// Query represents a top-level query.
+// Query represents a top-level query. A standalone CALL is a RegularQuery
+// whose only clause is a ProcedureCall.
 type Query struct {
-	Pos            lexer.Position
-	StandaloneCall *StandaloneCall `  @@`
-	RegularQuery   *RegularQuery   `| @@`
+	Pos          lexer.Position
+	RegularQuery *RegularQuery `@@`
 }
 
 // RegularQuery is a query with optional UNION clauses.
@@ -48,11 +48,12 @@ type SingleQuery struct {
 
 // Clause is any clause in a query (reading, updating, WITH, or RETURN).
 type Clause struct {
-	Pos      lexer.Position
-	Reading  *ReadingClause  `  @@`
-	Updating *UpdatingClause `| @@`
-	With     *WithClause     `| @@`
-	Return   *ReturnClause   `| @@`
+	Pos          lexer.Position
+	CallSubquery *CallSubquery   `  @@`
+	Reading      *ReadingClause  `| @@`
+	Updating     *UpdatingClause `| @@`
+	With         *WithClause     `| @@`
+	Return       *ReturnClause   `| @@`
 }
 
 // ----------------------------------------------------------------------------
@@ -62,19 +63,20 @@ type Clause struct {
 // ReadingClause represents MATCH, UNWIND, or CALL.
 type ReadingClause struct {
 	Pos    lexer.Position
-	Match  *MatchClause  `  @@`
-	Unwind *UnwindClause `| @@`
-	Call   *CallClause   `| @@`
+	Match  *MatchClause   `  @@`
+	Unwind *UnwindClause  `| @@`
+	Call   *ProcedureCall `| @@`
 }
 
-// UpdatingClause represents CREATE, MERGE, DELETE, SET, or REMOVE.
+// UpdatingClause represents CREATE, MERGE, DELETE, SET, REMOVE, or FOREACH.
 type UpdatingClause struct {
-	Pos    lexer.Position
-	Create *CreateClause `  @@`
-	Merge  *MergeClause  `| @@`
-	Delete *DeleteClause `| @@`
-	Set    *SetClause    `| @@`
-	Remove *RemoveClause `| @@`
+	Pos     lexer.Position
+	Create  *CreateClause  `  @@`
+	Merge   *MergeClause   `| @@`
+	Delete  *DeleteClause  `| @@`
+	Set     *SetClause     `| @@`
+	Remove  *RemoveClause  `| @@`
+	ForEach *ForEachClause `| @@`
 }
 
 // MatchClause represents an OPTIONAL? MATCH pattern WHERE clause.
@@ -92,41 +94,36 @@ type UnwindClause struct {
 	Symbol string      `"AS" @Ident`
 }
 
-// CallClause represents CALL in a query (not standalone).
-type CallClause struct {
+// ProcedureCall is a procedure CALL inside a query, e.g.
+// CALL db.labels() YIELD label. Namespace is the dotted prefix of the
+// procedure name ("db", "apoc.meta"), empty for an unnamespaced procedure.
+// Yield holds the columns the call brings into scope; YIELD * is a lone "*".
+type ProcedureCall struct {
 	Pos       lexer.Position
-	Procedure *InvocationName `"CALL" @@`
-	Args      *ParenExprList  `@@?`
-	Yield     *YieldClause    `( "YIELD" @@ )?`
+	Namespace string        `"CALL" ( @( Ident ( Dot Ident (?= Dot ) )* ) Dot )?`
+	Name      string        `@Ident`
+	Args      []*Expression `( LParen ( @@ ( Comma @@ )* )? RParen )?`
+	Yield     []*YieldItem  `( "YIELD" @@ ( Comma @@ )*`
+	Where     *Where        `  @@? )?`
 }
 
-// StandaloneCall represents a standalone CALL.
-type StandaloneCall struct {
-	Pos       lexer.Position
-	Procedure *InvocationName  `"CALL" @@`
-	Args      *ParenExprList   `@@?`
-	Yield     *StandaloneYield `( "YIELD" @@ )?`
-}
-
-// StandaloneYield is YIELD for standalone CALL (can be * or items).
-type StandaloneYield struct {
-	Pos   lexer.Position
-	Star  bool         `  @Star`
-	Items *YieldClause `| @@`
-}
-
-// YieldClause represents YIELD items with optional WHERE.
-type YieldClause struct {
-	Pos   lexer.Position
-	Items []*YieldItem `@@ ( Comma @@ )*`
-	Where *Where       `@@?`
-}
-
-// YieldItem is a single yield item with optional alias.
+// YieldItem is a column yielded by a procedure call. Name is the variable it
+// binds; Field is the column it was renamed from (label in YIELD label AS l),
+// empty when the column keeps its name.
 type YieldItem struct {
 	Pos    lexer.Position
-	Source string `( @Ident "AS" )?`
-	Target string `@Ident`
+	EndPos lexer.Position
+	Field  string `( @Ident "AS" )?`
+	Name   string `@( Star | Ident )`
+}
+
+// CallSubquery represents CALL { ... } with an optional IN TRANSACTIONS suffix.
+// BatchSize is nil when no OF n ROWS clause is present.
+type CallSubquery struct {
+	Pos            lexer.Position
+	Body           *Script `"CALL" LBrace @@ RBrace`
+	InTransactions bool    `( @"IN" "TRANSACTIONS"`
+	BatchSize      *int    `  ( "OF" @Int ( "ROWS" | "ROW" ) )? )?`
 }
 
 // ReturnClause represents RETURN projection.
@@ -210,7 +207,30 @@ type MergeClause struct {
 	Actions []*MergeAction `@@*`
 }
 
+// OnCreateItems returns the SET items of every ON CREATE action, in order.
+func (m *MergeClause) OnCreateItems() []*SetItem {
+	return m.actionItems(func(a *MergeAction) bool { return a.OnCreate })
+}
+
+// OnMatchItems returns the SET items of every ON MATCH action, in order.
+func (m *MergeClause) OnMatchItems() []*SetItem {
+	return m.actionItems(func(a *MergeAction) bool { return a.OnMatch })
+}
+
+func (m *MergeClause) actionItems(match func(*MergeAction) bool) []*SetItem {
+	var items []*SetItem
+
+	for _, action := range m.Actions {
+		if action != nil && action.Set != nil && match(action) {
+			items = append(items, action.Set.Items...)
+		}
+	}
+
+	return items
+}
+
 // MergeAction is ON MATCH or ON CREATE SET clause.
+// Actions may appear in either order and more than once.
 type MergeAction struct {
 	Pos      lexer.Position
 	OnMatch  bool       `"ON" ( @"MATCH"`
@@ -265,6 +285,16 @@ type RemoveItem struct {
 	Property *PropertyExpr `| @@ )`
 }
 
+// ForEachClause represents FOREACH (variable IN list | updates).
+// Updates accepts any clause so that non-updating clauses can be diagnosed
+// rather than failing the parse.
+type ForEachClause struct {
+	Pos      lexer.Position
+	Variable string      `"FOREACH" LParen @Ident "IN"`
+	List     *Expression `@@ Pipe`
+	Updates  []*Clause   `@@+ RParen`
+}
+
 // ----------------------------------------------------------------------------
 // Patterns
 // ----------------------------------------------------------------------------
@@ -275,11 +305,22 @@ type Pattern struct {
 	Parts []*PatternPart `@@ ( Comma @@ )*`
 }
 
-// PatternPart is an optional variable assignment to a pattern element.
+// PatternPart is an optional variable assignment to a pattern element,
+// or to a shortestPath/allShortestPaths call.
 type PatternPart struct {
+	Pos          lexer.Position
+	EndPos       lexer.Position
+	Var          string          `( @Ident Eq )?`
+	PathFunction *PathFunction   `( @@`
+	Element      *PatternElement `| @@ )`
+}
+
+// PathFunction is shortestPath(pattern) or allShortestPaths(pattern).
+type PathFunction struct {
 	Pos     lexer.Position
-	Var     string          `( @Ident Eq )?`
-	Element *PatternElement `@@`
+	Tokens  []lexer.Token
+	Name    string       `@( "shortestPath" | "allShortestPaths" ) LParen`
+	Pattern *PatternPart `@@ RParen`
 }
 
 // PatternElement is a node pattern followed by relationship chains.
@@ -298,12 +339,14 @@ type PatternElemChain struct {
 	Node *NodePattern         `@@`
 }
 
-// NodePattern is (variable? labels? properties?).
+// NodePattern is (variable? labels? properties? (WHERE expr)?).
 type NodePattern struct {
 	Pos        lexer.Position
 	Variable   string      `LParen @Ident?`
 	Labels     *NodeLabels `@@?`
-	Properties *Properties `@@? RParen`
+	Properties *Properties `@@?`
+	// InlineWhere is the Neo4j 5 predicate in (n:User WHERE n.age > 18)
+	InlineWhere *Expression `( "WHERE" @@ )? RParen`
 }
 
 // NodeLabels is a sequence of :Label.
@@ -334,6 +377,8 @@ type RelationshipDetail struct {
 	Types      *RelationshipTypes `@@?`
 	Range      *RangeLiteral      `@@?`
 	Properties *Properties        `@@?`
+	// InlineWhere is the Neo4j 5 predicate in -[r WHERE r.since > 2020]->
+	InlineWhere *Expression `( "WHERE" @@ )?`
 }
 
 // RelationshipTypes is :TYPE|TYPE|...
@@ -370,9 +415,10 @@ type RangeLiteral struct {
 
 // Expression is the top-level expression type (OR).
 type Expression struct {
-	Pos   lexer.Position
-	Left  *XorExpr  `@@`
-	Right []*OrTerm `@@*`
+	Pos    lexer.Position
+	EndPos lexer.Position
+	Left   *XorExpr  `@@`
+	Right  []*OrTerm `@@*`
 }
 
 // OrTerm is an OR operand.
@@ -530,7 +576,7 @@ type StringPredSuffix struct {
 // Atom is the base expression type.
 // Order matters for disambiguation:
 // 1. List/pattern comprehension before list literal (both start with [)
-// 2. COUNT(*) has special syntax
+// 2. COUNT(*) and COUNT { ... } have special syntax
 // 3. FunctionCall uses lookahead to require LParen, so safe to try first
 // 4. Variable is a fallback Ident
 type Atom struct {
@@ -541,13 +587,18 @@ type Atom struct {
 	Parameter            *Parameter            `| @@`
 	CaseExpr             *CaseExpression       `| @@`
 	CountAll             bool                  `| @( "COUNT" LParen Star RParen )`
+	CountSubquery        *CountSubquery        `| @@`
 	FilterPredicate      *FilterPredicate      `| @@`
 	ExistsSubquery       *ExistsSubquery       `| @@`
 	Parenthesized        *Expression           `| LParen @@ RParen`
+	// PathFunction must come before FunctionCall since both start with a name and LParen
+	PathFunction *PathFunction `| @@`
 	// FunctionCall uses lookahead for LParen, so it only matches actual function calls
 	FunctionCall *FunctionCall `| @@`
-	Literal      *Literal      `| @@`
-	Variable     string        `| @Ident`
+	// MapProjection must come before Variable since both start with an identifier
+	MapProjection *MapProjection `| @@`
+	Literal       *Literal       `| @@`
+	Variable      string         `| @Ident`
 }
 
 // Literal is a constant value.
@@ -588,6 +639,24 @@ type MapPair struct {
 	Value *Expression `@@`
 }
 
+// MapProjection is variable { .name, .*, key: expr, otherVar }.
+type MapProjection struct {
+	Pos      lexer.Position
+	Variable string               `@Ident LBrace`
+	Items    []*MapProjectionItem `( @@ ( Comma @@ )* )? RBrace`
+}
+
+// MapProjectionItem is a single entry in a map projection.
+// Exactly one of AllProperties, Property, Key (with Value) or Variable is set.
+type MapProjectionItem struct {
+	Pos           lexer.Position
+	AllProperties bool        `  Dot @Star`
+	Property      string      `| Dot @Ident`
+	Key           string      `| @Ident Colon`
+	Value         *Expression `  @@`
+	Variable      string      `| @Ident`
+}
+
 // Parameter is $name or $0.
 type Parameter struct {
 	Pos  lexer.Position
@@ -606,6 +675,7 @@ type ListComprehension struct {
 // PatternComprehension is [(var =)? pattern WHERE condition | mapping].
 type PatternComprehension struct {
 	Pos     lexer.Position
+	Tokens  []lexer.Token
 	Var     string                    `LBracket ( @Ident Eq )?`
 	Pattern *RelationshipChainPattern `@@`
 	Where   *Where                    `@@?`
@@ -628,11 +698,20 @@ type FilterPredicate struct {
 	Where    *Where      `@@? RParen`
 }
 
-// ExistsSubquery is EXISTS { subquery }.
+// ExistsSubquery is EXISTS { subquery }, or EXISTS { pattern } for a bare pattern.
 type ExistsSubquery struct {
 	Pos     lexer.Position
-	Query   *RegularQuery `"EXISTS" LBrace ( @@`
-	Pattern *Pattern      `         | @@ ) RBrace`
+	Tokens  []lexer.Token
+	Body    *Script  `"EXISTS" LBrace ( @@`
+	Pattern *Pattern `         | @@ ) RBrace`
+}
+
+// CountSubquery is COUNT { subquery }, or COUNT { pattern } for a bare pattern.
+type CountSubquery struct {
+	Pos     lexer.Position
+	Tokens  []lexer.Token
+	Body    *Script  `"COUNT" LBrace ( @@`
+	Pattern *Pattern `        | @@ ) RBrace`
 }
 
 // CaseExpression is CASE expr? (WHEN expr THEN expr)+ (ELSE expr)? END.
@@ -659,6 +738,7 @@ type CaseWhen struct {
 // otherwise we'd greedily consume property access chains like u.address.city.
 type FunctionCall struct {
 	Pos      lexer.Position
+	EndPos   lexer.Position
 	Name     *InvocationName `@@ (?= LParen )`
 	Distinct bool            `LParen @"DISTINCT"?`
 	Args     []*Expression   `( @@ ( Comma @@ )* )? RParen`
@@ -670,12 +750,6 @@ type InvocationName struct {
 	Parts []string `@Ident ( Dot @Ident )*`
 }
 
-// ParenExprList is (expr, expr, ...).
-type ParenExprList struct {
-	Pos   lexer.Position
-	Exprs []*Expression `LParen ( @@ ( Comma @@ )* )? RParen`
-}
-
 // PropertyExpr is a chain of property accesses: a.b.c
 type PropertyExpr struct {
 	Pos   lexer.Position
@@ -32,6 +32,53 @@ func (n *InvocationName) String() string {
 	return strings.Join(n.Parts, ".")
 }
 
+// FullName returns the namespaced name of a procedure call (e.g., "db.labels").
+func (c *ProcedureCall) FullName() string {
+	if c == nil {
+		return ""
+	}
+	if c.Namespace == "" {
+		return c.Name
+	}
+	return c.Namespace + "." + c.Name
+}
+
+// Column returns the procedure output a yield item reads, which is its name
+// unless it was renamed with AS.
+func (y *YieldItem) Column() string {
+	if y.Field != "" {
+		return y.Field
+	}
+	return y.Name
+}
+
+// PatternElement returns the pattern element of a part, looking through
+// shortestPath/allShortestPaths calls.
+func (p *PatternPart) PatternElement() *PatternElement {
+	if p == nil {
+		return nil
+	}
+	if p.PathFunction != nil {
+		return p.PathFunction.Pattern.PatternElement()
+	}
+	return p.Element
+}
+
+// Subquery returns the body of an EXISTS { ... } or COUNT { ... } atom,
+// or nil if the atom is not a subquery or uses the bare pattern form.
+func (a *Atom) Subquery() *Script {
+	switch {
+	case a == nil:
+		return nil
+	case a.ExistsSubquery != nil:
+		return a.ExistsSubquery.Body
+	case a.CountSubquery != nil:
+		return a.CountSubquery.Body
+	default:
+		return nil
+	}
+}
+
 // GetText returns the text representation of a PropertyExpr.
 func (p *PropertyExpr) GetText() string {
 	if p == nil {
@@ -1,6 +1,7 @@
 package cyphergrammar_test
 
 import (
+	"slices"
 	"testing"
 
 	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
@@ -38,6 +39,9 @@ func TestParse_BasicQueries(t *testing.T) {
 		{"optional match", "OPTIONAL MATCH (u:User) RETURN u"},
 		{"unwind", "UNWIND [1, 2, 3] AS x RETURN x"},
 		{"exists subquery", "MATCH (u:User) WHERE EXISTS { MATCH (u)-[:KNOWS]->() } RETURN u"},
+		{"inline where node", "MATCH (n:User WHERE n.age > 18) RETURN n"},
+		{"inline where relationship", "MATCH (n)-[r:KNOWS WHERE r.since > 2020]->(m) RETURN m"},
+		{"count subquery", "MATCH (u:User) RETURN COUNT { MATCH (u)-[:KNOWS]->() } AS friends"},
 		{"is null", "MATCH (u:User) WHERE u.email IS NULL RETURN u"},
 		{"is not null", "MATCH (u:User) WHERE u.email IS NOT NULL RETURN u"},
 		{"in list", "RETURN 1 IN [1, 2, 3]"},
@@ -118,3 +122,433 @@ func TestParse_ListComprehension(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_CallSubquery(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+
+	tests := []struct {
+		name           string
+		query          string
+		inTransactions bool
+		batchSize      *int
+	}{
+		{"plain subquery", "MATCH (u:User) CALL { WITH u MATCH (u)-[:KNOWS]->(f) RETURN count(f) AS friends } RETURN u, friends", false, nil},
+		{"in transactions", "MATCH (u:User) CALL { WITH u DETACH DELETE u } IN TRANSACTIONS", true, nil},
+		{"in transactions of rows", "UNWIND $rows AS row CALL { WITH row CREATE (:User {id: row.id}) } IN TRANSACTIONS OF 500 ROWS", true, intPtr(500)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := cyphergrammar.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+
+			var call *cyphergrammar.CallSubquery
+			for _, clause := range ast.Query.RegularQuery.SingleQuery.Clauses {
+				if clause.CallSubquery != nil {
+					call = clause.CallSubquery
+				}
+			}
+			if call == nil {
+				t.Fatalf("Parse(%q) produced no CallSubquery clause", tt.query)
+			}
+			if call.Body == nil {
+				t.Fatal("CallSubquery.Body is nil")
+			}
+			if call.InTransactions != tt.inTransactions {
+				t.Errorf("InTransactions = %v, want %v", call.InTransactions, tt.inTransactions)
+			}
+			switch {
+			case tt.batchSize == nil && call.BatchSize != nil:
+				t.Errorf("BatchSize = %d, want nil", *call.BatchSize)
+			case tt.batchSize != nil && call.BatchSize == nil:
+				t.Errorf("BatchSize = nil, want %d", *tt.batchSize)
+			case tt.batchSize != nil && *call.BatchSize != *tt.batchSize:
+				t.Errorf("BatchSize = %d, want %d", *call.BatchSize, *tt.batchSize)
+			}
+		})
+	}
+}
+
+func TestParse_ProcedureCall(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		namespace string
+		procName  string
+		args      int
+		yield     []string
+		where     bool
+	}{
+		{"standalone", "CALL db.labels()", "db", "labels", 0, nil, false},
+		{"yield then return", "CALL db.labels() YIELD label RETURN label", "db", "labels", 0, []string{"label"}, false},
+		{"nested namespace", "MATCH (n) CALL apoc.meta.schema() YIELD value RETURN n, value", "apoc.meta", "schema", 0, []string{"value"}, false},
+		{"unnamespaced", "CALL count() YIELD x RETURN x", "", "count", 0, []string{"x"}, false},
+		{"arguments", "CALL apoc.text.join($parts, ', ') YIELD value RETURN value", "apoc.text", "join", 2, []string{"value"}, false},
+		{"no parentheses", "CALL db.labels YIELD label RETURN label", "db", "labels", 0, []string{"label"}, false},
+		{"aliases and where", "CALL db.labels() YIELD label AS l, nodeCount WHERE l <> 'x' RETURN l", "db", "labels", 0, []string{"l", "nodeCount"}, true},
+		{"yield star", "CALL db.labels() YIELD *", "db", "labels", 0, []string{"*"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := cyphergrammar.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+
+			var call *cyphergrammar.ProcedureCall
+			for _, clause := range ast.Query.RegularQuery.SingleQuery.Clauses {
+				if clause.Reading != nil && clause.Reading.Call != nil {
+					call = clause.Reading.Call
+				}
+			}
+			if call == nil {
+				t.Fatalf("Parse(%q) produced no ProcedureCall clause", tt.query)
+			}
+			if call.Namespace != tt.namespace {
+				t.Errorf("Namespace = %q, want %q", call.Namespace, tt.namespace)
+			}
+			if call.Name != tt.procName {
+				t.Errorf("Name = %q, want %q", call.Name, tt.procName)
+			}
+			if len(call.Args) != tt.args {
+				t.Errorf("len(Args) = %d, want %d", len(call.Args), tt.args)
+			}
+			var yield []string
+			for _, item := range call.Yield {
+				yield = append(yield, item.Name)
+			}
+			if !slices.Equal(yield, tt.yield) {
+				t.Errorf("Yield = %v, want %v", yield, tt.yield)
+			}
+			if (call.Where != nil) != tt.where {
+				t.Errorf("Where = %v, want present=%v", call.Where, tt.where)
+			}
+		})
+	}
+}
+
+func TestParse_ForEach(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		variable string
+		updates  int
+	}{
+		{"create", "FOREACH (x IN $names | CREATE (:Node {prop: x}))", "x", 1},
+		{"lowercase", "match (u:User) foreach (t in u.tags | merge (:Tag {name: t}))", "t", 1},
+		{"multiple updates", "MATCH (u:User) FOREACH (f IN collect(u) | SET f.seen = true REMOVE f:New)", "f", 2},
+		{"nested", "FOREACH (a IN $rows | FOREACH (b IN a.items | CREATE (:Item {id: b})))", "a", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := cyphergrammar.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+
+			var forEach *cyphergrammar.ForEachClause
+			for _, clause := range ast.Query.RegularQuery.SingleQuery.Clauses {
+				if clause.Updating != nil && clause.Updating.ForEach != nil {
+					forEach = clause.Updating.ForEach
+				}
+			}
+			if forEach == nil {
+				t.Fatalf("Parse(%q) produced no ForEach clause", tt.query)
+			}
+			if forEach.Variable != tt.variable {
+				t.Errorf("Variable = %q, want %q", forEach.Variable, tt.variable)
+			}
+			if forEach.List == nil {
+				t.Error("List is nil")
+			}
+			if len(forEach.Updates) != tt.updates {
+				t.Errorf("len(Updates) = %d, want %d", len(forEach.Updates), tt.updates)
+			}
+		})
+	}
+}
+
+func TestParse_MergeActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		onCreate int
+		onMatch  int
+	}{
+		{"no actions", "MERGE (u:User {id: $id})", 0, 0},
+		{"create then match", "MERGE (u:User {id: $id}) ON CREATE SET u.name = $name, u.created = timestamp() ON MATCH SET u.seen = true", 2, 1},
+		{"match then create", "MERGE (u:User {id: $id}) ON MATCH SET u.seen = true ON CREATE SET u.name = $name", 1, 1},
+		{"repeated", "MERGE (u:User {id: $id}) ON CREATE SET u.a = 1 ON MATCH SET u.b = 2 ON CREATE SET u:New", 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := cyphergrammar.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+
+			clause := ast.Query.RegularQuery.SingleQuery.Clauses[0]
+			if clause.Updating == nil || clause.Updating.Merge == nil {
+				t.Fatalf("Parse(%q) produced no Merge clause", tt.query)
+			}
+
+			merge := clause.Updating.Merge
+			if got := len(merge.OnCreateItems()); got != tt.onCreate {
+				t.Errorf("len(OnCreateItems()) = %d, want %d", got, tt.onCreate)
+			}
+			if got := len(merge.OnMatchItems()); got != tt.onMatch {
+				t.Errorf("len(OnMatchItems()) = %d, want %d", got, tt.onMatch)
+			}
+		})
+	}
+}
+
+func TestParse_PathFunction(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		fn    string
+	}{
+		{"shortest path", "MATCH p = shortestPath((a:User)-[*]->(b:User)) RETURN p", "shortestPath"},
+		{"all shortest paths", "MATCH p = allShortestPaths((a:User)-[:KNOWS*..5]-(b:User)) RETURN p", "allShortestPaths"},
+		{"lowercase", "match p = shortestpath((a)-[*]-(b)) return p", "shortestpath"},
+		{"expression", "MATCH (a:User), (b:User) RETURN shortestPath((a)-[*]->(b)) AS p", "shortestPath"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := cyphergrammar.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+
+			var fn *cyphergrammar.PathFunction
+			for _, clause := range ast.Query.RegularQuery.SingleQuery.Clauses {
+				if clause.Reading != nil && clause.Reading.Match != nil {
+					for _, part := range clause.Reading.Match.Pattern.Parts {
+						if part.PathFunction != nil {
+							fn = part.PathFunction
+						}
+					}
+				}
+				if clause.Return != nil {
+					expr := clause.Return.Body.Items.Items[0].Expr
+					if atom := expr.Left.Left.Left.Expr.Left.Left.Left.Left.Expr.Atom; atom.PathFunction != nil {
+						fn = atom.PathFunction
+					}
+				}
+			}
+			if fn == nil {
+				t.Fatalf("Parse(%q) produced no PathFunction", tt.query)
+			}
+			if fn.Name != tt.fn {
+				t.Errorf("Name = %q, want %q", fn.Name, tt.fn)
+			}
+			if fn.Pattern.PatternElement() == nil {
+				t.Error("Pattern has no element")
+			}
+		})
+	}
+}
+
+func TestParse_Subquery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantCount   bool
+		wantPattern bool
+	}{
+		{"exists", "MATCH (u) WHERE EXISTS { MATCH (u)-[:KNOWS]->(f) WHERE f.age > 30 } RETURN u", false, false},
+		{"exists pattern", "MATCH (u) WHERE EXISTS { (u)-[:KNOWS]->() } RETURN u", false, true},
+		{"count", "MATCH (u) RETURN COUNT { MATCH (u)-[:KNOWS]->() RETURN u } AS n", true, false},
+		{"count pattern", "match (u) return count { (u)-->() } as n", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := cyphergrammar.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+
+			var atom *cyphergrammar.Atom
+			cyphergrammar.Inspect(ast, func(node any) bool {
+				if a, ok := node.(*cyphergrammar.Atom); ok && (a.ExistsSubquery != nil || a.CountSubquery != nil) {
+					atom = a
+				}
+				return atom == nil
+			})
+			if atom == nil {
+				t.Fatalf("Parse(%q) produced no subquery", tt.query)
+			}
+
+			if got := atom.CountSubquery != nil; got != tt.wantCount {
+				t.Errorf("CountSubquery = %v, want %v", got, tt.wantCount)
+			}
+			if got := atom.Subquery() == nil; got != tt.wantPattern {
+				t.Errorf("Subquery() == nil is %v, want %v", got, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestParse_CountForms(t *testing.T) {
+	ast, err := cyphergrammar.Parse("MATCH (u) RETURN count(*), count(u), COUNT { (u)-->() }")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	items := ast.Query.RegularQuery.SingleQuery.Clauses[1].Return.Body.Items.Items
+	atom := func(i int) *cyphergrammar.Atom {
+		return items[i].Expr.Left.Left.Left.Expr.Left.Left.Left.Left.Expr.Atom
+	}
+
+	if !atom(0).CountAll {
+		t.Error("count(*) did not parse as CountAll")
+	}
+	if atom(1).FunctionCall == nil {
+		t.Error("count(u) did not parse as a function call")
+	}
+	if atom(2).CountSubquery == nil {
+		t.Error("COUNT { } did not parse as a subquery")
+	}
+}
+
+func TestParse_InlineWhere(t *testing.T) {
+	ast, err := cyphergrammar.Parse("MATCH (n:User {active: true} WHERE n.age > 18)-[r WHERE r.since > 2020]->(m WHERE m.name STARTS WITH 'A') RETURN m")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	elem := ast.Query.RegularQuery.SingleQuery.Clauses[0].Reading.Match.Pattern.Parts[0].PatternElement()
+
+	if elem.Node.Properties == nil || elem.Node.InlineWhere == nil {
+		t.Error("first node should have both properties and an inline WHERE")
+	}
+	if elem.Chain[0].Rel.Detail.InlineWhere == nil {
+		t.Error("relationship should have an inline WHERE")
+	}
+	if elem.Chain[0].Node.InlineWhere == nil {
+		t.Error("last node should have an inline WHERE")
+	}
+
+	// WHERE is still a clause keyword after the pattern
+	ast, err = cyphergrammar.Parse("MATCH (n) WHERE n.age > 18 RETURN n")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	match := ast.Query.RegularQuery.SingleQuery.Clauses[0].Reading.Match
+	if match.Where == nil || match.Pattern.Parts[0].PatternElement().Node.InlineWhere != nil {
+		t.Error("outer WHERE parsed as an inline WHERE")
+	}
+}
+
+func TestParse_MultipleLabels(t *testing.T) {
+	ast, err := cyphergrammar.Parse("MATCH (n:Person:Employee:Manager)-[:REPORTS_TO]->(:Person:Employee) RETURN n")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	elem := ast.Query.RegularQuery.SingleQuery.Clauses[0].Reading.Match.Pattern.Parts[0].PatternElement()
+
+	if got, want := elem.Node.Labels.Labels, []string{"Person", "Employee", "Manager"}; !slices.Equal(got, want) {
+		t.Errorf("first node labels = %v, want %v", got, want)
+	}
+	if got, want := elem.Chain[0].Node.Labels.Labels, []string{"Person", "Employee"}; !slices.Equal(got, want) {
+		t.Errorf("second node labels = %v, want %v", got, want)
+	}
+}
+
+func TestParse_MapProjection(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		variable string
+		items    int
+	}{
+		{"property selectors", "MATCH (u:User) RETURN u { .name, .age }", "u", 2},
+		{"all properties", "MATCH (u:User) RETURN u { .* }", "u", 1},
+		{"literal entry", "MATCH (u:User) RETURN u { .name, score: u.score * 2 } AS user", "u", 2},
+		{"variable entry", "MATCH (u:User)-[:AUTHORED]->(p:Post) RETURN u { .name, p }", "u", 2},
+		{"empty", "MATCH (u:User) RETURN u {}", "u", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := cyphergrammar.Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.query, err)
+			}
+
+			clauses := ast.Query.RegularQuery.SingleQuery.Clauses
+			ret := clauses[len(clauses)-1].Return
+			if ret == nil {
+				t.Fatalf("Parse(%q) produced no RETURN clause", tt.query)
+			}
+
+			expr := ret.Body.Items.Items[0].Expr
+			atom := expr.Left.Left.Left.Expr.Left.Left.Left.Left.Expr.Atom
+			proj := atom.MapProjection
+			if proj == nil {
+				t.Fatalf("Parse(%q) produced no MapProjection", tt.query)
+			}
+			if proj.Variable != tt.variable {
+				t.Errorf("Variable = %q, want %q", proj.Variable, tt.variable)
+			}
+			if len(proj.Items) != tt.items {
+				t.Errorf("len(Items) = %d, want %d", len(proj.Items), tt.items)
+			}
+		})
+	}
+}
+
+func TestScript_Walk(t *testing.T) {
+	ast, err := cyphergrammar.Parse(`MATCH (u:User)-[:FOLLOWS]->(f) WHERE EXISTS { (f)-[:POSTED]->(:Post) } RETURN [x IN f.tags | x] AS tags`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	// Every node pointer Inspect reaches implements Node
+	cyphergrammar.Inspect(ast, func(node any) bool {
+		if _, ok := node.(cyphergrammar.Node); !ok {
+			t.Errorf("%T does not implement Node", node)
+		}
+
+		return true
+	})
+
+	var visited []cyphergrammar.Node
+	ast.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		visited = append(visited, node)
+
+		return true
+	}))
+
+	if len(visited) == 0 || visited[0] != cyphergrammar.Node(ast) {
+		t.Fatalf("Walk did not visit the script first")
+	}
+
+	var labels []string
+	ast.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		switch n := node.(type) {
+		case *cyphergrammar.Expression:
+			return false
+		case *cyphergrammar.NodePattern:
+			if n.Labels != nil {
+				labels = append(labels, n.Labels.Labels...)
+			}
+		}
+
+		return true
+	}))
+
+	// The :Post pattern is inside the EXISTS expression, so it's skipped
+	if want := []string{"User"}; !slices.Equal(labels, want) {
+		t.Errorf("labels outside expressions = %v, want %v", labels, want)
+	}
+}
@@ -0,0 +1,166 @@
+package cyphergrammar
+
+import (
+	"reflect"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+var (
+	positionType = reflect.TypeFor[lexer.Position]()
+	tokensType   = reflect.TypeFor[[]lexer.Token]()
+)
+
+// Node is implemented by every AST node pointer type (e.g. *Clause, *Atom).
+type Node interface {
+	node()
+}
+
+// Visitor visits the nodes of an AST during Walk.
+type Visitor interface {
+	// Visit is called with each node. If it returns false, the children of
+	// that node are skipped.
+	Visit(node Node) bool
+}
+
+// VisitorFunc adapts a function to a Visitor.
+type VisitorFunc func(node Node) bool
+
+// Visit calls f(node).
+func (f VisitorFunc) Visit(node Node) bool { return f(node) }
+
+// Walk traverses the script in depth-first pre-order, calling v.Visit with
+// each non-nil node, starting with s itself.
+func (s *Script) Walk(v Visitor) {
+	Inspect(s, func(n any) bool {
+		node, ok := n.(Node)
+
+		return !ok || v.Visit(node)
+	})
+}
+
+// Inspect traverses the AST rooted at node in depth-first order, calling fn
+// with each non-nil node pointer (e.g. *Clause, *Atom). If fn returns false,
+// the children of that node are skipped.
+func Inspect(node any, fn func(node any) bool) {
+	inspect(reflect.ValueOf(node), fn)
+}
+
+func inspect(v reflect.Value, fn func(node any) bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return
+		}
+
+		if !fn(v.Interface()) {
+			return
+		}
+
+		inspect(v.Elem(), fn)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Field(i)
+			if t := field.Type(); t == positionType || t == tokensType {
+				continue
+			}
+
+			inspect(field, fn)
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			inspect(v.Index(i), fn)
+		}
+	default:
+	}
+}
+
+// The node methods mark the AST types as Nodes.
+
+func (*Script) node()                   {}
+func (*Query) node()                    {}
+func (*RegularQuery) node()             {}
+func (*UnionClause) node()              {}
+func (*SingleQuery) node()              {}
+func (*Clause) node()                   {}
+func (*ReadingClause) node()            {}
+func (*UpdatingClause) node()           {}
+func (*MatchClause) node()              {}
+func (*UnwindClause) node()             {}
+func (*ProcedureCall) node()            {}
+func (*CallSubquery) node()             {}
+func (*ReturnClause) node()             {}
+func (*WithClause) node()               {}
+func (*ProjectionBody) node()           {}
+func (*ProjectionItems) node()          {}
+func (*ProjectionItem) node()           {}
+func (*OrderBy) node()                  {}
+func (*OrderItem) node()                {}
+func (*Skip) node()                     {}
+func (*Limit) node()                    {}
+func (*Where) node()                    {}
+func (*CreateClause) node()             {}
+func (*MergeClause) node()              {}
+func (*MergeAction) node()              {}
+func (*DeleteClause) node()             {}
+func (*SetClause) node()                {}
+func (*SetItem) node()                  {}
+func (*RemoveClause) node()             {}
+func (*RemoveItem) node()               {}
+func (*ForEachClause) node()            {}
+func (*Pattern) node()                  {}
+func (*PatternPart) node()              {}
+func (*PathFunction) node()             {}
+func (*PatternElement) node()           {}
+func (*PatternElemChain) node()         {}
+func (*NodePattern) node()              {}
+func (*NodeLabels) node()               {}
+func (*Properties) node()               {}
+func (*RelationshipPattern) node()      {}
+func (*RelationshipDetail) node()       {}
+func (*RelationshipTypes) node()        {}
+func (*RangeLiteral) node()             {}
+func (*Expression) node()               {}
+func (*OrTerm) node()                   {}
+func (*XorExpr) node()                  {}
+func (*XorTerm) node()                  {}
+func (*AndExpr) node()                  {}
+func (*AndTerm) node()                  {}
+func (*NotExpr) node()                  {}
+func (*ComparisonExpr) node()           {}
+func (*ComparisonTerm) node()           {}
+func (*AddSubExpr) node()               {}
+func (*AddSubTerm) node()               {}
+func (*MultDivExpr) node()              {}
+func (*MultDivTerm) node()              {}
+func (*PowerExpr) node()                {}
+func (*PowerTerm) node()                {}
+func (*UnaryExpr) node()                {}
+func (*PostfixExpr) node()              {}
+func (*PostfixSuffix) node()            {}
+func (*IndexSuffix) node()              {}
+func (*IsNullSuffix) node()             {}
+func (*InSuffix) node()                 {}
+func (*StringPredSuffix) node()         {}
+func (*Atom) node()                     {}
+func (*Literal) node()                  {}
+func (*ListLiteral) node()              {}
+func (*MapLiteral) node()               {}
+func (*MapPair) node()                  {}
+func (*MapProjection) node()            {}
+func (*MapProjectionItem) node()        {}
+func (*Parameter) node()                {}
+func (*ListComprehension) node()        {}
+func (*PatternComprehension) node()     {}
+func (*RelationshipChainPattern) node() {}
+func (*FilterPredicate) node()          {}
+func (*ExistsSubquery) node()           {}
+func (*CountSubquery) node()            {}
+func (*CaseExpression) node()           {}
+func (*CaseWhen) node()                 {}
+func (*FunctionCall) node()             {}
+func (*InvocationName) node()           {}
+func (*PropertyExpr) node()             {}
+func (*SetItemProperty) node()          {}
+func (*SetItemVariable) node()          {}
+func (*SetItemLabel) node()             {}
@@ -2,10 +2,14 @@ package cypher
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"sort"
 	"strings"
 	"unicode"
 
+	"github.com/alecthomas/participle/v2/lexer"
+
 	"github.com/rlch/scaf"
 	"github.com/rlch/scaf/analysis"
 	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
@@ -163,6 +167,10 @@ type completionContext struct {
 	inRelPattern   bool   // Inside [...]
 	inFunctionCall bool   // Inside function call
 	variableName   string // Variable name when completing properties
+	propertyPath   string // Nested property path before the dot, e.g. "address" in u.address.
+
+	// Map projection context: labels of the projected variable in u { .| }
+	projectionLabels []string
 
 	// Relationship pattern context (for context-aware rel type completions)
 	leftNodeLabels  []string // Labels on the node to the left of the relationship
@@ -191,6 +199,13 @@ func (d *Dialect) analyzeCompletionContext(textBefore string, parsed *cyphergram
 		return cc
 	}
 
+	// A parameter name being typed, as in $us
+	if cc.prefix != "" && strings.HasSuffix(textBefore[:len(textBefore)-len(cc.prefix)], "$") {
+		cc.afterDollar = true
+		cc.kind = completionContextParameter
+		return cc
+	}
+
 	lastChar := trimmed[len(trimmed)-1]
 
 	// Check for specific triggers
@@ -211,7 +226,12 @@ func (d *Dialect) analyzeCompletionContext(textBefore string, parsed *cyphergram
 	case '.':
 		cc.afterDot = true
 		cc.kind = completionContextProperty
-		cc.variableName = extractVariableBeforeDot(trimmed)
+		if varName := d.mapProjectionVariable(trimmed[:len(trimmed)-1]); varName != "" {
+			cc.variableName = varName
+			cc.projectionLabels = d.findLabelsForVariable(parsed, varName)
+		} else {
+			cc.variableName, cc.propertyPath = extractPropertyChain(trimmed)
+		}
 		return cc
 
 	case '$':
@@ -432,10 +452,10 @@ func (d *Dialect) findVariableLabelsInPattern(pattern *cyphergrammar.Pattern, va
 }
 
 func (d *Dialect) findVariableLabelsInPatternPart(part *cyphergrammar.PatternPart, varName string) []string {
-	if part == nil || part.Element == nil {
+	if part == nil || part.PatternElement() == nil {
 		return nil
 	}
-	return d.findVariableLabelsInPatternElement(part.Element, varName)
+	return d.findVariableLabelsInPatternElement(part.PatternElement(), varName)
 }
 
 func (d *Dialect) findVariableLabelsInPatternElement(elem *cyphergrammar.PatternElement, varName string) []string {
@@ -496,28 +516,77 @@ func (d *Dialect) looksLikeFunctionName(text string) bool {
 	return false
 }
 
-func extractVariableBeforeDot(text string) string {
-	if len(text) == 0 || text[len(text)-1] != '.' {
+// mapProjectionVariable returns the projected variable when text ends at the start
+// of a map projection item, e.g. "RETURN u { .name, " yields "u".
+// Property maps inside node and relationship patterns are not projections.
+func (d *Dialect) mapProjectionVariable(text string) string {
+	trimmed := strings.TrimRightFunc(text, unicode.IsSpace)
+	if trimmed == "" {
+		return ""
+	}
+	if last := trimmed[len(trimmed)-1]; last != '{' && last != ',' {
+		return ""
+	}
+
+	// Find the unclosed brace enclosing the cursor.
+	depth := 0
+	brace := -1
+	for i := len(trimmed) - 1; i >= 0 && brace < 0; i-- {
+		switch trimmed[i] {
+		case '}', ')', ']':
+			depth++
+		case '(', '[':
+			depth--
+		case '{':
+			if depth == 0 {
+				brace = i
+			} else {
+				depth--
+			}
+		}
+		if depth < 0 {
+			return ""
+		}
+	}
+	if brace < 0 {
+		return ""
+	}
+
+	before := strings.TrimRightFunc(trimmed[:brace], unicode.IsSpace)
+	if d.isInNodePattern(before) || d.isInRelPattern(before) {
 		return ""
 	}
 
+	return extractCypherPrefix(before)
+}
+
+// extractPropertyChain splits the property access before a trailing dot into
+// the variable and the nested property path: "u.address." gives ("u", "address").
+func extractPropertyChain(text string) (string, string) {
+	if len(text) == 0 || text[len(text)-1] != '.' {
+		return "", ""
+	}
+
 	end := len(text) - 1
 	start := end
 
 	for i := end - 1; i >= 0; i-- {
 		c := rune(text[i])
-		if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' {
 			start = i
 		} else {
 			break
 		}
 	}
 
-	if start == end {
-		return ""
+	chain := strings.Trim(text[start:end], ".")
+	if chain == "" {
+		return "", ""
 	}
 
-	return text[start:end]
+	variable, path, _ := strings.Cut(chain, ".")
+
+	return variable, path
 }
 
 func extractCypherPrefix(text string) string {
@@ -638,116 +707,64 @@ func (d *Dialect) completeRelationshipTypes(cc *completionContext, ctx *scaf.Que
 		return nil
 	}
 
-	hasLeftLabels := len(cc.leftNodeLabels) > 0
+	rels := schema.Relationships()
+	relTypes := slices.Sorted(maps.Keys(rels))
 
 	var items []scaf.QueryCompletion
-	seen := make(map[string]bool)
-
-	if hasLeftLabels {
-		if cc.relDirectionIn {
-			// Pattern: (leftNode)<-[:]- means we want relationships that TARGET leftNode
-			// Look for relationships from ANY model that have:
-			// - Target matching one of leftNodeLabels
-			// - Direction is Outgoing (the rel points TO our node)
-			for modelName, model := range schema.Models {
-				for _, rel := range model.Relationships {
-					if rel.RelType == "" || seen[rel.RelType] {
-						continue
-					}
-
-					// Only outgoing relationships can point TO our left node
-					if rel.Direction != analysis.DirectionOutgoing {
-						continue
-					}
-
-					// Check if this rel targets one of our left labels
-					targetMatches := false
-					for _, label := range cc.leftNodeLabels {
-						if rel.Target == label {
-							targetMatches = true
-							break
-						}
-					}
-
-					if !targetMatches {
-						continue
-					}
-
-					seen[rel.RelType] = true
 
-					items = append(items, scaf.QueryCompletion{
-						Label:      rel.RelType,
-						Kind:       scaf.QueryCompletionRelType,
-						Detail:     fmt.Sprintf("%s → %s", modelName, rel.Target),
-						InsertText: rel.RelType,
-						SortText:   "1" + rel.RelType,
-					})
-				}
-			}
-		} else {
-			// Pattern: (leftNode)-[:]- means we want relationships FROM leftNode
-			// Look at the leftNode's model for outgoing relationships
-			for _, label := range cc.leftNodeLabels {
-				model, exists := schema.Models[label]
-				if !exists {
-					continue
+	if len(cc.leftNodeLabels) > 0 {
+		for _, relType := range relTypes {
+			// Only outgoing relationships are offered. For (leftNode)-[:]- they
+			// must start at leftNode; for (leftNode)<-[:]- they must point TO it.
+			i := slices.IndexFunc(rels[relType], func(rel *analysis.RelationshipInfo) bool {
+				if rel.Direction != analysis.DirectionOutgoing {
+					return false
 				}
 
-				for _, rel := range model.Relationships {
-					if rel.RelType == "" || seen[rel.RelType] {
-						continue
-					}
-
-					// Only show outgoing relationships from this model
-					if rel.Direction != analysis.DirectionOutgoing {
-						continue
-					}
-
-					seen[rel.RelType] = true
-
-					items = append(items, scaf.QueryCompletion{
-						Label:      rel.RelType,
-						Kind:       scaf.QueryCompletionRelType,
-						Detail:     fmt.Sprintf("%s → %s", label, rel.Target),
-						InsertText: rel.RelType,
-						SortText:   "1" + rel.RelType,
-					})
+				if cc.relDirectionIn {
+					return slices.Contains(cc.leftNodeLabels, rel.Target)
 				}
+
+				return slices.Contains(cc.leftNodeLabels, rel.SourceModel)
+			})
+			if i < 0 {
+				continue
 			}
+
+			rel := rels[relType][i]
+			items = append(items, scaf.QueryCompletion{
+				Label:      relType,
+				Kind:       scaf.QueryCompletionRelType,
+				Detail:     fmt.Sprintf("%s → %s", rel.SourceModel, rel.Target),
+				InsertText: relType,
+				SortText:   "1" + relType,
+			})
 		}
 	}
 
 	// If no context-specific results, show all relationship types
 	if len(items) == 0 {
-		for modelName, model := range schema.Models {
-			for _, rel := range model.Relationships {
-				if rel.RelType != "" && !seen[rel.RelType] {
-					seen[rel.RelType] = true
-
-					direction := ""
-					switch rel.Direction {
-					case analysis.DirectionOutgoing:
-						direction = " →"
-					case analysis.DirectionIncoming:
-						direction = " ←"
-					}
-
-					items = append(items, scaf.QueryCompletion{
-						Label:      rel.RelType,
-						Kind:       scaf.QueryCompletionRelType,
-						Detail:     fmt.Sprintf("%s%s %s", modelName, direction, rel.Target),
-						InsertText: rel.RelType,
-						SortText:   "3" + rel.RelType,
-					})
-				}
+		for _, relType := range relTypes {
+			rel := rels[relType][0]
+
+			direction := ""
+			switch rel.Direction {
+			case analysis.DirectionOutgoing:
+				direction = " →"
+			case analysis.DirectionIncoming:
+				direction = " ←"
 			}
+
+			items = append(items, scaf.QueryCompletion{
+				Label:      relType,
+				Kind:       scaf.QueryCompletionRelType,
+				Detail:     fmt.Sprintf("%s%s %s", rel.SourceModel, direction, rel.Target),
+				InsertText: relType,
+				SortText:   "3" + relType,
+			})
 		}
 	}
 
-	sort.Slice(items, func(i, j int) bool {
-		return items[i].Label < items[j].Label
-	})
-
 	return items
 }
 
@@ -764,18 +781,34 @@ func (d *Dialect) completeProperties(cc *completionContext, ctx *scaf.QueryLSPCo
 	var items []scaf.QueryCompletion
 	seen := make(map[string]bool)
 
-	for _, model := range schema.Models {
-		for _, field := range model.Fields {
-			if !seen[field.Name] {
-				seen[field.Name] = true
-				items = append(items, scaf.QueryCompletion{
-					Label:      field.Name,
-					Kind:       scaf.QueryCompletionProperty,
-					Detail:     field.Type.String(),
-					InsertText: field.Name,
-					SortText:   "3" + field.Name,
-				})
-			}
+	// Nested struct properties are flattened into dotted names ("address.city");
+	// offer only the direct children of the path being accessed.
+	prefix := ""
+	fields := schema.AllFields()
+	if cc.propertyPath != "" {
+		prefix = cc.propertyPath + "."
+		fields = nestedFieldInfos(schema)
+	}
+
+	for _, field := range fields {
+		if len(cc.projectionLabels) > 0 && !slices.Contains(cc.projectionLabels, field.ModelName) {
+			continue
+		}
+
+		name, ok := strings.CutPrefix(field.Name, prefix)
+		if !ok || name == "" || strings.Contains(name, ".") {
+			continue
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			items = append(items, scaf.QueryCompletion{
+				Label:      name,
+				Kind:       scaf.QueryCompletionProperty,
+				Detail:     field.Type.String(),
+				InsertText: name,
+				SortText:   "3" + name,
+			})
 		}
 	}
 
@@ -786,6 +819,22 @@ func (d *Dialect) completeProperties(cc *completionContext, ctx *scaf.QueryLSPCo
 	return items
 }
 
+// nestedFieldInfos returns the nested fields of every model, ordered by model
+// name.
+func nestedFieldInfos(schema *analysis.TypeSchema) []*analysis.FieldInfo {
+	var fields []*analysis.FieldInfo
+
+	for _, name := range slices.Sorted(maps.Keys(schema.Models)) {
+		if model := schema.Models[name]; model != nil {
+			for _, field := range model.NestedFields {
+				fields = append(fields, &analysis.FieldInfo{Field: field, ModelName: name})
+			}
+		}
+	}
+
+	return fields
+}
+
 func (d *Dialect) completeVariables(cc *completionContext, parsed *cyphergrammar.Script) []scaf.QueryCompletion {
 	if parsed == nil || parsed.Query == nil || parsed.Query.RegularQuery == nil {
 		return nil
@@ -811,81 +860,38 @@ func (d *Dialect) completeVariables(cc *completionContext, parsed *cyphergrammar
 	return items
 }
 
+// extractVariables returns the variables bound by the query's patterns.
+// Patterns inside expressions, such as comprehensions and EXISTS subqueries,
+// bind variables only within them and are skipped.
 func (d *Dialect) extractVariables(parsed *cyphergrammar.Script) map[string]bool {
 	vars := make(map[string]bool)
 
-	if parsed == nil || parsed.Query == nil || parsed.Query.RegularQuery == nil {
-		return vars
-	}
-
-	rq := parsed.Query.RegularQuery
-	if rq.SingleQuery == nil {
+	if parsed == nil {
 		return vars
 	}
 
-	for _, clause := range rq.SingleQuery.Clauses {
-		d.extractVariablesFromClause(clause, vars)
-	}
-
-	return vars
-}
-
-func (d *Dialect) extractVariablesFromClause(clause *cyphergrammar.Clause, vars map[string]bool) {
-	if clause == nil {
-		return
-	}
-
-	if clause.Reading != nil && clause.Reading.Match != nil && clause.Reading.Match.Pattern != nil {
-		d.extractVariablesFromPattern(clause.Reading.Match.Pattern, vars)
-	}
-
-	if clause.Updating != nil {
-		if clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
-			d.extractVariablesFromPattern(clause.Updating.Create.Pattern, vars)
-		}
-	}
-}
-
-func (d *Dialect) extractVariablesFromPattern(pattern *cyphergrammar.Pattern, vars map[string]bool) {
-	if pattern == nil {
-		return
-	}
-
-	for _, part := range pattern.Parts {
-		if part == nil || part.Element == nil {
-			continue
-		}
-
-		if part.Var != "" {
-			vars[part.Var] = true
+	parsed.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		switch n := node.(type) {
+		case *cyphergrammar.Expression:
+			return false
+		case *cyphergrammar.PatternPart:
+			if n.Var != "" {
+				vars[n.Var] = true
+			}
+		case *cyphergrammar.NodePattern:
+			if n.Variable != "" {
+				vars[n.Variable] = true
+			}
+		case *cyphergrammar.RelationshipDetail:
+			if n.Variable != "" {
+				vars[n.Variable] = true
+			}
 		}
 
-		d.extractVariablesFromPatternElement(part.Element, vars)
-	}
-}
-
-func (d *Dialect) extractVariablesFromPatternElement(elem *cyphergrammar.PatternElement, vars map[string]bool) {
-	if elem == nil {
-		return
-	}
-
-	if elem.Paren != nil {
-		d.extractVariablesFromPatternElement(elem.Paren, vars)
-		return
-	}
-
-	if elem.Node != nil && elem.Node.Variable != "" {
-		vars[elem.Node.Variable] = true
-	}
+		return true
+	}))
 
-	for _, chain := range elem.Chain {
-		if chain.Rel != nil && chain.Rel.Detail != nil && chain.Rel.Detail.Variable != "" {
-			vars[chain.Rel.Detail.Variable] = true
-		}
-		if chain.Node != nil && chain.Node.Variable != "" {
-			vars[chain.Node.Variable] = true
-		}
-	}
+	return vars
 }
 
 func (d *Dialect) completeParameters(cc *completionContext, ctx *scaf.QueryLSPContext) []scaf.QueryCompletion {
@@ -932,7 +938,11 @@ func (d *Dialect) Hover(query string, offset int, ctx *scaf.QueryLSPContext) *sc
 		}
 	}
 
-	// Check if it's a function
+	// Check if it's a function, preferring full signatures over the return type
+	if sigs, ok := cypherFunctionSignatures[strings.ToLower(word)]; ok {
+		return &scaf.QueryHover{Contents: formatSignatureHover(sigs)}
+	}
+
 	if retType, ok := cypherFunctionTypes[strings.ToLower(word)]; ok {
 		return &scaf.QueryHover{
 			Contents: fmt.Sprintf("**%s** (function)\n\nReturns: `%s`", strings.ToLower(word), retType),
@@ -969,18 +979,50 @@ func (d *Dialect) Diagnostics(query string, ctx *scaf.QueryLSPContext) []scaf.Qu
 	parsed, err := cyphergrammar.Parse(query)
 	if err != nil {
 		diags = append(diags, scaf.QueryDiagnostic{
-			Range:    scaf.QueryRange{Start: 0, End: len(query)},
+			Range:    scaf.NewQueryDiagnosticRange(query, 0, len(query)),
 			Severity: scaf.QueryDiagnosticError,
 			Message:  err.Error(),
 			Code:     "syntax-error",
 		})
 	}
 
+	// FOREACH bodies may only contain updating clauses
+	if parsed != nil {
+		diags = append(diags, forEachDiagnostics(parsed)...)
+	}
+
+	// EXISTS { ... } and COUNT { ... } bodies must be read-only
+	if parsed != nil {
+		diags = append(diags, subqueryDiagnostics(parsed)...)
+	}
+
+	// Inline WHERE predicates may only use their own pattern's variable
+	if parsed != nil {
+		diags = append(diags, inlineWhereDiagnostics(parsed)...)
+	}
+
+	// MERGE without ON CREATE SET or ON MATCH SET
+	if parsed != nil {
+		diags = append(diags, mergeDiagnostics(parsed)...)
+	}
+
+	// Properties of OPTIONAL MATCH variables filtered without a null check
+	if parsed != nil {
+		diags = append(diags, nullableDiagnostics(parsed)...)
+	}
+
 	// Add semantic diagnostics if we have a schema
 	if parsed != nil && ctx != nil && ctx.Schema != nil {
 		diags = append(diags, d.semanticDiagnostics(query, parsed, ctx)...)
 	}
 
+	// Assertions only check the first row an assert query returns
+	if parsed != nil && ctx != nil && ctx.AssertQuery {
+		if schema, ok := ctx.Schema.(*analysis.TypeSchema); ok && schema != nil {
+			diags = append(diags, cardinalityDiagnostics(query, parsed, schema)...)
+		}
+	}
+
 	return diags
 }
 
@@ -1012,7 +1054,7 @@ func (d *Dialect) semanticDiagnostics(query string, parsed *cyphergrammar.Script
 	for label, pos := range labelsUsed {
 		if !knownLabels[label] {
 			diags = append(diags, scaf.QueryDiagnostic{
-				Range:    scaf.QueryRange{Start: pos.start, End: pos.end},
+				Range:    scaf.NewQueryDiagnosticRange(query, pos.start, pos.end),
 				Severity: scaf.QueryDiagnosticWarning,
 				Message:  fmt.Sprintf("Unknown node label: %s", label),
 				Code:     "unknown-label",
@@ -1024,7 +1066,7 @@ func (d *Dialect) semanticDiagnostics(query string, parsed *cyphergrammar.Script
 	for relType, pos := range relTypesUsed {
 		if !knownRelTypes[relType] {
 			diags = append(diags, scaf.QueryDiagnostic{
-				Range:    scaf.QueryRange{Start: pos.start, End: pos.end},
+				Range:    scaf.NewQueryDiagnosticRange(query, pos.start, pos.end),
 				Severity: scaf.QueryDiagnosticWarning,
 				Message:  fmt.Sprintf("Unknown relationship type: %s", relType),
 				Code:     "unknown-reltype",
@@ -1032,124 +1074,678 @@ func (d *Dialect) semanticDiagnostics(query string, parsed *cyphergrammar.Script
 		}
 	}
 
-	// Check for type mismatches in property comparisons
-	diags = append(diags, d.typeMismatchDiagnostics(parsed, schema)...)
+	// Check for unknown properties and type mismatches in property maps
+	diags = append(diags, d.propertyDiagnostics(parsed, schema)...)
+	diags = append(diags, indexDiagnostics(parsed, schema)...)
 
 	return diags
 }
 
-// typeMismatchDiagnostics checks for type mismatches in property comparisons.
-// For example: (u:User {name: false}) where name is a string field.
-func (d *Dialect) typeMismatchDiagnostics(parsed *cyphergrammar.Script, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+// forEachDiagnostics warns about clauses inside a FOREACH body that aren't
+// CREATE, MERGE, SET, DELETE, REMOVE, or a nested FOREACH.
+func forEachDiagnostics(parsed *cyphergrammar.Script) []scaf.QueryDiagnostic {
 	var diags []scaf.QueryDiagnostic
 
-	if parsed == nil || parsed.Query == nil {
-		return diags
-	}
+	var walkClauses func(clauses []*cyphergrammar.Clause, inForEach bool)
+	walkClauses = func(clauses []*cyphergrammar.Clause, inForEach bool) {
+		for _, clause := range clauses {
+			if clause == nil {
+				continue
+			}
 
-	if rq := parsed.Query.RegularQuery; rq != nil && rq.SingleQuery != nil {
-		for _, clause := range rq.SingleQuery.Clauses {
-			diags = append(diags, d.checkClauseTypeMismatches(clause, schema)...)
+			if inForEach && clause.Updating == nil {
+				keyword := clauseKeyword(clause)
+				diags = append(diags, scaf.QueryDiagnostic{
+					Range:    textRange(clause.Pos, keyword),
+					Severity: scaf.QueryDiagnosticWarning,
+					Message:  fmt.Sprintf("%s is not allowed inside FOREACH; use CREATE, MERGE, SET, DELETE, REMOVE, or FOREACH", keyword),
+					Code:     "invalid-foreach-clause",
+				})
+			}
+
+			if clause.Updating != nil && clause.Updating.ForEach != nil {
+				walkClauses(clause.Updating.ForEach.Updates, true)
+			}
+
+			if call := clause.CallSubquery; call != nil && call.Body != nil {
+				walkQueryClauses(call.Body, func(inner []*cyphergrammar.Clause) { walkClauses(inner, false) })
+			}
 		}
 	}
 
+	walkQueryClauses(parsed, func(clauses []*cyphergrammar.Clause) { walkClauses(clauses, false) })
+
 	return diags
 }
 
-func (d *Dialect) checkClauseTypeMismatches(clause *cyphergrammar.Clause, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+// subqueryDiagnostics reports updating clauses inside EXISTS { ... } and
+// COUNT { ... } subqueries, which Neo4j only allows to read.
+func subqueryDiagnostics(parsed *cyphergrammar.Script) []scaf.QueryDiagnostic {
 	var diags []scaf.QueryDiagnostic
 
-	if clause == nil {
-		return diags
-	}
+	cyphergrammar.Inspect(parsed, func(node any) bool {
+		atom, ok := node.(*cyphergrammar.Atom)
+		if !ok || atom.Subquery() == nil {
+			return true
+		}
 
-	// Check MATCH patterns
-	if clause.Reading != nil && clause.Reading.Match != nil && clause.Reading.Match.Pattern != nil {
-		diags = append(diags, d.checkPatternTypeMismatches(clause.Reading.Match.Pattern, schema)...)
-	}
+		kind := "EXISTS"
+		if atom.CountSubquery != nil {
+			kind = "COUNT"
+		}
 
-	// Check CREATE patterns
-	if clause.Updating != nil && clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
-		diags = append(diags, d.checkPatternTypeMismatches(clause.Updating.Create.Pattern, schema)...)
-	}
+		cyphergrammar.Inspect(atom.Subquery(), func(inner any) bool {
+			if nested, ok := inner.(*cyphergrammar.Atom); ok && nested.Subquery() != nil {
+				return false // Reported when the outer walk reaches it
+			}
 
-	// Check MERGE patterns
-	if clause.Updating != nil && clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
-		diags = append(diags, d.checkPatternElementTypeMismatches(clause.Updating.Merge.Pattern.Element, schema)...)
-	}
+			clause, ok := inner.(*cyphergrammar.Clause)
+			if !ok || clause.Updating == nil {
+				return true
+			}
+
+			keyword := clauseKeyword(clause)
+			diags = append(diags, scaf.QueryDiagnostic{
+				Range:    textRange(clause.Pos, keyword),
+				Severity: scaf.QueryDiagnosticError,
+				Message:  fmt.Sprintf("%s is not allowed inside %s { ... }; the subquery must be read-only", keyword, kind),
+				Code:     "write-in-subquery",
+			})
+
+			return false
+		})
+
+		return true
+	})
 
 	return diags
 }
 
-func (d *Dialect) checkPatternTypeMismatches(pattern *cyphergrammar.Pattern, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+// mergeDiagnostics hints at MERGE clauses with no ON CREATE SET or
+// ON MATCH SET action, where properties that only a new node should get
+// are often forgotten.
+func mergeDiagnostics(parsed *cyphergrammar.Script) []scaf.QueryDiagnostic {
 	var diags []scaf.QueryDiagnostic
 
-	if pattern == nil {
-		return diags
-	}
-
-	for _, part := range pattern.Parts {
-		if part != nil && part.Element != nil {
-			diags = append(diags, d.checkPatternElementTypeMismatches(part.Element, schema)...)
+	cyphergrammar.Inspect(parsed, func(node any) bool {
+		merge, ok := node.(*cyphergrammar.MergeClause)
+		if !ok || len(merge.Actions) > 0 {
+			return true
 		}
-	}
+
+		diags = append(diags, scaf.QueryDiagnostic{
+			Range:    textRange(merge.Pos, "MERGE"),
+			Severity: scaf.QueryDiagnosticHint,
+			Message:  "MERGE has no ON CREATE SET or ON MATCH SET; properties a created node needs may be missing",
+			Code:     "merge-without-on-create-set",
+		})
+
+		return true
+	})
 
 	return diags
 }
 
-func (d *Dialect) checkPatternElementTypeMismatches(elem *cyphergrammar.PatternElement, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+// nullableDiagnostics reports properties of a variable bound by OPTIONAL
+// MATCH that a later WHERE accesses without checking the variable IS NOT NULL.
+// The comparison is null rather than false when nothing matched, which is
+// easy to miss. A WHERE that checks the variable for null anywhere is assumed
+// to guard its accesses.
+func nullableDiagnostics(parsed *cyphergrammar.Script) []scaf.QueryDiagnostic {
 	var diags []scaf.QueryDiagnostic
 
-	if elem == nil {
-		return diags
-	}
+	check := func(where *cyphergrammar.Where, nullable map[string]bool) {
+		checked := make(map[string]bool)
 
-	// Handle parenthesized pattern
-	if elem.Paren != nil {
-		return d.checkPatternElementTypeMismatches(elem.Paren, schema)
-	}
+		cyphergrammar.Inspect(where, func(node any) bool {
+			post, ok := node.(*cyphergrammar.PostfixExpr)
+			if ok && post.Atom != nil && len(post.Suffixes) > 0 && post.Suffixes[0].IsNull != nil {
+				checked[post.Atom.Variable] = true
+			}
 
-	// Check node pattern
-	if elem.Node != nil {
-		diags = append(diags, d.checkNodePatternTypeMismatches(elem.Node, schema)...)
+			return true
+		})
+
+		cyphergrammar.Inspect(where, func(node any) bool {
+			post, ok := node.(*cyphergrammar.PostfixExpr)
+			if !ok || post.Atom == nil || len(post.Suffixes) == 0 || post.Suffixes[0].Property == "" {
+				return true
+			}
+
+			name := post.Atom.Variable
+			if !nullable[name] || checked[name] {
+				return true
+			}
+
+			diags = append(diags, scaf.QueryDiagnostic{
+				Range:    textRange(post.Atom.Pos, name),
+				Severity: scaf.QueryDiagnosticWarning,
+				Message:  fmt.Sprintf("%s may be null because it is bound by OPTIONAL MATCH; check %s IS NOT NULL before accessing its properties", name, name),
+				Code:     "nullable-variable",
+			})
+
+			return true
+		})
 	}
 
-	// Check chain elements
-	for _, chain := range elem.Chain {
-		if chain.Node != nil {
-			diags = append(diags, d.checkNodePatternTypeMismatches(chain.Node, schema)...)
+	cyphergrammar.Inspect(parsed, func(node any) bool {
+		if sq, ok := node.(*cyphergrammar.SingleQuery); ok {
+			nullableVariables(sq.Clauses, check)
 		}
-	}
+
+		return true
+	})
 
 	return diags
 }
 
-func (d *Dialect) checkNodePatternTypeMismatches(node *cyphergrammar.NodePattern, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+// inlineWhereDiagnostics reports inline WHERE predicates, as in
+// (a WHERE a.x = b.x)-->(b), that reference a variable declared by another
+// node or relationship of the same pattern. Neo4j rejects these because the
+// predicate is evaluated before the rest of the pattern is matched. Variables
+// bound by earlier clauses are allowed.
+func inlineWhereDiagnostics(parsed *cyphergrammar.Script) []scaf.QueryDiagnostic {
 	var diags []scaf.QueryDiagnostic
 
-	if node == nil || node.Properties == nil || node.Properties.Map == nil {
-		return diags
-	}
+	check := func(pattern any) {
+		declared := make(map[string]bool)
+		declare := func(name string) {
+			if name != "" {
+				declared[name] = true
+			}
+		}
 
-	// Get the labels for this node
-	var labels []string
-	if node.Labels != nil {
-		labels = node.Labels.Labels
-	}
+		cyphergrammar.Inspect(pattern, func(node any) bool {
+			switch n := node.(type) {
+			case *cyphergrammar.PatternPart:
+				declare(n.Var)
+			case *cyphergrammar.NodePattern:
+				declare(n.Variable)
+			case *cyphergrammar.RelationshipDetail:
+				declare(n.Variable)
+			case *cyphergrammar.Expression:
+				return false // Variables inside predicates are references
+			}
 
-	if len(labels) == 0 {
-		return diags // No labels to check against
-	}
+			return true
+		})
 
-	// Check each property in the map
-	for _, pair := range node.Properties.Map.Pairs {
-		if pair == nil || pair.Value == nil {
-			continue
-		}
+		report := func(where *cyphergrammar.Expression, own string) {
+			if where == nil {
+				return
+			}
 
-		propName := pair.Key
-		expectedType := d.lookupPropertyType(propName, labels, schema)
-		if expectedType == nil {
+			cyphergrammar.Inspect(where, func(node any) bool {
+				atom, ok := node.(*cyphergrammar.Atom)
+				if !ok || atom.Variable == "" || atom.Variable == own || !declared[atom.Variable] {
+					return true
+				}
+
+				diags = append(diags, scaf.QueryDiagnostic{
+					Range:    textRange(atom.Pos, atom.Variable),
+					Severity: scaf.QueryDiagnosticError,
+					Message:  fmt.Sprintf("inline WHERE cannot reference %s, which is declared elsewhere in the same pattern; move the condition to a WHERE clause", atom.Variable),
+					Code:     "invalid-inline-where",
+				})
+
+				return true
+			})
+		}
+
+		cyphergrammar.Inspect(pattern, func(node any) bool {
+			switch n := node.(type) {
+			case *cyphergrammar.NodePattern:
+				report(n.InlineWhere, n.Variable)
+			case *cyphergrammar.RelationshipDetail:
+				report(n.InlineWhere, n.Variable)
+			case *cyphergrammar.Expression:
+				return false
+			}
+
+			return true
+		})
+	}
+
+	cyphergrammar.Inspect(parsed, func(node any) bool {
+		switch n := node.(type) {
+		case *cyphergrammar.Pattern, *cyphergrammar.RelationshipChainPattern:
+			check(n)
+		case *cyphergrammar.MergeClause:
+			check(n.Pattern)
+		}
+
+		return true
+	})
+
+	return diags
+}
+
+// cardinalityDiagnostics warns about MATCH patterns that follow a relationship
+// with cardinality many, so the query may return several rows, unless its
+// RETURN aggregates everything or is limited to one row.
+func cardinalityDiagnostics(query string, parsed *cyphergrammar.Script, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	walkQueryClauses(parsed, func(clauses []*cyphergrammar.Clause) {
+		var ret *cyphergrammar.ReturnClause
+		for _, clause := range clauses {
+			if clause.Return != nil {
+				ret = clause.Return
+			}
+		}
+
+		if ret == nil || returnsSingleRow(ret) {
+			return
+		}
+
+		for _, clause := range clauses {
+			if clause.Reading == nil || clause.Reading.Match == nil {
+				continue
+			}
+
+			cyphergrammar.Inspect(clause.Reading.Match.Pattern, func(node any) bool {
+				elem, ok := node.(*cyphergrammar.PatternElement)
+				if !ok {
+					return true
+				}
+
+				source := elem.Node
+				for _, chain := range elem.Chain {
+					if rel := manyRelationship(schema, source, chain.Rel); rel != "" {
+						start := chain.Rel.Pos.Offset
+						if i := strings.Index(query[start:], rel); i >= 0 {
+							start += i
+						}
+
+						diags = append(diags, scaf.QueryDiagnostic{
+							Range:    scaf.NewQueryDiagnosticRange(query, start, start+len(rel)),
+							Severity: scaf.QueryDiagnosticWarning,
+							Message:  fmt.Sprintf("%s has cardinality many, so this query may return several rows, but assertions only check the first", rel),
+							Code:     "cardinality-mismatch",
+						})
+					}
+
+					source = chain.Node
+				}
+
+				return true
+			})
+		}
+	})
+
+	return diags
+}
+
+// returnsSingleRow reports whether a RETURN clause yields at most one row:
+// it is LIMIT 1 or every item is an aggregate.
+func returnsSingleRow(ret *cyphergrammar.ReturnClause) bool {
+	if ret.Body == nil || ret.Body.Items == nil {
+		return false
+	}
+
+	if limit := ret.Body.Limit; limit != nil {
+		one := false
+		cyphergrammar.Inspect(limit.Expr, func(node any) bool {
+			if lit, ok := node.(*cyphergrammar.Literal); ok && lit.Int != nil && *lit.Int <= 1 {
+				one = true
+			}
+
+			return true
+		})
+
+		if one {
+			return true
+		}
+	}
+
+	return aggregatesOnly(ret.Body)
+}
+
+// aggregatesOnly reports whether every item body projects is an aggregate,
+// with no grouping keys, so it projects a single row.
+func aggregatesOnly(body *cyphergrammar.ProjectionBody) bool {
+	items := body.Items
+	if items == nil || items.Star || len(items.Items) == 0 {
+		return false
+	}
+
+	for _, item := range items.Items {
+		if !isAggregateExpression(item.Expr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// manyRelationship returns the relationship type rel follows from source when
+// the schema gives it cardinality many, or "" otherwise. Undirected patterns
+// and unlabelled sources are skipped.
+func manyRelationship(schema *analysis.TypeSchema, source *cyphergrammar.NodePattern, rel *cyphergrammar.RelationshipPattern) string {
+	if source == nil || source.Labels == nil || rel == nil || rel.Detail == nil || rel.Detail.Types == nil {
+		return ""
+	}
+
+	var dir analysis.Direction
+
+	switch {
+	case rel.RightArrow && !rel.LeftArrow:
+		dir = analysis.DirectionOutgoing
+	case rel.LeftArrow && !rel.RightArrow:
+		dir = analysis.DirectionIncoming
+	default:
+		return ""
+	}
+
+	for _, label := range source.Labels.Labels {
+		model, ok := schema.Models[label]
+		if !ok {
+			continue
+		}
+
+		for _, r := range model.Relationships {
+			if r.Direction == dir && r.Cardinality == analysis.CardinalityMany && slices.Contains(rel.Detail.Types.Types, r.RelType) {
+				return r.RelType
+			}
+		}
+	}
+
+	return ""
+}
+
+// walkQueryClauses calls fn with the clauses of each single query in a script,
+// including the parts of a UNION.
+func walkQueryClauses(script *cyphergrammar.Script, fn func([]*cyphergrammar.Clause)) {
+	if script == nil || script.Query == nil || script.Query.RegularQuery == nil {
+		return
+	}
+
+	rq := script.Query.RegularQuery
+	if rq.SingleQuery != nil {
+		fn(rq.SingleQuery.Clauses)
+	}
+
+	for _, union := range rq.Unions {
+		if union.Query != nil {
+			fn(union.Query.Clauses)
+		}
+	}
+}
+
+// clauseKeyword returns the leading keyword of a clause, e.g. "MATCH" or "RETURN".
+func clauseKeyword(clause *cyphergrammar.Clause) string {
+	switch {
+	case clause.Reading != nil && clause.Reading.Match != nil && clause.Reading.Match.Optional:
+		return "OPTIONAL MATCH"
+	case clause.Reading != nil && clause.Reading.Match != nil:
+		return "MATCH"
+	case clause.Reading != nil && clause.Reading.Unwind != nil:
+		return "UNWIND"
+	case clause.Reading != nil, clause.CallSubquery != nil:
+		return "CALL"
+	case clause.With != nil:
+		return "WITH"
+	case clause.Return != nil:
+		return "RETURN"
+	case clause.Updating != nil && clause.Updating.Create != nil:
+		return "CREATE"
+	case clause.Updating != nil && clause.Updating.Merge != nil:
+		return "MERGE"
+	case clause.Updating != nil && clause.Updating.Delete != nil && clause.Updating.Delete.Detach:
+		return "DETACH DELETE"
+	case clause.Updating != nil && clause.Updating.Delete != nil:
+		return "DELETE"
+	case clause.Updating != nil && clause.Updating.Set != nil:
+		return "SET"
+	case clause.Updating != nil && clause.Updating.Remove != nil:
+		return "REMOVE"
+	case clause.Updating != nil && clause.Updating.ForEach != nil:
+		return "FOREACH"
+	default:
+		return "clause"
+	}
+}
+
+// indexDiagnostics hints at WHERE equality predicates, as in
+// MATCH (u:User) WHERE u.email = $email, on a field of the matched node that
+// has no index or uniqueness constraint, so finding the node scans its label.
+// Schemas that don't record any indexes get no hints, since their fields
+// would all look unindexed.
+func indexDiagnostics(parsed *cyphergrammar.Script, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	if !schemaHasIndexes(schema) {
+		return diags
+	}
+
+	walkQueryClauses(parsed, func(clauses []*cyphergrammar.Clause) {
+		for _, clause := range clauses {
+			if clause.Reading == nil || clause.Reading.Match == nil || clause.Reading.Match.Where == nil {
+				continue
+			}
+
+			match := clause.Reading.Match
+
+			// Only nodes of this MATCH are looked up by its WHERE
+			labels := make(map[string][]string)
+
+			cyphergrammar.Inspect(match.Pattern, func(node any) bool {
+				switch n := node.(type) {
+				case *cyphergrammar.Expression:
+					return false
+				case *cyphergrammar.NodePattern:
+					if n.Variable != "" && n.Labels != nil {
+						labels[n.Variable] = append(labels[n.Variable], n.Labels.Labels...)
+					}
+				}
+
+				return true
+			})
+
+			check := func(add *cyphergrammar.AddSubExpr) {
+				post := barePostfix(add)
+				if post == nil || post.Atom == nil || len(post.Suffixes) != 1 || post.Suffixes[0].Property == "" {
+					return
+				}
+
+				variable, property := post.Atom.Variable, post.Suffixes[0].Property
+
+				label, ok := unindexedField(labels[variable], property, schema)
+				if !ok {
+					return
+				}
+
+				diags = append(diags, scaf.QueryDiagnostic{
+					Range:    textRange(post.Pos, variable+"."+property),
+					Severity: scaf.QueryDiagnosticHint,
+					Message:  fmt.Sprintf("%s.%s has no index, so this lookup scans every %s node; add an index or uniqueness constraint", label, property, label),
+					Code:     "no-index-hint",
+				})
+			}
+
+			cyphergrammar.Inspect(match.Where, func(node any) bool {
+				comp, ok := node.(*cyphergrammar.ComparisonExpr)
+				if !ok || len(comp.Right) != 1 || comp.Right[0].Op != "=" {
+					return true
+				}
+
+				check(comp.Left)
+				check(comp.Right[0].Expr)
+
+				return true
+			})
+		}
+	})
+
+	return diags
+}
+
+// schemaHasIndexes reports whether any field in the schema is indexed.
+func schemaHasIndexes(schema *analysis.TypeSchema) bool {
+	for _, model := range schema.Models {
+		for _, field := range model.Fields {
+			if field.Indexed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// unindexedField returns the first of labels that has a property field, if
+// none of them index it or make it unique. Labels missing from the schema
+// are ignored.
+func unindexedField(labels []string, property string, schema *analysis.TypeSchema) (string, bool) {
+	var found string
+
+	for _, label := range labels {
+		model, ok := schema.Models[label]
+		if !ok {
+			continue
+		}
+
+		for _, field := range model.Fields {
+			if field.Name != property {
+				continue
+			}
+
+			if field.Indexed || field.Unique {
+				return "", false
+			}
+
+			if found == "" {
+				found = label
+			}
+		}
+	}
+
+	return found, found != ""
+}
+
+// propertyDiagnostics checks node property maps against the schema.
+// It reports property names not defined on any of the node's labels, e.g. (u:User {naem: $name}),
+// and type mismatches, e.g. (u:User {name: false}) where name is a string field.
+func (d *Dialect) propertyDiagnostics(parsed *cyphergrammar.Script, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	if parsed == nil || parsed.Query == nil {
+		return diags
+	}
+
+	if rq := parsed.Query.RegularQuery; rq != nil && rq.SingleQuery != nil {
+		for _, clause := range rq.SingleQuery.Clauses {
+			diags = append(diags, d.checkClauseProperties(clause, schema)...)
+		}
+	}
+
+	return diags
+}
+
+func (d *Dialect) checkClauseProperties(clause *cyphergrammar.Clause, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	if clause == nil {
+		return diags
+	}
+
+	// Check MATCH patterns
+	if clause.Reading != nil && clause.Reading.Match != nil && clause.Reading.Match.Pattern != nil {
+		diags = append(diags, d.checkPatternProperties(clause.Reading.Match.Pattern, schema)...)
+	}
+
+	// Check CREATE patterns
+	if clause.Updating != nil && clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
+		diags = append(diags, d.checkPatternProperties(clause.Updating.Create.Pattern, schema)...)
+	}
+
+	// Check MERGE patterns
+	if clause.Updating != nil && clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
+		diags = append(diags, d.checkPatternElementProperties(clause.Updating.Merge.Pattern.Element, schema)...)
+	}
+
+	return diags
+}
+
+func (d *Dialect) checkPatternProperties(pattern *cyphergrammar.Pattern, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	if pattern == nil {
+		return diags
+	}
+
+	for _, part := range pattern.Parts {
+		if part != nil && part.PatternElement() != nil {
+			diags = append(diags, d.checkPatternElementProperties(part.PatternElement(), schema)...)
+		}
+	}
+
+	return diags
+}
+
+func (d *Dialect) checkPatternElementProperties(elem *cyphergrammar.PatternElement, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	if elem == nil {
+		return diags
+	}
+
+	// Handle parenthesized pattern
+	if elem.Paren != nil {
+		return d.checkPatternElementProperties(elem.Paren, schema)
+	}
+
+	// Check node pattern
+	if elem.Node != nil {
+		diags = append(diags, d.checkNodePatternProperties(elem.Node, schema)...)
+	}
+
+	// Check chain elements
+	for _, chain := range elem.Chain {
+		if chain.Node != nil {
+			diags = append(diags, d.checkNodePatternProperties(chain.Node, schema)...)
+		}
+	}
+
+	return diags
+}
+
+func (d *Dialect) checkNodePatternProperties(node *cyphergrammar.NodePattern, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	if node == nil || node.Properties == nil || node.Properties.Map == nil {
+		return diags
+	}
+
+	// Get the labels for this node
+	var labels []string
+	if node.Labels != nil {
+		labels = node.Labels.Labels
+	}
+
+	if len(labels) == 0 {
+		return diags // No labels to check against
+	}
+
+	// Check each property in the map
+	for _, pair := range node.Properties.Map.Pairs {
+		if pair == nil || pair.Value == nil {
+			continue
+		}
+
+		propName := pair.Key
+		if !d.propertyKnown(propName, labels, schema) {
+			diags = append(diags, scaf.QueryDiagnostic{
+				Range:    textRange(pair.Pos, propName),
+				Severity: scaf.QueryDiagnosticWarning,
+				Message:  fmt.Sprintf("Unknown property '%s' on %s", propName, strings.Join(labels, ":")),
+				Code:     "unknown-property",
+			})
+			continue
+		}
+
+		expectedType := lookupPropertyType(propName, labels, schema)
+		if expectedType == nil {
 			continue // Unknown property, skip
 		}
 
@@ -1160,7 +1756,7 @@ func (d *Dialect) checkNodePatternTypeMismatches(node *cyphergrammar.NodePattern
 
 		if !d.typesCompatible(expectedType, actualType) {
 			diags = append(diags, scaf.QueryDiagnostic{
-				Range:    scaf.QueryRange{Start: pair.Value.Pos.Offset, End: d.expressionEndOffset(pair.Value)},
+				Range:    scaf.QueryDiagnosticRange{StartPos: pair.Value.Pos, EndPos: pair.Value.EndPos},
 				Severity: scaf.QueryDiagnosticWarning,
 				Message:  fmt.Sprintf("Type mismatch: property '%s' expects %s, got %s", propName, expectedType.Name, actualType),
 				Code:     "type-mismatch",
@@ -1171,13 +1767,44 @@ func (d *Dialect) checkNodePatternTypeMismatches(node *cyphergrammar.NodePattern
 	return diags
 }
 
+// propertyKnown reports whether propName is a field of any of the labels.
+// Labels missing from the schema are ignored (they get an unknown-label warning instead),
+// so a node whose labels are all unknown accepts every property.
+func (d *Dialect) propertyKnown(propName string, labels []string, schema *analysis.TypeSchema) bool {
+	anyModel := false
+	for _, label := range labels {
+		model, ok := schema.Models[label]
+		if !ok {
+			continue
+		}
+		anyModel = true
+		for _, field := range model.Fields {
+			if field.Name == propName {
+				return true
+			}
+		}
+	}
+	return !anyModel
+}
+
 // lookupPropertyType finds the type of a property from the schema.
-func (d *Dialect) lookupPropertyType(propName string, labels []string, schema *analysis.TypeSchema) *analysis.Type {
+func lookupPropertyType(propName string, labels []string, schema *analysis.TypeSchema) *analysis.Type {
+	if field := lookupProperty(propName, labels, schema); field != nil {
+		return field.Type
+	}
+	return nil
+}
+
+// lookupProperty finds a property on the first of labels' models that declares it.
+func lookupProperty(propName string, labels []string, schema *analysis.TypeSchema) *analysis.Field {
+	if schema == nil {
+		return nil
+	}
 	for _, label := range labels {
 		if model, ok := schema.Models[label]; ok {
 			for _, field := range model.Fields {
-				if field.Name == propName && field.Type != nil {
-					return field.Type
+				if field.Name == propName {
+					return field
 				}
 			}
 		}
@@ -1193,7 +1820,7 @@ func (d *Dialect) inferLiteralType(expr *cyphergrammar.Expression) string {
 	}
 
 	// Navigate through the expression tree to find a literal
-	atom := d.extractAtomFromExpression(expr)
+	atom := extractAtomFromExpression(expr)
 	if atom == nil {
 		return ""
 	}
@@ -1233,7 +1860,7 @@ func (d *Dialect) inferLiteralType(expr *cyphergrammar.Expression) string {
 }
 
 // extractAtomFromExpression navigates the expression tree to find the atom.
-func (d *Dialect) extractAtomFromExpression(expr *cyphergrammar.Expression) *cyphergrammar.Atom {
+func extractAtomFromExpression(expr *cyphergrammar.Expression) *cyphergrammar.Atom {
 	if expr == nil || expr.Left == nil {
 		return nil
 	}
@@ -1306,38 +1933,13 @@ func (d *Dialect) typesCompatible(expected *analysis.Type, actual string) bool {
 	}
 }
 
-// expressionEndOffset calculates the end offset of an expression.
-func (d *Dialect) expressionEndOffset(expr *cyphergrammar.Expression) int {
-	if expr == nil {
-		return 0
-	}
+// textRange returns the range of text starting at pos, such as a keyword or
+// variable name at a node's position.
+func textRange(pos lexer.Position, text string) scaf.QueryDiagnosticRange {
+	end := pos
+	end.Advance(text)
 
-	// For simple literals, estimate based on type
-	atom := d.extractAtomFromExpression(expr)
-	if atom != nil && atom.Literal != nil {
-		lit := atom.Literal
-		if lit.True {
-			return expr.Pos.Offset + 4 // "true"
-		}
-		if lit.False {
-			return expr.Pos.Offset + 5 // "false"
-		}
-		if lit.Null {
-			return expr.Pos.Offset + 4 // "null"
-		}
-		if lit.Int != nil {
-			return expr.Pos.Offset + len(fmt.Sprintf("%d", *lit.Int))
-		}
-		if lit.Float != nil {
-			return expr.Pos.Offset + len(fmt.Sprintf("%g", *lit.Float))
-		}
-		if lit.String != nil {
-			return expr.Pos.Offset + len(*lit.String) + 2 // include quotes
-		}
-	}
-
-	// Fallback: use position + some reasonable length
-	return expr.Pos.Offset + 5
+	return scaf.QueryDiagnosticRange{StartPos: pos, EndPos: end}
 }
 
 type labelPos struct {
@@ -1345,74 +1947,23 @@ type labelPos struct {
 	end   int
 }
 
+// extractLabelsFromAST returns the position of each node label in the
+// query's patterns, including those in subqueries.
 func (d *Dialect) extractLabelsFromAST(script *cyphergrammar.Script) map[string]labelPos {
 	labels := make(map[string]labelPos)
 
-	if script == nil || script.Query == nil {
+	if script == nil {
 		return labels
 	}
 
-	if rq := script.Query.RegularQuery; rq != nil && rq.SingleQuery != nil {
-		for _, clause := range rq.SingleQuery.Clauses {
-			d.extractLabelsFromClause(clause, labels)
-		}
-	}
-
-	return labels
-}
-
-func (d *Dialect) extractLabelsFromClause(clause *cyphergrammar.Clause, labels map[string]labelPos) {
-	if clause == nil {
-		return
-	}
-
-	if clause.Reading != nil {
-		if clause.Reading.Match != nil && clause.Reading.Match.Pattern != nil {
-			d.extractLabelsFromPattern(clause.Reading.Match.Pattern, labels)
-		}
-	}
-
-	if clause.Updating != nil {
-		if clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
-			d.extractLabelsFromPattern(clause.Updating.Create.Pattern, labels)
-		}
-		if clause.Updating.Merge != nil && clause.Updating.Merge.Pattern != nil {
-			d.extractLabelsFromPatternPart(clause.Updating.Merge.Pattern, labels)
+	script.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		n, ok := node.(*cyphergrammar.NodePattern)
+		if !ok || n.Labels == nil {
+			return true
 		}
-	}
-}
 
-func (d *Dialect) extractLabelsFromPattern(pattern *cyphergrammar.Pattern, labels map[string]labelPos) {
-	if pattern == nil {
-		return
-	}
-
-	for _, part := range pattern.Parts {
-		d.extractLabelsFromPatternPart(part, labels)
-	}
-}
-
-func (d *Dialect) extractLabelsFromPatternPart(part *cyphergrammar.PatternPart, labels map[string]labelPos) {
-	if part == nil || part.Element == nil {
-		return
-	}
-
-	d.extractLabelsFromPatternElement(part.Element, labels)
-}
-
-func (d *Dialect) extractLabelsFromPatternElement(elem *cyphergrammar.PatternElement, labels map[string]labelPos) {
-	if elem == nil {
-		return
-	}
-
-	if elem.Paren != nil {
-		d.extractLabelsFromPatternElement(elem.Paren, labels)
-		return
-	}
-
-	if elem.Node != nil && elem.Node.Labels != nil {
-		currentOffset := elem.Node.Labels.Pos.Offset + 1
-		for _, label := range elem.Node.Labels.Labels {
+		currentOffset := n.Labels.Pos.Offset + 1
+		for _, label := range n.Labels.Labels {
 			if label != "" {
 				labels[label] = labelPos{
 					start: currentOffset,
@@ -1421,197 +1972,195 @@ func (d *Dialect) extractLabelsFromPatternElement(elem *cyphergrammar.PatternEle
 			}
 			currentOffset += len(label) + 1
 		}
-	}
 
-	for _, chain := range elem.Chain {
-		if chain.Node != nil && chain.Node.Labels != nil {
-			currentOffset := chain.Node.Labels.Pos.Offset + 1
-			for _, label := range chain.Node.Labels.Labels {
-				if label != "" {
-					labels[label] = labelPos{
-						start: currentOffset,
-						end:   currentOffset + len(label),
-					}
-				}
-				currentOffset += len(label) + 1
-			}
-		}
-	}
+		return true
+	}))
+
+	return labels
 }
 
+// extractRelTypesFromAST returns the position of each relationship type in
+// the query's patterns, including those in subqueries.
 func (d *Dialect) extractRelTypesFromAST(script *cyphergrammar.Script) map[string]labelPos {
 	relTypes := make(map[string]labelPos)
 
-	if script == nil || script.Query == nil {
+	if script == nil {
 		return relTypes
 	}
 
-	if rq := script.Query.RegularQuery; rq != nil && rq.SingleQuery != nil {
-		for _, clause := range rq.SingleQuery.Clauses {
-			d.extractRelTypesFromClause(clause, relTypes)
+	script.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		detail, ok := node.(*cyphergrammar.RelationshipDetail)
+		if !ok || detail.Types == nil {
+			return true
+		}
+
+		currentOffset := detail.Types.Pos.Offset + 1
+		for i, relType := range detail.Types.Types {
+			if relType != "" {
+				relTypes[relType] = labelPos{
+					start: currentOffset,
+					end:   currentOffset + len(relType),
+				}
+			}
+			if i < len(detail.Types.Types)-1 {
+				currentOffset += len(relType) + 1
+			}
 		}
-	}
+
+		return true
+	}))
 
 	return relTypes
 }
 
-func (d *Dialect) extractRelTypesFromClause(clause *cyphergrammar.Clause, relTypes map[string]labelPos) {
-	if clause == nil {
-		return
+// SignatureHelp returns signature help for function calls, with every
+// overload of the innermost function around offset and the one matching the
+// arguments typed so far active.
+func (d *Dialect) SignatureHelp(query string, offset int, ctx *scaf.QueryLSPContext) *scaf.QuerySignatureHelp {
+	// Find if we're inside a function call
+	funcName, paramIdx := d.findFunctionContext(query, offset)
+	if funcName == "" {
+		return nil
 	}
 
-	if clause.Reading != nil {
-		if clause.Reading.Match != nil && clause.Reading.Match.Pattern != nil {
-			d.extractRelTypesFromPattern(clause.Reading.Match.Pattern, relTypes)
-		}
+	overloads := cypherFunctionSignatures[funcName]
+	if len(overloads) == 0 {
+		return nil
 	}
 
-	if clause.Updating != nil {
-		if clause.Updating.Create != nil && clause.Updating.Create.Pattern != nil {
-			d.extractRelTypesFromPattern(clause.Updating.Create.Pattern, relTypes)
-		}
+	help := &scaf.QuerySignatureHelp{
+		Signatures:      make([]scaf.QuerySignature, len(overloads)),
+		ActiveSignature: -1,
+		ActiveParameter: paramIdx,
 	}
-}
 
-func (d *Dialect) extractRelTypesFromPattern(pattern *cyphergrammar.Pattern, relTypes map[string]labelPos) {
-	if pattern == nil {
-		return
-	}
+	for i, sig := range overloads {
+		help.Signatures[i] = d.getFunctionSignature(sig)
 
-	for _, part := range pattern.Parts {
-		if part == nil || part.Element == nil {
-			continue
+		if help.ActiveSignature < 0 && acceptsArgument(sig, paramIdx) {
+			help.ActiveSignature = i
 		}
-		d.extractRelTypesFromPatternElement(part.Element, relTypes)
 	}
-}
 
-func (d *Dialect) extractRelTypesFromPatternElement(elem *cyphergrammar.PatternElement, relTypes map[string]labelPos) {
-	if elem == nil {
-		return
+	if help.ActiveSignature < 0 {
+		help.ActiveSignature = len(overloads) - 1
 	}
 
-	if elem.Paren != nil {
-		d.extractRelTypesFromPatternElement(elem.Paren, relTypes)
-		return
+	// Every argument past a variadic parameter belongs to it
+	if params := overloads[help.ActiveSignature].Params; len(params) > 0 && paramIdx >= len(params) && params[len(params)-1].variadic() {
+		help.ActiveParameter = len(params) - 1
 	}
 
-	for _, chain := range elem.Chain {
-		if chain.Rel != nil && chain.Rel.Detail != nil && chain.Rel.Detail.Types != nil {
-			currentOffset := chain.Rel.Detail.Types.Pos.Offset + 1
-			for i, relType := range chain.Rel.Detail.Types.Types {
-				if relType != "" {
-					relTypes[relType] = labelPos{
-						start: currentOffset,
-						end:   currentOffset + len(relType),
-					}
-				}
-				if i < len(chain.Rel.Detail.Types.Types)-1 {
-					currentOffset += len(relType) + 1
-				}
-			}
-		}
-	}
+	return help
 }
 
-// SignatureHelp returns signature help for function calls.
-func (d *Dialect) SignatureHelp(query string, offset int, ctx *scaf.QueryLSPContext) *scaf.QuerySignatureHelp {
-	// Find if we're inside a function call
-	funcName, paramIdx := d.findFunctionContext(query, offset)
-	if funcName == "" {
-		return nil
-	}
-
-	sig := d.getFunctionSignature(funcName)
-	if sig == nil {
-		return nil
+// acceptsArgument reports whether sig has a parameter for the argument at
+// index paramIdx.
+func acceptsArgument(sig FunctionSignature, paramIdx int) bool {
+	if paramIdx < len(sig.Params) {
+		return true
 	}
 
-	return &scaf.QuerySignatureHelp{
-		Signatures:      []scaf.QuerySignature{*sig},
-		ActiveSignature: 0,
-		ActiveParameter: paramIdx,
-	}
+	return len(sig.Params) > 0 && sig.Params[len(sig.Params)-1].variadic()
 }
 
+// findFunctionContext returns the lowercased name of the innermost function
+// call that offset is inside of, including any namespace such as apoc.text,
+// and the index of the argument at offset. Commas inside nested calls, list
+// and map literals, strings, and comments are not counted.
 func (d *Dialect) findFunctionContext(query string, offset int) (string, int) {
 	if offset <= 0 || offset > len(query) {
 		return "", 0
 	}
 
-	// Look backwards for function call
-	parenDepth := 0
-	commaCount := 0
+	// frame is an open bracket; name is the function called, if any
+	type frame struct {
+		name   string
+		commas int
+	}
 
-	for i := offset - 1; i >= 0; i-- {
-		switch query[i] {
-		case ')':
-			parenDepth++
-		case '(':
-			if parenDepth == 0 {
-				// Found the opening paren, extract function name
-				funcEnd := i
-				funcStart := funcEnd
-				for funcStart > 0 && (unicode.IsLetter(rune(query[funcStart-1])) || unicode.IsDigit(rune(query[funcStart-1])) || query[funcStart-1] == '_') {
-					funcStart--
+	var stack []frame
+
+	for i := 0; i < offset; i++ {
+		switch c := query[i]; c {
+		case '\'', '"', '`':
+			// Skip to the closing quote
+			for i++; i < offset && query[i] != c; i++ {
+				if query[i] == '\\' && c != '`' {
+					i++
 				}
-				if funcStart < funcEnd {
-					return strings.ToLower(query[funcStart:funcEnd]), commaCount
+			}
+		case '/':
+			switch {
+			case strings.HasPrefix(query[i:], "//"):
+				for i < offset && query[i] != '\n' {
+					i++
+				}
+			case strings.HasPrefix(query[i:], "/*"):
+				end := strings.Index(query[i+2:], "*/")
+				if end < 0 {
+					return "", 0
 				}
-				return "", 0
+
+				i += end + 3
+			}
+		case '(':
+			stack = append(stack, frame{name: functionNameBefore(query, i)})
+		case '[', '{':
+			stack = append(stack, frame{})
+		case ')', ']', '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
 			}
-			parenDepth--
 		case ',':
-			if parenDepth == 0 {
-				commaCount++
+			if len(stack) > 0 {
+				stack[len(stack)-1].commas++
 			}
 		}
 	}
 
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].name != "" {
+			return strings.ToLower(stack[i].name), stack[i].commas
+		}
+	}
+
 	return "", 0
 }
 
-func (d *Dialect) getFunctionSignature(name string) *scaf.QuerySignature {
-	// Common function signatures
-	signatures := map[string]*scaf.QuerySignature{
-		"count": {
-			Label:         "count(expression) → integer",
-			Documentation: "Returns the number of values/rows.",
-			Parameters: []scaf.QueryParameterInfo{
-				{Label: "expression", Documentation: "Expression to count. Use * to count rows."},
-			},
-		},
-		"sum": {
-			Label:         "sum(expression) → number",
-			Documentation: "Returns the sum of numeric values.",
-			Parameters: []scaf.QueryParameterInfo{
-				{Label: "expression", Documentation: "Numeric expression to sum."},
-			},
-		},
-		"avg": {
-			Label:         "avg(expression) → float",
-			Documentation: "Returns the average of numeric values.",
-			Parameters: []scaf.QueryParameterInfo{
-				{Label: "expression", Documentation: "Numeric expression to average."},
-			},
-		},
-		"collect": {
-			Label:         "collect(expression) → list",
-			Documentation: "Returns a list containing all values.",
-			Parameters: []scaf.QueryParameterInfo{
-				{Label: "expression", Documentation: "Values to collect into a list."},
-			},
-		},
-		"coalesce": {
-			Label:         "coalesce(expression...) → any",
-			Documentation: "Returns the first non-null value.",
-			Parameters: []scaf.QueryParameterInfo{
-				{Label: "expression", Documentation: "Expressions to evaluate."},
-			},
-		},
-	}
-
-	return signatures[name]
+// functionNameBefore returns the possibly dotted identifier that ends right
+// before the paren at index paren, or "" if there isn't one.
+func functionNameBefore(query string, paren int) string {
+	start := paren
+	for start > 0 {
+		c := rune(query[start-1])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+			break
+		}
+
+		start--
+	}
+
+	return strings.Trim(query[start:paren], ".")
+}
+
+// getFunctionSignature converts a function signature to signature help, with
+// parameter labels matching their text in the signature label.
+func (d *Dialect) getFunctionSignature(sig FunctionSignature) scaf.QuerySignature {
+	params := make([]scaf.QueryParameterInfo, len(sig.Params))
+	for i, p := range sig.Params {
+		params[i] = scaf.QueryParameterInfo{
+			Label:         p.String(),
+			Documentation: p.Description,
+			Optional:      p.Optional,
+		}
+	}
+
+	return scaf.QuerySignature{
+		Label:         sig.String(),
+		Documentation: sig.Description,
+		Parameters:    params,
+	}
 }
 
 // Definition returns go-to-definition targets.
@@ -1620,29 +2169,29 @@ func (d *Dialect) Definition(query string, offset int, ctx *scaf.QueryLSPContext
 	return nil
 }
 
-// InlayHints returns inlay hints for inferred parameter types.
-// Shows type annotations for parameters that lack explicit types in the function signature.
+// InlayHints returns inlay hints for inferred parameter types and for the
+// types of apoc.path calls and their yields in the query body.
+// Parameter hints need a schema and skip parameters with explicit types in the function signature.
 func (d *Dialect) InlayHints(query string, ctx *scaf.QueryLSPContext) []scaf.QueryInlayHint {
 	if ctx == nil {
 		return nil
 	}
 
+	hints := apocPathHints(query)
+
 	// Get schema for type inference
 	schema, _ := ctx.Schema.(*analysis.TypeSchema)
 	if schema == nil {
-		return nil
+		return hints
 	}
 
 	// Use the analyzer to extract parameters with inferred types
-	analyzer := NewAnalyzer()
-	metadata, err := analyzer.AnalyzeQueryWithSchema(query, schema)
-	if err != nil || metadata == nil {
-		return nil
+	params, err := NewAnalyzer().AnalyzeQueryParams(query, schema)
+	if err != nil {
+		return hints
 	}
 
-	var hints []scaf.QueryInlayHint
-
-	for _, param := range metadata.Parameters {
+	for _, param := range params {
 		// Skip if the parameter already has an explicit type annotation
 		if declType, exists := ctx.DeclaredParams[param.Name]; exists && declType != nil {
 			continue
@@ -1664,3 +2213,56 @@ func (d *Dialect) InlayHints(query string, ctx *scaf.QueryLSPContext) []scaf.Que
 
 	return hints
 }
+
+// apocPathHints returns a type hint after each apoc.path.* function call and
+// each column yielded by an apoc.path.* procedure call with a known type.
+// apoc.path calls return graph elements whose shape isn't obvious from the
+// call site.
+func apocPathHints(query string) []scaf.QueryInlayHint {
+	script, err := cyphergrammar.Parse(query)
+	if err != nil {
+		return nil
+	}
+
+	var hints []scaf.QueryInlayHint
+
+	cyphergrammar.Inspect(script, func(node any) bool {
+		switch node := node.(type) {
+		case *cyphergrammar.FunctionCall:
+			name := strings.ToLower(node.Name.String())
+			if !strings.HasPrefix(name, "apoc.path.") {
+				return true
+			}
+
+			if typ := cypherFunctionTypes[name]; typ != nil {
+				hints = append(hints, scaf.QueryInlayHint{
+					Offset:  node.EndPos.Offset,
+					Label:   ": " + typ.String(),
+					Kind:    scaf.QueryInlayHintType,
+					Tooltip: "Return type of `" + node.Name.String() + "`",
+				})
+			}
+		case *cyphergrammar.ProcedureCall:
+			name := node.FullName()
+			if !strings.HasPrefix(name, "apoc.path.") {
+				return true
+			}
+
+			known := procedureYields[name]
+			for _, item := range node.Yield {
+				if typ := known[item.Column()]; typ != nil {
+					hints = append(hints, scaf.QueryInlayHint{
+						Offset:  item.EndPos.Offset,
+						Label:   ": " + typ.String(),
+						Kind:    scaf.QueryInlayHintType,
+						Tooltip: "Type of `" + item.Column() + "` yielded by `" + name + "`",
+					})
+				}
+			}
+		}
+
+		return true
+	})
+
+	return hints
+}
@@ -2,6 +2,9 @@
 package cypher
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/rlch/scaf"
@@ -186,6 +189,253 @@ func TestDialect_Complete_RelTypes_VariableInference(t *testing.T) {
 	// This test documents current behavior - enhancement would infer Person from FRIENDS target
 }
 
+func TestDialect_Complete_MapProjection(t *testing.T) {
+	d := NewDialect()
+	ctx := &scaf.QueryLSPContext{
+		Schema: createTestSchema(),
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantProps []string
+		noWant    []string
+	}{
+		{
+			name:      "first item",
+			query:     "MATCH (m:Movie) RETURN m { .",
+			wantProps: []string{"title", "released"},
+			noWant:    []string{"age", "email"},
+		},
+		{
+			name:      "after comma",
+			query:     "MATCH (p:Person) RETURN p { .name, .",
+			wantProps: []string{"name", "age", "email"},
+			noWant:    []string{"title", "released"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := d.Complete(tt.query, len(tt.query), ctx)
+
+			gotProps := make(map[string]bool)
+			for _, item := range items {
+				if item.Kind == scaf.QueryCompletionProperty {
+					gotProps[item.Label] = true
+				}
+			}
+
+			for _, want := range tt.wantProps {
+				if !gotProps[want] {
+					t.Errorf("expected property %q in completions, got: %v", want, keysOf(gotProps))
+				}
+			}
+			for _, noWant := range tt.noWant {
+				if gotProps[noWant] {
+					t.Errorf("did not expect property %q in completions", noWant)
+				}
+			}
+		})
+	}
+}
+
+func TestDialect_Complete_NestedProperties(t *testing.T) {
+	d := NewDialect()
+	ctx := &scaf.QueryLSPContext{
+		Schema: &analysis.TypeSchema{
+			Models: map[string]*analysis.Model{
+				"User": {
+					Name: "User",
+					Fields: []*analysis.Field{
+						{Name: "name", Type: analysis.TypeString},
+						{Name: "address", Type: analysis.NamedType("models", "Address")},
+					},
+					NestedFields: []*analysis.Field{
+						{Name: "address.city", Type: analysis.TypeString},
+						{Name: "address.geo", Type: analysis.NamedType("models", "Geo")},
+						{Name: "address.geo.lat", Type: analysis.TypeFloat64},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"MATCH (u:User) RETURN u.", []string{"address", "name"}},
+		{"MATCH (u:User) RETURN u.address.", []string{"city", "geo"}},
+		{"MATCH (u:User) RETURN u.address.geo.", []string{"lat"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, item := range d.Complete(tt.query, len(tt.query), ctx) {
+				if item.Kind == scaf.QueryCompletionProperty {
+					got = append(got, item.Label)
+				}
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("properties = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_Complete_MultipleLabels(t *testing.T) {
+	d := NewDialect()
+	ctx := &scaf.QueryLSPContext{
+		Schema: createTestSchema(),
+	}
+
+	query := "MATCH (n:Person:"
+	items := d.Complete(query, len(query), ctx)
+
+	var labels []string
+	for _, item := range items {
+		if item.Kind == scaf.QueryCompletionLabel {
+			labels = append(labels, item.Label)
+		}
+	}
+
+	if want := []string{"Company", "Movie", "Person"}; !slices.Equal(labels, want) {
+		t.Errorf("labels after second colon = %v, want %v", labels, want)
+	}
+
+	// Properties of a multi-label node come from every label.
+	query = "MATCH (n:Person:Movie) RETURN n { ."
+	items = d.Complete(query, len(query), ctx)
+
+	props := make(map[string]bool)
+	for _, item := range items {
+		if item.Kind == scaf.QueryCompletionProperty {
+			props[item.Label] = true
+		}
+	}
+
+	for _, want := range []string{"age", "title"} {
+		if !props[want] {
+			t.Errorf("expected property %q in completions, got: %v", want, keysOf(props))
+		}
+	}
+}
+
+func TestDialect_Complete_Parameters(t *testing.T) {
+	d := NewDialect()
+	str := "string"
+	ctx := &scaf.QueryLSPContext{
+		DeclaredParams: map[string]*scaf.TypeExpr{
+			"userId": {Simple: &str},
+			"limit":  nil,
+		},
+	}
+
+	labelsAt := func(query string) []string {
+		var labels []string
+		for _, item := range d.Complete(query, len(query), ctx) {
+			if item.Kind == scaf.QueryCompletionParameter {
+				labels = append(labels, item.Label)
+			}
+		}
+
+		return labels
+	}
+
+	if got, want := labelsAt("MATCH (u:User) WHERE u.id = $"), []string{"limit", "userId"}; !slices.Equal(got, want) {
+		t.Errorf("parameters after $ = %v, want %v", got, want)
+	}
+
+	// A partially typed name is completed as a parameter, not a keyword
+	if got, want := labelsAt("MATCH (u:User) WHERE u.id = $us"), []string{"userId"}; !slices.Equal(got, want) {
+		t.Errorf("parameters after $us = %v, want %v", got, want)
+	}
+}
+
+func TestDialect_Rename(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name   string
+		query  string
+		offset int
+		want   string
+	}{
+		{
+			name:   "node variable",
+			query:  "MATCH (u:User)-[:FOLLOWS]->(f) WHERE u.name = $u RETURN u {.name, f}",
+			offset: 7,
+			want:   "MATCH (user:User)-[:FOLLOWS]->(f) WHERE user.name = $u RETURN user {.name, f}",
+		},
+		{
+			name:   "relationship variable",
+			query:  "MATCH (a)-[r:KNOWS]->(b) RETURN type(r), r.since",
+			offset: 37,
+			want:   "MATCH (a)-[user:KNOWS]->(b) RETURN type(user), user.since",
+		},
+		{
+			name:   "alias",
+			query:  "MATCH (u) WITH u.name AS n WHERE n <> '' RETURN n",
+			offset: 25,
+			want:   "MATCH (u) WITH u.name AS user WHERE user <> '' RETURN user",
+		},
+		{
+			name:   "unwind and set",
+			query:  "UNWIND $names AS n CREATE (p:Person) SET p.name = n, p += {n: n}",
+			offset: 17,
+			want:   "UNWIND $names AS user CREATE (p:Person) SET p.name = user, p += {n: user}",
+		},
+		{
+			name:   "property with the same name",
+			query:  "MATCH (name:Tag) RETURN name.name",
+			offset: 24,
+			want:   "MATCH (user:Tag) RETURN user.name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, edits, err := d.Rename(tt.query, tt.offset, "user")
+			if err != nil {
+				t.Fatalf("Rename() error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Rename() = %q, want %q", got, tt.want)
+			}
+
+			for i := 1; i < len(edits); i++ {
+				if edits[i].Start >= edits[i-1].Start {
+					t.Errorf("edits not sorted by descending offset: %+v", edits)
+				}
+			}
+		})
+	}
+}
+
+func TestDialect_Rename_Errors(t *testing.T) {
+	d := NewDialect()
+	query := "MATCH (u:User) WHERE u.id = $id RETURN u"
+
+	// On a label, a parameter, and whitespace
+	for _, offset := range []int{10, 29, 14} {
+		if _, _, err := d.Rename(query, offset, "user"); !errors.Is(err, scaf.ErrNoRenameTarget) {
+			t.Errorf("Rename() at %d error = %v, want ErrNoRenameTarget", offset, err)
+		}
+	}
+
+	if _, _, err := d.Rename("MATCH (u RETURN u", 7, "user"); !errors.Is(err, scaf.ErrNoRenameTarget) {
+		t.Errorf("Rename() of an invalid query error = %v, want ErrNoRenameTarget", err)
+	}
+
+	if _, _, err := d.Rename(query, 7, "user name"); !errors.Is(err, ErrInvalidVariableName) {
+		t.Errorf("Rename() to an invalid name error = %v, want ErrInvalidVariableName", err)
+	}
+}
+
 func TestExtractLabelsFromNodeContent(t *testing.T) {
 	tests := []struct {
 		content string
@@ -323,6 +573,637 @@ func TestDialect_Diagnostics_TypeMismatch(t *testing.T) {
 	}
 }
 
+func TestDialect_Diagnostics_Positions(t *testing.T) {
+	d := NewDialect()
+	ctx := &scaf.QueryLSPContext{Schema: createTestSchema()}
+
+	query := "MATCH (p:Person)\nWHERE p.age > 1\n  MERGE (q:Person {name: \"Bob\", age: \"old\"})\nRETURN p"
+
+	type pos struct{ line, col int }
+
+	got := make(map[string][2]pos)
+	for _, diag := range d.Diagnostics(query, ctx) {
+		r := diag.Range
+		if text := query[r.StartPos.Offset:r.EndPos.Offset]; text != "" {
+			got[diag.Code+" "+text] = [2]pos{{r.StartPos.Line, r.StartPos.Column}, {r.EndPos.Line, r.EndPos.Column}}
+		}
+	}
+
+	want := map[string][2]pos{
+		"merge-without-on-create-set MERGE": {{3, 3}, {3, 8}},
+		"type-mismatch \"old\"":             {{3, 38}, {3, 43}},
+	}
+
+	for key, wantPos := range want {
+		if gotPos, ok := got[key]; !ok || gotPos != wantPos {
+			t.Errorf("%s at %v, want %v (all: %v)", key, gotPos, wantPos, got)
+		}
+	}
+}
+
+func TestDialect_Diagnostics_ForEach(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name     string
+		query    string
+		wantMsgs []string
+	}{
+		{
+			name:  "updating clauses only",
+			query: "MATCH (u:User) FOREACH (x IN $names | CREATE (:Tag {name: x}) SET u.tagged = true)",
+		},
+		{
+			name:     "match inside foreach",
+			query:    "FOREACH (x IN $ids | MATCH (n {id: x}) DELETE n)",
+			wantMsgs: []string{"MATCH is not allowed inside FOREACH"},
+		},
+		{
+			name:     "nested foreach with with",
+			query:    "FOREACH (a IN $rows | FOREACH (b IN a | WITH b CREATE (:Item)))",
+			wantMsgs: []string{"WITH is not allowed inside FOREACH"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msgs []string
+			for _, diag := range d.Diagnostics(tt.query, nil) {
+				if diag.Code == "invalid-foreach-clause" {
+					if diag.Severity != scaf.QueryDiagnosticWarning {
+						t.Errorf("Severity = %v, want warning", diag.Severity)
+					}
+					msgs = append(msgs, tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset]+": "+diag.Message)
+				}
+			}
+
+			if len(msgs) != len(tt.wantMsgs) {
+				t.Fatalf("got diagnostics %v, want %d", msgs, len(tt.wantMsgs))
+			}
+			for i, want := range tt.wantMsgs {
+				if !strings.Contains(msgs[i], want) {
+					t.Errorf("diagnostic %q does not contain %q", msgs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDialect_Diagnostics_WriteInSubquery(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name     string
+		query    string
+		wantMsgs []string
+	}{
+		{
+			name:  "read-only exists",
+			query: "MATCH (u:User) WHERE EXISTS { MATCH (u)-[:KNOWS]->(f) WHERE f.age > 30 } RETURN u",
+		},
+		{
+			name:  "write outside subquery",
+			query: "MATCH (u:User) WHERE COUNT { (u)-->() } > 2 SET u.popular = true",
+		},
+		{
+			name:     "create inside exists",
+			query:    "MATCH (u:User) WHERE EXISTS { MATCH (u) CREATE (u)-[:SEEN]->(:Log) } RETURN u",
+			wantMsgs: []string{"CREATE: CREATE is not allowed inside EXISTS"},
+		},
+		{
+			name:     "set and delete inside count",
+			query:    "MATCH (u:User) RETURN COUNT { MATCH (u)-->(m) SET m.seen = true DETACH DELETE m } AS n",
+			wantMsgs: []string{"SET: SET is not allowed inside COUNT", "DETACH DELETE: DETACH DELETE is not allowed inside COUNT"},
+		},
+		{
+			name:     "merge inside nested subquery",
+			query:    "MATCH (u) WHERE EXISTS { MATCH (u)-->(f) WHERE COUNT { MERGE (f)-[:TAG]->(:Tag) } > 0 } RETURN u",
+			wantMsgs: []string{"MERGE: MERGE is not allowed inside COUNT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msgs []string
+			for _, diag := range d.Diagnostics(tt.query, nil) {
+				if diag.Code == "write-in-subquery" {
+					if diag.Severity != scaf.QueryDiagnosticError {
+						t.Errorf("Severity = %v, want error", diag.Severity)
+					}
+					msgs = append(msgs, tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset]+": "+diag.Message)
+				}
+			}
+
+			if len(msgs) != len(tt.wantMsgs) {
+				t.Fatalf("got diagnostics %v, want %d", msgs, len(tt.wantMsgs))
+			}
+			for i, want := range tt.wantMsgs {
+				if !strings.Contains(msgs[i], want) {
+					t.Errorf("diagnostic %q does not contain %q", msgs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDialect_Hover_FunctionSignatures(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name  string
+		query string
+		word  string
+		want  []string
+	}{
+		{
+			name:  "optional parameter",
+			query: "RETURN substring('hello', 1, 2)",
+			word:  "substring",
+			want:  []string{"**substring** (function)", "`substring(original: STRING, start: INTEGER, length?: INTEGER) → STRING`"},
+		},
+		{
+			name:  "all overloads",
+			query: "RETURN size([1, 2])",
+			word:  "size",
+			want:  []string{"`size(input: LIST<ANY>) → INTEGER`", "`size(input: STRING) → INTEGER`", "number of characters"},
+		},
+		{
+			name:  "canonical name",
+			query: "RETURN TOLOWER('A')",
+			word:  "TOLOWER",
+			want:  []string{"**toLower** (function)", "`toLower(input: STRING) → STRING`"},
+		},
+		{
+			name:  "no parameters",
+			query: "RETURN randomUUID()",
+			word:  "randomUUID",
+			want:  []string{"`randomUUID() → STRING`"},
+		},
+		{
+			name:  "return type fallback",
+			query: "RETURN log10(10)",
+			word:  "log10",
+			want:  []string{"**log10** (function)", "Returns: `float64`"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hover := d.Hover(tt.query, strings.Index(tt.query, tt.word)+1, nil)
+			if hover == nil {
+				t.Fatal("Hover() = nil")
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(hover.Contents, want) {
+					t.Errorf("hover missing %q:\n%s", want, hover.Contents)
+				}
+			}
+		})
+	}
+}
+
+func TestFunctionSignatures_Coverage(t *testing.T) {
+	if len(cypherFunctionSignatures) < 50 {
+		t.Errorf("len(cypherFunctionSignatures) = %d, want at least 50", len(cypherFunctionSignatures))
+	}
+
+	for name, sigs := range cypherFunctionSignatures {
+		if len(sigs) == 0 {
+			t.Errorf("%s has no signatures", name)
+		}
+
+		for _, sig := range sigs {
+			if strings.ToLower(sig.Name) != name {
+				t.Errorf("signature name %q does not match key %q", sig.Name, name)
+			}
+			if sig.ReturnType == "" || sig.Description == "" {
+				t.Errorf("%s is missing a return type or description", sig)
+			}
+			for _, p := range sig.Params {
+				if p.Description == "" {
+					t.Errorf("%s: parameter %s has no description", sig, p.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestDialect_SignatureHelp(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name           string
+		query          string
+		wantLabel      string
+		wantSignature  int
+		wantParameter  int
+		wantParamLabel string
+		wantOptional   bool
+	}{
+		{
+			name:           "first argument",
+			query:          "RETURN substring(",
+			wantLabel:      "substring(original: STRING, start: INTEGER, length?: INTEGER) → STRING",
+			wantParamLabel: "original: STRING",
+		},
+		{
+			name:           "optional argument",
+			query:          "RETURN substring(n.name, 1, ",
+			wantLabel:      "substring(original: STRING, start: INTEGER, length?: INTEGER) → STRING",
+			wantParameter:  2,
+			wantParamLabel: "length?: INTEGER",
+			wantOptional:   true,
+		},
+		{
+			name:           "nested call",
+			query:          "RETURN toLower(substring(n.name, size(n.name), ",
+			wantLabel:      "substring(original: STRING, start: INTEGER, length?: INTEGER) → STRING",
+			wantParameter:  2,
+			wantParamLabel: "length?: INTEGER",
+			wantOptional:   true,
+		},
+		{
+			name:           "after nested call",
+			query:          "RETURN toLower(trim(n.name)",
+			wantLabel:      "toLower(input: STRING) → STRING",
+			wantParamLabel: "input: STRING",
+		},
+		{
+			name:           "list and map literals",
+			query:          "RETURN coalesce([1, 2], {a: 1, b: 2}, ",
+			wantLabel:      "coalesce(input: ANY, inputs?: ANY...) → ANY",
+			wantParameter:  1,
+			wantParamLabel: "inputs?: ANY...",
+			wantOptional:   true,
+		},
+		{
+			name:           "variadic",
+			query:          "RETURN coalesce(a, b, c, ",
+			wantLabel:      "coalesce(input: ANY, inputs?: ANY...) → ANY",
+			wantParameter:  1,
+			wantParamLabel: "inputs?: ANY...",
+			wantOptional:   true,
+		},
+		{
+			name:           "string literal",
+			query:          "RETURN replace(n.name, ',)', ",
+			wantLabel:      "replace(original: STRING, search: STRING, replace: STRING) → STRING",
+			wantParameter:  2,
+			wantParamLabel: "replace: STRING",
+		},
+		{
+			name:           "namespaced",
+			query:          "RETURN apoc.text.join(n.tags, ",
+			wantLabel:      "apoc.text.join(list: LIST<STRING>, delimiter: STRING) → STRING",
+			wantParameter:  1,
+			wantParamLabel: "delimiter: STRING",
+		},
+		{
+			name:           "overload",
+			query:          "RETURN round(n.score, ",
+			wantLabel:      "round(value: FLOAT, precision: INTEGER, mode?: STRING) → FLOAT",
+			wantSignature:  1,
+			wantParameter:  1,
+			wantParamLabel: "precision: INTEGER",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			help := d.SignatureHelp(tt.query, len(tt.query), nil)
+			if help == nil {
+				t.Fatalf("SignatureHelp(%q) = nil", tt.query)
+			}
+
+			if help.ActiveSignature != tt.wantSignature || help.ActiveParameter != tt.wantParameter {
+				t.Errorf("active = (%d, %d), want (%d, %d)", help.ActiveSignature, help.ActiveParameter, tt.wantSignature, tt.wantParameter)
+			}
+
+			sig := help.Signatures[help.ActiveSignature]
+			if sig.Label != tt.wantLabel {
+				t.Errorf("Label = %q, want %q", sig.Label, tt.wantLabel)
+			}
+
+			param := sig.Parameters[help.ActiveParameter]
+			if param.Label != tt.wantParamLabel || param.Optional != tt.wantOptional {
+				t.Errorf("parameter = %+v, want label %q optional %v", param, tt.wantParamLabel, tt.wantOptional)
+			}
+			if !strings.Contains(sig.Label, param.Label) || param.Documentation == "" {
+				t.Errorf("parameter = %+v, want documentation and a label within %q", param, sig.Label)
+			}
+		})
+	}
+
+	for _, query := range []string{"RETURN size(x) + ", "RETURN 'size(", "RETURN unknown(", "MATCH (n"} {
+		if help := d.SignatureHelp(query, len(query), nil); help != nil {
+			t.Errorf("SignatureHelp(%q) = %+v, want nil", query, help)
+		}
+	}
+}
+
+func TestDialect_Diagnostics_InlineWhere(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name     string
+		query    string
+		wantVars []string
+	}{
+		{
+			name:  "own variable",
+			query: "MATCH (n:User WHERE n.age > 18)-[r WHERE r.since > 2020]->(m) RETURN m",
+		},
+		{
+			name:  "variable from earlier clause",
+			query: "MATCH (a) WITH a MATCH (n WHERE n.age > a.age) RETURN n",
+		},
+		{
+			name:     "other node in pattern",
+			query:    "MATCH (a WHERE a.age = b.age)-->(b) RETURN a",
+			wantVars: []string{"b"},
+		},
+		{
+			name:     "relationship references nodes",
+			query:    "MATCH (a)-[r WHERE r.since > a.born AND r.since < b.born]->(b) RETURN r",
+			wantVars: []string{"a", "b"},
+		},
+		{
+			name:     "other part of pattern",
+			query:    "MATCH (a), (b WHERE b.name = a.name) RETURN b",
+			wantVars: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vars []string
+			for _, diag := range d.Diagnostics(tt.query, nil) {
+				if diag.Code == "invalid-inline-where" {
+					if diag.Severity != scaf.QueryDiagnosticError {
+						t.Errorf("Severity = %v, want error", diag.Severity)
+					}
+					vars = append(vars, tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset])
+				}
+			}
+
+			if !slices.Equal(vars, tt.wantVars) {
+				t.Errorf("flagged %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestDialect_Diagnostics_NullableVariable(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name     string
+		query    string
+		wantVars []string
+	}{
+		{
+			name:     "later where",
+			query:    "MATCH (u:User) OPTIONAL MATCH (u)-[:FOLLOWS]->(u2:User) WITH u, u2 WHERE u2.name = 'Alice' RETURN u",
+			wantVars: []string{"u2"},
+		},
+		{
+			name:  "own where",
+			query: "MATCH (u:User) OPTIONAL MATCH (u)-[:FOLLOWS]->(u2:User) WHERE u2.name = 'Alice' RETURN u2",
+		},
+		{
+			name:  "null check",
+			query: "OPTIONAL MATCH (u2:User) WITH u2 WHERE u2 IS NOT NULL AND u2.name = 'Alice' RETURN u2",
+		},
+		{
+			name:  "required by later match",
+			query: "OPTIONAL MATCH (u2:User) MATCH (u2)-[:FOLLOWS]->(v) WHERE u2.age > 18 RETURN v",
+		},
+		{
+			name:  "bound before optional match",
+			query: "MATCH (u:User) OPTIONAL MATCH (u)-[:FOLLOWS]->(v) WITH u, v WHERE u.age > 18 RETURN v",
+		},
+		{
+			name:     "through alias",
+			query:    "OPTIONAL MATCH (u2:User) WITH u2 AS friend MATCH (p:Post) WHERE p.author = friend.name RETURN p",
+			wantVars: []string{"friend"},
+		},
+		{
+			name:  "dropped by with",
+			query: "OPTIONAL MATCH (u2:User) WITH count(u2) AS n MATCH (u2:User) WHERE u2.age > n RETURN u2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vars []string
+			for _, diag := range d.Diagnostics(tt.query, nil) {
+				if diag.Code == "nullable-variable" {
+					if diag.Severity != scaf.QueryDiagnosticWarning {
+						t.Errorf("Severity = %v, want warning", diag.Severity)
+					}
+					vars = append(vars, tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset])
+				}
+			}
+
+			if !slices.Equal(vars, tt.wantVars) {
+				t.Errorf("flagged %v, want %v", vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestDialect_Diagnostics_MergeWithoutActions(t *testing.T) {
+	d := NewDialect()
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"no actions", "MERGE (u:User {id: $id}) RETURN u", 1},
+		{"on create", "MERGE (u:User {id: $id}) ON CREATE SET u.name = $name RETURN u", 0},
+		{"on match", "MERGE (u:User {id: $id}) ON MATCH SET u.seen = true RETURN u", 0},
+		{"inside foreach", "FOREACH (t IN $tags | MERGE (:Tag {name: t}))", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got int
+			for _, diag := range d.Diagnostics(tt.query, nil) {
+				if diag.Code == "merge-without-on-create-set" {
+					got++
+					if diag.Severity != scaf.QueryDiagnosticHint {
+						t.Errorf("Severity = %v, want hint", diag.Severity)
+					}
+					if text := tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset]; text != "MERGE" {
+						t.Errorf("range covers %q, want MERGE", text)
+					}
+				}
+			}
+
+			if got != tt.want {
+				t.Errorf("got %d merge-without-on-create-set diagnostics, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_Diagnostics_NoIndex(t *testing.T) {
+	d := NewDialect()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name: "User",
+				Fields: []*analysis.Field{
+					{Name: "id", Type: analysis.TypeString, Unique: true},
+					{Name: "email", Type: analysis.TypeString},
+					{Name: "handle", Type: analysis.TypeString, Indexed: true},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		schema    *analysis.TypeSchema
+		wantProps []string
+	}{
+		{
+			name:      "unindexed equality",
+			query:     "MATCH (u:User) WHERE u.email = $email RETURN u",
+			schema:    schema,
+			wantProps: []string{"u.email"},
+		},
+		{
+			name:      "value on the left",
+			query:     "MATCH (u:User) WHERE $email = u.email AND u.handle = $handle RETURN u",
+			schema:    schema,
+			wantProps: []string{"u.email"},
+		},
+		{
+			name:   "indexed and unique fields",
+			query:  "MATCH (u:User) WHERE u.handle = $handle OR u.id = $id RETURN u",
+			schema: schema,
+		},
+		{
+			name:   "not equality",
+			query:  "MATCH (u:User) WHERE u.email <> $email RETURN u",
+			schema: schema,
+		},
+		{
+			name:   "node from an earlier clause",
+			query:  "MATCH (u:User) WITH u MATCH (v) WHERE u.email = $email RETURN v",
+			schema: schema,
+		},
+		{
+			name:   "schema without indexes",
+			query:  "MATCH (p:Person) WHERE p.name = $name RETURN p",
+			schema: createTestSchema(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotProps []string
+			for _, diag := range d.Diagnostics(tt.query, &scaf.QueryLSPContext{Schema: tt.schema}) {
+				if diag.Code == "no-index-hint" {
+					if diag.Severity != scaf.QueryDiagnosticHint {
+						t.Errorf("Severity = %v, want hint", diag.Severity)
+					}
+					gotProps = append(gotProps, tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset])
+				}
+			}
+
+			if !slices.Equal(gotProps, tt.wantProps) {
+				t.Errorf("flagged %v, want %v", gotProps, tt.wantProps)
+			}
+		})
+	}
+}
+
+func TestDialect_Diagnostics_UnknownProperty(t *testing.T) {
+	d := NewDialect()
+	schema := createTestSchema()
+
+	tests := []struct {
+		name      string
+		query     string
+		schema    *analysis.TypeSchema
+		wantProps []string // property names expected to be flagged
+	}{
+		{
+			name:      "typo in property name",
+			query:     `MATCH (p:Person {naem: $name}) RETURN p`,
+			schema:    schema,
+			wantProps: []string{"naem"},
+		},
+		{
+			name:   "known properties",
+			query:  `MATCH (p:Person {name: $name, age: 30}) RETURN p`,
+			schema: schema,
+		},
+		{
+			name:      "CREATE with unknown property",
+			query:     `CREATE (m:Movie {title: "Up", rating: 5})`,
+			schema:    schema,
+			wantProps: []string{"rating"},
+		},
+		{
+			name:   "multi-label accepts property from any label",
+			query:  `MATCH (n:Person:Movie {title: "Up", age: 30}) RETURN n`,
+			schema: schema,
+		},
+		{
+			name:      "multi-label rejects property on no label",
+			query:     `MATCH (n:Person:Movie {rating: 5}) RETURN n`,
+			schema:    schema,
+			wantProps: []string{"rating"},
+		},
+		{
+			name:   "unknown label is not checked",
+			query:  `MATCH (n:Planet {mass: 5}) RETURN n`,
+			schema: schema,
+		},
+		{
+			name:   "no schema",
+			query:  `MATCH (p:Person {naem: $name}) RETURN p`,
+			schema: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &scaf.QueryLSPContext{}
+			if tt.schema != nil {
+				ctx.Schema = tt.schema
+			}
+
+			var gotProps []string
+			for _, diag := range d.Diagnostics(tt.query, ctx) {
+				if diag.Code != "unknown-property" {
+					continue
+				}
+				if diag.Severity != scaf.QueryDiagnosticWarning {
+					t.Errorf("unknown-property severity = %v, want warning", diag.Severity)
+				}
+				gotProps = append(gotProps, tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset])
+			}
+
+			if len(gotProps) != len(tt.wantProps) {
+				t.Fatalf("got unknown-property diagnostics for %v, want %v", gotProps, tt.wantProps)
+			}
+			for i, want := range tt.wantProps {
+				if gotProps[i] != want {
+					t.Errorf("diagnostic[%d] covers %q, want %q", i, gotProps[i], want)
+				}
+			}
+		})
+	}
+}
+
 func containsSubstring(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > 0 && len(substr) > 0 && findSubstring(s, substr)))
@@ -344,3 +1225,86 @@ func keysOf(m map[string]bool) []string {
 	}
 	return keys
 }
+
+func TestDialect_Diagnostics_CardinalityMismatch(t *testing.T) {
+	d := NewDialect()
+	schema := &analysis.TypeSchema{Models: map[string]*analysis.Model{
+		"Person": {
+			Name: "Person",
+			Relationships: []*analysis.Relationship{
+				{Name: "Friends", RelType: "FRIENDS", Target: "Person", Direction: analysis.DirectionOutgoing, Cardinality: analysis.CardinalityMany},
+				{Name: "Passport", RelType: "HOLDS", Target: "Passport", Direction: analysis.DirectionOutgoing, Cardinality: analysis.CardinalityOne},
+				{Name: "Pets", RelType: "OWNS", Target: "Pet", Direction: analysis.DirectionOutgoing},
+			},
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		query    string
+		assert   bool
+		wantRels []string
+	}{
+		{
+			name:     "many relationship",
+			query:    "MATCH (p:Person {id: $id})-[:FRIENDS]->(f) RETURN f.name",
+			assert:   true,
+			wantRels: []string{"FRIENDS"},
+		},
+		{
+			name:     "many relationship from later node",
+			query:    "MATCH (x)-[:HOLDS]->(p:Person)-[r:FRIENDS]->(f) RETURN f",
+			assert:   true,
+			wantRels: []string{"FRIENDS"},
+		},
+		{
+			name:   "wrong direction",
+			query:  "MATCH (p:Person)<-[:FRIENDS]-(f) RETURN f",
+			assert: true,
+		},
+		{
+			name:   "one relationship",
+			query:  "MATCH (p:Person)-[:HOLDS]->(x) RETURN x",
+			assert: true,
+		},
+		{
+			name:   "unknown cardinality",
+			query:  "MATCH (p:Person)-[:OWNS]->(x) RETURN x",
+			assert: true,
+		},
+		{
+			name:   "aggregated",
+			query:  "MATCH (p:Person)-[:FRIENDS]->(f) RETURN count(f) AS n",
+			assert: true,
+		},
+		{
+			name:   "limited to one row",
+			query:  "MATCH (p:Person)-[:FRIENDS]->(f) RETURN f LIMIT 1",
+			assert: true,
+		},
+		{
+			name:  "not an assert query",
+			query: "MATCH (p:Person)-[:FRIENDS]->(f) RETURN f",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &scaf.QueryLSPContext{Schema: schema, AssertQuery: tt.assert}
+
+			var rels []string
+			for _, diag := range d.Diagnostics(tt.query, ctx) {
+				if diag.Code == "cardinality-mismatch" {
+					if diag.Severity != scaf.QueryDiagnosticWarning {
+						t.Errorf("Severity = %v, want warning", diag.Severity)
+					}
+					rels = append(rels, tt.query[diag.Range.StartPos.Offset:diag.Range.EndPos.Offset])
+				}
+			}
+
+			if !slices.Equal(rels, tt.wantRels) {
+				t.Errorf("flagged %v, want %v", rels, tt.wantRels)
+			}
+		})
+	}
+}
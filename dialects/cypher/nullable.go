@@ -0,0 +1,94 @@
+package cypher
+
+import (
+	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
+)
+
+// nullableVariables walks the clauses of a single query in order and returns
+// the variables that may be null after the last of them: those first bound by
+// an OPTIONAL MATCH, and projected through WITH, that no later MATCH has
+// required to exist. visit, if not nil, is called with each WHERE after such
+// a variable is bound and the variables that may be null inside it. The WHERE
+// of an OPTIONAL MATCH sees the variables of earlier clauses only, since it
+// filters the optional pattern itself.
+func nullableVariables(clauses []*cyphergrammar.Clause, visit func(where *cyphergrammar.Where, nullable map[string]bool)) map[string]bool {
+	nullable := make(map[string]bool)
+	bound := make(map[string]bool)
+
+	see := func(where *cyphergrammar.Where) {
+		if visit != nil && where != nil && len(nullable) > 0 {
+			visit(where, nullable)
+		}
+	}
+
+	for _, clause := range clauses {
+		switch {
+		case clause.Reading != nil && clause.Reading.Match != nil:
+			match := clause.Reading.Match
+			if match.Optional {
+				see(match.Where)
+			}
+
+			if match.Pattern != nil {
+				for _, part := range match.Pattern.Parts {
+					vars, _ := patternPartVariables(part)
+					if part.Var != "" {
+						vars[part.Var] = true
+					}
+
+					for v := range vars {
+						switch {
+						case !match.Optional:
+							delete(nullable, v)
+						case !bound[v]:
+							nullable[v] = true
+						}
+
+						bound[v] = true
+					}
+				}
+			}
+
+			if !match.Optional {
+				see(match.Where)
+			}
+		case clause.Reading != nil && clause.Reading.Unwind != nil:
+			bound[clause.Reading.Unwind.Symbol] = true
+			delete(nullable, clause.Reading.Unwind.Symbol)
+		case clause.Reading != nil && clause.Reading.Call != nil:
+			for _, item := range clause.Reading.Call.Yield {
+				bound[item.Name] = true
+				delete(nullable, item.Name)
+			}
+		case clause.With != nil:
+			with := clause.With
+			if with.Body != nil && with.Body.Items != nil && !with.Body.Items.Star {
+				projected := make(map[string]bool)
+				projectedBound := make(map[string]bool)
+
+				for _, item := range with.Body.Items.Items {
+					if item == nil || item.Expr == nil {
+						continue
+					}
+
+					expression := expressionToString(item.Expr)
+					name := item.Alias
+					if name == "" {
+						name = expression
+					}
+
+					projectedBound[name] = true
+					if nullable[expression] {
+						projected[name] = true
+					}
+				}
+
+				nullable, bound = projected, projectedBound
+			}
+
+			see(with.Where)
+		}
+	}
+
+	return nullable
+}
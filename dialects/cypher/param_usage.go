@@ -0,0 +1,149 @@
+package cypher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rlch/scaf"
+	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
+)
+
+// Type kinds compared when checking declared parameter types.
+const (
+	kindString = "string"
+	kindNumber = "number"
+	kindBool   = "bool"
+	kindList   = "list"
+	kindMap    = "map"
+)
+
+// paramUsage records an operator that requires a parameter to have a kind of type.
+type paramUsage struct {
+	op   string // e.g., "STARTS WITH", "IN"
+	kind string // e.g., kindString
+
+	// rng is the location of the $parameter in the query.
+	rng scaf.QueryDiagnosticRange
+}
+
+// recordParamUsage records that the parameter in operand, if any, is used with op.
+func recordParamUsage(operand *cyphergrammar.AddSubExpr, op string, ctx *queryContext) {
+	atom := bareAtom(operand)
+	if atom == nil || atom.Parameter == nil {
+		return
+	}
+
+	kind := kindString
+	if op == "IN" {
+		kind = kindList
+	}
+
+	addParamUsage(atom.Parameter, op, kind, ctx)
+}
+
+// addParamUsage records that the parameter p is used with op as a kind.
+func addParamUsage(p *cyphergrammar.Parameter, op, kind string, ctx *queryContext) {
+	ctx.paramUsages[p.Name] = append(ctx.paramUsages[p.Name], paramUsage{
+		op:   op,
+		kind: kind,
+		rng:  textRange(p.Pos, "$"+p.Name),
+	})
+}
+
+// recordStringPredUsage records parameters on either side of STARTS WITH, ENDS WITH, or CONTAINS.
+func recordStringPredUsage(subject *cyphergrammar.Atom, pred *cyphergrammar.StringPredSuffix, ctx *queryContext) {
+	var op string
+	var operand *cyphergrammar.AddSubExpr
+
+	switch {
+	case pred.StartsWith != nil:
+		op, operand = "STARTS WITH", pred.StartsWith
+	case pred.EndsWith != nil:
+		op, operand = "ENDS WITH", pred.EndsWith
+	case pred.Contains != nil:
+		op, operand = "CONTAINS", pred.Contains
+	default:
+		return
+	}
+
+	if subject != nil && subject.Parameter != nil {
+		addParamUsage(subject.Parameter, op, kindString, ctx)
+	}
+
+	recordParamUsage(operand, op, ctx)
+}
+
+// addSubParameter returns the parameter name if add is a bare $parameter.
+func addSubParameter(add *cyphergrammar.AddSubExpr) string {
+	atom := bareAtom(add)
+	if atom == nil || atom.Parameter == nil {
+		return ""
+	}
+
+	return atom.Parameter.Name
+}
+
+// checkDeclaredParameters compares declared parameter types with the operators
+// each parameter is used with. Schema-inferred types are not compared: an
+// explicit annotation takes precedence over the schema.
+// Returns one warning per conflict, in query order.
+func checkDeclaredParameters(params []scaf.ParameterInfo, declared map[string]*scaf.TypeExpr, ctx *queryContext) []scaf.QueryWarning {
+	var warnings []scaf.QueryWarning
+
+	for _, p := range params {
+		typeExpr := declared[p.Name]
+		declaredKind := typeExprKind(typeExpr)
+		if declaredKind == "" {
+			continue // Untyped, any, or a custom type
+		}
+
+		seen := make(map[string]bool)
+
+		for _, usage := range ctx.paramUsages[p.Name] {
+			if usage.kind == declaredKind || seen[usage.op] {
+				continue
+			}
+			seen[usage.op] = true
+
+			warnings = append(warnings, scaf.QueryWarning{
+				Code: "param-type-mismatch",
+				Message: fmt.Sprintf("parameter $%s is declared as %s but used as a %s with %s",
+					p.Name, typeExpr.ToGoType(), usage.kind, usage.op),
+				Range: usage.rng,
+				Param: p.Name,
+			})
+		}
+	}
+
+	return warnings
+}
+
+// typeExprKind returns the kind of a declared type, or "" if it accepts anything.
+func typeExprKind(t *scaf.TypeExpr) string {
+	switch {
+	case t == nil:
+		return ""
+	case t.Array != nil:
+		return kindList
+	case t.Map != nil:
+		return kindMap
+	case t.Simple != nil:
+		return primitiveKind(*t.Simple)
+	default:
+		return ""
+	}
+}
+
+// primitiveKind groups primitive type names; all numeric types compare as numbers.
+func primitiveKind(name string) string {
+	switch {
+	case name == "string":
+		return kindString
+	case name == "bool":
+		return kindBool
+	case strings.HasPrefix(name, "int"), strings.HasPrefix(name, "uint"), strings.HasPrefix(name, "float"):
+		return kindNumber
+	default:
+		return ""
+	}
+}
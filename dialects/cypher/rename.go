@@ -0,0 +1,162 @@
+package cypher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"github.com/rlch/scaf"
+	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
+)
+
+// ErrInvalidVariableName is returned by Rename for a new name that isn't a
+// Cypher identifier.
+var ErrInvalidVariableName = errors.New("invalid variable name")
+
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Rename renames the node variable, relationship variable, or alias at offset
+// to newName, everywhere it occurs in the query. It returns the renamed query
+// and the edits that produce it, sorted by descending offset so they can be
+// applied back to front, or scaf.ErrNoRenameTarget if there's no variable at
+// offset or the query doesn't parse. Properties, labels, map keys, and
+// parameters that share the name are left alone. A variable is one name
+// across the whole query, so a comprehension variable that shadows it is
+// renamed too.
+func (d *Dialect) Rename(query string, offset int, newName string) (string, []scaf.RenameEdit, error) {
+	if !identifierPattern.MatchString(newName) {
+		return query, nil, ErrInvalidVariableName
+	}
+
+	parsed, err := cyphergrammar.Parse(query)
+	if err != nil {
+		return query, nil, fmt.Errorf("%w: %w", scaf.ErrNoRenameTarget, err)
+	}
+
+	tokens, err := lexCypher(query)
+	if err != nil {
+		return query, nil, fmt.Errorf("%w: %w", scaf.ErrNoRenameTarget, err)
+	}
+
+	occurrences := variableOccurrences(parsed, tokens)
+
+	var name string
+
+	for _, occ := range occurrences {
+		if offset >= occ.Pos.Offset && offset <= occ.Pos.Offset+len(occ.Value) {
+			name = occ.Value
+			break
+		}
+	}
+
+	if name == "" {
+		return query, nil, scaf.ErrNoRenameTarget
+	}
+
+	var edits []scaf.RenameEdit
+
+	for _, occ := range occurrences {
+		if occ.Value == name {
+			edits = append(edits, scaf.RenameEdit{
+				Start:   occ.Pos.Offset,
+				End:     occ.Pos.Offset + len(occ.Value),
+				NewText: newName,
+			})
+		}
+	}
+
+	slices.SortFunc(edits, func(a, b scaf.RenameEdit) int {
+		return b.Start - a.Start
+	})
+
+	edits = slices.CompactFunc(edits, func(a, b scaf.RenameEdit) bool {
+		return a.Start == b.Start
+	})
+
+	renamed := query
+	for _, edit := range edits {
+		renamed = renamed[:edit.Start] + edit.NewText + renamed[edit.End:]
+	}
+
+	return renamed, edits, nil
+}
+
+// variableOccurrences returns the identifier tokens in the query that name a
+// variable or alias, whether binding or referencing it. The AST records names
+// without their positions, so each is matched to a token near the start of
+// its node.
+func variableOccurrences(parsed *cyphergrammar.Script, tokens []lexer.Token) []lexer.Token {
+	var occurrences []lexer.Token
+
+	// add records the first token named name among the few starting at
+	// offset, which covers keywords and brackets before the name.
+	add := func(name string, offset int) {
+		if name == "" {
+			return
+		}
+
+		start := sort.Search(len(tokens), func(i int) bool {
+			return tokens[i].Pos.Offset >= offset
+		})
+
+		for i := start; i < len(tokens) && i < start+3; i++ {
+			if tokens[i].Type == tokIdent && tokens[i].Value == name {
+				occurrences = append(occurrences, tokens[i])
+				return
+			}
+		}
+	}
+
+	cyphergrammar.Inspect(parsed, func(node any) bool {
+		switch n := node.(type) {
+		case *cyphergrammar.Atom:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.NodePattern:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.RelationshipDetail:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.PatternPart:
+			add(n.Var, n.Pos.Offset)
+		case *cyphergrammar.MapProjection:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.MapProjectionItem:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.ListComprehension:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.PatternComprehension:
+			add(n.Var, n.Pos.Offset)
+		case *cyphergrammar.FilterPredicate:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.ForEachClause:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.PropertyExpr:
+			add(n.Base, n.Pos.Offset)
+		case *cyphergrammar.SetItem:
+			add(n.Variable, n.Pos.Offset)
+			add(n.LabelVar, n.Pos.Offset)
+		case *cyphergrammar.SetItemVariable:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.SetItemLabel:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.RemoveItem:
+			add(n.Variable, n.Pos.Offset)
+		case *cyphergrammar.ProjectionItem:
+			// The alias follows the expression: expr AS alias
+			if n.Expr != nil {
+				add(n.Alias, n.Expr.EndPos.Offset)
+			}
+		case *cyphergrammar.UnwindClause:
+			if n.Expr != nil {
+				add(n.Symbol, n.Expr.EndPos.Offset)
+			}
+		}
+
+		return true
+	})
+
+	return occurrences
+}
@@ -30,6 +30,17 @@ func inferExpression(expr *cyphergrammar.Expression, qctx *queryContext) *analys
 		return nil
 	}
 
+	// Nested expressions (list elements, function arguments, subscripts)
+	// come back through here, so this bounds the recursion
+	if state := qctx.inference; state != nil && state.maxDepth > 0 {
+		if state.depth >= state.maxDepth {
+			return nil
+		}
+
+		state.depth++
+		defer func() { state.depth-- }()
+	}
+
 	// OR produces bool
 	if len(expr.Right) > 0 {
 		return analysis.TypeBool
@@ -116,6 +127,14 @@ func inferAddSubExpression(add *cyphergrammar.AddSubExpr, qctx *queryContext) *a
 		return analysis.TypeString
 	}
 
+	// Temporal arithmetic: instant ± duration → instant, duration ± duration → duration
+	if i := slices.IndexFunc(types, isTemporalType); i >= 0 {
+		return types[i]
+	}
+	if slices.ContainsFunc(types, isDurationType) {
+		return analysis.TypeDuration
+	}
+
 	return unifyNumericTypes(types...)
 }
 
@@ -129,6 +148,12 @@ func inferMultDivExpression(mult *cyphergrammar.MultDivExpr, qctx *queryContext)
 		return inferPowerExpression(mult.Left, qctx)
 	}
 
+	// Scaling a duration by a number produces a duration
+	left := inferPowerExpression(mult.Left, qctx)
+	if isDurationType(left) {
+		return analysis.TypeDuration
+	}
+
 	// Check for division - always produces float64
 	for _, term := range mult.Right {
 		if term.Op == "/" {
@@ -137,11 +162,13 @@ func inferMultDivExpression(mult *cyphergrammar.MultDivExpr, qctx *queryContext)
 	}
 
 	// Multiplication/modulo - unify numeric types
-	var types []*analysis.Type
-	types = append(types, inferPowerExpression(mult.Left, qctx))
+	types := []*analysis.Type{left}
 	for _, term := range mult.Right {
 		types = append(types, inferPowerExpression(term.Expr, qctx))
 	}
+	if slices.ContainsFunc(types, isDurationType) {
+		return analysis.TypeDuration
+	}
 	return unifyNumericTypes(types...)
 }
 
@@ -175,9 +202,18 @@ func inferPostfixExpression(post *cyphergrammar.PostfixExpr, qctx *queryContext)
 	}
 
 	baseType := inferAtom(post.Atom, qctx)
+	suffixes := post.Suffixes
+
+	// A property of a multi-label node may be declared on any of its labels,
+	// e.g. n.department on (n:Person:Employee).
+	if labels := boundLabels(post.Atom, qctx); len(labels) > 1 && len(suffixes) > 0 && suffixes[0].Property != "" {
+		baseType = lookupPropertyType(suffixes[0].Property, labels, qctx.schema)
+
+		suffixes = suffixes[1:]
+	}
 
 	// Apply suffixes
-	for _, suffix := range post.Suffixes {
+	for _, suffix := range suffixes {
 		baseType = applySuffix(baseType, suffix, qctx)
 	}
 
@@ -243,6 +279,11 @@ func resolvePropertyType(baseType *analysis.Type, propName string, qctx *queryCo
 		return nil
 	}
 
+	// Temporal and spatial values expose typed components (d.year, p.x, ...)
+	if typ := componentType(baseType, propName); typ != nil {
+		return typ
+	}
+
 	// Pointer to model (*User) - look up field in schema
 	if baseType.Kind == analysis.TypeKindPointer && baseType.Elem != nil {
 		return lookupModelField(baseType.Elem.Name, propName, qctx)
@@ -258,6 +299,33 @@ func resolvePropertyType(baseType *analysis.Type, propName string, qctx *queryCo
 		return baseType.Elem
 	}
 
+	// Struct (from a map projection) - property access returns the field type
+	if baseType.Kind == analysis.TypeKindStruct {
+		for _, field := range baseType.Fields {
+			if field.Name == propName {
+				return field.Type
+			}
+		}
+	}
+
+	return nil
+}
+
+// boundLabels returns the labels of the MATCH binding an atom refers to, or nil
+// if the atom isn't a variable bound to a labeled node.
+func boundLabels(atom *cyphergrammar.Atom, qctx *queryContext) []string {
+	if atom == nil || atom.Variable == "" {
+		return nil
+	}
+
+	if _, ok := qctx.local(atom.Variable); ok {
+		return nil // Shadowed by a local
+	}
+
+	if binding, ok := qctx.binding(atom.Variable); ok {
+		return binding.labels
+	}
+
 	return nil
 }
 
@@ -302,6 +370,11 @@ func inferAtom(atom *cyphergrammar.Atom, qctx *queryContext) *analysis.Type {
 		return analysis.TypeInt
 	}
 
+	// shortestPath(...) / allShortestPaths(...)
+	if atom.PathFunction != nil {
+		return cypherFunctionTypes[strings.ToLower(atom.PathFunction.Name)]
+	}
+
 	// Function call
 	if atom.FunctionCall != nil {
 		return inferFunctionInvocation(atom.FunctionCall, qctx)
@@ -342,9 +415,78 @@ func inferAtom(atom *cyphergrammar.Atom, qctx *queryContext) *analysis.Type {
 		return analysis.TypeBool
 	}
 
+	// COUNT subquery
+	if atom.CountSubquery != nil {
+		return analysis.TypeInt
+	}
+
+	// Map projection
+	if atom.MapProjection != nil {
+		return inferMapProjection(atom.MapProjection, qctx)
+	}
+
 	return nil
 }
 
+// inferMapProjection infers the type of a map projection like u { .name, score: expr }.
+// Without a schema the result is an untyped map; with a schema each entry becomes
+// a struct field carrying its inferred type.
+func inferMapProjection(proj *cyphergrammar.MapProjection, qctx *queryContext) *analysis.Type {
+	if qctx.schema == nil {
+		return analysis.MapOf(analysis.TypeString, nil)
+	}
+
+	baseType := inferSymbol(proj.Variable, qctx)
+
+	var fields []*analysis.StructField
+	for _, item := range proj.Items {
+		switch {
+		case item.AllProperties:
+			fields = append(fields, modelStructFields(baseType, qctx)...)
+		case item.Property != "":
+			fields = append(fields, &analysis.StructField{
+				Name: item.Property,
+				Type: resolvePropertyType(baseType, item.Property, qctx),
+			})
+		case item.Key != "":
+			fields = append(fields, &analysis.StructField{
+				Name: item.Key,
+				Type: inferExpression(item.Value, qctx),
+			})
+		case item.Variable != "":
+			fields = append(fields, &analysis.StructField{
+				Name: item.Variable,
+				Type: inferSymbol(item.Variable, qctx),
+			})
+		}
+	}
+
+	return analysis.StructOf(fields...)
+}
+
+// modelStructFields returns struct fields for every property of the model behind baseType.
+func modelStructFields(baseType *analysis.Type, qctx *queryContext) []*analysis.StructField {
+	if baseType == nil {
+		return nil
+	}
+
+	if baseType.Kind == analysis.TypeKindPointer && baseType.Elem != nil {
+		baseType = baseType.Elem
+	}
+
+	model, ok := qctx.schema.Models[baseType.Name]
+	if !ok {
+		return nil
+	}
+
+	fields := make([]*analysis.StructField, 0, len(model.Fields))
+	for _, field := range model.Fields {
+		fields = append(fields, &analysis.StructField{Name: field.Name, Type: field.Type})
+	}
+
+	return fields
+}
+
 // inferSymbol looks up a variable in the query context.
 func inferSymbol(name string, qctx *queryContext) *analysis.Type {
 	// Check if this is actually a numeric literal parsed as a symbol
@@ -356,18 +498,24 @@ func inferSymbol(name string, qctx *queryContext) *analysis.Type {
 	}
 
 	// Check local variable bindings first
-	if typ, ok := qctx.locals[name]; ok {
+	if typ, ok := qctx.local(name); ok {
 		return typ
 	}
 
 	// Look up as variable binding from MATCH clauses
-	if binding, ok := qctx.bindings[name]; ok {
+	if binding, ok := qctx.binding(name); ok {
 		if len(binding.labels) > 0 {
 			return analysis.PointerTo(&analysis.Type{
 				Kind: analysis.TypeKindNamed,
 				Name: binding.labels[0],
 			})
 		}
+
+		return nil
+	}
+
+	if state := qctx.inference; state != nil && state.strict && !slices.Contains(state.undefined, name) {
+		state.undefined = append(state.undefined, name)
 	}
 
 	return nil
@@ -468,25 +616,42 @@ func inferListLiteral(list *cyphergrammar.ListLiteral, qctx *queryContext) *anal
 	return analysis.SliceOf(elemType)
 }
 
-// inferCaseExpression infers the type of a CASE expression.
+// inferCaseExpression infers the type of a CASE expression as the common type
+// of its THEN and ELSE branches. Mixed int and float branches promote to
+// float64; other mismatches are unknown. Branches of unknown type, such as
+// NULL, and a missing ELSE don't affect the result.
 func inferCaseExpression(caseExpr *cyphergrammar.CaseExpression, qctx *queryContext) *analysis.Type {
 	if caseExpr == nil {
 		return nil
 	}
 
-	// Infer from THEN expressions
+	branches := make([]*analysis.Type, 0, len(caseExpr.Whens)+1)
 	for _, when := range caseExpr.Whens {
-		if t := inferExpression(when.Then, qctx); t != nil {
-			return t
-		}
+		branches = append(branches, inferExpression(when.Then, qctx))
 	}
 
-	// Fall back to ELSE
 	if caseExpr.Else != nil {
-		return inferExpression(caseExpr.Else, qctx)
+		branches = append(branches, inferExpression(caseExpr.Else, qctx))
 	}
 
-	return nil
+	var result *analysis.Type
+
+	for _, t := range branches {
+		switch {
+		case t == nil:
+			continue
+		case result == nil:
+			result = t
+		case result.String() == t.String():
+			continue
+		case (isIntType(result) || isFloatType(result)) && (isIntType(t) || isFloatType(t)):
+			result = unifyNumericTypes(result, t)
+		default:
+			return nil
+		}
+	}
+
+	return result
 }
 
 // inferListComprehension infers the type of a list comprehension.
@@ -496,15 +661,17 @@ func inferListComprehension(lc *cyphergrammar.ListComprehension, qctx *queryCont
 	}
 
 	// Get element type from source list
-	var elemType *analysis.Type
+	var listType, elemType *analysis.Type
 	if lc.Source != nil {
-		listType := inferExpression(lc.Source, qctx)
+		listType = inferExpression(lc.Source, qctx)
 		if listType != nil && listType.Kind == analysis.TypeKindSlice {
 			elemType = listType.Elem
 		}
 	}
 
 	// Create scoped context with loop variable
+	qctx.declare(lc.Variable)
+
 	scopedCtx := qctx
 	if lc.Variable != "" && elemType != nil {
 		scopedCtx = qctx.withLocal(lc.Variable, elemType)
@@ -516,8 +683,12 @@ func inferListComprehension(lc *cyphergrammar.ListComprehension, qctx *queryCont
 		return analysis.SliceOf(mappedType)
 	}
 
-	// No mapping - preserves element type
-	return analysis.SliceOf(elemType)
+	// No mapping - a filter keeps the source list's type
+	if elemType != nil {
+		return listType
+	}
+
+	return analysis.SliceOf(nil)
 }
 
 // inferPatternComprehension infers the type of a pattern comprehension.
@@ -526,10 +697,13 @@ func inferPatternComprehension(pc *cyphergrammar.PatternComprehension, qctx *que
 		return analysis.SliceOf(nil)
 	}
 
-	// Create scoped context with pattern bindings
+	// Create scoped context with pattern bindings, including a named path
 	scopedCtx := qctx
+	if pc.Var != "" {
+		scopedCtx = scopedCtx.withLocal(pc.Var, analysis.TypePath)
+	}
 	if pc.Pattern != nil {
-		scopedCtx = extractPatternBindings(pc.Pattern, qctx)
+		scopedCtx = extractPatternBindings(pc.Pattern, scopedCtx)
 	}
 
 	// Result is list of the mapped expression type
@@ -556,6 +730,9 @@ func extractPatternBindings(pattern *cyphergrammar.RelationshipChainPattern, qct
 
 	// Extract from chain
 	for _, chain := range pattern.Chain {
+		if chain.Rel != nil && chain.Rel.Detail != nil {
+			scopedCtx.declare(chain.Rel.Detail.Variable)
+		}
 		if chain.Node != nil {
 			scopedCtx = extractNodePatternBinding(chain.Node, scopedCtx)
 		}
@@ -570,6 +747,8 @@ func extractNodePatternBinding(node *cyphergrammar.NodePattern, qctx *queryConte
 		return qctx
 	}
 
+	qctx.declare(node.Variable)
+
 	var labels []string
 	if node.Labels != nil {
 		labels = node.Labels.Labels
@@ -595,6 +774,65 @@ func isStringType(t *analysis.Type) bool {
 	return t != nil && t.Kind == analysis.TypeKindPrimitive && t.Name == "string"
 }
 
+// sameNamedType checks if t is the named type want.
+func sameNamedType(t, want *analysis.Type) bool {
+	return t != nil && t.Kind == analysis.TypeKindNamed &&
+		t.Package == want.Package && t.Name == want.Name
+}
+
+// isTemporalType checks if a type is a temporal instant (date, time, datetime, ...).
+func isTemporalType(t *analysis.Type) bool {
+	for _, want := range []*analysis.Type{
+		analysis.TypeDate, analysis.TypeTime, analysis.TypeDateTime,
+		analysis.TypeLocalDateTime, analysis.TypeLocalTime,
+	} {
+		if sameNamedType(t, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDurationType checks if a type is a duration.
+func isDurationType(t *analysis.Type) bool {
+	return sameNamedType(t, analysis.TypeDuration)
+}
+
+// isPointType checks if a type is a 2D or 3D point.
+func isPointType(t *analysis.Type) bool {
+	return sameNamedType(t, analysis.TypePoint) || sameNamedType(t, analysis.TypePoint3D)
+}
+
+// componentType returns the type of a component accessed on a temporal,
+// duration, or point value (e.g. date.year, duration.days, point.x).
+func componentType(t *analysis.Type, component string) *analysis.Type {
+	switch {
+	case isTemporalType(t):
+		switch strings.ToLower(component) {
+		case "timezone", "offset":
+			return analysis.TypeString
+		case "epochseconds", "epochmillis", "year", "quarter", "month", "week", "weekyear",
+			"dayofquarter", "quarterday", "day", "ordinalday", "dayofweek", "weekday",
+			"hour", "minute", "second", "millisecond", "microsecond", "nanosecond",
+			"offsetminutes", "offsetseconds":
+			return analysis.TypeInt
+		}
+	case isDurationType(t):
+		// All duration components (years, months, days, hours, seconds, ...) are integers
+		return analysis.TypeInt
+	case isPointType(t):
+		switch strings.ToLower(component) {
+		case "x", "y", "z", "longitude", "latitude", "height":
+			return analysis.TypeFloat64
+		case "crs":
+			return analysis.TypeString
+		case "srid":
+			return analysis.TypeInt
+		}
+	}
+	return nil
+}
+
 // isFloatType checks if a type is floating point.
 func isFloatType(t *analysis.Type) bool {
 	return t != nil && t.Kind == analysis.TypeKindPrimitive &&
@@ -657,6 +657,79 @@ func TestTypeInference_PropertyAccess(t *testing.T) {
 	}
 }
 
+func TestTypeInference_TemporalAndSpatial(t *testing.T) {
+	t.Parallel()
+
+	parse := func(s string) *analysis.Type {
+		typ, err := analysis.ParseTypeString(s)
+		if err != nil {
+			t.Fatalf("ParseTypeString(%q) error: %v", s, err)
+		}
+		return typ
+	}
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"Event": {
+				Name: "Event",
+				Fields: []*analysis.Field{
+					{Name: "createdAt", Type: parse("datetime")},
+					{Name: "day", Type: parse("date")},
+					{Name: "length", Type: parse("duration")},
+					{Name: "location", Type: parse("point")},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantType string
+	}{
+		{"date()", "RETURN date()", "neo4j.Date"},
+		{"time()", "RETURN time()", "neo4j.Time"},
+		{"datetime()", "RETURN datetime('2024-01-01T00:00:00Z')", "time.Time"},
+		{"localdatetime()", "RETURN localdatetime()", "neo4j.LocalDateTime"},
+		{"duration()", "RETURN duration('P1D')", "neo4j.Duration"},
+		{"duration.between()", "RETURN duration.between(date(), date())", "neo4j.Duration"},
+		{"date.truncate()", "RETURN date.truncate('month', date())", "neo4j.Date"},
+		{"2D point", "RETURN point({x: 1.0, y: 2.0})", "neo4j.Point2D"},
+		{"3D point", "RETURN point({x: 1.0, y: 2.0, z: 3.0})", "neo4j.Point3D"},
+		{"geographic 3D point", "RETURN point({longitude: 1.0, latitude: 2.0, height: 3.0})", "neo4j.Point3D"},
+		{"schema datetime property", "MATCH (e:Event) RETURN e.createdAt", "time.Time"},
+		{"schema date property", "MATCH (e:Event) RETURN e.day", "neo4j.Date"},
+		{"datetime plus duration", "MATCH (e:Event) RETURN e.createdAt + duration('P1D')", "time.Time"},
+		{"date minus duration", "MATCH (e:Event) RETURN e.day - e.length", "neo4j.Date"},
+		{"duration plus duration", "MATCH (e:Event) RETURN e.length + duration('PT1H')", "neo4j.Duration"},
+		{"duration times number", "MATCH (e:Event) RETURN e.length * 2", "neo4j.Duration"},
+		{"duration divided by number", "MATCH (e:Event) RETURN e.length / 2", "neo4j.Duration"},
+		{"temporal component", "MATCH (e:Event) RETURN e.createdAt.year", "int"},
+		{"point component", "MATCH (e:Event) RETURN e.location.x", "float64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			analyzer := cypher.NewAnalyzer()
+			metadata, err := analyzer.AnalyzeQueryWithSchema(tt.query, schema)
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			if len(metadata.Returns) < 1 {
+				t.Fatalf("expected at least 1 return, got %d", len(metadata.Returns))
+			}
+
+			gotType := typeString(metadata.Returns[0].Type)
+			if gotType != tt.wantType {
+				t.Errorf("type = %q, want %q", gotType, tt.wantType)
+			}
+		})
+	}
+}
+
 func TestTypeInference_StringOperations(t *testing.T) {
 	t.Parallel()
 
@@ -876,6 +949,31 @@ func TestTypeInference_CaseExpression(t *testing.T) {
 			query:    "RETURN CASE WHEN true THEN 1 ELSE 0 END",
 			wantType: "int",
 		},
+		{
+			name:     "simple CASE with int result",
+			query:    "RETURN CASE 'a' WHEN 'active' THEN 1 WHEN 'inactive' THEN 0 ELSE -1 END",
+			wantType: "int",
+		},
+		{
+			name:     "CASE promotes mixed int and float",
+			query:    "RETURN CASE WHEN true THEN 1 ELSE 2.5 END",
+			wantType: "float64",
+		},
+		{
+			name:     "CASE with incompatible branches is unknown",
+			query:    `RETURN CASE WHEN true THEN "yes" ELSE 0 END`,
+			wantType: "",
+		},
+		{
+			name:     "CASE without ELSE",
+			query:    "RETURN CASE WHEN true THEN 1 WHEN false THEN 2 END",
+			wantType: "int",
+		},
+		{
+			name:     "CASE ignores NULL branch",
+			query:    "RETURN CASE WHEN true THEN 'x' ELSE null END",
+			wantType: "string",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1315,6 +1413,16 @@ func TestTypeInference_ListComprehensionVariableBinding(t *testing.T) {
 			query:    "MATCH (u:User) RETURN [s IN u.scores WHERE s > 50]",
 			wantType: "[]int",
 		},
+		{
+			name:     "list comprehension filter and mapping",
+			query:    "MATCH (u:User) RETURN [s IN u.scores WHERE s > 0 | s * 2]",
+			wantType: "[]int",
+		},
+		{
+			name:     "list comprehension filter and mapping to another type",
+			query:    "MATCH (u:User) RETURN [x IN u.tags WHERE size(x) > 3 | size(x)]",
+			wantType: "[]int",
+		},
 		// Nested list comprehensions
 		{
 			name:     "size of list comprehension variable",
@@ -1354,6 +1462,74 @@ func TestTypeInference_ListComprehensionVariableBinding(t *testing.T) {
 	}
 }
 
+func TestTypeInference_ListComprehensionFilterParameters(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"User": {
+				Name: "User",
+				Fields: []*analysis.Field{
+					{Name: "tags", Type: analysis.SliceOf(analysis.TypeString)},
+					{Name: "scores", Type: analysis.SliceOf(analysis.TypeInt)},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		param    string
+		wantType string
+	}{
+		{
+			name:     "compared with loop variable",
+			query:    "MATCH (u:User) RETURN [x IN u.tags WHERE x = $tag]",
+			param:    "tag",
+			wantType: "string",
+		},
+		{
+			name:     "loop variable on the right",
+			query:    "MATCH (u:User) RETURN [s IN u.scores WHERE $score = s | s * 2]",
+			param:    "score",
+			wantType: "int",
+		},
+		{
+			name:     "used in the filter without a type",
+			query:    "MATCH (u:User) RETURN [x IN u.tags WHERE size(x) > $min]",
+			param:    "min",
+			wantType: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := cypher.NewAnalyzer().AnalyzeQueryWithSchema(tt.query, schema)
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+
+			var param *scaf.ParameterInfo
+			for i := range metadata.Parameters {
+				if metadata.Parameters[i].Name == tt.param {
+					param = &metadata.Parameters[i]
+				}
+			}
+
+			if param == nil {
+				t.Fatalf("parameter $%s not found in %+v", tt.param, metadata.Parameters)
+			}
+
+			if got := typeString(param.Type); got != tt.wantType {
+				t.Errorf("$%s type = %q, want %q", tt.param, got, tt.wantType)
+			}
+		})
+	}
+}
+
 // TestTypeInference_APOCFunctions tests type inference for APOC functions.
 func TestTypeInference_APOCFunctions(t *testing.T) {
 	t.Parallel()
@@ -1627,6 +1803,23 @@ func TestTypeInference_APOCFunctions(t *testing.T) {
 			query:    "RETURN apoc.bitwise.op(5, '&', 3)",
 			wantType: "int64",
 		},
+
+		// APOC Path functions
+		{
+			name:     "apoc.path.combine",
+			query:    "MATCH p1 = (a)-[]->(b), p2 = (b)-[]->(c) RETURN apoc.path.combine(p1, p2)",
+			wantType: "neo4j.Path",
+		},
+		{
+			name:     "apoc.path.subgraphNodes",
+			query:    "MATCH (u) RETURN apoc.path.subgraphNodes(u, {maxLevel: 2})",
+			wantType: "[]*neo4j.Node",
+		},
+		{
+			name:     "apoc.path.expand",
+			query:    "MATCH (u) RETURN apoc.path.expand(u, 'KNOWS>', '+User', 1, 3)",
+			wantType: "[]neo4j.Path",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1701,6 +1894,32 @@ func TestTypeInference_PatternComprehensionVariableBinding(t *testing.T) {
 			query:    "MATCH (m:Movie) RETURN [(p:Person)-[:ACTED_IN]->(m) | p.age]",
 			wantType: "[]int",
 		},
+		// Pattern comprehension returning whole nodes
+		{
+			name:     "pattern comprehension returns matched node",
+			query:    "MATCH (p:Person) RETURN [(p)-[:ACTED_IN]->(m:Movie) | m] AS movies",
+			wantType: "[]*Movie",
+		},
+		{
+			name:     "pattern comprehension with WHERE filter",
+			query:    "MATCH (p:Person) RETURN [(p)-[:ACTED_IN]->(m:Movie) WHERE m.year > 2000 | m.title] AS titles",
+			wantType: "[]string",
+		},
+		{
+			name:     "pattern comprehension with arithmetic projection",
+			query:    "MATCH (p:Person) RETURN [(p)-[:ACTED_IN]->(m:Movie) | m.year + 1] AS years",
+			wantType: "[]int",
+		},
+		{
+			name:     "pattern comprehension binds named path",
+			query:    "MATCH (p:Person) RETURN [path = (p)-[:ACTED_IN]->(:Movie) | path] AS paths",
+			wantType: "[]neo4j.Path",
+		},
+		{
+			name:     "size of pattern comprehension",
+			query:    "MATCH (p:Person) RETURN size([(p)-[:ACTED_IN]->(m:Movie) | m]) AS n",
+			wantType: "int",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1722,3 +1941,44 @@ func TestTypeInference_PatternComprehensionVariableBinding(t *testing.T) {
 		})
 	}
 }
+
+func TestTypeInference_PatternComprehensionParameters(t *testing.T) {
+	t.Parallel()
+
+	schema := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"Movie": {
+				Name: "Movie",
+				Fields: []*analysis.Field{
+					{Name: "title", Type: analysis.TypeString},
+					{Name: "year", Type: analysis.TypeInt},
+				},
+			},
+		},
+	}
+
+	query := "MATCH (p:Person) RETURN [(p)-[:ACTED_IN]->(m:Movie {title: $title}) WHERE m.year > $year | m.title]"
+
+	metadata, err := cypher.NewAnalyzer().AnalyzeQueryWithSchema(query, schema)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, p := range metadata.Parameters {
+		got[p.Name] = typeString(p.Type)
+	}
+
+	for name, want := range map[string]string{"title": "string", "year": "int"} {
+		if got[name] != want {
+			t.Errorf("param %s type = %q, want %q", name, got[name], want)
+		}
+	}
+
+	if len(metadata.Returns) != 1 {
+		t.Fatalf("expected 1 return, got %d", len(metadata.Returns))
+	}
+	if metadata.Returns[0].Expression != "[(p)-[:ACTED_IN]->(m:Movie {title: $title}) WHERE m.year > $year | m.title]" {
+		t.Errorf("return expression = %q", metadata.Returns[0].Expression)
+	}
+}
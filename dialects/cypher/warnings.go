@@ -0,0 +1,277 @@
+package cypher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rlch/scaf"
+	cyphergrammar "github.com/rlch/scaf/dialects/cypher/grammar"
+)
+
+// queryWarnings returns the non-fatal problems found in a query: deprecated
+// functions, disconnected MATCH patterns, and LIMIT without ORDER BY. With a
+// schema, property accesses on nodes without a label are also reported, since
+// their properties can't be checked.
+func queryWarnings(ast *cyphergrammar.Script, ctx *queryContext) []scaf.QueryWarning {
+	var warnings []scaf.QueryWarning
+
+	warnings = append(warnings, deprecatedFunctionWarnings(ast)...)
+
+	ast.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		if sq, ok := node.(*cyphergrammar.SingleQuery); ok {
+			warnings = append(warnings, cartesianProductWarnings(sq)...)
+			warnings = append(warnings, unorderedLimitWarnings(sq)...)
+		}
+
+		return true
+	}))
+
+	if ctx.schema != nil {
+		warnings = append(warnings, unlabeledPropertyWarnings(ast)...)
+	}
+
+	return warnings
+}
+
+// deprecatedFunctionWarnings reports exists(n.prop), removed in Neo4j 5 in
+// favour of IS NOT NULL. EXISTS { ... } subqueries are not affected.
+func deprecatedFunctionWarnings(ast *cyphergrammar.Script) []scaf.QueryWarning {
+	var warnings []scaf.QueryWarning
+
+	ast.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		call, ok := node.(*cyphergrammar.FunctionCall)
+		if !ok || call.Name == nil || len(call.Args) != 1 {
+			return true
+		}
+
+		if strings.EqualFold(strings.Join(call.Name.Parts, "."), "exists") {
+			warnings = append(warnings, scaf.QueryWarning{
+				Code:    "deprecated-function",
+				Message: "deprecated function exists() is removed in Neo4j 5; use IS NOT NULL instead",
+				Range:   scaf.QueryDiagnosticRange{StartPos: call.Pos, EndPos: call.EndPos},
+			})
+		}
+
+		return true
+	}))
+
+	return warnings
+}
+
+// cartesianProductWarnings reports MATCH patterns in a single query that share
+// no variables, so the database pairs every row of one with every row of the
+// other. Patterns are connected through node and relationship variables.
+// The warning is on the first pattern that isn't connected to the one before.
+func cartesianProductWarnings(sq *cyphergrammar.SingleQuery) []scaf.QueryWarning {
+	// components holds the variables of each group of connected patterns,
+	// in order of first appearance; names and parts hold the first variable
+	// and pattern part of each.
+	var components []map[string]bool
+
+	var (
+		names []string
+		parts []*cyphergrammar.PatternPart
+	)
+
+	for _, clause := range sq.Clauses {
+		if clause.Reading == nil || clause.Reading.Match == nil || clause.Reading.Match.Pattern == nil {
+			continue
+		}
+
+		for _, part := range clause.Reading.Match.Pattern.Parts {
+			vars, first := patternPartVariables(part)
+
+			target := -1
+
+			for i := 0; i < len(components); i++ {
+				if !sharesVariable(components[i], vars) {
+					continue
+				}
+
+				if target < 0 {
+					target = i
+
+					continue
+				}
+
+				// Connects two earlier groups; fold this one into the first
+				for v := range components[i] {
+					components[target][v] = true
+				}
+
+				components = append(components[:i], components[i+1:]...)
+				names = append(names[:i], names[i+1:]...)
+				parts = append(parts[:i], parts[i+1:]...)
+				i--
+			}
+
+			if target < 0 {
+				components = append(components, vars)
+				names = append(names, first)
+				parts = append(parts, part)
+
+				continue
+			}
+
+			for v := range vars {
+				components[target][v] = true
+			}
+		}
+	}
+
+	if len(components) < 2 {
+		return nil
+	}
+
+	patterns := make([]string, len(names))
+	for i, name := range names {
+		patterns[i] = "(" + name + ")"
+	}
+
+	return []scaf.QueryWarning{{
+		Code: "cartesian-product",
+		Message: fmt.Sprintf("cartesian product between disconnected patterns %s; connect them with a relationship",
+			strings.Join(patterns, ", ")),
+		Range: scaf.QueryDiagnosticRange{StartPos: parts[1].Pos, EndPos: parts[1].EndPos},
+	}}
+}
+
+// patternPartVariables returns the node and relationship variables of a
+// pattern part, and the first of them. Variables inside property maps and
+// inline WHERE predicates are references, not part of the pattern.
+func patternPartVariables(part *cyphergrammar.PatternPart) (map[string]bool, string) {
+	vars := make(map[string]bool)
+
+	var first string
+
+	add := func(name string) {
+		if name == "" {
+			return
+		}
+
+		if first == "" {
+			first = name
+		}
+
+		vars[name] = true
+	}
+
+	cyphergrammar.Inspect(part, func(node any) bool {
+		switch n := node.(type) {
+		case *cyphergrammar.Expression:
+			return false
+		case *cyphergrammar.NodePattern:
+			add(n.Variable)
+		case *cyphergrammar.RelationshipDetail:
+			add(n.Variable)
+		}
+
+		return true
+	})
+
+	return vars, first
+}
+
+// sharesVariable reports whether a and b have a variable in common.
+func sharesVariable(a, b map[string]bool) bool {
+	for v := range b {
+		if a[v] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unorderedLimitWarnings reports WITH and RETURN projections that LIMIT rows
+// that aren't ordered, which returns an arbitrary subset. A RETURN is ordered
+// by a preceding WITH ... ORDER BY as for QueryMetadata.IsOrdered, and a
+// projection of only aggregates is a single row, which needs no order.
+func unorderedLimitWarnings(sq *cyphergrammar.SingleQuery) []scaf.QueryWarning {
+	var warnings []scaf.QueryWarning
+
+	ordered := false
+
+	for _, clause := range sq.Clauses {
+		var body *cyphergrammar.ProjectionBody
+
+		switch {
+		case clause.With != nil && clause.With.Body != nil:
+			body = clause.With.Body
+		case clause.Return != nil && clause.Return.Body != nil:
+			body = clause.Return.Body
+		default:
+			ordered = false
+
+			continue
+		}
+
+		inherited := ordered && !hasAggregate(body)
+		if body.Limit != nil && body.Order == nil && !inherited && !aggregatesOnly(body) {
+			keyword := "RETURN"
+			if clause.With != nil {
+				keyword = "WITH"
+			}
+
+			rng := textRange(body.Limit.Pos, "LIMIT")
+			if body.Limit.Expr != nil {
+				rng.EndPos = body.Limit.Expr.EndPos
+			}
+
+			warnings = append(warnings, scaf.QueryWarning{
+				Code:    "limit-without-order-by",
+				Message: fmt.Sprintf("LIMIT without ORDER BY on %s returns an arbitrary subset of rows", keyword),
+				Range:   rng,
+			})
+		}
+
+		ordered = body.Order != nil || inherited
+	}
+
+	return warnings
+}
+
+// unlabeledPropertyWarnings reports property accesses on variables bound only
+// by node patterns without a label, e.g. n.name after MATCH (n).
+func unlabeledPropertyWarnings(ast *cyphergrammar.Script) []scaf.QueryWarning {
+	labeled := make(map[string]bool)
+
+	ast.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		if n, ok := node.(*cyphergrammar.NodePattern); ok && n.Variable != "" {
+			labeled[n.Variable] = labeled[n.Variable] || (n.Labels != nil && len(n.Labels.Labels) > 0)
+		}
+
+		return true
+	}))
+
+	var warnings []scaf.QueryWarning
+
+	seen := make(map[string]bool)
+
+	ast.Walk(cyphergrammar.VisitorFunc(func(node cyphergrammar.Node) bool {
+		expr, ok := node.(*cyphergrammar.PostfixExpr)
+		if !ok || expr.Atom == nil || len(expr.Suffixes) == 0 || expr.Suffixes[0].Property == "" {
+			return true
+		}
+
+		variable := expr.Atom.Variable
+		if isLabeled, isNode := labeled[variable]; !isNode || isLabeled {
+			return true
+		}
+
+		access := variable + "." + expr.Suffixes[0].Property
+		if !seen[access] {
+			seen[access] = true
+			warnings = append(warnings, scaf.QueryWarning{
+				Code: "unlabeled-property-access",
+				Message: fmt.Sprintf("property access %s on unlabeled node %s can't be checked against the schema; add a label",
+					access, variable),
+				Range: textRange(expr.Pos, access),
+			})
+		}
+
+		return true
+	}))
+
+	return warnings
+}
@@ -0,0 +1,555 @@
+package sql
+
+import (
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+	sqlgrammar "github.com/rlch/scaf/dialects/sql/grammar"
+)
+
+func init() {
+	// Register the SQL analyzer with the analyzer registry so the LSP and
+	// linter can analyze SQL queries without importing this package directly.
+	scaf.RegisterAnalyzer(scaf.DialectSQL, func() scaf.QueryAnalyzer {
+		return NewAnalyzer()
+	})
+}
+
+// Analyzer implements scaf.QueryAnalyzer for SQL queries.
+type Analyzer struct{}
+
+// NewAnalyzer creates a new SQL query analyzer.
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{}
+}
+
+// AnalyzeQuery parses a SQL query and extracts metadata.
+func (a *Analyzer) AnalyzeQuery(query string) (*scaf.QueryMetadata, error) {
+	return a.AnalyzeQueryWithSchema(query, nil)
+}
+
+// AnalyzeQueryWithSchema parses a SQL query and extracts metadata with type inference.
+// If schema is provided, it infers types for parameters and returns from the
+// columns they are compared with or select.
+//
+// Parameters are found in any statement. Returns, bindings, and cardinality
+// are only extracted from SELECT statements the grammar can parse.
+func (a *Analyzer) AnalyzeQueryWithSchema(query string, schema *analysis.TypeSchema) (*scaf.QueryMetadata, error) {
+	result := &scaf.QueryMetadata{
+		Parameters: extractParameters(query),
+		Returns:    []scaf.ReturnInfo{},
+		Bindings:   make(map[string][]string),
+	}
+
+	stmt, err := sqlgrammar.Parse(query)
+	if err != nil {
+		// Return partial results even on parse errors - parameters are still
+		// useful for completion and for statements outside the grammar
+		return result, nil
+	}
+
+	scope := newScope(stmt, schema)
+
+	for _, table := range stmt.Tables() {
+		name := table.Name
+		if model := scope.tables[table.Binding()]; model != nil {
+			name = model.Name
+		}
+
+		result.Bindings[table.Binding()] = []string{name}
+	}
+
+	inferParameterTypes(stmt, result, scope)
+	extractReturns(query, stmt, result, scope)
+	result.ReturnsOne = returnsOne(stmt, scope)
+
+	return result, nil
+}
+
+var _ analysis.SchemaAwareAnalyzer = (*Analyzer)(nil)
+
+// scope resolves the tables and columns a SELECT statement refers to.
+type scope struct {
+	// tables maps each binding (alias, or table name if unaliased) to its
+	// model, or nil if the table is not in the schema.
+	tables map[string]*analysis.Model
+	// order lists the bindings in FROM order, for resolving unqualified columns.
+	order []string
+}
+
+func newScope(stmt *sqlgrammar.Select, schema *analysis.TypeSchema) *scope {
+	s := &scope{tables: make(map[string]*analysis.Model)}
+
+	for _, table := range stmt.Tables() {
+		s.tables[table.Binding()] = findModel(schema, table.Name)
+		s.order = append(s.order, table.Binding())
+	}
+
+	return s
+}
+
+// resolveColumn returns the schema field a column reference refers to, or nil
+// if it cannot be resolved. Unqualified columns resolve against the first
+// table in FROM order that has them.
+func (s *scope) resolveColumn(col *sqlgrammar.ColumnRef) *analysis.Field {
+	if col == nil {
+		return nil
+	}
+
+	if qualifier := col.Qualifier(); qualifier != "" {
+		return findField(s.tables[qualifier], col.Name())
+	}
+
+	for _, binding := range s.order {
+		if field := findField(s.tables[binding], col.Name()); field != nil {
+			return field
+		}
+	}
+
+	return nil
+}
+
+// findModel looks up the model for a table. SQL identifiers are
+// case-insensitive, so an exact match is preferred but not required.
+func findModel(schema *analysis.TypeSchema, table string) *analysis.Model {
+	if schema == nil {
+		return nil
+	}
+
+	if model, ok := schema.Models[table]; ok {
+		return model
+	}
+
+	for name, model := range schema.Models {
+		if strings.EqualFold(name, table) {
+			return model
+		}
+	}
+
+	return nil
+}
+
+// findField looks up a column on a model, ignoring case.
+func findField(model *analysis.Model, column string) *analysis.Field {
+	if model == nil {
+		return nil
+	}
+
+	for _, field := range model.Fields {
+		if strings.EqualFold(field.Name, column) {
+			return field
+		}
+	}
+
+	return nil
+}
+
+// extractParameters finds every $parameter in the query by scanning tokens,
+// so it works for any statement, not just those the grammar parses.
+func extractParameters(query string) []scaf.ParameterInfo {
+	params := []scaf.ParameterInfo{}
+	indexByName := make(map[string]int)
+
+	for _, token := range queryTokens(query) {
+		if token.Type != paramToken {
+			continue
+		}
+
+		name := strings.TrimPrefix(token.Value, "$")
+		if idx, exists := indexByName[name]; exists {
+			params[idx].Count++
+
+			continue
+		}
+
+		indexByName[name] = len(params)
+		params = append(params, scaf.ParameterInfo{
+			Name:     name,
+			Position: token.Pos.Offset,
+			Line:     token.Pos.Line,
+			Column:   token.Pos.Column,
+			Length:   len(token.Value),
+			Count:    1,
+		})
+	}
+
+	return params
+}
+
+var (
+	paramToken   = sqlgrammar.Lexer.Symbols()["Param"]
+	keywordToken = sqlgrammar.Lexer.Symbols()["Keyword"]
+	identToken   = sqlgrammar.Lexer.Symbols()["Ident"]
+	quotedToken  = sqlgrammar.Lexer.Symbols()["QuotedIdent"]
+	punctToken   = sqlgrammar.Lexer.Symbols()["Punct"]
+	elidedTokens = map[lexer.TokenType]bool{
+		sqlgrammar.Lexer.Symbols()["Whitespace"]:   true,
+		sqlgrammar.Lexer.Symbols()["BlockComment"]: true,
+		sqlgrammar.Lexer.Symbols()["LineComment"]:  true,
+	}
+)
+
+// queryTokens lexes query, dropping whitespace and comments. Lexing stops at
+// the first error, returning the tokens read so far.
+func queryTokens(query string) []lexer.Token {
+	lex, err := sqlgrammar.Lexer.LexString("", query)
+	if err != nil {
+		return nil
+	}
+
+	var tokens []lexer.Token
+
+	for {
+		token, err := lex.Next()
+		if err != nil || token.EOF() {
+			return tokens
+		}
+
+		if !elidedTokens[token.Type] {
+			tokens = append(tokens, token)
+		}
+	}
+}
+
+// isSelect reports whether the query is a SELECT statement, i.e. one the
+// grammar is expected to parse.
+func isSelect(query string) bool {
+	tokens := queryTokens(query)
+
+	return len(tokens) > 0 && tokens[0].Type == keywordToken && strings.EqualFold(tokens[0].Value, "SELECT")
+}
+
+// inferParameterTypes types parameters from the columns they are compared
+// with, e.g. u.id = $id gives $id the type of users.id. LIKE patterns are
+// strings, and LIMIT and OFFSET take integers.
+func inferParameterTypes(stmt *sqlgrammar.Select, result *scaf.QueryMetadata, scope *scope) {
+	setType := func(prim *sqlgrammar.Primary, typ *analysis.Type) {
+		if prim == nil || prim.Param == "" || typ == nil {
+			return
+		}
+
+		name := strings.TrimPrefix(prim.Param, "$")
+		for i := range result.Parameters {
+			if result.Parameters[i].Name == name && result.Parameters[i].Type == nil {
+				result.Parameters[i].Type = typ
+			}
+		}
+	}
+
+	sqlgrammar.Inspect(stmt, func(node any) bool {
+		cmp, ok := node.(*sqlgrammar.Comparison)
+		if !ok || cmp.Tail == nil {
+			return true
+		}
+
+		left := cmp.Left.Primary()
+		tail := cmp.Tail
+
+		var columnType *analysis.Type
+		if left != nil {
+			if field := scope.resolveColumn(left.Column); field != nil {
+				columnType = field.Type
+			}
+		}
+
+		switch {
+		case tail.Right != nil:
+			right := tail.Right.Primary()
+			setType(right, columnType)
+
+			// $x = u.id works as well as u.id = $x
+			if right != nil {
+				if field := scope.resolveColumn(right.Column); field != nil {
+					setType(left, field.Type)
+				}
+			}
+		case tail.In != nil:
+			for _, value := range tail.In.Values {
+				setType(value.Primary(), columnType)
+			}
+		case tail.Like != nil:
+			setType(tail.Like.Pattern.Primary(), analysis.TypeString)
+		case tail.Between != nil:
+			setType(tail.Between.Low.Primary(), columnType)
+			setType(tail.Between.High.Primary(), columnType)
+		}
+
+		return true
+	})
+
+	setType(stmt.Limit.Primary(), analysis.TypeInt)
+	setType(stmt.Offset.Primary(), analysis.TypeInt)
+}
+
+// extractReturns records one return per SELECT item.
+func extractReturns(query string, stmt *sqlgrammar.Select, result *scaf.QueryMetadata, scope *scope) {
+	for _, item := range stmt.Items {
+		expression := query[item.Pos.Offset:item.EndPos.Offset]
+		info := scaf.ReturnInfo{
+			Expression: expression,
+			Required:   true,
+			Line:       item.Pos.Line,
+			Column:     item.Pos.Column,
+			Length:     len(expression),
+		}
+
+		switch {
+		case item.Star:
+			info.Name = "*"
+			info.IsWildcard = true
+		case item.TableStar != "":
+			info.Name = item.TableStar + ".*"
+			info.IsWildcard = true
+		default:
+			info.Expression = query[item.Expr.Pos.Offset:item.Expr.EndPos.Offset]
+
+			// SQL names an unaliased column after the column or function rather
+			// than the expression text, so Alias carries the output column name
+			info.Alias = columnName(item)
+			info.Name = info.Alias
+			if info.Name == "" {
+				info.Name = info.Expression
+			}
+
+			info.Type = inferType(item.Expr, scope)
+			info.IsAggregate = isAggregate(item.Expr)
+
+			// Default to required (non-nullable) unless the schema says otherwise
+			if field := scope.resolveColumn(item.Expr.Column()); field != nil {
+				info.Required = field.Required
+			}
+		}
+
+		result.Returns = append(result.Returns, info)
+	}
+}
+
+// columnName is the name of the column a SELECT item produces: its alias,
+// the column name, or the lowercased function name. Returns "" for other
+// expressions, which the database names arbitrarily.
+func columnName(item *sqlgrammar.SelectItem) string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+
+	if prim := item.Expr.Primary(); prim != nil {
+		switch {
+		case prim.Column != nil:
+			return prim.Column.Name()
+		case prim.Call != nil:
+			return strings.ToLower(prim.Call.Name)
+		}
+	}
+
+	return ""
+}
+
+// sqlFunctionTypes are the return types of common functions whose result
+// does not depend on their arguments.
+var sqlFunctionTypes = map[string]*analysis.Type{
+	"count":        analysis.TypeInt64,
+	"avg":          analysis.TypeFloat64,
+	"string_agg":   analysis.TypeString,
+	"lower":        analysis.TypeString,
+	"upper":        analysis.TypeString,
+	"trim":         analysis.TypeString,
+	"concat":       analysis.TypeString,
+	"substring":    analysis.TypeString,
+	"replace":      analysis.TypeString,
+	"length":       analysis.TypeInt,
+	"char_length":  analysis.TypeInt,
+	"now":          analysis.TypeDateTime,
+	"current_date": analysis.TypeDate,
+}
+
+// argTypedFunctions return a value of the same type as their first argument.
+var argTypedFunctions = map[string]bool{
+	"sum":      true,
+	"min":      true,
+	"max":      true,
+	"coalesce": true,
+	"abs":      true,
+}
+
+// aggregateFunctions collapse rows into a single value.
+var aggregateFunctions = map[string]bool{
+	"count":      true,
+	"sum":        true,
+	"avg":        true,
+	"min":        true,
+	"max":        true,
+	"array_agg":  true,
+	"string_agg": true,
+}
+
+// sqlCastTypes maps ::type casts to Go types.
+var sqlCastTypes = map[string]*analysis.Type{
+	"text":        analysis.TypeString,
+	"varchar":     analysis.TypeString,
+	"int":         analysis.TypeInt,
+	"integer":     analysis.TypeInt,
+	"bigint":      analysis.TypeInt64,
+	"float":       analysis.TypeFloat64,
+	"real":        analysis.TypeFloat64,
+	"numeric":     analysis.TypeFloat64,
+	"bool":        analysis.TypeBool,
+	"boolean":     analysis.TypeBool,
+	"timestamp":   analysis.TypeDateTime,
+	"timestamptz": analysis.TypeDateTime,
+	"date":        analysis.TypeDate,
+}
+
+// inferType infers the Go type of an expression, or nil if unknown.
+func inferType(expr *sqlgrammar.Expression, scope *scope) *analysis.Type {
+	if expr == nil || len(expr.Or) == 0 {
+		return nil
+	}
+
+	// Anything involving OR, AND, NOT, or a comparison is a boolean
+	if len(expr.Or) > 1 || len(expr.Or[0].And) > 1 {
+		return analysis.TypeBool
+	}
+
+	not := expr.Or[0].And[0]
+	if not.Not || not.Comparison.Tail != nil {
+		return analysis.TypeBool
+	}
+
+	return inferAdditiveType(not.Comparison.Left, scope)
+}
+
+func inferAdditiveType(add *sqlgrammar.Additive, scope *scope) *analysis.Type {
+	for _, op := range add.Rest {
+		if op.Op == "||" {
+			return analysis.TypeString
+		}
+	}
+
+	// Arithmetic keeps the type of its first operand
+	unary := add.Left.Left
+	if unary.Cast != "" {
+		return sqlCastTypes[strings.ToLower(unary.Cast)]
+	}
+
+	return inferPrimaryType(unary.Primary, scope)
+}
+
+func inferPrimaryType(prim *sqlgrammar.Primary, scope *scope) *analysis.Type {
+	switch {
+	case prim.Literal != nil:
+		return inferLiteralType(prim.Literal)
+	case prim.Column != nil:
+		if field := scope.resolveColumn(prim.Column); field != nil {
+			return field.Type
+		}
+	case prim.Call != nil:
+		return inferCallType(prim.Call, scope)
+	case prim.Paren != nil:
+		return inferType(prim.Paren, scope)
+	}
+
+	return nil
+}
+
+func inferLiteralType(lit *sqlgrammar.Literal) *analysis.Type {
+	switch {
+	case lit.String != nil:
+		return analysis.TypeString
+	case lit.Int != nil:
+		return analysis.TypeInt
+	case lit.Float != nil:
+		return analysis.TypeFloat64
+	case lit.True, lit.False:
+		return analysis.TypeBool
+	default:
+		return nil
+	}
+}
+
+func inferCallType(call *sqlgrammar.FunctionCall, scope *scope) *analysis.Type {
+	name := strings.ToLower(call.Name)
+
+	if typ, ok := sqlFunctionTypes[name]; ok {
+		return typ
+	}
+
+	if len(call.Args) == 0 {
+		return nil
+	}
+
+	if argTypedFunctions[name] {
+		return inferType(call.Args[0], scope)
+	}
+
+	if name == "array_agg" {
+		if elem := inferType(call.Args[0], scope); elem != nil {
+			return analysis.SliceOf(elem)
+		}
+	}
+
+	return nil
+}
+
+// isAggregate reports whether the expression is an aggregate function call.
+func isAggregate(expr *sqlgrammar.Expression) bool {
+	prim := expr.Primary()
+
+	return prim != nil && prim.Call != nil && aggregateFunctions[strings.ToLower(prim.Call.Name)]
+}
+
+// returnsOne reports whether the statement returns at most one row: it ends
+// in LIMIT 1, aggregates without GROUP BY, or filters a single table by
+// equality on a unique column.
+func returnsOne(stmt *sqlgrammar.Select, scope *scope) bool {
+	if prim := stmt.Limit.Primary(); prim != nil && prim.Literal != nil && prim.Literal.Int != nil && *prim.Literal.Int == "1" {
+		return true
+	}
+
+	if len(stmt.GroupBy) == 0 && allAggregates(stmt.Items) {
+		return true
+	}
+
+	if len(stmt.Tables()) != 1 || stmt.Where == nil || len(stmt.Where.Or) != 1 {
+		return false
+	}
+
+	for _, not := range stmt.Where.Or[0].And {
+		if not.Not || not.Comparison.Tail == nil || not.Comparison.Tail.Op != "=" {
+			continue
+		}
+
+		left := not.Comparison.Left.Primary()
+		right := not.Comparison.Tail.Right.Primary()
+
+		if uniqueMatch(left, right, scope) || uniqueMatch(right, left, scope) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// uniqueMatch reports whether column is a unique column compared with a
+// parameter or literal value.
+func uniqueMatch(column, value *sqlgrammar.Primary, scope *scope) bool {
+	if column == nil || value == nil || (value.Param == "" && value.Literal == nil) {
+		return false
+	}
+
+	field := scope.resolveColumn(column.Column)
+
+	return field != nil && field.Unique
+}
+
+func allAggregates(items []*sqlgrammar.SelectItem) bool {
+	for _, item := range items {
+		if item.Expr == nil || !isAggregate(item.Expr) {
+			return false
+		}
+	}
+
+	return len(items) > 0
+}
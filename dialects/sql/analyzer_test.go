@@ -0,0 +1,340 @@
+package sql_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+	"github.com/rlch/scaf/dialects/sql"
+)
+
+// testSchema has users and posts tables.
+func testSchema() *analysis.TypeSchema {
+	return &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"users": {
+				Name: "users",
+				Fields: []*analysis.Field{
+					{Name: "id", Type: analysis.TypeInt64, Required: true, Unique: true},
+					{Name: "name", Type: analysis.TypeString, Required: true},
+					{Name: "email", Type: analysis.TypeString, Required: true, Unique: true},
+					{Name: "age", Type: analysis.TypeInt},
+				},
+			},
+			"posts": {
+				Name: "posts",
+				Fields: []*analysis.Field{
+					{Name: "id", Type: analysis.TypeInt64, Required: true, Unique: true},
+					{Name: "user_id", Type: analysis.TypeInt64, Required: true},
+					{Name: "title", Type: analysis.TypeString, Required: true},
+				},
+			},
+		},
+	}
+}
+
+// typeStr returns the string representation of a type, or "" if nil.
+func typeStr(t *scaf.Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+func TestAnalyzer_AnalyzeQuery_Parameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]int // name -> count
+	}{
+		{
+			name:  "select",
+			query: "SELECT id FROM users WHERE id = $id AND age > $minAge",
+			want:  map[string]int{"id": 1, "minAge": 1},
+		},
+		{
+			name:  "repeated",
+			query: "SELECT id FROM users WHERE name = $q OR email = $q",
+			want:  map[string]int{"q": 2},
+		},
+		{
+			name:  "insert",
+			query: "INSERT INTO users (name, email) VALUES ($name, $email) RETURNING id",
+			want:  map[string]int{"name": 1, "email": 1},
+		},
+		{
+			name:  "param in string is not a param",
+			query: "SELECT id FROM users WHERE name = '$name'",
+			want:  map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := sql.NewAnalyzer().AnalyzeQuery(tt.query)
+			if err != nil {
+				t.Fatalf("AnalyzeQuery() error: %v", err)
+			}
+
+			got := make(map[string]int)
+			for _, p := range result.Parameters {
+				got[p.Name] = p.Count
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_ParameterPosition(t *testing.T) {
+	t.Parallel()
+
+	result, err := sql.NewAnalyzer().AnalyzeQuery("SELECT id\nFROM users WHERE id = $id")
+	if err != nil {
+		t.Fatalf("AnalyzeQuery() error: %v", err)
+	}
+
+	want := []scaf.ParameterInfo{{Name: "id", Position: 32, Line: 2, Column: 23, Length: 3, Count: 1}}
+	if diff := cmp.Diff(want, result.Parameters); diff != "" {
+		t.Errorf("parameters mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithSchema_ParameterTypes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string]string
+	}{
+		{
+			name:  "equality",
+			query: "SELECT name FROM users WHERE id = $id",
+			want:  map[string]string{"id": "int64"},
+		},
+		{
+			name:  "reversed equality",
+			query: "SELECT name FROM users u WHERE $name = u.name",
+			want:  map[string]string{"name": "string"},
+		},
+		{
+			name:  "joined column",
+			query: "SELECT u.name FROM users u JOIN posts p ON p.user_id = u.id WHERE p.title = $title",
+			want:  map[string]string{"title": "string"},
+		},
+		{
+			name:  "in and between",
+			query: "SELECT id FROM users WHERE id IN ($a, $b) AND age BETWEEN $lo AND $hi",
+			want:  map[string]string{"a": "int64", "b": "int64", "lo": "int", "hi": "int"},
+		},
+		{
+			name:  "like and limit",
+			query: "SELECT id FROM users WHERE name LIKE $pattern LIMIT $limit OFFSET $offset",
+			want:  map[string]string{"pattern": "string", "limit": "int", "offset": "int"},
+		},
+		{
+			name:  "unknown column",
+			query: "SELECT id FROM users WHERE nickname = $nick",
+			want:  map[string]string{"nick": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := sql.NewAnalyzer().AnalyzeQueryWithSchema(tt.query, testSchema())
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, p := range result.Parameters {
+				got[p.Name] = typeStr(p.Type)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parameter types mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithSchema_Returns(t *testing.T) {
+	t.Parallel()
+
+	type ret struct {
+		Name        string
+		Alias       string
+		Type        string
+		IsAggregate bool
+		IsWildcard  bool
+		Required    bool
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []ret
+	}{
+		{
+			name:  "columns",
+			query: "SELECT u.id, u.name AS display_name, age FROM users u",
+			want: []ret{
+				{Name: "id", Alias: "id", Type: "int64", Required: true},
+				{Name: "display_name", Alias: "display_name", Type: "string", Required: true},
+				{Name: "age", Alias: "age", Type: "int"},
+			},
+		},
+		{
+			name:  "wildcards",
+			query: "SELECT *, u.* FROM users u",
+			want: []ret{
+				{Name: "*", IsWildcard: true, Required: true},
+				{Name: "u.*", IsWildcard: true, Required: true},
+			},
+		},
+		{
+			name:  "aggregates",
+			query: "SELECT count(*), avg(age), max(name) AS last_name FROM users",
+			want: []ret{
+				{Name: "count", Alias: "count", Type: "int64", IsAggregate: true, Required: true},
+				{Name: "avg", Alias: "avg", Type: "float64", IsAggregate: true, Required: true},
+				{Name: "last_name", Alias: "last_name", Type: "string", IsAggregate: true, Required: true},
+			},
+		},
+		{
+			name:  "expressions",
+			query: "SELECT name || email AS label, age > 18 AS adult, id::text AS key, 1 AS one, age + 1 FROM users",
+			want: []ret{
+				{Name: "label", Alias: "label", Type: "string", Required: true},
+				{Name: "adult", Alias: "adult", Type: "bool", Required: true},
+				{Name: "key", Alias: "key", Type: "string", Required: true},
+				{Name: "one", Alias: "one", Type: "int", Required: true},
+				{Name: "age + 1", Type: "int", Required: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := sql.NewAnalyzer().AnalyzeQueryWithSchema(tt.query, testSchema())
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			var got []ret
+			for _, r := range result.Returns {
+				got = append(got, ret{
+					Name:        r.Name,
+					Alias:       r.Alias,
+					Type:        typeStr(r.Type),
+					IsAggregate: r.IsAggregate,
+					IsWildcard:  r.IsWildcard,
+					Required:    r.Required,
+				})
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("returns mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithSchema_Bindings(t *testing.T) {
+	t.Parallel()
+
+	result, err := sql.NewAnalyzer().AnalyzeQueryWithSchema(
+		"SELECT u.id FROM USERS u, comments c JOIN posts ON posts.user_id = u.id", testSchema())
+	if err != nil {
+		t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+	}
+
+	want := map[string][]string{
+		"u":     {"users"},
+		"c":     {"comments"},
+		"posts": {"posts"},
+	}
+	if diff := cmp.Diff(want, result.Bindings); diff != "" {
+		t.Errorf("bindings mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnalyzer_AnalyzeQueryWithSchema_ReturnsOne(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"unique equality", "SELECT name FROM users WHERE id = $id", true},
+		{"unique equality reversed", "SELECT name FROM users WHERE $email = email AND age > 1", true},
+		{"non-unique equality", "SELECT name FROM users WHERE name = $name", false},
+		{"unique under or", "SELECT name FROM users WHERE id = $id OR age = 1", false},
+		{"unique across join", "SELECT u.name FROM users u JOIN posts p ON p.user_id = u.id WHERE u.id = $id", false},
+		{"limit one", "SELECT name FROM users LIMIT 1", true},
+		{"aggregate only", "SELECT count(*) FROM users", true},
+		{"grouped aggregate", "SELECT count(*) FROM users GROUP BY age", false},
+		{"plain select", "SELECT name FROM users", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := sql.NewAnalyzer().AnalyzeQueryWithSchema(tt.query, testSchema())
+			if err != nil {
+				t.Fatalf("AnalyzeQueryWithSchema() error: %v", err)
+			}
+
+			if result.ReturnsOne != tt.want {
+				t.Errorf("ReturnsOne = %v, want %v", result.ReturnsOne, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AnalyzeQuery_Unparseable(t *testing.T) {
+	t.Parallel()
+
+	result, err := sql.NewAnalyzer().AnalyzeQuery("UPDATE users SET name = $name WHERE id = $id")
+	if err != nil {
+		t.Fatalf("AnalyzeQuery() error: %v", err)
+	}
+
+	if len(result.Parameters) != 2 {
+		t.Errorf("got %d parameters, want 2", len(result.Parameters))
+	}
+
+	if len(result.Returns) != 0 {
+		t.Errorf("got %d returns, want 0", len(result.Returns))
+	}
+}
+
+func TestDialect_Registered(t *testing.T) {
+	t.Parallel()
+
+	if scaf.GetDialect(scaf.DialectSQL) == nil {
+		t.Error("sql dialect not registered")
+	}
+
+	if scaf.GetAnalyzer(scaf.DialectSQL) == nil {
+		t.Error("sql analyzer not registered")
+	}
+
+	if scaf.GetDialectLSP(scaf.DialectSQL) == nil {
+		t.Error("sql dialect does not implement DialectLSP")
+	}
+}
@@ -0,0 +1,215 @@
+package sqlgrammar
+
+import "github.com/alecthomas/participle/v2/lexer"
+
+// ----------------------------------------------------------------------------
+// SQL AST
+//
+// This file defines the Abstract Syntax Tree for SQL SELECT statements.
+// Nodes that diagnostics point at carry EndPos as well as Pos.
+// ----------------------------------------------------------------------------
+
+// Select is a SELECT statement.
+type Select struct {
+	Pos      lexer.Position
+	EndPos   lexer.Position
+	Distinct bool          `"SELECT" ( @"DISTINCT" | "ALL" )?`
+	Items    []*SelectItem `@@ ( "," @@ )*`
+	From     *From         `( "FROM" @@ )?`
+	Where    *Expression   `( "WHERE" @@ )?`
+	GroupBy  []*Expression `( "GROUP" "BY" @@ ( "," @@ )* )?`
+	Having   *Expression   `( "HAVING" @@ )?`
+	OrderBy  []*OrderItem  `( "ORDER" "BY" @@ ( "," @@ )* )?`
+	Limit    *Expression   `( "LIMIT" @@ )?`
+	Offset   *Expression   `( "OFFSET" @@ )? ";"?`
+}
+
+// SelectItem is one entry in the SELECT list: *, table.*, or an expression
+// with an optional alias.
+type SelectItem struct {
+	Pos       lexer.Position
+	EndPos    lexer.Position
+	Star      bool        `(  @"*"`
+	TableStar string      ` | @( Ident | QuotedIdent ) "." "*"`
+	Expr      *Expression ` | @@ )`
+	Alias     string      `( "AS"? @( Ident | QuotedIdent ) )?`
+}
+
+// OrderItem is one ORDER BY entry.
+type OrderItem struct {
+	Pos  lexer.Position
+	Expr *Expression `@@`
+	Desc bool        `( @"DESC" | "ASC" )?`
+}
+
+// ----------------------------------------------------------------------------
+// FROM
+// ----------------------------------------------------------------------------
+
+// From is the FROM clause: comma-separated tables followed by joins.
+type From struct {
+	Pos    lexer.Position
+	Tables []*TableRef `@@ ( "," @@ )*`
+	Joins  []*Join     `@@*`
+}
+
+// TableRef is a table name with an optional schema qualifier and alias.
+type TableRef struct {
+	Pos    lexer.Position
+	EndPos lexer.Position
+	Schema string `( @( Ident | QuotedIdent ) "." )?`
+	Name   string `@( Ident | QuotedIdent )`
+	Alias  string `( "AS"? @( Ident | QuotedIdent ) )?`
+}
+
+// Join is a JOIN clause. Kind is the join type as written (e.g. "LEFT"),
+// or empty for a plain JOIN.
+type Join struct {
+	Pos   lexer.Position
+	Kind  string      `@( "INNER" | "LEFT" | "RIGHT" | "FULL" | "CROSS" )? "OUTER"? "JOIN"`
+	Table *TableRef   `@@`
+	On    *Expression `( "ON" @@ )?`
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+// ----------------------------------------------------------------------------
+
+// Expression is a boolean expression: a chain of AND expressions joined by OR.
+type Expression struct {
+	Pos    lexer.Position
+	EndPos lexer.Position
+	Or     []*AndExpr `@@ ( "OR" @@ )*`
+}
+
+// AndExpr is a chain of NOT expressions joined by AND.
+type AndExpr struct {
+	Pos lexer.Position
+	And []*NotExpr `@@ ( "AND" @@ )*`
+}
+
+// NotExpr is a comparison with an optional NOT prefix.
+type NotExpr struct {
+	Pos        lexer.Position
+	Not        bool        `@"NOT"?`
+	Comparison *Comparison `@@`
+}
+
+// Comparison is an arithmetic expression with an optional comparison tail.
+type Comparison struct {
+	Pos  lexer.Position
+	Left *Additive       `@@`
+	Tail *ComparisonTail `@@?`
+}
+
+// ComparisonTail is the right-hand side of a comparison.
+type ComparisonTail struct {
+	Pos     lexer.Position
+	Op      string    `(  @( "=" | "<>" | "!=" | "<=" | ">=" | "<" | ">" )`
+	Right   *Additive `   @@`
+	IsNull  *IsNull   ` | @@`
+	In      *InList   ` | @@`
+	Like    *Like     ` | @@`
+	Between *Between  ` | @@ )`
+}
+
+// IsNull is IS NULL or IS NOT NULL.
+type IsNull struct {
+	Not bool `"IS" @"NOT"? "NULL"`
+}
+
+// InList is [NOT] IN (values...).
+type InList struct {
+	Not    bool          `@"NOT"? "IN"`
+	Values []*Expression `"(" @@ ( "," @@ )* ")"`
+}
+
+// Like is [NOT] LIKE or ILIKE with a pattern.
+type Like struct {
+	Not     bool      `@"NOT"?`
+	Op      string    `@( "LIKE" | "ILIKE" )`
+	Pattern *Additive `@@`
+}
+
+// Between is [NOT] BETWEEN low AND high.
+type Between struct {
+	Not  bool      `@"NOT"? "BETWEEN"`
+	Low  *Additive `@@`
+	High *Additive `"AND" @@`
+}
+
+// Additive is a chain of multiplicative expressions joined by +, -, or ||.
+type Additive struct {
+	Pos  lexer.Position
+	Left *Multiplicative `@@`
+	Rest []*AddOp        `@@*`
+}
+
+// AddOp is one +, -, or || operation.
+type AddOp struct {
+	Op    string          `@( "+" | "-" | "||" )`
+	Right *Multiplicative `@@`
+}
+
+// Multiplicative is a chain of unary expressions joined by *, /, or %.
+type Multiplicative struct {
+	Pos  lexer.Position
+	Left *Unary   `@@`
+	Rest []*MulOp `@@*`
+}
+
+// MulOp is one *, /, or % operation.
+type MulOp struct {
+	Op    string `@( "*" | "/" | "%" )`
+	Right *Unary `@@`
+}
+
+// Unary is a primary expression with an optional sign and a PostgreSQL-style
+// ::type cast.
+type Unary struct {
+	Pos     lexer.Position
+	Negate  bool     `@"-"?`
+	Primary *Primary `@@`
+	Cast    string   `( "::" @( Ident | QuotedIdent ) )?`
+}
+
+// Primary is an atomic expression.
+type Primary struct {
+	Pos    lexer.Position
+	EndPos lexer.Position
+	// Order matters: literals first, then params, then function calls
+	// (which need lookahead for the parenthesis) before column references.
+	Literal *Literal      `(  @@`
+	Param   string        ` | @Param`
+	Call    *FunctionCall ` | @@`
+	Column  *ColumnRef    ` | @@`
+	Paren   *Expression   ` | "(" @@ ")" )`
+}
+
+// Literal is a constant value. Numbers and strings keep their source text.
+type Literal struct {
+	Pos    lexer.Position
+	String *string `(  @String`
+	Float  *string ` | @Float`
+	Int    *string ` | @Int`
+	True   bool    ` | @"TRUE"`
+	False  bool    ` | @"FALSE"`
+	Null   bool    ` | @"NULL" )`
+}
+
+// FunctionCall is a function call such as count(*) or lower(u.name).
+type FunctionCall struct {
+	Pos      lexer.Position
+	EndPos   lexer.Position
+	Name     string        `@Ident "("`
+	Distinct bool          `@"DISTINCT"?`
+	Star     bool          `(  @"*"`
+	Args     []*Expression ` | @@ ( "," @@ )* )? ")"`
+}
+
+// ColumnRef is a possibly qualified column name such as u.name.
+type ColumnRef struct {
+	Pos    lexer.Position
+	EndPos lexer.Position
+	Parts  []string `@( Ident | QuotedIdent ) ( "." @( Ident | QuotedIdent ) )*`
+}
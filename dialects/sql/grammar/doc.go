@@ -0,0 +1,16 @@
+// Package sqlgrammar provides a parser for SQL SELECT statements built with
+// participle.
+//
+// The grammar covers the common subset of SELECT shared by PostgreSQL and
+// MySQL: column lists with aliases, FROM with joins, WHERE, GROUP BY, HAVING,
+// ORDER BY, LIMIT, and OFFSET. Parameters use scaf's $name syntax.
+// Other statements can still be tokenized with Lexer, e.g. to find parameters.
+//
+// # Usage
+//
+//	stmt, err := sqlgrammar.Parse("SELECT u.name FROM users u WHERE u.id = $id")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	// Work with stmt...
+package sqlgrammar
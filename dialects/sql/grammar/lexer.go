@@ -0,0 +1,44 @@
+package sqlgrammar
+
+import (
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Keywords are the reserved words recognized by the grammar. They lex as
+// Keyword tokens, so they are never mistaken for identifiers or aliases.
+var Keywords = []string{
+	"SELECT", "DISTINCT", "ALL", "AS", "FROM", "WHERE",
+	"JOIN", "INNER", "LEFT", "RIGHT", "FULL", "OUTER", "CROSS", "ON",
+	"GROUP", "BY", "HAVING", "ORDER", "ASC", "DESC",
+	"LIMIT", "OFFSET",
+	"AND", "OR", "NOT", "IS", "NULL", "IN", "LIKE", "ILIKE", "BETWEEN",
+	"TRUE", "FALSE",
+}
+
+// Lexer defines the lexer for SQL queries.
+// Keywords are case-insensitive; identifiers preserve case.
+var Lexer = lexer.MustSimple([]lexer.SimpleRule{
+	// Whitespace and comments (elided from output)
+	{Name: "Whitespace", Pattern: `[ \t\r\n]+`},
+	{Name: "BlockComment", Pattern: `/\*[^*]*\*+(?:[^/*][^*]*\*+)*/`},
+	{Name: "LineComment", Pattern: `--[^\r\n]*`},
+
+	{Name: "Param", Pattern: `\$[a-zA-Z_][a-zA-Z0-9_]*`},
+	{Name: "String", Pattern: `'(?:[^']|'')*'`},
+	{Name: "QuotedIdent", Pattern: `"(?:[^"]|"")+"`},
+	{Name: "Float", Pattern: `(?:\d+\.\d*|\.\d+)(?:[eE][+-]?\d+)?`},
+	{Name: "Int", Pattern: `\d+`},
+
+	// Keywords must come before identifiers
+	{Name: "Keyword", Pattern: `(?i)\b(?:` + strings.Join(Keywords, "|") + `)\b`},
+	{Name: "Ident", Pattern: `[a-zA-Z_][a-zA-Z0-9_]*`},
+
+	// Multi-character operators must come before single characters
+	{Name: "Operator", Pattern: `<>|!=|<=|>=|\|\||::`},
+	{Name: "Punct", Pattern: `[-+*/%=<>(),.;]`},
+
+	// Anything else, so statements outside the grammar can still be tokenized
+	{Name: "Other", Pattern: `.`},
+})
@@ -0,0 +1,122 @@
+package sqlgrammar
+
+import (
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Parser is the SQL parser instance.
+var Parser = participle.MustBuild[Select](
+	participle.Lexer(Lexer),
+	participle.Elide("Whitespace", "BlockComment", "LineComment"),
+	participle.Map(unquoteIdent, "QuotedIdent"),
+	participle.UseLookahead(4),            // Distinguishes t.* and f(...) from column references
+	participle.CaseInsensitive("Keyword"), // SQL keywords are case-insensitive
+)
+
+// Parse parses a SQL SELECT statement into an AST.
+func Parse(query string) (*Select, error) {
+	return Parser.ParseString("", query)
+}
+
+// ParseBytes parses a SQL SELECT statement from bytes into an AST.
+func ParseBytes(query []byte) (*Select, error) {
+	return Parser.ParseBytes("", query)
+}
+
+// unquoteIdent strips the double quotes from a quoted identifier so "User"
+// and User compare equal to schema names.
+func unquoteIdent(token lexer.Token) (lexer.Token, error) {
+	token.Value = strings.ReplaceAll(token.Value[1:len(token.Value)-1], `""`, `"`)
+
+	return token, nil
+}
+
+// Name returns the column name, i.e. the last part of the reference.
+func (c *ColumnRef) Name() string {
+	if c == nil || len(c.Parts) == 0 {
+		return ""
+	}
+
+	return c.Parts[len(c.Parts)-1]
+}
+
+// Qualifier returns the table or alias qualifying the column, or "" if the
+// reference is unqualified.
+func (c *ColumnRef) Qualifier() string {
+	if c == nil || len(c.Parts) < 2 {
+		return ""
+	}
+
+	return c.Parts[len(c.Parts)-2]
+}
+
+// Binding returns the name the table is referred to by in the query: its
+// alias if it has one, otherwise its name.
+func (t *TableRef) Binding() string {
+	if t == nil {
+		return ""
+	}
+
+	if t.Alias != "" {
+		return t.Alias
+	}
+
+	return t.Name
+}
+
+// Tables returns every table in the FROM clause, including joined tables.
+func (s *Select) Tables() []*TableRef {
+	if s == nil || s.From == nil {
+		return nil
+	}
+
+	tables := append([]*TableRef(nil), s.From.Tables...)
+	for _, join := range s.From.Joins {
+		tables = append(tables, join.Table)
+	}
+
+	return tables
+}
+
+// Column returns the column reference if the expression is nothing but a
+// single column, or nil otherwise.
+func (e *Expression) Column() *ColumnRef {
+	if p := e.Primary(); p != nil {
+		return p.Column
+	}
+
+	return nil
+}
+
+// Primary returns the primary expression if the expression is nothing but a
+// single unsigned, uncast primary, or nil otherwise.
+func (e *Expression) Primary() *Primary {
+	if e == nil || len(e.Or) != 1 || len(e.Or[0].And) != 1 {
+		return nil
+	}
+
+	not := e.Or[0].And[0]
+	if not.Not || not.Comparison.Tail != nil {
+		return nil
+	}
+
+	return not.Comparison.Left.Primary()
+}
+
+// Primary returns the primary expression if the additive expression is
+// nothing but a single unsigned, uncast primary, or nil otherwise.
+func (a *Additive) Primary() *Primary {
+	if a == nil || len(a.Rest) != 0 || len(a.Left.Rest) != 0 {
+		return nil
+	}
+
+	unary := a.Left.Left
+	if unary.Negate || unary.Cast != "" {
+		return nil
+	}
+
+	return unary.Primary
+}
@@ -0,0 +1,146 @@
+package sqlgrammar_test
+
+import (
+	"slices"
+	"testing"
+
+	sqlgrammar "github.com/rlch/scaf/dialects/sql/grammar"
+)
+
+func TestParse_BasicQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"star", "SELECT * FROM users"},
+		{"lowercase keywords", "select id from users"},
+		{"columns and aliases", "SELECT u.id, u.name AS display_name, email e FROM users u"},
+		{"table star", "SELECT u.* FROM users AS u"},
+		{"schema qualified", "SELECT id FROM public.users"},
+		{"quoted identifiers", `SELECT "User".id FROM "User"`},
+		{"no from", "SELECT 1"},
+		{"distinct", "SELECT DISTINCT name FROM users"},
+		{"where params", "SELECT id FROM users WHERE id = $id AND age >= $minAge"},
+		{"or and not", "SELECT id FROM users WHERE NOT active OR (age < 18 AND name <> 'x')"},
+		{"is null", "SELECT id FROM users WHERE deleted_at IS NOT NULL"},
+		{"in list", "SELECT id FROM users WHERE id IN ($a, $b) AND role NOT IN ('admin')"},
+		{"like", "SELECT id FROM users WHERE name LIKE $pattern OR email NOT ILIKE '%@x'"},
+		{"between", "SELECT id FROM users WHERE age BETWEEN 18 AND 65 AND active = TRUE"},
+		{"join", "SELECT u.id, p.title FROM users u JOIN posts p ON p.user_id = u.id"},
+		{"outer joins", "SELECT u.id FROM users u LEFT OUTER JOIN posts p ON p.user_id = u.id CROSS JOIN tags"},
+		{"comma tables", "SELECT u.id FROM users u, posts p WHERE p.user_id = u.id"},
+		{"aggregates", "SELECT count(*), count(DISTINCT name), avg(age) FROM users"},
+		{"zero-argument calls", "SELECT now(), count() FROM users WHERE created_at < now()"},
+		{"group by having", "SELECT role, count(*) AS n FROM users GROUP BY role HAVING count(*) > 1"},
+		{"order by", "SELECT id FROM users ORDER BY name DESC, id ASC"},
+		{"limit offset", "SELECT id FROM users LIMIT $limit OFFSET 10;"},
+		{"arithmetic", "SELECT age * 2 + 1, -age, first || ' ' || last FROM users"},
+		{"cast", "SELECT id::text FROM users"},
+		{"comments", "SELECT id -- the id\nFROM /* table */ users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := sqlgrammar.Parse(tt.query); err != nil {
+				t.Errorf("Parse(%q) error: %v", tt.query, err)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"empty select", "SELECT FROM users"},
+		{"missing table", "SELECT id FROM"},
+		{"dangling where", "SELECT id FROM users WHERE"},
+		{"not a select", "DELETE FROM users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := sqlgrammar.Parse(tt.query); err == nil {
+				t.Errorf("Parse(%q) expected error", tt.query)
+			}
+		})
+	}
+}
+
+// TestParse_Unsupported checks that valid SQL outside the grammar fails to
+// parse with an error rather than a panic.
+func TestParse_Unsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"exists subquery", "SELECT id FROM users u WHERE EXISTS (SELECT 1 FROM posts p WHERE p.user_id = u.id)"},
+		{"in subquery", "SELECT id FROM users WHERE id IN (SELECT user_id FROM posts)"},
+		{"union", "SELECT id FROM users UNION SELECT id FROM admins"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := sqlgrammar.Parse(tt.query); err == nil {
+				t.Errorf("Parse(%q) expected error", tt.query)
+			}
+		})
+	}
+}
+
+func TestParse_Structure(t *testing.T) {
+	stmt, err := sqlgrammar.Parse(`SELECT u.name AS n, "u".* FROM public.users u LEFT JOIN posts ON posts.user_id = u.id LIMIT 1`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	if got := stmt.Items[0].Alias; got != "n" {
+		t.Errorf("Items[0].Alias = %q, want %q", got, "n")
+	}
+
+	if got := stmt.Items[0].Expr.Column(); got == nil || got.Qualifier() != "u" || got.Name() != "name" {
+		t.Errorf("Items[0] column = %+v, want u.name", got)
+	}
+
+	if got := stmt.Items[1].TableStar; got != "u" {
+		t.Errorf("Items[1].TableStar = %q, want %q", got, "u")
+	}
+
+	var bindings []string
+	for _, table := range stmt.Tables() {
+		bindings = append(bindings, table.Binding())
+	}
+
+	if want := []string{"u", "posts"}; !slices.Equal(bindings, want) {
+		t.Errorf("bindings = %v, want %v", bindings, want)
+	}
+
+	if got := stmt.From.Tables[0].Schema; got != "public" {
+		t.Errorf("Schema = %q, want %q", got, "public")
+	}
+
+	if got := stmt.From.Joins[0].Kind; got != "LEFT" {
+		t.Errorf("Joins[0].Kind = %q, want %q", got, "LEFT")
+	}
+}
+
+func TestInspect_ColumnRefs(t *testing.T) {
+	stmt, err := sqlgrammar.Parse("SELECT u.id FROM users u WHERE u.age > 1 AND lower(u.name) = $name ORDER BY created_at")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	var columns []string
+	sqlgrammar.Inspect(stmt, func(node any) bool {
+		if col, ok := node.(*sqlgrammar.ColumnRef); ok {
+			columns = append(columns, col.Name())
+		}
+
+		return true
+	})
+
+	if want := []string{"id", "age", "name", "created_at"}; !slices.Equal(columns, want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+}
@@ -0,0 +1,45 @@
+package sqlgrammar
+
+import (
+	"reflect"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+var positionType = reflect.TypeFor[lexer.Position]()
+
+// Inspect traverses the AST rooted at node in depth-first order, calling fn
+// with each non-nil node pointer (e.g. *Select, *ColumnRef). If fn returns
+// false, the children of that node are skipped.
+func Inspect(node any, fn func(node any) bool) {
+	inspect(reflect.ValueOf(node), fn)
+}
+
+func inspect(v reflect.Value, fn func(node any) bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return
+		}
+
+		if !fn(v.Interface()) {
+			return
+		}
+
+		inspect(v.Elem(), fn)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Field(i)
+			if field.Type() == positionType {
+				continue
+			}
+
+			inspect(field, fn)
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			inspect(v.Index(i), fn)
+		}
+	default:
+	}
+}
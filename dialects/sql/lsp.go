@@ -0,0 +1,484 @@
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+	sqlgrammar "github.com/rlch/scaf/dialects/sql/grammar"
+)
+
+// Ensure Dialect implements DialectLSP.
+var _ scaf.DialectLSP = (*Dialect)(nil)
+
+// sqlKeywords are the SQL keywords offered for completion.
+var sqlKeywords = map[string]string{
+	// Clauses
+	"SELECT":   "Specify the columns to return",
+	"FROM":     "Specify the tables to query",
+	"WHERE":    "Filter rows based on conditions",
+	"JOIN":     "Combine rows from another table",
+	"ON":       "Specify the join condition",
+	"GROUP BY": "Group rows that share values",
+	"HAVING":   "Filter groups based on conditions",
+	"ORDER BY": "Order results",
+	"LIMIT":    "Limit number of results",
+	"OFFSET":   "Skip a number of results",
+
+	// Joins
+	"INNER": "Join only matching rows",
+	"LEFT":  "Join keeping every row from the left table",
+	"RIGHT": "Join keeping every row from the right table",
+	"FULL":  "Join keeping every row from both tables",
+	"CROSS": "Join every row with every other row",
+
+	// Modifiers
+	"AS":       "Alias for tables and expressions",
+	"DISTINCT": "Remove duplicate rows",
+	"ASC":      "Ascending order",
+	"DESC":     "Descending order",
+
+	// Operators
+	"AND":     "Logical AND",
+	"OR":      "Logical OR",
+	"NOT":     "Logical NOT",
+	"IN":      "Check if value is in list",
+	"IS":      "Used with NULL check",
+	"LIKE":    "Match a string against a pattern",
+	"ILIKE":   "Match a string against a pattern, ignoring case",
+	"BETWEEN": "Check if value is within a range",
+
+	// Literals
+	"NULL":  "Null value literal",
+	"TRUE":  "Boolean true",
+	"FALSE": "Boolean false",
+}
+
+// Complete provides completions for a position within a SQL query.
+// After FROM or JOIN it offers tables, after alias. the columns of that
+// table, and keywords everywhere else.
+func (d *Dialect) Complete(query string, offset int, ctx *scaf.QueryLSPContext) []scaf.QueryCompletion {
+	if offset < 0 || offset > len(query) {
+		return nil
+	}
+
+	before := query[:offset]
+	prefix := identifierSuffix(before)
+	before = before[:len(before)-len(prefix)]
+
+	schema := schemaOf(ctx)
+
+	var items []scaf.QueryCompletion
+
+	switch _, expectTable := scanTables(queryTokens(before)); {
+	case strings.HasSuffix(before, "."):
+		// Look up the alias in the whole query, since FROM usually follows the cursor
+		bindings, _ := scanTables(queryTokens(query))
+		qualifier := identifierSuffix(before[:len(before)-1])
+		items = d.completeColumns(findModel(schema, bindings[qualifier]))
+	case expectTable:
+		items = d.completeTables(schema)
+	default:
+		items = d.completeKeywords()
+	}
+
+	return filterCompletions(items, prefix)
+}
+
+// scanTables finds the tables named in FROM and JOIN clauses by scanning
+// tokens, so it also works on incomplete queries. It returns each binding
+// (alias, or table name if unaliased) mapped to its table, and whether the
+// tokens end where a table name is expected.
+func scanTables(tokens []lexer.Token) (map[string]string, bool) {
+	bindings := make(map[string]string)
+
+	inFrom, inOn, expectTable := false, false, false
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		switch {
+		case isKeyword(token, "FROM"):
+			inFrom, inOn, expectTable = true, false, true
+		case isKeyword(token, "JOIN"):
+			inOn, expectTable = false, true
+		case isKeyword(token, "ON"):
+			inOn, expectTable = true, false
+		case isKeyword(token, "WHERE", "GROUP", "ORDER", "HAVING", "LIMIT", "OFFSET"):
+			inFrom, inOn, expectTable = false, false, false
+		case token.Type == punctToken && token.Value == ",":
+			expectTable = inFrom && !inOn
+		case expectTable && isName(token):
+			table := token.Value
+
+			// schema.table
+			if i+2 < len(tokens) && tokens[i+1].Value == "." && isName(tokens[i+2]) {
+				table = tokens[i+2].Value
+				i += 2
+			}
+
+			binding := table
+			if i+1 < len(tokens) && isKeyword(tokens[i+1], "AS") {
+				i++
+			}
+
+			if i+1 < len(tokens) && isName(tokens[i+1]) {
+				binding = tokens[i+1].Value
+				i++
+			}
+
+			bindings[binding] = table
+			expectTable = false
+		default:
+			expectTable = false
+		}
+	}
+
+	return bindings, expectTable
+}
+
+func isKeyword(token lexer.Token, keywords ...string) bool {
+	if token.Type != keywordToken {
+		return false
+	}
+
+	for _, keyword := range keywords {
+		if strings.EqualFold(token.Value, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isName(token lexer.Token) bool {
+	return token.Type == identToken || token.Type == quotedToken
+}
+
+// identifierSuffix returns the identifier characters at the end of text.
+func identifierSuffix(text string) string {
+	start := len(text)
+	for start > 0 && isIdentChar(text[start-1]) {
+		start--
+	}
+
+	return text[start:]
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// schemaOf returns the schema from ctx, or nil if there is none.
+func schemaOf(ctx *scaf.QueryLSPContext) *analysis.TypeSchema {
+	if ctx == nil {
+		return nil
+	}
+
+	schema, _ := ctx.Schema.(*analysis.TypeSchema)
+
+	return schema
+}
+
+func filterCompletions(items []scaf.QueryCompletion, prefix string) []scaf.QueryCompletion {
+	if prefix == "" {
+		return items
+	}
+
+	prefix = strings.ToLower(prefix)
+	var filtered []scaf.QueryCompletion
+
+	for _, item := range items {
+		if strings.HasPrefix(strings.ToLower(item.Label), prefix) {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
+
+func (d *Dialect) completeKeywords() []scaf.QueryCompletion {
+	var items []scaf.QueryCompletion
+
+	for keyword, desc := range sqlKeywords {
+		items = append(items, scaf.QueryCompletion{
+			Label:         keyword,
+			Kind:          scaf.QueryCompletionKeyword,
+			Detail:        desc,
+			InsertText:    keyword,
+			Documentation: desc,
+			SortText:      "1" + keyword,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+
+	return items
+}
+
+func (d *Dialect) completeTables(schema *analysis.TypeSchema) []scaf.QueryCompletion {
+	if schema == nil {
+		return nil
+	}
+
+	var items []scaf.QueryCompletion
+
+	for name := range schema.Models {
+		items = append(items, scaf.QueryCompletion{
+			Label:      name,
+			Kind:       scaf.QueryCompletionLabel,
+			Detail:     "table",
+			InsertText: name,
+			SortText:   "3" + name,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+
+	return items
+}
+
+func (d *Dialect) completeColumns(model *analysis.Model) []scaf.QueryCompletion {
+	if model == nil {
+		return nil
+	}
+
+	items := make([]scaf.QueryCompletion, 0, len(model.Fields))
+
+	for _, field := range model.Fields {
+		items = append(items, scaf.QueryCompletion{
+			Label:      field.Name,
+			Kind:       scaf.QueryCompletionProperty,
+			Detail:     field.Type.String(),
+			InsertText: field.Name,
+			SortText:   "3" + field.Name,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+
+	return items
+}
+
+// Hover returns hover information for a keyword or table in a SQL query.
+func (d *Dialect) Hover(query string, offset int, ctx *scaf.QueryLSPContext) *scaf.QueryHover {
+	if offset < 0 || offset > len(query) {
+		return nil
+	}
+
+	start := offset - len(identifierSuffix(query[:offset]))
+	end := offset
+	for end < len(query) && isIdentChar(query[end]) {
+		end++
+	}
+
+	word := query[start:end]
+	if word == "" {
+		return nil
+	}
+
+	if desc, ok := sqlKeywords[strings.ToUpper(word)]; ok {
+		return &scaf.QueryHover{
+			Contents: fmt.Sprintf("**%s** (keyword)\n\n%s", strings.ToUpper(word), desc),
+		}
+	}
+
+	if model := findModel(schemaOf(ctx), word); model != nil {
+		var sb strings.Builder
+
+		fmt.Fprintf(&sb, "**%s** (table)\n", model.Name)
+
+		for _, field := range model.Fields {
+			fmt.Fprintf(&sb, "\n- `%s`: `%s`", field.Name, field.Type)
+		}
+
+		return &scaf.QueryHover{
+			Contents: sb.String(),
+			Range:    &scaf.QueryRange{Start: start, End: end},
+		}
+	}
+
+	return nil
+}
+
+// Diagnostics returns diagnostics for a SQL query. Only SELECT statements
+// are parsed; with a schema, their tables and columns are checked against it.
+// The grammar covers a subset of SQL, so a query it can't parse (a UNION or
+// subquery, say) is skipped rather than reported as a syntax error.
+func (d *Dialect) Diagnostics(query string, ctx *scaf.QueryLSPContext) []scaf.QueryDiagnostic {
+	if !isSelect(query) {
+		return nil
+	}
+
+	stmt, err := sqlgrammar.Parse(query)
+	if err != nil {
+		return nil
+	}
+
+	schema := schemaOf(ctx)
+	if schema == nil {
+		return nil
+	}
+
+	return schemaDiagnostics(query, stmt, schema)
+}
+
+// schemaDiagnostics reports tables missing from the schema and columns
+// missing from their table.
+func schemaDiagnostics(query string, stmt *sqlgrammar.Select, schema *analysis.TypeSchema) []scaf.QueryDiagnostic {
+	var diags []scaf.QueryDiagnostic
+
+	scope := newScope(stmt, schema)
+
+	for _, table := range stmt.Tables() {
+		if scope.tables[table.Binding()] == nil {
+			diags = append(diags, scaf.QueryDiagnostic{
+				Range:    tableNameRange(query, table),
+				Severity: scaf.QueryDiagnosticWarning,
+				Message:  "Unknown table: " + table.Name,
+				Code:     "unknown-table",
+			})
+		}
+	}
+
+	// ORDER BY and friends may refer to output columns by alias
+	aliases := make(map[string]bool)
+	for _, item := range stmt.Items {
+		if item.Alias != "" {
+			aliases[item.Alias] = true
+		}
+	}
+
+	sqlgrammar.Inspect(stmt, func(node any) bool {
+		col, ok := node.(*sqlgrammar.ColumnRef)
+		if !ok {
+			return true
+		}
+
+		if diag := checkColumn(col, scope, aliases); diag != nil {
+			diags = append(diags, *diag)
+		}
+
+		return true
+	})
+
+	return diags
+}
+
+// checkColumn returns a diagnostic if col cannot be found in scope, or nil.
+// Columns are only reported when every table they could belong to is known.
+func checkColumn(col *sqlgrammar.ColumnRef, scope *scope, aliases map[string]bool) *scaf.QueryDiagnostic {
+	diag := &scaf.QueryDiagnostic{
+		Range:    scaf.QueryDiagnosticRange{StartPos: col.Pos, EndPos: col.EndPos},
+		Severity: scaf.QueryDiagnosticWarning,
+		Code:     "unknown-column",
+	}
+
+	if qualifier := col.Qualifier(); qualifier != "" {
+		model, bound := scope.tables[qualifier]
+
+		switch {
+		case !bound:
+			diag.Message = "Unknown table or alias: " + qualifier
+			diag.Code = "unknown-table"
+		case model == nil, findField(model, col.Name()) != nil:
+			return nil
+		default:
+			diag.Message = fmt.Sprintf("Unknown column '%s' on %s", col.Name(), model.Name)
+		}
+
+		return diag
+	}
+
+	if aliases[col.Name()] || len(scope.order) == 0 || scope.resolveColumn(col) != nil {
+		return nil
+	}
+
+	for _, model := range scope.tables {
+		if model == nil {
+			return nil
+		}
+	}
+
+	diag.Message = fmt.Sprintf("Unknown column '%s'", col.Name())
+
+	return diag
+}
+
+// tableNameRange returns the range of the table name within a table
+// reference, excluding any schema qualifier and alias.
+func tableNameRange(query string, table *sqlgrammar.TableRef) scaf.QueryDiagnosticRange {
+	start := table.Pos.Offset
+	text := query[start:table.EndPos.Offset]
+
+	if table.Schema != "" {
+		if dot := strings.Index(text, "."); dot >= 0 {
+			start += dot + 1
+			text = text[dot+1:]
+		}
+	}
+
+	if idx := strings.Index(text, table.Name); idx >= 0 {
+		start += idx
+	}
+
+	return scaf.NewQueryDiagnosticRange(query, start, start+len(table.Name))
+}
+
+// SignatureHelp returns signature help for a position in a SQL query.
+func (d *Dialect) SignatureHelp(query string, offset int, ctx *scaf.QueryLSPContext) *scaf.QuerySignatureHelp {
+	// TODO: Add signatures for common SQL functions
+	return nil
+}
+
+// Definition returns definition locations for a position in a SQL query.
+func (d *Dialect) Definition(query string, offset int, ctx *scaf.QueryLSPContext) []scaf.QueryLocation {
+	// TODO: Implement go-to-definition for tables
+	return nil
+}
+
+// InlayHints returns inlay hints for inferred parameter types.
+// Shows type annotations for parameters that lack explicit types in the function signature.
+func (d *Dialect) InlayHints(query string, ctx *scaf.QueryLSPContext) []scaf.QueryInlayHint {
+	schema := schemaOf(ctx)
+	if schema == nil {
+		return nil
+	}
+
+	metadata, err := NewAnalyzer().AnalyzeQueryWithSchema(query, schema)
+	if err != nil || metadata == nil {
+		return nil
+	}
+
+	var hints []scaf.QueryInlayHint
+
+	for _, param := range metadata.Parameters {
+		// Skip if the parameter already has an explicit type annotation
+		if declType, exists := ctx.DeclaredParams[param.Name]; exists && declType != nil {
+			continue
+		}
+
+		if param.Type == nil {
+			continue
+		}
+
+		hints = append(hints, scaf.QueryInlayHint{
+			ParameterName: param.Name,
+			Label:         ": " + param.Type.String(),
+			Kind:          scaf.QueryInlayHintType,
+			Tooltip:       "Type inferred from the column it is compared with",
+		})
+	}
+
+	return hints
+}
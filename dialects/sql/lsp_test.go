@@ -0,0 +1,195 @@
+package sql_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/dialects/sql"
+)
+
+func completionLabels(items []scaf.QueryCompletion) []string {
+	labels := make([]string, 0, len(items))
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+
+	return labels
+}
+
+func TestDialect_Complete(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string // | marks the cursor
+		want  []string
+	}{
+		{
+			name:  "tables after from",
+			query: "SELECT id FROM |",
+			want:  []string{"posts", "users"},
+		},
+		{
+			name:  "tables after join with prefix",
+			query: "SELECT id FROM users u JOIN po|",
+			want:  []string{"posts"},
+		},
+		{
+			name:  "tables after comma",
+			query: "SELECT id FROM users u, |",
+			want:  []string{"posts", "users"},
+		},
+		{
+			name:  "columns after alias",
+			query: "SELECT u.| FROM users u",
+			want:  []string{"age", "email", "id", "name"},
+		},
+		{
+			name:  "columns after alias with prefix",
+			query: "SELECT p.id FROM users u JOIN posts p ON p.us|",
+			want:  []string{"user_id"},
+		},
+		{
+			name:  "keywords",
+			query: "SELECT id FROM users WH|",
+			want:  []string{"WHERE"},
+		},
+		{
+			name:  "multi-word keywords",
+			query: "SELECT id FROM users u or|",
+			want:  []string{"OR", "ORDER BY"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			offset := strings.Index(tt.query, "|")
+			query := strings.Replace(tt.query, "|", "", 1)
+
+			items := sql.NewDialect().Complete(query, offset, &scaf.QueryLSPContext{Schema: testSchema()})
+			if got := completionLabels(items); !slices.Equal(got, tt.want) {
+				t.Errorf("Complete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_Complete_Keywords(t *testing.T) {
+	t.Parallel()
+
+	labels := completionLabels(sql.NewDialect().Complete("", 0, nil))
+	for _, want := range []string{"SELECT", "FROM", "WHERE", "JOIN", "GROUP BY", "ORDER BY"} {
+		if !slices.Contains(labels, want) {
+			t.Errorf("Complete() missing keyword %q", want)
+		}
+	}
+}
+
+func TestDialect_Diagnostics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string // code: text the diagnostic covers
+	}{
+		{
+			name:  "valid",
+			query: "SELECT u.id, p.title AS t FROM users u JOIN posts p ON p.user_id = u.id ORDER BY t",
+		},
+		{
+			name:  "queries outside the grammar are not checked",
+			query: "SELECT id FROM users WHERE id IN (SELECT user_id FROM posts) UNION SELECT id FROM nowhere",
+		},
+		{
+			name:  "window functions are not checked",
+			query: "SELECT id, row_number() OVER (PARTITION BY role ORDER BY id NULLS LAST) FROM users FOR UPDATE",
+		},
+		{
+			name:  "other statements are not checked",
+			query: "UPDATE nowhere SET x = 1",
+		},
+		{
+			name:  "unknown table",
+			query: "SELECT id FROM public.accounts a",
+			want:  []string{"unknown-table: accounts"},
+		},
+		{
+			name:  "unknown column",
+			query: "SELECT u.nickname, email, shoe_size FROM users u",
+			want:  []string{"unknown-column: u.nickname", "unknown-column: shoe_size"},
+		},
+		{
+			name:  "unknown alias",
+			query: "SELECT x.id FROM users u",
+			want:  []string{"unknown-table: x.id"},
+		},
+		{
+			name:  "columns of unknown tables are not checked",
+			query: "SELECT a.whatever, anything FROM users u, accounts a",
+			want:  []string{"unknown-table: accounts"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			diags := sql.NewDialect().Diagnostics(tt.query, &scaf.QueryLSPContext{Schema: testSchema()})
+
+			var got []string
+			for _, d := range diags {
+				got = append(got, d.Code+": "+tt.query[d.Range.StartPos.Offset:d.Range.EndPos.Offset])
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Diagnostics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialect_Diagnostics_NoSchema(t *testing.T) {
+	t.Parallel()
+
+	if diags := sql.NewDialect().Diagnostics("SELECT x FROM nowhere", nil); len(diags) != 0 {
+		t.Errorf("Diagnostics() = %v, want none without a schema", diags)
+	}
+}
+
+func TestDialect_Hover(t *testing.T) {
+	t.Parallel()
+
+	d := sql.NewDialect()
+	ctx := &scaf.QueryLSPContext{Schema: testSchema()}
+	query := "SELECT id FROM posts"
+
+	hover := d.Hover(query, strings.Index(query, "posts")+2, ctx)
+	if hover == nil || !strings.Contains(hover.Contents, "**posts** (table)") || !strings.Contains(hover.Contents, "`user_id`: `int64`") {
+		t.Fatalf("Hover(table) = %+v", hover)
+	}
+
+	hover = d.Hover(query, 1, ctx)
+	if hover == nil || !strings.Contains(hover.Contents, "**SELECT** (keyword)") {
+		t.Errorf("Hover(keyword) = %+v", hover)
+	}
+}
+
+func TestDialect_InlayHints(t *testing.T) {
+	t.Parallel()
+
+	str := "string"
+	ctx := &scaf.QueryLSPContext{
+		Schema:         testSchema(),
+		DeclaredParams: map[string]*scaf.TypeExpr{"id": nil, "name": {Simple: &str}},
+	}
+
+	hints := sql.NewDialect().InlayHints("SELECT id FROM users WHERE id = $id AND name = $name", ctx)
+	if len(hints) != 1 || hints[0].ParameterName != "id" || hints[0].Label != ": int64" {
+		t.Errorf("InlayHints() = %+v", hints)
+	}
+}
@@ -0,0 +1,38 @@
+// Package sql provides a scaf Dialect for SQL query analysis.
+//
+// It understands SELECT statements well enough to infer parameter and return
+// types from a schema, where each model is a table and each field a column.
+// Other statements are accepted as-is; only their parameters are extracted.
+package sql
+
+import "github.com/rlch/scaf"
+
+//nolint:gochecknoinits // Dialect self-registration pattern
+func init() {
+	scaf.RegisterDialect(NewDialect())
+}
+
+// Dialect implements scaf.Dialect for SQL query analysis.
+type Dialect struct{}
+
+// NewDialect creates a new SQL dialect for query analysis.
+func NewDialect() *Dialect {
+	return &Dialect{}
+}
+
+// Name returns the dialect identifier.
+func (d *Dialect) Name() string {
+	return scaf.DialectSQL
+}
+
+// Analyze extracts metadata from a SQL query.
+func (d *Dialect) Analyze(query string) (*scaf.QueryMetadata, error) {
+	return NewAnalyzer().AnalyzeQuery(query)
+}
+
+// Format returns query unchanged; SQL bodies are not reformatted.
+func (d *Dialect) Format(query string) (string, error) {
+	return query, nil
+}
+
+var _ scaf.Dialect = (*Dialect)(nil)
@@ -1,6 +1,13 @@
 package scaf
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
 
 // Sentinel errors.
 var (
@@ -9,4 +16,72 @@ var (
 
 	// ErrUnknownDatabase is returned when an unknown database is requested.
 	ErrUnknownDatabase = errors.New("scaf: unknown database")
+
+	// ErrInvalidSeverity is returned when a rule config names an unknown severity.
+	ErrInvalidSeverity = errors.New("scaf: invalid severity")
+
+	// ErrInvalidKeywordCase is returned when cypher.formatting names an unknown keyword case.
+	ErrInvalidKeywordCase = errors.New("scaf: invalid keyword case")
+
+	// ErrInvalidTimeout is returned when test_timeout or timeouts holds an invalid duration.
+	ErrInvalidTimeout = errors.New("scaf: invalid timeout")
+
+	// ErrUnknownConnection is returned when a connection profile isn't defined in connections.
+	ErrUnknownConnection = errors.New("scaf: unknown connection")
+)
+
+// Parse limits reported by ParseLimitError.
+const (
+	LimitBytes   = "bytes"
+	LimitTokens  = "tokens"
+	LimitTimeout = "timeout"
 )
+
+// ParseLimitError is returned by ParseWithOptions when the input exceeds one
+// of the configured limits.
+type ParseLimitError struct {
+	// Limit is the limit exceeded: LimitBytes, LimitTokens, or LimitTimeout.
+	Limit string
+	// Max is the configured limit: a byte or token count, or the timeout as
+	// a time.Duration.
+	Max int64
+}
+
+func (e *ParseLimitError) Error() string {
+	switch e.Limit {
+	case LimitTimeout:
+		return "scaf: parse timed out after " + time.Duration(e.Max).String()
+	default:
+		return fmt.Sprintf("scaf: file exceeds the limit of %d %s", e.Max, e.Limit)
+	}
+}
+
+// ParseError is a syntax error in a scaf file. The parse functions return one
+// for each error, joined in a *participle.RecoveryError when recovery finds
+// several; errors.As finds the first.
+type ParseError struct {
+	// Pos is where the error was found.
+	Pos lexer.Position
+	// Expected are the tokens the parser could have accepted at Pos: literals
+	// unquoted, as in "{", token types in angle brackets, as in <ident>, and
+	// the names of grammar rules that could start there. Empty when the parser
+	// doesn't say.
+	Expected []string
+	// Got is the token found at Pos, or "<EOF>" at the end of the input.
+	// Empty for errors that aren't about an unexpected token.
+	Got string
+
+	msg string
+	err error
+}
+
+func (e *ParseError) Error() string { return participle.FormatError(e) }
+
+// Message returns the error message without its position.
+func (e *ParseError) Message() string { return e.msg }
+
+// Position returns Pos, so a ParseError is a participle.Error.
+func (e *ParseError) Position() lexer.Position { return e.Pos }
+
+// Unwrap returns the underlying parser or lexer error.
+func (e *ParseError) Unwrap() error { return e.err }
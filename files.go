@@ -0,0 +1,56 @@
+package scaf
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CollectFiles expands files, directories, and glob patterns into .scaf paths,
+// in argument order. Directories are walked recursively and glob matches are
+// filtered to .scaf files; a file named directly is kept as is. An argument
+// that is neither an existing path nor a glob with matches is an error.
+func CollectFiles(args []string) ([]string, error) {
+	var files []string
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			// Not an existing path; try it as a glob pattern (e.g. "queries/*.scaf")
+			matches, globErr := filepath.Glob(arg)
+			if globErr != nil || len(matches) == 0 {
+				return nil, err
+			}
+
+			for _, match := range matches {
+				if strings.HasSuffix(match, ".scaf") {
+					files = append(files, match)
+				}
+			}
+
+			continue
+		}
+
+		if info.IsDir() {
+			err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if !d.IsDir() && strings.HasSuffix(path, ".scaf") {
+					files = append(files, path)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,65 @@
+package scaf_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rlch/scaf"
+)
+
+func TestCollectFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.scaf", "notes.txt", "sub/b.scaf", "sub/deep/c.scaf", "other/d.scaf"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	join := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+
+		return paths
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"directory", join("sub"), join("sub/b.scaf", "sub/deep/c.scaf")},
+		{"glob", join("*"), join("a.scaf")},
+		{"file named directly", join("notes.txt"), join("notes.txt")},
+		{"in argument order", join("other", "a.scaf"), join("other/d.scaf", "a.scaf")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := scaf.CollectFiles(tt.args)
+			if err != nil {
+				t.Fatalf("CollectFiles() error: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("CollectFiles(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := scaf.CollectFiles(join("missing", "*.none")); err == nil {
+		t.Error("CollectFiles() of a missing path: expected an error")
+	}
+}
@@ -19,9 +19,20 @@ func Format(s *Suite) string {
 
 // FormatWithWidth formats a Suite AST with a specific target line width.
 func FormatWithWidth(s *Suite, maxWidth int) string {
+	return formatSuite(s, maxWidth, nil)
+}
+
+// FormatWithDialect formats a Suite AST like Format, also rewriting each query
+// body with d.Format. Bodies keep their surrounding whitespace, and lines after
+// the first take the indentation of the first. Bodies d fails on are kept as written.
+func FormatWithDialect(s *Suite, d Dialect) string {
+	return formatSuite(s, DefaultMaxLineWidth, d)
+}
+
+func formatSuite(s *Suite, maxWidth int, d Dialect) string {
 	var b strings.Builder
 
-	f := &formatter{b: &b, indent: 0, maxWidth: maxWidth}
+	f := &formatter{b: &b, indent: 0, maxWidth: maxWidth, dialect: d}
 	f.formatSuite(s)
 
 	return strings.TrimSpace(b.String()) + "\n"
@@ -31,6 +42,8 @@ type formatter struct {
 	b        *strings.Builder
 	indent   int
 	maxWidth int
+	dialect  Dialect // formats query bodies when non-nil
+	inline   bool    // keeps collections on one line, ignoring trailing commas
 }
 
 func (f *formatter) write(s string) {
@@ -104,9 +117,13 @@ func (f *formatter) formatSuite(s *Suite) {
 		f.formatSetupClause(s.Setup)
 	}
 
-	// Global teardown
+	// Global teardown, directly after the setup if there is one
 	if s.Teardown != nil {
-		f.formatTeardown(*s.Teardown)
+		if s.Setup == nil && (len(s.Functions) > 0 || len(s.Imports) > 0) {
+			f.blankLine()
+		}
+
+		f.formatTeardown(s.Teardown)
 	}
 
 	// Scopes
@@ -226,31 +243,40 @@ func (f *formatter) formatTypeExpr(t *TypeExpr) string {
 }
 
 func (f *formatter) formatSetupClause(s *SetupClause) {
+	f.formatClause("setup", s)
+}
+
+func (f *formatter) formatTeardown(t *TeardownClause) {
+	f.formatClause("teardown", (*SetupClause)(t))
+}
+
+// formatClause formats a setup or teardown clause, introduced by keyword.
+func (f *formatter) formatClause(keyword string, s *SetupClause) {
 	switch {
 	case s.Inline != nil:
-		f.writeLine("setup " + f.rawString(*s.Inline))
+		f.writeLine(keyword + " " + f.rawString(*s.Inline))
 	case s.Module != nil:
-		f.writeLine("setup " + *s.Module)
+		f.writeLine(keyword + " " + *s.Module)
 	case s.Call != nil:
-		f.formatSetupCallLine(s.Call)
+		f.formatSetupCallLine(keyword, s.Call)
 	case len(s.Block) > 0:
-		f.formatSetupBlock(s.Block)
+		f.formatSetupBlock(keyword, s.Block)
 	}
 }
 
-func (f *formatter) formatSetupCallLine(c *SetupCall) {
+func (f *formatter) formatSetupCallLine(keyword string, c *SetupCall) {
 	// Trailing comma controls formatting: present = multi-line, absent = single-line
 	if c.TrailingComma {
-		f.formatSetupCallMultiLine(c)
+		f.formatSetupCallMultiLine(keyword, c)
 	} else {
-		f.writeLine("setup " + f.formatSetupCallSingleLine(c))
+		f.writeLine(keyword + " " + f.formatSetupCallSingleLine(c))
 	}
 }
 
-func (f *formatter) formatSetupBlock(items []*SetupItem) {
+func (f *formatter) formatSetupBlock(keyword string, items []*SetupItem) {
 	if len(items) == 1 {
 		// Try single line format
-		singleLine := "setup { " + f.formatSetupItem(items[0]) + " }"
+		singleLine := keyword + " { " + f.formatSetupItem(items[0]) + " }"
 		if !f.wouldExceedWidth(singleLine) {
 			f.writeLine(singleLine)
 			return
@@ -258,7 +284,7 @@ func (f *formatter) formatSetupBlock(items []*SetupItem) {
 	}
 
 	// Multiple items or too long - block format
-	f.writeLine("setup {")
+	f.writeLine(keyword + " {")
 	f.indent++
 
 	for _, item := range items {
@@ -308,9 +334,9 @@ func (f *formatter) formatSetupCallSingleLine(c *SetupCall) string {
 	return b.String()
 }
 
-func (f *formatter) formatSetupCallMultiLine(c *SetupCall) {
+func (f *formatter) formatSetupCallMultiLine(keyword string, c *SetupCall) {
 	f.writeIndent()
-	f.write("setup ")
+	f.write(keyword + " ")
 	f.write(c.Module)
 	f.write(".")
 	f.write(c.Query)
@@ -336,10 +362,6 @@ func (f *formatter) formatSetupCallMultiLine(c *SetupCall) {
 	f.write(")\n")
 }
 
-func (f *formatter) formatTeardown(body string) {
-	f.writeLine("teardown " + f.rawString(body))
-}
-
 func (f *formatter) formatScope(s *QueryScope) {
 	f.writeLeadingComments(s.LeadingComments)
 	f.writeLine(s.FunctionName + " {")
@@ -350,7 +372,7 @@ func (f *formatter) formatScope(s *QueryScope) {
 	}
 
 	if s.Teardown != nil {
-		f.formatTeardown(*s.Teardown)
+		f.formatTeardown(s.Teardown)
 	}
 
 	f.formatItems(s.Items, s.Setup != nil || s.Teardown != nil)
@@ -367,11 +389,21 @@ func (f *formatter) formatItems(items []*TestOrGroup, hasSetupOrTeardown bool) {
 		needsBlank := i > 0 || hasSetupOrTeardown
 
 		if item.Test != nil {
+			if item.Test.FromTable != nil {
+				continue // Written by its table
+			}
+
 			if needsBlank {
 				f.blankLine()
 			}
 
 			f.formatTest(item.Test)
+		} else if item.Table != nil {
+			if needsBlank {
+				f.blankLine()
+			}
+
+			f.formatTable(item.Table)
 		} else if item.Group != nil {
 			if needsBlank {
 				f.blankLine()
@@ -392,7 +424,7 @@ func (f *formatter) formatGroup(g *Group) {
 	}
 
 	if g.Teardown != nil {
-		f.formatTeardown(*g.Teardown)
+		f.formatTeardown(g.Teardown)
 	}
 
 	f.formatItems(g.Items, g.Setup != nil || g.Teardown != nil)
@@ -413,6 +445,12 @@ func (f *formatter) formatTest(t *Test) {
 		f.formatSetupClause(t.Setup)
 	}
 
+	if t.Teardown != nil {
+		f.formatTeardown(t.Teardown)
+	}
+
+	hasHooks := t.Setup != nil || t.Teardown != nil
+
 	// Separate inputs from outputs
 	var inputs, outputs []*Statement
 
@@ -426,7 +464,7 @@ func (f *formatter) formatTest(t *Test) {
 
 	// Format inputs
 	for i, stmt := range inputs {
-		if i == 0 && t.Setup != nil {
+		if i == 0 && hasHooks {
 			f.blankLine()
 		}
 
@@ -444,7 +482,7 @@ func (f *formatter) formatTest(t *Test) {
 
 	// Assertions
 	for i, a := range t.Asserts {
-		if i == 0 && (len(t.Statements) > 0 || t.Setup != nil) {
+		if i == 0 && (len(t.Statements) > 0 || hasHooks) {
 			f.blankLine()
 		}
 
@@ -458,6 +496,67 @@ func (f *formatter) formatTest(t *Test) {
 	f.write("\n")
 }
 
+// formatTable writes a table with its columns padded to a common width.
+func (f *formatter) formatTable(t *Table) {
+	f.writeLeadingComments(t.LeadingComments)
+	f.writeLine("table " + f.quotedString(t.Name) + " {")
+	f.indent++
+
+	var rows [][]string
+
+	if t.Header != nil {
+		header := make([]string, len(t.Header.Columns))
+		for i, col := range t.Header.Columns {
+			header[i] = col.String()
+		}
+
+		rows = append(rows, header)
+	}
+
+	f.inline = true
+
+	for _, row := range t.Rows {
+		cells := make([]string, len(row.Cells))
+		for i, cell := range row.Cells {
+			cells[i] = f.formatStatementValueSingleLine(cell)
+		}
+
+		rows = append(rows, cells)
+	}
+
+	f.inline = false
+
+	var widths []int
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	for _, row := range rows {
+		var b strings.Builder
+
+		b.WriteString("|")
+
+		for i, cell := range row {
+			b.WriteString(" " + cell + strings.Repeat(" ", widths[i]-len(cell)) + " |")
+		}
+
+		f.writeLine(b.String())
+	}
+
+	f.indent--
+	f.writeIndent()
+	f.write("}")
+	f.writeTrailingComment(t.TrailingComment)
+	f.write("\n")
+}
+
 func (f *formatter) formatStatement(s *Statement) {
 	// Write leading comments
 	f.writeLeadingComments(s.LeadingComments)
@@ -753,7 +852,7 @@ func (f *formatter) formatMapSingleLine(m *Map) string {
 	}
 
 	// If trailing comma is set, cannot use single-line format
-	if m.TrailingComma {
+	if m.TrailingComma && !f.inline {
 		return "" // Signal to use multi-line
 	}
 
@@ -806,7 +905,7 @@ func (f *formatter) formatListSingleLine(l *List) string {
 	}
 
 	// If trailing comma is set, cannot use single-line format
-	if l.TrailingComma {
+	if l.TrailingComma && !f.inline {
 		return "" // Signal to use multi-line
 	}
 
@@ -851,8 +950,37 @@ func (f *formatter) formatListMultiLine(l *List) {
 	f.write("]")
 }
 
+// rawString writes a query body as a raw string.
 func (f *formatter) rawString(s string) string {
-	return "`" + s + "`"
+	return "`" + f.queryBody(s) + "`"
+}
+
+// queryBody reformats a query body with the formatter's dialect, if any.
+func (f *formatter) queryBody(s string) string {
+	if f.dialect == nil {
+		return s
+	}
+
+	content := strings.TrimSpace(s)
+	if content == "" {
+		return s
+	}
+
+	formatted, err := f.dialect.Format(content)
+	if err != nil || formatted == content {
+		return s
+	}
+
+	start := strings.Index(s, content)
+	lead, trail := s[:start], s[start+len(content):]
+
+	// Continuation lines line up with the first line of the body.
+	var indent string
+	if i := strings.LastIndex(lead, "\n"); i >= 0 {
+		indent = lead[i+1:]
+	}
+
+	return lead + strings.ReplaceAll(formatted, "\n", "\n"+indent) + trail
 }
 
 func (f *formatter) quotedString(s string) string {
@@ -1,6 +1,7 @@
 package scaf_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -961,6 +962,47 @@ func TestFormatQueryOnly(t *testing.T) {
 	}
 }
 
+// clauseDialect formats queries by breaking before RETURN, failing on "bad".
+type clauseDialect struct{}
+
+func (clauseDialect) Name() string { return "clauses" }
+
+func (clauseDialect) Analyze(string) (*scaf.QueryMetadata, error) { return &scaf.QueryMetadata{}, nil }
+
+func (clauseDialect) Format(query string) (string, error) {
+	if query == "bad" {
+		return "", errors.New("cannot format")
+	}
+
+	return strings.ReplaceAll(query, " RETURN", "\nRETURN"), nil
+}
+
+func TestFormatWithDialect(t *testing.T) {
+	t.Parallel()
+
+	suite := &scaf.Suite{
+		Functions: []*scaf.Query{
+			{Name: "Inline", Body: "MATCH (u) RETURN u"},
+			{Name: "Block", Body: "\n\tMATCH (u) RETURN u\n"},
+			{Name: "Bad", Body: " bad "},
+		},
+		Setup: inlineSetup("MATCH (n) RETURN n"),
+	}
+
+	expected := "fn Inline() `MATCH (u)\nRETURN u`\n" +
+		"\n" +
+		"fn Block() `\n\tMATCH (u)\n\tRETURN u\n`\n" +
+		"\n" +
+		"fn Bad() ` bad `\n" +
+		"\n" +
+		"setup `MATCH (n)\nRETURN n`\n"
+	got := scaf.FormatWithDialect(suite, clauseDialect{})
+
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("FormatWithDialect() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestFormatSetupOnly(t *testing.T) {
 	t.Parallel()
 
@@ -1705,6 +1747,41 @@ Q {
 	}
 }
 
+func TestFormatTeardownClauses(t *testing.T) {
+	input := `import fixtures "./fixtures"
+
+fn Q() ` + "`Q`" + `
+
+teardown fixtures
+
+Q {
+	teardown {
+		fixtures.DeleteUser($id: 1)
+		` + "`MATCH (n) DETACH DELETE n`" + `
+	}
+
+	test "test" {
+	}
+
+	test "with teardown" {
+		setup ` + "`CREATE (:User)`" + `
+		teardown ` + "`MATCH (u:User) DELETE u`" + `
+
+		$id: 1
+	}
+}
+`
+
+	suite, err := scaf.Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	if got := scaf.Format(suite); got != input {
+		t.Errorf("Format() mismatch:\n%s", cmp.Diff(input, got))
+	}
+}
+
 func TestFormatTrailingCommaNestedStructures(t *testing.T) {
 	// Nested structures with trailing commas
 	input := `fn Q() ` + "`Q`" + `
@@ -1743,3 +1820,33 @@ Q {
 		t.Errorf("Format() not idempotent (-first +second):\n%s", diff)
 	}
 }
+
+func TestFormatTable(t *testing.T) {
+	t.Parallel()
+
+	input := "fn Q() `Q`\nQ {\n\ttest \"first\" {}\n\ttable \"t\" {\n| $id | u.name |\n|1|\"Alice\"|\n| 22 | [1,2,] |\n\t}\n}\n"
+
+	suite, err := scaf.Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	expected := `fn Q() ` + "`Q`" + `
+
+Q {
+	test "first" {
+	}
+
+	table "t" {
+		| $id | u.name  |
+		| 1   | "Alice" |
+		| 22  | [1, 2]  |
+	}
+}
+`
+
+	got := scaf.Format(suite)
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("Format() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,25 @@
+package scaf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Hash returns a SHA-256 hex digest of the file as Format prints it, so it
+// changes with the file's contents and comments but not its formatting.
+func (f *File) Hash() string {
+	if f == nil {
+		return FileHash(nil)
+	}
+
+	return FileHash([]byte(Format(f)))
+}
+
+// FileHash returns a SHA-256 hex digest of src.
+// It is a fast pre-check before parsing: if the source hash is unchanged,
+// the previous parse can be reused.
+func FileHash(src []byte) string {
+	sum := sha256.Sum256(src)
+
+	return hex.EncodeToString(sum[:])
+}
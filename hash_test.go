@@ -0,0 +1,81 @@
+package scaf_test
+
+import (
+	"testing"
+
+	"github.com/rlch/scaf"
+)
+
+func TestFile_Hash(t *testing.T) {
+	t.Parallel()
+
+	parse := func(src string) *scaf.File {
+		t.Helper()
+
+		f, err := scaf.Parse([]byte(src))
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", src, err)
+		}
+
+		return f
+	}
+
+	base := parse("fn Q(id) `MATCH (n) RETURN n`\nQ {\n\ttest \"t\" {\n\t\t$id: 1\n\t}\n}\n")
+
+	tests := []struct {
+		name     string
+		src      string
+		wantSame bool
+	}{
+		{"identical", "fn Q(id) `MATCH (n) RETURN n`\nQ {\n\ttest \"t\" {\n\t\t$id: 1\n\t}\n}\n", true},
+		{"reformatted", "fn Q( id )   `MATCH (n) RETURN n`\n\n\nQ { test \"t\" { $id: 1 } }", true},
+		{"changed value", "fn Q(id) `MATCH (n) RETURN n`\nQ {\n\ttest \"t\" {\n\t\t$id: 2\n\t}\n}\n", false},
+		{"changed query", "fn Q(id) `MATCH (m) RETURN m`\nQ {\n\ttest \"t\" {\n\t\t$id: 1\n\t}\n}\n", false},
+		{"added comment", "// about Q\nfn Q(id) `MATCH (n) RETURN n`\nQ {\n\ttest \"t\" {\n\t\t$id: 1\n\t}\n}\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if same := parse(tt.src).Hash() == base.Hash(); same != tt.wantSame {
+				t.Errorf("Hash() equal = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestFile_Hash_Stable(t *testing.T) {
+	t.Parallel()
+
+	f, err := scaf.Parse([]byte("fn Q() `Q`\n"))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	hash := f.Hash()
+	if len(hash) != 64 {
+		t.Errorf("Hash() = %q, want a 64-character hex digest", hash)
+	}
+
+	if f.Hash() != hash {
+		t.Error("Hash() is not stable across calls")
+	}
+}
+
+func TestFileHash(t *testing.T) {
+	t.Parallel()
+
+	// SHA-256 of the empty input.
+	const empty = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got := scaf.FileHash(nil); got != empty {
+		t.Errorf("FileHash(nil) = %q, want %q", got, empty)
+	}
+
+	a := scaf.FileHash([]byte("fn Q() `Q`\n"))
+	b := scaf.FileHash([]byte("fn Q()  `Q`\n"))
+
+	if a == b {
+		t.Error("FileHash() should differ when the source bytes differ")
+	}
+}
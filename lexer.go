@@ -41,6 +41,8 @@ const (
 	TokenAssert   // assert
 	TokenWhere    // where (constraint clause)
 	TokenQuestion // ? (nullability marker)
+	// TokenAnnotation is a rule suppression comment (// scaf-disable-next-line rule-code).
+	TokenAnnotation
 )
 
 // keywords maps keyword strings to their token types.
@@ -91,7 +93,14 @@ type dslDefinition struct {
 	symbols map[string]lexer.TokenType
 	// lastTrivia holds trivia from the most recent lex operation.
 	lastTrivia *TriviaList
-	// mu protects lastTrivia for concurrent access.
+	// maxTokens limits the tokens produced by the next lex (0 for no limit).
+	// Set under mu by the parser.
+	maxTokens int
+	// lexed, if set, is called with the next lex's trivia once it has
+	// produced all its tokens, so the parser can release mu before parsing
+	// them. Set under mu by the parser.
+	lexed func(*TriviaList)
+	// mu protects lastTrivia, maxTokens, and lexed for concurrent access.
 	mu sync.Mutex
 }
 
@@ -101,6 +110,7 @@ func newDSLLexer() *dslDefinition {
 		symbols: map[string]lexer.TokenType{
 			"EOF":        TokenEOF,
 			"Comment":    TokenComment,
+			"Annotation": TokenAnnotation,
 			"RawString":  TokenRawString,
 			"String":     TokenString,
 			"Number":     TokenNumber,
@@ -156,7 +166,11 @@ func (d *dslDefinition) LexBytes(filename string, data []byte) (lexer.Lexer, err
 	trivia := &TriviaList{}
 	d.lastTrivia = trivia
 
-	return newLexerState(filename, string(data), trivia), nil
+	l := newLexerState(filename, string(data), trivia)
+	l.maxTokens = d.maxTokens
+	l.done, d.lexed = d.lexed, nil
+
+	return l, nil
 }
 
 // LexString implements lexer.StringDefinition for efficiency.
@@ -166,7 +180,11 @@ func (d *dslDefinition) LexString(filename string, input string) (lexer.Lexer, e
 	trivia := &TriviaList{}
 	d.lastTrivia = trivia
 
-	return newLexerState(filename, input, trivia), nil
+	l := newLexerState(filename, input, trivia)
+	l.maxTokens = d.maxTokens
+	l.done, d.lexed = d.lexed, nil
+
+	return l, nil
 }
 
 // Trivia returns the collected trivia from the last lex operation.
@@ -194,6 +212,10 @@ type lexerState struct {
 	col            int
 	trivia         *TriviaList
 	lastWasNewline bool // tracks if we just saw a blank line (for detached comments)
+	tokens         int  // tokens produced so far
+	maxTokens      int  // 0 for no limit
+
+	done func(*TriviaList) // called once, at EOF or on an error
 }
 
 func newLexerState(filename, input string, trivia *TriviaList) *lexerState {
@@ -207,8 +229,25 @@ func newLexerState(filename, input string, trivia *TriviaList) *lexerState {
 	}
 }
 
-// Next returns the next token.
+// Next returns the next token, calling l.done once the input is exhausted or
+// can't be lexed.
 func (l *lexerState) Next() (lexer.Token, error) {
+	tok, err := l.next()
+	if (err != nil || tok.EOF()) && l.done != nil {
+		done := l.done
+		l.done = nil
+		done(l.trivia)
+	}
+
+	return tok, err
+}
+
+func (l *lexerState) next() (lexer.Token, error) {
+	l.tokens++
+	if l.maxTokens > 0 && l.tokens > l.maxTokens {
+		return lexer.Token{}, &ParseLimitError{Limit: LimitTokens, Max: int64(l.maxTokens)}
+	}
+
 	if l.eof() {
 		return lexer.EOFToken(l.pos()), nil
 	}
@@ -240,6 +279,9 @@ func (l *lexerState) Next() (lexer.Token, error) {
 		}
 
 		tok := l.token(TokenComment, start)
+		if isAnnotation(tok.Value) {
+			tok.Type = TokenAnnotation
+		}
 
 		// Record in trivia list
 		if l.trivia != nil {
@@ -15,7 +15,7 @@ func TestLexer_Symbols(t *testing.T) {
 	symbols := def.Symbols()
 
 	expected := []string{
-		"EOF", "Comment", "RawString", "String", "Number", "Ident", "Op",
+		"EOF", "Comment", "Annotation", "RawString", "String", "Number", "Ident", "Op",
 		"Dot", "Colon", "Comma", "Semi", "Whitespace",
 		"(", ")", "[", "]", "{", "}",
 	}
@@ -301,6 +301,9 @@ func TestLexer_Comments(t *testing.T) {
 		{"comment before token", "// comment\nfoo", []tokenExpect{{"Comment", "// comment"}, {"Ident", "foo"}}},
 		{"comment only", "// just a comment", []tokenExpect{{"Comment", "// just a comment"}}},
 		{"empty comment", "//\nfoo", []tokenExpect{{"Comment", "//"}, {"Ident", "foo"}}},
+		{"annotation", "// scaf-disable-next-line unused-import\nfoo", []tokenExpect{{"Annotation", "// scaf-disable-next-line unused-import"}, {"Ident", "foo"}}},
+		{"annotation with rules", "// scaf-disable empty-test, unused-import", []tokenExpect{{"Annotation", "// scaf-disable empty-test, unused-import"}}},
+		{"directive without rule", "// scaf-disable\nfoo", []tokenExpect{{"Comment", "// scaf-disable"}, {"Ident", "foo"}}},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+
+	"github.com/rlch/scaf"
+)
+
+// analysisDebounce is how long to wait after an edit before re-analyzing,
+// so a burst of keystrokes triggers a single analysis.
+const analysisDebounce = 150 * time.Millisecond
+
+// parseLimits bounds parsing of editor buffers and imported files, so a huge
+// or pathological file produces a diagnostic instead of hanging the server.
+var parseLimits = scaf.ParseOptions{
+	MaxBytes:  10 << 20,
+	MaxTokens: 1_000_000,
+	Timeout:   2 * time.Second,
+}
+
+// analyzeDocument analyzes doc's current content.
+// Must be called with s.mu held or before doc is shared.
+func (s *Server) analyzeDocument(doc *Document) {
+	start := time.Now()
+
+	// Use the file system path (not URI) for proper import resolution
+	doc.Analysis = s.analyzer.Analyze(URIToPath(doc.URI), []byte(doc.Content))
+	doc.analyzedContent = doc.Content
+	doc.analyzedHash = scaf.FileHash([]byte(doc.Content))
+	doc.lastAnalysisVersion = doc.Version
+
+	// If parsing succeeded, save as last valid analysis for completion fallback
+	if doc.Analysis.ParseError == nil {
+		doc.LastValidAnalysis = doc.Analysis
+	}
+
+	s.logger.Debug("analysis complete",
+		zap.String("uri", string(doc.URI)),
+		zap.Int32("version", doc.Version),
+		zap.Duration("analyzeTime", time.Since(start)),
+		zap.Bool("hasParseError", doc.Analysis.ParseError != nil))
+}
+
+// scheduleAnalysis (re)starts doc's debounce timer.
+// Must be called with s.mu held.
+func (s *Server) scheduleAnalysis(ctx context.Context, doc *Document) {
+	doc.stopAnalysisTimer()
+
+	uri, version := doc.URI, doc.Version
+	doc.analysisTimer = time.AfterFunc(s.analysisDelay, func() {
+		s.runScheduledAnalysis(ctx, uri, version)
+	})
+}
+
+// runScheduledAnalysis analyzes the document if it is still at version and
+// publishes its diagnostics. A request may already have analyzed it via
+// getDocument, in which case only the publishing remains.
+func (s *Server) runScheduledAnalysis(ctx context.Context, uri protocol.DocumentURI, version int32) {
+	// Hold the lock only for document state updates, not for RPC calls.
+	// This prevents deadlock when client sends requests while we're publishing diagnostics.
+	s.mu.Lock()
+	doc, ok := s.documents[uri]
+	if !ok || doc.Version != version || s.shutdownReceived {
+		// Closed, superseded by a newer edit with its own timer, or the
+		// server is shutting down.
+		s.mu.Unlock()
+		return
+	}
+
+	doc.analysisTimer = nil
+	if doc.lastAnalysisVersion != doc.Version {
+		s.analyzeDocument(doc)
+	}
+	s.mu.Unlock()
+
+	s.publishDiagnostics(ctx, doc)
+
+	// Test counts and diagnostic lenses depend on the new content.
+	s.refreshCodeLenses(ctx)
+}
+
+// stopAnalysisTimer cancels any pending debounced analysis.
+func (d *Document) stopAnalysisTimer() {
+	if d.analysisTimer != nil {
+		d.analysisTimer.Stop()
+		d.analysisTimer = nil
+	}
+}
+
+// reuseAnalysis reports whether doc's analysis still holds for its current
+// content, whose scaf.FileHash is hash: the content is unchanged, e.g. after
+// an undo, or the edit only touched whitespace.
+// Must be called with s.mu held.
+func (s *Server) reuseAnalysis(doc *Document, hash string) bool {
+	if hash == doc.analyzedHash {
+		s.logger.Debug("DidChange: content unchanged, reusing analysis")
+
+		return true
+	}
+
+	if whitespaceOnlyChange(doc.analyzedContent, doc.Content) {
+		s.logger.Debug("DidChange: whitespace-only change, reusing analysis")
+
+		return true
+	}
+
+	return false
+}
+
+// whitespaceOnlyChange reports whether before and after lex to the same
+// tokens at the same lines and columns, ignoring whitespace. Such an edit
+// (e.g. trailing spaces, or indentation on a blank line) leaves every span in
+// the previous analysis valid, so it can be reused.
+func whitespaceOnlyChange(before, after string) bool {
+	if before == after {
+		return true
+	}
+
+	beforeTokens, ok := significantTokens(before)
+	if !ok {
+		return false
+	}
+
+	afterTokens, ok := significantTokens(after)
+	if !ok || len(beforeTokens) != len(afterTokens) {
+		return false
+	}
+
+	for i, tok := range beforeTokens {
+		other := afterTokens[i]
+		if tok.Type != other.Type || tok.Value != other.Value ||
+			tok.Pos.Line != other.Pos.Line || tok.Pos.Column != other.Pos.Column {
+			return false
+		}
+	}
+
+	return true
+}
+
+// significantTokens lexes content and returns its non-whitespace tokens.
+// Returns false if the content fails to lex.
+func significantTokens(content string) ([]lexer.Token, bool) {
+	def := scaf.ExportedLexer()
+
+	// Lexing records trivia on the shared definition, so serialize with parsing.
+	def.Lock()
+	defer def.Unlock()
+
+	lex, err := def.LexString("", content)
+	if err != nil {
+		return nil, false
+	}
+
+	var tokens []lexer.Token
+
+	for {
+		tok, err := lex.Next()
+		if err != nil {
+			return nil, false
+		}
+
+		if tok.EOF() {
+			return tokens, true
+		}
+
+		if tok.Type != scaf.TokenWhitespace {
+			tokens = append(tokens, tok)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package lsp
+
+import (
+	"context"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+)
+
+// client extends the protocol client dispatcher with server-to-client
+// requests that go.lsp.dev/protocol doesn't expose.
+type client struct {
+	protocol.Client
+	conn jsonrpc2.Conn
+}
+
+// NewClient creates the client used to send notifications and requests to the editor.
+func NewClient(conn jsonrpc2.Conn, logger *zap.Logger) protocol.Client {
+	return &client{
+		Client: protocol.ClientDispatcher(conn, logger),
+		conn:   conn,
+	}
+}
+
+// CodeLensRefresh sends a workspace/codeLens/refresh request.
+func (c *client) CodeLensRefresh(ctx context.Context) error {
+	_, err := c.conn.Call(ctx, protocol.MethodCodeLensRefresh, nil, nil)
+	return err
+}
+
+// DiagnosticRefresh sends a workspace/diagnostic/refresh request.
+func (c *client) DiagnosticRefresh(ctx context.Context) error {
+	_, err := c.conn.Call(ctx, methodDiagnosticRefresh, nil, nil)
+	return err
+}
@@ -100,13 +100,14 @@ func (s *Server) codeActionsForDiagnostic(doc *Document, diag protocol.Diagnosti
 		return nil
 	}
 
+	if action := autoFixAction(doc, code, diag); action != nil {
+		actions = append(actions, *action)
+	}
+
 	switch code {
 	case "missing-required-params":
 		actions = append(actions, s.fixMissingParams(doc, diag)...)
 
-	case "unused-import":
-		actions = append(actions, s.fixUnusedImport(doc, diag)...)
-
 	case "undefined-query":
 		actions = append(actions, s.fixUndefinedQuery(doc, diag)...)
 
@@ -215,53 +216,50 @@ func (s *Server) findTestAtRange(item *scaf.TestOrGroup, rng protocol.Range) *sc
 	return nil
 }
 
-// fixUnusedImport generates a quick fix to remove an unused import.
-func (s *Server) fixUnusedImport(doc *Document, diag protocol.Diagnostic) []protocol.CodeAction {
-	if doc.Analysis.Suite == nil {
-		return nil
-	}
-
-	// Find the import at this position
-	var targetImport *scaf.Import
-	for _, imp := range doc.Analysis.Suite.Imports {
-		impRange := spanToRange(imp.Span())
-		if rangesOverlap(impRange, diag.Range) {
-			targetImport = imp
-			break
+// autoFixAction returns a quick fix applying the AutoFix of the analysis
+// diagnostic matching diag, or nil if it has none.
+func autoFixAction(doc *Document, code string, diag protocol.Diagnostic) *protocol.CodeAction {
+	for _, d := range doc.Analysis.Diagnostics {
+		if d.AutoFix == nil || d.Code != code || d.Message != diag.Message ||
+			!rangesOverlap(spanToRange(d.Span), diag.Range) {
+			continue
 		}
-	}
 
-	if targetImport == nil {
-		return nil
-	}
-
-	// Delete the entire import line (including newline)
-	impRange := spanToRange(targetImport.Span())
-	// Extend to include the entire line
-	deleteRange := protocol.Range{
-		Start: protocol.Position{Line: impRange.Start.Line, Character: 0},
-		End:   protocol.Position{Line: impRange.End.Line + 1, Character: 0},
-	}
-
-	edit := protocol.WorkspaceEdit{
-		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
-			doc.URI: {
-				{
-					Range:   deleteRange,
-					NewText: "",
+		edit := protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				doc.URI: {
+					{
+						Range:   spanToRange(d.AutoFix.Span),
+						NewText: d.AutoFix.NewText,
+					},
 				},
 			},
-		},
-	}
+		}
 
-	alias := baseNameFromImport(targetImport)
-	return []protocol.CodeAction{
-		{
-			Title:       fmt.Sprintf("Remove unused import '%s'", alias),
+		return &protocol.CodeAction{
+			Title:       autoFixTitle(d),
 			Kind:        protocol.QuickFix,
 			Diagnostics: []protocol.Diagnostic{diag},
+			IsPreferred: true,
 			Edit:        &edit,
-		},
+		}
+	}
+
+	return nil
+}
+
+// autoFixTitle names the quick fix for a diagnostic's AutoFix.
+func autoFixTitle(diag analysis.Diagnostic) string {
+	switch diag.Code {
+	case "unused-import":
+		return fmt.Sprintf("Remove unused import '%s'", strings.TrimPrefix(diag.Message, "unused import: "))
+
+	case "unused-declared-param":
+		name, _, _ := strings.Cut(strings.TrimPrefix(diag.Message, "declared parameter "), " ")
+		return fmt.Sprintf("Remove unused parameter '%s'", name)
+
+	default:
+		return "Fix: " + diag.Message
 	}
 }
 
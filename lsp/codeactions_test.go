@@ -171,6 +171,60 @@ GetUser {
 	}
 }
 
+func TestServer_CodeAction_UnusedDeclaredParam(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := "fn GetUser(id: string, unused: int) `MATCH (u:User {id: $id}) RETURN u`\n"
+	uri := protocol.DocumentURI("file:///test.scaf")
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     uri,
+			Version: 1,
+			Text:    content,
+		},
+	})
+
+	// No diagnostics in the request; the document's own are used
+	result, err := server.CodeAction(ctx, &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 25},
+			End:   protocol.Position{Line: 0, Character: 25},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CodeAction() error: %v", err)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 code action, got %d", len(result))
+	}
+
+	action := result[0]
+	if action.Title != "Remove unused parameter 'unused'" || action.Kind != protocol.QuickFix {
+		t.Errorf("unexpected action %q of kind %q", action.Title, action.Kind)
+	}
+
+	edits := action.Edit.Changes[uri]
+	if len(edits) != 1 {
+		t.Fatalf("Expected 1 edit, got %d", len(edits))
+	}
+
+	want := protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 11},
+		End:   protocol.Position{Line: 0, Character: 34},
+	}
+	if edits[0].Range != want || edits[0].NewText != "id: string" {
+		t.Errorf("edit = %+v, want %q over %+v", edits[0], "id: string", want)
+	}
+}
+
 func TestServer_CodeAction_UndefinedQuery(t *testing.T) {
 	t.Parallel()
 
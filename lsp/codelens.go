@@ -2,15 +2,27 @@ package lsp
 
 import (
 	"context"
+	"fmt"
 
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
 
 	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+)
+
+// Commands issued by code lenses.
+const (
+	CommandRunScope       = "scaf.runScope"
+	CommandRunTest        = "scaf.runTest"
+	CommandRunGroup       = "scaf.runGroup"
+	CommandShowDiagnostic = "scaf.showDiagnostics"
 )
 
 // CodeLens handles textDocument/codeLens requests.
 // Returns code lenses for running tests, groups, and query scopes.
+// Each scope gets a "▶ Run N tests" lens, plus a "⚠ K diagnostics" lens
+// when errors or warnings are reported inside the scope.
 func (s *Server) CodeLens(_ context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
 	defer s.traceHandler("CodeLens")()
 	s.logger.Debug("CodeLens",
@@ -26,16 +38,27 @@ func (s *Server) CodeLens(_ context.Context, params *protocol.CodeLensParams) ([
 
 	// Walk through all query scopes
 	for _, scope := range doc.Analysis.Suite.Scopes {
-		// Add "Run All" lens for the query scope
+		// Add "Run N tests" lens for the query scope
 		lenses = append(lenses, protocol.CodeLens{
 			Range: scopeNameRange(scope),
 			Command: &protocol.Command{
-				Title:     "▶ Run All",
-				Command:   "scaf.runScope",
+				Title:     "▶ Run " + pluralize(countTests(scope.Items), "test"),
+				Command:   CommandRunScope,
 				Arguments: []any{filePath, scope.FunctionName},
 			},
 		})
 
+		if n := countScopeDiagnostics(doc.Analysis, scope); n > 0 {
+			lenses = append(lenses, protocol.CodeLens{
+				Range: scopeNameRange(scope),
+				Command: &protocol.Command{
+					Title:     "⚠ " + pluralize(n, "diagnostic"),
+					Command:   CommandShowDiagnostic,
+					Arguments: []any{filePath, scope.FunctionName},
+				},
+			})
+		}
+
 		// Walk through tests and groups in this scope
 		lenses = append(lenses, s.collectItemLenses(filePath, scope.FunctionName, "", scope.Items)...)
 	}
@@ -43,6 +66,104 @@ func (s *Server) CodeLens(_ context.Context, params *protocol.CodeLensParams) ([
 	return lenses, nil
 }
 
+// ExecuteCommand handles workspace/executeCommand.
+// scaf.runScope is acknowledged with a window/showMessage notification until
+// the test runner is wired in; other commands are handled by the editor.
+func (s *Server) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (any, error) {
+	s.logger.Debug("ExecuteCommand",
+		zap.String("command", params.Command),
+		zap.Any("arguments", params.Arguments))
+
+	if params.Command != CommandRunScope {
+		return nil, nil //nolint:nilnil // Unknown commands have no result
+	}
+
+	var filePath, scopeName string
+	if len(params.Arguments) >= 2 {
+		filePath, _ = params.Arguments[0].(string)
+		scopeName, _ = params.Arguments[1].(string)
+	}
+
+	err := s.client.ShowMessage(ctx, &protocol.ShowMessageParams{
+		Type:    protocol.MessageTypeInfo,
+		Message: fmt.Sprintf("scaf: running %s in %s", scopeName, filePath),
+	})
+	if err != nil {
+		s.logger.Debug("Failed to show message", zap.Error(err))
+	}
+
+	return nil, nil //nolint:nilnil // Command has no result
+}
+
+// codeLensRefresher is implemented by clients that can send
+// workspace/codeLens/refresh requests (see NewClient).
+type codeLensRefresher interface {
+	CodeLensRefresh(ctx context.Context) error
+}
+
+// refreshCodeLenses asks the client to re-request code lenses,
+// if it advertised support for workspace/codeLens/refresh.
+func (s *Server) refreshCodeLenses(ctx context.Context) {
+	if !s.codeLensRefresh {
+		return
+	}
+
+	refresher, ok := s.client.(codeLensRefresher)
+	if !ok {
+		return
+	}
+
+	// The refresh is a request, and the connection only reads responses between
+	// handler calls, so it must not block the handler that triggered it.
+	go func() {
+		if err := refresher.CodeLensRefresh(context.WithoutCancel(ctx)); err != nil {
+			s.logger.Debug("Failed to refresh code lenses", zap.Error(err))
+		}
+	}()
+}
+
+// countTests counts tests in items, recursing into groups.
+func countTests(items []*scaf.TestOrGroup) int {
+	n := 0
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if item.Test != nil {
+			n++
+		}
+		if item.Group != nil {
+			n += countTests(item.Group.Items)
+		}
+	}
+
+	return n
+}
+
+// countScopeDiagnostics counts unsuppressed error and warning diagnostics inside a scope.
+func countScopeDiagnostics(f *analysis.AnalyzedFile, scope *scaf.QueryScope) int {
+	n := 0
+	for _, diag := range f.Diagnostics {
+		if diag.Suppressed || (diag.Severity != analysis.SeverityError && diag.Severity != analysis.SeverityWarning) {
+			continue
+		}
+		if scope.Span().Contains(diag.Span.Start) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// pluralize formats a count with a noun, e.g. "1 test" or "3 tests".
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
 // collectItemLenses recursively collects code lenses for tests and groups.
 func (s *Server) collectItemLenses(filePath, queryScope, groupPath string, items []*scaf.TestOrGroup) []protocol.CodeLens {
 	lenses := make([]protocol.CodeLens, 0, len(items))
@@ -58,7 +179,7 @@ func (s *Server) collectItemLenses(filePath, queryScope, groupPath string, items
 				Range: testNameRange(item.Test),
 				Command: &protocol.Command{
 					Title:     "▶ Run Test",
-					Command:   "scaf.runTest",
+					Command:   CommandRunTest,
 					Arguments: []any{filePath, testFullPath},
 				},
 			})
@@ -70,7 +191,7 @@ func (s *Server) collectItemLenses(filePath, queryScope, groupPath string, items
 				Range: groupNameRange(item.Group),
 				Command: &protocol.Command{
 					Title:     "▶ Run Group",
-					Command:   "scaf.runGroup",
+					Command:   CommandRunGroup,
 					Arguments: []any{filePath, groupFullPath},
 				},
 			})
@@ -100,12 +100,17 @@ func (s *Server) Completion(ctx context.Context, params *protocol.CompletionPara
 		items = s.completeTypeAnnotations(cc)
 	case CompletionKindExprVariable:
 		items = s.completeExprVariables(doc, cc)
+	case CompletionKindTableColumn:
+		items = s.completeTableColumns(doc, cc)
+	case CompletionKindTableValue:
+		items = s.completeTableValues(doc, cc)
 	}
 
 	// Filter by prefix
 	// Skip filtering for return fields and expr variables when prefix contains a dot (e.g., "u.")
 	// because completeReturnFields and completeExprVariables already handle prefix matching internally
-	skipPrefixFilter := (cc.Kind == CompletionKindReturnField || cc.Kind == CompletionKindExprVariable) && strings.Contains(cc.Prefix, ".")
+	skipPrefixFilter := (cc.Kind == CompletionKindReturnField || cc.Kind == CompletionKindExprVariable ||
+		cc.Kind == CompletionKindTableColumn) && strings.Contains(cc.Prefix, ".")
 	if cc.Prefix != "" && !skipPrefixFilter {
 		items = filterByPrefix(items, cc.Prefix)
 	}
@@ -129,21 +134,29 @@ const (
 	CompletionKindSetupFunction  CompletionKind = "setup_function"
 	CompletionKindTypeAnnotation CompletionKind = "type_annotation"
 	CompletionKindExprVariable   CompletionKind = "expr_variable"
+	CompletionKindTableColumn    CompletionKind = "table_column"
+	CompletionKindTableValue     CompletionKind = "table_value"
 )
 
 // CompletionContext holds information about where completion was triggered.
 type CompletionContext struct {
 	Kind            CompletionKind
-	Prefix          string // Text being typed (for filtering)
-	InScope         string // Name of enclosing QueryScope
-	InTest          bool   // Inside a test body
-	InSetup         bool   // Inside a setup clause
-	InAssert        bool   // Inside an assert block
-	InExpr          bool   // Inside a parenthesized expression (assert or where clause)
-	AssertQueryName string // Name of the assert's query (if any) - takes precedence over InScope for expression variables
-	AssertQueryBody string // Inline query body (if any) - for inline assert queries
-	ModuleAlias     string // Import alias for module.function completion
-	TriggerChar     string // The trigger character (., $)
+	Prefix          string   // Text being typed (for filtering)
+	InScope         string   // Name of enclosing QueryScope
+	InTest          bool     // Inside a test body
+	InSetup         bool     // Inside a setup clause
+	InAssert        bool     // Inside an assert block
+	InExpr          bool     // Inside a parenthesized expression (assert or where clause)
+	AssertQueryName string   // Name of the assert's query (if any) - takes precedence over InScope for expression variables
+	AssertQueryBody string   // Inline query body (if any) - for inline assert queries
+	ModuleAlias     string   // Import alias for module.function completion
+	TriggerChar     string   // The trigger character (., $)
+	InTable         bool     // On a row of a table block
+	InTableHeader   bool     // On the header row of a table block
+	TableColumns    []string // Header cells of the enclosing table
+	TableColumn     int      // Index of the cell being typed in a table row
+	InMapKeyType    bool     // After "{" in a map type annotation
+	InMapValueType  bool     // After "{key:" in a map type annotation
 }
 
 // buildCompletionContext analyzes the document and returns completion context.
@@ -193,6 +206,9 @@ func (s *Server) buildCompletionContext(doc *Document, pos protocol.Position, tr
 	// Determine positional context (InScope, InTest, InSetup, InAssert)
 	s.determinePositionalContext(cc, symbolsAnalysis, lexPos)
 
+	// Table rows are found from the text, since a row being typed rarely parses
+	determineTableContext(cc, lines, int(pos.Line), textBeforeCursor)
+
 	// === SINGLE DISPATCH: Look at token before cursor ===
 	// This is the gopls approach: one decision tree based on prev token
 	cc.Kind = s.determineCompletionKind(cc, doc, symbolsAnalysis, lexPos, textBeforeCursor)
@@ -207,25 +223,25 @@ func (s *Server) determinePositionalContext(cc *CompletionContext, af *analysis.
 	}
 
 	for _, scope := range af.Suite.Scopes {
-		if !containsLexerPosition(scope.Span(), pos) {
+		if !scope.Span().Contains(pos) {
 			continue
 		}
 		cc.InScope = scope.FunctionName
 
 		// Check scope-level setup
-		if scope.Setup != nil && containsLexerPosition(scope.Setup.Span(), pos) {
+		if scope.Setup != nil && scope.Setup.Span().Contains(pos) {
 			cc.InSetup = true
 		}
 
 		// Check items (tests and groups)
 		for _, item := range scope.Items {
-			if item.Test != nil && containsLexerPosition(item.Test.Span(), pos) {
+			if item.Test != nil && item.Test.Span().Contains(pos) {
 				cc.InTest = true
-				if item.Test.Setup != nil && containsLexerPosition(item.Test.Setup.Span(), pos) {
+				if item.Test.Setup != nil && item.Test.Setup.Span().Contains(pos) {
 					cc.InSetup = true
 				}
 				for _, assert := range item.Test.Asserts {
-					if containsLexerPosition(assert.Span(), pos) {
+					if assert.Span().Contains(pos) {
 						cc.InAssert = true
 						// Capture the assert's query scope if present
 						if assert.Query != nil {
@@ -237,7 +253,7 @@ func (s *Server) determinePositionalContext(cc *CompletionContext, af *analysis.
 						}
 						// Check if inside a condition expression
 						for _, cond := range assert.AllConditions() {
-							if cond != nil && containsLexerPosition(cond.Span(), pos) {
+							if cond != nil && cond.Span().Contains(pos) {
 								cc.InExpr = true
 							}
 						}
@@ -247,17 +263,17 @@ func (s *Server) determinePositionalContext(cc *CompletionContext, af *analysis.
 				for _, stmt := range item.Test.Statements {
 					if stmt != nil && stmt.Value != nil {
 						// Check statement expression
-						if stmt.Value.Expr != nil && containsLexerPosition(stmt.Value.Expr.Span(), pos) {
+						if stmt.Value.Expr != nil && stmt.Value.Expr.Span().Contains(pos) {
 							cc.InExpr = true
 						}
 						// Check where clause
-						if stmt.Value.Where != nil && containsLexerPosition(stmt.Value.Where.Span(), pos) {
+						if stmt.Value.Where != nil && stmt.Value.Where.Span().Contains(pos) {
 							cc.InExpr = true
 						}
 					}
 				}
 			}
-			if item.Group != nil && containsLexerPosition(item.Group.Span(), pos) {
+			if item.Group != nil && item.Group.Span().Contains(pos) {
 				s.checkGroupContext(cc, item.Group, pos)
 			}
 		}
@@ -266,18 +282,18 @@ func (s *Server) determinePositionalContext(cc *CompletionContext, af *analysis.
 
 // checkGroupContext recursively checks context within a group.
 func (s *Server) checkGroupContext(cc *CompletionContext, group *scaf.Group, pos lexer.Position) {
-	if group.Setup != nil && containsLexerPosition(group.Setup.Span(), pos) {
+	if group.Setup != nil && group.Setup.Span().Contains(pos) {
 		cc.InSetup = true
 	}
 	for _, item := range group.Items {
-		if item.Test != nil && containsLexerPosition(item.Test.Span(), pos) {
+		if item.Test != nil && item.Test.Span().Contains(pos) {
 			cc.InTest = true
-			if item.Test.Setup != nil && containsLexerPosition(item.Test.Setup.Span(), pos) {
+			if item.Test.Setup != nil && item.Test.Setup.Span().Contains(pos) {
 				cc.InSetup = true
 			}
 			// Check asserts for expression context
 			for _, assert := range item.Test.Asserts {
-				if containsLexerPosition(assert.Span(), pos) {
+				if assert.Span().Contains(pos) {
 					cc.InAssert = true
 					// Capture the assert's query scope if present
 					if assert.Query != nil {
@@ -288,7 +304,7 @@ func (s *Server) checkGroupContext(cc *CompletionContext, group *scaf.Group, pos
 						}
 					}
 					for _, cond := range assert.AllConditions() {
-						if cond != nil && containsLexerPosition(cond.Span(), pos) {
+						if cond != nil && cond.Span().Contains(pos) {
 							cc.InExpr = true
 						}
 					}
@@ -297,16 +313,16 @@ func (s *Server) checkGroupContext(cc *CompletionContext, group *scaf.Group, pos
 			// Check statements for expression context
 			for _, stmt := range item.Test.Statements {
 				if stmt != nil && stmt.Value != nil {
-					if stmt.Value.Expr != nil && containsLexerPosition(stmt.Value.Expr.Span(), pos) {
+					if stmt.Value.Expr != nil && stmt.Value.Expr.Span().Contains(pos) {
 						cc.InExpr = true
 					}
-					if stmt.Value.Where != nil && containsLexerPosition(stmt.Value.Where.Span(), pos) {
+					if stmt.Value.Where != nil && stmt.Value.Where.Span().Contains(pos) {
 						cc.InExpr = true
 					}
 				}
 			}
 		}
-		if item.Group != nil && containsLexerPosition(item.Group.Span(), pos) {
+		if item.Group != nil && item.Group.Span().Contains(pos) {
 			s.checkGroupContext(cc, item.Group, pos)
 		}
 	}
@@ -325,9 +341,18 @@ func (s *Server) determineCompletionKind(cc *CompletionContext, doc *Document, a
 	// Case 0: Type annotation trigger - after colon in function parameter list
 	// Check via AST first (more reliable), fall back to text-based detection
 	if s.isInFunctionParamTypePosition(af, pos, prevToken, textBeforeCursor) {
+		cc.InMapKeyType, cc.InMapValueType = mapTypePosition(textBeforeCursor)
 		return CompletionKindTypeAnnotation
 	}
 
+	// Case 0.5: Table row - column names in the header, values below it
+	if cc.InTable {
+		if cc.InTableHeader {
+			return CompletionKindTableColumn
+		}
+		return CompletionKindTableValue
+	}
+
 	// Case 1: Dot trigger - module.function completion
 	if cc.TriggerChar == "." {
 		return s.handleDotCompletion(cc, doc, af, pos, prevToken)
@@ -774,6 +799,12 @@ func (s *Server) completeKeywords(cc *CompletionContext) []protocol.CompletionIt
 				snippet: "test \"${1:test name}\" {\n\t${2:\\$param: value}\n\t${3:field: expected}\n}",
 				doc:     "Defines a test case with inputs and expected outputs.",
 			},
+			{
+				label:   "table",
+				detail:  "Define a table of test cases",
+				snippet: "table \"${1:table name}\" {\n\t| ${2:\\$param} | ${3:field} |\n\t| ${4:value} | ${5:expected} |\n}",
+				doc:     "Defines one test per data row. The header names inputs ($param) and expected fields.",
+			},
 			{
 				label:   "group",
 				detail:  "Group related tests",
@@ -833,6 +864,143 @@ func (s *Server) completeParameters(doc *Document, cc *CompletionContext) []prot
 	return items
 }
 
+// determineTableContext sets the table fields of cc when the cursor is on a
+// row of a table block: a line starting with a pipe, below a table header
+// line, with only other rows in between.
+func determineTableContext(cc *CompletionContext, lines []string, line int, textBeforeCursor string) {
+	if cc.InScope == "" || !strings.HasPrefix(strings.TrimSpace(textBeforeCursor), "|") {
+		return
+	}
+
+	first := line
+	for first > 0 && strings.HasPrefix(strings.TrimSpace(lines[first-1]), "|") {
+		first--
+	}
+
+	if first == 0 || !strings.HasPrefix(strings.TrimSpace(lines[first-1]), "table ") {
+		return
+	}
+
+	cc.InTable = true
+	cc.InTableHeader = first == line
+	cc.TableColumn = strings.Count(textBeforeCursor, "|") - 1
+
+	for _, cell := range strings.Split(lines[first], "|") {
+		if cell = strings.TrimSpace(cell); cell != "" {
+			cc.TableColumns = append(cc.TableColumns, cell)
+		}
+	}
+}
+
+// completeTableColumns returns the parameters and return fields of the query
+// in scope that aren't already in the table header.
+func (s *Server) completeTableColumns(doc *Document, cc *CompletionContext) []protocol.CompletionItem {
+	used := make(map[string]bool, len(cc.TableColumns))
+	for _, col := range cc.TableColumns {
+		used[col] = true
+	}
+
+	// After "u." return fields complete just the property part
+	base := cc.Prefix[:strings.LastIndex(cc.Prefix, ".")+1]
+
+	candidates := s.completeReturnFields(doc, cc)
+	if base == "" {
+		candidates = append(s.completeParameters(doc, cc), candidates...)
+	}
+
+	// Completions insert just the name, without the ": " used in tests
+	var items []protocol.CompletionItem
+	for _, item := range candidates {
+		name := strings.TrimSuffix(item.InsertText, ": ")
+		if used[base+name] {
+			continue
+		}
+
+		item.InsertText = name
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// completeTableValues returns example values for the table column being
+// typed, based on the parameter's declared type or the field's inferred type.
+// Columns of unknown type get one value of each kind.
+func (s *Server) completeTableValues(doc *Document, cc *CompletionContext) []protocol.CompletionItem {
+	if cc.TableColumn < 0 || cc.TableColumn >= len(cc.TableColumns) {
+		return nil
+	}
+
+	column := cc.TableColumns[cc.TableColumn]
+	typ := s.tableColumnType(doc, cc.InScope, column)
+
+	base := strings.TrimLeft(typ, "*")
+
+	var values []string
+
+	switch {
+	case base == "bool":
+		values = []string{"true", "false"}
+	case base == "string":
+		values = []string{`""`}
+	case strings.HasPrefix(base, "int") || strings.HasPrefix(base, "uint") || strings.HasPrefix(base, "float"):
+		values = []string{"0"}
+	case strings.HasPrefix(base, "[]"):
+		values = []string{"[]"}
+	case strings.HasPrefix(base, "map["):
+		values = []string{"{}"}
+	case typ == "" || base == "any":
+		values = []string{`""`, "0", "true", "false", "[]", "{}"}
+	}
+
+	values = append(values, "null")
+
+	detail := column
+	if typ != "" {
+		detail += " " + typ
+	}
+
+	items := make([]protocol.CompletionItem, 0, len(values))
+	for _, v := range values {
+		items = append(items, protocol.CompletionItem{
+			Label:  v,
+			Kind:   protocol.CompletionItemKindValue,
+			Detail: detail,
+		})
+	}
+
+	return items
+}
+
+// tableColumnType returns the Go type of a table column, or "" if unknown.
+func (s *Server) tableColumnType(doc *Document, scope, column string) string {
+	af := s.getSymbolsAnalysis(doc)
+	if af == nil || af.Symbols == nil {
+		return ""
+	}
+
+	q, ok := af.Symbols.Queries[scope]
+	if !ok {
+		return ""
+	}
+
+	if param, ok := strings.CutPrefix(column, "$"); ok {
+		if t := q.TypedParams[param]; t != nil {
+			return t.ToGoType()
+		}
+
+		return ""
+	}
+
+	for _, ret := range q.QueryBodyReturns {
+		if ret.Type != nil && (ret.Name == column || ret.Expression == column || ret.Alias == column) {
+			return ret.Type.String()
+		}
+	}
+
+	return ""
+}
+
 // completeReturnFields returns return field completions from the query in scope.
 func (s *Server) completeReturnFields(doc *Document, cc *CompletionContext) []protocol.CompletionItem {
 	af := s.getSymbolsAnalysis(doc)
@@ -909,6 +1077,10 @@ func (s *Server) completeReturnFields(doc *Document, cc *CompletionContext) []pr
 		if ret.IsAggregate {
 			item.Detail = "aggregate field"
 		}
+		// OPTIONAL MATCH variables may be null, which the type annotation shows
+		if ret.Nullable && ret.Type != nil {
+			item.Detail += " (" + ret.Type.String() + "?)"
+		}
 		items = append(items, item)
 	}
 	return items
@@ -1224,18 +1396,20 @@ func (s *Server) completeSetupFunctions(doc *Document, cc *CompletionContext) []
 		return nil
 	}
 
-	if importedFile.Symbols == nil {
+	// Internal helper queries aren't offered to importers.
+	exports := importedFile.ExportSymbols()
+	if exports == nil {
 		s.logger.Debug("completeSetupFunctions: imported file has no symbols")
 		return nil
 	}
 
 	s.logger.Debug("completeSetupFunctions: building completions",
-		zap.Int("queryCount", len(importedFile.Symbols.Queries)))
+		zap.Int("queryCount", len(exports.Queries)))
 
 	var items []protocol.CompletionItem
 
 	// Add queries from imported file as setup functions
-	for name, q := range importedFile.Symbols.Queries {
+	for name, q := range exports.Queries {
 		item := protocol.CompletionItem{
 			Label:  name,
 			Kind:   protocol.CompletionItemKindFunction,
@@ -1336,17 +1510,6 @@ func startsWithUpper(s string) bool {
 	return unicode.IsUpper(rune(s[0]))
 }
 
-// containsLexerPosition checks if a span contains a lexer position.
-func containsLexerPosition(span scaf.Span, pos lexer.Position) bool {
-	if pos.Line < span.Start.Line || (pos.Line == span.Start.Line && pos.Column < span.Start.Column) {
-		return false
-	}
-	if pos.Line > span.End.Line || (pos.Line == span.End.Line && pos.Column > span.End.Column) {
-		return false
-	}
-	return true
-}
-
 // markdownCodeBlock wraps code in a markdown code block.
 func (s *Server) markdownCodeBlock(code string) string {
 	lang := scaf.MarkdownLanguage(s.dialectName)
@@ -1363,7 +1526,7 @@ func (s *Server) isInFunctionParamTypePosition(af *analysis.AnalyzedFile, pos le
 				continue
 			}
 			// Check if position is within the function's span
-			if !analysis.ContainsPosition(fn.Span(), pos) {
+			if !fn.Span().Contains(pos) {
 				continue
 			}
 			// We're inside the function. Check if we're in the parameter list (before the body).
@@ -1379,7 +1542,7 @@ func (s *Server) isInFunctionParamTypePosition(af *analysis.AnalyzedFile, pos le
 					continue
 				}
 				// If the param has a type, check if we're inside that type's span
-				if param.Type != nil && analysis.ContainsPosition(param.Type.Span(), pos) {
+				if param.Type != nil && param.Type.Span().Contains(pos) {
 					return true
 				}
 				// If param has no type but we're after a colon on the same param
@@ -1459,8 +1622,61 @@ func (s *Server) isInFunctionParamTypePositionText(textBeforeCursor string) bool
 	return lastColon >= 0
 }
 
+// mapTypePosition reports whether the end of textBeforeCursor is in the key
+// or value type of the innermost unclosed map type, as in "{str" or "{string: ".
+func mapTypePosition(textBeforeCursor string) (inKey, inValue bool) {
+	// Innermost open bracket: '[' for arrays, 'k' or 'v' for a map's key or value
+	var open []byte
+
+	for i := range len(textBeforeCursor) {
+		switch textBeforeCursor[i] {
+		case '[':
+			open = append(open, '[')
+		case '{':
+			open = append(open, 'k')
+		case ':':
+			if len(open) > 0 && open[len(open)-1] == 'k' {
+				open[len(open)-1] = 'v'
+			}
+		case ']', '}':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case '(', ',':
+			// A new parameter list or parameter starts outside any type
+			open = open[:0]
+		}
+	}
+
+	if len(open) == 0 {
+		return false, false
+	}
+
+	top := open[len(open)-1]
+
+	return top == 'k', top == 'v'
+}
+
 // completeTypeAnnotations returns type annotation completions.
+// Map keys are always strings, so a map's key position only offers string.
 func (s *Server) completeTypeAnnotations(cc *CompletionContext) []protocol.CompletionItem {
+	if cc.InMapKeyType {
+		return []protocol.CompletionItem{{
+			Label:  "string",
+			Kind:   protocol.CompletionItemKindTypeParameter,
+			Detail: "map key type",
+			Documentation: &protocol.MarkupContent{
+				Kind:  protocol.Markdown,
+				Value: "Map keys are always strings",
+			},
+		}}
+	}
+
+	detail := "type"
+	if cc.InMapValueType {
+		detail = "map value type"
+	}
+
 	// Define available types with their documentation
 	types := []struct {
 		name string
@@ -1483,7 +1699,7 @@ func (s *Server) completeTypeAnnotations(cc *CompletionContext) []protocol.Compl
 		items = append(items, protocol.CompletionItem{
 			Label:  t.name,
 			Kind:   protocol.CompletionItemKindTypeParameter,
-			Detail: "type",
+			Detail: detail,
 			Documentation: &protocol.MarkupContent{
 				Kind:  protocol.Markdown,
 				Value: t.doc,
@@ -3,6 +3,7 @@ package lsp_test
 import (
 	"context"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -129,6 +130,59 @@ GetUser {
 	}
 }
 
+func TestServer_Completion_QueryBodyParameters(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	// The cursor is after the $ in the body of GetUser
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text:    "fn GetUser(id: string, name) `MATCH (u:User) WHERE u.id = $ RETURN u`\n",
+		},
+	})
+
+	result, err := server.Completion(ctx, &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+			Position:     protocol.Position{Line: 0, Character: 59},
+		},
+		Context: &protocol.CompletionContext{
+			TriggerKind:      protocol.CompletionTriggerKindTriggerCharacter,
+			TriggerCharacter: "$",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion() error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected completion result")
+	}
+
+	details := make(map[string]string)
+	for _, item := range result.Items {
+		details[item.Label] = item.Detail
+	}
+
+	want := map[string]string{"id": "string", "name": "parameter"}
+	if len(details) != len(want) {
+		t.Errorf("completions = %v, want %v", details, want)
+	}
+
+	for label, detail := range want {
+		if got, ok := details[label]; !ok || got != detail {
+			t.Errorf("completion %q detail = %q, want %q (all: %v)", label, got, detail, details)
+		}
+	}
+}
+
 func TestServer_Completion_ReturnFields(t *testing.T) {
 	t.Parallel()
 
@@ -568,6 +622,7 @@ func TestServer_Completion_SetupFunctions_CrossFile(t *testing.T) {
 	fixturesContent := `fn CreateUser() ` + "`CREATE (u:User {name: $name, email: $email}) RETURN u`" + `
 fn CreatePost() ` + "`CREATE (p:Post {title: $title, authorId: $authorId}) RETURN p`" + `
 fn SetupDatabase() ` + "`CREATE CONSTRAINT FOR (u:User) REQUIRE u.id IS UNIQUE`" + `
+fn _resetDatabase() ` + "`MATCH (n) DETACH DELETE n`" + `
 `
 	fixturesPath := tmpDir + "/fixtures.scaf"
 	if err := writeFile(fixturesPath, fixturesContent); err != nil {
@@ -713,6 +768,11 @@ GetUser {
 	if !queryLabels["SetupDatabase"] {
 		t.Errorf("Expected SetupDatabase query completion, got: %v", queryLabels)
 	}
+
+	// Queries starting with _ are internal to the module.
+	if queryLabels["_resetDatabase"] {
+		t.Errorf("Expected internal _resetDatabase query to be hidden, got: %v", queryLabels)
+	}
 }
 
 // writeFile is a test helper to write content to a file.
@@ -1341,6 +1401,79 @@ func TestServer_Completion_TypeAnnotations_MultipleParams(t *testing.T) {
 	}
 }
 
+func TestServer_Completion_TypeAnnotations_MapType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		text       string
+		wantDetail string
+		wantLabels []string
+	}{
+		{"key", "fn GetData(data: {", "map key type", []string{"string"}},
+		{"value", "fn GetData(data: {string: ", "map value type", []string{"string", "int", "bool", "any", "[...]", "{...: ...}"}},
+		{"nested value", "fn GetData(data: {string: {string: ", "map value type", []string{"string", "int", "bool", "any", "[...]", "{...: ...}"}},
+		{"after map", "fn GetData(data: {string: int}, other: ", "type", []string{"string", "int", "bool", "any", "[...]", "{...: ...}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, _ := newTestServer(t)
+			ctx := context.Background()
+
+			_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+			_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+			_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+				TextDocument: protocol.TextDocumentItem{
+					URI:     "file:///test.scaf",
+					Version: 1,
+					Text:    tt.text,
+				},
+			})
+
+			result, err := server.Completion(ctx, &protocol.CompletionParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+					Position:     protocol.Position{Line: 0, Character: uint32(len(tt.text))},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Completion() error: %v", err)
+			}
+
+			if result == nil {
+				t.Fatal("Expected completion result")
+			}
+
+			var labels []string
+			for _, item := range result.Items {
+				labels = append(labels, item.Label)
+
+				if item.Kind != protocol.CompletionItemKindTypeParameter {
+					t.Errorf("Item %q has kind %v, want type parameter", item.Label, item.Kind)
+				}
+
+				if !strings.HasPrefix(item.Label, "[") && !strings.HasPrefix(item.Label, "{") && item.Detail != tt.wantDetail {
+					t.Errorf("Item %q has detail %q, want %q", item.Label, item.Detail, tt.wantDetail)
+				}
+			}
+
+			for _, want := range tt.wantLabels {
+				if !slices.Contains(labels, want) {
+					t.Errorf("Expected %q in completions, got %v", want, labels)
+				}
+			}
+
+			if tt.name == "key" && len(labels) != 1 {
+				t.Errorf("Expected only string for a map key, got %v", labels)
+			}
+		})
+	}
+}
+
 func TestServer_Completion_ExprVariables_AssertContext(t *testing.T) {
 	t.Parallel()
 
@@ -2043,3 +2176,62 @@ GetUser {
 		t.Errorf("Should NOT have 'age' from GetUser in inline assert query scope")
 	}
 }
+
+func TestServer_Completion_Table(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text: `fn GetUser(id: int, active: bool) ` + "`MATCH (u:User {id: $id, active: $active}) RETURN u.name, u.email`" + `
+
+GetUser {
+	table "lookups" {
+		| $id | $active | u.name  |
+		| 1   | true    | "Alice" |
+	}
+}
+`,
+		},
+	})
+
+	complete := func(line, char uint32) map[string]bool {
+		t.Helper()
+
+		result, err := server.Completion(ctx, &protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+				Position:     protocol.Position{Line: line, Character: char},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Completion() error: %v", err)
+		}
+
+		labels := make(map[string]bool)
+		for _, item := range result.Items {
+			labels[item.Label] = true
+		}
+
+		return labels
+	}
+
+	// Header after "| $id | $active | u": return fields not already columns
+	header := complete(4, 21)
+	if !header["u.email"] || header["u.name"] {
+		t.Errorf("header completions = %v, want u.email without u.name", header)
+	}
+
+	// Second data cell is the bool $active column
+	values := complete(5, 10)
+	if !values["true"] || !values["false"] || !values["null"] || values["0"] {
+		t.Errorf("value completions = %v, want true, false, and null", values)
+	}
+}
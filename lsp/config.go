@@ -0,0 +1,241 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+)
+
+// methodDiagnosticRefresh is the LSP 3.17 workspace/diagnostic/refresh request,
+// which go.lsp.dev/protocol v0.12.0 doesn't define.
+const methodDiagnosticRefresh = "workspace/diagnostic/refresh"
+
+// configWatcherID identifies the file watcher registered for config files.
+const configWatcherID = "scaf-config-watcher"
+
+// configGlob matches every name in scaf.DefaultConfigNames.
+const configGlob = "**/{.scaf,scaf}.{yaml,yml}"
+
+// schemaCacheGlob matches the cache of a schema fetched from a schema URL.
+const schemaCacheGlob = "**/" + analysis.SchemaCacheFile
+
+// workspaceConfig is a config read by readConfig, with the schema it names.
+type workspaceConfig struct {
+	cfg *scaf.Config
+
+	// schema is nil when the config names no schema or it failed to load;
+	// source is the path or URL it came from.
+	schema *analysis.TypeSchema
+	source string
+}
+
+// readConfig reads .scaf.yaml from the workspace root and loads the schema it
+// names, fetching a schema URL. It does I/O but changes no server state, so it
+// runs without s.mu held. It returns nil when there's no workspace root.
+func (s *Server) readConfig() *workspaceConfig {
+	if s.workspaceRoot == "" {
+		s.logger.Debug("No workspace root, skipping config load")
+		return nil
+	}
+
+	cfg, err := scaf.LoadConfig(s.workspaceRoot)
+	if err != nil {
+		s.logger.Debug("No usable .scaf.yaml config found",
+			zap.String("root", s.workspaceRoot),
+			zap.Error(err))
+
+		return &workspaceConfig{}
+	}
+
+	schema, source := s.loadSchema(cfg)
+
+	return &workspaceConfig{cfg: cfg, schema: schema, source: source}
+}
+
+// applyConfig applies a config from readConfig: its schema and analysis rule
+// overrides. Without a config, rule overrides are cleared. The caller holds
+// s.mu, except during Initialize.
+func (s *Server) applyConfig(c *workspaceConfig) {
+	if c == nil {
+		return
+	}
+
+	if c.cfg == nil {
+		s.analyzer.SetAnalysisConfig(nil)
+		s.config = nil
+
+		return
+	}
+
+	s.config = c.cfg
+	s.schemaURL = c.cfg.SchemaURL
+	s.analyzer.SetAnalysisConfig(&c.cfg.Analysis)
+
+	if c.schema != nil {
+		s.applySchema(c.schema, c.source)
+	}
+}
+
+// loadSchemaCache reloads the schema from a schema cache file, if the
+// workspace schema comes from a schema URL.
+func (s *Server) loadSchemaCache(path string) {
+	if s.schemaURL == "" {
+		s.logger.Debug("Schema cache changed, but no schema URL is configured")
+		return
+	}
+
+	schema, err := analysis.LoadSchemaCache(path)
+	if err != nil {
+		s.logger.Warn("Failed to load schema cache",
+			zap.String("path", path),
+			zap.Error(err))
+		return
+	}
+
+	s.applySchema(schema, path)
+}
+
+// registerConfigWatcher asks the client to report changes to config files and
+// the schema cache, if it supports registering file watchers.
+func (s *Server) registerConfigWatcher(ctx context.Context) {
+	if !s.watchConfig {
+		return
+	}
+
+	params := &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{{
+			ID:     configWatcherID,
+			Method: protocol.MethodWorkspaceDidChangeWatchedFiles,
+			RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+				Watchers: []protocol.FileSystemWatcher{
+					{GlobPattern: configGlob},
+					{GlobPattern: schemaCacheGlob},
+				},
+			},
+		}},
+	}
+
+	// Registration is a request; like refreshes, it must not block the handler.
+	go func() {
+		if err := s.client.RegisterCapability(context.WithoutCancel(ctx), params); err != nil {
+			s.logger.Debug("Failed to register config watcher", zap.Error(err))
+		}
+	}()
+}
+
+// DidChangeWatchedFiles handles workspace/didChangeWatchedFiles.
+// A change to a config file reloads it and re-analyzes every open document.
+// A change to the schema cache reloads the schema from it; the URL isn't
+// re-fetched, since fetching rewrites the cache.
+func (s *Server) DidChangeWatchedFiles(ctx context.Context, params *protocol.DidChangeWatchedFilesParams) error {
+	configChanged := slices.ContainsFunc(params.Changes, func(change *protocol.FileEvent) bool {
+		return change != nil && slices.Contains(scaf.DefaultConfigNames, filepath.Base(URIToPath(change.URI)))
+	})
+
+	cacheIndex := slices.IndexFunc(params.Changes, func(change *protocol.FileEvent) bool {
+		return change != nil && change.Type != protocol.FileChangeTypeDeleted &&
+			filepath.Base(URIToPath(change.URI)) == analysis.SchemaCacheFile
+	})
+
+	if !configChanged && cacheIndex < 0 {
+		return nil
+	}
+
+	// The schema URL, if any, is fetched before taking the lock
+	var cfg *workspaceConfig
+	if configChanged {
+		cfg = s.readConfig()
+	}
+
+	s.mu.Lock()
+	switch {
+	case configChanged:
+		s.logger.Info("Config changed, re-analyzing open documents")
+		s.applyConfig(cfg)
+	default:
+		s.logger.Info("Schema cache changed, re-analyzing open documents")
+		s.loadSchemaCache(URIToPath(params.Changes[cacheIndex].URI))
+	}
+	s.fileLoader.InvalidateAll()
+	s.analysisEpoch++
+
+	docs := make([]*Document, 0, len(s.documents))
+	for _, doc := range s.documents {
+		s.analyzeDocument(doc)
+		docs = append(docs, doc)
+	}
+	s.mu.Unlock()
+
+	for _, doc := range docs {
+		s.publishDiagnostics(ctx, doc)
+	}
+
+	s.refreshDiagnostics(ctx)
+	s.refreshCodeLenses(ctx)
+
+	return nil
+}
+
+// DidChangeConfiguration handles workspace/didChangeConfiguration, applying
+// the scaf.formatOnSave setting when the client sends it.
+func (s *Server) DidChangeConfiguration(_ context.Context, params *protocol.DidChangeConfigurationParams) error {
+	formatOnSave, ok := formatOnSaveSetting(params.Settings)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.formatOnSave = formatOnSave
+	s.mu.Unlock()
+
+	return nil
+}
+
+// formatOnSaveSetting returns the scaf.formatOnSave setting from decoded
+// client settings, nested as {"scaf": {"formatOnSave": false}} or flat as
+// {"scaf.formatOnSave": false}, and whether it was set.
+func formatOnSaveSetting(settings any) (bool, bool) {
+	m, ok := settings.(map[string]any)
+	if !ok {
+		return false, false
+	}
+
+	if v, ok := m["scaf.formatOnSave"].(bool); ok {
+		return v, true
+	}
+
+	if scafSettings, ok := m["scaf"].(map[string]any); ok {
+		if v, ok := scafSettings["formatOnSave"].(bool); ok {
+			return v, true
+		}
+	}
+
+	return false, false
+}
+
+// diagnosticRefresher is implemented by clients that can send
+// workspace/diagnostic/refresh requests (see NewClient).
+type diagnosticRefresher interface {
+	DiagnosticRefresh(ctx context.Context) error
+}
+
+// refreshDiagnostics asks the client to re-pull diagnostics. Diagnostics are
+// also pushed, so clients that don't support the request still update.
+func (s *Server) refreshDiagnostics(ctx context.Context) {
+	refresher, ok := s.client.(diagnosticRefresher)
+	if !ok {
+		return
+	}
+
+	go func() {
+		if err := refresher.DiagnosticRefresh(context.WithoutCancel(ctx)); err != nil {
+			s.logger.Debug("Failed to refresh diagnostics", zap.Error(err))
+		}
+	}()
+}
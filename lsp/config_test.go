@@ -0,0 +1,323 @@
+package lsp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+
+	"github.com/rlch/scaf/analysis"
+	"github.com/rlch/scaf/lsp"
+)
+
+// waitForCount waits until counter is non-zero, since refreshes and
+// registrations are sent asynchronously.
+func waitForCount(counter *atomic.Int32) int32 {
+	deadline := time.Now().Add(time.Second)
+	for counter.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return counter.Load()
+}
+
+// diagnosticCodes returns the severity of each diagnostic by code.
+func diagnosticCodes(params protocol.PublishDiagnosticsParams) map[string]protocol.DiagnosticSeverity {
+	codes := make(map[string]protocol.DiagnosticSeverity)
+	for _, d := range params.Diagnostics {
+		if code, ok := d.Code.(string); ok {
+			codes[code] = d.Severity
+		}
+	}
+
+	return codes
+}
+
+func TestServer_AnalysisConfig(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".scaf.yaml")
+
+	writeConfig := func(body string) {
+		t.Helper()
+
+		if err := os.WriteFile(configPath, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig("analysis:\n  rules:\n    unused-import:\n      enabled: false\n")
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, err := server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+		Capabilities: protocol.ClientCapabilities{
+			Workspace: &protocol.WorkspaceClientCapabilities{
+				DidChangeWatchedFiles: &protocol.DidChangeWatchedFilesWorkspaceClientCapabilities{
+					DynamicRegistration: true,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	if err := server.Initialized(ctx, &protocol.InitializedParams{}); err != nil {
+		t.Fatalf("Initialized() error: %v", err)
+	}
+
+	if waitForCount(&client.registrations) == 0 {
+		t.Error("Expected the config file watcher to be registered")
+	}
+
+	uri := lsp.PathToURI(filepath.Join(tmpDir, "test.scaf"))
+
+	err = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     uri,
+			Version: 1,
+			Text:    "import fixtures \"./fixtures\"\n\nfn Q() `MATCH (n) RETURN n`\n",
+		},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen() error: %v", err)
+	}
+
+	published := client.waitForDiagnostics(t, 1)
+	if _, ok := diagnosticCodes(published[0])["unused-import"]; ok {
+		t.Error("unused-import is disabled in .scaf.yaml but was reported")
+	}
+
+	// Re-enable the rule as an error; the change is picked up without reopening.
+	writeConfig("analysis:\n  rules:\n    unused-import:\n      severity: error\n")
+
+	err = server.DidChangeWatchedFiles(ctx, &protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{{URI: lsp.PathToURI(configPath), Type: protocol.FileChangeTypeChanged}},
+	})
+	if err != nil {
+		t.Fatalf("DidChangeWatchedFiles() error: %v", err)
+	}
+
+	published = client.waitForDiagnostics(t, 2)
+	if len(published) < 2 {
+		t.Fatal("Expected diagnostics to be republished after the config changed")
+	}
+
+	severity, ok := diagnosticCodes(published[1])["unused-import"]
+	if !ok || severity != protocol.DiagnosticSeverityError {
+		t.Errorf("unused-import severity = %v (reported %v), want error", severity, ok)
+	}
+
+	if waitForCount(&client.diagnosticRefreshes) == 0 {
+		t.Error("Expected workspace/diagnostic/refresh after the config changed")
+	}
+}
+
+func TestServer_DidChangeWatchedFiles_NonConfig(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	err := server.DidChangeWatchedFiles(ctx, &protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{{URI: "file:///tmp/other.yaml", Type: protocol.FileChangeTypeChanged}},
+	})
+	if err != nil {
+		t.Fatalf("DidChangeWatchedFiles() error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if client.diagnosticRefreshes.Load() != 0 {
+		t.Error("Unexpected workspace/diagnostic/refresh for a non-config file")
+	}
+}
+
+func TestServer_InitialSchema(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		".scaf.yaml":        "generate:\n  schema: .scaf-schema.yaml\n",
+		".scaf-schema.yaml": "models:\n  Person:\n    fields:\n      age:\n        type: string\n",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	initial := &analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"Person": {
+				Name: "Person",
+				Fields: []*analysis.Field{
+					{Name: "name", Type: analysis.TypeString},
+					{Name: "age", Type: analysis.TypeInt},
+				},
+				NestedFields: []*analysis.Field{{Name: "address.city", Type: analysis.TypeString}},
+			},
+			"Movie": {
+				Name:   "Movie",
+				Fields: []*analysis.Field{{Name: "year", Type: analysis.TypeInt}},
+			},
+		},
+	}
+
+	server := lsp.NewServerWithSchema(&mockClient{}, zap.NewNop(), "cypher", initial)
+	ctx := context.Background()
+
+	_, err := server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	if err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	uri := lsp.PathToURI(filepath.Join(tmpDir, "test.scaf"))
+	doc := "fn Q(name, age, year) `\nMATCH (p:Person), (m:Movie)\nWHERE p.name = $name AND p.age = $age AND m.year = $year\nRETURN p\n`"
+
+	err = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: doc},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen() error: %v", err)
+	}
+
+	hints, err := server.InlayHint(ctx, &lsp.InlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        protocol.Range{End: protocol.Position{Line: 5}},
+	})
+	if err != nil {
+		t.Fatalf("InlayHint() error: %v", err)
+	}
+
+	got := make([]string, 0, len(hints))
+	for _, hint := range hints {
+		got = append(got, hint.Label)
+	}
+
+	// age comes from the workspace schema, name and year from the initial one.
+	want := []string{": string", ": string", ": int"}
+	if !slices.Equal(got, want) {
+		t.Errorf("inlay hints = %q, want %q", got, want)
+	}
+
+	// Nested fields of a model the workspace also defines are kept.
+	nestedURI := lsp.PathToURI(filepath.Join(tmpDir, "nested.scaf"))
+	nested := "fn R() `\nMATCH (p:Person) RETURN p.address.\n`"
+
+	err = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: nestedURI, Version: 1, Text: nested},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen() error: %v", err)
+	}
+
+	result, err := server.Completion(ctx, &protocol.CompletionParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: nestedURI},
+			Position:     protocol.Position{Line: 1, Character: uint32(len("MATCH (p:Person) RETURN p.address."))},
+		},
+		Context: &protocol.CompletionContext{
+			TriggerKind:      protocol.CompletionTriggerKindTriggerCharacter,
+			TriggerCharacter: ".",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Completion() error: %v", err)
+	}
+
+	if result == nil || !slices.ContainsFunc(result.Items, func(item protocol.CompletionItem) bool { return item.Label == "city" }) {
+		t.Errorf("completions after p.address. = %+v, want city", result)
+	}
+}
+
+func TestServer_SchemaURL_CacheChanged(t *testing.T) {
+	t.Parallel()
+
+	schemaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"models": {"Person": {"fields": {"age": {"type": "int"}}}}}`))
+	}))
+	defer schemaServer.Close()
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, ".scaf.yaml"), []byte("schema_url: "+schemaServer.URL+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, err := server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	if err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	uri := lsp.PathToURI(filepath.Join(tmpDir, "test.scaf"))
+	doc := "fn Q(age) `\nMATCH (p:Person)\nWHERE p.age = $age\nRETURN p\n`"
+
+	err = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: doc},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen() error: %v", err)
+	}
+
+	hintLabels := func() []string {
+		t.Helper()
+
+		hints, err := server.InlayHint(ctx, &lsp.InlayHintParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Range:        protocol.Range{End: protocol.Position{Line: 5}},
+		})
+		if err != nil {
+			t.Fatalf("InlayHint() error: %v", err)
+		}
+
+		labels := make([]string, 0, len(hints))
+		for _, hint := range hints {
+			labels = append(labels, hint.Label)
+		}
+
+		return labels
+	}
+
+	if got, want := hintLabels(), []string{": int"}; !slices.Equal(got, want) {
+		t.Fatalf("inlay hints from the fetched schema = %q, want %q", got, want)
+	}
+
+	// Another process refreshes the cache; the server reloads it without fetching.
+	cachePath := filepath.Join(tmpDir, analysis.SchemaCacheFile)
+	cache := "url: " + schemaServer.URL + "\nmodels:\n  Person:\n    fields:\n      age:\n        type: float64\n"
+
+	if err := os.WriteFile(cachePath, []byte(cache), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = server.DidChangeWatchedFiles(ctx, &protocol.DidChangeWatchedFilesParams{
+		Changes: []*protocol.FileEvent{{URI: lsp.PathToURI(cachePath), Type: protocol.FileChangeTypeChanged}},
+	})
+	if err != nil {
+		t.Fatalf("DidChangeWatchedFiles() error: %v", err)
+	}
+
+	if got, want := hintLabels(), []string{": float64"}; !slices.Equal(got, want) {
+		t.Errorf("inlay hints after the cache changed = %q, want %q", got, want)
+	}
+}
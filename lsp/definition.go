@@ -420,16 +420,31 @@ func (s *Server) findReturnFieldDefinition(doc *Document, tokenCtx *analysis.Tok
 
 // findCrossFileDefinition looks up a query definition in an imported module.
 func (s *Server) findCrossFileDefinition(doc *Document, moduleAlias, queryName string) *protocol.Location {
+	q, uri := s.findImportedQuery(doc, moduleAlias, queryName)
+	if q == nil {
+		return nil
+	}
+
+	// Return location in the imported file
+	return &protocol.Location{
+		URI:   uri,
+		Range: queryNameRange(q.Node),
+	}
+}
+
+// findImportedQuery loads the module imported as moduleAlias and returns the
+// query it exports as queryName, with the URI of the module's file.
+func (s *Server) findImportedQuery(doc *Document, moduleAlias, queryName string) (*analysis.QuerySymbol, protocol.DocumentURI) {
 	if s.fileLoader == nil {
 		s.logger.Debug("FileLoader not available for cross-file definition")
-		return nil
+		return nil, ""
 	}
 
 	// Get the import for this alias
 	imp, ok := doc.Analysis.Symbols.Imports[moduleAlias]
 	if !ok {
 		s.logger.Debug("Import not found for module alias", zap.String("alias", moduleAlias))
-		return nil
+		return nil, ""
 	}
 
 	// Resolve the import path to an absolute file path
@@ -448,25 +463,22 @@ func (s *Server) findCrossFileDefinition(doc *Document, moduleAlias, queryName s
 		s.logger.Debug("Failed to load imported file",
 			zap.String("path", importedPath),
 			zap.Error(err))
-		return nil
+		return nil, ""
 	}
 
-	if importedFile.Symbols == nil {
-		return nil
+	exports := importedFile.ExportSymbols()
+	if exports == nil {
+		return nil, ""
 	}
 
-	// Find the query in the imported file
-	q, ok := importedFile.Symbols.Queries[queryName]
+	// Find the query among those the imported file exports
+	q, ok := exports.Queries[queryName]
 	if !ok {
 		s.logger.Debug("Query not found in imported file",
 			zap.String("queryName", queryName),
 			zap.String("path", importedPath))
-		return nil
+		return nil, ""
 	}
 
-	// Return location in the imported file
-	return &protocol.Location{
-		URI:   PathToURI(importedPath),
-		Range: queryNameRange(q.Node),
-	}
+	return q, PathToURI(importedPath)
 }
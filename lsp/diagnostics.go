@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"context"
+	"strings"
 
 	"github.com/alecthomas/participle/v2/lexer"
 	"go.lsp.dev/protocol"
@@ -22,14 +23,42 @@ func (s *Server) publishDiagnostics(ctx context.Context, doc *Document) {
 		return
 	}
 
-	s.logger.Debug("publishDiagnostics: converting diagnostics",
+	diagnostics := s.documentDiagnostics(doc)
+
+	s.logger.Debug("publishDiagnostics: calling client.PublishDiagnostics RPC",
+		zap.Int("diagnosticCount", len(diagnostics)))
+
+	err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+		URI:         doc.URI,
+		Version:     uint32(doc.Version), //nolint:gosec // LSP version numbers are always non-negative
+		Diagnostics: diagnostics,
+	})
+
+	if err != nil {
+		s.logger.Error("publishDiagnostics: RPC failed", zap.Error(err))
+	} else {
+		s.logger.Debug("publishDiagnostics: RPC completed successfully")
+	}
+
+	s.logger.Debug("publishDiagnostics: END")
+}
+
+// documentDiagnostics returns doc's analysis and dialect diagnostics in LSP
+// format, for both published and pulled diagnostics. doc.Analysis must be set.
+func (s *Server) documentDiagnostics(doc *Document) []protocol.Diagnostic {
+	s.logger.Debug("documentDiagnostics: converting diagnostics",
 		zap.Int("count", len(doc.Analysis.Diagnostics)))
 
 	diagnostics := make([]protocol.Diagnostic, 0, len(doc.Analysis.Diagnostics))
 
 	for i, d := range doc.Analysis.Diagnostics {
-		lspDiag := convertDiagnostic(d)
-		s.logger.Debug("publishDiagnostics: converted diagnostic",
+		// Commented-out code isn't analyzed, so nothing can be wrong with it
+		if doc.Analysis.InComment(d.Span) {
+			continue
+		}
+
+		lspDiag := convertDiagnostic(d, doc.URI)
+		s.logger.Debug("documentDiagnostics: converted diagnostic",
 			zap.Int("index", i),
 			zap.Int("span.start.line", d.Span.Start.Line),
 			zap.Int("span.start.col", d.Span.Start.Column),
@@ -42,35 +71,63 @@ func (s *Server) publishDiagnostics(ctx context.Context, doc *Document) {
 
 	// Add dialect diagnostics for query bodies
 	dialectDiags := s.collectDialectDiagnostics(doc)
-	diagnostics = append(diagnostics, dialectDiags...)
 
-	s.logger.Debug("publishDiagnostics: calling client.PublishDiagnostics RPC",
-		zap.Int("diagnosticCount", len(diagnostics)))
-
-	err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
-		URI:         doc.URI,
-		Version:     uint32(doc.Version), //nolint:gosec // LSP version numbers are always non-negative
-		Diagnostics: diagnostics,
-	})
+	return append(diagnostics, dialectDiags...)
+}
 
-	if err != nil {
-		s.logger.Error("publishDiagnostics: RPC failed", zap.Error(err))
-	} else {
-		s.logger.Debug("publishDiagnostics: RPC completed successfully")
+// convertDiagnostic converts an analysis.Diagnostic to an LSP protocol.Diagnostic.
+// uri is the document the diagnostic belongs to, used for related locations in
+// the same file. Suppressed diagnostics are downgraded to faded hints so they
+// stay discoverable.
+func convertDiagnostic(d analysis.Diagnostic, uri protocol.DocumentURI) protocol.Diagnostic {
+	related := convertRelatedInformation(d.Related, uri)
+
+	if d.Suppressed {
+		return protocol.Diagnostic{
+			Range:              spanToRange(d.Span),
+			Severity:           protocol.DiagnosticSeverityHint,
+			Code:               d.Code,
+			Source:             d.Source,
+			Message:            d.Message + " (suppressed)",
+			Tags:               []protocol.DiagnosticTag{protocol.DiagnosticTagUnnecessary},
+			RelatedInformation: related,
+		}
 	}
 
-	s.logger.Debug("publishDiagnostics: END")
+	return protocol.Diagnostic{
+		Range:              spanToRange(d.Span),
+		Severity:           convertSeverity(d.Severity),
+		Code:               d.Code,
+		Source:             d.Source,
+		Message:            d.Message,
+		RelatedInformation: related,
+	}
 }
 
-// convertDiagnostic converts an analysis.Diagnostic to an LSP protocol.Diagnostic.
-func convertDiagnostic(d analysis.Diagnostic) protocol.Diagnostic {
-	return protocol.Diagnostic{
-		Range:    spanToRange(d.Span),
-		Severity: convertSeverity(d.Severity),
-		Code:     d.Code,
-		Source:   d.Source,
-		Message:  d.Message,
+// convertRelatedInformation converts related locations to LSP format.
+// Locations without a URI are in uri; others are file paths or URIs.
+func convertRelatedInformation(related []analysis.DiagnosticRelatedInformation, uri protocol.DocumentURI) []protocol.DiagnosticRelatedInformation {
+	if len(related) == 0 {
+		return nil
 	}
+
+	result := make([]protocol.DiagnosticRelatedInformation, 0, len(related))
+	for _, r := range related {
+		location := uri
+		switch {
+		case strings.Contains(r.URI, "://"):
+			location = protocol.DocumentURI(r.URI)
+		case r.URI != "":
+			location = PathToURI(r.URI)
+		}
+
+		result = append(result, protocol.DiagnosticRelatedInformation{
+			Location: protocol.Location{URI: location, Range: spanToRange(r.Span)},
+			Message:  r.Message,
+		})
+	}
+
+	return result
 }
 
 // convertSeverity converts analysis severity to LSP severity.
@@ -95,6 +152,7 @@ type queryBodyForDiagnostics struct {
 	bodyToken    *lexer.Token
 	functionName string
 	params       map[string]*scaf.TypeExpr
+	assert       bool
 }
 
 // collectDialectDiagnostics runs the dialect's diagnostic checks on all query bodies in the document.
@@ -133,6 +191,7 @@ func (s *Server) collectDialectDiagnostics(doc *Document) []protocol.Diagnostic
 			Query:          qb.body,
 			FunctionName:   qb.functionName,
 			DeclaredParams: qb.params,
+			AssertQuery:    qb.assert,
 		}
 
 		// Get position from token (after opening backtick)
@@ -151,12 +210,12 @@ func (s *Server) collectDialectDiagnostics(doc *Document) []protocol.Diagnostic
 
 		// Convert to LSP diagnostics with adjusted positions
 		for i, d := range dialectDiags {
-			lspDiag := s.convertQueryDiagnosticWithContext(d, qbc, qb.body)
+			lspDiag := s.convertQueryDiagnosticWithContext(d, qbc)
 			s.logger.Debug("collectDialectDiagnostics: converted diagnostic",
 				zap.Int("index", i),
 				zap.String("code", d.Code),
-				zap.Int("queryRangeStart", d.Range.Start),
-				zap.Int("queryRangeEnd", d.Range.End),
+				zap.Int("queryRangeStart", d.Range.StartPos.Offset),
+				zap.Int("queryRangeEnd", d.Range.EndPos.Offset),
 				zap.Uint32("lspStartLine", lspDiag.Range.Start.Line),
 				zap.Uint32("lspStartChar", lspDiag.Range.Start.Character),
 				zap.Uint32("lspEndLine", lspDiag.Range.End.Line),
@@ -172,6 +231,9 @@ func (s *Server) collectDialectDiagnostics(doc *Document) []protocol.Diagnostic
 func (s *Server) collectAllQueryBodies(suite *scaf.File) []queryBodyForDiagnostics {
 	var bodies []queryBodyForDiagnostics
 
+	// Functions that named assert queries run are checked as assert queries
+	asserted := namedAssertQueries(suite)
+
 	// Collect from function definitions
 	for _, fn := range suite.Functions {
 		if fn == nil || fn.Body == "" {
@@ -194,6 +256,7 @@ func (s *Server) collectAllQueryBodies(suite *scaf.File) []queryBodyForDiagnosti
 			bodyToken:    tok,
 			functionName: fn.Name,
 			params:       params,
+			assert:       asserted[fn.Name],
 		})
 	}
 
@@ -204,12 +267,7 @@ func (s *Server) collectAllQueryBodies(suite *scaf.File) []queryBodyForDiagnosti
 
 	// Collect from global teardown
 	if suite.Teardown != nil {
-		if tok := s.findRawStringTokenAfterKeyword(suite.Tokens, scaf.TokenTeardown); tok != nil {
-			bodies = append(bodies, queryBodyForDiagnostics{
-				body:      *suite.Teardown,
-				bodyToken: tok,
-			})
-		}
+		bodies = append(bodies, s.collectQueryBodiesFromSetup((*scaf.SetupClause)(suite.Teardown))...)
 	}
 
 	// Collect from scopes
@@ -262,12 +320,7 @@ func (s *Server) collectQueryBodiesFromScope(scope *scaf.FunctionScope) []queryB
 
 	// Scope teardown
 	if scope.Teardown != nil {
-		if tok := s.findRawStringTokenAfterKeyword(scope.Tokens, scaf.TokenTeardown); tok != nil {
-			bodies = append(bodies, queryBodyForDiagnostics{
-				body:      *scope.Teardown,
-				bodyToken: tok,
-			})
-		}
+		bodies = append(bodies, s.collectQueryBodiesFromSetup((*scaf.SetupClause)(scope.Teardown))...)
 	}
 
 	// Items (tests and groups)
@@ -301,6 +354,11 @@ func (s *Server) collectQueryBodiesFromTest(test *scaf.Test) []queryBodyForDiagn
 		bodies = append(bodies, s.collectQueryBodiesFromSetup(test.Setup)...)
 	}
 
+	// Test teardown
+	if test.Teardown != nil {
+		bodies = append(bodies, s.collectQueryBodiesFromSetup((*scaf.SetupClause)(test.Teardown))...)
+	}
+
 	// Assert queries
 	for _, assert := range test.Asserts {
 		if assert == nil || assert.Query == nil || assert.Query.Inline == nil {
@@ -310,6 +368,7 @@ func (s *Server) collectQueryBodiesFromTest(test *scaf.Test) []queryBodyForDiagn
 			bodies = append(bodies, queryBodyForDiagnostics{
 				body:      *assert.Query.Inline,
 				bodyToken: tok,
+				assert:    true,
 			})
 		}
 	}
@@ -328,12 +387,7 @@ func (s *Server) collectQueryBodiesFromGroup(group *scaf.Group) []queryBodyForDi
 
 	// Group teardown
 	if group.Teardown != nil {
-		if tok := s.findRawStringTokenAfterKeyword(group.Tokens, scaf.TokenTeardown); tok != nil {
-			bodies = append(bodies, queryBodyForDiagnostics{
-				body:      *group.Teardown,
-				bodyToken: tok,
-			})
-		}
+		bodies = append(bodies, s.collectQueryBodiesFromSetup((*scaf.SetupClause)(group.Teardown))...)
 	}
 
 	// Nested items
@@ -349,18 +403,11 @@ func (s *Server) collectQueryBodiesFromGroup(group *scaf.Group) []queryBodyForDi
 
 // convertQueryDiagnosticWithContext converts a dialect query diagnostic to LSP format
 // using the precise query body context.
-func (s *Server) convertQueryDiagnosticWithContext(d scaf.QueryDiagnostic, qbc *QueryBodyContext, queryBody string) protocol.Diagnostic {
-	// The diagnostic range (d.Range) is in byte offsets within the query body.
-	// We need to map these offsets to document positions.
-
-	// Calculate start position: QueryBodyStart + offset from query start
-	startPos := s.queryOffsetToDocPosition(qbc, d.Range.Start, queryBody)
-	endPos := s.queryOffsetToDocPosition(qbc, d.Range.End, queryBody)
-
+func (s *Server) convertQueryDiagnosticWithContext(d scaf.QueryDiagnostic, qbc *QueryBodyContext) protocol.Diagnostic {
 	return protocol.Diagnostic{
 		Range: protocol.Range{
-			Start: startPos,
-			End:   endPos,
+			Start: queryPositionToDocPosition(qbc, d.Range.StartPos),
+			End:   queryPositionToDocPosition(qbc, d.Range.EndPos),
 		},
 		Severity: s.convertQueryDiagnosticSeverity(d.Severity),
 		Code:     d.Code,
@@ -369,31 +416,19 @@ func (s *Server) convertQueryDiagnosticWithContext(d scaf.QueryDiagnostic, qbc *
 	}
 }
 
-// queryOffsetToDocPosition converts a byte offset within a query body to a document position.
-// It handles multi-line queries by counting newlines from the query body start.
-func (s *Server) queryOffsetToDocPosition(qbc *QueryBodyContext, offset int, queryBody string) protocol.Position {
-	if offset < 0 {
-		offset = 0
-	}
-	if offset > len(queryBody) {
-		offset = len(queryBody)
-	}
+// queryPositionToDocPosition converts a position within a query body to a
+// document position. Columns on the body's first line are relative to the
+// body start; later lines start at the beginning of the document line.
+func queryPositionToDocPosition(qbc *QueryBodyContext, pos lexer.Position) protocol.Position {
+	line := max(pos.Line, 1) - 1
+	char := max(pos.Column, 1) - 1
 
-	// Count newlines and characters to get to the offset
-	line := qbc.QueryBodyStart.Line
-	char := qbc.QueryBodyStart.Character
-
-	for i := 0; i < offset && i < len(queryBody); i++ {
-		if queryBody[i] == '\n' {
-			line++
-			char = 0
-		} else {
-			char++
-		}
+	if line == 0 {
+		char += int(qbc.QueryBodyStart.Character)
 	}
 
 	return protocol.Position{
-		Line:      line,
-		Character: char,
+		Line:      qbc.QueryBodyStart.Line + uint32(line), //nolint:gosec // Query lines are non-negative
+		Character: uint32(char),                           //nolint:gosec // Query columns are non-negative
 	}
 }
@@ -1,11 +1,13 @@
 package lsp
 
 import (
+	"container/list"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
@@ -13,58 +15,230 @@ import (
 	"github.com/rlch/scaf/analysis"
 )
 
+// File cache defaults.
+const (
+	// DefaultMaxFileCache is the default number of files an LSPFileLoader caches.
+	DefaultMaxFileCache = 512
+
+	// fileCacheRetention is how long after its last access a file is kept,
+	// even when the cache is over its cap.
+	fileCacheRetention = 60 * time.Second
+
+	// fileCacheErrorTTL is how long a failed load is cached before it is retried.
+	fileCacheErrorTTL = 30 * time.Second
+
+	// fileCacheStatsInterval is how often cache statistics are logged.
+	fileCacheStatsInterval = 60 * time.Second
+)
+
 // LSPFileLoader implements analysis.FileLoader for the LSP server.
-// It resolves relative import paths based on document URIs and caches loaded files.
+// It resolves relative import paths based on document URIs and caches loaded
+// files in an LRU cache, so memory stays bounded in large workspaces.
 type LSPFileLoader struct {
 	logger *zap.Logger
 
 	// workspaceRoot is the root directory of the workspace (from LSP initialize).
 	workspaceRoot string
 
-	// mu protects the cache.
-	mu sync.RWMutex
+	// mu protects the cache and its statistics.
+	mu sync.Mutex
+
+	// maxEntries caps the number of cached files; 0 means unlimited.
+	maxEntries int
+
+	// entries maps absolute file paths to their element in lru.
+	entries map[string]*list.Element
+
+	// lru holds *fileCacheEntry values, most recently used first.
+	lru *list.List
 
-	// cache maps absolute file paths to their content.
-	cache map[string][]byte
+	stats         FileCacheStats
+	statsLoggedAt time.Time
 
-	// analyzed maps absolute file paths to their analyzed files.
-	analyzed map[string]*analysis.AnalyzedFile
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+// fileCacheEntry is a cached file: its content or load error, and its
+// analysis once LoadAndAnalyze has run.
+type fileCacheEntry struct {
+	path       string
+	loaded     bool
+	content    []byte
+	err        error
+	analyzed   *analysis.AnalyzedFile
+	loadedAt   time.Time
+	accessedAt time.Time
+}
+
+// FileCacheStats counts how an LSPFileLoader's cache has been used.
+type FileCacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+	Entries   int
 }
 
 // NewLSPFileLoader creates a new file loader for the LSP server.
+// It caches up to DefaultMaxFileCache files.
 func NewLSPFileLoader(logger *zap.Logger, workspaceRoot string) *LSPFileLoader {
 	return &LSPFileLoader{
 		logger:        logger,
 		workspaceRoot: workspaceRoot,
-		cache:         make(map[string][]byte),
-		analyzed:      make(map[string]*analysis.AnalyzedFile),
+		maxEntries:    DefaultMaxFileCache,
+		entries:       make(map[string]*list.Element),
+		lru:           list.New(),
+		statsLoggedAt: time.Now(),
+		now:           time.Now,
 	}
 }
 
+// SetMaxEntries sets the number of files to cache; 0 means unlimited.
+// Entries over the new cap are evicted unless they were used recently.
+func (l *LSPFileLoader) SetMaxEntries(n int) {
+	l.mu.Lock()
+	l.maxEntries = max(n, 0)
+	l.evict()
+	l.mu.Unlock()
+}
+
+// SetClockForTesting replaces the clock used for cache expiry.
+// This should only be used in tests.
+func (l *LSPFileLoader) SetClockForTesting(now func() time.Time) {
+	l.mu.Lock()
+	l.now = now
+	l.statsLoggedAt = now()
+	l.mu.Unlock()
+}
+
+// Stats returns the cache statistics so far.
+func (l *LSPFileLoader) Stats() FileCacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.Entries = l.lru.Len()
+
+	return stats
+}
+
 // Load implements analysis.FileLoader.
 // It loads the content of a file at the given path.
 // The path may be absolute or relative to some base (resolved by caller).
+// A failed load is cached too, and returns the same error until it expires.
 func (l *LSPFileLoader) Load(path string) ([]byte, error) {
+	defer l.logStats()
+
 	// Check cache first
-	l.mu.RLock()
-	if content, ok := l.cache[path]; ok {
-		l.mu.RUnlock()
-		return content, nil
+	l.mu.Lock()
+	if entry := l.lookup(path); entry != nil && entry.loaded {
+		l.stats.Hits++
+		l.mu.Unlock()
+		return entry.content, entry.err
 	}
-	l.mu.RUnlock()
+	l.stats.Misses++
+	l.mu.Unlock()
 
 	// Load from disk
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		content = nil
+	}
+
+	// Cache it, or the error
+	l.mu.Lock()
+	entry := l.store(path)
+	entry.loaded = true
+	entry.content = content
+	entry.err = err
+	entry.loadedAt = entry.accessedAt
+	l.mu.Unlock()
+
+	return content, err
+}
+
+// lookup returns the cache entry for path and marks it as used, or nil if
+// there is none. An expired load error is dropped, and entries that have gone
+// idle since the cache went over its cap are evicted. l.mu must be held.
+func (l *LSPFileLoader) lookup(path string) *fileCacheEntry {
+	elem, ok := l.entries[path]
+	if !ok {
+		return nil
+	}
+
+	entry := elem.Value.(*fileCacheEntry)
+	now := l.now()
+
+	if entry.err != nil && now.Sub(entry.loadedAt) >= fileCacheErrorTTL {
+		l.lru.Remove(elem)
+		delete(l.entries, path)
+		return nil
+	}
+
+	entry.accessedAt = now
+	l.lru.MoveToFront(elem)
+	l.evict()
+
+	return entry
+}
+
+// store returns the cache entry for path, adding it if needed, and marks it
+// as used. l.mu must be held.
+func (l *LSPFileLoader) store(path string) *fileCacheEntry {
+	if entry := l.lookup(path); entry != nil {
+		return entry
+	}
+
+	entry := &fileCacheEntry{path: path, accessedAt: l.now()}
+	l.entries[path] = l.lru.PushFront(entry)
+	l.evict()
+
+	return entry
+}
+
+// evict removes least recently used entries until the cache is within its
+// cap, stopping at the first entry used within fileCacheRetention.
+// l.mu must be held.
+func (l *LSPFileLoader) evict() {
+	if l.maxEntries == 0 {
+		return
+	}
+
+	now := l.now()
+
+	for l.lru.Len() > l.maxEntries {
+		elem := l.lru.Back()
+		entry := elem.Value.(*fileCacheEntry)
+
+		if now.Sub(entry.accessedAt) < fileCacheRetention {
+			return
+		}
+
+		l.lru.Remove(elem)
+		delete(l.entries, entry.path)
+		l.stats.Evictions++
 	}
+}
 
-	// Cache it
+// logStats logs the cache statistics at debug level, at most once every
+// fileCacheStatsInterval. It runs on cache access, so an idle cache logs nothing.
+func (l *LSPFileLoader) logStats() {
 	l.mu.Lock()
-	l.cache[path] = content
+	now := l.now()
+	if now.Sub(l.statsLoggedAt) < fileCacheStatsInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.statsLoggedAt = now
+	stats := l.stats
+	stats.Entries = l.lru.Len()
 	l.mu.Unlock()
 
-	return content, nil
+	l.logger.Debug("File cache stats",
+		zap.Int("hits", stats.Hits),
+		zap.Int("misses", stats.Misses),
+		zap.Int("evictions", stats.Evictions),
+		zap.Int("entries", stats.Entries))
 }
 
 // ResolveImportPath resolves a relative import path to an absolute file path.
@@ -136,16 +310,19 @@ func PathToURI(path string) protocol.DocumentURI {
 // LoadAndAnalyze loads a file and returns its analysis.
 // This is used for cross-file completion to get symbols from imported modules.
 func (l *LSPFileLoader) LoadAndAnalyze(path string) (*analysis.AnalyzedFile, error) {
+	defer l.logStats()
+
 	// Check cache first
-	l.mu.RLock()
-	if analyzed, ok := l.analyzed[path]; ok {
-		l.mu.RUnlock()
+	l.mu.Lock()
+	if entry := l.lookup(path); entry != nil && entry.analyzed != nil {
+		l.stats.Hits++
+		l.mu.Unlock()
 		l.logger.Debug("LoadAndAnalyze: returning cached analysis",
 			zap.String("path", path),
-			zap.Int("queryCount", len(analyzed.Symbols.Queries)))
-		return analyzed, nil
+			zap.Int("queryCount", len(entry.analyzed.Symbols.Queries)))
+		return entry.analyzed, nil
 	}
-	l.mu.RUnlock()
+	l.mu.Unlock()
 
 	// Load the file
 	content, err := l.Load(path)
@@ -160,6 +337,7 @@ func (l *LSPFileLoader) LoadAndAnalyze(path string) (*analysis.AnalyzedFile, err
 	// Create a temporary analyzer (without loader to avoid infinite recursion for now)
 	// TODO: Support recursive imports with cycle detection
 	analyzer := analysis.NewAnalyzer(nil)
+	analyzer.SetParseOptions(parseLimits)
 	result := analyzer.Analyze(path, content)
 
 	l.logger.Debug("LoadAndAnalyze: analyzed file",
@@ -167,9 +345,15 @@ func (l *LSPFileLoader) LoadAndAnalyze(path string) (*analysis.AnalyzedFile, err
 		zap.Int("queryCount", len(result.Symbols.Queries)),
 		zap.Bool("hasParseError", result.ParseError != nil))
 
-	// Cache the analysis
+	// Cache the analysis alongside the content
 	l.mu.Lock()
-	l.analyzed[path] = result
+	entry := l.store(path)
+	entry.analyzed = result
+	if !entry.loaded {
+		entry.loaded = true
+		entry.content = content
+		entry.loadedAt = entry.accessedAt
+	}
 	l.mu.Unlock()
 
 	return result, nil
@@ -179,16 +363,18 @@ func (l *LSPFileLoader) LoadAndAnalyze(path string) (*analysis.AnalyzedFile, err
 // Called when a file is modified.
 func (l *LSPFileLoader) InvalidatePath(path string) {
 	l.mu.Lock()
-	delete(l.cache, path)
-	delete(l.analyzed, path)
+	if elem, ok := l.entries[path]; ok {
+		l.lru.Remove(elem)
+		delete(l.entries, path)
+	}
 	l.mu.Unlock()
 }
 
 // InvalidateAll clears all cached data.
 func (l *LSPFileLoader) InvalidateAll() {
 	l.mu.Lock()
-	l.cache = make(map[string][]byte)
-	l.analyzed = make(map[string]*analysis.AnalyzedFile)
+	l.entries = make(map[string]*list.Element)
+	l.lru.Init()
 	l.mu.Unlock()
 }
 
@@ -1,9 +1,11 @@
 package lsp_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
@@ -302,3 +304,80 @@ func TestLSPFileLoader_InvalidatePath(t *testing.T) {
 		t.Errorf("Load() after invalidate = %q, want %q", string(content), newContent)
 	}
 }
+
+func TestLSPFileLoader_LRUEviction(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	paths := make([]string, 3)
+	for i := range paths {
+		paths[i] = filepath.Join(tmpDir, fmt.Sprintf("f%d.scaf", i))
+		if err := os.WriteFile(paths[i], []byte("fn Q() `RETURN 1`\n"), 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	now := time.Unix(0, 0)
+	loader := lsp.NewLSPFileLoader(zap.NewNop(), tmpDir)
+	loader.SetClockForTesting(func() time.Time { return now })
+	loader.SetMaxEntries(2)
+
+	_, _ = loader.Load(paths[0])
+	_, _ = loader.Load(paths[1])
+
+	// Everything was used within the last minute, so the cap is exceeded
+	_, _ = loader.Load(paths[2])
+	if got := loader.Stats(); got.Entries != 3 || got.Evictions != 0 {
+		t.Fatalf("recent entries: got %+v, want 3 entries and no evictions", got)
+	}
+
+	// Once idle, the least recently used entry is evicted at the cap
+	now = now.Add(2 * time.Minute)
+	_, _ = loader.Load(paths[1])
+	_, _ = loader.Load(paths[2])
+	_, _ = loader.LoadAndAnalyze(paths[2])
+
+	got := loader.Stats()
+	if got.Entries != 2 || got.Evictions != 1 {
+		t.Fatalf("after eviction: got %+v, want 2 entries and 1 eviction", got)
+	}
+
+	_, _ = loader.Load(paths[0])
+	if after := loader.Stats(); after.Misses != got.Misses+1 {
+		t.Errorf("loading the evicted file should miss: before %+v, after %+v", got, after)
+	}
+}
+
+func TestLSPFileLoader_CachesErrors(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "missing.scaf")
+
+	now := time.Unix(0, 0)
+	loader := lsp.NewLSPFileLoader(zap.NewNop(), tmpDir)
+	loader.SetClockForTesting(func() time.Time { return now })
+
+	if _, err := loader.Load(testFile); err == nil {
+		t.Fatal("Load() of a missing file should fail")
+	}
+
+	if err := os.WriteFile(testFile, []byte("fn Q() `RETURN 1`\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// The failure is cached, so the new file isn't seen yet
+	now = now.Add(10 * time.Second)
+	if _, err := loader.Load(testFile); err == nil {
+		t.Error("Load() within the error TTL should return the cached error")
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := loader.Load(testFile); err != nil {
+		t.Errorf("Load() after the error TTL: %v", err)
+	}
+
+	if got := loader.Stats(); got.Hits != 1 || got.Misses != 2 {
+		t.Errorf("got %+v, want 1 hit and 2 misses", got)
+	}
+}
@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"context"
+	"fmt"
 
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
@@ -9,63 +10,133 @@ import (
 	"github.com/rlch/scaf"
 )
 
+// minFoldedImports is the fewest consecutive imports worth folding.
+const minFoldedImports = 3
+
+// FoldingRange is a protocol.FoldingRange with the LSP 3.17 collapsedText
+// property, which go.lsp.dev/protocol v0.12.0 doesn't include.
+type FoldingRange struct {
+	protocol.FoldingRange
+
+	// CollapsedText is shown in place of the folded lines, e.g. the test name.
+	CollapsedText string `json:"collapsedText,omitempty"`
+}
+
 // FoldingRanges handles textDocument/foldingRange requests.
-// Returns folding ranges for queries, scopes, groups, tests, and setup blocks.
-func (s *Server) FoldingRanges(_ context.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+// It satisfies protocol.Server; NewHandler answers the request with
+// FoldingRangesWithText instead so editors also receive collapsed text.
+func (s *Server) FoldingRanges(ctx context.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	withText, err := s.FoldingRangesWithText(ctx, params)
+	if err != nil || withText == nil {
+		return nil, err
+	}
+
+	ranges := make([]protocol.FoldingRange, len(withText))
+	for i, r := range withText {
+		ranges[i] = r.FoldingRange
+	}
+
+	return ranges, nil
+}
+
+// FoldingRangesWithText returns folding ranges for imports, functions, scopes,
+// groups, tests, asserts, and setup blocks, labelled with the name of what
+// they fold. While the document fails to parse, ranges come from the last
+// successful parse.
+func (s *Server) FoldingRangesWithText(_ context.Context, params *protocol.FoldingRangeParams) ([]FoldingRange, error) {
 	s.logger.Debug("FoldingRanges",
 		zap.String("uri", string(params.TextDocument.URI)))
 
 	doc, ok := s.getDocument(params.TextDocument.URI)
-	if !ok || doc.Analysis == nil || doc.Analysis.Suite == nil {
+	if !ok {
 		return nil, nil
 	}
 
+	af := doc.Analysis
+	if (af == nil || af.ParseError != nil) && doc.LastValidAnalysis != nil {
+		af = doc.LastValidAnalysis
+	}
+
+	if af == nil || af.Suite == nil {
+		return nil, nil
+	}
+
+	suite := af.Suite
+
 	// Get document line count to validate ranges
 	lineCount := uint32(len(splitLines(doc.Content)))
 
-	var ranges []protocol.FoldingRange
+	var ranges []FoldingRange
 
-	// Add folding ranges for imports (if multiple)
-	if len(doc.Analysis.Suite.Imports) > 1 {
-		firstImport := doc.Analysis.Suite.Imports[0]
-		lastImport := doc.Analysis.Suite.Imports[len(doc.Analysis.Suite.Imports)-1]
-		if r, ok := s.validFoldingRange(firstImport.Pos.Line-1, lastImport.EndPos.Line-1, lineCount, protocol.ImportsFoldingRange); ok {
-			ranges = append(ranges, r)
-		}
-	}
+	// Add folding ranges for runs of consecutive imports
+	ranges = append(ranges, s.importFoldingRanges(suite.Imports, lineCount)...)
 
 	// Add folding ranges for queries
-	for _, q := range doc.Analysis.Suite.Functions {
-		if r, ok := s.validFoldingRange(q.Pos.Line-1, q.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
+	for _, q := range suite.Functions {
+		if r, ok := s.validFoldingRange(q.Pos.Line-1, q.EndPos.Line-1, lineCount, protocol.RegionFoldingRange, "fn "+q.Name); ok {
 			ranges = append(ranges, r)
 		}
 	}
 
 	// Add folding range for global setup
-	if doc.Analysis.Suite.Setup != nil {
-		if r, ok := s.validFoldingRange(doc.Analysis.Suite.Setup.Pos.Line-1, doc.Analysis.Suite.Setup.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
-			ranges = append(ranges, r)
-		}
-	}
+	ranges = append(ranges, s.setupFoldingRanges(suite.Setup, lineCount)...)
 
 	// Add folding ranges for scopes
-	for _, scope := range doc.Analysis.Suite.Scopes {
+	for _, scope := range suite.Scopes {
 		ranges = append(ranges, s.scopeFoldingRanges(scope, lineCount)...)
 	}
 
 	s.logger.Debug("FoldingRanges result",
 		zap.Int("count", len(ranges)),
-		zap.Uint32("lineCount", lineCount))
+		zap.Uint32("lineCount", lineCount),
+		zap.Bool("fromLastValid", af != doc.Analysis))
 
 	return ranges, nil
 }
 
+// importFoldingRanges folds each run of at least minFoldedImports imports on
+// consecutive lines.
+func (s *Server) importFoldingRanges(imports []*scaf.Import, lineCount uint32) []FoldingRange {
+	var ranges []FoldingRange
+
+	for start := 0; start < len(imports); {
+		end := start
+		for end+1 < len(imports) && imports[end+1].Pos.Line <= imports[end].EndPos.Line+1 {
+			end++
+		}
+
+		if n := end - start + 1; n >= minFoldedImports {
+			text := fmt.Sprintf("%d imports", n)
+			if r, ok := s.validFoldingRange(imports[start].Pos.Line-1, imports[end].EndPos.Line-1, lineCount, protocol.ImportsFoldingRange, text); ok {
+				ranges = append(ranges, r)
+			}
+		}
+
+		start = end + 1
+	}
+
+	return ranges
+}
+
+// setupFoldingRanges folds a multi-line setup clause, such as a setup block.
+func (s *Server) setupFoldingRanges(setup *scaf.SetupClause, lineCount uint32) []FoldingRange {
+	if setup == nil {
+		return nil
+	}
+
+	if r, ok := s.validFoldingRange(setup.Pos.Line-1, setup.EndPos.Line-1, lineCount, protocol.RegionFoldingRange, "setup"); ok {
+		return []FoldingRange{r}
+	}
+
+	return nil
+}
+
 // validFoldingRange creates a folding range only if the line numbers are valid.
 // Returns false if the range is invalid (e.g., from a parse error with bad positions).
-func (s *Server) validFoldingRange(startLine, endLine int, lineCount uint32, kind protocol.FoldingRangeKind) (protocol.FoldingRange, bool) {
+func (s *Server) validFoldingRange(startLine, endLine int, lineCount uint32, kind protocol.FoldingRangeKind, text string) (FoldingRange, bool) {
 	// Check for invalid/negative line numbers
 	if startLine < 0 || endLine < 0 {
-		return protocol.FoldingRange{}, false
+		return FoldingRange{}, false
 	}
 
 	start := uint32(startLine)
@@ -73,18 +144,21 @@ func (s *Server) validFoldingRange(startLine, endLine int, lineCount uint32, kin
 
 	// Check for overflow (very large numbers from uninitialized positions)
 	if start > lineCount || end > lineCount {
-		return protocol.FoldingRange{}, false
+		return FoldingRange{}, false
 	}
 
 	// Folding range must span at least 2 lines
 	if end <= start {
-		return protocol.FoldingRange{}, false
+		return FoldingRange{}, false
 	}
 
-	return protocol.FoldingRange{
-		StartLine: start,
-		EndLine:   end,
-		Kind:      kind,
+	return FoldingRange{
+		FoldingRange: protocol.FoldingRange{
+			StartLine: start,
+			EndLine:   end,
+			Kind:      kind,
+		},
+		CollapsedText: text,
 	}, true
 }
 
@@ -108,20 +182,16 @@ func splitLines(content string) []string {
 }
 
 // scopeFoldingRanges creates folding ranges for a query scope and its contents.
-func (s *Server) scopeFoldingRanges(scope *scaf.QueryScope, lineCount uint32) []protocol.FoldingRange {
-	var ranges []protocol.FoldingRange
+func (s *Server) scopeFoldingRanges(scope *scaf.QueryScope, lineCount uint32) []FoldingRange {
+	var ranges []FoldingRange
 
 	// Add range for the scope itself
-	if r, ok := s.validFoldingRange(scope.Pos.Line-1, scope.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
+	if r, ok := s.validFoldingRange(scope.Pos.Line-1, scope.EndPos.Line-1, lineCount, protocol.RegionFoldingRange, scope.FunctionName); ok {
 		ranges = append(ranges, r)
 	}
 
 	// Add range for scope setup if present
-	if scope.Setup != nil {
-		if r, ok := s.validFoldingRange(scope.Setup.Pos.Line-1, scope.Setup.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
-			ranges = append(ranges, r)
-		}
-	}
+	ranges = append(ranges, s.setupFoldingRanges(scope.Setup, lineCount)...)
 
 	// Add ranges for items (tests and groups)
 	for _, item := range scope.Items {
@@ -132,8 +202,8 @@ func (s *Server) scopeFoldingRanges(scope *scaf.QueryScope, lineCount uint32) []
 }
 
 // itemFoldingRanges creates folding ranges for a test or group.
-func (s *Server) itemFoldingRanges(item *scaf.TestOrGroup, lineCount uint32) []protocol.FoldingRange {
-	var ranges []protocol.FoldingRange
+func (s *Server) itemFoldingRanges(item *scaf.TestOrGroup, lineCount uint32) []FoldingRange {
+	var ranges []FoldingRange
 
 	if item.Test != nil {
 		ranges = append(ranges, s.testFoldingRanges(item.Test, lineCount)...)
@@ -147,24 +217,20 @@ func (s *Server) itemFoldingRanges(item *scaf.TestOrGroup, lineCount uint32) []p
 }
 
 // testFoldingRanges creates folding ranges for a test.
-func (s *Server) testFoldingRanges(test *scaf.Test, lineCount uint32) []protocol.FoldingRange {
-	var ranges []protocol.FoldingRange
+func (s *Server) testFoldingRanges(test *scaf.Test, lineCount uint32) []FoldingRange {
+	var ranges []FoldingRange
 
 	// Add range for the test itself
-	if r, ok := s.validFoldingRange(test.Pos.Line-1, test.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
+	if r, ok := s.validFoldingRange(test.Pos.Line-1, test.EndPos.Line-1, lineCount, protocol.RegionFoldingRange, fmt.Sprintf("test %q", test.Name)); ok {
 		ranges = append(ranges, r)
 	}
 
 	// Add range for test setup if present
-	if test.Setup != nil {
-		if r, ok := s.validFoldingRange(test.Setup.Pos.Line-1, test.Setup.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
-			ranges = append(ranges, r)
-		}
-	}
+	ranges = append(ranges, s.setupFoldingRanges(test.Setup, lineCount)...)
 
 	// Add ranges for asserts
 	for _, assert := range test.Asserts {
-		if r, ok := s.validFoldingRange(assert.Pos.Line-1, assert.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
+		if r, ok := s.validFoldingRange(assert.Pos.Line-1, assert.EndPos.Line-1, lineCount, protocol.RegionFoldingRange, "assert"); ok {
 			ranges = append(ranges, r)
 		}
 	}
@@ -173,20 +239,16 @@ func (s *Server) testFoldingRanges(test *scaf.Test, lineCount uint32) []protocol
 }
 
 // groupFoldingRanges creates folding ranges for a group and its contents.
-func (s *Server) groupFoldingRanges(group *scaf.Group, lineCount uint32) []protocol.FoldingRange {
-	var ranges []protocol.FoldingRange
+func (s *Server) groupFoldingRanges(group *scaf.Group, lineCount uint32) []FoldingRange {
+	var ranges []FoldingRange
 
 	// Add range for the group itself
-	if r, ok := s.validFoldingRange(group.Pos.Line-1, group.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
+	if r, ok := s.validFoldingRange(group.Pos.Line-1, group.EndPos.Line-1, lineCount, protocol.RegionFoldingRange, fmt.Sprintf("group %q", group.Name)); ok {
 		ranges = append(ranges, r)
 	}
 
 	// Add range for group setup if present
-	if group.Setup != nil {
-		if r, ok := s.validFoldingRange(group.Setup.Pos.Line-1, group.Setup.EndPos.Line-1, lineCount, protocol.RegionFoldingRange); ok {
-			ranges = append(ranges, r)
-		}
-	}
+	ranges = append(ranges, s.setupFoldingRanges(group.Setup, lineCount)...)
 
 	// Add ranges for nested items
 	for _, item := range group.Items {
@@ -2,9 +2,15 @@ package lsp_test
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
+
+	"github.com/rlch/scaf/lsp"
 )
 
 func TestServer_FoldingRanges(t *testing.T) {
@@ -17,7 +23,7 @@ func TestServer_FoldingRanges(t *testing.T) {
 	_ = server.Initialized(ctx, &protocol.InitializedParams{})
 
 	// Open a file with various foldable elements
-	content := "import fixtures \"./fixtures\"\nimport utils \"./utils\"\n\n" +
+	content := "import fixtures \"./fixtures\"\nimport utils \"./utils\"\nimport seed \"./seed\"\n\n" +
 		"fn GetUser() `MATCH (u:User {id: $id}) RETURN u`\n\n" +
 		"fn CountPosts() `MATCH (p:Post)\nRETURN count(p)`\n\n" +
 		"GetUser {\n" +
@@ -66,13 +72,13 @@ func TestServer_FoldingRanges(t *testing.T) {
 		case protocol.RegionFoldingRange:
 			// Distinguish by looking at start line
 			switch r.StartLine {
-			case 3, 5: // query lines
+			case 4, 6: // query lines
 				queries++
-			case 8: // scope
+			case 9: // scope
 				scopes++
-			case 10, 17, 20: // tests
+			case 11, 18, 21: // tests
 				tests++
-			case 14: // group
+			case 15: // group
 				groups++
 			}
 		}
@@ -80,7 +86,7 @@ func TestServer_FoldingRanges(t *testing.T) {
 
 	t.Logf("Found: imports=%d, queries=%d, scopes=%d, tests=%d, groups=%d", imports, queries, scopes, tests, groups)
 
-	// Should have at least one import fold (for multiple imports)
+	// Should have at least one import fold (for 3+ imports)
 	if imports < 1 {
 		t.Error("Expected at least 1 import folding range")
 	}
@@ -154,3 +160,164 @@ Q { test "t" {} }
 		}
 	}
 }
+
+const foldingTextContent = "import a \"./a\"\nimport b \"./b\"\nimport c \"./c\"\n\n" +
+	"fn Q() `Q`\n\n" +
+	"Q {\n" +
+	"\tsetup {\n" +
+	"\t\t`CREATE (:A)`\n" +
+	"\t\t`CREATE (:B)`\n" +
+	"\t}\n" +
+	"\tgroup \"users\" {\n" +
+	"\t\ttest \"finds\" {\n" +
+	"\t\t\t$id: 1\n" +
+	"\t\t}\n" +
+	"\t}\n" +
+	"}\n"
+
+// foldingTextWant is the folding ranges expected for foldingTextContent.
+var foldingTextWant = []lsp.FoldingRange{
+	{FoldingRange: protocol.FoldingRange{StartLine: 0, EndLine: 2, Kind: protocol.ImportsFoldingRange}, CollapsedText: "3 imports"},
+	{FoldingRange: protocol.FoldingRange{StartLine: 6, EndLine: 16, Kind: protocol.RegionFoldingRange}, CollapsedText: "Q"},
+	{FoldingRange: protocol.FoldingRange{StartLine: 7, EndLine: 10, Kind: protocol.RegionFoldingRange}, CollapsedText: "setup"},
+	{FoldingRange: protocol.FoldingRange{StartLine: 11, EndLine: 15, Kind: protocol.RegionFoldingRange}, CollapsedText: `group "users"`},
+	{FoldingRange: protocol.FoldingRange{StartLine: 12, EndLine: 14, Kind: protocol.RegionFoldingRange}, CollapsedText: `test "finds"`},
+}
+
+func TestServer_FoldingRangesWithText(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: foldingTextContent},
+	})
+
+	result, err := server.FoldingRangesWithText(ctx, &protocol.FoldingRangeParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FoldingRangesWithText() error: %v", err)
+	}
+
+	if diff := cmp.Diff(foldingTextWant, result); diff != "" {
+		t.Errorf("FoldingRangesWithText() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServer_FoldingRanges_TwoImports(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	// Two imports, then three split by a blank line: no run is long enough
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text:    "import a \"./a\"\nimport b \"./b\"\n\nimport c \"./c\"\nimport d \"./d\"\n\nfn Q() `Q`\n",
+		},
+	})
+
+	result, err := server.FoldingRanges(ctx, &protocol.FoldingRangeParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FoldingRanges() error: %v", err)
+	}
+
+	for _, r := range result {
+		if r.Kind == protocol.ImportsFoldingRange {
+			t.Errorf("unexpected import folding range %d-%d", r.StartLine, r.EndLine)
+		}
+	}
+}
+
+func TestServer_FoldingRanges_ParseError(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: foldingTextContent},
+	})
+
+	// Break the test block mid-edit; folds should come from the last good parse
+	broken := strings.Replace(foldingTextContent, "$id: 1", "$id:", 1)
+	_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: broken}},
+	})
+
+	result, err := server.FoldingRangesWithText(ctx, &protocol.FoldingRangeParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("FoldingRangesWithText() error: %v", err)
+	}
+
+	if diff := cmp.Diff(foldingTextWant, result); diff != "" {
+		t.Errorf("FoldingRangesWithText() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewHandler_FoldingRange(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: foldingTextContent},
+	})
+
+	req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodTextDocumentFoldingRange, &protocol.FoldingRangeParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewCall() error: %v", err)
+	}
+
+	var reply []byte
+	err = lsp.NewHandler(server)(ctx, func(_ context.Context, result any, err error) error {
+		if err != nil {
+			return err
+		}
+		reply, err = json.Marshal(result)
+		return err
+	}, req)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+
+	// The collapsed text must survive serialization to the client
+	if !strings.Contains(string(reply), `"collapsedText":"test \"finds\""`) {
+		t.Errorf("reply missing collapsedText: %s", reply)
+	}
+}
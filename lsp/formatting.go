@@ -3,6 +3,7 @@ package lsp
 import (
 	"context"
 	"strings"
+	"time"
 
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
@@ -10,7 +11,12 @@ import (
 	"github.com/rlch/scaf"
 )
 
+// formatOnSaveTimeout bounds formatting on save, which blocks the save.
+const formatOnSaveTimeout = 500 * time.Millisecond
+
 // Formatting handles textDocument/formatting requests.
+// Query bodies follow the workspace config's formatting section, with the
+// editor's tab size as the indent width when the config sets none.
 func (s *Server) Formatting(_ context.Context, params *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
 	s.logger.Debug("Formatting", zap.String("uri", string(params.TextDocument.URI)))
 
@@ -19,17 +25,86 @@ func (s *Server) Formatting(_ context.Context, params *protocol.DocumentFormatti
 		return nil, nil
 	}
 
+	return s.formatDocument(doc, int(params.Options.TabSize)), nil
+}
+
+// WillSaveWaitUntil handles textDocument/willSaveWaitUntil requests.
+// Manual saves format the document unless the scaf.formatOnSave setting is
+// false. Formatting that takes longer than formatOnSaveTimeout is abandoned
+// so the document saves unformatted.
+func (s *Server) WillSaveWaitUntil(ctx context.Context, params *protocol.WillSaveTextDocumentParams) ([]protocol.TextEdit, error) {
+	s.logger.Debug("WillSaveWaitUntil",
+		zap.String("uri", string(params.TextDocument.URI)),
+		zap.Int("reason", int(params.Reason)))
+
+	if params.Reason != protocol.TextDocumentSaveReasonManual {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	enabled := s.formatOnSave
+	s.mu.RUnlock()
+
+	if !enabled {
+		return nil, nil
+	}
+
+	doc, ok := s.getDocument(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+
+	done := make(chan []protocol.TextEdit, 1)
+
+	go func() {
+		done <- s.formatDocument(doc, 0)
+	}()
+
+	timer := time.NewTimer(formatOnSaveTimeout)
+	defer timer.Stop()
+
+	select {
+	case edits := <-done:
+		return edits, nil
+	case <-timer.C:
+		s.logger.Warn("Format on save timed out",
+			zap.String("uri", string(params.TextDocument.URI)),
+			zap.Duration("timeout", formatOnSaveTimeout))
+
+		return []protocol.TextEdit{}, nil
+	case <-ctx.Done():
+		return []protocol.TextEdit{}, nil
+	}
+}
+
+// formatDocument returns the edit that replaces doc with its formatted
+// source, no edits if it's already formatted, or nil if it doesn't parse.
+// A tabSize of 0 leaves the query indent width to the config or dialect.
+func (s *Server) formatDocument(doc *Document, tabSize int) []protocol.TextEdit {
 	// Need a valid parse to format (no parse errors)
 	if doc.Analysis == nil || doc.Analysis.Suite == nil || doc.Analysis.ParseError != nil {
-		return nil, nil //nolint:nilerr // Cannot format documents with parse errors; return no edits
+		return nil
 	}
 
-	// Use the existing formatter
-	formatted := scaf.Format(doc.Analysis.Suite)
+	// Query bodies are formatted by the dialect when one is registered
+	var formatted string
+	if d := scaf.GetDialect(s.dialectName); d != nil {
+		if cf, ok := d.(scaf.ConfigurableFormatter); ok {
+			s.mu.RLock()
+			cfg := s.config
+			s.mu.RUnlock()
+
+			d = cf.WithFormatConfig(cfg, tabSize)
+		}
+
+		formatted = scaf.FormatWithDialect(doc.Analysis.Suite, d)
+	} else {
+		formatted = scaf.Format(doc.Analysis.Suite)
+	}
 
 	// If no change, return empty edits
 	if formatted == doc.Content {
-		return []protocol.TextEdit{}, nil
+		return []protocol.TextEdit{}
 	}
 
 	// Return a single edit that replaces the entire document
@@ -47,5 +122,5 @@ func (s *Server) Formatting(_ context.Context, params *protocol.DocumentFormatti
 			},
 			NewText: formatted,
 		},
-	}, nil
+	}
 }
@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// NewHandler returns the jsonrpc2 handler that dispatches requests to server.
+// It answers textDocument/foldingRange, textDocument/selectionRange, and the
+// pull diagnostic requests itself so results can carry LSP 3.15+ types
+// go.lsp.dev/protocol doesn't expose, advertises those capabilities on
+// initialize, and passes everything else to protocol.ServerHandler. After shutdown, requests other
+// than exit fail with jsonrpc2.ErrInvalidRequest and notifications are dropped.
+func NewHandler(server *Server) jsonrpc2.Handler {
+	next := protocol.ServerHandler(server, nil)
+
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if ctx.Err() != nil {
+			return next(ctx, reply, req)
+		}
+
+		if req.Method() != protocol.MethodExit && server.ShutdownReceived() {
+			if _, isCall := req.(*jsonrpc2.Call); isCall {
+				return reply(ctx, nil, fmt.Errorf("%w: %s after shutdown", jsonrpc2.ErrInvalidRequest, req.Method()))
+			}
+
+			return nil
+		}
+
+		switch req.Method() {
+		case protocol.MethodInitialize:
+			return next(ctx, withExtraCapabilities(reply), req)
+
+		case protocol.MethodTextDocumentFoldingRange:
+			var params protocol.FoldingRangeParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%w: %w", jsonrpc2.ErrParse, err))
+			}
+
+			ranges, err := server.FoldingRangesWithText(ctx, &params)
+
+			return reply(ctx, ranges, err)
+
+		case MethodTextDocumentSelectionRange:
+			var params SelectionRangeParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%w: %w", jsonrpc2.ErrParse, err))
+			}
+
+			ranges, err := server.SelectionRange(ctx, &params)
+
+			return reply(ctx, ranges, err)
+
+		case MethodTextDocumentDiagnostic:
+			var params DocumentDiagnosticParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%w: %w", jsonrpc2.ErrParse, err))
+			}
+
+			report, err := server.Diagnostic(ctx, &params)
+
+			return reply(ctx, report, err)
+
+		case MethodWorkspaceDiagnostic:
+			var params WorkspaceDiagnosticParams
+			if err := json.Unmarshal(req.Params(), &params); err != nil {
+				return reply(ctx, nil, fmt.Errorf("%w: %w", jsonrpc2.ErrParse, err))
+			}
+
+			report, err := server.WorkspaceDiagnostic(ctx, &params)
+
+			return reply(ctx, report, err)
+
+		default:
+			return next(ctx, reply, req)
+		}
+	}
+}
+
+// serverCapabilities adds capabilities missing from protocol.ServerCapabilities.
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+
+	SelectionRangeProvider bool               `json:"selectionRangeProvider,omitempty"`
+	DiagnosticProvider     *DiagnosticOptions `json:"diagnosticProvider,omitempty"`
+}
+
+// initializeResult is a protocol.InitializeResult with serverCapabilities.
+type initializeResult struct {
+	Capabilities serverCapabilities   `json:"capabilities"`
+	ServerInfo   *protocol.ServerInfo `json:"serverInfo,omitempty"`
+}
+
+// withExtraCapabilities wraps reply so a successful initialize result also
+// advertises selectionRangeProvider and diagnosticProvider. Diagnostics depend
+// on imported files, so clients should re-pull after related edits.
+func withExtraCapabilities(reply jsonrpc2.Replier) jsonrpc2.Replier {
+	return func(ctx context.Context, result any, err error) error {
+		r, ok := result.(*protocol.InitializeResult)
+		if err != nil || !ok || r == nil {
+			return reply(ctx, result, err)
+		}
+
+		return reply(ctx, &initializeResult{
+			Capabilities: serverCapabilities{
+				ServerCapabilities:     r.Capabilities,
+				SelectionRangeProvider: true,
+				DiagnosticProvider: &DiagnosticOptions{
+					InterFileDependencies: true,
+					WorkspaceDiagnostics:  true,
+				},
+			},
+			ServerInfo: r.ServerInfo,
+		}, nil)
+	}
+}
@@ -62,6 +62,11 @@ func (s *Server) DocumentHighlight(_ context.Context, params *protocol.DocumentH
 			highlights = s.highlightImportUsages(doc, *node.Module)
 		}
 
+	case *scaf.TeardownClause:
+		if node.Module != nil && tokenCtx.Token != nil && tokenCtx.Token.Value == *node.Module {
+			highlights = s.highlightImportUsages(doc, *node.Module)
+		}
+
 	case *scaf.SetupItem:
 		if node.Module != nil && tokenCtx.Token != nil && tokenCtx.Token.Value == *node.Module {
 			highlights = s.highlightImportUsages(doc, *node.Module)
@@ -186,9 +191,11 @@ func (s *Server) highlightImportUsages(doc *Document, alias string) []protocol.D
 
 	// Find all usages in setup clauses
 	s.findSetupImportHighlights(doc.Analysis.Suite.Setup, alias, &highlights)
+	s.findSetupImportHighlights((*scaf.SetupClause)(doc.Analysis.Suite.Teardown), alias, &highlights)
 
 	for _, scope := range doc.Analysis.Suite.Scopes {
 		s.findSetupImportHighlights(scope.Setup, alias, &highlights)
+		s.findSetupImportHighlights((*scaf.SetupClause)(scope.Teardown), alias, &highlights)
 		s.findItemSetupImportHighlights(scope.Items, alias, &highlights)
 	}
 
@@ -306,9 +313,11 @@ func (s *Server) findItemSetupImportHighlights(items []*scaf.TestOrGroup, alias
 	for _, item := range items {
 		if item.Test != nil {
 			s.findSetupImportHighlights(item.Test.Setup, alias, highlights)
+			s.findSetupImportHighlights((*scaf.SetupClause)(item.Test.Teardown), alias, highlights)
 		}
 		if item.Group != nil {
 			s.findSetupImportHighlights(item.Group.Setup, alias, highlights)
+			s.findSetupImportHighlights((*scaf.SetupClause)(item.Group.Teardown), alias, highlights)
 			s.findItemSetupImportHighlights(item.Group.Items, alias, highlights)
 		}
 	}
@@ -370,21 +370,21 @@ func (s *Server) hoverStatement(f *analysis.AnalyzedFile, stmt *scaf.Statement,
 
 	// Check where clause first
 	if stmt.Value != nil && stmt.Value.Where != nil {
-		if analysis.ContainsPosition(stmt.Value.Where.Span(), pos) {
+		if stmt.Value.Where.Span().Contains(pos) {
 			return s.hoverWhereClause(stmt.Value.Where), rangePtr(spanToRange(stmt.Value.Where.Span()))
 		}
 	}
 
 	// Check expression
 	if stmt.Value != nil && stmt.Value.Expr != nil {
-		if analysis.ContainsPosition(stmt.Value.Expr.Span(), pos) {
+		if stmt.Value.Expr.Span().Contains(pos) {
 			return s.hoverExpression(stmt.Value.Expr), rangePtr(spanToRange(stmt.Value.Expr.Span()))
 		}
 	}
 
 	// Check literal value
 	if stmt.Value != nil && stmt.Value.Literal != nil {
-		if analysis.ContainsPosition(stmt.Value.Literal.Span(), pos) {
+		if stmt.Value.Literal.Span().Contains(pos) {
 			return s.hoverLiteralValue(stmt.Value.Literal), rangePtr(spanToRange(stmt.Value.Literal.Span()))
 		}
 	}
@@ -444,7 +444,7 @@ func (s *Server) hoverDottedKey(key *scaf.DottedIdent, pos lexer.Position, q *an
 				Start: tok.Pos,
 				End:   lexer.Position{Line: tok.Pos.Line, Column: tok.Pos.Column + len(tok.Value)},
 			}
-			if analysis.ContainsPosition(tokSpan, pos) {
+			if tokSpan.Contains(pos) {
 				hoveredPart = tok.Value
 				hoveredIndex = partIndex
 				partRange = spanToRange(tokSpan)
@@ -803,7 +803,7 @@ func (s *Server) hoverSetupCallWithAnalysis(call *scaf.SetupCall, importedFile *
 
 	// Query not found - provide helpful diagnostics
 	var queryNames []string
-	for name := range importedFile.Symbols.Queries {
+	for name := range importedFile.ExportSymbols().Queries {
 		queryNames = append(queryNames, name)
 	}
 	s.logger.Debug("Query not found in imported file",
@@ -945,7 +945,7 @@ func (s *Server) hoverAssert(f *analysis.AnalyzedFile, assert *scaf.Assert, toke
 
 	// Check all conditions (shorthand or block form)
 	for _, cond := range assert.AllConditions() {
-		if !analysis.ContainsPosition(cond.Span(), pos) {
+		if !cond.Span().Contains(pos) {
 			continue
 		}
 		// Find the identifier at this position within the expression
@@ -1010,7 +1010,7 @@ func (s *Server) hoverExprIdentifierWithQuery(expr *scaf.ParenExpr, pos lexer.Po
 			Start: tok.Pos,
 			End:   lexer.Position{Line: tok.Pos.Line, Column: tok.Pos.Column + len(*tok.Ident)},
 		}
-		if !analysis.ContainsPosition(tokSpan, pos) {
+		if !tokSpan.Contains(pos) {
 			continue
 		}
 
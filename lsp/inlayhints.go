@@ -60,12 +60,10 @@ const (
 )
 
 // InlayHint handles textDocument/inlayHint requests.
-// Returns inlay hints for inferred parameter types in function signatures.
+// Returns inlay hints for inferred parameter types in function signatures
+// and for types the dialect annotates inside query bodies.
 func (s *Server) InlayHint(ctx context.Context, params *InlayHintParams) ([]InlayHint, error) {
-	s.mu.RLock()
-	doc, ok := s.documents[params.TextDocument.URI]
-	s.mu.RUnlock()
-
+	doc, ok := s.getDocument(params.TextDocument.URI)
 	if !ok {
 		return nil, nil
 	}
@@ -122,16 +120,22 @@ func (s *Server) InlayHint(ctx context.Context, params *InlayHintParams) ([]Inla
 		// Get inlay hints from dialect
 		dialectHints := dialectLSP.InlayHints(fn.Body, qctx)
 
-		// Convert dialect hints to LSP hints, positioning at the parameter declarations
+		// Convert dialect hints to LSP hints, positioning parameter hints at the
+		// parameter declarations and the rest inside the query body
 		for _, dh := range dialectHints {
-			// Find the parameter position in the function signature
-			paramPos := s.findParameterPosition(fn, dh.ParameterName)
-			if paramPos == nil {
+			var pos *protocol.Position
+			if dh.ParameterName != "" {
+				pos = s.findParameterPosition(fn, dh.ParameterName)
+			} else {
+				pos = s.findQueryBodyPosition(fn, dh.Offset)
+			}
+
+			if pos == nil {
 				continue
 			}
 
 			hint := InlayHint{
-				Position:    *paramPos,
+				Position:    *pos,
 				Label:       dh.Label,
 				Kind:        InlayHintKindType,
 				PaddingLeft: false,
@@ -175,6 +179,27 @@ func (s *Server) findParameterPosition(fn *scaf.Function, paramName string) *pro
 	return nil
 }
 
+// findQueryBodyPosition converts a byte offset within a function's query body
+// to a document position.
+func (s *Server) findQueryBodyPosition(fn *scaf.Function, offset int) *protocol.Position {
+	tok := s.findRawStringToken(fn.Tokens)
+	if tok == nil {
+		return nil
+	}
+
+	qbc := &QueryBodyContext{
+		Query: fn.Body,
+		QueryBodyStart: protocol.Position{
+			Line:      uint32(tok.Pos.Line - 1), //nolint:gosec
+			Character: uint32(tok.Pos.Column),   //nolint:gosec // column after opening backtick
+		},
+	}
+
+	pos := s.queryOffsetToDocPos(qbc, offset)
+
+	return &pos
+}
+
 // handleInlayHintRequest handles the textDocument/inlayHint request.
 // This is called from Request() since the protocol library doesn't have inlay hint types.
 func (s *Server) handleInlayHintRequest(ctx context.Context, params any) (any, error) {
@@ -281,3 +281,117 @@ func TestServer_InlayHints_NoSchema(t *testing.T) {
 		t.Fatalf("Expected 0 inlay hints (no schema), got %d", len(hints))
 	}
 }
+
+func TestServer_InlayHints_ApocPathReturnType(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	// No schema needed: apoc.path return types are fixed
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	doc := `fn Reachable(id: string) ` + "`" + `
+  MATCH (u:User {id: $id})
+  RETURN apoc.path.subgraphNodes(u, {maxLevel: 2}) AS nodes
+` + "`"
+
+	err := server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text:    doc,
+		},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen() error: %v", err)
+	}
+
+	hints, err := server.InlayHint(ctx, &lsp.InlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: 4, Character: 0},
+		},
+	})
+	if err != nil {
+		t.Fatalf("InlayHint() error: %v", err)
+	}
+
+	if len(hints) != 1 {
+		t.Fatalf("Expected 1 inlay hint, got %d: %+v", len(hints), hints)
+	}
+
+	hint := hints[0]
+	if hint.Label != ": []*neo4j.Node" {
+		t.Errorf("Expected label ': []*neo4j.Node', got %q", hint.Label)
+	}
+
+	// After the closing paren of the call on line 2
+	want := protocol.Position{Line: 2, Character: 50}
+	if hint.Position != want {
+		t.Errorf("Expected hint at %+v, got %+v", want, hint.Position)
+	}
+}
+
+func TestServer_InlayHints_ApocPathProcedureYields(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	doc := `fn Reachable(id: string) ` + "`" + `
+  MATCH (u:User {id: $id})
+  CALL apoc.path.subgraphAll(u, {maxLevel: 2}) YIELD nodes AS ns, relationships
+  RETURN ns, relationships
+` + "`"
+
+	err := server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text:    doc,
+		},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen() error: %v", err)
+	}
+
+	hints, err := server.InlayHint(ctx, &lsp.InlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		Range: protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: 5, Character: 0},
+		},
+	})
+	if err != nil {
+		t.Fatalf("InlayHint() error: %v", err)
+	}
+
+	if len(hints) != 2 {
+		t.Fatalf("Expected 2 inlay hints, got %d: %+v", len(hints), hints)
+	}
+
+	// After each yield item on line 2
+	want := []struct {
+		label string
+		pos   protocol.Position
+	}{
+		{": []*neo4j.Node", protocol.Position{Line: 2, Character: 64}},
+		{": []*neo4j.Relationship", protocol.Position{Line: 2, Character: 79}},
+	}
+
+	for i, w := range want {
+		if hints[i].Label != w.label {
+			t.Errorf("hint %d: expected label %q, got %q", i, w.label, hints[i].Label)
+		}
+
+		if hints[i].Position != w.pos {
+			t.Errorf("hint %d: expected position %+v, got %+v", i, w.pos, hints[i].Position)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+)
+
+// Pull diagnostic methods from LSP 3.17, which go.lsp.dev/protocol v0.12.0
+// doesn't define. They coexist with textDocument/publishDiagnostics, which is
+// still sent for clients that don't pull.
+const (
+	MethodTextDocumentDiagnostic = "textDocument/diagnostic"
+	MethodWorkspaceDiagnostic    = "workspace/diagnostic"
+)
+
+// Kinds of document diagnostic report.
+const (
+	// DiagnosticReportFull carries the document's complete diagnostics.
+	DiagnosticReportFull = "full"
+	// DiagnosticReportUnchanged says the diagnostics the client holds for
+	// ResultID are still current.
+	DiagnosticReportUnchanged = "unchanged"
+)
+
+// DiagnosticOptions is the diagnosticProvider server capability.
+type DiagnosticOptions struct {
+	Identifier            string `json:"identifier,omitempty"`
+	InterFileDependencies bool   `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool   `json:"workspaceDiagnostics"`
+}
+
+// DocumentDiagnosticParams are the parameters of a textDocument/diagnostic request.
+type DocumentDiagnosticParams struct {
+	TextDocument     protocol.TextDocumentIdentifier `json:"textDocument"`
+	Identifier       string                          `json:"identifier,omitempty"`
+	PreviousResultID string                          `json:"previousResultId,omitempty"`
+}
+
+// WorkspaceDiagnosticParams are the parameters of a workspace/diagnostic request.
+type WorkspaceDiagnosticParams struct {
+	Identifier        string             `json:"identifier,omitempty"`
+	PreviousResultIDs []PreviousResultID `json:"previousResultIds"`
+}
+
+// PreviousResultID is the result ID the client last received for a document.
+type PreviousResultID struct {
+	URI   protocol.DocumentURI `json:"uri"`
+	Value string               `json:"value"`
+}
+
+// DocumentDiagnosticReport is the result of a textDocument/diagnostic request.
+// Kind is DiagnosticReportFull, with Items, or DiagnosticReportUnchanged.
+type DocumentDiagnosticReport struct {
+	Kind     string
+	ResultID string
+	Items    []protocol.Diagnostic
+}
+
+// MarshalJSON encodes the report, always including items in a full report
+// and leaving them out of an unchanged one, as the protocol requires.
+func (r DocumentDiagnosticReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(diagnosticReportJSON(r.Kind, r.ResultID, r.Items))
+}
+
+// WorkspaceDiagnosticReport is the result of a workspace/diagnostic request.
+type WorkspaceDiagnosticReport struct {
+	Items []WorkspaceDocumentDiagnosticReport `json:"items"`
+}
+
+// WorkspaceDocumentDiagnosticReport is one document's report within a
+// WorkspaceDiagnosticReport.
+type WorkspaceDocumentDiagnosticReport struct {
+	DocumentDiagnosticReport
+
+	URI     protocol.DocumentURI
+	Version int32
+}
+
+// MarshalJSON encodes the report like DocumentDiagnosticReport, plus the
+// document's URI and version.
+func (r WorkspaceDocumentDiagnosticReport) MarshalJSON() ([]byte, error) {
+	report := diagnosticReportJSON(r.Kind, r.ResultID, r.Items)
+	report["uri"] = r.URI
+	report["version"] = r.Version
+
+	return json.Marshal(report)
+}
+
+// diagnosticReportJSON builds the JSON object of a document diagnostic report.
+func diagnosticReportJSON(kind, resultID string, items []protocol.Diagnostic) map[string]any {
+	report := map[string]any{"kind": kind}
+	if resultID != "" {
+		report["resultId"] = resultID
+	}
+
+	if kind == DiagnosticReportFull {
+		if items == nil {
+			items = []protocol.Diagnostic{}
+		}
+		report["items"] = items
+	}
+
+	return report
+}
+
+// Diagnostic handles textDocument/diagnostic requests.
+// The result ID is the hash of the analyzed content, plus a count of config
+// and schema reloads, so a document that hasn't changed since
+// previousResultId gets an unchanged report.
+func (s *Server) Diagnostic(_ context.Context, params *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error) {
+	defer s.traceHandler("Diagnostic")()
+	s.logger.Debug("Diagnostic",
+		zap.String("uri", string(params.TextDocument.URI)),
+		zap.String("previousResultId", params.PreviousResultID))
+
+	doc, ok := s.getDocument(params.TextDocument.URI)
+	if !ok || doc.Analysis == nil {
+		return &DocumentDiagnosticReport{Kind: DiagnosticReportFull}, nil
+	}
+
+	report := s.documentDiagnosticReport(doc, params.PreviousResultID)
+
+	return &report, nil
+}
+
+// WorkspaceDiagnostic handles workspace/diagnostic requests.
+// It reports on every open document, sorted by URI. Documents whose content
+// hash matches their previous result ID get unchanged reports.
+func (s *Server) WorkspaceDiagnostic(_ context.Context, params *WorkspaceDiagnosticParams) (*WorkspaceDiagnosticReport, error) {
+	defer s.traceHandler("WorkspaceDiagnostic")()
+	s.logger.Debug("WorkspaceDiagnostic",
+		zap.Int("previousResultIds", len(params.PreviousResultIDs)))
+
+	previous := make(map[protocol.DocumentURI]string, len(params.PreviousResultIDs))
+	for _, p := range params.PreviousResultIDs {
+		previous[p.URI] = p.Value
+	}
+
+	s.mu.RLock()
+	uris := make([]protocol.DocumentURI, 0, len(s.documents))
+	for uri := range s.documents {
+		uris = append(uris, uri)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(uris, func(a, b protocol.DocumentURI) int {
+		return strings.Compare(string(a), string(b))
+	})
+
+	result := &WorkspaceDiagnosticReport{Items: []WorkspaceDocumentDiagnosticReport{}}
+
+	for _, uri := range uris {
+		// getDocument analyzes documents with a pending debounced analysis
+		doc, ok := s.getDocument(uri)
+		if !ok || doc.Analysis == nil {
+			continue // Closed since listing
+		}
+
+		result.Items = append(result.Items, WorkspaceDocumentDiagnosticReport{
+			DocumentDiagnosticReport: s.documentDiagnosticReport(doc, previous[uri]),
+			URI:                      uri,
+			Version:                  doc.Version,
+		})
+	}
+
+	return result, nil
+}
+
+// documentDiagnosticReport returns doc's diagnostics, or an unchanged report
+// if previousResultID is still doc's result ID.
+func (s *Server) documentDiagnosticReport(doc *Document, previousResultID string) DocumentDiagnosticReport {
+	s.mu.RLock()
+	resultID := fmt.Sprintf("%d:%s", s.analysisEpoch, doc.analyzedHash)
+	s.mu.RUnlock()
+
+	if previousResultID != "" && previousResultID == resultID {
+		return DocumentDiagnosticReport{Kind: DiagnosticReportUnchanged, ResultID: resultID}
+	}
+
+	return DocumentDiagnosticReport{
+		Kind:     DiagnosticReportFull,
+		ResultID: resultID,
+		Items:    s.documentDiagnostics(doc),
+	}
+}
@@ -0,0 +1,227 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+
+	"github.com/rlch/scaf/lsp"
+)
+
+const (
+	pullValidContent   = "fn Q() `MATCH (n) RETURN n.name AS name`\n\nQ {\n\ttest \"t\" {\n\t\tname: \"a\"\n\t}\n}\n"
+	pullInvalidContent = "fn Q() `MATCH (n) RETURN n`\n\nUndefinedQuery {\n\ttest \"t\" {}\n}\n"
+)
+
+func TestServer_WorkspaceDiagnostic(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+
+	for uri, text := range map[protocol.DocumentURI]string{
+		"file:///b.scaf": pullInvalidContent,
+		"file:///a.scaf": pullValidContent,
+	} {
+		_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: text},
+		})
+	}
+
+	report, err := server.WorkspaceDiagnostic(ctx, &lsp.WorkspaceDiagnosticParams{})
+	if err != nil {
+		t.Fatalf("WorkspaceDiagnostic() error: %v", err)
+	}
+
+	if len(report.Items) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(report.Items))
+	}
+
+	a, b := report.Items[0], report.Items[1]
+	if a.URI != "file:///a.scaf" || b.URI != "file:///b.scaf" {
+		t.Fatalf("reports not sorted by URI: %s, %s", a.URI, b.URI)
+	}
+
+	if a.Kind != lsp.DiagnosticReportFull || len(a.Items) != 0 {
+		t.Errorf("a.scaf: kind %q with %d diagnostics, want a full, empty report", a.Kind, len(a.Items))
+	}
+
+	if b.Kind != lsp.DiagnosticReportFull || len(b.Items) == 0 || b.Items[0].Code != "undefined-query" {
+		t.Errorf("b.scaf: want a full report with undefined-query, got %+v", b)
+	}
+
+	if a.ResultID == "" || b.ResultID == "" || a.ResultID == b.ResultID {
+		t.Errorf("result IDs should be set and differ: %q, %q", a.ResultID, b.ResultID)
+	}
+
+	// Edit b.scaf; a.scaf is unchanged since its previous result
+	_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: "file:///b.scaf"},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: pullValidContent}},
+	})
+
+	report, err = server.WorkspaceDiagnostic(ctx, &lsp.WorkspaceDiagnosticParams{
+		PreviousResultIDs: []lsp.PreviousResultID{
+			{URI: a.URI, Value: a.ResultID},
+			{URI: b.URI, Value: b.ResultID},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WorkspaceDiagnostic() error: %v", err)
+	}
+
+	if got := report.Items[0]; got.Kind != lsp.DiagnosticReportUnchanged || got.ResultID != a.ResultID {
+		t.Errorf("a.scaf: want unchanged report for %q, got %+v", a.ResultID, got)
+	}
+
+	if got := report.Items[1]; got.Kind != lsp.DiagnosticReportFull || len(got.Items) != 0 || got.Version != 2 {
+		t.Errorf("b.scaf: want a full, empty report at version 2, got %+v", got)
+	}
+}
+
+func TestServer_Diagnostic(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: pullInvalidContent},
+	})
+
+	params := &lsp.DocumentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+	}
+
+	report, err := server.Diagnostic(ctx, params)
+	if err != nil {
+		t.Fatalf("Diagnostic() error: %v", err)
+	}
+
+	if report.Kind != lsp.DiagnosticReportFull || len(report.Items) == 0 {
+		t.Fatalf("want a full report with diagnostics, got %+v", report)
+	}
+
+	params.PreviousResultID = report.ResultID
+
+	report, err = server.Diagnostic(ctx, params)
+	if err != nil {
+		t.Fatalf("Diagnostic() error: %v", err)
+	}
+
+	if report.Kind != lsp.DiagnosticReportUnchanged {
+		t.Errorf("want an unchanged report, got %+v", report)
+	}
+}
+
+func TestServer_Diagnostic_QueryWarnings(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text:    "fn Q() `MATCH (u:User) RETURN u.name AS name LIMIT 10`\n",
+		},
+	})
+
+	report, err := server.Diagnostic(ctx, &lsp.DocumentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+	})
+	if err != nil {
+		t.Fatalf("Diagnostic() error: %v", err)
+	}
+
+	for _, d := range report.Items {
+		if d.Code == "limit-without-order-by" {
+			if d.Severity != protocol.DiagnosticSeverityWarning {
+				t.Errorf("severity = %v, want warning", d.Severity)
+			}
+
+			return
+		}
+	}
+
+	t.Errorf("expected a limit-without-order-by diagnostic, got %+v", report.Items)
+}
+
+func TestNewHandler_PullDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+	handler := lsp.NewHandler(server)
+
+	call := func(method string, params any) string {
+		t.Helper()
+
+		req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), method, params)
+		if err != nil {
+			t.Fatalf("NewCall() error: %v", err)
+		}
+
+		var reply []byte
+		err = handler(ctx, func(_ context.Context, result any, err error) error {
+			if err != nil {
+				return err
+			}
+			reply, err = json.Marshal(result)
+			return err
+		}, req)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+
+		return string(reply)
+	}
+
+	initReply := call(protocol.MethodInitialize, &protocol.InitializeParams{})
+	if !strings.Contains(initReply, `"workspaceDiagnostics":true`) {
+		t.Errorf("initialize reply missing diagnosticProvider: %s", initReply)
+	}
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: pullValidContent},
+	})
+
+	var full struct {
+		Items []map[string]json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(call(lsp.MethodWorkspaceDiagnostic, &lsp.WorkspaceDiagnosticParams{})), &full); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+
+	if len(full.Items) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(full.Items))
+	}
+
+	// A full report always carries items, even when there are none
+	if got := string(full.Items[0]["items"]); got != "[]" {
+		t.Errorf("full report items = %s, want []", got)
+	}
+
+	var resultID string
+	_ = json.Unmarshal(full.Items[0]["resultId"], &resultID)
+
+	unchanged := call(lsp.MethodTextDocumentDiagnostic, &lsp.DocumentDiagnosticParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		PreviousResultID: resultID,
+	})
+	if !strings.Contains(unchanged, `"kind":"unchanged"`) || strings.Contains(unchanged, `"items"`) {
+		t.Errorf("unchanged report = %s", unchanged)
+	}
+}
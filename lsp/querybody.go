@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/alecthomas/participle/v2/lexer"
 	"go.lsp.dev/protocol"
@@ -29,6 +30,10 @@ type QueryBodyContext struct {
 
 	// QueryBodyEnd is the document position where the query body ends.
 	QueryBodyEnd protocol.Position
+
+	// AssertQuery is true for an inline assert query, or for the body of a
+	// function that a named assert query runs.
+	AssertQuery bool
 }
 
 // queryBodyInfo holds information about a query body location found in the AST.
@@ -41,6 +46,8 @@ type queryBodyInfo struct {
 	functionName string
 	// params are declared parameters (for function definitions).
 	params map[string]*scaf.TypeExpr
+	// assert is set for an inline assert query.
+	assert bool
 }
 
 // getQueryBodyContext determines if the cursor position is inside a query body.
@@ -68,7 +75,9 @@ func (s *Server) getQueryBodyContext(doc *Document, pos protocol.Position) *Quer
 
 	// Calculate offset within the query body
 	// The token value includes backticks, so body starts at position + 1
-	bodyStartOffset := info.bodyToken.Pos.Offset + 1 // skip opening backtick
+	// Derived from line and column rather than Pos.Offset, which goes stale when
+	// an analysis is reused across a whitespace-only edit.
+	bodyStartOffset := tokenOffset(doc.Content, info.bodyToken.Pos) + 1 // skip opening backtick
 	cursorOffset := s.positionToOffset(doc.Content, pos)
 	queryOffset := cursorOffset - bodyStartOffset
 
@@ -95,9 +104,41 @@ func (s *Server) getQueryBodyContext(doc *Document, pos protocol.Position) *Quer
 		DeclaredParams: info.params,
 		QueryBodyStart: bodyStartPos,
 		QueryBodyEnd:   bodyEndPos,
+		AssertQuery:    info.assert || (info.functionName != "" && namedAssertQueries(af.Suite)[info.functionName]),
 	}
 }
 
+// namedAssertQueries returns the names of the functions that assert queries
+// in suite run, as in assert GetUser(id: 1) { ... }.
+func namedAssertQueries(suite *scaf.File) map[string]bool {
+	names := make(map[string]bool)
+
+	var walk func(items []*scaf.TestOrGroup)
+	walk = func(items []*scaf.TestOrGroup) {
+		for _, item := range items {
+			switch {
+			case item == nil:
+			case item.Test != nil:
+				for _, assert := range item.Test.Asserts {
+					if assert != nil && assert.Query != nil && assert.Query.QueryName != nil {
+						names[*assert.Query.QueryName] = true
+					}
+				}
+			case item.Group != nil:
+				walk(item.Group.Items)
+			}
+		}
+	}
+
+	for _, scope := range suite.Scopes {
+		if scope != nil {
+			walk(scope.Items)
+		}
+	}
+
+	return names
+}
+
 // findQueryBodyAtPosition searches the AST for a query body containing the given position.
 func (s *Server) findQueryBodyAtPosition(suite *scaf.File, pos lexer.Position) *queryBodyInfo {
 	// Check function definitions
@@ -119,7 +160,7 @@ func (s *Server) findQueryBodyAtPosition(suite *scaf.File, pos lexer.Position) *
 
 	// Check global teardown
 	if suite.Teardown != nil {
-		if info := s.checkTeardownBody(*suite.Teardown, suite.Tokens, pos); info != nil {
+		if info := s.checkSetupClause((*scaf.SetupClause)(suite.Teardown), pos); info != nil {
 			return info
 		}
 	}
@@ -207,41 +248,6 @@ func (s *Server) checkSetupItem(item *scaf.SetupItem, pos lexer.Position) *query
 	}
 }
 
-// checkTeardownBody checks if position is inside a teardown query body.
-// Takes the body string from the AST (already has backticks stripped by participle.Unquote).
-func (s *Server) checkTeardownBody(body string, tokens []lexer.Token, pos lexer.Position) *queryBodyInfo {
-	// Find the RawString token after the teardown keyword
-	tok := s.findRawStringTokenAfterKeyword(tokens, scaf.TokenTeardown)
-	if tok == nil || !s.positionInRawString(tok, pos) {
-		return nil
-	}
-
-	return &queryBodyInfo{
-		body:      body,
-		bodyToken: tok,
-	}
-}
-
-// findRawStringTokenAfterKeyword finds a RawString token that appears after a specific keyword token.
-func (s *Server) findRawStringTokenAfterKeyword(tokens []lexer.Token, keywordType lexer.TokenType) *lexer.Token {
-	foundKeyword := false
-	for i := range tokens {
-		if tokens[i].Type == keywordType {
-			foundKeyword = true
-			continue
-		}
-		if foundKeyword && tokens[i].Type == scaf.TokenRawString {
-			return &tokens[i]
-		}
-		// Skip whitespace and comments while looking
-		if foundKeyword && tokens[i].Type != scaf.TokenWhitespace && tokens[i].Type != scaf.TokenComment {
-			// Found something else after keyword, reset
-			foundKeyword = false
-		}
-	}
-	return nil
-}
-
 // checkScope checks if position is inside any query body within a scope.
 func (s *Server) checkScope(scope *scaf.FunctionScope, pos lexer.Position) *queryBodyInfo {
 	// Check scope setup
@@ -253,7 +259,7 @@ func (s *Server) checkScope(scope *scaf.FunctionScope, pos lexer.Position) *quer
 
 	// Check scope teardown
 	if scope.Teardown != nil {
-		if info := s.checkTeardownBody(*scope.Teardown, scope.Tokens, pos); info != nil {
+		if info := s.checkSetupClause((*scaf.SetupClause)(scope.Teardown), pos); info != nil {
 			return info
 		}
 	}
@@ -291,6 +297,13 @@ func (s *Server) checkTest(test *scaf.Test, pos lexer.Position) *queryBodyInfo {
 		}
 	}
 
+	// Check test teardown
+	if test.Teardown != nil {
+		if info := s.checkSetupClause((*scaf.SetupClause)(test.Teardown), pos); info != nil {
+			return info
+		}
+	}
+
 	// Check assert queries
 	for _, assert := range test.Asserts {
 		if assert == nil || assert.Query == nil {
@@ -315,7 +328,7 @@ func (s *Server) checkGroup(group *scaf.Group, pos lexer.Position) *queryBodyInf
 
 	// Check group teardown
 	if group.Teardown != nil {
-		if info := s.checkTeardownBody(*group.Teardown, group.Tokens, pos); info != nil {
+		if info := s.checkSetupClause((*scaf.SetupClause)(group.Teardown), pos); info != nil {
 			return info
 		}
 	}
@@ -347,6 +360,7 @@ func (s *Server) checkAssertQuery(aq *scaf.AssertQuery, pos lexer.Position) *que
 	return &queryBodyInfo{
 		body:      *aq.Inline,
 		bodyToken: tok,
+		assert:    true,
 	}
 }
 
@@ -416,6 +430,26 @@ func (s *Server) positionToOffset(content string, pos protocol.Position) int {
 	return offset
 }
 
+// tokenOffset converts a token's 1-indexed line and rune column to a byte offset in the content.
+func tokenOffset(content string, pos lexer.Position) int {
+	offset := 0
+
+	for line := 1; line < pos.Line; line++ {
+		next := strings.IndexByte(content[offset:], '\n')
+		if next < 0 {
+			return len(content)
+		}
+		offset += next + 1
+	}
+
+	for col := 1; col < pos.Column && offset < len(content) && content[offset] != '\n'; col++ {
+		_, size := utf8.DecodeRuneInString(content[offset:])
+		offset += size
+	}
+
+	return offset
+}
+
 // offsetToPosition converts a byte offset to an LSP Position.
 func (s *Server) offsetToPosition(content string, offset int) protocol.Position {
 	if offset < 0 {
@@ -439,21 +473,7 @@ func (s *Server) offsetToPosition(content string, offset int) protocol.Position
 
 // positionInSpan checks if an LSP position is within a scaf Span.
 func (s *Server) positionInSpan(pos protocol.Position, span scaf.Span) bool {
-	// Convert LSP 0-indexed to scaf 1-indexed
-	line := int(pos.Line) + 1
-	col := int(pos.Character) + 1
-
-	// Check start boundary
-	if line < span.Start.Line || (line == span.Start.Line && col < span.Start.Column) {
-		return false
-	}
-
-	// Check end boundary
-	if line > span.End.Line || (line == span.End.Line && col > span.End.Column) {
-		return false
-	}
-
-	return true
+	return span.Contains(analysis.PositionToLexer(pos.Line, pos.Character))
 }
 
 // buildQueryLSPContext creates a QueryLSPContext for dialect LSP calls.
@@ -461,14 +481,36 @@ func (s *Server) buildQueryLSPContext(doc *Document, qbc *QueryBodyContext, trig
 	ctx := &scaf.QueryLSPContext{
 		FunctionScope:    qbc.FunctionName,
 		FilePath:         URIToPath(doc.URI),
-		DeclaredParams:   qbc.DeclaredParams,
+		DeclaredParams:   declaredQueryParams(doc, qbc),
 		TriggerCharacter: triggerChar,
 		Schema:           s.getSchema(),
+		AssertQuery:      qbc.AssertQuery,
 	}
 
 	return ctx
 }
 
+// declaredQueryParams returns the parameters of the function enclosing a query
+// body, with their type annotations (nil if untyped). The analyzed symbol is
+// preferred, falling back to the function's own parameter list.
+func declaredQueryParams(doc *Document, qbc *QueryBodyContext) map[string]*scaf.TypeExpr {
+	if qbc.FunctionName == "" || doc.Analysis == nil || doc.Analysis.Symbols == nil {
+		return qbc.DeclaredParams
+	}
+
+	query, ok := doc.Analysis.Symbols.Queries[qbc.FunctionName]
+	if !ok || len(query.DeclaredParams) == 0 {
+		return qbc.DeclaredParams
+	}
+
+	params := make(map[string]*scaf.TypeExpr, len(query.DeclaredParams))
+	for name := range query.DeclaredParams {
+		params[name] = query.TypedParams[name]
+	}
+
+	return params
+}
+
 // getDialectLSP returns the DialectLSP for the current dialect.
 func (s *Server) getDialectLSP() scaf.DialectLSP { //nolint:ireturn
 	return scaf.GetDialectLSP(s.dialectName)
@@ -36,10 +36,16 @@ func (s *Server) References(_ context.Context, params *protocol.ReferenceParams)
 	// Determine what kind of symbol we're on and find all references
 	switch node := tokenCtx.Node.(type) {
 	case *scaf.Query:
-		locations = s.findQueryReferences(doc, node.Name, includeDecl)
+		// Only the name identifier refers to the query, not the fn keyword or body
+		if tokenCtx.Token != nil && tokenCtx.Token.Value == node.Name {
+			locations = s.findQueryReferences(doc, node.Name, includeDecl)
+		}
 
 	case *scaf.QueryScope:
-		locations = s.findQueryReferences(doc, node.FunctionName, includeDecl)
+		// The scope header is itself a reference to the query definition
+		if tokenCtx.Token != nil && tokenCtx.Token.Value == node.FunctionName {
+			locations = s.findQueryReferences(doc, node.FunctionName, includeDecl)
+		}
 
 	case *scaf.Import:
 		alias := baseNameFromImport(node)
@@ -61,6 +67,11 @@ func (s *Server) References(_ context.Context, params *protocol.ReferenceParams)
 			locations = s.findImportReferences(doc, *node.Module, includeDecl)
 		}
 
+	case *scaf.TeardownClause:
+		if node.Module != nil && tokenCtx.Token != nil && tokenCtx.Token.Value == *node.Module {
+			locations = s.findImportReferences(doc, *node.Module, includeDecl)
+		}
+
 	case *scaf.SetupItem:
 		if node.Module != nil && tokenCtx.Token != nil && tokenCtx.Token.Value == *node.Module {
 			locations = s.findImportReferences(doc, *node.Module, includeDecl)
@@ -85,7 +96,9 @@ func (s *Server) References(_ context.Context, params *protocol.ReferenceParams)
 	return locations, nil
 }
 
-// findQueryReferences finds all references to a query in the current document.
+// findQueryReferences finds all references to a query defined in the current
+// document: its scopes and assert blocks, plus setup calls to it from other
+// open documents that import this one.
 func (s *Server) findQueryReferences(doc *Document, queryName string, includeDecl bool) []protocol.Location {
 	if doc.Analysis.Suite == nil {
 		return nil
@@ -119,16 +132,25 @@ func (s *Server) findQueryReferences(doc *Document, queryName string, includeDec
 		s.collectAssertQueryRefs(doc.URI, scope.Items, queryName, &locations)
 	}
 
-	// Also search other open documents in the workspace
+	// Other files can only reach the query through setup calls on an import
+	if s.fileLoader == nil {
+		return locations
+	}
+
+	docPath := URIToPath(doc.URI)
+
 	s.mu.RLock()
 	for uri, otherDoc := range s.documents {
-		if uri == doc.URI || otherDoc.Analysis == nil || otherDoc.Analysis.Suite == nil {
+		if uri == doc.URI || otherDoc.Analysis == nil || otherDoc.Analysis.Suite == nil || otherDoc.Analysis.Symbols == nil {
 			continue
 		}
 
-		// Check if this document imports the current document and references the query
-		// For now, just check same-file references
-		// Cross-file query references happen through setup calls, which are handled separately
+		otherDocPath := URIToPath(uri)
+		for alias, imp := range otherDoc.Analysis.Symbols.Imports {
+			if s.fileLoader.ResolveImportPath(otherDocPath, imp.Path) == docPath {
+				s.collectSetupCallQueryRefs(uri, otherDoc.Analysis.Suite, alias, queryName, &locations)
+			}
+		}
 	}
 	s.mu.RUnlock()
 
@@ -177,9 +199,11 @@ func (s *Server) findImportReferences(doc *Document, alias string, includeDecl b
 
 	// Find all usages
 	s.collectSetupImportRefs(doc.URI, doc.Analysis.Suite.Setup, alias, &locations)
+	s.collectSetupImportRefs(doc.URI, (*scaf.SetupClause)(doc.Analysis.Suite.Teardown), alias, &locations)
 
 	for _, scope := range doc.Analysis.Suite.Scopes {
 		s.collectSetupImportRefs(doc.URI, scope.Setup, alias, &locations)
+		s.collectSetupImportRefs(doc.URI, (*scaf.SetupClause)(scope.Teardown), alias, &locations)
 		s.collectItemSetupImportRefs(doc.URI, scope.Items, alias, &locations)
 	}
 
@@ -227,9 +251,11 @@ func (s *Server) collectItemSetupImportRefs(uri protocol.DocumentURI, items []*s
 	for _, item := range items {
 		if item.Test != nil {
 			s.collectSetupImportRefs(uri, item.Test.Setup, alias, locations)
+			s.collectSetupImportRefs(uri, (*scaf.SetupClause)(item.Test.Teardown), alias, locations)
 		}
 		if item.Group != nil {
 			s.collectSetupImportRefs(uri, item.Group.Setup, alias, locations)
+			s.collectSetupImportRefs(uri, (*scaf.SetupClause)(item.Group.Teardown), alias, locations)
 			s.collectItemSetupImportRefs(uri, item.Group.Items, alias, locations)
 		}
 	}
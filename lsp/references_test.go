@@ -174,3 +174,116 @@ GetUser {
 		t.Errorf("Expected reference on line 2, got line %d", result[0].Range.Start.Line)
 	}
 }
+
+func TestServer_References_Query_OpenImporters(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	fixturesPath := filepath.Join(tmpDir, "fixtures.scaf")
+	fixturesContent := `fn CreateUser() ` + "`CREATE (u:User {name: $name}) RETURN u`" + `
+
+fn CountUsers() ` + "`MATCH (u:User) RETURN count(u) AS n`" + `
+
+CreateUser {
+	test "creates user" {
+		$name: "Alice"
+		assert CountUsers() { (n == 1) }
+	}
+}
+`
+	mainPath := filepath.Join(tmpDir, "main.scaf")
+	mainContent := `import fixtures "./fixtures"
+
+fn GetUser() ` + "`MATCH (u:User) RETURN u`" + `
+
+GetUser {
+	setup fixtures.CreateUser($name: "Alice")
+	test "finds user" {
+		setup fixtures.CreateUser($name: "Bob")
+	}
+}
+`
+	for path, content := range map[string]string{fixturesPath: fixturesContent, mainPath: mainContent} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	fixturesURI := protocol.DocumentURI("file://" + fixturesPath)
+	mainURI := protocol.DocumentURI("file://" + mainPath)
+
+	for uri, content := range map[protocol.DocumentURI]string{fixturesURI: fixturesContent, mainURI: mainContent} {
+		_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: content},
+		})
+	}
+
+	references := func(t *testing.T, pos protocol.Position, includeDecl bool) []protocol.Location {
+		t.Helper()
+
+		result, err := server.References(ctx, &protocol.ReferenceParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: fixturesURI},
+				Position:     pos,
+			},
+			Context: protocol.ReferenceContext{IncludeDeclaration: includeDecl},
+		})
+		if err != nil {
+			t.Fatalf("References() error: %v", err)
+		}
+
+		return result
+	}
+
+	t.Run("from scope header", func(t *testing.T) {
+		t.Parallel()
+
+		// Scope in fixtures.scaf plus two setup calls in main.scaf
+		result := references(t, protocol.Position{Line: 4, Character: 3}, false)
+		if len(result) != 3 {
+			t.Fatalf("Expected 3 references, got %d: %v", len(result), result)
+		}
+
+		inMain := 0
+		for _, loc := range result {
+			if loc.URI == mainURI {
+				inMain++
+			}
+			if loc.URI == fixturesURI && loc.Range.Start.Line == 0 {
+				t.Errorf("Declaration included with IncludeDeclaration false")
+			}
+		}
+
+		if inMain != 2 {
+			t.Errorf("Expected 2 references in main.scaf, got %d", inMain)
+		}
+	})
+
+	t.Run("with declaration", func(t *testing.T) {
+		t.Parallel()
+
+		result := references(t, protocol.Position{Line: 0, Character: 5}, true)
+		if len(result) != 4 {
+			t.Fatalf("Expected 4 references, got %d: %v", len(result), result)
+		}
+	})
+
+	t.Run("assert query", func(t *testing.T) {
+		t.Parallel()
+
+		// Definition plus the assert block
+		result := references(t, protocol.Position{Line: 2, Character: 5}, true)
+		if len(result) != 2 {
+			t.Fatalf("Expected 2 references, got %d: %v", len(result), result)
+		}
+	})
+}
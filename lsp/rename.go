@@ -55,6 +55,13 @@ func (s *Server) PrepareRename(_ context.Context, params *protocol.PrepareRename
 		return nil, nil //nolint:nilnil
 	}
 
+	// Variables in a query body are renamed by the dialect
+	if qbc := s.getQueryBodyContext(doc, params.Position); qbc != nil {
+		if _, rng, err := s.renameInQueryBody(qbc, queryIdentifierAt(qbc.Query, qbc.Offset)); err == nil && rng != nil {
+			return rng, nil
+		}
+	}
+
 	pos := analysis.PositionToLexer(params.Position.Line, params.Position.Character)
 	tokenCtx := analysis.GetTokenContext(doc.Analysis, pos)
 
@@ -82,6 +89,20 @@ func (s *Server) Rename(_ context.Context, params *protocol.RenameParams) (*prot
 		return nil, nil //nolint:nilnil
 	}
 
+	// Variables in a query body are renamed by the dialect
+	if qbc := s.getQueryBodyContext(doc, params.Position); qbc != nil {
+		edits, _, err := s.renameInQueryBody(qbc, params.NewName)
+		if err != nil {
+			return nil, err
+		}
+
+		if edits != nil {
+			return &protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentURI][]protocol.TextEdit{params.TextDocument.URI: edits},
+			}, nil
+		}
+	}
+
 	pos := analysis.PositionToLexer(params.Position.Line, params.Position.Character)
 	tokenCtx := analysis.GetTokenContext(doc.Analysis, pos)
 
@@ -111,18 +132,75 @@ func (s *Server) Rename(_ context.Context, params *protocol.RenameParams) (*prot
 	}, nil
 }
 
+// renameInQueryBody renames the variable at the cursor in a query body to
+// newName using the dialect's QueryRenamer. It returns the document edits and
+// the range of the variable at the cursor. With nothing for the dialect to
+// rename there, such as a $parameter, it returns no edits and no error so the
+// caller can rename a scaf symbol instead.
+func (s *Server) renameInQueryBody(qbc *QueryBodyContext, newName string) ([]protocol.TextEdit, *protocol.Range, error) {
+	renamer, ok := scaf.GetDialect(s.dialectName).(scaf.QueryRenamer)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	_, queryEdits, err := renamer.Rename(qbc.Query, qbc.Offset, newName)
+	if errors.Is(err, scaf.ErrNoRenameTarget) {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rng *protocol.Range
+
+	edits := make([]protocol.TextEdit, 0, len(queryEdits))
+
+	for _, e := range queryEdits {
+		editRange := s.queryRangeToDocRange(&scaf.QueryRange{Start: e.Start, End: e.End}, qbc)
+		if qbc.Offset >= e.Start && qbc.Offset <= e.End {
+			rng = &editRange
+		}
+
+		edits = append(edits, protocol.TextEdit{Range: editRange, NewText: e.NewText})
+	}
+
+	return edits, rng, nil
+}
+
+// queryIdentifierAt returns the identifier in query around offset, or "".
+func queryIdentifierAt(query string, offset int) string {
+	start, end := offset, offset
+
+	for start > 0 && isIdentChar(rune(query[start-1])) {
+		start--
+	}
+
+	for end < len(query) && isIdentChar(rune(query[end])) {
+		end++
+	}
+
+	return query[start:end]
+}
+
 // getRenameContext determines what kind of rename operation this is.
 func (s *Server) getRenameContext(doc *Document, tokenCtx *analysis.TokenContext) RenameContext {
 	ctx := RenameContext{Kind: RenameKindNone}
 
 	switch node := tokenCtx.Node.(type) {
 	case *scaf.Query:
-		ctx.Kind = RenameKindQuery
-		ctx.OldName = node.Name
+		// Only the name identifier is renameable, not the fn keyword or body
+		if tokenCtx.Token != nil && tokenCtx.Token.Value == node.Name {
+			ctx.Kind = RenameKindQuery
+			ctx.OldName = node.Name
+		}
 
 	case *scaf.QueryScope:
-		ctx.Kind = RenameKindQuery
-		ctx.OldName = node.FunctionName
+		// Only the scope header is renameable, not the tests inside
+		if tokenCtx.Token != nil && tokenCtx.Token.Value == node.FunctionName {
+			ctx.Kind = RenameKindQuery
+			ctx.OldName = node.FunctionName
+		}
 
 	case *scaf.Import:
 		ctx.Kind = RenameKindImport
@@ -130,12 +208,16 @@ func (s *Server) getRenameContext(doc *Document, tokenCtx *analysis.TokenContext
 
 	case *scaf.SetupCall:
 		if tokenCtx.Token != nil {
-			if tokenCtx.Token.Value == node.Module {
+			switch tokenCtx.Token.Value {
+			case node.Module:
 				ctx.Kind = RenameKindImport
 				ctx.OldName = node.Module
+			case node.Query:
+				// Query defined in the imported module
+				ctx.Kind = RenameKindQuery
+				ctx.OldName = node.Query
+				ctx.ModuleAlias = node.Module
 			}
-			// Note: Renaming the query name in a setup call would require
-			// cross-file rename, which we don't support yet
 		}
 
 	case *scaf.SetupClause:
@@ -144,6 +226,12 @@ func (s *Server) getRenameContext(doc *Document, tokenCtx *analysis.TokenContext
 			ctx.OldName = *node.Module
 		}
 
+	case *scaf.TeardownClause:
+		if node.Module != nil && tokenCtx.Token != nil && tokenCtx.Token.Value == *node.Module {
+			ctx.Kind = RenameKindImport
+			ctx.OldName = *node.Module
+		}
+
 	case *scaf.SetupItem:
 		if node.Module != nil && tokenCtx.Token != nil && tokenCtx.Token.Value == *node.Module {
 			ctx.Kind = RenameKindImport
@@ -190,6 +278,10 @@ func (s *Server) getRenameRange(doc *Document, tokenCtx *analysis.TokenContext,
 		return &rng
 
 	case *scaf.SetupCall:
+		if ctx.Kind == RenameKindQuery {
+			rng := setupCallQueryRange(node)
+			return &rng
+		}
 		if tokenCtx.Token != nil && tokenCtx.Token.Value == node.Module {
 			rng := setupCallModuleRange(node)
 			return &rng
@@ -201,6 +293,12 @@ func (s *Server) getRenameRange(doc *Document, tokenCtx *analysis.TokenContext,
 			return &rng
 		}
 
+	case *scaf.TeardownClause:
+		if node.Module != nil {
+			rng := setupModuleRange((*scaf.SetupClause)(node))
+			return &rng
+		}
+
 	case *scaf.SetupItem:
 		if node.Module != nil {
 			rng := setupItemModuleRange(node)
@@ -271,9 +369,12 @@ func isDigit(r rune) bool {
 func (s *Server) checkRenameConflicts(doc *Document, newName string, ctx RenameContext) error {
 	switch ctx.Kind {
 	case RenameKindQuery:
-		// Check if query name already exists
-		if _, exists := doc.Analysis.Symbols.Queries[newName]; exists {
-			return fmt.Errorf("%w: %s", ErrQueryAlreadyExists, newName)
+		// Check if query name already exists in the file that defines it
+		_, target := s.resolveQueryRenameTarget(doc, ctx)
+		if target != nil && target.Symbols != nil {
+			if _, exists := target.Symbols.Queries[newName]; exists {
+				return fmt.Errorf("%w: %s", ErrQueryAlreadyExists, newName)
+			}
 		}
 
 	case RenameKindImport:
@@ -299,7 +400,7 @@ func (s *Server) generateRenameEdits(doc *Document, newName string, ctx RenameCo
 
 	switch ctx.Kind {
 	case RenameKindQuery:
-		s.generateQueryRenameEdits(doc, ctx.OldName, newName, edits)
+		s.generateQueryRenameEdits(doc, ctx, newName, edits)
 
 	case RenameKindImport:
 		s.generateImportRenameEdits(doc, ctx.OldName, newName, edits)
@@ -314,16 +415,48 @@ func (s *Server) generateRenameEdits(doc *Document, newName string, ctx RenameCo
 	return edits
 }
 
-// generateQueryRenameEdits generates edits to rename a query.
-func (s *Server) generateQueryRenameEdits(doc *Document, oldName, newName string, edits map[protocol.DocumentURI][]protocol.TextEdit) {
-	if doc.Analysis.Suite == nil {
+// resolveQueryRenameTarget returns the URI and analysis of the file defining
+// the query being renamed. For references through a setup call this is the
+// imported module; otherwise it is the current document.
+func (s *Server) resolveQueryRenameTarget(doc *Document, ctx RenameContext) (protocol.DocumentURI, *analysis.AnalyzedFile) {
+	if ctx.ModuleAlias == "" {
+		return doc.URI, doc.Analysis
+	}
+
+	imp, ok := doc.Analysis.Symbols.Imports[ctx.ModuleAlias]
+	if !ok || s.fileLoader == nil {
+		return "", nil
+	}
+
+	importedPath := s.fileLoader.ResolveImportPath(URIToPath(doc.URI), imp.Path)
+	uri := PathToURI(importedPath)
+
+	// Prefer the open document's analysis, which may have unsaved changes
+	if target, ok := s.getDocument(uri); ok && target.Analysis != nil {
+		return uri, target.Analysis
+	}
+
+	analyzed, err := s.fileLoader.LoadAndAnalyze(importedPath)
+	if err != nil {
+		return "", nil
+	}
+
+	return uri, analyzed
+}
+
+// generateQueryRenameEdits generates edits to rename a query in the file that
+// defines it and in every workspace file that calls it through an import.
+func (s *Server) generateQueryRenameEdits(doc *Document, ctx RenameContext, newName string, edits map[protocol.DocumentURI][]protocol.TextEdit) {
+	targetURI, target := s.resolveQueryRenameTarget(doc, ctx)
+	if target == nil || target.Suite == nil {
 		return
 	}
+	oldName := ctx.OldName
 
 	var docEdits []protocol.TextEdit
 
 	// Rename the query definition
-	for _, q := range doc.Analysis.Suite.Functions {
+	for _, q := range target.Suite.Functions {
 		if q.Name == oldName {
 			docEdits = append(docEdits, protocol.TextEdit{
 				Range:   queryNameRange(q),
@@ -334,7 +467,7 @@ func (s *Server) generateQueryRenameEdits(doc *Document, oldName, newName string
 	}
 
 	// Rename all query scope references
-	for _, scope := range doc.Analysis.Suite.Scopes {
+	for _, scope := range target.Suite.Scopes {
 		if scope.FunctionName == oldName {
 			docEdits = append(docEdits, protocol.TextEdit{
 				Range:   scopeNameRange(scope),
@@ -347,8 +480,47 @@ func (s *Server) generateQueryRenameEdits(doc *Document, oldName, newName string
 	}
 
 	if len(docEdits) > 0 {
-		edits[doc.URI] = docEdits
+		edits[targetURI] = docEdits
+	}
+
+	// Rename module.Query setup calls in files importing the defining file
+	for _, loc := range s.findImporterQueryRefs(URIToPath(targetURI), oldName) {
+		edits[loc.URI] = append(edits[loc.URI], protocol.TextEdit{
+			Range:   loc.Range,
+			NewText: newName,
+		})
+	}
+}
+
+// findImporterQueryRefs finds setup calls to queryName in open documents and
+// workspace files that import the file at definingPath.
+func (s *Server) findImporterQueryRefs(definingPath, queryName string) []protocol.Location {
+	if s.fileLoader == nil {
+		return nil
+	}
+
+	var locations []protocol.Location
+
+	s.mu.RLock()
+	for uri, otherDoc := range s.documents {
+		if otherDoc.Analysis == nil || otherDoc.Analysis.Suite == nil || otherDoc.Analysis.Symbols == nil {
+			continue
+		}
+		otherDocPath := URIToPath(uri)
+		for alias, imp := range otherDoc.Analysis.Symbols.Imports {
+			if s.fileLoader.ResolveImportPath(otherDocPath, imp.Path) == definingPath {
+				s.collectSetupCallQueryRefs(uri, otherDoc.Analysis.Suite, alias, queryName, &locations)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	// Open documents were handled above; searchWorkspaceForQueryRefs skips them
+	if s.workspaceRoot != "" {
+		s.searchWorkspaceForQueryRefs(definingPath, queryName, PathToURI(definingPath), &locations)
 	}
+
+	return locations
 }
 
 // collectAssertQueryEdits recursively collects edits for assert query references.
@@ -408,9 +580,11 @@ func (s *Server) generateImportRenameEdits(doc *Document, oldAlias, newAlias str
 
 	// Rename all usages in setup clauses
 	s.collectSetupImportEdits(doc.Analysis.Suite.Setup, oldAlias, newAlias, &docEdits)
+	s.collectSetupImportEdits((*scaf.SetupClause)(doc.Analysis.Suite.Teardown), oldAlias, newAlias, &docEdits)
 
 	for _, scope := range doc.Analysis.Suite.Scopes {
 		s.collectSetupImportEdits(scope.Setup, oldAlias, newAlias, &docEdits)
+		s.collectSetupImportEdits((*scaf.SetupClause)(scope.Teardown), oldAlias, newAlias, &docEdits)
 		s.collectItemSetupImportEdits(scope.Items, oldAlias, newAlias, &docEdits)
 	}
 
@@ -460,9 +634,11 @@ func (s *Server) collectItemSetupImportEdits(items []*scaf.TestOrGroup, oldAlias
 	for _, item := range items {
 		if item.Test != nil {
 			s.collectSetupImportEdits(item.Test.Setup, oldAlias, newAlias, edits)
+			s.collectSetupImportEdits((*scaf.SetupClause)(item.Test.Teardown), oldAlias, newAlias, edits)
 		}
 		if item.Group != nil {
 			s.collectSetupImportEdits(item.Group.Setup, oldAlias, newAlias, edits)
+			s.collectSetupImportEdits((*scaf.SetupClause)(item.Group.Teardown), oldAlias, newAlias, edits)
 			s.collectItemSetupImportEdits(item.Group.Items, oldAlias, newAlias, edits)
 		}
 	}
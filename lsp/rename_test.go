@@ -2,6 +2,8 @@ package lsp_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"go.lsp.dev/protocol"
@@ -245,3 +247,212 @@ func TestServer_Rename_InvalidName(t *testing.T) {
 		t.Error("Expected error for invalid name")
 	}
 }
+
+func TestServer_PrepareRename_RejectsNonName(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := `fn GetUser() ` + "`MATCH (u:User {id: $id}) RETURN u`" + `
+`
+	uri := protocol.DocumentURI("file:///test.scaf")
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     uri,
+			Version: 1,
+			Text:    content,
+		},
+	})
+
+	// Prepare rename on the "fn" keyword
+	result, err := server.PrepareRename(ctx, &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 0, Character: 1}, // On "fn"
+		},
+	})
+	if err != nil {
+		t.Fatalf("PrepareRename() error: %v", err)
+	}
+
+	if result != nil {
+		t.Errorf("Expected no prepare rename result on keyword, got %+v", result)
+	}
+}
+
+func TestServer_Rename_QueryAcrossFiles(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	fixturesPath := filepath.Join(tmpDir, "fixtures.scaf")
+	fixturesContent := `fn CreateUser() ` + "`CREATE (u:User {name: $name}) RETURN u`" + `
+
+CreateUser {
+	test "creates user" {
+		$name: "alice"
+	}
+}
+`
+	if err := os.WriteFile(fixturesPath, []byte(fixturesContent), 0o644); err != nil {
+		t.Fatalf("Failed to write fixtures.scaf: %v", err)
+	}
+
+	mainPath := filepath.Join(tmpDir, "main.scaf")
+	mainContent := `import fixtures "./fixtures"
+
+fn GetUser() ` + "`MATCH (u:User {id: $id}) RETURN u`" + `
+
+GetUser {
+	setup fixtures.CreateUser($name: "test")
+	test "finds user" {
+		$id: 1
+	}
+}
+`
+	if err := os.WriteFile(mainPath, []byte(mainContent), 0o644); err != nil {
+		t.Fatalf("Failed to write main.scaf: %v", err)
+	}
+
+	fixturesURI := protocol.DocumentURI("file://" + fixturesPath)
+	mainURI := protocol.DocumentURI("file://" + mainPath)
+
+	tests := []struct {
+		name     string
+		uri      protocol.DocumentURI
+		content  string
+		position protocol.Position
+	}{
+		{"from definition", fixturesURI, fixturesContent, protocol.Position{Line: 0, Character: 5}},
+		{"from setup call", mainURI, mainContent, protocol.Position{Line: 5, Character: 17}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, _ := newTestServer(t)
+			ctx := context.Background()
+
+			_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+				RootURI: protocol.DocumentURI("file://" + tmpDir),
+			})
+			_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+			_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+				TextDocument: protocol.TextDocumentItem{
+					URI:     tt.uri,
+					Version: 1,
+					Text:    tt.content,
+				},
+			})
+
+			result, err := server.Rename(ctx, &protocol.RenameParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: tt.uri},
+					Position:     tt.position,
+				},
+				NewName: "InsertUser",
+			})
+			if err != nil {
+				t.Fatalf("Rename() error: %v", err)
+			}
+
+			if result == nil {
+				t.Fatal("Expected workspace edit")
+			}
+
+			// Definition + scope header in fixtures.scaf
+			if got := len(result.Changes[fixturesURI]); got != 2 {
+				t.Errorf("Expected 2 edits in fixtures.scaf, got %d", got)
+			}
+
+			// Setup call in main.scaf
+			mainEdits := result.Changes[mainURI]
+			if len(mainEdits) != 1 {
+				t.Fatalf("Expected 1 edit in main.scaf, got %d", len(mainEdits))
+			}
+			if mainEdits[0].Range.Start.Line != 5 || mainEdits[0].Range.Start.Character != 16 {
+				t.Errorf("Expected edit at 5:16, got %d:%d",
+					mainEdits[0].Range.Start.Line, mainEdits[0].Range.Start.Character)
+			}
+		})
+	}
+}
+
+func TestServer_Rename_QueryBodyVariable(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := "fn GetUser(id) `MATCH (u:User {id: $id})\nRETURN u.name AS name, u`\n"
+	uri := protocol.DocumentURI("file:///test.scaf")
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: content},
+	})
+
+	position := protocol.Position{Line: 1, Character: 7} // On "u" in "u.name"
+
+	rng, err := server.PrepareRename(ctx, &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     position,
+		},
+	})
+	if err != nil {
+		t.Fatalf("PrepareRename() error: %v", err)
+	}
+
+	want := protocol.Range{Start: protocol.Position{Line: 1, Character: 7}, End: protocol.Position{Line: 1, Character: 8}}
+	if rng == nil || *rng != want {
+		t.Errorf("PrepareRename() = %v, want %v", rng, want)
+	}
+
+	result, err := server.Rename(ctx, &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     position,
+		},
+		NewName: "user",
+	})
+	if err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected workspace edit")
+	}
+
+	// The pattern variable and both references, back to front
+	wantStarts := []protocol.Position{{Line: 1, Character: 23}, {Line: 1, Character: 7}, {Line: 0, Character: 23}}
+
+	edits := result.Changes[uri]
+	if len(edits) != len(wantStarts) {
+		t.Fatalf("Expected %d edits, got %+v", len(wantStarts), edits)
+	}
+
+	for i, edit := range edits {
+		if edit.Range.Start != wantStarts[i] || edit.NewText != "user" {
+			t.Errorf("edit %d = %+v, want %q at %v", i, edit, "user", wantStarts[i])
+		}
+	}
+
+	// An invalid name is rejected rather than falling back to scaf renames
+	if _, err := server.Rename(ctx, &protocol.RenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     position,
+		},
+		NewName: "1user",
+	}); err == nil {
+		t.Error("Rename() to an invalid name should fail")
+	}
+}
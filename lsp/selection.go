@@ -0,0 +1,251 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+)
+
+// MethodTextDocumentSelectionRange is the LSP 3.15 smart selection request,
+// which go.lsp.dev/protocol v0.12.0 doesn't define.
+const MethodTextDocumentSelectionRange = "textDocument/selectionRange"
+
+// SelectionRangeParams are the parameters of a textDocument/selectionRange request.
+type SelectionRangeParams struct {
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	Positions    []protocol.Position             `json:"positions"`
+}
+
+// SelectionRange is one step of a smart selection. Parent is the next larger
+// range that contains Range, or nil for the whole file.
+type SelectionRange struct {
+	Range  protocol.Range  `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+// SelectionRange handles textDocument/selectionRange requests.
+// It returns one chain per position, expanding from the token under the
+// cursor through the enclosing statement or assert, test, groups, scope or
+// function, and finally the whole file. While the document fails to parse,
+// ranges come from the last successful parse.
+func (s *Server) SelectionRange(_ context.Context, params *SelectionRangeParams) ([]SelectionRange, error) {
+	defer s.traceHandler("SelectionRange")()
+	s.logger.Debug("SelectionRange",
+		zap.String("uri", string(params.TextDocument.URI)),
+		zap.Int("positions", len(params.Positions)))
+
+	doc, ok := s.getDocument(params.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+
+	af := doc.Analysis
+	if (af == nil || af.ParseError != nil) && doc.LastValidAnalysis != nil {
+		af = doc.LastValidAnalysis
+	}
+
+	fileRange := documentRange(doc.Content)
+
+	ranges := make([]SelectionRange, len(params.Positions))
+	for i, p := range params.Positions {
+		// Outermost first; the chain is linked up from the innermost range.
+		chain := []protocol.Range{fileRange}
+		if af != nil && af.Suite != nil {
+			chain = append(chain, selectionSpans(af, doc.Content, analysis.PositionToLexer(p.Line, p.Character))...)
+		}
+
+		ranges[i] = linkSelectionRanges(chain)
+	}
+
+	return ranges, nil
+}
+
+// selectionSpans returns the ranges of the nodes enclosing pos, outermost first,
+// ending with the token under the cursor.
+func selectionSpans(af *analysis.AnalyzedFile, content string, pos lexer.Position) []protocol.Range {
+	suite := af.Suite
+
+	var spans []scaf.Span
+
+	add := func(span scaf.Span) bool {
+		if !span.Contains(pos) {
+			return false
+		}
+
+		spans = append(spans, span)
+
+		return true
+	}
+
+	for _, imp := range suite.Imports {
+		add(imp.Span())
+	}
+
+	for _, fn := range suite.Functions {
+		add(fn.Span())
+	}
+
+	if suite.Setup != nil {
+		add(suite.Setup.Span())
+	}
+
+	for _, scope := range suite.Scopes {
+		if add(scope.Span()) {
+			if scope.Setup != nil {
+				add(scope.Setup.Span())
+			}
+
+			spans = append(spans, itemSelectionSpans(scope.Items, pos)...)
+		}
+	}
+
+	if tok := selectionToken(af, content, pos); tok != nil {
+		spans = append(spans, tokenSpan(tok, content))
+	}
+
+	ranges := make([]protocol.Range, len(spans))
+	for i, span := range spans {
+		ranges[i] = spanToRange(span)
+	}
+
+	return ranges
+}
+
+// itemSelectionSpans returns the spans of the groups, test, and test body
+// entry enclosing pos, outermost first.
+func itemSelectionSpans(items []*scaf.TestOrGroup, pos lexer.Position) []scaf.Span {
+	for _, item := range items {
+		switch {
+		case item.Group != nil && item.Group.Span().Contains(pos):
+			spans := []scaf.Span{item.Group.Span()}
+			if item.Group.Setup != nil && item.Group.Setup.Span().Contains(pos) {
+				return append(spans, item.Group.Setup.Span())
+			}
+
+			return append(spans, itemSelectionSpans(item.Group.Items, pos)...)
+
+		case item.Test != nil && item.Test.Span().Contains(pos):
+			spans := []scaf.Span{item.Test.Span()}
+
+			if item.Test.Setup != nil && item.Test.Setup.Span().Contains(pos) {
+				return append(spans, item.Test.Setup.Span())
+			}
+
+			for _, stmt := range item.Test.Statements {
+				if stmt.Span().Contains(pos) {
+					return append(spans, stmt.Span())
+				}
+			}
+
+			for _, assert := range item.Test.Asserts {
+				if assert.Span().Contains(pos) {
+					return append(spans, assert.Span())
+				}
+			}
+
+			return spans
+		}
+	}
+
+	return nil
+}
+
+// selectionToken returns the token under pos, or the token ending right at
+// pos when the cursor sits just after a word.
+func selectionToken(af *analysis.AnalyzedFile, content string, pos lexer.Position) *lexer.Token {
+	if tok := analysis.TokenAtPosition(af, pos); tok != nil && tok.Type != scaf.TokenWhitespace {
+		return tok
+	}
+
+	tok := analysis.PrevTokenAtPosition(af, pos)
+	if tok == nil || !tokenSpan(tok, content).Contains(pos) {
+		return nil
+	}
+
+	return tok
+}
+
+// tokenSpan returns the span covered by tok in content. String tokens are
+// measured from the source, since the parser unquotes their values. The
+// analysis tok comes from may predate content, e.g. when it was reused across
+// a whitespace-only edit, so the string is found by tok's line and column,
+// which such edits keep, rather than its offset.
+func tokenSpan(tok *lexer.Token, content string) scaf.Span {
+	text := tok.Value
+	if tok.Type == scaf.TokenString || tok.Type == scaf.TokenRawString {
+		if quoted := quotedText(content, tokenOffset(content, tok.Pos)); quoted != "" {
+			text = quoted
+		}
+	}
+
+	end := tok.Pos
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		end.Line += strings.Count(text, "\n")
+		end.Column = len(text) - i
+	} else {
+		end.Column += len(text)
+	}
+
+	return scaf.Span{Start: tok.Pos, End: end}
+}
+
+// quotedText returns the quoted string starting at offset in content,
+// including its quotes, or "" if no string starts there.
+func quotedText(content string, offset int) string {
+	if offset < 0 || offset >= len(content) || !strings.ContainsRune("\"'`", rune(content[offset])) {
+		return ""
+	}
+
+	quote := content[offset]
+	for i := offset + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return content[offset : i+1]
+		}
+	}
+
+	return content[offset:]
+}
+
+// documentRange returns the range covering all of content.
+func documentRange(content string) protocol.Range {
+	lines := strings.Split(content, "\n")
+
+	return protocol.Range{
+		End: protocol.Position{
+			Line:      uint32(len(lines) - 1),           //nolint:gosec // G115: values are small line numbers
+			Character: uint32(len(lines[len(lines)-1])), //nolint:gosec // G115: values are small column numbers
+		},
+	}
+}
+
+// linkSelectionRanges links ranges, given outermost first, into a chain and
+// returns its innermost step. Ranges equal to their parent are dropped so each
+// expansion visibly grows the selection.
+func linkSelectionRanges(ranges []protocol.Range) SelectionRange {
+	var parent *SelectionRange
+
+	for i, r := range ranges {
+		if parent != nil && r == parent.Range {
+			continue
+		}
+
+		if i == len(ranges)-1 {
+			return SelectionRange{Range: r, Parent: parent}
+		}
+
+		parent = &SelectionRange{Range: r, Parent: parent}
+	}
+
+	return *parent
+}
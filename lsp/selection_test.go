@@ -0,0 +1,268 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+
+	"github.com/rlch/scaf/lsp"
+)
+
+const selectionContent = "fn GetUser() `MATCH (u:User {id: $id}) RETURN u`\n" +
+	"\n" +
+	"GetUser {\n" +
+	"\tgroup \"lookups\" {\n" +
+	"\t\ttest \"finds user\" {\n" +
+	"\t\t\t$id: 1\n" +
+	"\t\t\tu.name: \"Alice\"\n" +
+	"\t\t}\n" +
+	"\t}\n" +
+	"}\n"
+
+func rng(startLine, startChar, endLine, endChar uint32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: startLine, Character: startChar},
+		End:   protocol.Position{Line: endLine, Character: endChar},
+	}
+}
+
+// flattenSelection returns the ranges of a selection chain, innermost first.
+func flattenSelection(sr lsp.SelectionRange) []protocol.Range {
+	var ranges []protocol.Range
+	for r := &sr; r != nil; r = r.Parent {
+		ranges = append(ranges, r.Range)
+	}
+	return ranges
+}
+
+func TestServer_SelectionRange(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: selectionContent},
+	})
+
+	file := rng(0, 0, 10, 0)
+	scope := rng(2, 0, 9, 1)
+	group := rng(3, 1, 8, 2)
+	test := rng(4, 2, 7, 3)
+
+	tests := []struct {
+		name     string
+		position protocol.Position
+		want     []protocol.Range
+	}{
+		{
+			name:     "statement key",
+			position: protocol.Position{Line: 6, Character: 3},
+			want:     []protocol.Range{rng(6, 3, 6, 4), rng(6, 3, 6, 18), test, group, scope, file},
+		},
+		{
+			name:     "statement value",
+			position: protocol.Position{Line: 6, Character: 12},
+			want:     []protocol.Range{rng(6, 11, 6, 18), rng(6, 3, 6, 18), test, group, scope, file},
+		},
+		{
+			name:     "end of line",
+			position: protocol.Position{Line: 5, Character: 9},
+			want:     []protocol.Range{rng(5, 8, 5, 9), rng(5, 3, 5, 9), test, group, scope, file},
+		},
+		{
+			name:     "test name",
+			position: protocol.Position{Line: 4, Character: 9},
+			want:     []protocol.Range{rng(4, 7, 4, 19), test, group, scope, file},
+		},
+		{
+			name:     "function name",
+			position: protocol.Position{Line: 0, Character: 4},
+			want:     []protocol.Range{rng(0, 3, 0, 10), rng(0, 0, 0, 48), file},
+		},
+		{
+			name:     "blank line",
+			position: protocol.Position{Line: 1, Character: 0},
+			want:     []protocol.Range{file},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := server.SelectionRange(ctx, &lsp.SelectionRangeParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+				Positions:    []protocol.Position{tt.position},
+			})
+			if err != nil {
+				t.Fatalf("SelectionRange() error: %v", err)
+			}
+
+			if len(result) != 1 {
+				t.Fatalf("SelectionRange() returned %d chains, want 1", len(result))
+			}
+
+			if diff := cmp.Diff(tt.want, flattenSelection(result[0])); diff != "" {
+				t.Errorf("SelectionRange() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServer_SelectionRange_WhitespaceEdit(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: selectionContent},
+	})
+
+	// Trailing spaces move every later offset but no line or column, so the
+	// analysis is reused
+	edited := strings.Replace(selectionContent, "RETURN u`\n", "RETURN u`    \n", 1)
+	_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: edited}},
+	})
+
+	result, err := server.SelectionRange(ctx, &lsp.SelectionRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		Positions:    []protocol.Position{{Line: 6, Character: 12}},
+	})
+	if err != nil {
+		t.Fatalf("SelectionRange() error: %v", err)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("SelectionRange() returned %d chains, want 1", len(result))
+	}
+
+	if got, want := result[0].Range, rng(6, 11, 6, 18); got != want {
+		t.Errorf("SelectionRange() innermost = %+v, want %+v", got, want)
+	}
+}
+
+func TestServer_SelectionRange_MultiplePositions(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: selectionContent},
+	})
+
+	result, err := server.SelectionRange(ctx, &lsp.SelectionRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		Positions: []protocol.Position{
+			{Line: 0, Character: 4},
+			{Line: 6, Character: 3},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SelectionRange() error: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("SelectionRange() returned %d chains, want 2", len(result))
+	}
+
+	for i, want := range []protocol.Range{rng(0, 3, 0, 10), rng(6, 3, 6, 4)} {
+		if result[i].Range != want {
+			t.Errorf("chain %d starts at %v, want %v", i, result[i].Range, want)
+		}
+
+		chain := flattenSelection(result[i])
+		if outer := chain[len(chain)-1]; outer != rng(0, 0, 10, 0) {
+			t.Errorf("chain %d ends at %v, want the whole file", i, outer)
+		}
+	}
+}
+
+func TestServer_SelectionRange_UnknownDocument(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	result, err := server.SelectionRange(ctx, &lsp.SelectionRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///missing.scaf"},
+		Positions:    []protocol.Position{{Line: 0, Character: 0}},
+	})
+	if err != nil {
+		t.Fatalf("SelectionRange() error: %v", err)
+	}
+
+	if result != nil {
+		t.Errorf("SelectionRange() = %v, want nil", result)
+	}
+}
+
+func TestNewHandler_SelectionRange(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+	handler := lsp.NewHandler(server)
+
+	call := func(method string, params any) string {
+		t.Helper()
+
+		req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), method, params)
+		if err != nil {
+			t.Fatalf("NewCall() error: %v", err)
+		}
+
+		var reply []byte
+		err = handler(ctx, func(_ context.Context, result any, err error) error {
+			if err != nil {
+				return err
+			}
+			reply, err = json.Marshal(result)
+			return err
+		}, req)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+
+		return string(reply)
+	}
+
+	// The capability must be advertised alongside the standard ones
+	initReply := call(protocol.MethodInitialize, &protocol.InitializeParams{})
+	if !strings.Contains(initReply, `"selectionRangeProvider":true`) || !strings.Contains(initReply, `"hoverProvider":true`) {
+		t.Errorf("initialize reply missing capabilities: %s", initReply)
+	}
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: selectionContent},
+	})
+
+	reply := call(lsp.MethodTextDocumentSelectionRange, &lsp.SelectionRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		Positions:    []protocol.Position{{Line: 0, Character: 4}},
+	})
+	if !strings.Contains(reply, `"parent":{"range"`) {
+		t.Errorf("selectionRange reply missing parent chain: %s", reply)
+	}
+}
@@ -0,0 +1,294 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+
+	"github.com/rlch/scaf"
+)
+
+// Semantic token type indices into SemanticTokenLegend.TokenTypes.
+const (
+	semanticKeyword uint32 = iota
+	semanticFunction
+	semanticParameter
+	semanticString
+	semanticProperty
+	semanticComment
+)
+
+// SemanticTokenLegend is the legend advertised on initialize. Token types in
+// SemanticTokens results index into TokenTypes; no modifiers are used.
+var SemanticTokenLegend = protocol.SemanticTokensLegend{
+	TokenTypes: []protocol.SemanticTokenTypes{
+		protocol.SemanticTokenKeyword,
+		protocol.SemanticTokenFunction,
+		protocol.SemanticTokenParameter,
+		protocol.SemanticTokenString,
+		protocol.SemanticTokenProperty,
+		protocol.SemanticTokenComment,
+	},
+	TokenModifiers: []protocol.SemanticTokenModifiers{},
+}
+
+// SemanticTokensOptions is the semanticTokensProvider capability.
+// go.lsp.dev/protocol v0.12.0's SemanticTokensOptions lacks legend and full.
+type SemanticTokensOptions struct {
+	Legend protocol.SemanticTokensLegend `json:"legend"`
+	Full   bool                          `json:"full"`
+}
+
+// SemanticTokensFull handles textDocument/semanticTokens/full.
+func (s *Server) SemanticTokensFull(ctx context.Context, params *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
+	return s.SemanticTokens(ctx, params)
+}
+
+// SemanticTokens returns delta-encoded semantic tokens for a document.
+// Positions come from the document's token stream; the parsed suite decides
+// which identifiers are query names and return fields. While the document
+// fails to parse, identifiers are classified using the last successful parse,
+// carried over to the tokens before and after the edit that broke it.
+func (s *Server) SemanticTokens(_ context.Context, params *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
+	defer s.traceHandler("SemanticTokens")()
+	s.logger.Debug("SemanticTokens",
+		zap.String("uri", string(params.TextDocument.URI)))
+
+	doc, ok := s.getDocument(params.TextDocument.URI)
+	if !ok {
+		return nil, nil //nolint:nilnil // LSP returns null for unknown documents
+	}
+
+	tokens := documentTokens(doc)
+
+	var idents map[int]uint32
+
+	switch af, last := doc.Analysis, doc.LastValidAnalysis; {
+	case af != nil && af.ParseError == nil && af.Suite != nil:
+		idents = classifyIdents(af.Suite)
+	case last != nil && last.Suite != nil:
+		idents = mapIdents(classifyIdents(last.Suite), last.Tokens, tokens)
+	}
+
+	enc := &semanticEncoder{}
+
+	for _, tok := range tokens {
+		typ, ok := semanticTokenType(tok, idents)
+		if ok {
+			enc.add(tok, typ)
+		}
+	}
+
+	return &protocol.SemanticTokens{Data: enc.data}, nil
+}
+
+// documentTokens returns the document's tokens, without whitespace. They are
+// cached on the current analysis and stop at the first lexer error, so tokens
+// before bad input are still highlighted. A document that hasn't been
+// analyzed is lexed here instead.
+func documentTokens(doc *Document) []lexer.Token {
+	if doc.Analysis != nil {
+		return doc.Analysis.Tokens
+	}
+
+	tokens, _ := significantTokens(doc.Content)
+
+	return tokens
+}
+
+// mapIdents moves identifier classifications from the offsets of one token
+// stream to another, for the runs of identical tokens the two start and end
+// with. Tokens in between, where the streams differ, are left unclassified.
+func mapIdents(idents map[int]uint32, from, to []lexer.Token) map[int]uint32 {
+	mapped := make(map[int]uint32)
+
+	carry := func(f, t lexer.Token) {
+		if typ, ok := idents[f.Pos.Offset]; ok {
+			mapped[t.Pos.Offset] = typ
+		}
+	}
+
+	n := min(len(from), len(to))
+
+	prefix := 0
+	for ; prefix < n && sameToken(from[prefix], to[prefix]); prefix++ {
+		carry(from[prefix], to[prefix])
+	}
+
+	for i := 1; i <= n-prefix; i++ {
+		f, t := from[len(from)-i], to[len(to)-i]
+		if !sameToken(f, t) {
+			break
+		}
+
+		carry(f, t)
+	}
+
+	return mapped
+}
+
+func sameToken(a, b lexer.Token) bool {
+	return a.Type == b.Type && a.Value == b.Value
+}
+
+// semanticTokenType classifies a lexer token. idents maps byte offsets of
+// identifiers the AST gives meaning to (query names, return fields).
+func semanticTokenType(tok lexer.Token, idents map[int]uint32) (uint32, bool) {
+	switch tok.Type {
+	case scaf.TokenFn, scaf.TokenImport, scaf.TokenSetup, scaf.TokenTeardown,
+		scaf.TokenTest, scaf.TokenGroup, scaf.TokenAssert, scaf.TokenWhere:
+		return semanticKeyword, true
+	case scaf.TokenComment, scaf.TokenAnnotation:
+		return semanticComment, true
+	case scaf.TokenRawString, scaf.TokenString:
+		return semanticString, true
+	case scaf.TokenIdent:
+		if strings.HasPrefix(tok.Value, "$") {
+			return semanticParameter, true
+		}
+
+		typ, ok := idents[tok.Pos.Offset]
+
+		return typ, ok
+	}
+
+	return 0, false
+}
+
+// classifyIdents records the offsets of query names at definition and
+// reference sites, and of return field names in test statements.
+func classifyIdents(suite *scaf.Suite) map[int]uint32 {
+	idents := make(map[int]uint32)
+
+	for _, fn := range suite.Functions {
+		if tok := nthIdent(fn.Tokens, 0); tok != nil {
+			idents[tok.Pos.Offset] = semanticFunction
+		}
+	}
+
+	for _, scope := range suite.Scopes {
+		if tok := nthIdent(scope.Tokens, 0); tok != nil && tok.Value == scope.FunctionName {
+			idents[tok.Pos.Offset] = semanticFunction
+		}
+
+		classifySetup(idents, scope.Setup)
+		classifyItems(idents, scope.Items)
+	}
+
+	classifySetup(idents, suite.Setup)
+
+	return idents
+}
+
+func classifyItems(idents map[int]uint32, items []*scaf.TestOrGroup) {
+	for _, item := range items {
+		switch {
+		case item.Group != nil:
+			classifySetup(idents, item.Group.Setup)
+			classifyItems(idents, item.Group.Items)
+
+		case item.Test != nil:
+			classifySetup(idents, item.Test.Setup)
+
+			for _, stmt := range item.Test.Statements {
+				if stmt.KeyParts == nil || strings.HasPrefix(stmt.Key(), "$") {
+					continue
+				}
+
+				for i := range stmt.KeyParts.Tokens {
+					if tok := &stmt.KeyParts.Tokens[i]; tok.Type == scaf.TokenIdent {
+						idents[tok.Pos.Offset] = semanticProperty
+					}
+				}
+			}
+
+			for _, a := range item.Test.Asserts {
+				if a.Query != nil && a.Query.QueryName != nil {
+					if tok := nthIdent(a.Query.Tokens, 0); tok != nil {
+						idents[tok.Pos.Offset] = semanticFunction
+					}
+				}
+			}
+		}
+	}
+}
+
+func classifySetup(idents map[int]uint32, setup *scaf.SetupClause) {
+	if setup == nil {
+		return
+	}
+
+	calls := []*scaf.SetupCall{setup.Call}
+	for _, item := range setup.Block {
+		calls = append(calls, item.Call)
+	}
+
+	for _, call := range calls {
+		if call == nil {
+			continue
+		}
+
+		// module.Query(...): the second identifier is the query name.
+		if tok := nthIdent(call.Tokens, 1); tok != nil {
+			idents[tok.Pos.Offset] = semanticFunction
+		}
+	}
+}
+
+// nthIdent returns the n-th (0-based) identifier token, or nil.
+func nthIdent(tokens []lexer.Token, n int) *lexer.Token {
+	for i := range tokens {
+		if tokens[i].Type != scaf.TokenIdent {
+			continue
+		}
+
+		if n == 0 {
+			return &tokens[i]
+		}
+
+		n--
+	}
+
+	return nil
+}
+
+// semanticEncoder builds the relative encoding of semantic tokens:
+// deltaLine, deltaStartChar, length, tokenType, tokenModifiers.
+type semanticEncoder struct {
+	data     []uint32
+	prevLine uint32
+	prevChar uint32
+}
+
+// add encodes tok, splitting tokens that span lines (query bodies) into one
+// token per line since clients aren't required to support multiline tokens.
+func (e *semanticEncoder) add(tok lexer.Token, typ uint32) {
+	line := uint32(tok.Pos.Line - 1)   //nolint:gosec // G115: values are small line numbers
+	char := uint32(tok.Pos.Column - 1) //nolint:gosec // G115: values are small column numbers
+
+	for i, part := range strings.Split(tok.Value, "\n") {
+		if i > 0 {
+			line++
+			char = 0
+		}
+
+		length := uint32(utf8.RuneCountInString(part)) //nolint:gosec // G115: token lengths are small
+		if length == 0 {
+			continue
+		}
+
+		deltaLine := line - e.prevLine
+
+		deltaChar := char
+		if deltaLine == 0 {
+			deltaChar = char - e.prevChar
+		}
+
+		e.data = append(e.data, deltaLine, deltaChar, length, typ, 0)
+		e.prevLine, e.prevChar = line, char
+	}
+}
@@ -0,0 +1,168 @@
+package lsp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.lsp.dev/protocol"
+
+	"github.com/rlch/scaf/lsp"
+)
+
+// semanticToken is a decoded semantic token with absolute position.
+type semanticToken struct {
+	Line, Char, Length uint32
+	Type               protocol.SemanticTokenTypes
+}
+
+func decodeSemanticTokens(data []uint32) []semanticToken {
+	var (
+		tokens     []semanticToken
+		line, char uint32
+	)
+
+	for i := 0; i+4 < len(data); i += 5 {
+		if data[i] > 0 {
+			line += data[i]
+			char = data[i+1]
+		} else {
+			char += data[i+1]
+		}
+
+		tokens = append(tokens, semanticToken{
+			Line:   line,
+			Char:   char,
+			Length: data[i+2],
+			Type:   lsp.SemanticTokenLegend.TokenTypes[data[i+3]],
+		})
+	}
+
+	return tokens
+}
+
+func TestServer_SemanticTokens(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := "// users\n" +
+		"fn GetUser() `MATCH (u:User {id: $id})\nRETURN u`\n" +
+		"\n" +
+		"GetUser {\n" +
+		"\ttest \"finds\" {\n" +
+		"\t\t$id: 1\n" +
+		"\t\tu.name: \"Alice\"\n" +
+		"\t\tassert GetUser($id: 1) { (u != null) }\n" +
+		"\t}\n" +
+		"}\n"
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: content},
+	})
+
+	result, err := server.SemanticTokens(ctx, &protocol.SemanticTokensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+	})
+	if err != nil {
+		t.Fatalf("SemanticTokens() error: %v", err)
+	}
+
+	want := []semanticToken{
+		{0, 0, 8, protocol.SemanticTokenComment},
+		{1, 0, 2, protocol.SemanticTokenKeyword},
+		{1, 3, 7, protocol.SemanticTokenFunction},
+		{1, 13, 25, protocol.SemanticTokenString},
+		{2, 0, 9, protocol.SemanticTokenString},
+		{4, 0, 7, protocol.SemanticTokenFunction},
+		{5, 1, 4, protocol.SemanticTokenKeyword},
+		{5, 6, 7, protocol.SemanticTokenString},
+		{6, 2, 3, protocol.SemanticTokenParameter},
+		{7, 2, 1, protocol.SemanticTokenProperty},
+		{7, 4, 4, protocol.SemanticTokenProperty},
+		{7, 10, 7, protocol.SemanticTokenString},
+		{8, 2, 6, protocol.SemanticTokenKeyword},
+		{8, 9, 7, protocol.SemanticTokenFunction},
+		{8, 17, 3, protocol.SemanticTokenParameter},
+	}
+
+	if diff := cmp.Diff(want, decodeSemanticTokens(result.Data)); diff != "" {
+		t.Errorf("SemanticTokens() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServer_SemanticTokens_ParseError(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := "fn GetUser() `MATCH (u:User) RETURN u`\n" +
+		"GetUser {\n" +
+		"\ttest \"finds\" {\n" +
+		"\t\tu.name: \"Alice\"\n" +
+		"\t}\n" +
+		"}\n"
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: content},
+	})
+
+	// An unfinished declaration shifts everything after it
+	broken := "fn (\n" + content
+	_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: broken}},
+	})
+
+	result, err := server.SemanticTokens(ctx, &protocol.SemanticTokensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+	})
+	if err != nil {
+		t.Fatalf("SemanticTokens() error: %v", err)
+	}
+
+	want := []semanticToken{
+		{0, 0, 2, protocol.SemanticTokenKeyword},
+		{1, 0, 2, protocol.SemanticTokenKeyword},
+		{1, 3, 7, protocol.SemanticTokenFunction},
+		{1, 13, 25, protocol.SemanticTokenString},
+		{2, 0, 7, protocol.SemanticTokenFunction},
+		{3, 1, 4, protocol.SemanticTokenKeyword},
+		{3, 6, 7, protocol.SemanticTokenString},
+		{4, 2, 1, protocol.SemanticTokenProperty},
+		{4, 4, 4, protocol.SemanticTokenProperty},
+		{4, 10, 7, protocol.SemanticTokenString},
+	}
+
+	if diff := cmp.Diff(want, decodeSemanticTokens(result.Data)); diff != "" {
+		t.Errorf("SemanticTokens() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestServer_SemanticTokens_UnknownDocument(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+
+	result, err := server.SemanticTokens(context.Background(), &protocol.SemanticTokensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///missing.scaf"},
+	})
+	if err != nil {
+		t.Fatalf("SemanticTokens() error: %v", err)
+	}
+
+	if result != nil {
+		t.Errorf("SemanticTokens() = %v, want nil", result)
+	}
+}
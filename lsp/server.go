@@ -3,6 +3,8 @@ package lsp
 
 import (
 	"context"
+	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -35,10 +37,45 @@ type Server struct {
 	// Schema for LSP features (labels, properties, etc.)
 	schema *analysis.TypeSchema
 
+	// initialSchema is the schema given at construction, merged beneath any
+	// schema the workspace config names. May be nil.
+	initialSchema *analysis.TypeSchema
+
+	// schemaURL is the workspace config's schema URL, if the schema is fetched
+	// and cached rather than read from a file.
+	schemaURL string
+
+	// config is the workspace .scaf.yaml, or nil without one. Guarded by mu.
+	config *scaf.Config
+
 	// Server state
 	initialized   bool
-	shutdown      bool
 	workspaceRoot string
+
+	// shutdownReceived is set by Shutdown; afterwards only exit is accepted.
+	// Guarded by mu.
+	shutdownReceived bool
+
+	// exit ends the process on the exit notification (os.Exit outside tests).
+	exit func(code int)
+
+	// codeLensRefresh is set when the client supports workspace/codeLens/refresh.
+	codeLensRefresh bool
+
+	// watchConfig is set when the client can register file watchers, so
+	// .scaf.yaml edits are reported through workspace/didChangeWatchedFiles.
+	watchConfig bool
+
+	// analysisDelay is how long DidChange waits for further edits before re-analyzing.
+	analysisDelay time.Duration
+
+	// analysisEpoch counts workspace-wide re-analyses, such as after a config
+	// change, and is part of pulled diagnostic result IDs. Guarded by mu.
+	analysisEpoch int
+
+	// formatOnSave is the scaf.formatOnSave workspace setting, on unless the
+	// client turns it off. Guarded by mu.
+	formatOnSave bool
 }
 
 // Document represents an open document in the server.
@@ -51,12 +88,27 @@ type Document struct {
 	// LastValidAnalysis holds the most recent analysis that parsed successfully.
 	// Used for completion when the current document has parse errors.
 	LastValidAnalysis *analysis.AnalyzedFile
+
+	// lastAnalysisVersion is the document version Analysis reflects.
+	lastAnalysisVersion int32
+	// analyzedContent is the content Analysis was computed from.
+	analyzedContent string
+	// analyzedHash is scaf.FileHash of analyzedContent.
+	analyzedHash string
+	// analysisTimer is the pending debounced re-analysis, if any.
+	analysisTimer *time.Timer
 }
 
 // NewServer creates a new LSP server.
 // dialectName specifies the query dialect (e.g., "cypher", "sql") for completion/hover.
 // If empty, defaults to "cypher".
 func NewServer(client protocol.Client, logger *zap.Logger, dialectName string) *Server {
+	return NewServerWithSchema(client, logger, dialectName, nil)
+}
+
+// NewServerWithSchema creates a new LSP server that starts with schema, which
+// may be nil. A schema named by the workspace's .scaf.yaml is merged over it.
+func NewServerWithSchema(client protocol.Client, logger *zap.Logger, dialectName string, schema *analysis.TypeSchema) *Server {
 	fileLoader := NewLSPFileLoader(logger, "")
 	resolver := NewLSPCrossFileResolver(fileLoader)
 
@@ -73,14 +125,23 @@ func NewServer(client protocol.Client, logger *zap.Logger, dialectName string) *
 			zap.Strings("available", scaf.RegisteredAnalyzers()))
 	}
 
+	analyzer := analysis.NewAnalyzerWithQueryAnalyzer(fileLoader, resolver, queryAnalyzer)
+	analyzer.SetParseOptions(parseLimits)
+	analyzer.SetSchema(schema)
+
 	return &Server{
 		client:        client,
 		logger:        logger,
 		documents:     make(map[protocol.DocumentURI]*Document),
-		analyzer:      analysis.NewAnalyzerWithQueryAnalyzer(fileLoader, resolver, queryAnalyzer),
+		analyzer:      analyzer,
 		fileLoader:    fileLoader,
 		dialectName:   dialectName,
 		queryAnalyzer: queryAnalyzer,
+		schema:        schema,
+		initialSchema: schema,
+		analysisDelay: analysisDebounce,
+		exit:          os.Exit,
+		formatOnSave:  true,
 	}
 }
 
@@ -99,8 +160,22 @@ func (s *Server) Initialize(_ context.Context, params *protocol.InitializeParams
 		s.logger.Info("Workspace root (from RootPath)", zap.String("root", s.workspaceRoot))
 	}
 
-	// Load schema if available
-	s.loadSchema()
+	// Load config (schema and rule overrides) if available
+	s.applyConfig(s.readConfig())
+
+	if formatOnSave, ok := formatOnSaveSetting(params.InitializationOptions); ok {
+		s.formatOnSave = formatOnSave
+	}
+
+	if ws := params.Capabilities.Workspace; ws != nil {
+		if ws.CodeLens != nil {
+			s.codeLensRefresh = ws.CodeLens.RefreshSupport
+		}
+
+		if ws.DidChangeWatchedFiles != nil {
+			s.watchConfig = ws.DidChangeWatchedFiles.DynamicRegistration
+		}
+	}
 
 	return &protocol.InitializeResult{
 		Capabilities: protocol.ServerCapabilities{
@@ -108,11 +183,15 @@ func (s *Server) Initialize(_ context.Context, params *protocol.InitializeParams
 			TextDocumentSync: &protocol.TextDocumentSyncOptions{
 				OpenClose: true,
 				Change:    protocol.TextDocumentSyncKindFull,
+				// Format on save
+				WillSaveWaitUntil: true,
 			},
 			// Hover support
 			HoverProvider: true,
 			// Go to definition
 			DefinitionProvider: true,
+			// Go to type definition (the fn declaration of a query)
+			TypeDefinitionProvider: true,
 			// Completion support
 			CompletionProvider: &protocol.CompletionOptions{
 				TriggerCharacters: []string{"$", ".", ":"},
@@ -153,6 +232,15 @@ func (s *Server) Initialize(_ context.Context, params *protocol.InitializeParams
 			CodeLensProvider: &protocol.CodeLensOptions{
 				ResolveProvider: false,
 			},
+			// Commands triggered from code lenses
+			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+				Commands: []string{CommandRunScope},
+			},
+			// Semantic highlighting
+			SemanticTokensProvider: &SemanticTokensOptions{
+				Legend: SemanticTokenLegend,
+				Full:   true,
+			},
 			// Note: InlayHintProvider requires LSP 3.17+ protocol types
 			// not available in go.lsp.dev/protocol v0.12.0
 		},
@@ -164,28 +252,68 @@ func (s *Server) Initialize(_ context.Context, params *protocol.InitializeParams
 }
 
 // Initialized handles the initialized notification.
-func (s *Server) Initialized(_ context.Context, _ *protocol.InitializedParams) error {
+func (s *Server) Initialized(ctx context.Context, _ *protocol.InitializedParams) error {
 	s.logger.Info("Initialized")
 	s.initialized = true
 
+	s.registerConfigWatcher(ctx)
+
 	return nil
 }
 
-// Shutdown handles the shutdown request.
+// Shutdown handles the shutdown request. Pending debounced analyses are
+// cancelled and cached imports dropped; from then on the handler rejects
+// every request but exit.
 func (s *Server) Shutdown(_ context.Context) error {
 	s.logger.Info("Shutdown")
-	s.shutdown = true
+
+	s.mu.Lock()
+	s.shutdownReceived = true
+	for _, doc := range s.documents {
+		doc.stopAnalysisTimer()
+	}
+	s.mu.Unlock()
+
+	s.fileLoader.InvalidateAll()
 
 	return nil
 }
 
-// Exit handles the exit notification.
+// Exit handles the exit notification, ending the process with status 0 after
+// a shutdown request and 1 without one, as the LSP specification requires.
 func (s *Server) Exit(_ context.Context) error {
 	s.logger.Info("Exit")
-	// The main loop should handle exiting after this
+
+	code := 1
+	if s.ShutdownReceived() {
+		code = 0
+	}
+
+	s.exit(code)
+
 	return nil
 }
 
+// ShutdownReceived reports whether the client has sent the shutdown request.
+func (s *Server) ShutdownReceived() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.shutdownReceived
+}
+
+// SetMaxFileCache sets how many imported files the server caches; 0 means
+// unlimited. It defaults to DefaultMaxFileCache.
+func (s *Server) SetMaxFileCache(n int) {
+	s.fileLoader.SetMaxEntries(n)
+}
+
+// SetExitForTesting replaces the function Exit calls to end the process.
+// This should only be used in tests.
+func (s *Server) SetExitForTesting(exit func(code int)) {
+	s.exit = exit
+}
+
 // DidOpen handles textDocument/didOpen notifications.
 func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
 	s.logger.Info("DidOpen", zap.String("uri", string(params.TextDocument.URI)))
@@ -197,14 +325,7 @@ func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocume
 	}
 
 	// Analyze the document
-	// Use the file system path (not URI) for proper import resolution
-	docPath := URIToPath(params.TextDocument.URI)
-	doc.Analysis = s.analyzer.Analyze(docPath, []byte(params.TextDocument.Text))
-
-	// If parsing succeeded, save as last valid analysis for completion fallback
-	if doc.Analysis.ParseError == nil {
-		doc.LastValidAnalysis = doc.Analysis
-	}
+	s.analyzeDocument(doc)
 
 	// Hold lock only for document map update
 	s.mu.Lock()
@@ -218,60 +339,50 @@ func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocume
 }
 
 // DidChange handles textDocument/didChange notifications.
+// Re-analysis is debounced so a burst of keystrokes is analyzed once, and
+// skipped entirely when the content is unchanged or the edit only touched
+// whitespace.
 func (s *Server) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
-	start := time.Now()
-	s.logger.Info("DidChange START",
+	s.logger.Info("DidChange",
 		zap.String("uri", string(params.TextDocument.URI)),
 		zap.Int32("version", params.TextDocument.Version))
 
-	// Hold the lock only for document state updates, not for RPC calls.
-	// This prevents deadlock when client sends requests while we're publishing diagnostics.
-	var docForDiagnostics *Document
-
 	s.mu.Lock()
-	s.logger.Debug("DidChange: acquired lock", zap.Duration("elapsed", time.Since(start)))
+	defer s.mu.Unlock()
+
 	doc, ok := s.documents[params.TextDocument.URI]
 	if !ok {
-		s.mu.Unlock()
 		s.logger.Warn("DidChange for unknown document", zap.String("uri", string(params.TextDocument.URI)))
 
 		return nil
 	}
 
 	// Full sync - take the last content change (should only be one with full sync)
-	if len(params.ContentChanges) > 0 {
-		doc.Content = params.ContentChanges[len(params.ContentChanges)-1].Text
-		doc.Version = params.TextDocument.Version
-
-		// Re-analyze (use file system path for proper import resolution)
-		analyzeStart := time.Now()
-		docPath := URIToPath(params.TextDocument.URI)
-		doc.Analysis = s.analyzer.Analyze(docPath, []byte(doc.Content))
-		s.logger.Debug("DidChange: analysis complete",
-			zap.Duration("analyzeTime", time.Since(analyzeStart)),
-			zap.Bool("hasParseError", doc.Analysis.ParseError != nil))
-
-		// If parsing succeeded, save as last valid analysis for completion fallback
-		if doc.Analysis.ParseError == nil {
-			doc.LastValidAnalysis = doc.Analysis
+	if len(params.ContentChanges) == 0 {
+		return nil
+	}
+
+	doc.Content = params.ContentChanges[len(params.ContentChanges)-1].Text
+	doc.Version = params.TextDocument.Version
+
+	// Hash the new content to skip analysis when it is unchanged
+	hash := scaf.FileHash([]byte(doc.Content))
+
+	if doc.Analysis != nil && s.reuseAnalysis(doc, hash) {
+		doc.analyzedContent = doc.Content
+		doc.analyzedHash = hash
+		doc.lastAnalysisVersion = doc.Version
+
+		// An earlier edit may still be waiting to publish its diagnostics.
+		if doc.analysisTimer != nil {
+			s.scheduleAnalysis(context.WithoutCancel(ctx), doc)
 		}
 
-		docForDiagnostics = doc
-	}
-	s.mu.Unlock()
-	s.logger.Debug("DidChange: released lock", zap.Duration("elapsed", time.Since(start)))
-
-	// Publish diagnostics outside the lock to prevent deadlock.
-	// The client may send requests (e.g., completion) while we're publishing.
-	if docForDiagnostics != nil {
-		diagStart := time.Now()
-		s.publishDiagnostics(ctx, docForDiagnostics)
-		s.logger.Debug("DidChange: diagnostics published",
-			zap.Duration("diagTime", time.Since(diagStart)),
-			zap.Duration("totalTime", time.Since(start)))
+		return nil
 	}
 
-	s.logger.Info("DidChange END", zap.Duration("elapsed", time.Since(start)))
+	s.scheduleAnalysis(context.WithoutCancel(ctx), doc)
+
 	return nil
 }
 
@@ -281,6 +392,9 @@ func (s *Server) DidClose(ctx context.Context, params *protocol.DidCloseTextDocu
 
 	// Hold lock only for document map update
 	s.mu.Lock()
+	if doc, ok := s.documents[params.TextDocument.URI]; ok {
+		doc.stopAnalysisTimer()
+	}
 	delete(s.documents, params.TextDocument.URI)
 	s.mu.Unlock()
 
@@ -303,61 +417,114 @@ func (s *Server) DidSave(_ context.Context, params *protocol.DidSaveTextDocument
 	return nil
 }
 
-// getDocument returns a document by URI (read-locked).
+// getDocument returns a copy of a document by URI, taken under the lock so
+// later edits don't race with the caller's reads.
+// A document with a pending debounced analysis is analyzed first, so requests
+// always see the latest content.
 func (s *Server) getDocument(uri protocol.DocumentURI) (*Document, bool) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	doc, ok := s.documents[uri]
+	if !ok {
+		s.mu.RUnlock()
 
-	return doc, ok
-}
+		return nil, false
+	}
 
-// loadSchema loads the TypeSchema from the workspace configuration.
-// It looks for .scaf.yaml config and loads the schema file if specified.
-func (s *Server) loadSchema() {
-	if s.workspaceRoot == "" {
-		s.logger.Debug("No workspace root, skipping schema load")
-		return
+	if doc.lastAnalysisVersion == doc.Version {
+		snapshot := *doc
+		s.mu.RUnlock()
+
+		return &snapshot, true
 	}
+	s.mu.RUnlock()
 
-	// Try to load config from workspace root
-	cfg, err := scaf.LoadConfig(s.workspaceRoot)
-	if err != nil {
-		s.logger.Debug("No .scaf.yaml config found, skipping schema load",
-			zap.String("root", s.workspaceRoot),
-			zap.Error(err))
-		return
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc.lastAnalysisVersion != doc.Version {
+		s.analyzeDocument(doc)
 	}
 
-	// Check if schema path is configured
-	schemaPath := cfg.Generate.Schema
-	if schemaPath == "" {
+	snapshot := *doc
+
+	return &snapshot, true
+}
+
+// loadSchema loads the TypeSchema named by cfg, returning nil if it names
+// none or it fails to load, along with the path or URL it names. A schema URL
+// is fetched and cached in the workspace root.
+func (s *Server) loadSchema(cfg *scaf.Config) (*analysis.TypeSchema, string) {
+	source := cfg.Generate.Schema
+	if cfg.SchemaURL != "" {
+		source = cfg.SchemaURL
+	}
+
+	if source == "" {
 		s.logger.Debug("No schema path in config")
-		return
+		return nil, ""
 	}
 
 	// Load the schema
-	schema, err := analysis.LoadSchema(schemaPath, s.workspaceRoot)
+	schema, err := analysis.LoadConfigSchema(cfg, s.workspaceRoot)
 	if err != nil {
 		s.logger.Warn("Failed to load schema",
-			zap.String("path", schemaPath),
+			zap.String("path", source),
 			zap.Error(err))
-		return
+		return nil, source
 	}
 
+	return schema, source
+}
+
+// applySchema merges schema over the initial schema and uses the result for
+// analysis. source names where it was loaded from, for logging.
+func (s *Server) applySchema(schema *analysis.TypeSchema, source string) {
 	if schema == nil {
 		s.logger.Debug("Schema loaded but is nil")
 		return
 	}
 
+	schema = s.mergeInitialSchema(schema)
+
 	s.schema = schema
 	s.analyzer.SetSchema(schema)
 	s.logger.Info("Schema loaded successfully",
-		zap.String("path", schemaPath),
+		zap.String("path", source),
 		zap.Int("models", len(schema.Models)))
 }
 
+// mergeInitialSchema returns the initial schema with workspace merged over it,
+// leaving both unchanged. Fields defined by both take the workspace's type.
+func (s *Server) mergeInitialSchema(workspace *analysis.TypeSchema) *analysis.TypeSchema {
+	if s.initialSchema == nil {
+		return workspace
+	}
+
+	merged := analysis.NewTypeSchema()
+	_ = merged.Merge(s.initialSchema) // An empty schema can't conflict
+
+	// Drop initial fields the workspace redefines so Merge has no conflicts.
+	for name, model := range workspace.Models {
+		if base := merged.Models[name]; base != nil && model != nil {
+			trimmed := *base
+			trimmed.Fields = slices.DeleteFunc(slices.Clone(base.Fields), func(f *analysis.Field) bool {
+				return f != nil && slices.ContainsFunc(model.Fields, func(wf *analysis.Field) bool {
+					return wf != nil && wf.Name == f.Name
+				})
+			})
+			merged.Models[name] = &trimmed
+		}
+	}
+
+	if err := merged.Merge(workspace); err != nil {
+		s.logger.Warn("Failed to merge workspace schema over initial schema", zap.Error(err))
+
+		return workspace
+	}
+
+	return merged
+}
+
 // getSchema returns the loaded TypeSchema (may be nil).
 func (s *Server) getSchema() *analysis.TypeSchema {
 	return s.schema
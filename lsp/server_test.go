@@ -2,8 +2,17 @@ package lsp_test
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
 
@@ -17,21 +26,76 @@ import (
 // mockClient implements protocol.Client for testing.
 type mockClient struct {
 	diagnostics []protocol.PublishDiagnosticsParams
+	progress    []protocol.ProgressParams
+	messages    []protocol.ShowMessageParams
+
+	codeLensRefreshes   atomic.Int32
+	diagnosticRefreshes atomic.Int32
+	registrations       atomic.Int32
+
+	// diagMu guards diagnostics, which debounced analysis publishes from a timer.
+	diagMu sync.Mutex
 }
 
 func (m *mockClient) PublishDiagnostics(_ context.Context, params *protocol.PublishDiagnosticsParams) error {
+	m.diagMu.Lock()
+	defer m.diagMu.Unlock()
+
 	m.diagnostics = append(m.diagnostics, *params)
 
 	return nil
 }
 
-// Stub out remaining Client interface methods.
-func (m *mockClient) Progress(context.Context, *protocol.ProgressParams) error { return nil }
+// waitForDiagnostics waits until at least n diagnostics notifications have been
+// published, returning them. Diagnostics after a change are published once the
+// debounced analysis runs.
+func (m *mockClient) waitForDiagnostics(t *testing.T, n int) []protocol.PublishDiagnosticsParams {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.diagMu.Lock()
+		diagnostics := append([]protocol.PublishDiagnosticsParams(nil), m.diagnostics...)
+		m.diagMu.Unlock()
+
+		if len(diagnostics) >= n || time.Now().After(deadline) {
+			return diagnostics
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (m *mockClient) Progress(_ context.Context, params *protocol.ProgressParams) error {
+	m.progress = append(m.progress, *params)
+
+	return nil
+}
 
+func (m *mockClient) ShowMessage(_ context.Context, params *protocol.ShowMessageParams) error {
+	m.messages = append(m.messages, *params)
+
+	return nil
+}
+
+// CodeLensRefresh records workspace/codeLens/refresh requests.
+func (m *mockClient) CodeLensRefresh(context.Context) error {
+	m.codeLensRefreshes.Add(1)
+
+	return nil
+}
+
+// DiagnosticRefresh records workspace/diagnostic/refresh requests.
+func (m *mockClient) DiagnosticRefresh(context.Context) error {
+	m.diagnosticRefreshes.Add(1)
+
+	return nil
+}
+
+// Stub out remaining Client interface methods.
 func (m *mockClient) WorkDoneProgressCreate(context.Context, *protocol.WorkDoneProgressCreateParams) error {
 	return nil
 }
-func (m *mockClient) ShowMessage(context.Context, *protocol.ShowMessageParams) error { return nil }
 func (m *mockClient) ShowMessageRequest(
 	context.Context, *protocol.ShowMessageRequestParams,
 ) (*protocol.MessageActionItem, error) {
@@ -40,6 +104,8 @@ func (m *mockClient) ShowMessageRequest(
 func (m *mockClient) LogMessage(context.Context, *protocol.LogMessageParams) error { return nil }
 func (m *mockClient) Telemetry(context.Context, any) error                         { return nil }
 func (m *mockClient) RegisterCapability(context.Context, *protocol.RegistrationParams) error {
+	m.registrations.Add(1)
+
 	return nil
 }
 
@@ -229,6 +295,52 @@ UndefinedQuery {
 	}
 }
 
+func TestServer_DidOpen_RelatedInformation(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	err := server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text: `fn GetUser() ` + "`Q`" + `
+fn GetUser() ` + "`Q`" + `
+`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("DidOpen() error: %v", err)
+	}
+
+	if len(client.diagnostics) == 0 {
+		t.Fatal("Expected diagnostics to be published")
+	}
+
+	for _, d := range client.diagnostics[0].Diagnostics {
+		if d.Code != "duplicate-query" {
+			continue
+		}
+
+		if len(d.RelatedInformation) != 1 {
+			t.Fatalf("Expected 1 related location, got %d", len(d.RelatedInformation))
+		}
+
+		related := d.RelatedInformation[0]
+		if related.Location.URI != "file:///test.scaf" || related.Location.Range.Start.Line != 0 {
+			t.Errorf("Expected related location at line 0 of the same file, got %+v", related.Location)
+		}
+
+		return
+	}
+
+	t.Errorf("Expected duplicate-query diagnostic, got: %v", client.diagnostics[0].Diagnostics)
+}
+
 func TestServer_DidChange(t *testing.T) {
 	t.Parallel()
 
@@ -268,17 +380,236 @@ Q { test "t" {} }
 	}
 
 	// Should have new diagnostics.
-	if len(client.diagnostics) <= initialDiagCount {
-		t.Error("Expected new diagnostics after change")
+	diagnostics := client.waitForDiagnostics(t, initialDiagCount+1)
+	if len(diagnostics) <= initialDiagCount {
+		t.Fatal("Expected new diagnostics after change")
 	}
 
 	// Latest diagnostics should have errors.
-	latestDiag := client.diagnostics[len(client.diagnostics)-1]
+	latestDiag := diagnostics[len(diagnostics)-1]
 	if len(latestDiag.Diagnostics) == 0 {
 		t.Error("Expected parse error after invalid change")
 	}
 }
 
+func TestServer_DidChange_Debounced(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	uri := protocol.DocumentURI("file:///test.scaf")
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: "fn Q() `Q`\n"},
+	})
+
+	initialDiagCount := len(client.waitForDiagnostics(t, 1))
+
+	// A burst of edits, as when typing.
+	for i, text := range []string{"fn Q() `Q`\nf", "fn Q() `Q`\nfn", "fn Q() `Q`\nfn R() `R`\n"} {
+		_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+				Version:                int32(i + 2), //nolint:gosec // small loop index
+			},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: text}},
+		})
+	}
+
+	diagnostics := client.waitForDiagnostics(t, initialDiagCount+1)
+	if len(diagnostics) != initialDiagCount+1 {
+		t.Fatalf("Expected one diagnostics notification after the burst, got %d", len(diagnostics)-initialDiagCount)
+	}
+
+	latest := diagnostics[len(diagnostics)-1]
+	if latest.Version != 4 {
+		t.Errorf("Version = %d, want 4", latest.Version)
+	}
+
+	// Later edits in the burst cancel earlier timers, so nothing else arrives.
+	time.Sleep(300 * time.Millisecond)
+	if got := len(client.waitForDiagnostics(t, 0)); got != initialDiagCount+1 {
+		t.Errorf("Expected no further diagnostics, got %d notifications", got-initialDiagCount)
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	uri := protocol.DocumentURI("file:///test.scaf")
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: "fn Q() `Q`\n"},
+	})
+
+	opened := len(client.waitForDiagnostics(t, 1))
+
+	// A pending analysis is cancelled by shutdown
+	_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "fn Q() `Q`\nfn R() `R`\n"}},
+	})
+
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+
+	if !server.ShutdownReceived() {
+		t.Error("ShutdownReceived() = false after Shutdown")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if got := len(client.waitForDiagnostics(t, 0)); got != opened {
+		t.Errorf("Expected no diagnostics after shutdown, got %d notifications", got-opened)
+	}
+
+	// Requests other than exit are rejected
+	req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), protocol.MethodTextDocumentHover, &protocol.HoverParams{})
+	if err != nil {
+		t.Fatalf("NewCall() error: %v", err)
+	}
+
+	var replyErr error
+	_ = lsp.NewHandler(server)(ctx, func(_ context.Context, _ any, err error) error {
+		replyErr = err
+		return nil
+	}, req)
+
+	if !errors.Is(replyErr, jsonrpc2.ErrInvalidRequest) {
+		t.Errorf("hover after shutdown error = %v, want ErrInvalidRequest", replyErr)
+	}
+}
+
+func TestServer_Exit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		shutdown bool
+		want     int
+	}{
+		{name: "after shutdown", shutdown: true, want: 0},
+		{name: "without shutdown", shutdown: false, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, _ := newTestServer(t)
+			ctx := context.Background()
+
+			code := -1
+			server.SetExitForTesting(func(c int) { code = c })
+
+			if tt.shutdown {
+				_ = server.Shutdown(ctx)
+			}
+
+			if err := server.Exit(ctx); err != nil {
+				t.Fatalf("Exit() error: %v", err)
+			}
+
+			if code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_DidChange_RequestSeesPendingChange(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	uri := protocol.DocumentURI("file:///test.scaf")
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: "fn Q() `Q`\n"},
+	})
+	_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "fn Q() `Q`\nfn R() `R`\n"}},
+	})
+
+	// Requested before the debounce fires: the change must still be visible.
+	result, err := server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("DocumentSymbol() error: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Errorf("Expected 2 symbols after change, got %d", len(result))
+	}
+}
+
+func TestServer_DidChange_WhitespaceOnly(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		text        string
+		wantPublish bool
+	}{
+		{"unchanged content", "fn Q() `Q`\n", false},
+		{"trailing spaces", "fn Q() `Q`   \n\t\n", false},
+		{"moves tokens", "\nfn Q()  `Q`\n", true},
+		{"token change", "fn R() `Q`\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, client := newTestServer(t)
+			ctx := context.Background()
+
+			_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+			_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+			uri := protocol.DocumentURI("file:///test.scaf")
+			_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+				TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: "fn Q() `Q`\n"},
+			})
+			initialDiagCount := len(client.waitForDiagnostics(t, 1))
+
+			_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+				TextDocument: protocol.VersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+					Version:                2,
+				},
+				ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: tt.text}},
+			})
+
+			time.Sleep(300 * time.Millisecond)
+
+			published := len(client.waitForDiagnostics(t, 0)) > initialDiagCount
+			if published != tt.wantPublish {
+				t.Errorf("re-analyzed = %v, want %v", published, tt.wantPublish)
+			}
+		})
+	}
+}
+
 func TestServer_DidClose(t *testing.T) {
 	t.Parallel()
 
@@ -1089,6 +1420,50 @@ func TestServer_Diagnostic_UndefinedSetupQuery(t *testing.T) {
 	}
 }
 
+func TestServer_Diagnostic_Suppressed(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := "// scaf-disable-next-line unused-import\nimport fixtures \"./fixtures\"\n\nfn Q() `Q`\n\nQ {\n\ttest \"t\" {\n\t\t$id: 1\n\t}\n}\n"
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text:    content,
+		},
+	})
+
+	if len(client.diagnostics) == 0 {
+		t.Fatal("Expected diagnostics to be published")
+	}
+
+	lastDiag := client.diagnostics[len(client.diagnostics)-1]
+
+	var found *protocol.Diagnostic
+	for i, d := range lastDiag.Diagnostics {
+		if d.Code == "unused-import" {
+			found = &lastDiag.Diagnostics[i]
+		}
+	}
+
+	if found == nil {
+		t.Fatal("Expected suppressed unused-import diagnostic to still be published")
+	}
+
+	if found.Severity != protocol.DiagnosticSeverityHint {
+		t.Errorf("Severity = %v, want Hint", found.Severity)
+	}
+
+	if len(found.Tags) != 1 || found.Tags[0] != protocol.DiagnosticTagUnnecessary {
+		t.Errorf("Tags = %v, want [Unnecessary]", found.Tags)
+	}
+}
+
 func TestServer_Diagnostic_ValidSetupQuery(t *testing.T) {
 	t.Parallel()
 
@@ -1396,6 +1771,145 @@ GetUser {
 	}
 }
 
+func TestServer_CodeLens_Titles(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := `fn GetUser() ` + "`MATCH (u:User {id: $id}) RETURN u`" + `
+fn CountUsers() ` + "`MATCH (u:User) RETURN count(u)`" + `
+
+GetUser {
+	test "finds user" {
+		$id: 1
+	}
+	group "edge cases" {
+		test "handles null id" {
+			$id: null
+		}
+		test "handles zero id" {
+			$id: 0
+		}
+	}
+}
+
+CountUsers {
+	test "counts" {}
+	test "counts" {}
+}
+`
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     "file:///test.scaf",
+			Version: 1,
+			Text:    content,
+		},
+	})
+
+	result, err := server.CodeLens(ctx, &protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+	})
+	if err != nil {
+		t.Fatalf("CodeLens() error: %v", err)
+	}
+
+	titles := make(map[string][]string)
+	for _, lens := range result {
+		if lens.Command == nil || lens.Command.Command == lsp.CommandRunTest || lens.Command.Command == lsp.CommandRunGroup {
+			continue
+		}
+		scope, _ := lens.Command.Arguments[1].(string)
+		titles[scope] = append(titles[scope], lens.Command.Title)
+	}
+
+	if got := strings.Join(titles["GetUser"], ", "); got != "▶ Run 3 tests" {
+		t.Errorf("GetUser lenses = %q, want %q", got, "▶ Run 3 tests")
+	}
+
+	// The duplicate test name is an error inside the CountUsers scope.
+	countLenses := titles["CountUsers"]
+	if len(countLenses) != 2 || countLenses[0] != "▶ Run 2 tests" || !strings.HasPrefix(countLenses[1], "⚠ ") {
+		t.Errorf("CountUsers lenses = %q, want run and diagnostics lenses", countLenses)
+	}
+}
+
+func TestServer_ExecuteCommand_RunScope(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	result, err := server.Initialize(ctx, &protocol.InitializeParams{})
+	if err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	if result.Capabilities.ExecuteCommandProvider == nil ||
+		len(result.Capabilities.ExecuteCommandProvider.Commands) == 0 ||
+		result.Capabilities.ExecuteCommandProvider.Commands[0] != lsp.CommandRunScope {
+		t.Errorf("ExecuteCommandProvider = %+v, want %s", result.Capabilities.ExecuteCommandProvider, lsp.CommandRunScope)
+	}
+
+	_, err = server.ExecuteCommand(ctx, &protocol.ExecuteCommandParams{
+		Command:   lsp.CommandRunScope,
+		Arguments: []any{"/path/to/test.scaf", "GetUser"},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteCommand() error: %v", err)
+	}
+
+	if len(client.messages) != 1 {
+		t.Fatalf("Expected 1 window/showMessage, got %d", len(client.messages))
+	}
+	if msg := client.messages[0].Message; !strings.Contains(msg, "GetUser") {
+		t.Errorf("Message = %q, want it to mention the scope", msg)
+	}
+}
+
+func TestServer_CodeLens_RefreshOnChange(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+		Capabilities: protocol.ClientCapabilities{
+			Workspace: &protocol.WorkspaceClientCapabilities{
+				CodeLens: &protocol.CodeLensWorkspaceClientCapabilities{RefreshSupport: true},
+			},
+		},
+	})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	uri := protocol.DocumentURI("file:///test.scaf")
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: "fn Q() `Q`\n"},
+	})
+	_ = server.DidChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+			Version:                2,
+		},
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{
+			{Text: "fn Q() `Q`\n\nQ {\n\ttest \"a\" {}\n}\n"},
+		},
+	})
+
+	// The refresh is sent asynchronously.
+	deadline := time.Now().Add(time.Second)
+	for client.codeLensRefreshes.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if client.codeLensRefreshes.Load() == 0 {
+		t.Error("Expected workspace/codeLens/refresh after document change")
+	}
+}
+
 func TestServer_Formatting(t *testing.T) {
 	t.Parallel()
 
@@ -1478,7 +1992,7 @@ func TestServer_Formatting_AlreadyFormatted(t *testing.T) {
 	_ = server.Initialized(ctx, &protocol.InitializedParams{})
 
 	// Open an already well-formatted file
-	formattedContent := `fn GetUser() ` + "`MATCH (u:User {id: $id}) RETURN u`" + `
+	formattedContent := `fn GetUser() ` + "`MATCH (u:User {id: $id})\nRETURN u`" + `
 
 GetUser {
 	test "finds user" {
@@ -1519,6 +2033,197 @@ GetUser {
 	}
 }
 
+func TestServer_Formatting_QueryBodies(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	content := "fn GetUser() `\n\tmatch (u:User {id:$id}) return u\n`\n"
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: content},
+	})
+
+	edits, err := server.Formatting(ctx, &protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+	})
+	if err != nil {
+		t.Fatalf("Formatting() error: %v", err)
+	}
+
+	if len(edits) != 1 {
+		t.Fatalf("Expected 1 edit, got %d", len(edits))
+	}
+
+	want := "fn GetUser() `\n\tMATCH (u:User {id: $id})\n\tRETURN u\n`\n"
+	if edits[0].NewText != want {
+		t.Errorf("Formatted content = %q, want %q", edits[0].NewText, want)
+	}
+}
+
+func TestServer_Formatting_Options(t *testing.T) {
+	t.Parallel()
+
+	content := "fn Upsert() `merge (n:User) on create set n.new = true`\n"
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name: "tab size sets the indent width",
+			want: "fn Upsert() `MERGE (n:User)\n    ON CREATE SET n.new = true`\n",
+		},
+		{
+			name:   "config overrides the tab size",
+			config: "cypher:\n  formatting:\n    keyword_case: lower\n    indent_width: 2\n",
+			want:   "fn Upsert() `merge (n:User)\n  on create set n.new = true`\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpDir := t.TempDir()
+			if tt.config != "" {
+				if err := os.WriteFile(filepath.Join(tmpDir, ".scaf.yaml"), []byte(tt.config), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			server, _ := newTestServer(t)
+			ctx := context.Background()
+
+			_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+				RootURI: protocol.DocumentURI("file://" + tmpDir),
+			})
+
+			uri := protocol.DocumentURI("file://" + filepath.Join(tmpDir, "test.scaf"))
+			_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+				TextDocument: protocol.TextDocumentItem{URI: uri, Version: 1, Text: content},
+			})
+
+			edits, err := server.Formatting(ctx, &protocol.DocumentFormattingParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Options:      protocol.FormattingOptions{TabSize: 4, InsertSpaces: true},
+			})
+			if err != nil {
+				t.Fatalf("Formatting() error: %v", err)
+			}
+
+			if len(edits) != 1 {
+				t.Fatalf("Expected 1 edit, got %d", len(edits))
+			}
+
+			if edits[0].NewText != tt.want {
+				t.Errorf("Formatted content = %q, want %q", edits[0].NewText, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_WillSaveWaitUntil(t *testing.T) {
+	t.Parallel()
+
+	content := "fn GetUser() `MATCH (u:User) RETURN u`\nGetUser {\ntest \"t\" {}\n}\n"
+
+	tests := []struct {
+		name      string
+		initOpts  any
+		settings  any
+		reason    protocol.TextDocumentSaveReason
+		wantEdits bool
+	}{
+		{name: "manual save formats", reason: protocol.TextDocumentSaveReasonManual, wantEdits: true},
+		{name: "auto save doesn't format", reason: protocol.TextDocumentSaveReasonAfterDelay},
+		{
+			name:     "disabled by configuration",
+			settings: map[string]any{"scaf.formatOnSave": false},
+			reason:   protocol.TextDocumentSaveReasonManual,
+		},
+		{
+			name:     "disabled by initialization options",
+			initOpts: map[string]any{"scaf": map[string]any{"formatOnSave": false}},
+			reason:   protocol.TextDocumentSaveReasonManual,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, _ := newTestServer(t)
+			ctx := context.Background()
+
+			result, _ := server.Initialize(ctx, &protocol.InitializeParams{InitializationOptions: tt.initOpts})
+			if sync, ok := result.Capabilities.TextDocumentSync.(*protocol.TextDocumentSyncOptions); !ok || !sync.WillSaveWaitUntil {
+				t.Errorf("TextDocumentSync = %+v, want willSaveWaitUntil", result.Capabilities.TextDocumentSync)
+			}
+
+			if tt.settings != nil {
+				_ = server.DidChangeConfiguration(ctx, &protocol.DidChangeConfigurationParams{Settings: tt.settings})
+			}
+
+			_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+				TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: content},
+			})
+
+			edits, err := server.WillSaveWaitUntil(ctx, &protocol.WillSaveTextDocumentParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+				Reason:       tt.reason,
+			})
+			if err != nil {
+				t.Fatalf("WillSaveWaitUntil() error: %v", err)
+			}
+
+			if got := len(edits) > 0; got != tt.wantEdits {
+				t.Fatalf("got %d edits, want edits: %t", len(edits), tt.wantEdits)
+			}
+
+			if tt.wantEdits && !strings.Contains(edits[0].NewText, "\ttest \"t\" {") {
+				t.Errorf("Formatted content = %q, want indented test", edits[0].NewText)
+			}
+		})
+	}
+}
+
+func TestServer_WillSaveWaitUntil_KeywordVariables(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+
+	content := "fn Route() `match (from:Airport)-[rows:FLIGHT]->(end) return from, end.code as count`\n"
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: content},
+	})
+
+	edits, err := server.WillSaveWaitUntil(ctx, &protocol.WillSaveTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: "file:///test.scaf"},
+		Reason:       protocol.TextDocumentSaveReasonManual,
+	})
+	if err != nil {
+		t.Fatalf("WillSaveWaitUntil() error: %v", err)
+	}
+
+	if len(edits) != 1 {
+		t.Fatalf("Expected 1 edit, got %d", len(edits))
+	}
+
+	want := "fn Route() `MATCH (from:Airport)-[rows:FLIGHT]->(end)\nRETURN from, end.code AS count`\n"
+	if edits[0].NewText != want {
+		t.Errorf("Formatted content = %q, want %q", edits[0].NewText, want)
+	}
+}
+
 func TestServer_Formatting_ParseError(t *testing.T) {
 	t.Parallel()
 
@@ -1690,3 +2395,82 @@ func TestServer_DialectDiagnostics_PrecisePosition(t *testing.T) {
 		}
 	}
 }
+
+func TestServer_DialectDiagnostics_MultilineQuery(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+
+	// MERGE is on the second line of the body, after two spaces
+	doc := "fn Upsert() `MATCH (u:User)\n  MERGE (u)-[:LIKES]->(p:Post)\n  RETURN p`\n"
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: doc},
+	})
+
+	for _, params := range client.diagnostics {
+		for _, diag := range params.Diagnostics {
+			if diag.Code != "merge-without-on-create-set" {
+				continue
+			}
+
+			want := protocol.Range{
+				Start: protocol.Position{Line: 1, Character: 2},
+				End:   protocol.Position{Line: 1, Character: 7},
+			}
+			if diag.Range != want {
+				t.Errorf("range = %+v, want %+v", diag.Range, want)
+			}
+
+			return
+		}
+	}
+
+	t.Fatal("expected a merge-without-on-create-set diagnostic")
+}
+
+func TestServer_DialectDiagnostics_NamedAssertQuery(t *testing.T) {
+	t.Parallel()
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{})
+
+	server.SetSchemaForTesting(&analysis.TypeSchema{
+		Models: map[string]*analysis.Model{
+			"Person": {
+				Name: "Person",
+				Relationships: []*analysis.Relationship{
+					{Name: "Friends", RelType: "FRIENDS", Target: "Person", Direction: analysis.DirectionOutgoing, Cardinality: analysis.CardinalityMany},
+				},
+			},
+		},
+	})
+
+	// Friends runs as a named assert query; Others is never asserted on
+	doc := "fn Friends() `MATCH (p:Person)-[:FRIENDS]->(f) RETURN f`\n" +
+		"fn Others() `MATCH (p:Person)-[:FRIENDS]->(f) RETURN f`\n\n" +
+		"Others {\n\ttest \"t\" {\n\t\tassert Friends() { (f != null) }\n\t}\n}\n"
+
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: "file:///test.scaf", Version: 1, Text: doc},
+	})
+
+	var lines []uint32
+
+	for _, params := range client.diagnostics {
+		for _, diag := range params.Diagnostics {
+			if diag.Code == "cardinality-mismatch" {
+				lines = append(lines, diag.Range.Start.Line)
+			}
+		}
+	}
+
+	if !slices.Equal(lines, []uint32{0}) {
+		t.Errorf("cardinality-mismatch on lines %v, want [0]", lines)
+	}
+}
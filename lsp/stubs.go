@@ -54,15 +54,9 @@ func (s *Server) Declaration(_ context.Context, _ *protocol.DeclarationParams) (
 
 // Definition is implemented in definition.go
 
-// DidChangeConfiguration handles workspace/didChangeConfiguration.
-func (s *Server) DidChangeConfiguration(_ context.Context, _ *protocol.DidChangeConfigurationParams) error {
-	return nil
-}
+// DidChangeConfiguration is implemented in config.go
 
-// DidChangeWatchedFiles handles workspace/didChangeWatchedFiles.
-func (s *Server) DidChangeWatchedFiles(_ context.Context, _ *protocol.DidChangeWatchedFilesParams) error {
-	return nil
-}
+// DidChangeWatchedFiles is implemented in config.go
 
 // DidChangeWorkspaceFolders handles workspace/didChangeWorkspaceFolders.
 func (s *Server) DidChangeWorkspaceFolders(_ context.Context, _ *protocol.DidChangeWorkspaceFoldersParams) error {
@@ -85,10 +79,7 @@ func (s *Server) DocumentLinkResolve(_ context.Context, _ *protocol.DocumentLink
 
 // DocumentSymbol is implemented in symbols.go
 
-// ExecuteCommand handles workspace/executeCommand.
-func (s *Server) ExecuteCommand(_ context.Context, _ *protocol.ExecuteCommandParams) (any, error) {
-	return nil, nil //nolint:nilnil // LSP stub returns nil for unimplemented features
-}
+// ExecuteCommand is implemented in codelens.go
 
 // FoldingRanges is implemented in folding.go
 
@@ -119,20 +110,14 @@ func (s *Server) RangeFormatting(_ context.Context, _ *protocol.DocumentRangeFor
 
 // Symbols is implemented in workspace_symbols.go
 
-// TypeDefinition handles textDocument/typeDefinition.
-func (s *Server) TypeDefinition(_ context.Context, _ *protocol.TypeDefinitionParams) ([]protocol.Location, error) {
-	return nil, nil
-}
+// TypeDefinition is implemented in typedefinition.go
 
 // WillSave handles textDocument/willSave.
 func (s *Server) WillSave(_ context.Context, _ *protocol.WillSaveTextDocumentParams) error {
 	return nil
 }
 
-// WillSaveWaitUntil handles textDocument/willSaveWaitUntil.
-func (s *Server) WillSaveWaitUntil(_ context.Context, _ *protocol.WillSaveTextDocumentParams) ([]protocol.TextEdit, error) {
-	return nil, nil
-}
+// WillSaveWaitUntil is implemented in formatting.go
 
 // ShowDocument handles window/showDocument.
 func (s *Server) ShowDocument(_ context.Context, _ *protocol.ShowDocumentParams) (*protocol.ShowDocumentResult, error) {
@@ -189,10 +174,7 @@ func (s *Server) OutgoingCalls(_ context.Context, _ *protocol.CallHierarchyOutgo
 	return nil, nil
 }
 
-// SemanticTokensFull handles textDocument/semanticTokens/full.
-func (s *Server) SemanticTokensFull(_ context.Context, _ *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
-	return nil, nil //nolint:nilnil // LSP stub returns nil for unimplemented features
-}
+// SemanticTokensFull is implemented in semantictokens.go
 
 // SemanticTokensFullDelta handles textDocument/semanticTokens/full/delta.
 func (s *Server) SemanticTokensFullDelta(_ context.Context, _ *protocol.SemanticTokensDeltaParams) (any, error) {
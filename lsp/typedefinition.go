@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"go.lsp.dev/protocol"
+	"go.uber.org/zap"
+
+	"github.com/rlch/scaf"
+	"github.com/rlch/scaf/analysis"
+)
+
+// TypeDefinition handles textDocument/typeDefinition requests. A query
+// name, whether in a scope header like `GetUser {`, a named assert query, or
+// its own declaration, navigates to the whole `fn GetUser(...)` declaration,
+// where Definition stops at the name. On a `module.Query()` setup call,
+// either part navigates to the declaration in the imported file.
+func (s *Server) TypeDefinition(_ context.Context, params *protocol.TypeDefinitionParams) ([]protocol.Location, error) {
+	s.logger.Debug("TypeDefinition",
+		zap.String("uri", string(params.TextDocument.URI)),
+		zap.Uint32("line", params.Position.Line),
+		zap.Uint32("character", params.Position.Character))
+
+	doc, ok := s.getDocument(params.TextDocument.URI)
+	if !ok || doc.Analysis == nil || doc.Analysis.Suite == nil || doc.Analysis.Symbols == nil {
+		return nil, nil
+	}
+
+	pos := analysis.PositionToLexer(params.Position.Line, params.Position.Character)
+
+	tokenCtx := analysis.GetTokenContext(doc.Analysis, pos)
+	if tokenCtx.Token == nil {
+		return nil, nil
+	}
+
+	if call, ok := tokenCtx.Node.(*scaf.SetupCall); ok && call.IsComplete() {
+		q, uri := s.findImportedQuery(doc, call.Module, call.Query)
+		if q == nil {
+			return nil, nil
+		}
+
+		return []protocol.Location{{URI: uri, Range: spanToRange(q.Span)}}, nil
+	}
+
+	name := queryNameAt(tokenCtx)
+	if name == "" {
+		return nil, nil
+	}
+
+	q, ok := doc.Analysis.Symbols.Queries[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return []protocol.Location{{URI: doc.URI, Range: spanToRange(q.Span)}}, nil
+}
+
+// queryNameAt returns the query name under the cursor if it's a scope
+// header, a named assert query, or a fn declaration's name, and "" otherwise.
+func queryNameAt(tokenCtx *analysis.TokenContext) string {
+	var (
+		name   string
+		tokens []lexer.Token
+	)
+
+	switch n := tokenCtx.Node.(type) {
+	case *scaf.QueryScope:
+		name, tokens = n.FunctionName, n.Tokens
+	case *scaf.AssertQuery:
+		if n.QueryName == nil {
+			return ""
+		}
+
+		name, tokens = *n.QueryName, n.Tokens
+	case *scaf.Function:
+		name, tokens = n.Name, n.Tokens
+	default:
+		return ""
+	}
+
+	// The name is the node's first token with that value; later ones are
+	// strings or parameters that happen to match it
+	for _, tok := range tokens {
+		if tok.Value == name {
+			if tok.Pos.Offset != tokenCtx.Token.Pos.Offset {
+				return ""
+			}
+
+			return name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,112 @@
+package lsp_test
+
+import (
+	"context"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestServer_TypeDefinition(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	fixturesContent := `fn CreatePost() ` + "`CREATE (p:Post) RETURN p`" + `
+
+fn CreateUser(name: string) ` + "`CREATE (u:User {name: $name}) RETURN u`" + `
+`
+	fixturesPath := tmpDir + "/fixtures.scaf"
+	if err := writeFile(fixturesPath, fixturesContent); err != nil {
+		t.Fatalf("Failed to create fixtures file: %v", err)
+	}
+
+	mainContent := `import fixtures "./fixtures"
+
+fn GetUser(id: int) ` + "`MATCH (u:User {id: $id}) RETURN u`" + `
+
+GetUser {
+	setup fixtures.CreateUser($name: "test")
+	test "finds user" {
+		$id: 1
+		u.name: "GetUser"
+		assert GetUser($id: 1) { (u != null) }
+	}
+}
+`
+	mainPath := tmpDir + "/main.scaf"
+	if err := writeFile(mainPath, mainContent); err != nil {
+		t.Fatalf("Failed to create main file: %v", err)
+	}
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	mainURI := protocol.DocumentURI("file://" + mainPath)
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     mainURI,
+			Version: 1,
+			Text:    mainContent,
+		},
+	})
+
+	fixturesURI := protocol.DocumentURI("file://" + fixturesPath)
+
+	tests := []struct {
+		name    string
+		pos     protocol.Position
+		wantURI protocol.DocumentURI
+		// wantLine is the line of the fn declaration, or -1 for no result
+		wantLine int
+	}{
+		{"scope header", protocol.Position{Line: 4, Character: 2}, mainURI, 2},
+		{"setup call query", protocol.Position{Line: 5, Character: 18}, fixturesURI, 2},
+		{"setup call module", protocol.Position{Line: 5, Character: 9}, fixturesURI, 2},
+		{"declaration", protocol.Position{Line: 2, Character: 4}, mainURI, 2},
+		{"assert query", protocol.Position{Line: 9, Character: 11}, mainURI, 2},
+		{"parameter", protocol.Position{Line: 7, Character: 3}, "", -1},
+		{"string matching a query name", protocol.Position{Line: 8, Character: 12}, "", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := server.TypeDefinition(ctx, &protocol.TypeDefinitionParams{
+				TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: mainURI},
+					Position:     tt.pos,
+				},
+			})
+			if err != nil {
+				t.Fatalf("TypeDefinition() error: %v", err)
+			}
+
+			if tt.wantLine < 0 {
+				if len(result) != 0 {
+					t.Errorf("TypeDefinition() = %+v, want none", result)
+				}
+
+				return
+			}
+
+			if len(result) != 1 {
+				t.Fatalf("Expected 1 location, got %d", len(result))
+			}
+
+			loc := result[0]
+			if loc.URI != tt.wantURI || loc.Range.Start.Line != uint32(tt.wantLine) || loc.Range.Start.Character != 0 {
+				t.Errorf("TypeDefinition() = %+v, want %s line %d", loc, tt.wantURI, tt.wantLine)
+			}
+
+			// The whole declaration, up to the end of its body
+			if loc.Range.End.Character <= uint32(len("fn CreateUser")) {
+				t.Errorf("Range = %+v, want the whole fn declaration", loc.Range)
+			}
+		})
+	}
+}
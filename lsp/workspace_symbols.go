@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"unicode"
 
 	"go.lsp.dev/protocol"
 	"go.uber.org/zap"
@@ -13,45 +15,230 @@ import (
 	"github.com/rlch/scaf/analysis"
 )
 
+// maxWorkspaceSymbols caps the number of workspace/symbol results so large
+// workspaces don't flood the editor.
+const maxWorkspaceSymbols = 100
+
 // Symbols handles workspace/symbol requests.
-// Searches for queries, tests, and groups across all .scaf files in the workspace.
-func (s *Server) Symbols(_ context.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+// Searches for queries, tests, and groups across open documents and all .scaf
+// files in the workspace. Open documents take precedence over their on-disk
+// contents so unsaved edits are reflected. Matching is case-insensitive fuzzy
+// prefix matching (see matchesSymbolQuery) and results are capped at
+// maxWorkspaceSymbols.
+//
+// When the client supplies a work done token, progress is reported per file.
+// When it supplies a partial result token, each file's symbols are streamed
+// via $/progress and the final response is empty.
+func (s *Server) Symbols(ctx context.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
 	s.logger.Debug("Symbols",
 		zap.String("query", params.Query))
 
-	if s.workspaceRoot == "" {
-		return nil, nil
-	}
+	query := strings.ToLower(params.Query)
+	progress := s.beginSymbolProgress(ctx, params.WorkDoneToken)
 
 	var symbols []protocol.SymbolInformation
-	query := strings.ToLower(params.Query)
+	streamed := 0
 
-	// Walk workspace looking for .scaf files
-	err := filepath.Walk(s.workspaceRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil //nolint:nilerr // Skip inaccessible paths and continue walking
+	// emit records a file's symbols, returning false once the cap is reached.
+	emit := func(fileSymbols []protocol.SymbolInformation) bool {
+		remaining := maxWorkspaceSymbols - len(symbols) - streamed
+		if len(fileSymbols) > remaining {
+			fileSymbols = fileSymbols[:remaining]
 		}
-		if info.IsDir() || !strings.HasSuffix(path, ".scaf") {
-			return nil
+
+		if params.PartialResultToken != nil {
+			if len(fileSymbols) > 0 {
+				s.sendProgress(ctx, params.PartialResultToken, fileSymbols)
+				streamed += len(fileSymbols)
+			}
+		} else {
+			symbols = append(symbols, fileSymbols...)
 		}
 
-		// Load and analyze the file
-		analyzed, err := s.fileLoader.LoadAndAnalyze(path)
-		if err != nil || analyzed.Suite == nil {
-			return nil //nolint:nilerr // Skip files that fail to parse and continue walking
+		return len(symbols)+streamed < maxWorkspaceSymbols
+	}
+
+	// Open documents first, so unsaved changes win over disk contents.
+	seen := make(map[protocol.DocumentURI]bool)
+	full := false
+	for _, doc := range s.openDocuments() {
+		seen[doc.URI] = true
+		if doc.Analysis == nil || doc.Analysis.Suite == nil {
+			continue
 		}
+		if !emit(s.extractWorkspaceSymbols(doc.URI, doc.Analysis, query)) {
+			full = true
+			break
+		}
+	}
+
+	if s.workspaceRoot != "" && !full {
+		// Walk workspace looking for .scaf files
+		err := filepath.Walk(s.workspaceRoot, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil //nolint:nilerr // Skip inaccessible paths and continue walking
+			}
+			if info.IsDir() || !strings.HasSuffix(path, ".scaf") {
+				return nil
+			}
 
-		uri := PathToURI(path)
-		fileSymbols := s.extractWorkspaceSymbols(uri, analyzed, query)
-		symbols = append(symbols, fileSymbols...)
+			uri := PathToURI(path)
+			if seen[uri] {
+				return nil
+			}
+
+			progress.report(path)
+
+			// Load and analyze the file
+			analyzed, err := s.fileLoader.LoadAndAnalyze(path)
+			if err != nil || analyzed.Suite == nil {
+				return nil //nolint:nilerr // Skip files that fail to parse and continue walking
+			}
 
-		return nil
+			if !emit(s.extractWorkspaceSymbols(uri, analyzed, query)) {
+				return filepath.SkipAll
+			}
+
+			return nil
+		})
+		if err != nil {
+			s.logger.Debug("Error walking workspace for symbols", zap.Error(err))
+		}
+	}
+
+	progress.end()
+
+	return symbols, nil
+}
+
+// openDocuments returns a snapshot of the currently open documents.
+func (s *Server) openDocuments() []*Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs := make([]*Document, 0, len(s.documents))
+	for _, doc := range s.documents {
+		docs = append(docs, doc)
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].URI < docs[j].URI
+	})
+
+	return docs
+}
+
+// symbolProgress reports work done progress for a workspace symbol search.
+// A nil token makes every method a no-op.
+type symbolProgress struct {
+	s     *Server
+	ctx   context.Context
+	token *protocol.ProgressToken
+}
+
+func (s *Server) beginSymbolProgress(ctx context.Context, token *protocol.ProgressToken) *symbolProgress {
+	p := &symbolProgress{s: s, ctx: ctx, token: token}
+	if token != nil {
+		s.sendProgress(ctx, token, &protocol.WorkDoneProgressBegin{
+			Kind:  protocol.WorkDoneProgressKindBegin,
+			Title: "Searching workspace symbols",
+		})
+	}
+
+	return p
+}
+
+func (p *symbolProgress) report(path string) {
+	if p.token == nil {
+		return
+	}
+
+	message := path
+	if rel, err := filepath.Rel(p.s.workspaceRoot, path); err == nil {
+		message = rel
+	}
+
+	p.s.sendProgress(p.ctx, p.token, &protocol.WorkDoneProgressReport{
+		Kind:    protocol.WorkDoneProgressKindReport,
+		Message: message,
+	})
+}
+
+func (p *symbolProgress) end() {
+	if p.token == nil {
+		return
+	}
+
+	p.s.sendProgress(p.ctx, p.token, &protocol.WorkDoneProgressEnd{
+		Kind: protocol.WorkDoneProgressKindEnd,
+	})
+}
+
+// sendProgress sends a $/progress notification, logging failures.
+func (s *Server) sendProgress(ctx context.Context, token *protocol.ProgressToken, value any) {
+	err := s.client.Progress(ctx, &protocol.ProgressParams{
+		Token: *token,
+		Value: value,
 	})
 	if err != nil {
-		s.logger.Debug("Error walking workspace for symbols", zap.Error(err))
+		s.logger.Debug("Failed to send progress", zap.Error(err))
 	}
+}
 
-	return symbols, nil
+// matchesSymbolQuery reports whether name matches the lowercased query using
+// case-insensitive fuzzy prefix matching: the query's first character must
+// start a word in name (the beginning, after a separator, or at a camelCase
+// boundary), and the remaining characters must follow in order.
+// For example "gu" matches "GetUser" and "user" matches "CountUsers".
+func matchesSymbolQuery(name, query string) bool {
+	if query == "" {
+		return true
+	}
+
+	runes := []rune(name)
+	for start := range runes {
+		if isWordStart(runes, start) && isSubsequence(strings.ToLower(string(runes[start:])), query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWordStart reports whether runes[i] begins a word.
+func isWordStart(runes []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	prev, cur := runes[i-1], runes[i]
+	switch {
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+		return unicode.IsLetter(cur) || unicode.IsDigit(cur)
+	case unicode.IsUpper(cur) && !unicode.IsUpper(prev):
+		return true
+	default:
+		return false
+	}
+}
+
+// isSubsequence reports whether every rune of sub appears in s in order,
+// with the first rune of sub matching the first rune of s.
+func isSubsequence(s, sub string) bool {
+	target := []rune(sub)
+	i := 0
+	for j, r := range s {
+		if i == len(target) {
+			break
+		}
+		if r == target[i] {
+			i++
+		} else if j == 0 {
+			return false
+		}
+	}
+
+	return i == len(target)
 }
 
 // extractWorkspaceSymbols extracts symbols from an analyzed file that match the query.
@@ -64,7 +251,7 @@ func (s *Server) extractWorkspaceSymbols(uri protocol.DocumentURI, f *analysis.A
 		if imp.Alias != nil {
 			name = *imp.Alias
 		}
-		if query == "" || strings.Contains(strings.ToLower(name), query) {
+		if query == "" || matchesSymbolQuery(name, query) {
 			symbols = append(symbols, protocol.SymbolInformation{
 				Name: name,
 				Kind: protocol.SymbolKindModule,
@@ -79,7 +266,7 @@ func (s *Server) extractWorkspaceSymbols(uri protocol.DocumentURI, f *analysis.A
 
 	// Add queries
 	for _, q := range f.Suite.Functions {
-		if query == "" || strings.Contains(strings.ToLower(q.Name), query) {
+		if query == "" || matchesSymbolQuery(q.Name, query) {
 			symbols = append(symbols, protocol.SymbolInformation{
 				Name: q.Name,
 				Kind: protocol.SymbolKindFunction,
@@ -94,7 +281,7 @@ func (s *Server) extractWorkspaceSymbols(uri protocol.DocumentURI, f *analysis.A
 
 	// Add scopes, tests, and groups
 	for _, scope := range f.Suite.Scopes {
-		if query == "" || strings.Contains(strings.ToLower(scope.FunctionName), query) {
+		if query == "" || matchesSymbolQuery(scope.FunctionName, query) {
 			symbols = append(symbols, protocol.SymbolInformation{
 				Name: scope.FunctionName,
 				Kind: protocol.SymbolKindClass,
@@ -119,7 +306,7 @@ func (s *Server) extractItemSymbols(uri protocol.DocumentURI, container string,
 
 	for _, item := range items {
 		if item.Test != nil {
-			if query == "" || strings.Contains(strings.ToLower(item.Test.Name), query) {
+			if query == "" || matchesSymbolQuery(item.Test.Name, query) {
 				symbols = append(symbols, protocol.SymbolInformation{
 					Name: item.Test.Name,
 					Kind: protocol.SymbolKindMethod,
@@ -134,7 +321,7 @@ func (s *Server) extractItemSymbols(uri protocol.DocumentURI, container string,
 
 		if item.Group != nil {
 			groupContainer := container + "/" + item.Group.Name
-			if query == "" || strings.Contains(strings.ToLower(item.Group.Name), query) {
+			if query == "" || matchesSymbolQuery(item.Group.Name, query) {
 				symbols = append(symbols, protocol.SymbolInformation{
 					Name: item.Group.Name,
 					Kind: protocol.SymbolKindNamespace,
@@ -2,7 +2,9 @@ package lsp_test
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"go.lsp.dev/protocol"
@@ -154,3 +156,190 @@ func TestServer_Symbols_NoWorkspace(t *testing.T) {
 		t.Error("Expected nil or empty result without workspace")
 	}
 }
+
+func TestServer_Symbols_FuzzyPrefix(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	filePath := tmpDir + "/test.scaf"
+	fileContent := `fn GetUser() ` + "`Q`" + `
+fn GetPost() ` + "`Q`" + `
+fn CountUsers() ` + "`Q`" + `
+`
+	if err := os.WriteFile(filePath, []byte(fileContent), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"gu", []string{"GetUser"}},
+		{"GETP", []string{"GetPost"}},
+		{"users", []string{"CountUsers"}},
+		{"g", []string{"GetUser", "GetPost"}},
+		{"ser", nil},
+	}
+
+	for _, tt := range tests {
+		result, err := server.Symbols(ctx, &protocol.WorkspaceSymbolParams{Query: tt.query})
+		if err != nil {
+			t.Fatalf("Symbols(%q) error: %v", tt.query, err)
+		}
+
+		var got []string
+		for _, sym := range result {
+			got = append(got, sym.Name)
+		}
+
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Symbols(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestServer_Symbols_OpenDocuments(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	// On disk the file only defines GetUser; the open buffer renames it.
+	filePath := tmpDir + "/test.scaf"
+	if err := os.WriteFile(filePath, []byte("fn GetUser() `Q`\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	uri := protocol.DocumentURI("file://" + filePath)
+	_ = server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:     uri,
+			Version: 1,
+			Text:    "fn GetAccount() `Q`\n",
+		},
+	})
+
+	result, err := server.Symbols(ctx, &protocol.WorkspaceSymbolParams{Query: "get"})
+	if err != nil {
+		t.Fatalf("Symbols() error: %v", err)
+	}
+
+	if len(result) != 1 || result[0].Name != "GetAccount" {
+		t.Fatalf("Expected only unsaved GetAccount, got %v", result)
+	}
+	if result[0].Location.URI != uri {
+		t.Errorf("URI = %s, want %s", result[0].Location.URI, uri)
+	}
+	if result[0].Kind != protocol.SymbolKindFunction {
+		t.Errorf("Kind = %v, want Function", result[0].Kind)
+	}
+}
+
+func TestServer_Symbols_CapAndProgress(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	var sb strings.Builder
+	for i := range 150 {
+		fmt.Fprintf(&sb, "fn Query%d() `Q`\n", i)
+	}
+	if err := os.WriteFile(tmpDir+"/many.scaf", []byte(sb.String()), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	result, err := server.Symbols(ctx, &protocol.WorkspaceSymbolParams{
+		WorkDoneProgressParams: protocol.WorkDoneProgressParams{
+			WorkDoneToken: protocol.NewProgressToken("work"),
+		},
+		Query: "query",
+	})
+	if err != nil {
+		t.Fatalf("Symbols() error: %v", err)
+	}
+
+	if len(result) != 100 {
+		t.Errorf("Expected results capped at 100, got %d", len(result))
+	}
+
+	if len(client.progress) < 2 {
+		t.Fatalf("Expected begin and end progress notifications, got %d", len(client.progress))
+	}
+	if _, ok := client.progress[0].Value.(*protocol.WorkDoneProgressBegin); !ok {
+		t.Errorf("First progress value = %T, want *WorkDoneProgressBegin", client.progress[0].Value)
+	}
+	if _, ok := client.progress[len(client.progress)-1].Value.(*protocol.WorkDoneProgressEnd); !ok {
+		t.Errorf("Last progress value = %T, want *WorkDoneProgressEnd", client.progress[len(client.progress)-1].Value)
+	}
+}
+
+func TestServer_Symbols_PartialResults(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	for _, name := range []string{"a", "b"} {
+		content := fmt.Sprintf("fn Get%s() `Q`\n", strings.ToUpper(name))
+		if err := os.WriteFile(tmpDir+"/"+name+".scaf", []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	server, client := newTestServer(t)
+	ctx := context.Background()
+
+	_, _ = server.Initialize(ctx, &protocol.InitializeParams{
+		RootURI: protocol.DocumentURI("file://" + tmpDir),
+	})
+	_ = server.Initialized(ctx, &protocol.InitializedParams{})
+
+	result, err := server.Symbols(ctx, &protocol.WorkspaceSymbolParams{
+		PartialResultParams: protocol.PartialResultParams{
+			PartialResultToken: protocol.NewProgressToken("partial"),
+		},
+		Query: "get",
+	})
+	if err != nil {
+		t.Fatalf("Symbols() error: %v", err)
+	}
+
+	if len(result) != 0 {
+		t.Errorf("Expected empty final response when streaming, got %d symbols", len(result))
+	}
+
+	streamed := 0
+	for _, p := range client.progress {
+		batch, ok := p.Value.([]protocol.SymbolInformation)
+		if !ok {
+			t.Fatalf("Partial result value = %T, want []SymbolInformation", p.Value)
+		}
+		streamed += len(batch)
+	}
+	if streamed != 2 {
+		t.Errorf("Expected 2 streamed symbols, got %d", streamed)
+	}
+}
@@ -49,6 +49,14 @@ func (m *Module) GetSetup() *scaf.SetupClause {
 	return m.Suite.Setup
 }
 
+// GetTeardown returns the module's teardown clause, or nil if none.
+func (m *Module) GetTeardown() *scaf.TeardownClause {
+	if m.Suite == nil {
+		return nil
+	}
+	return m.Suite.Teardown
+}
+
 // GetQuery returns a query by name, or empty string if not found.
 func (m *Module) GetQuery(name string) (string, bool) {
 	q, ok := m.Queries[name]
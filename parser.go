@@ -1,7 +1,14 @@
 package scaf
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/alecthomas/participle/v2"
 )
@@ -13,7 +20,7 @@ var dslLexer = newDSLLexer()
 var parser = participle.MustBuild[File](
 	participle.Lexer(dslLexer),
 	participle.Unquote("RawString", "String"),
-	participle.Elide("Whitespace", "Comment"),
+	participle.Elide("Whitespace", "Comment", "Annotation"),
 )
 
 // Parse parses a scaf DSL file and returns the AST with comments attached to nodes.
@@ -37,19 +44,93 @@ func Parse(data []byte) (*File, error) {
 //   - Skips to keywords that start new constructs: test, group, fn, import, setup, teardown, assert
 //   - Handles nested braces and parentheses correctly
 func ParseWithRecovery(data []byte, withRecovery bool) (*File, error) {
-	return parseWithOptions(data, withRecovery, nil)
+	return parse(data, withRecovery, nil)
 }
 
 // ParseWithRecoveryTrace is like ParseWithRecovery but writes recovery trace to w.
 // Useful for debugging recovery behavior.
 func ParseWithRecoveryTrace(data []byte, withRecovery bool, w io.Writer) (*File, error) {
-	return parseWithOptions(data, withRecovery, w)
+	return parse(data, withRecovery, w)
 }
 
-func parseWithOptions(data []byte, withRecovery bool, traceWriter io.Writer) (*File, error) {
-	// Lock to ensure trivia isn't overwritten by concurrent parses
+// ParseOptions bounds the resources a parse may use. Zero values mean no limit.
+type ParseOptions struct {
+	// MaxBytes is the largest input accepted, in bytes.
+	MaxBytes int64
+	// MaxTokens is the most tokens the lexer may produce, including whitespace
+	// and comments.
+	MaxTokens int
+	// Timeout bounds how long to wait for the parse, including waiting for
+	// other parses to finish lexing.
+	Timeout time.Duration
+	// Recovery enables error recovery, as in ParseWithRecovery.
+	Recovery bool
+}
+
+// ParseWithOptions parses a scaf DSL file like ParseWithRecovery, enforcing the
+// limits in opts. Use it for untrusted input such as editor buffers.
+//
+// Exceeding a limit returns a nil AST and a *ParseLimitError. A parse that
+// times out is abandoned rather than interrupted: it finishes in the
+// background and its result is discarded. Parses only wait for each other
+// while lexing, so an abandoned parse doesn't hold up later ones.
+func ParseWithOptions(data []byte, opts ParseOptions) (*File, error) {
+	if opts.MaxBytes > 0 && int64(len(data)) > opts.MaxBytes {
+		return nil, &ParseLimitError{Limit: LimitBytes, Max: opts.MaxBytes}
+	}
+
+	if opts.Timeout <= 0 {
+		return parseLimited(data, opts.Recovery, nil, opts.MaxTokens)
+	}
+
+	type result struct {
+		file *File
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		file, err := parseLimited(data, opts.Recovery, nil, opts.MaxTokens)
+		done <- result{file, err}
+	}()
+
+	timer := time.NewTimer(opts.Timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.file, r.err
+	case <-timer.C:
+		return nil, &ParseLimitError{Limit: LimitTimeout, Max: int64(opts.Timeout)}
+	}
+}
+
+func parse(data []byte, withRecovery bool, traceWriter io.Writer) (*File, error) {
+	return parseLimited(data, withRecovery, traceWriter, 0)
+}
+
+// parseLimited parses data, failing once the lexer produces more than
+// maxTokens tokens (0 for no limit).
+func parseLimited(data []byte, withRecovery bool, traceWriter io.Writer, maxTokens int) (*File, error) {
+	// Lock to ensure trivia isn't overwritten by concurrent parses. The
+	// lexer's shared state is done with once the input is lexed, so the
+	// lock is released then rather than after parsing.
+	var (
+		once   sync.Once
+		trivia *TriviaList
+	)
+
+	unlock := func() { once.Do(dslLexer.Unlock) }
+
 	dslLexer.Lock()
-	defer dslLexer.Unlock()
+	defer unlock()
+
+	dslLexer.maxTokens = maxTokens
+	dslLexer.lexed = func(t *TriviaList) {
+		trivia = t
+		unlock()
+	}
 
 	var file *File
 	var err error
@@ -65,6 +146,7 @@ func parseWithOptions(data []byte, withRecovery bool, traceWriter io.Writer) (*F
 					"}",        // Block closer - ends test, group, assert, scope, setup block
 					"test",     // Test definition
 					"group",    // Group definition
+					"table",    // Table definition
 					"fn",       // Function definition
 					"import",   // Import statement
 					"setup",    // Setup clause
@@ -90,15 +172,196 @@ func parseWithOptions(data []byte, withRecovery bool, traceWriter io.Writer) (*F
 		file, err = parser.ParseBytes("", data)
 	}
 
+	var limitErr *ParseLimitError
+	if errors.As(err, &limitErr) {
+		return nil, limitErr
+	}
+
+	err = toParseErrors(err)
+
 	// Attach comments even to partial ASTs - Participle populates as much
 	// of the AST as possible before the error location
 	if file != nil {
-		attachComments(file, dslLexer.Trivia())
+		attachComments(file, trivia)
+		file.Annotations = collectAnnotations(trivia)
+		expandTables(file)
 	}
 
 	return file, err
 }
 
+// toParseErrors converts the errors participle returns to *ParseError,
+// keeping a *participle.RecoveryError around several.
+func toParseErrors(err error) error {
+	var recoveryErr *participle.RecoveryError
+	if errors.As(err, &recoveryErr) {
+		for i, e := range recoveryErr.Errors {
+			recoveryErr.Errors[i] = toParseError(e)
+		}
+
+		return recoveryErr
+	}
+
+	return toParseError(err)
+}
+
+// toParseError converts a single parser or lexer error to a *ParseError.
+// Other errors are returned unchanged.
+func toParseError(err error) error {
+	var tokenErr *participle.UnexpectedTokenError
+	if errors.As(err, &tokenErr) {
+		msg := tokenErr.Message()
+
+		var expected []string
+
+		// The expectation is only available in the message
+		prefix := fmt.Sprintf("unexpected token %q (expected ", tokenErr.Unexpected)
+		if rest, ok := strings.CutPrefix(msg, prefix); ok {
+			expected = expectedTokens(strings.TrimSuffix(rest, ")"))
+		}
+
+		return &ParseError{
+			Pos:      tokenErr.Position(),
+			Expected: expected,
+			Got:      tokenErr.Unexpected.String(),
+			msg:      msg,
+			err:      err,
+		}
+	}
+
+	var lexErr *LexerError
+	if errors.As(err, &lexErr) {
+		e := &ParseError{Pos: lexErr.pos, msg: lexErr.msg, err: err}
+		if lexErr.ch != 0 {
+			e.Got = string(lexErr.ch)
+			e.msg += ": " + e.Got
+		}
+
+		return e
+	}
+
+	var perr participle.Error
+	if errors.As(err, &perr) {
+		return &ParseError{Pos: perr.Position(), msg: perr.Message(), err: err}
+	}
+
+	return err
+}
+
+// expectedTokens returns the tokens that can start expect, the grammar
+// participle reports it expected, such as "{" ("setup" SetupClause)? "}".
+// Optional and repeated groups add their first tokens to those that follow.
+// Grammar rules are not expanded, so they appear by name.
+func expectedTokens(expect string) []string {
+	var tokens []string
+
+	seen := make(map[string]bool)
+
+	var first func(expr string)
+	first = func(expr string) {
+		terms := grammarTerms(expr)
+
+		for len(terms) > 0 {
+			// Each alternative contributes the first tokens of its sequence
+			alt := terms
+			if i := slices.Index(terms, "|"); i >= 0 {
+				alt, terms = terms[:i], terms[i+1:]
+			} else {
+				terms = nil
+			}
+
+			for _, term := range alt {
+				optional := false
+				if last := term[len(term)-1]; last == '?' || last == '*' || last == '+' {
+					optional = last != '+'
+					term = term[:len(term)-1]
+				}
+
+				if strings.HasPrefix(term, "(") && strings.HasSuffix(term, ")") {
+					first(term[1 : len(term)-1])
+				} else {
+					if unquoted, err := strconv.Unquote(term); err == nil {
+						term = unquoted
+					}
+
+					if !seen[term] {
+						seen[term] = true
+						tokens = append(tokens, term)
+					}
+				}
+
+				if !optional {
+					break
+				}
+			}
+		}
+	}
+
+	first(expect)
+
+	return tokens
+}
+
+// grammarTerms splits a participle grammar expression into its top-level
+// terms: quoted literals, <token> types, parenthesised groups, names, and |.
+// A trailing ?, * or + stays with its term.
+func grammarTerms(expr string) []string {
+	var terms []string
+
+	for i := 0; i < len(expr); {
+		if expr[i] == ' ' {
+			i++
+			continue
+		}
+
+		start := i
+
+		switch expr[i] {
+		case '"':
+			for i++; i < len(expr) && expr[i] != '"'; i++ {
+				if expr[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case '(':
+			for depth := 0; i < len(expr); i++ {
+				if expr[i] == '"' {
+					for i++; i < len(expr) && expr[i] != '"'; i++ {
+						if expr[i] == '\\' {
+							i++
+						}
+					}
+
+					continue
+				}
+
+				if expr[i] == '(' {
+					depth++
+				} else if expr[i] == ')' {
+					depth--
+					if depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+		default:
+			for i < len(expr) && expr[i] != ' ' && expr[i] != '(' && expr[i] != '"' {
+				i++
+			}
+		}
+
+		for i < len(expr) && (expr[i] == '?' || expr[i] == '*' || expr[i] == '+') {
+			i++
+		}
+
+		terms = append(terms, expr[start:min(i, len(expr))])
+	}
+
+	return terms
+}
+
 // ExportedLexer returns the lexer definition for testing purposes.
 //
 //nolint:revive // unexported-return: intentionally returns unexported type for internal test use
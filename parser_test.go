@@ -1,9 +1,14 @@
 package scaf_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alecthomas/participle/v2"
 	"github.com/google/go-cmp/cmp"
+
 	"github.com/rlch/scaf"
 )
 
@@ -300,12 +305,43 @@ func TestParse(t *testing.T) {
 			expected: &scaf.Suite{
 				Functions: []*scaf.Query{{Name: "Q", Body: "Q"}},
 				Setup:     &scaf.SetupClause{Inline: ptr("CREATE (:User)")},
-				Teardown:  ptr("MATCH (u:User) DELETE u"),
+				Teardown:  &scaf.TeardownClause{Inline: ptr("MATCH (u:User) DELETE u")},
 				Scopes: []*scaf.QueryScope{
 					{FunctionName: "Q", Items: []*scaf.TestOrGroup{{Test: &scaf.Test{Name: "t"}}}},
 				},
 			},
 		},
+		{
+			name: "module and call teardowns",
+			input: `
+				import fixtures "./fixtures"
+				fn Q() ` + "`Q`" + `
+				teardown fixtures
+				Q {
+					teardown { fixtures.DeleteUser($id: 1) ` + "`MATCH (n) DETACH DELETE n`" + ` }
+					test "t" {}
+				}
+			`,
+			expected: &scaf.Suite{
+				Imports:   []*scaf.Import{{Alias: ptr("fixtures"), Path: "./fixtures"}},
+				Functions: []*scaf.Query{{Name: "Q", Body: "Q"}},
+				Teardown:  &scaf.TeardownClause{Module: ptr("fixtures")},
+				Scopes: []*scaf.QueryScope{
+					{
+						FunctionName: "Q",
+						Teardown: &scaf.TeardownClause{Block: []*scaf.SetupItem{
+							{Call: &scaf.SetupCall{
+								Module: "fixtures",
+								Query:  "DeleteUser",
+								Params: []*scaf.SetupParam{{Name: "$id", Value: &scaf.ParamValue{Literal: &scaf.Value{Number: ptr(1.0)}}}},
+							}},
+							{Inline: ptr("MATCH (n) DETACH DELETE n")},
+						}},
+						Items: []*scaf.TestOrGroup{{Test: &scaf.Test{Name: "t"}}},
+					},
+				},
+			},
+		},
 		{
 			name: "scope teardown",
 			input: `
@@ -322,7 +358,7 @@ func TestParse(t *testing.T) {
 					{
 						FunctionName: "Q",
 						Setup:        &scaf.SetupClause{Inline: ptr("SCOPE SETUP")},
-						Teardown:     ptr("SCOPE TEARDOWN"),
+						Teardown:     &scaf.TeardownClause{Inline: ptr("SCOPE TEARDOWN")},
 						Items:        []*scaf.TestOrGroup{{Test: &scaf.Test{Name: "t"}}},
 					},
 				},
@@ -350,7 +386,7 @@ func TestParse(t *testing.T) {
 								Group: &scaf.Group{
 									Name:     "g",
 									Setup:    &scaf.SetupClause{Inline: ptr("GROUP SETUP")},
-									Teardown: ptr("GROUP TEARDOWN"),
+									Teardown: &scaf.TeardownClause{Inline: ptr("GROUP TEARDOWN")},
 									Items:    []*scaf.TestOrGroup{{Test: &scaf.Test{Name: "t"}}},
 								},
 							},
@@ -1771,3 +1807,269 @@ func TestIsComplete(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTable(t *testing.T) {
+	t.Parallel()
+
+	input := `
+		fn GetUser(id) ` + "`MATCH (u:User {id: $id}) RETURN u.name, u.active`" + `
+		GetUser {
+			table "lookups" {
+				| $id | u.name  | u.active |
+				| 1   | "Alice" | true     |
+				| 2   | "Bob"   | false    |
+			}
+			group "g" {
+				table "nested" {
+					| $id |
+					| [1, 2] |
+				}
+			}
+		}
+	`
+
+	suite, err := scaf.Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	items := suite.Scopes[0].Items
+	if len(items) != 4 {
+		t.Fatalf("len(Items) = %d, want table, 2 row tests, and group", len(items))
+	}
+
+	table := items[0].Table
+	if table == nil || !table.IsComplete() {
+		t.Fatalf("Items[0].Table = %+v, want a complete table", table)
+	}
+
+	want := [][]string{
+		{"lookups #1", "$id: 1", "u.name: \"Alice\"", "u.active: true"},
+		{"lookups #2", "$id: 2", "u.name: \"Bob\"", "u.active: false"},
+	}
+
+	for i, w := range want {
+		test := items[i+1].Test
+		if test == nil {
+			t.Fatalf("Items[%d].Test = nil, want %q", i+1, w[0])
+		}
+
+		got := []string{test.Name}
+		for _, stmt := range test.Statements {
+			got = append(got, stmt.Key()+": "+stmt.Value.String())
+		}
+
+		if diff := cmp.Diff(w, got); diff != "" {
+			t.Errorf("row %d mismatch (-want +got):\n%s", i+1, diff)
+		}
+
+		if test.FromTable != table {
+			t.Errorf("row %d FromTable not set to its table", i+1)
+		}
+
+		if test.Pos.Line != 6+i {
+			t.Errorf("row %d test at line %d, want %d", i+1, test.Pos.Line, 6+i)
+		}
+	}
+
+	group := items[3].Group
+	if group == nil || len(group.Items) != 2 || group.Items[1].Test == nil {
+		t.Fatalf("group items = %+v, want nested table and its row test", group)
+	}
+
+	if name := group.Items[1].Test.Name; name != "nested #1" {
+		t.Errorf("nested row test name = %q, want %q", name, "nested #1")
+	}
+}
+
+func TestParseWithOptions(t *testing.T) {
+	t.Parallel()
+
+	input := []byte("fn Q() `MATCH (n) RETURN n`\n\nQ {\n\ttest \"t\" {}\n}\n")
+
+	tests := []struct {
+		name      string
+		opts      scaf.ParseOptions
+		wantLimit string
+	}{
+		{name: "no limits", opts: scaf.ParseOptions{}},
+		{name: "within limits", opts: scaf.ParseOptions{MaxBytes: 1 << 10, MaxTokens: 100, Timeout: time.Second}},
+		{name: "too many bytes", opts: scaf.ParseOptions{MaxBytes: 10}, wantLimit: scaf.LimitBytes},
+		{name: "too many tokens", opts: scaf.ParseOptions{MaxTokens: 5}, wantLimit: scaf.LimitTokens},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			file, err := scaf.ParseWithOptions(input, tt.opts)
+
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("ParseWithOptions() error: %v", err)
+				}
+
+				if len(file.Functions) != 1 || len(file.Scopes) != 1 {
+					t.Errorf("ParseWithOptions() = %d functions, %d scopes, want 1 and 1", len(file.Functions), len(file.Scopes))
+				}
+
+				return
+			}
+
+			var limitErr *scaf.ParseLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("ParseWithOptions() error = %v, want *ParseLimitError", err)
+			}
+
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("Limit = %q, want %q", limitErr.Limit, tt.wantLimit)
+			}
+
+			if file != nil {
+				t.Error("expected nil file when a limit is exceeded")
+			}
+		})
+	}
+}
+
+// Not parallel: holds the shared lexer lock to stall the parse.
+func TestParseWithOptions_Timeout(t *testing.T) {
+	lex := scaf.ExportedLexer()
+	lex.Lock()
+
+	_, err := scaf.ParseWithOptions([]byte("fn Q() `Q`"), scaf.ParseOptions{Timeout: 10 * time.Millisecond})
+
+	lex.Unlock()
+
+	var limitErr *scaf.ParseLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != scaf.LimitTimeout {
+		t.Fatalf("ParseWithOptions() error = %v, want timeout", err)
+	}
+
+	if want := "scaf: parse timed out after 10ms"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+// Not parallel: the abandoned parse keeps running after the test.
+func TestParseWithOptions_AbandonedParseDoesNotBlock(t *testing.T) {
+	var b strings.Builder
+
+	b.WriteString("fn Q() `Q`\n\nQ {\n")
+
+	for range 10000 {
+		b.WriteString("\ttest \"t\" { a: 1 b: [1, 2, {c: 3}] }\n")
+	}
+
+	b.WriteString("}\n")
+
+	// Lexing this takes a few milliseconds, parsing it most of a second
+	_, err := scaf.ParseWithOptions([]byte(b.String()), scaf.ParseOptions{Timeout: time.Millisecond})
+
+	var limitErr *scaf.ParseLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != scaf.LimitTimeout {
+		t.Fatalf("ParseWithOptions() error = %v, want timeout", err)
+	}
+
+	// The abandoned parse only holds the lexer while lexing
+	file, err := scaf.ParseWithOptions([]byte("fn Q() `Q`"), scaf.ParseOptions{Timeout: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("ParseWithOptions() after an abandoned parse error: %v", err)
+	}
+
+	if len(file.Functions) != 1 {
+		t.Errorf("ParseWithOptions() = %d functions, want 1", len(file.Functions))
+	}
+}
+
+func TestParseError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unexpected token", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := scaf.Parse([]byte("import 12"))
+
+		var parseErr *scaf.ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("Parse() error = %T %v, want *scaf.ParseError", err, err)
+		}
+
+		if parseErr.Pos.Line != 1 || parseErr.Pos.Column != 8 {
+			t.Errorf("Pos = %v, want 1:8", parseErr.Pos)
+		}
+
+		if parseErr.Got != "12" {
+			t.Errorf("Got = %q, want %q", parseErr.Got, "12")
+		}
+
+		if diff := cmp.Diff([]string{"<string>"}, parseErr.Expected); diff != "" {
+			t.Errorf("Expected mismatch (-want +got):\n%s", diff)
+		}
+
+		if want := `1:8: unexpected token "12" (expected <string>)`; err.Error() != want {
+			t.Errorf("Error() = %q, want %q", err.Error(), want)
+		}
+	})
+
+	t.Run("end of input", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := scaf.Parse([]byte("fn"))
+
+		var parseErr *scaf.ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("Parse() error = %T %v, want *scaf.ParseError", err, err)
+		}
+
+		if parseErr.Got != "<EOF>" {
+			t.Errorf("Got = %q, want <EOF>", parseErr.Got)
+		}
+
+		if diff := cmp.Diff([]string{"<ident>"}, parseErr.Expected); diff != "" {
+			t.Errorf("Expected mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("recovery", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := scaf.ParseWithRecovery([]byte("import 12\nimport 13\n"), true)
+
+		var recoveryErr *participle.RecoveryError
+		if !errors.As(err, &recoveryErr) || len(recoveryErr.Errors) < 2 {
+			t.Fatalf("ParseWithRecovery() error = %T %v, want several errors", err, err)
+		}
+
+		for _, e := range recoveryErr.Errors {
+			if _, ok := e.(*scaf.ParseError); !ok {
+				t.Errorf("recovered error = %T %v, want *scaf.ParseError", e, e)
+			}
+		}
+
+		var parseErr *scaf.ParseError
+		if !errors.As(err, &parseErr) || parseErr.Pos.Line != 1 {
+			t.Errorf("errors.As() = %v, want the first error", parseErr)
+		}
+	})
+
+	t.Run("lexer error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := scaf.Parse([]byte(`import "unterminated`))
+
+		var parseErr *scaf.ParseError
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("Parse() error = %T %v, want *scaf.ParseError", err, err)
+		}
+
+		if parseErr.Pos.Column != 8 || parseErr.Message() != "unterminated string" {
+			t.Errorf("ParseError = %v at %v, want unterminated string at 1:8", parseErr.Message(), parseErr.Pos)
+		}
+
+		var lexErr *scaf.LexerError
+		if !errors.As(err, &lexErr) {
+			t.Errorf("errors.As(%v) found no *scaf.LexerError", err)
+		}
+	})
+}
@@ -29,6 +29,9 @@ var (
 	// ErrAssertNoQuery is returned when an assert has no inline or named query.
 	ErrAssertNoQuery = errors.New("runner: assert query has no inline or named query")
 
+	// ErrTestTimeout is returned when a test runs past its timeout.
+	ErrTestTimeout = errors.New("runner: test timed out")
+
 	// Test errors for use in unit tests.
 	errTestSetupFailed = errors.New("test: setup failed")
 	errTestStop        = errors.New("test: stop")
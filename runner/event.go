@@ -11,18 +11,21 @@ type Action string
 
 // Action constants for test events.
 const (
-	ActionRun    Action = "run"
-	ActionPass   Action = "passed"
-	ActionFail   Action = "failed"
-	ActionSkip   Action = "skipped"
-	ActionError  Action = "error"
-	ActionOutput Action = "output"
-	ActionSetup  Action = "setup"
+	ActionRun         Action = "run"
+	ActionPass        Action = "passed"
+	ActionFail        Action = "failed"
+	ActionSkip        Action = "skipped"
+	ActionError       Action = "error"
+	ActionTimeout     Action = "timeout"
+	ActionSetupFailed Action = "setup_failed"
+	ActionOutput      Action = "output"
+	ActionSetup       Action = "setup"
 )
 
 // IsTerminal returns true if this action ends a test.
 func (a Action) IsTerminal() bool {
-	return a == ActionPass || a == ActionFail || a == ActionSkip || a == ActionError
+	return a == ActionPass || a == ActionFail || a == ActionSkip || a == ActionError || a == ActionTimeout ||
+		a == ActionSetupFailed
 }
 
 // Event represents a single test event emitted during execution.
@@ -33,7 +36,7 @@ type Event struct {
 	Path    []string      // Test path: ["QueryScope", "Group", "Test"]
 	Elapsed time.Duration // Time taken (for terminal events)
 	Output  string        // Log output (for ActionOutput)
-	Error   error         // Error details (for ActionFail/ActionError)
+	Error   error         // Error details (for ActionFail/ActionError/ActionTimeout/ActionSetupFailed)
 
 	// For assertion failures
 	Expected any
@@ -5,6 +5,9 @@ import (
 	"strings"
 
 	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/rlch/scaf"
 )
 
 // ExprResult holds the result of evaluating an expression.
@@ -36,6 +39,34 @@ func EvalExpr(exprStr string, env map[string]any) ExprResult {
 		return result
 	}
 
+	return runProgram(result, program, env)
+}
+
+// EvalParenExpr evaluates an assert condition like EvalExpr, reusing the
+// program cached on cond when it was compiled against an env of the same shape.
+func EvalParenExpr(cond *scaf.ParenExpr, env map[string]any) ExprResult {
+	result := ExprResult{Expression: cond.String()}
+
+	if strings.TrimSpace(result.Expression) == "" {
+		result.Passed = true
+
+		return result
+	}
+
+	program, err := cond.Compile(env, expr.AsBool())
+	if err != nil {
+		result.Error = fmt.Errorf("compile expression %q: %w", result.Expression, err)
+
+		return result
+	}
+
+	return runProgram(result, program, env)
+}
+
+// runProgram runs a compiled boolean expression and records its outcome in result.
+func runProgram(result ExprResult, program *vm.Program, env map[string]any) ExprResult {
+	exprStr := result.Expression
+
 	// Run the expression
 	output, err := expr.Run(program, env)
 	if err != nil {
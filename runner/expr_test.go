@@ -4,6 +4,8 @@ package runner
 import (
 	"strings"
 	"testing"
+
+	"github.com/rlch/scaf"
 )
 
 func TestEvalExpr_Comparisons(t *testing.T) {
@@ -711,3 +713,31 @@ func TestEvalExpr_TernaryOperator(t *testing.T) {
 		})
 	}
 }
+
+func TestEvalParenExpr(t *testing.T) {
+	t.Parallel()
+
+	cond := makeParenExpr([]*scaf.ExprToken{
+		{Ident: ptr("age")},
+		{Op: ptr(">")},
+		{Number: ptr("18")},
+	})
+
+	// The cached program is reused for ints and recompiled for int64s.
+	for _, env := range []map[string]any{{"age": 30}, {"age": 12}, {"age": int64(30)}} {
+		want := env["age"] != 12
+
+		result := EvalParenExpr(cond, env)
+		if result.Error != nil {
+			t.Fatalf("EvalParenExpr(%v) error: %v", env, result.Error)
+		}
+
+		if result.Passed != want {
+			t.Errorf("EvalParenExpr(%v) = %v, want %v", env, result.Passed, want)
+		}
+	}
+
+	if result := EvalParenExpr(&scaf.ParenExpr{}, nil); !result.Passed {
+		t.Error("expected an empty expression to pass")
+	}
+}
@@ -3,8 +3,11 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -76,6 +79,12 @@ func (v *VerboseFormatter) Format(event Event, _ *Result) error {
 	case ActionError:
 		_, _ = fmt.Fprintf(v.w, "--- ERROR: %s (%s)\n", event.PathString(), event.Elapsed)
 		_, _ = fmt.Fprintf(v.w, "    %v\n", event.Error)
+	case ActionTimeout:
+		_, _ = fmt.Fprintf(v.w, "--- TIMEOUT: %s (%s)\n", event.PathString(), event.Elapsed)
+		_, _ = fmt.Fprintf(v.w, "    %v\n", event.Error)
+	case ActionSetupFailed:
+		_, _ = fmt.Fprintf(v.w, "--- SETUP_FAILED: %s (%s)\n", event.PathString(), event.Elapsed)
+		_, _ = fmt.Fprintf(v.w, "    %v\n", event.Error)
 	case ActionOutput:
 		_, _ = fmt.Fprintf(v.w, "    %s\n", event.Output)
 	case ActionSetup:
@@ -95,12 +104,14 @@ func (v *VerboseFormatter) Summary(result *Result) error {
 	}
 
 	_, _ = fmt.Fprintf(v.w, "%s\n", status)
-	_, _ = fmt.Fprintf(v.w, "  %d total, %d passed, %d failed, %d skipped, %d errors\n",
+	_, _ = fmt.Fprintf(v.w, "  %d total, %d passed, %d failed, %d skipped, %d errors, %d timed out, %d setup failed\n",
 		result.Total,
 		result.Passed,
 		result.Failed,
 		result.Skipped,
 		result.Errors,
+		result.TimedOut,
+		result.SetupFailed,
 	)
 	_, _ = fmt.Fprintf(v.w, "  elapsed: %s\n", result.Elapsed().Round(time.Millisecond))
 
@@ -205,15 +216,17 @@ type jsonTestResult struct {
 }
 
 type jsonSummary struct {
-	Action  string                    `json:"action"`
-	Total   int                       `json:"total"`
-	Passed  int                       `json:"passed"`
-	Failed  int                       `json:"failed"`
-	Skipped int                       `json:"skipped"`
-	Errors  int                       `json:"errors"`
-	Elapsed float64                   `json:"elapsed"`
-	Ok      bool                      `json:"ok"`
-	Results map[string]jsonTestResult `json:"results"`
+	Action      string                    `json:"action"`
+	Total       int                       `json:"total"`
+	Passed      int                       `json:"passed"`
+	Failed      int                       `json:"failed"`
+	Skipped     int                       `json:"skipped"`
+	Errors      int                       `json:"errors"`
+	TimedOut    int                       `json:"timedOut"`
+	SetupFailed int                       `json:"setupFailed"`
+	Elapsed     float64                   `json:"elapsed"`
+	Ok          bool                      `json:"ok"`
+	Results     map[string]jsonTestResult `json:"results"`
 }
 
 // Summary outputs the final JSON summary.
@@ -245,14 +258,212 @@ func (j *JSONFormatter) Summary(result *Result) error {
 	}
 
 	return j.enc.Encode(jsonSummary{
-		Action:  "summary",
-		Total:   result.Total,
-		Passed:  result.Passed,
-		Failed:  result.Failed,
-		Skipped: result.Skipped,
-		Errors:  result.Errors,
-		Elapsed: result.Elapsed().Seconds(),
-		Ok:      result.Ok(),
-		Results: results,
+		Action:      "summary",
+		Total:       result.Total,
+		Passed:      result.Passed,
+		Failed:      result.Failed,
+		Skipped:     result.Skipped,
+		Errors:      result.Errors,
+		TimedOut:    result.TimedOut,
+		SetupFailed: result.SetupFailed,
+		Elapsed:     result.Elapsed().Seconds(),
+		Ok:          result.Ok(),
+		Results:     results,
 	})
 }
+
+// -----------------------------------------------------------------------------
+// JUnit Formatter
+// -----------------------------------------------------------------------------
+
+// JUnitFormatter writes a JUnit-compatible XML report once all tests have run.
+// Each source file becomes a <testsuite>; each test becomes a <testcase> whose
+// classname is the query scope and whose name is the remaining test path.
+// JUnit has no timeout or setup failure status, so those tests are errors of
+// type Timeout and SetupFailed.
+type JUnitFormatter struct {
+	w io.Writer
+}
+
+// NewJUnitFormatter creates a JUnit XML formatter.
+func NewJUnitFormatter(w io.Writer) *JUnitFormatter {
+	return &JUnitFormatter{w: w}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// Format is a no-op; the report is written by Summary.
+func (j *JUnitFormatter) Format(Event, *Result) error {
+	return nil
+}
+
+// Summary writes the XML report.
+func (j *JUnitFormatter) Summary(result *Result) error {
+	result.mu.RLock()
+
+	report := junitTestSuites{
+		Tests:    result.Total,
+		Failures: result.Failed,
+		Errors:   result.Errors + result.TimedOut + result.SetupFailed,
+		Skipped:  result.Skipped,
+	}
+
+	// Group tests by source file, preserving execution order
+	suiteIndex := make(map[string]int)
+	suiteElapsed := []time.Duration{}
+
+	for _, path := range result.Order {
+		tr := result.Tests[path]
+		if tr == nil {
+			continue
+		}
+
+		idx, ok := suiteIndex[tr.Suite]
+		if !ok {
+			idx = len(report.Suites)
+			suiteIndex[tr.Suite] = idx
+			report.Suites = append(report.Suites, junitTestSuite{Name: tr.Suite})
+			suiteElapsed = append(suiteElapsed, 0)
+		}
+
+		suiteElapsed[idx] += tr.Elapsed
+
+		suite := &report.Suites[idx]
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, junitCase(tr))
+
+		switch tr.Status {
+		case ActionFail:
+			suite.Failures++
+		case ActionError, ActionTimeout, ActionSetupFailed:
+			suite.Errors++
+		case ActionSkip:
+			suite.Skipped++
+		case ActionPass, ActionRun, ActionOutput, ActionSetup:
+			// Counted in Tests only
+		}
+	}
+
+	result.mu.RUnlock()
+
+	for i, elapsed := range suiteElapsed {
+		report.Suites[i].Time = junitSeconds(elapsed)
+	}
+
+	report.Time = junitSeconds(result.Elapsed())
+
+	_, err := io.WriteString(j.w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(j.w)
+	enc.Indent("", "  ")
+
+	err = enc.Encode(report)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(j.w, "\n")
+
+	return err
+}
+
+func junitCase(tr *TestResult) junitTestCase {
+	tc := junitTestCase{
+		Time:      junitSeconds(tr.Elapsed),
+		SystemOut: strings.Join(tr.Output, "\n"),
+	}
+
+	// Path is ["QueryScope", "Group"..., "Test"]
+	if len(tr.Path) > 0 {
+		tc.ClassName = tr.Path[0]
+		tc.Name = strings.Join(tr.Path[1:], "/")
+	}
+
+	if tc.Name == "" {
+		tc.Name = tc.ClassName
+	}
+
+	switch tr.Status {
+	case ActionFail:
+		msg := ""
+		if tr.Error != nil {
+			msg = tr.Error.Error()
+		} else if tr.Field != "" {
+			msg = fmt.Sprintf("%s: expected %v, got %v", tr.Field, tr.Expected, tr.Actual)
+		}
+
+		tc.Failure = &junitProblem{Message: msg, Type: "AssertionError", Text: msg}
+	case ActionError:
+		msg := ""
+		if tr.Error != nil {
+			msg = tr.Error.Error()
+		}
+
+		tc.Error = &junitProblem{Message: msg, Text: msg}
+	case ActionTimeout:
+		msg := ""
+		if tr.Error != nil {
+			msg = tr.Error.Error()
+		}
+
+		tc.Error = &junitProblem{Message: msg, Type: "Timeout", Text: msg}
+	case ActionSetupFailed:
+		msg := ""
+		if tr.Error != nil {
+			msg = tr.Error.Error()
+		}
+
+		tc.Error = &junitProblem{Message: msg, Type: "SetupFailed", Text: msg}
+	case ActionSkip:
+		tc.Skipped = &junitSkipped{}
+	case ActionPass, ActionRun, ActionOutput, ActionSetup:
+		// No child element
+	}
+
+	return tc
+}
+
+func junitSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
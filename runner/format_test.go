@@ -4,6 +4,8 @@ package runner
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"testing"
 	"time"
 )
@@ -109,3 +111,59 @@ func TestJSONFormatter_Summary(t *testing.T) {
 		t.Errorf("ok = %v, want false", got["ok"])
 	}
 }
+
+func TestJUnitFormatter_Summary(t *testing.T) {
+	var buf bytes.Buffer
+
+	f := NewJUnitFormatter(&buf)
+
+	result := NewResult()
+	result.Add(Event{Action: ActionPass, Suite: "a.scaf", Path: []string{"GetUser", "finds user"}, Elapsed: 20 * time.Millisecond})
+	result.Add(Event{Action: ActionFail, Suite: "a.scaf", Path: []string{"GetUser", "group", "wrong name"}, Field: "u.name", Expected: "alice", Actual: "bob"})
+	result.Add(Event{Action: ActionError, Suite: "b.scaf", Path: []string{"CountUsers", "counts"}, Error: errors.New("connection refused")})
+	result.Add(Event{Action: ActionSkip, Suite: "b.scaf", Path: []string{"CountUsers", "skipped"}})
+	result.Finish()
+
+	err := f.Summary(result)
+	if err != nil {
+		t.Fatalf("Summary() error: %v", err)
+	}
+
+	var got junitTestSuites
+
+	err = xml.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+
+	if got.Tests != 4 || got.Failures != 1 || got.Errors != 1 || got.Skipped != 1 {
+		t.Errorf("totals = %d/%d/%d/%d, want 4/1/1/1", got.Tests, got.Failures, got.Errors, got.Skipped)
+	}
+
+	if len(got.Suites) != 2 {
+		t.Fatalf("got %d testsuites, want 2", len(got.Suites))
+	}
+
+	a := got.Suites[0]
+	if a.Name != "a.scaf" || a.Tests != 2 || a.Failures != 1 {
+		t.Errorf("suite[0] = %s tests=%d failures=%d, want a.scaf tests=2 failures=1", a.Name, a.Tests, a.Failures)
+	}
+
+	failed := a.TestCases[1]
+	if failed.ClassName != "GetUser" || failed.Name != "group/wrong name" {
+		t.Errorf("testcase = %s.%s, want GetUser.group/wrong name", failed.ClassName, failed.Name)
+	}
+
+	if failed.Failure == nil || failed.Failure.Message != "u.name: expected alice, got bob" {
+		t.Errorf("failure = %+v, want u.name mismatch", failed.Failure)
+	}
+
+	b := got.Suites[1]
+	if b.TestCases[0].Error == nil || b.TestCases[0].Error.Message != "connection refused" {
+		t.Errorf("error = %+v, want connection refused", b.TestCases[0].Error)
+	}
+
+	if b.TestCases[1].Skipped == nil {
+		t.Error("expected skipped element")
+	}
+}
@@ -90,8 +90,9 @@ func (h *StopOnFailHandler) Event(_ context.Context, event Event, result *Result
 		return nil
 	}
 
-	if event.Action == ActionFail || event.Action == ActionError {
-		if result.Failed+result.Errors >= h.maxFails {
+	if event.Action == ActionFail || event.Action == ActionError || event.Action == ActionTimeout ||
+		event.Action == ActionSetupFailed {
+		if result.Failed+result.Errors+result.TimedOut+result.SetupFailed >= h.maxFails {
 			return ErrMaxFailures
 		}
 	}
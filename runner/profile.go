@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultSlowThreshold is the duration above which a profile entry is slow.
+const DefaultSlowThreshold = time.Second
+
+// ProfileEntry summarizes the durations of a query's tests or of one test.
+type ProfileEntry struct {
+	// Name is the query name for a query's tests, or the test path
+	// ("GetUser/existing users/finds Alice") for a single test.
+	Name  string
+	Count int
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+// Profile returns an entry for each query and each test that ran, slowest
+// first by p99, then by name. Skipped tests are left out.
+func (r *Result) Profile() []ProfileEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+
+	durations := make(map[string][]time.Duration)
+
+	record := func(name string, d time.Duration) {
+		if _, ok := durations[name]; !ok {
+			names = append(names, name)
+		}
+
+		durations[name] = append(durations[name], d)
+	}
+
+	for _, path := range r.Order {
+		tr := r.Tests[path]
+		if tr.Status == ActionSkip || len(tr.Path) == 0 {
+			continue
+		}
+
+		if len(tr.Path) > 1 {
+			record(tr.Path[0], tr.Elapsed)
+		}
+
+		record(tr.PathString(), tr.Elapsed)
+	}
+
+	entries := make([]ProfileEntry, 0, len(names))
+
+	for _, name := range names {
+		ds := durations[name]
+		slices.Sort(ds)
+
+		entries = append(entries, ProfileEntry{
+			Name:  name,
+			Count: len(ds),
+			P50:   percentile(ds, 50),
+			P95:   percentile(ds, 95),
+			P99:   percentile(ds, 99),
+		})
+	}
+
+	slices.SortStableFunc(entries, func(a, b ProfileEntry) int {
+		if c := cmp.Compare(b.P99, a.P99); c != 0 {
+			return c
+		}
+
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return entries
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+
+	return sorted[max(rank, 1)-1]
+}
+
+// WriteProfile writes entries as a table, marking those whose p99 exceeds
+// slowThreshold.
+func WriteProfile(w io.Writer, entries []ProfileEntry, slowThreshold time.Duration) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "NAME\tP50\tP95\tP99\tCOUNT\t")
+
+	for _, e := range entries {
+		slow := ""
+		if e.P99 > slowThreshold {
+			slow = "⚠ SLOW"
+		}
+
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			e.Name,
+			e.P50.Round(time.Microsecond),
+			e.P95.Round(time.Microsecond),
+			e.P99.Round(time.Microsecond),
+			e.Count,
+			slow,
+		)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,85 @@
+package runner //nolint:testpackage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResult_Profile(t *testing.T) {
+	r := NewResult()
+
+	for i, ms := range []int{10, 20, 30, 40, 1500} {
+		r.Add(Event{
+			Action:  ActionPass,
+			Path:    []string{"GetUser", "test" + string(rune('a'+i))},
+			Elapsed: time.Duration(ms) * time.Millisecond,
+		})
+	}
+
+	r.Add(Event{Action: ActionFail, Path: []string{"CountUsers", "counts"}, Elapsed: 5 * time.Millisecond})
+	r.Add(Event{Action: ActionSkip, Path: []string{"CountUsers", "skipped"}})
+
+	entries := r.Profile()
+
+	byName := make(map[string]ProfileEntry, len(entries))
+	for _, e := range entries {
+		byName[e.Name] = e
+	}
+
+	getUser, ok := byName["GetUser"]
+	if !ok {
+		t.Fatal("missing GetUser query entry")
+	}
+
+	if getUser.Count != 5 || getUser.P50 != 30*time.Millisecond || getUser.P95 != 1500*time.Millisecond {
+		t.Errorf("GetUser = %+v, want count 5, p50 30ms, p95 1.5s", getUser)
+	}
+
+	if e := byName["CountUsers"]; e.Count != 1 {
+		t.Errorf("CountUsers count = %d, want 1 (skipped tests are left out)", e.Count)
+	}
+
+	if _, ok := byName["CountUsers/skipped"]; ok {
+		t.Error("skipped test should not be profiled")
+	}
+
+	// Slowest first; the query and its slowest test tie on p99, broken by name
+	if entries[0].Name != "GetUser" || entries[1].Name != "GetUser/teste" {
+		t.Errorf("first entries = %s, %s; want GetUser, GetUser/teste", entries[0].Name, entries[1].Name)
+	}
+
+	if last := entries[len(entries)-1]; last.P99 != 5*time.Millisecond {
+		t.Errorf("last entry = %+v, want the 5ms test", last)
+	}
+}
+
+func TestWriteProfile(t *testing.T) {
+	entries := []ProfileEntry{
+		{Name: "GetUser", Count: 2, P50: 200 * time.Millisecond, P95: 2 * time.Second, P99: 2 * time.Second},
+		{Name: "GetUser/finds", Count: 1, P50: 200 * time.Millisecond, P95: 200 * time.Millisecond, P99: 200 * time.Millisecond},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProfile(&buf, entries, 500*time.Millisecond); err != nil {
+		t.Fatalf("WriteProfile() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header and 2 entries:\n%s", len(lines), buf.String())
+	}
+
+	if !strings.HasPrefix(lines[0], "NAME") {
+		t.Errorf("header = %q", lines[0])
+	}
+
+	if !strings.Contains(lines[1], "⚠ SLOW") || !strings.Contains(lines[1], "2s") {
+		t.Errorf("slow entry = %q, want 2s marked slow", lines[1])
+	}
+
+	if strings.Contains(lines[2], "SLOW") {
+		t.Errorf("fast entry marked slow: %q", lines[2])
+	}
+}
@@ -14,11 +14,13 @@ type Result struct {
 	StartTime time.Time
 	EndTime   time.Time
 
-	Total   int
-	Passed  int
-	Failed  int
-	Skipped int
-	Errors  int
+	Total       int
+	Passed      int
+	Failed      int
+	Skipped     int
+	Errors      int
+	TimedOut    int
+	SetupFailed int
 
 	// Tests indexed by path string: "GetUser/existing users/finds Alice"
 	Tests map[string]*TestResult
@@ -74,6 +76,10 @@ func (r *Result) Add(event Event) {
 		r.Skipped++
 	case ActionError:
 		r.Errors++
+	case ActionTimeout:
+		r.TimedOut++
+	case ActionSetupFailed:
+		r.SetupFailed++
 	case ActionRun, ActionOutput, ActionSetup:
 		// Not terminal actions
 	}
@@ -119,10 +125,11 @@ func (r *Result) Ok() bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return r.Failed == 0 && r.Errors == 0
+	return r.Failed == 0 && r.Errors == 0 && r.TimedOut == 0 && r.SetupFailed == 0
 }
 
-// FailedTests returns all failed test results.
+// FailedTests returns all failed, errored, timed-out, and setup-failed test
+// results.
 func (r *Result) FailedTests() []*TestResult {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -131,7 +138,8 @@ func (r *Result) FailedTests() []*TestResult {
 
 	for _, path := range r.Order {
 		tr := r.Tests[path]
-		if tr.Status == ActionFail || tr.Status == ActionError {
+		if tr.Status == ActionFail || tr.Status == ActionError || tr.Status == ActionTimeout ||
+			tr.Status == ActionSetupFailed {
 			failed = append(failed, tr)
 		}
 	}
@@ -152,6 +160,8 @@ func (r *Result) Merge(other *Result) {
 	r.Failed += other.Failed
 	r.Skipped += other.Skipped
 	r.Errors += other.Errors
+	r.TimedOut += other.TimedOut
+	r.SetupFailed += other.SetupFailed
 
 	maps.Copy(r.Tests, other.Tests)
 
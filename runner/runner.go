@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
-	"reflect"
 	"regexp"
 	"strings"
 	"time"
@@ -23,8 +22,13 @@ var (
 	ErrNoModuleContext = errors.New("module context required for module setup")
 	// ErrModuleNoSetup is returned when a referenced module has no setup clause.
 	ErrModuleNoSetup = errors.New("module has no setup clause")
+	// ErrModuleNoTeardown is returned when a referenced module has no teardown clause.
+	ErrModuleNoTeardown = errors.New("module has no teardown clause")
 )
 
+// discardTimeout bounds the cleanup after a test times out.
+const discardTimeout = 5 * time.Second
+
 // Runner executes scaf test suites.
 type Runner struct {
 	database scaf.Database
@@ -33,6 +37,9 @@ type Runner struct {
 	filter   *regexp.Regexp
 	modules  *module.ResolvedContext
 	lag      bool // artificial lag for TUI testing
+
+	timeout       time.Duration            // per-test limit; 0 means none
+	scopeTimeouts map[string]time.Duration // per-scope overrides of timeout
 }
 
 // Option configures a Runner.
@@ -89,6 +96,22 @@ func WithLag(enabled bool) Option {
 	}
 }
 
+// WithTimeout bounds each test's execution, including its setup and asserts.
+// A test that runs past d is reported with ActionTimeout. 0 means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.timeout = d
+	}
+}
+
+// WithScopeTimeouts overrides the test timeout for the tests of the query
+// scopes named in timeouts.
+func WithScopeTimeouts(timeouts map[string]time.Duration) Option {
+	return func(r *Runner) {
+		r.scopeTimeouts = timeouts
+	}
+}
+
 // New creates a Runner with the given options.
 func New(opts ...Option) *Runner {
 	r := &Runner{}
@@ -168,7 +191,7 @@ func (r *Runner) Run(ctx context.Context, suite *scaf.Suite, suitePath string) (
 		if err != nil {
 			// Run suite teardown even on error
 			if suite.Teardown != nil {
-				_ = r.executeQuery(ctx, r.database, *suite.Teardown, nil)
+				_ = r.executeTeardown(ctx, r.database, suite.Teardown)
 			}
 
 			return result, err
@@ -177,7 +200,7 @@ func (r *Runner) Run(ctx context.Context, suite *scaf.Suite, suitePath string) (
 
 	// Execute suite teardown
 	if suite.Teardown != nil {
-		err := r.executeQuery(ctx, r.database, *suite.Teardown, nil)
+		err := r.executeTeardown(ctx, r.database, suite.Teardown)
 		if err != nil {
 			return result, fmt.Errorf("suite teardown: %w", err)
 		}
@@ -225,7 +248,7 @@ func (r *Runner) runQueryScope(
 		if errors.Is(err, ErrMaxFailures) {
 			// Run scope teardown before returning
 			if scope.Teardown != nil {
-				_ = r.executeQuery(ctx, r.database, *scope.Teardown, nil)
+				_ = r.executeTeardown(ctx, r.database, scope.Teardown)
 			}
 
 			return err
@@ -234,7 +257,7 @@ func (r *Runner) runQueryScope(
 
 	// Execute scope teardown
 	if scope.Teardown != nil {
-		err := r.executeQuery(ctx, r.database, *scope.Teardown, nil)
+		err := r.executeTeardown(ctx, r.database, scope.Teardown)
 		if err != nil {
 			return fmt.Errorf("scope %s teardown: %w", scope.FunctionName, err)
 		}
@@ -279,7 +302,7 @@ func (r *Runner) runGroup(
 		if errors.Is(err, ErrMaxFailures) {
 			// Run group teardown before returning
 			if group.Teardown != nil {
-				_ = r.executeQuery(ctx, r.database, *group.Teardown, nil)
+				_ = r.executeTeardown(ctx, r.database, group.Teardown)
 			}
 
 			return err
@@ -288,7 +311,7 @@ func (r *Runner) runGroup(
 
 	// Execute group teardown
 	if group.Teardown != nil {
-		err := r.executeQuery(ctx, r.database, *group.Teardown, nil)
+		err := r.executeTeardown(ctx, r.database, group.Teardown)
 		if err != nil {
 			return fmt.Errorf("group %s teardown: %w", group.Name, err)
 		}
@@ -330,14 +353,67 @@ func (r *Runner) runTest(
 		time.Sleep(time.Duration(500+rand.Intn(1000)) * time.Millisecond) //nolint:gosec // G404: weak random is fine for artificial lag
 	}
 
+	testCtx := ctx
+	if timeout := r.timeoutFor(path[0]); timeout > 0 {
+		var cancel context.CancelFunc
+
+		testCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var err error
+
 	// Try to run test in a transaction for isolation
 	txDB, canTx := r.database.(scaf.TransactionalDatabase)
 	if canTx {
-		return r.runTestInTransaction(ctx, txDB, test, queryBody, queries, path, suitePath, start, handler, result)
+		err = r.runTestInTransaction(testCtx, txDB, test, queryBody, queries, path, suitePath, start, handler, result)
+	} else {
+		// Fallback: run without transaction isolation
+		err = r.runTestDirect(testCtx, r.database, test, queryBody, queries, path, suitePath, start, handler, result)
+
+		if timedOut(testCtx) {
+			r.discardConnection(testCtx, nil, handler)
+		}
+	}
+
+	return err
+}
+
+// timeoutFor returns the test timeout for tests in the named query scope.
+func (r *Runner) timeoutFor(scope string) time.Duration {
+	if d, ok := r.scopeTimeouts[scope]; ok {
+		return d
+	}
+
+	return r.timeout
+}
+
+// timedOut reports whether ctx has passed its test timeout.
+func timedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// discardConnection cleans up after a test timed out. The query may still be
+// running, so the connection is dropped if the database supports it;
+// otherwise tx, if the test ran in one, is rolled back. The test's context
+// has expired, so both get a fresh one bounded by discardTimeout.
+func (r *Runner) discardConnection(ctx context.Context, tx scaf.DatabaseTransaction, handler Handler) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), discardTimeout)
+	defer cancel()
+
+	if db, ok := r.database.(scaf.DiscardableDatabase); ok {
+		if err := db.Discard(ctx); err != nil {
+			_ = handler.Err(fmt.Sprintf("discard connection: %v", err))
+		}
+
+		return
 	}
 
-	// Fallback: run without transaction isolation
-	return r.runTestDirect(ctx, r.database, test, queryBody, queries, path, suitePath, start, handler, result)
+	if tx != nil {
+		if err := tx.Rollback(ctx); err != nil {
+			_ = handler.Err(fmt.Sprintf("rollback timed-out transaction: %v", err))
+		}
+	}
 }
 
 func (r *Runner) runTestInTransaction(
@@ -357,9 +433,14 @@ func (r *Runner) runTestInTransaction(
 		return r.emitError(ctx, path, suitePath, start, fmt.Errorf("begin transaction: %w", err), handler, result)
 	}
 
-	// Always rollback - tests should not persist changes
+	// Always rollback - tests should not persist changes. A timed-out
+	// transaction goes with its connection instead, where it can.
 	defer func() {
-		_ = tx.Rollback(ctx)
+		if timedOut(ctx) {
+			r.discardConnection(ctx, tx, handler)
+		} else {
+			_ = tx.Rollback(ctx)
+		}
 	}()
 
 	return r.runTestDirect(ctx, tx, test, queryBody, queries, path, suitePath, start, handler, result)
@@ -381,27 +462,54 @@ func (r *Runner) runTestDirect(
 	if test.Setup != nil {
 		err := r.executeSetup(ctx, exec, test.Setup)
 		if err != nil {
-			return r.emitError(ctx, path, suitePath, start, fmt.Errorf("test setup: %w", err), handler, result)
+			event := r.errorEvent(ctx, fmt.Errorf("test setup: %w", err))
+			if event.Action == ActionError {
+				event.Action = ActionSetupFailed
+			}
+
+			return r.emit(ctx, event, path, suitePath, start, handler, result)
 		}
 	}
 
+	event := r.testOutcome(ctx, exec, test, queryBody, queries)
+
+	// Teardown runs whatever the outcome, but only fails a passing test
+	if test.Teardown != nil {
+		err := r.executeTeardown(ctx, exec, test.Teardown)
+		if err != nil && event.Action == ActionPass {
+			event = r.errorEvent(ctx, fmt.Errorf("test teardown: %w", err))
+		}
+	}
+
+	return r.emit(ctx, event, path, suitePath, start, handler, result)
+}
+
+// testOutcome runs the test's query and asserts and returns the terminal
+// event for the result, without its time, suite, or path.
+func (r *Runner) testOutcome(
+	ctx context.Context,
+	exec executor,
+	test *scaf.Test,
+	queryBody string,
+	queries map[string]string,
+) Event {
 	// Build params and expectations from statements
 	params := make(map[string]any)
-	expectations := make(map[string]any)
+	expectations := make(map[string]*scaf.StatementValue)
 
 	for _, stmt := range test.Statements {
 		key := stmt.Key()
 		if len(key) > 0 && key[0] == '$' {
 			params[key[1:]] = stmt.Value.ToGo()
 		} else {
-			expectations[key] = stmt.Value.ToGo()
+			expectations[key] = stmt.Value
 		}
 	}
 
 	// Execute query
 	rows, err := exec.Execute(ctx, queryBody, params)
 	if err != nil {
-		return r.emitError(ctx, path, suitePath, start, err, handler, result)
+		return r.errorEvent(ctx, err)
 	}
 
 	// Compare results - check first row against expectations
@@ -420,72 +528,43 @@ func (r *Runner) runTestDirect(
 			got = nil
 		}
 
-		if !valuesEqual(expected, got) {
-			elapsed := time.Since(start)
-
-			return handler.Event(ctx, Event{
-				Time:     time.Now(),
+		if !valuesEqual(expected.ToValue(), got) {
+			return Event{
 				Action:   ActionFail,
-				Suite:    suitePath,
-				Path:     path,
-				Elapsed:  elapsed,
 				Field:    field,
-				Expected: expected,
+				Expected: expected.ToGo(),
 				Actual:   got,
-			}, result)
+			}
 		}
 	}
 
 	// Evaluate assert blocks
 	for _, assert := range test.Asserts {
-		err := r.evaluateAssert(ctx, exec, assert, actual, queries, path, suitePath, start, handler, result)
-		if err != nil {
-			return err
+		if event := r.evaluateAssert(ctx, exec, assert, actual, queries); event != nil {
+			return *event
 		}
 	}
 
-	elapsed := time.Since(start)
-
-	return handler.Event(ctx, Event{
-		Time:    time.Now(),
-		Action:  ActionPass,
-		Suite:   suitePath,
-		Path:    path,
-		Elapsed: elapsed,
-	}, result)
+	return Event{Action: ActionPass}
 }
 
-// valuesEqual compares expected and actual values for equality.
-func valuesEqual(expected, actual any) bool {
-	// Handle nil cases
-	if expected == nil && actual == nil {
-		return true
-	}
+// emit sends a terminal event for the test at path, stamped with its time and
+// elapsed duration.
+func (r *Runner) emit(ctx context.Context, event Event, path []string, suitePath string, start time.Time, handler Handler, result *Result) error {
+	event.Time = time.Now()
+	event.Suite = suitePath
+	event.Path = path
+	event.Elapsed = time.Since(start)
 
-	if expected == nil || actual == nil {
-		return false
-	}
+	return handler.Event(ctx, event, result)
+}
 
-	// Handle numeric comparison (int64 from neo4j vs float64 from parser)
-	switch e := expected.(type) {
-	case float64:
-		switch a := actual.(type) {
-		case float64:
-			return e == a
-		case int64:
-			return e == float64(a)
-		}
-	case int64:
-		switch a := actual.(type) {
-		case float64:
-			return float64(e) == a
-		case int64:
-			return e == a
-		}
-	}
+// valuesEqual reports whether a query result matches the expected value.
+// Results with no scaf value form, such as temporal types, never match.
+func valuesEqual(expected *scaf.Value, actual any) bool {
+	got, ok := scaf.ValueFromGo(actual)
 
-	// Default: use reflect.DeepEqual
-	return reflect.DeepEqual(expected, actual)
+	return ok && expected.Equal(got)
 }
 
 func (r *Runner) executeQuery(ctx context.Context, exec executor, query string, params map[string]any) error {
@@ -540,6 +619,56 @@ func (r *Runner) executeSetupItem(ctx context.Context, exec executor, item *scaf
 	return nil
 }
 
+// executeTeardown executes a teardown clause. It runs like a setup clause,
+// except that module references run the module's teardown.
+func (r *Runner) executeTeardown(ctx context.Context, exec executor, teardown *scaf.TeardownClause) error {
+	if teardown == nil {
+		return nil
+	}
+
+	if teardown.Module != nil {
+		return r.executeModuleTeardown(ctx, exec, *teardown.Module)
+	}
+
+	if len(teardown.Block) == 0 {
+		return r.executeSetup(ctx, exec, (*scaf.SetupClause)(teardown))
+	}
+
+	for _, item := range teardown.Block {
+		var err error
+		if item.Module != nil {
+			err = r.executeModuleTeardown(ctx, exec, *item.Module)
+		} else {
+			err = r.executeSetupItem(ctx, exec, item)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// executeModuleTeardown runs an imported module's teardown clause.
+func (r *Runner) executeModuleTeardown(ctx context.Context, exec executor, moduleAlias string) error {
+	if r.modules == nil {
+		return fmt.Errorf("%w: %s", ErrNoModuleContext, moduleAlias)
+	}
+
+	mod, err := r.modules.ResolveModule(moduleAlias)
+	if err != nil {
+		return fmt.Errorf("failed to resolve module: %w", err)
+	}
+
+	modTeardown := mod.GetTeardown()
+	if modTeardown == nil {
+		return fmt.Errorf("%w: %s", ErrModuleNoTeardown, moduleAlias)
+	}
+
+	return r.executeTeardown(ctx, exec, modTeardown)
+}
+
 // executeModuleSetup runs an imported module's setup clause.
 func (r *Runner) executeModuleSetup(ctx context.Context, exec executor, moduleAlias string) error {
 	if r.modules == nil {
@@ -600,14 +729,17 @@ func (r *Runner) emitError(
 	handler Handler,
 	result *Result,
 ) error {
-	return handler.Event(ctx, Event{
-		Time:    time.Now(),
-		Action:  ActionError,
-		Suite:   suitePath,
-		Path:    path,
-		Elapsed: time.Since(start),
-		Error:   err,
-	}, result)
+	return r.emit(ctx, r.errorEvent(ctx, err), path, suitePath, start, handler, result)
+}
+
+// errorEvent returns the terminal event for a test that failed with err: an
+// error, or a timeout if ctx passed its deadline.
+func (r *Runner) errorEvent(ctx context.Context, err error) Event {
+	if timedOut(ctx) {
+		return Event{Action: ActionTimeout, Error: fmt.Errorf("%w: %w", ErrTestTimeout, err)}
+	}
+
+	return Event{Action: ActionError, Error: err}
 }
 
 // matchesFilter returns true if the test path matches the filter pattern.
@@ -625,18 +757,14 @@ func (r *Runner) matchesFilter(path []string) bool {
 // evaluateAssert evaluates an assert block's conditions.
 // If the assert has a query, it runs that query first and evaluates conditions against its results.
 // Otherwise, it evaluates conditions against the main query results.
+// It returns the terminal event for the first condition that fails, or nil.
 func (r *Runner) evaluateAssert(
 	ctx context.Context,
 	exec executor,
 	assert *scaf.Assert,
 	mainResult map[string]any,
 	queries map[string]string,
-	path []string,
-	suitePath string,
-	start time.Time,
-	handler Handler,
-	result *Result,
-) error {
+) *Event {
 	// Determine which result to evaluate against
 	env := mainResult
 
@@ -644,7 +772,9 @@ func (r *Runner) evaluateAssert(
 	if assert.Query != nil {
 		assertResult, err := r.runAssertQuery(ctx, exec, assert.Query, queries, mainResult)
 		if err != nil {
-			return r.emitError(ctx, path, suitePath, start, fmt.Errorf("assert query: %w", err), handler, result)
+			event := r.errorEvent(ctx, fmt.Errorf("assert query: %w", err))
+
+			return &event
 		}
 
 		env = assertResult
@@ -654,24 +784,20 @@ func (r *Runner) evaluateAssert(
 	for _, condition := range assert.Conditions {
 		exprStr := condition.String()
 
-		evalResult := EvalExpr(exprStr, env)
+		evalResult := EvalParenExpr(condition, env)
 		if evalResult.Error != nil {
-			return r.emitError(ctx, path, suitePath, start, evalResult.Error, handler, result)
+			event := r.errorEvent(ctx, evalResult.Error)
+
+			return &event
 		}
 
 		if !evalResult.Passed {
-			elapsed := time.Since(start)
-
-			return handler.Event(ctx, Event{
-				Time:     time.Now(),
+			return &Event{
 				Action:   ActionFail,
-				Suite:    suitePath,
-				Path:     path,
-				Elapsed:  elapsed,
 				Field:    exprStr,
 				Expected: true,
 				Actual:   false,
-			}, result)
+			}
 		}
 	}
 
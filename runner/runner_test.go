@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/rlch/scaf"
 	"github.com/rlch/scaf/module"
@@ -619,3 +620,235 @@ func TestRunner_SetupModuleReference(t *testing.T) {
 		t.Errorf("Passed = %d, want 1", result.Passed)
 	}
 }
+
+func TestRunner_TeardownModuleReference(t *testing.T) {
+	d := &mockDatabase{}
+
+	// The module's teardown runs, not its setup
+	fixturesSuite := &scaf.Suite{
+		Setup:    &scaf.SetupClause{Inline: ptr("CREATE (:TestNode)")},
+		Teardown: &scaf.TeardownClause{Inline: ptr("MATCH (n:TestNode) DELETE n")},
+	}
+
+	suite := &scaf.Suite{
+		Functions: []*scaf.Query{
+			{Name: "GetUser", Body: "MATCH (u:User) RETURN u.name"},
+		},
+		Imports: []*scaf.Import{{Alias: ptr("fixtures"), Path: "./fixtures.scaf"}},
+		Scopes: []*scaf.QueryScope{{
+			FunctionName: "GetUser",
+			Teardown: &scaf.TeardownClause{Block: []*scaf.SetupItem{
+				{Inline: ptr("MATCH (u:User) DELETE u")},
+				{Module: ptr("fixtures")},
+			}},
+			Items: []*scaf.TestOrGroup{{
+				Test: &scaf.Test{Name: "test"},
+			}},
+		}},
+	}
+
+	rootMod := module.NewModule("/root.scaf", suite)
+	fixturesMod := module.NewModule("/fixtures.scaf", fixturesSuite)
+
+	ctx := module.NewResolvedContext(rootMod)
+	ctx.Imports["fixtures"] = fixturesMod
+	ctx.AllModules[fixturesMod.Path] = fixturesMod
+
+	r := New(WithDatabase(d), WithModules(ctx))
+
+	if _, err := r.Run(context.Background(), suite, "/root.scaf"); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	n := len(d.executed)
+	if n < 2 || d.executed[n-2] != "MATCH (u:User) DELETE u" || d.executed[n-1] != "MATCH (n:TestNode) DELETE n" {
+		t.Errorf("executed = %v, want the teardown block last", d.executed)
+	}
+}
+
+func TestRunner_TestTeardown(t *testing.T) {
+	d := &mockDatabase{}
+	r := New(WithDatabase(d))
+
+	suite := &scaf.Suite{
+		Functions: []*scaf.Query{{Name: "GetUser", Body: "MATCH (u:User) RETURN u"}},
+		Scopes: []*scaf.QueryScope{{
+			FunctionName: "GetUser",
+			Items: []*scaf.TestOrGroup{{Test: &scaf.Test{
+				Name:     "test",
+				Setup:    &scaf.SetupClause{Inline: ptr("CREATE (:User)")},
+				Teardown: &scaf.TeardownClause{Inline: ptr("MATCH (u:User) DELETE u")},
+			}}},
+		}},
+	}
+
+	result, err := r.Run(context.Background(), suite, "test.scaf")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if result.Passed != 1 {
+		t.Errorf("got %d passed, want 1", result.Passed)
+	}
+
+	want := []string{"CREATE (:User)", "MATCH (u:User) RETURN u", "MATCH (u:User) DELETE u"}
+	if len(d.executed) != len(want) {
+		t.Fatalf("executed = %v, want %v", d.executed, want)
+	}
+
+	for i := range want {
+		if d.executed[i] != want[i] {
+			t.Errorf("executed[%d] = %q, want %q", i, d.executed[i], want[i])
+		}
+	}
+}
+
+func TestRunner_TestSetupFailed(t *testing.T) {
+	d := &mockDatabase{}
+	r := New(WithDatabase(d)) // No modules configured, so the setup call fails
+
+	suite := &scaf.Suite{
+		Functions: []*scaf.Query{{Name: "GetUser", Body: "MATCH (u:User) RETURN u"}},
+		Scopes: []*scaf.QueryScope{{
+			FunctionName: "GetUser",
+			Items: []*scaf.TestOrGroup{{Test: &scaf.Test{
+				Name:  "test",
+				Setup: &scaf.SetupClause{Call: &scaf.SetupCall{Module: "fixtures", Query: "CreateUser"}},
+			}}},
+		}},
+	}
+
+	result, err := r.Run(context.Background(), suite, "test.scaf")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if result.SetupFailed != 1 || result.Failed != 0 || result.Errors != 0 || result.Ok() {
+		t.Errorf("got %d setup failed, %d failed, %d errors, ok %t; want 1, 0, 0, false",
+			result.SetupFailed, result.Failed, result.Errors, result.Ok())
+	}
+
+	tr := result.Tests["GetUser/test"]
+	if tr == nil || tr.Status != ActionSetupFailed || !errors.Is(tr.Error, ErrNoModuleContext) {
+		t.Errorf("test result = %+v, want setup failure from missing modules", tr)
+	}
+
+	if len(d.executed) != 0 {
+		t.Errorf("executed = %v, want nothing after failed setup", d.executed)
+	}
+}
+
+// slowDatabase blocks each query until its context is done, like a server
+// running a long query, and records connections discarded after a timeout.
+type slowDatabase struct {
+	mockDatabase
+
+	discards int
+}
+
+func (s *slowDatabase) Execute(ctx context.Context, _ string, _ map[string]any) ([]map[string]any, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func (s *slowDatabase) Discard(context.Context) error {
+	s.discards++
+
+	return nil
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	d := &slowDatabase{}
+	h := &mockHandler{}
+	r := New(
+		WithDatabase(d),
+		WithHandler(h),
+		WithTimeout(time.Hour),
+		WithScopeTimeouts(map[string]time.Duration{"Slow": 10 * time.Millisecond}),
+	)
+
+	suite := &scaf.Suite{
+		Functions: []*scaf.Query{{Name: "Slow", Body: "CALL apoc.util.sleep(100000)"}},
+		Scopes: []*scaf.QueryScope{{
+			FunctionName: "Slow",
+			Items:        []*scaf.TestOrGroup{{Test: &scaf.Test{Name: "hangs"}}},
+		}},
+	}
+
+	result, err := r.Run(context.Background(), suite, "test.scaf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TimedOut != 1 || result.Errors != 0 || result.Ok() {
+		t.Errorf("got %d timed out, %d errors, ok %t; want 1, 0, false", result.TimedOut, result.Errors, result.Ok())
+	}
+
+	tr := result.Tests["Slow/hangs"]
+	if tr == nil || tr.Status != ActionTimeout || !errors.Is(tr.Error, ErrTestTimeout) {
+		t.Errorf("test result = %+v, want a timeout", tr)
+	}
+
+	if d.discards != 1 {
+		t.Errorf("discards = %d, want 1", d.discards)
+	}
+}
+
+// slowTxDatabase is a transactional database that can't discard
+// connections. Its transactions block each query until the context is done
+// and record their rollbacks.
+type slowTxDatabase struct {
+	mockDatabase
+
+	rollbacks    int
+	rollbackErrs []error
+}
+
+func (s *slowTxDatabase) Begin(context.Context) (scaf.DatabaseTransaction, error) {
+	return slowTransaction{s}, nil
+}
+
+type slowTransaction struct{ db *slowTxDatabase }
+
+func (t slowTransaction) Execute(ctx context.Context, _ string, _ map[string]any) ([]map[string]any, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func (t slowTransaction) Commit(context.Context) error { return nil }
+
+func (t slowTransaction) Rollback(ctx context.Context) error {
+	t.db.rollbacks++
+	t.db.rollbackErrs = append(t.db.rollbackErrs, ctx.Err())
+
+	return nil
+}
+
+func TestRunner_Timeout_RollsBackWithoutDiscard(t *testing.T) {
+	d := &slowTxDatabase{}
+	r := New(WithDatabase(d), WithTimeout(10*time.Millisecond))
+
+	suite := &scaf.Suite{
+		Functions: []*scaf.Query{{Name: "Slow", Body: "CALL apoc.util.sleep(100000)"}},
+		Scopes: []*scaf.QueryScope{{
+			FunctionName: "Slow",
+			Items:        []*scaf.TestOrGroup{{Test: &scaf.Test{Name: "hangs"}}},
+		}},
+	}
+
+	result, err := r.Run(context.Background(), suite, "test.scaf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.TimedOut != 1 {
+		t.Errorf("got %d timed out, want 1", result.TimedOut)
+	}
+
+	// The rollback can't use the test's expired context
+	if d.rollbacks != 1 || d.rollbackErrs[0] != nil {
+		t.Errorf("rollbacks = %d with context errors %v, want 1 with a live context", d.rollbacks, d.rollbackErrs)
+	}
+}
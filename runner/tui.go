@@ -411,7 +411,7 @@ func (m *tuiModel) handleEvent(event Event) { //nolint:funcorder
 		node.elapsed = event.Elapsed
 		m.counters.skipped++
 
-	case ActionError:
+	case ActionError, ActionTimeout, ActionSetupFailed:
 		node.status = statusError
 		node.elapsed = event.Elapsed
 		node.err = event.Error
@@ -0,0 +1,91 @@
+package scaf
+
+// expandTables appends a Test for each data row of every table in f, placed
+// directly after the table in its item list. Each column becomes a statement
+// keyed by the header cell, so runners, analysis rules, and code generators
+// see ordinary tests. Rows with more cells than header columns ignore the
+// extras; rows with fewer leave the remaining columns unset.
+func expandTables(f *File) {
+	if f == nil {
+		return
+	}
+
+	for _, scope := range f.Scopes {
+		if scope != nil {
+			scope.Items = expandTableItems(scope.Items)
+		}
+	}
+}
+
+func expandTableItems(items []*TestOrGroup) []*TestOrGroup {
+	hasTable := false
+
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+
+		if item.Group != nil {
+			item.Group.Items = expandTableItems(item.Group.Items)
+		}
+
+		if item.Table != nil {
+			hasTable = true
+		}
+	}
+
+	if !hasTable {
+		return items
+	}
+
+	expanded := make([]*TestOrGroup, 0, len(items))
+
+	for _, item := range items {
+		expanded = append(expanded, item)
+
+		if item == nil || item.Table == nil {
+			continue
+		}
+
+		for i, row := range item.Table.Rows {
+			expanded = append(expanded, &TestOrGroup{
+				NodeMeta: row.NodeMeta,
+				Test:     tableTest(item.Table, i, row),
+			})
+		}
+	}
+
+	return expanded
+}
+
+// tableTest builds the test for the row at index i of table.
+func tableTest(table *Table, i int, row *TableRow) *Test {
+	test := &Test{
+		NodeMeta:  row.NodeMeta,
+		Name:      table.TestName(i),
+		Close:     "}",
+		FromTable: table,
+	}
+
+	if table.Header == nil {
+		return test
+	}
+
+	for j, cell := range row.Cells {
+		if j >= len(table.Header.Columns) {
+			break
+		}
+
+		if cell == nil {
+			continue
+		}
+
+		test.Statements = append(test.Statements, &Statement{
+			NodeMeta: cell.NodeMeta,
+			KeyParts: table.Header.Columns[j],
+			Value:    cell,
+		})
+	}
+
+	return test
+}
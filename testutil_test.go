@@ -16,6 +16,7 @@ import (
 //   - LeadingComments, TrailingComment (via embedded CommentMeta)
 //   - RecoveredSpan (via embedded RecoveryMeta)
 //   - Close (captured closing braces on completable nodes)
+//   - the compiled program cached on ParenExpr
 var cmpIgnoreAST = cmp.Options{
 	// Ignore position and token types completely
 	cmpopts.IgnoreTypes(lexer.Position{}, lexer.Token{}, []lexer.Token{}),
@@ -30,6 +31,8 @@ var cmpIgnoreAST = cmp.Options{
 	// Ignore recovery metadata (from embedded RecoveryMeta)
 	cmpopts.IgnoreFields(scaf.Statement{}, "RecoveredSpan"),
 	cmpopts.IgnoreFields(scaf.StatementValue{}, "RecoveredSpan"),
+	// Ignore the compiled expression cache
+	cmpopts.IgnoreUnexported(scaf.ParenExpr{}),
 }
 
 // ptr returns a pointer to the given value.
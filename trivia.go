@@ -8,6 +8,35 @@ type Span struct {
 	End   lexer.Position
 }
 
+// Contains reports whether pos lies within the span. Both ends are
+// inclusive, so a zero-width span contains its own position. Only lines and
+// columns are compared.
+func (s Span) Contains(pos lexer.Position) bool {
+	return !positionBefore(pos, s.Start) && !positionBefore(s.End, pos)
+}
+
+// ContainsSpan reports whether other lies entirely within the span.
+func (s Span) ContainsSpan(other Span) bool {
+	return s.Contains(other.Start) && s.Contains(other.End)
+}
+
+// Overlaps reports whether the spans share at least one position. Spans that
+// only touch, one ending where the other starts, overlap.
+func (s Span) Overlaps(other Span) bool {
+	return !positionBefore(s.End, other.Start) && !positionBefore(other.End, s.Start)
+}
+
+// IsEmpty reports whether the span covers no text: its end is at or before
+// its start.
+func (s Span) IsEmpty() bool {
+	return !positionBefore(s.Start, s.End)
+}
+
+// positionBefore reports whether a comes before b by line, then column.
+func positionBefore(a, b lexer.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
 // Trivia represents non-semantic tokens like comments and whitespace.
 type Trivia struct {
 	Type TriviaType
@@ -268,6 +297,9 @@ func collectItemNodes(items []*TestOrGroup, add func(Commentable)) {
 		if item.Group != nil {
 			collectGroupNodes(item.Group, add)
 		}
+		if item.Table != nil {
+			add(item.Table)
+		}
 	}
 }
 
@@ -3,6 +3,7 @@ package scaf_test
 import (
 	"testing"
 
+	"github.com/alecthomas/participle/v2/lexer"
 	"github.com/google/go-cmp/cmp"
 	"github.com/rlch/scaf"
 )
@@ -607,3 +608,104 @@ Q {
 		})
 	}
 }
+
+func TestSpan(t *testing.T) {
+	t.Parallel()
+
+	pos := func(line, col int) lexer.Position { return lexer.Position{Line: line, Column: col} }
+	span := func(startLine, startCol, endLine, endCol int) scaf.Span {
+		return scaf.Span{Start: pos(startLine, startCol), End: pos(endLine, endCol)}
+	}
+
+	sameLine := span(2, 5, 2, 10)
+	crossLine := span(2, 5, 4, 3)
+	zeroWidth := span(3, 7, 3, 7)
+
+	t.Run("Contains", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name string
+			span scaf.Span
+			pos  lexer.Position
+			want bool
+		}{
+			{"same line start", sameLine, pos(2, 5), true},
+			{"same line end", sameLine, pos(2, 10), true},
+			{"same line before", sameLine, pos(2, 4), false},
+			{"same line after", sameLine, pos(2, 11), false},
+			{"other line", sameLine, pos(3, 6), false},
+			{"cross line middle line", crossLine, pos(3, 1), true},
+			{"cross line first line after start", crossLine, pos(2, 80), true},
+			{"cross line last line past end", crossLine, pos(4, 4), false},
+			{"zero width at pos", zeroWidth, pos(3, 7), true},
+			{"zero width next column", zeroWidth, pos(3, 8), false},
+		}
+
+		for _, tt := range tests {
+			if got := tt.span.Contains(tt.pos); got != tt.want {
+				t.Errorf("%s: Contains(%v) = %v, want %v", tt.name, tt.pos, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("ContainsSpan", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name        string
+			outer, span scaf.Span
+			want        bool
+		}{
+			{"itself", crossLine, crossLine, true},
+			{"nested same line", crossLine, sameLine, true},
+			{"zero width inside", crossLine, zeroWidth, true},
+			{"extends past end", sameLine, span(2, 6, 2, 11), false},
+			{"larger", sameLine, crossLine, false},
+		}
+
+		for _, tt := range tests {
+			if got := tt.outer.ContainsSpan(tt.span); got != tt.want {
+				t.Errorf("%s: ContainsSpan = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Overlaps", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name string
+			a, b scaf.Span
+			want bool
+		}{
+			{"nested", crossLine, sameLine, true},
+			{"touching", sameLine, span(2, 10, 2, 12), true},
+			{"disjoint same line", sameLine, span(2, 11, 2, 12), false},
+			{"disjoint lines", sameLine, span(5, 1, 6, 1), false},
+			{"zero width inside", crossLine, zeroWidth, true},
+			{"zero width outside", sameLine, zeroWidth, false},
+		}
+
+		for _, tt := range tests {
+			if got := tt.a.Overlaps(tt.b); got != tt.want {
+				t.Errorf("%s: a.Overlaps(b) = %v, want %v", tt.name, got, tt.want)
+			}
+			if got := tt.b.Overlaps(tt.a); got != tt.want {
+				t.Errorf("%s: b.Overlaps(a) = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("IsEmpty", func(t *testing.T) {
+		t.Parallel()
+
+		if !zeroWidth.IsEmpty() || !(scaf.Span{}).IsEmpty() {
+			t.Error("zero-width spans should be empty")
+		}
+
+		if sameLine.IsEmpty() || crossLine.IsEmpty() {
+			t.Error("spans covering text should not be empty")
+		}
+	})
+}
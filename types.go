@@ -27,6 +27,7 @@ const (
 	TypeKindMap       TypeKind = "map"       // map[K]V
 	TypeKindPointer   TypeKind = "pointer"   // *T
 	TypeKindNamed     TypeKind = "named"     // time.Time, uuid.UUID, etc.
+	TypeKindStruct    TypeKind = "struct"    // struct{name string; age int}
 )
 
 // Type represents a type in the schema.
@@ -52,6 +53,15 @@ type Type struct {
 
 	// ArrayLen is the length for array types.
 	ArrayLen int
+
+	// Fields are the named fields for struct types, in declaration order.
+	Fields []*StructField
+}
+
+// StructField is a named field of a struct type.
+type StructField struct {
+	Name string
+	Type *Type
 }
 
 // String returns a Go-style string representation of the type.
@@ -77,6 +87,18 @@ func (t *Type) String() string {
 		}
 
 		return t.Name
+	case TypeKindStruct:
+		fields := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			typ := f.Type.String()
+			if typ == "" {
+				typ = "any"
+			}
+
+			fields[i] = f.Name + " " + typ
+		}
+
+		return "struct{" + strings.Join(fields, "; ") + "}"
 	default:
 		return t.Name
 	}
@@ -93,6 +115,7 @@ func (t *Type) String() string {
 //	"*int"             -> TypeKindPointer, Elem=int
 //	"map[string]int"   -> TypeKindMap, Key=string, Elem=int
 //	"time.Time"        -> TypeKindNamed, Package="time", Name="Time"
+//	"datetime"         -> TypeDateTime (also date, time, localdatetime, localtime, duration, point, point3d)
 //	"[]map[string]*int" -> nested types
 func ParseTypeString(s string) (*Type, error) {
 	s = strings.TrimSpace(s)
@@ -206,6 +229,11 @@ func parseType(s string) (*Type, error) {
 		return &Type{Kind: TypeKindPrimitive, Name: s}, nil
 	}
 
+	// Check for database temporal/spatial type names
+	if typ, ok := databaseTypeNames[s]; ok {
+		return typ, nil
+	}
+
 	// Treat as named type without package (user-defined type in same package)
 	if isValidTypeIdentifier(s) {
 		return NamedType("", s), nil
@@ -259,6 +287,42 @@ var (
 	TypeBool    = &Type{Kind: TypeKindPrimitive, Name: "bool"}
 )
 
+// Temporal and spatial types, named after the Go driver types they decode to.
+// DATETIME values decode to time.Time; the rest use the driver's own types.
+var (
+	TypeDate          = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Date"}
+	TypeTime          = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Time"}
+	TypeDateTime      = &Type{Kind: TypeKindNamed, Package: "time", Name: "Time"}
+	TypeLocalDateTime = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "LocalDateTime"}
+	TypeLocalTime     = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "LocalTime"}
+	TypeDuration      = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Duration"}
+	TypePoint         = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Point2D"}
+	TypePoint3D       = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Point3D"}
+)
+
+// Graph structure types, named after the Go driver types they decode to.
+var (
+	TypePath         = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Path"}
+	TypeNode         = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Node"}
+	TypeRelationship = &Type{Kind: TypeKindNamed, Package: "neo4j", Name: "Relationship"}
+)
+
+// databaseTypeNames maps database type names used in schema files
+// (e.g. "date", "path") to their temporal, spatial, and graph types.
+var databaseTypeNames = map[string]*Type{
+	"date":          TypeDate,
+	"time":          TypeTime,
+	"datetime":      TypeDateTime,
+	"localdatetime": TypeLocalDateTime,
+	"localtime":     TypeLocalTime,
+	"duration":      TypeDuration,
+	"point":         TypePoint,
+	"point3d":       TypePoint3D,
+	"path":          TypePath,
+	"node":          TypeNode,
+	"relationship":  TypeRelationship,
+}
+
 // SliceOf creates a slice type.
 func SliceOf(elem *Type) *Type {
 	return &Type{Kind: TypeKindSlice, Elem: elem}
@@ -274,6 +338,11 @@ func MapOf(key, value *Type) *Type {
 	return &Type{Kind: TypeKindMap, Key: key, Elem: value}
 }
 
+// StructOf creates a struct type with the given fields.
+func StructOf(fields ...*StructField) *Type {
+	return &Type{Kind: TypeKindStruct, Fields: fields}
+}
+
 // NamedType creates a named type.
 func NamedType(pkg, name string) *Type {
 	return &Type{Kind: TypeKindNamed, Package: pkg, Name: name}